@@ -4,10 +4,13 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/api-gateway/middleware"
+	"github.com/yourusername/ticket-system/claims"
 )
 
 // ServiceConfig holds configuration for each microservice
@@ -15,42 +18,53 @@ type ServiceConfig struct {
 	Name string
 	Port string
 	Host string
+
+	// BasePath is the prefix the service serves the /api/v1 routes of the
+	// gateway under
+	BasePath string
 }
 
 // ProxyHandler handles proxy requests to microservices
 type ProxyHandler struct {
 	services map[string]ServiceConfig
 	client   *http.Client
+	signer   *claims.Signer
 }
 
-// NewProxyHandler creates a new proxy handler
-func NewProxyHandler() *ProxyHandler {
+// NewProxyHandler creates a new proxy handler that forwards the identity of
+// authenticated callers signed by signer
+func NewProxyHandler(signer *claims.Signer) *ProxyHandler {
 	return &ProxyHandler{
 		services: map[string]ServiceConfig{
 			"user": {
-				Name: "user-service",
-				Port: "8081",
-				Host: getEnvOrDefault("USER_SERVICE_HOST", "localhost"),
+				Name:     "user-service",
+				Port:     "8081",
+				Host:     getEnvOrDefault("USER_SERVICE_HOST", "localhost"),
+				BasePath: "/api",
 			},
 			"event": {
-				Name: "event-ticket-service",
-				Port: "8082",
-				Host: getEnvOrDefault("EVENT_TICKET_SERVICE_HOST", "localhost"),
+				Name:     "event-ticket-service",
+				Port:     "8082",
+				Host:     getEnvOrDefault("EVENT_TICKET_SERVICE_HOST", "localhost"),
+				BasePath: "/api",
 			},
 			"payment": {
-				Name: "payment-service",
-				Port: "8083",
-				Host: getEnvOrDefault("PAYMENT_SERVICE_HOST", "localhost"),
+				Name:     "payment-service",
+				Port:     "8083",
+				Host:     getEnvOrDefault("PAYMENT_SERVICE_HOST", "localhost"),
+				BasePath: "/api",
 			},
 			"notification": {
-				Name: "notification-service",
-				Port: "8084",
-				Host: getEnvOrDefault("NOTIFICATION_SERVICE_HOST", "localhost"),
+				Name:     "notification-service",
+				Port:     "8084",
+				Host:     getEnvOrDefault("NOTIFICATION_SERVICE_HOST", "localhost"),
+				BasePath: "/api/v1",
 			},
 		},
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		signer: signer,
 	}
 }
 
@@ -70,8 +84,13 @@ func (p *ProxyHandler) ProxyToService(serviceName string) gin.HandlerFunc {
 
 // proxyRequest handles the actual proxy logic
 func (p *ProxyHandler) proxyRequest(c *gin.Context, service ServiceConfig) {
-	// Build target URL
-	targetURL := "http://" + service.Host + ":" + service.Port + c.Request.URL.Path
+	// Build target URL, with the /api/v1 prefix of the gateway replaced by
+	// the prefix of the service
+	path := c.Request.URL.Path
+	if strings.HasPrefix(path, "/api/v1/") {
+		path = service.BasePath + strings.TrimPrefix(path, "/api/v1")
+	}
+	targetURL := "http://" + service.Host + ":" + service.Port + path
 	if c.Request.URL.RawQuery != "" {
 		targetURL += "?" + c.Request.URL.RawQuery
 	}
@@ -97,6 +116,18 @@ func (p *ProxyHandler) proxyRequest(c *gin.Context, service ServiceConfig) {
 		}
 	}
 
+	// Forward the identity the gateway authenticated, never one sent by the client
+	req.Header.Del(claims.Header)
+	if identity, ok := c.Get(middleware.IdentityKey); ok {
+		value, err := p.signer.Sign(identity.(claims.Identity))
+		if err != nil {
+			logrus.Errorf("Failed to sign identity: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		req.Header.Set(claims.Header, value)
+	}
+
 	// Add X-Forwarded headers
 	req.Header.Set("X-Forwarded-For", c.ClientIP())
 	req.Header.Set("X-Forwarded-Proto", "http")
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/ticket-system/claims"
+)
+
+func TestProxyHandler_ReplacesSpoofedForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var forwardedFor, path string
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwardedFor = r.Header.Get("X-Forwarded-For")
+		path = r.URL.Path
+	}))
+	defer service.Close()
+
+	serviceURL, err := url.Parse(service.URL)
+	require.NoError(t, err)
+	host, port, err := net.SplitHostPort(serviceURL.Host)
+	require.NoError(t, err)
+
+	proxyHandler := NewProxyHandler(claims.NewSigner(claims.Config{}))
+	proxyHandler.services["event"] = ServiceConfig{Name: "event-ticket-service", Host: host, Port: port, BasePath: "/api"}
+
+	// Configured like the gateway, which trusts no proxy in front of it
+	router := gin.New()
+	require.NoError(t, router.SetTrustedProxies(nil))
+	router.GET("/api/v1/events", proxyHandler.ProxyToService("event"))
+
+	// Responses are streamed, which needs a real connection
+	gateway := httptest.NewServer(router)
+	defer gateway.Close()
+
+	request, err := http.NewRequest(http.MethodGet, gateway.URL+"/api/v1/events", nil)
+	require.NoError(t, err)
+	request.Header.Set("X-Forwarded-For", "203.0.113.7")
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	response.Body.Close()
+
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "127.0.0.1", forwardedFor)
+
+	// The /api/v1 prefix of the gateway is replaced by the prefix of the service
+	assert.Equal(t, "/api/events", path)
+}
@@ -11,6 +11,8 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/claims"
+	"github.com/yourusername/ticket-system/jwks"
 
 	"./handler"
 	"./middleware"
@@ -29,8 +31,21 @@ func main() {
 	// Initialize Gin router
 	router := gin.New()
 
+	// The gateway faces the clients, so the address of the client is the
+	// address the request comes from and X-Forwarded-For is never trusted
+	if err := router.SetTrustedProxies(nil); err != nil {
+		logrus.Fatalf("Failed to configure trusted proxies: %v", err)
+	}
+
+	// Identities of authenticated callers are forwarded to the services
+	// signed with the secret shared with them
+	identitySigner := claims.NewSigner(claims.ConfigFromEnv())
+	if !identitySigner.Configured() {
+		logrus.Warn("INTERNAL_IDENTITY_SECRET is not set, authenticated requests cannot be forwarded")
+	}
+
 	// Initialize proxy handler
-	proxyHandler := handler.NewProxyHandler()
+	proxyHandler := handler.NewProxyHandler(identitySigner)
 
 	// Initialize rate limiter (100 requests per minute)
 	rateLimiter := middleware.NewRateLimiter(100)
@@ -46,15 +61,21 @@ func main() {
 	config := cors.DefaultConfig()
 	config.AllowAllOrigins = true
 	config.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Request-ID"}
+	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Request-ID", "X-API-Key", "Idempotency-Key"}
 	router.Use(cors.New(config))
 
-	// JWT secret key
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "your-secret-key" // Default for development
-		logrus.Warn("JWT_SECRET not set, using default key")
+	// Public keys of the user service, which verify access tokens
+	jwksClient := jwks.NewClient(jwks.ConfigFromEnv())
+
+	// Revoked sessions, synced from the user service every 5 seconds
+	userServiceHost := os.Getenv("USER_SERVICE_HOST")
+	if userServiceHost == "" {
+		userServiceHost = "localhost"
 	}
+	revocations := middleware.NewRevocationList("http://"+userServiceHost+":8081", 5*time.Second)
+
+	// API keys of partner systems, exchanged for access tokens at the user service
+	apiKeys := middleware.NewAPIKeyAuthenticator("http://" + userServiceHost + ":8081")
 
 	// Health check endpoint
 	router.GET("/health", func(c *gin.Context) {
@@ -77,42 +98,132 @@ func main() {
 			// Public routes
 			userGroup.POST("/register", proxyHandler.ProxyToService("user"))
 			userGroup.POST("/login", proxyHandler.ProxyToService("user"))
+			userGroup.POST("/login/mfa", proxyHandler.ProxyToService("user"))
+			userGroup.POST("/refresh", proxyHandler.ProxyToService("user"))
+			userGroup.POST("/verify-email/request", proxyHandler.ProxyToService("user"))
+			userGroup.POST("/verify-email/confirm", proxyHandler.ProxyToService("user"))
+			userGroup.POST("/password-reset/request", proxyHandler.ProxyToService("user"))
+			userGroup.POST("/password-reset/confirm", proxyHandler.ProxyToService("user"))
+			userGroup.GET("/oidc/providers", proxyHandler.ProxyToService("user"))
+			userGroup.GET("/oidc/:provider/authorize", proxyHandler.ProxyToService("user"))
+			userGroup.GET("/oidc/:provider/callback", proxyHandler.ProxyToService("user"))
+			userGroup.POST("/oidc/:provider/callback", proxyHandler.ProxyToService("user"))
 			
 			// Protected routes
 			protectedUser := userGroup.Group("")
-			protectedUser.Use(middleware.AuthMiddleware(jwtSecret))
+			protectedUser.Use(middleware.AuthMiddleware(jwksClient.Keyfunc, revocations, apiKeys))
 			{
 				protectedUser.GET("/profile", proxyHandler.ProxyToService("user"))
 				protectedUser.PUT("/profile", proxyHandler.ProxyToService("user"))
+				protectedUser.POST("/change-password", proxyHandler.ProxyToService("user"))
+				protectedUser.POST("/logout", proxyHandler.ProxyToService("user"))
+				protectedUser.GET("/sessions", proxyHandler.ProxyToService("user"))
+				protectedUser.DELETE("/sessions", proxyHandler.ProxyToService("user"))
+				protectedUser.DELETE("/sessions/:id", proxyHandler.ProxyToService("user"))
+				protectedUser.GET("/mfa", proxyHandler.ProxyToService("user"))
+				protectedUser.DELETE("/mfa", proxyHandler.ProxyToService("user"))
+				protectedUser.POST("/mfa/enroll", proxyHandler.ProxyToService("user"))
+				protectedUser.POST("/mfa/confirm", proxyHandler.ProxyToService("user"))
+				protectedUser.POST("/mfa/recovery-codes", proxyHandler.ProxyToService("user"))
 			}
 		}
 
+		// Organization routes (require auth)
+		organizationGroup := api.Group("/organizations")
+		organizationGroup.Use(middleware.AuthMiddleware(jwksClient.Keyfunc, revocations, apiKeys))
+		{
+			organizationGroup.POST("", proxyHandler.ProxyToService("user"))
+			organizationGroup.GET("", proxyHandler.ProxyToService("user"))
+			organizationGroup.GET("/:id/members", proxyHandler.ProxyToService("user"))
+			organizationGroup.POST("/:id/members", proxyHandler.ProxyToService("user"))
+			organizationGroup.PUT("/:id/members/:userId", proxyHandler.ProxyToService("user"))
+			organizationGroup.DELETE("/:id/members/:userId", proxyHandler.ProxyToService("user"))
+			organizationGroup.POST("/:id/api-keys", proxyHandler.ProxyToService("user"))
+			organizationGroup.GET("/:id/api-keys", proxyHandler.ProxyToService("user"))
+			organizationGroup.POST("/:id/api-keys/:keyId/rotate", proxyHandler.ProxyToService("user"))
+			organizationGroup.DELETE("/:id/api-keys/:keyId", proxyHandler.ProxyToService("user"))
+		}
+
 		// Event service routes
 		eventGroup := api.Group("/events")
 		{
-			// Public routes
-			eventGroup.GET("", proxyHandler.ProxyToService("event"))
-			eventGroup.GET("/:id", proxyHandler.ProxyToService("event"))
+			// Public routes, also called by partners with API keys
+			eventGroup.GET("", middleware.APIKeyMiddleware(apiKeys), proxyHandler.ProxyToService("event"))
+			eventGroup.GET("/:id", middleware.APIKeyMiddleware(apiKeys), proxyHandler.ProxyToService("event"))
+			eventGroup.GET("/search", proxyHandler.ProxyToService("event"))
+			eventGroup.GET("/:id/refund-policy", proxyHandler.ProxyToService("event"))
 			
-			// Protected routes (admin only)
+			// Protected routes, the event service checks the organization
+			// permissions of the user on each event
 			protectedEvent := eventGroup.Group("")
-			protectedEvent.Use(middleware.AuthMiddleware(jwtSecret))
-			protectedEvent.Use(middleware.RoleMiddleware("admin", "organizer"))
+			protectedEvent.Use(middleware.AuthMiddleware(jwksClient.Keyfunc, revocations, apiKeys))
 			{
 				protectedEvent.POST("", proxyHandler.ProxyToService("event"))
+				protectedEvent.POST("/import", proxyHandler.ProxyToService("event"))
 				protectedEvent.PUT("/:id", proxyHandler.ProxyToService("event"))
 				protectedEvent.DELETE("/:id", proxyHandler.ProxyToService("event"))
+				protectedEvent.PUT("/:id/refund-policy", proxyHandler.ProxyToService("event"))
+				protectedEvent.DELETE("/:id/refund-policy", proxyHandler.ProxyToService("event"))
 			}
 		}
 
 		// Ticket booking routes (require auth)
 		bookingGroup := api.Group("/bookings")
-		bookingGroup.Use(middleware.AuthMiddleware(jwtSecret))
+		bookingGroup.Use(middleware.AuthMiddleware(jwksClient.Keyfunc, revocations, apiKeys))
 		{
 			bookingGroup.POST("", proxyHandler.ProxyToService("event"))
+			bookingGroup.GET("/user", proxyHandler.ProxyToService("event"))
 			bookingGroup.GET("/:id", proxyHandler.ProxyToService("event"))
-			bookingGroup.GET("/user/:userId", proxyHandler.ProxyToService("event"))
-			bookingGroup.PUT("/:id/cancel", proxyHandler.ProxyToService("event"))
+			bookingGroup.POST("/:id/cancel", proxyHandler.ProxyToService("event"))
+			bookingGroup.GET("/:id/refund-quote", proxyHandler.ProxyToService("event"))
+			bookingGroup.POST("/:id/amendments", proxyHandler.ProxyToService("event"))
+			bookingGroup.GET("/:id/amendments", proxyHandler.ProxyToService("event"))
+			bookingGroup.POST("/tickets/:ticketId/check-in", proxyHandler.ProxyToService("event"))
+		}
+
+		// Sales reports and exports (require auth), the event service checks
+		// the organization permissions of the user on each event
+		reportGroup := api.Group("/reports")
+		reportGroup.Use(middleware.AuthMiddleware(jwksClient.Keyfunc, revocations, apiKeys))
+		{
+			reportGroup.GET("/events/:id", proxyHandler.ProxyToService("event"))
+			reportGroup.POST("/events/:id/refresh", proxyHandler.ProxyToService("event"))
+		}
+
+		exportGroup := api.Group("/exports")
+		exportGroup.Use(middleware.AuthMiddleware(jwksClient.Keyfunc, revocations, apiKeys))
+		{
+			exportGroup.GET("/events/:id/:dataset", proxyHandler.ProxyToService("event"))
+			exportGroup.POST("/events/:id/:dataset/jobs", proxyHandler.ProxyToService("event"))
+			exportGroup.GET("/jobs/:jobId", proxyHandler.ProxyToService("event"))
+			exportGroup.GET("/jobs/:jobId/download", proxyHandler.ProxyToService("event"))
+		}
+
+		// Admin routes (admin only)
+		adminGroup := api.Group("/admin")
+		adminGroup.Use(middleware.AuthMiddleware(jwksClient.Keyfunc, revocations, apiKeys))
+		adminGroup.Use(middleware.RoleMiddleware("admin"))
+		{
+			adminGroup.GET("/users", proxyHandler.ProxyToService("user"))
+			adminGroup.POST("/users/import", proxyHandler.ProxyToService("user"))
+			adminGroup.GET("/users/:id/activity", proxyHandler.ProxyToService("user"))
+			adminGroup.PUT("/users/:id/role", proxyHandler.ProxyToService("user"))
+			adminGroup.POST("/users/:id/impersonate", proxyHandler.ProxyToService("user"))
+			adminGroup.POST("/users/:id/suspend", proxyHandler.ProxyToService("user"))
+			adminGroup.POST("/users/:id/reactivate", proxyHandler.ProxyToService("user"))
+			adminGroup.POST("/users/:id/unlock", proxyHandler.ProxyToService("user"))
+			adminGroup.DELETE("/users/:id", proxyHandler.ProxyToService("user"))
+			adminGroup.DELETE("/users/:id/mfa", proxyHandler.ProxyToService("user"))
+			adminGroup.POST("/users/:id/data-requests", proxyHandler.ProxyToService("user"))
+			adminGroup.GET("/users/:id/data-requests", proxyHandler.ProxyToService("user"))
+			adminGroup.GET("/data-requests/:id", proxyHandler.ProxyToService("user"))
+			adminGroup.GET("/data-requests/:id/archive", proxyHandler.ProxyToService("user"))
+
+			adminGroup.GET("/dead-letters", proxyHandler.ProxyToService("event"))
+			adminGroup.POST("/dead-letters/:messageId/replay", proxyHandler.ProxyToService("event"))
+			adminGroup.DELETE("/dead-letters/:messageId", proxyHandler.ProxyToService("event"))
+			adminGroup.GET("/sagas", proxyHandler.ProxyToService("event"))
+			adminGroup.GET("/sagas/:bookingId", proxyHandler.ProxyToService("event"))
 		}
 
 		// Payment service routes
@@ -123,22 +234,43 @@ func main() {
 			
 			// Protected routes
 			protectedPayment := paymentGroup.Group("")
-			protectedPayment.Use(middleware.AuthMiddleware(jwtSecret))
+			protectedPayment.Use(middleware.AuthMiddleware(jwksClient.Keyfunc, revocations, apiKeys))
 			{
 				protectedPayment.POST("", proxyHandler.ProxyToService("payment"))
+				protectedPayment.POST("/process", proxyHandler.ProxyToService("payment"))
+				protectedPayment.GET("/user", proxyHandler.ProxyToService("payment"))
+				protectedPayment.GET("/booking/:bookingId", proxyHandler.ProxyToService("payment"))
 				protectedPayment.GET("/:id", proxyHandler.ProxyToService("payment"))
-				protectedPayment.POST("/:id/confirm", proxyHandler.ProxyToService("payment"))
+				protectedPayment.POST("/:id/refund", proxyHandler.ProxyToService("payment"))
+				protectedPayment.GET("/:id/refunds", proxyHandler.ProxyToService("payment"))
 			}
 		}
 
-		// Notification service routes (admin only)
+		// Notification service routes
 		notificationGroup := api.Group("/notifications")
-		notificationGroup.Use(middleware.AuthMiddleware(jwtSecret))
-		notificationGroup.Use(middleware.RoleMiddleware("admin"))
 		{
-			notificationGroup.POST("/send", proxyHandler.ProxyToService("notification"))
-			notificationGroup.GET("/templates", proxyHandler.ProxyToService("notification"))
-			notificationGroup.POST("/templates", proxyHandler.ProxyToService("notification"))
+			// Public unsubscribe links, signed by the notification service
+			notificationGroup.GET("/unsubscribe", proxyHandler.ProxyToService("notification"))
+			notificationGroup.POST("/unsubscribe", proxyHandler.ProxyToService("notification"))
+
+			// Preferences of the current user
+			preferenceGroup := notificationGroup.Group("/preferences")
+			preferenceGroup.Use(middleware.AuthMiddleware(jwksClient.Keyfunc, revocations, apiKeys))
+			{
+				preferenceGroup.GET("", proxyHandler.ProxyToService("notification"))
+				preferenceGroup.PUT("", proxyHandler.ProxyToService("notification"))
+				preferenceGroup.PUT("/marketing", proxyHandler.ProxyToService("notification"))
+			}
+
+			// Admin only
+			adminNotification := notificationGroup.Group("")
+			adminNotification.Use(middleware.AuthMiddleware(jwksClient.Keyfunc, revocations, apiKeys))
+			adminNotification.Use(middleware.RoleMiddleware("admin"))
+			{
+				adminNotification.POST("/send", proxyHandler.ProxyToService("notification"))
+				adminNotification.GET("/templates", proxyHandler.ProxyToService("notification"))
+				adminNotification.POST("/templates", proxyHandler.ProxyToService("notification"))
+			}
 		}
 	}
 
@@ -0,0 +1,237 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/claims"
+)
+
+// apiKeyRouteScopes are the routes API keys can call, with the scope each
+// of them requires. Every other route rejects API keys.
+var apiKeyRouteScopes = map[string]string{
+	"GET /api/v1/events":                               "events:read",
+	"GET /api/v1/events/:id":                           "events:read",
+	"POST /api/v1/bookings":                            "bookings:create",
+	"POST /api/v1/bookings/tickets/:ticketId/check-in": "tickets:scan",
+}
+
+// apiKeyTokenRefreshMargin is how long before it expires a cached access
+// token is exchanged again
+const apiKeyTokenRefreshMargin = 30 * time.Second
+
+// errInvalidAPIKey is returned when the user service rejects an API key
+var errInvalidAPIKey = errors.New("invalid or expired API key")
+
+// APIKeyAuthenticator exchanges the API keys of partner systems for
+// short-lived access tokens issued by the user service, caches the tokens
+// until they are about to expire and enforces the rate limit of each key
+type APIKeyAuthenticator struct {
+	tokens  map[string]*apiKeyToken // hash of the API key to its access token
+	mutex   sync.RWMutex
+	url     string
+	client  *http.Client
+	limiter *RateLimiter
+}
+
+// apiKeyToken is an access token issued by the user service for an API key
+type apiKeyToken struct {
+	Token          string    `json:"token"`
+	ExpiresIn      int64     `json:"expires_in"`
+	KeyID          uuid.UUID `json:"key_id"`
+	OrganizationID uuid.UUID `json:"organization_id"`
+	Scopes         []string  `json:"scopes"`
+	RateLimit      int       `json:"rate_limit"`
+	expiresAt      time.Time
+}
+
+// NewAPIKeyAuthenticator creates an API key authenticator that exchanges
+// keys with the user service at userServiceURL
+func NewAPIKeyAuthenticator(userServiceURL string) *APIKeyAuthenticator {
+	return &APIKeyAuthenticator{
+		tokens: make(map[string]*apiKeyToken),
+		url:    userServiceURL + "/internal/api-keys/token",
+		client: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		limiter: NewRateLimiter(0),
+	}
+}
+
+// APIKeyMiddleware authenticates requests that carry an API key and lets
+// the others through, for public routes that partners also call
+func APIKeyMiddleware(apiKeys *APIKeyAuthenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key := extractAPIKey(c); key != "" {
+			apiKeys.authenticate(c, key)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// authenticate checks that the API key may call the route and is within its
+// rate limit, and replaces it by an access token for the downstream service
+func (a *APIKeyAuthenticator) authenticate(c *gin.Context, key string) {
+	scope, ok := apiKeyRouteScopes[c.Request.Method+" "+c.FullPath()]
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API keys cannot access this resource"})
+		c.Abort()
+		return
+	}
+
+	token, err := a.token(key)
+	if err == errInvalidAPIKey {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired API key"})
+		c.Abort()
+		return
+	}
+	if err != nil {
+		logrus.Errorf("Failed to exchange API key: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Failed to validate API key"})
+		c.Abort()
+		return
+	}
+
+	if !token.hasScope(scope) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API key lacks the " + scope + " scope"})
+		c.Abort()
+		return
+	}
+
+	allowed, remaining, reset := a.limiter.take(token.KeyID.String(), token.RateLimit)
+	c.Header("X-RateLimit-Limit", strconv.Itoa(token.RateLimit))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	c.Header("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+	if !allowed {
+		c.Header("Retry-After", strconv.Itoa(int(time.Until(reset).Seconds())+1))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":       "API key rate limit exceeded",
+			"retry_after": "60 seconds",
+		})
+		c.Abort()
+		return
+	}
+
+	// Downstream services only see the access token and identity of the key
+	c.Request.Header.Del("X-API-Key")
+	c.Request.Header.Set("Authorization", "Bearer "+token.Token)
+
+	// Set API key info in context
+	c.Set("api_key_id", token.KeyID)
+	c.Set("organization_id", token.OrganizationID)
+	c.Set("user_role", claims.APIKeyRole)
+	c.Set(IdentityKey, token.identity())
+
+	c.Next()
+}
+
+// token returns a cached access token for the API key, or exchanges the key
+// for a new one
+func (a *APIKeyAuthenticator) token(key string) (*apiKeyToken, error) {
+	hash := sha256.Sum256([]byte(key))
+	keyHash := hex.EncodeToString(hash[:])
+
+	a.mutex.RLock()
+	token, ok := a.tokens[keyHash]
+	a.mutex.RUnlock()
+	if ok && time.Now().Before(token.expiresAt) {
+		return token, nil
+	}
+
+	token, err := a.exchange(key)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	// Drop the tokens that have expired while storing the new one
+	now := time.Now()
+	for cachedHash, cached := range a.tokens {
+		if !now.Before(cached.expiresAt) {
+			delete(a.tokens, cachedHash)
+		}
+	}
+	a.tokens[keyHash] = token
+
+	return token, nil
+}
+
+// exchange exchanges an API key for an access token at the user service
+func (a *APIKeyAuthenticator) exchange(key string) (*apiKeyToken, error) {
+	body, err := json.Marshal(map[string]string{"key": key})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := a.client.Post(a.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusBadRequest {
+		return nil, errInvalidAPIKey
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var token apiKeyToken
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return nil, err
+	}
+	token.expiresAt = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - apiKeyTokenRefreshMargin)
+
+	return &token, nil
+}
+
+// identity returns the identity of the API key of the token
+func (t *apiKeyToken) identity() claims.Identity {
+	organizationID := t.OrganizationID
+	return claims.Identity{
+		UserID:         t.KeyID,
+		Role:           claims.APIKeyRole,
+		OrganizationID: &organizationID,
+		Scopes:         t.Scopes,
+	}
+}
+
+// hasScope reports whether the API key of the token was granted scope
+func (t *apiKeyToken) hasScope(scope string) bool {
+	for _, granted := range t.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// extractAPIKey extracts the API key from the X-API-Key header, or from an
+// "ApiKey <key>" Authorization header
+func extractAPIKey(c *gin.Context) string {
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return key
+	}
+
+	authorization := c.GetHeader("Authorization")
+	if len(authorization) > 7 && strings.EqualFold(authorization[:7], "ApiKey ") {
+		return authorization[7:]
+	}
+	return ""
+}
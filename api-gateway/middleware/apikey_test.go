@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupAPIKeyRouter creates a router with the API key routes of the gateway,
+// whose user service exchanges every key for a token with scopes and
+// rateLimit
+func setupAPIKeyRouter(t *testing.T, scopes []string, rateLimit int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	userService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/internal/api-keys/token", r.URL.Path)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"token":           "access-token",
+			"expires_in":      300,
+			"key_id":          uuid.New(),
+			"organization_id": uuid.New(),
+			"scopes":          scopes,
+			"rate_limit":      rateLimit,
+		})
+	}))
+	t.Cleanup(userService.Close)
+
+	// Echo the Authorization header the downstream service receives
+	downstream := func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetHeader("Authorization"))
+	}
+
+	router := gin.New()
+	api := router.Group("/api/v1")
+	api.Use(APIKeyMiddleware(NewAPIKeyAuthenticator(userService.URL)))
+	api.GET("/events", downstream)
+	api.POST("/bookings", downstream)
+	api.POST("/bookings/tickets/:ticketId/check-in", downstream)
+	api.GET("/payments/:id", downstream)
+	return router
+}
+
+// callWithAPIKey sends a request with an API key to the router
+func callWithAPIKey(router *gin.Engine, method, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("X-API-Key", "tk_partner")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAPIKeyMiddleware_ForwardsAccessTokenForScopedRoute(t *testing.T) {
+	router := setupAPIKeyRouter(t, []string{"tickets:scan"}, 60)
+
+	w := callWithAPIKey(router, http.MethodPost, "/api/v1/bookings/tickets/"+uuid.New().String()+"/check-in")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Bearer access-token", w.Body.String())
+}
+
+func TestAPIKeyMiddleware_RejectsMissingScope(t *testing.T) {
+	router := setupAPIKeyRouter(t, []string{"events:read"}, 60)
+
+	w := callWithAPIKey(router, http.MethodPost, "/api/v1/bookings")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "API key lacks the bookings:create scope")
+
+	w = callWithAPIKey(router, http.MethodPost, "/api/v1/bookings/tickets/"+uuid.New().String()+"/check-in")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "API key lacks the tickets:scan scope")
+}
+
+func TestAPIKeyMiddleware_RejectsRouteWithoutScope(t *testing.T) {
+	router := setupAPIKeyRouter(t, []string{"events:read", "bookings:create", "tickets:scan"}, 60)
+
+	w := callWithAPIKey(router, http.MethodGet, "/api/v1/payments/"+uuid.New().String())
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "API keys cannot access this resource")
+}
+
+func TestAPIKeyMiddleware_SetsRateLimitHeaders(t *testing.T) {
+	router := setupAPIKeyRouter(t, []string{"events:read"}, 3)
+
+	for remaining := 2; remaining >= 0; remaining-- {
+		w := callWithAPIKey(router, http.MethodGet, "/api/v1/events")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "3", w.Header().Get("X-RateLimit-Limit"))
+		assert.Equal(t, strconv.Itoa(remaining), w.Header().Get("X-RateLimit-Remaining"))
+		assert.NotEmpty(t, w.Header().Get("X-RateLimit-Reset"))
+	}
+}
+
+func TestAPIKeyMiddleware_RejectsKeyOverRateLimit(t *testing.T) {
+	router := setupAPIKeyRouter(t, []string{"events:read"}, 2)
+
+	for i := 0; i < 2; i++ {
+		require.Equal(t, http.StatusOK, callWithAPIKey(router, http.MethodGet, "/api/v1/events").Code)
+	}
+
+	w := callWithAPIKey(router, http.MethodGet, "/api/v1/events")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	assert.True(t, retryAfter > 0 && retryAfter <= 60)
+}
@@ -8,25 +8,33 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/claims"
+	"github.com/yourusername/ticket-system/jwks"
 )
 
-// JWTClaims represents the JWT claims
-type JWTClaims struct {
-	UserID   uint   `json:"user_id"`
-	Email    string `json:"email"`
-	Role     string `json:"role"`
-	jwt.RegisteredClaims
-}
+// IdentityKey is the context key of the claims.Identity of the caller, which
+// the proxy forwards signed to the services
+const IdentityKey = "identity"
 
-// AuthMiddleware validates JWT tokens
-func AuthMiddleware(secretKey string) gin.HandlerFunc {
+// AuthMiddleware validates JWT tokens, looking up the key that signed them
+// with keyfunc, and rejects tokens of revoked sessions. Requests of partner
+// systems that carry an API key instead are authenticated by apiKeys. The
+// services trust the identity of the caller set here instead of parsing the
+// token again.
+func AuthMiddleware(keyfunc jwt.Keyfunc, revocations *RevocationList, apiKeys *APIKeyAuthenticator) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Skip auth for certain endpoints
-		if shouldSkipAuth(c.Request.URL.Path) {
+		if shouldSkipAuth(c.Request.Method, c.Request.URL.Path) {
 			c.Next()
 			return
 		}
 
+		// Authenticate API keys of partner systems
+		if key := extractAPIKey(c); key != "" {
+			apiKeys.authenticate(c, key)
+			return
+		}
+
 		// Get token from Authorization header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -45,10 +53,9 @@ func AuthMiddleware(secretKey string) gin.HandlerFunc {
 
 		tokenString := tokenParts[1]
 
-		// Parse and validate token
-		token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-			return []byte(secretKey), nil
-		})
+		// Parse and validate token, only the signing methods of the user
+		// service keys are accepted
+		token, err := jwt.ParseWithClaims(tokenString, &claims.Claims{}, keyfunc, jwt.WithValidMethods(jwks.Algorithms))
 
 		if err != nil || !token.Valid {
 			logrus.Warnf("Invalid JWT token: %v", err)
@@ -58,7 +65,7 @@ func AuthMiddleware(secretKey string) gin.HandlerFunc {
 		}
 
 		// Extract claims
-		claims, ok := token.Claims.(*JWTClaims)
+		tokenClaims, ok := token.Claims.(*claims.Claims)
 		if !ok {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
 			c.Abort()
@@ -66,29 +73,42 @@ func AuthMiddleware(secretKey string) gin.HandlerFunc {
 		}
 
 		// Check token expiration
-		if claims.ExpiresAt != nil && claims.ExpiresAt.Time.Before(time.Now()) {
+		if tokenClaims.ExpiresAt != nil && tokenClaims.ExpiresAt.Time.Before(time.Now()) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token expired"})
 			c.Abort()
 			return
 		}
 
+		// Check session revocation
+		if revocations.IsRevoked(tokenClaims.SessionID.String()) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session revoked"})
+			c.Abort()
+			return
+		}
+
 		// Set user info in context
-		c.Set("user_id", claims.UserID)
-		c.Set("user_email", claims.Email)
-		c.Set("user_role", claims.Role)
+		c.Set("user_id", tokenClaims.UserID)
+		c.Set("user_email", tokenClaims.Email)
+		c.Set("user_role", tokenClaims.Role)
+		c.Set(IdentityKey, tokenClaims.Identity())
 
 		c.Next()
 	}
 }
 
-// shouldSkipAuth determines if authentication should be skipped for certain endpoints
-func shouldSkipAuth(path string) bool {
+// shouldSkipAuth determines if authentication should be skipped for certain
+// endpoints. Of the events, only the listing and the details of an event are
+// public; creating, changing and deleting events needs an identity.
+func shouldSkipAuth(method, path string) bool {
 	skipPaths := []string{
 		"/health",
 		"/metrics",
 		"/api/v1/users/register",
-		"/api/v1/users/login",
-		"/api/v1/events", // Public event listing
+		"/api/v1/users/login", // Includes the second step of MFA logins
+		"/api/v1/users/refresh",
+		"/api/v1/users/verify-email",
+		"/api/v1/users/password-reset",
+		"/api/v1/users/oidc",
 		"/api/v1/payments/webhook", // Payment webhooks
 	}
 
@@ -98,12 +118,15 @@ func shouldSkipAuth(path string) bool {
 		}
 	}
 
-	// Allow GET requests to events (public)
-	if strings.HasPrefix(path, "/api/v1/events") && strings.Contains(path, "GET") {
+	// Allow GET requests to the event listing and event details (public)
+	if method != http.MethodGet {
+		return false
+	}
+	if path == "/api/v1/events" {
 		return true
 	}
-
-	return false
+	eventID := strings.TrimPrefix(path, "/api/v1/events/")
+	return eventID != path && eventID != "" && !strings.Contains(eventID, "/")
 }
 
 // RoleMiddleware checks if user has required role
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShouldSkipAuth_OnlyPublicEventReads(t *testing.T) {
+	assert.True(t, shouldSkipAuth(http.MethodGet, "/api/v1/events"))
+	assert.True(t, shouldSkipAuth(http.MethodGet, "/api/v1/events/0b7e6f2c-5d1a-4c55-9a53-2f6c1f0e8a41"))
+	assert.True(t, shouldSkipAuth(http.MethodPost, "/api/v1/users/login"))
+
+	// Changing events and reading their private data needs an identity
+	assert.False(t, shouldSkipAuth(http.MethodPost, "/api/v1/events"))
+	assert.False(t, shouldSkipAuth(http.MethodPut, "/api/v1/events/0b7e6f2c-5d1a-4c55-9a53-2f6c1f0e8a41"))
+	assert.False(t, shouldSkipAuth(http.MethodDelete, "/api/v1/events/0b7e6f2c-5d1a-4c55-9a53-2f6c1f0e8a41"))
+	assert.False(t, shouldSkipAuth(http.MethodPost, "/api/v1/events/import"))
+	assert.False(t, shouldSkipAuth(http.MethodPut, "/api/v1/events/0b7e6f2c-5d1a-4c55-9a53-2f6c1f0e8a41/refund-policy"))
+	assert.False(t, shouldSkipAuth(http.MethodGet, "/api/v1/eventsx"))
+}
@@ -41,7 +41,7 @@ func (rl *RateLimiter) RateLimitMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		clientIP := c.ClientIP()
 
-		if !rl.allowRequest(clientIP) {
+		if !rl.allowRequest(clientIP, rl.rate) {
 			c.JSON(http.StatusTooManyRequests, gin.H{
 				"error": "Rate limit exceeded",
 				"retry_after": "60 seconds",
@@ -54,8 +54,17 @@ func (rl *RateLimiter) RateLimitMiddleware() gin.HandlerFunc {
 	}
 }
 
-// allowRequest checks if request is allowed for the client
-func (rl *RateLimiter) allowRequest(clientIP string) bool {
+// allowRequest checks if request is allowed for the client, which may make
+// limit requests per window
+func (rl *RateLimiter) allowRequest(clientIP string, limit int) bool {
+	allowed, _, _ := rl.take(clientIP, limit)
+	return allowed
+}
+
+// take records a request of the client if it is within limit requests per
+// window, and returns how many requests the client has left and when the
+// oldest request in the window expires
+func (rl *RateLimiter) take(clientIP string, limit int) (bool, int, time.Time) {
 	rl.mutex.Lock()
 	client, exists := rl.clients[clientIP]
 	if !exists {
@@ -82,13 +91,13 @@ func (rl *RateLimiter) allowRequest(clientIP string) bool {
 	client.requests = validRequests
 
 	// Check if rate limit is exceeded
-	if len(client.requests) >= rl.rate {
-		return false
+	if len(client.requests) >= limit {
+		return false, 0, client.requests[0].Add(rl.window)
 	}
 
 	// Add current request
 	client.requests = append(client.requests, now)
-	return true
+	return true, limit - len(client.requests), client.requests[0].Add(rl.window)
 }
 
 // cleanup removes old clients that haven't made requests recently
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RevocationList keeps the sessions revoked by the user service whose access
+// tokens have not expired yet, so that tokens can be checked without a call
+// to the user service on every request
+type RevocationList struct {
+	sessions map[string]time.Time // session ID to when its access tokens expire
+	mutex    sync.RWMutex
+	url      string
+	client   *http.Client
+}
+
+// revokedSessionsResponse is the response of the user service listing revoked sessions
+type revokedSessionsResponse struct {
+	Sessions []struct {
+		ID        string    `json:"id"`
+		ExpiresAt time.Time `json:"expires_at"`
+	} `json:"sessions"`
+}
+
+// NewRevocationList creates a revocation list that syncs with the user
+// service at userServiceURL every interval
+func NewRevocationList(userServiceURL string, interval time.Duration) *RevocationList {
+	rl := &RevocationList{
+		sessions: make(map[string]time.Time),
+		url:      userServiceURL + "/internal/sessions/revoked",
+		client: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+	}
+
+	if err := rl.sync(); err != nil {
+		logrus.Warnf("Failed to sync revoked sessions: %v", err)
+	}
+
+	// Sync revoked sessions periodically
+	go rl.syncLoop(interval)
+
+	return rl
+}
+
+// IsRevoked checks if a session has been revoked
+func (rl *RevocationList) IsRevoked(sessionID string) bool {
+	rl.mutex.RLock()
+	defer rl.mutex.RUnlock()
+
+	expiresAt, revoked := rl.sessions[sessionID]
+	return revoked && time.Now().Before(expiresAt)
+}
+
+// syncLoop syncs revoked sessions every interval
+func (rl *RevocationList) syncLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := rl.sync(); err != nil {
+			// Keep the sessions known so far, they stay revoked until they expire
+			logrus.Warnf("Failed to sync revoked sessions: %v", err)
+		}
+	}
+}
+
+// sync fetches the revoked sessions from the user service and drops the
+// sessions whose access tokens have expired
+func (rl *RevocationList) sync() error {
+	resp, err := rl.client.Get(rl.url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var body revokedSessionsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return err
+	}
+
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	for _, session := range body.Sessions {
+		rl.sessions[session.ID] = session.ExpiresAt
+	}
+
+	now := time.Now()
+	for sessionID, expiresAt := range rl.sessions {
+		if !now.Before(expiresAt) {
+			delete(rl.sessions, sessionID)
+		}
+	}
+
+	return nil
+}
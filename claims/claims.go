@@ -0,0 +1,60 @@
+package claims
+
+import (
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// APIKeyRole is the role of the access tokens issued for API keys
+const APIKeyRole = "api_key"
+
+// Claims are the claims of the access tokens signed by the user service
+type Claims struct {
+	UserID    uuid.UUID `json:"user_id"`
+	SessionID uuid.UUID `json:"sid"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+
+	// Verified is whether the user had verified their email address when
+	// the token was issued
+	Verified bool `json:"email_verified"`
+
+	// MFA is whether the user had proven a second factor in the session the
+	// token was issued for
+	MFA bool `json:"mfa"`
+
+	// OrganizationID and Scopes are only set for the tokens of API keys,
+	// whose UserID is the ID of the key
+	OrganizationID *uuid.UUID `json:"org_id,omitempty"`
+	Scopes         []string   `json:"scopes,omitempty"`
+
+	// Actor is only set for the tokens of an admin impersonating the user
+	Actor *Actor `json:"act,omitempty"`
+
+	jwt.RegisteredClaims
+}
+
+// Actor is the party acting on behalf of the subject of a token, as in the
+// act claim of RFC 8693
+type Actor struct {
+	Subject uuid.UUID `json:"sub"`
+}
+
+// Identity returns the identity the claims authenticate
+func (c *Claims) Identity() Identity {
+	identity := Identity{
+		UserID:         c.UserID,
+		SessionID:      c.SessionID,
+		Email:          c.Email,
+		Role:           c.Role,
+		Verified:       c.Verified,
+		MFA:            c.MFA,
+		OrganizationID: c.OrganizationID,
+		Scopes:         c.Scopes,
+	}
+	if c.Actor != nil {
+		impersonatorID := c.Actor.Subject
+		identity.ImpersonatorID = &impersonatorID
+	}
+	return identity
+}
@@ -0,0 +1,178 @@
+package claims
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Header is the request header the gateway forwards the signed identity of
+// the caller in
+const Header = "X-Identity"
+
+var (
+	// ErrMissingSecret is returned when no secret is configured to sign or
+	// verify identities with
+	ErrMissingSecret = errors.New("identity secret is not configured")
+
+	// ErrInvalidIdentity is returned for identity headers that are malformed
+	// or were not signed with the shared secret
+	ErrInvalidIdentity = errors.New("invalid identity header")
+
+	// ErrExpiredIdentity is returned for identity headers whose lifetime has
+	// passed
+	ErrExpiredIdentity = errors.New("identity header expired")
+)
+
+// Identity is the caller of a request, as authenticated by the gateway
+type Identity struct {
+	UserID    uuid.UUID `json:"user_id"`
+	SessionID uuid.UUID `json:"sid"`
+	Email     string    `json:"email,omitempty"`
+	Role      string    `json:"role"`
+	Verified  bool      `json:"email_verified"`
+	MFA       bool      `json:"mfa"`
+
+	// OrganizationID and Scopes are only set for API keys
+	OrganizationID *uuid.UUID `json:"org_id,omitempty"`
+	Scopes         []string   `json:"scopes,omitempty"`
+
+	// ImpersonatorID is only set when an admin impersonates the user
+	ImpersonatorID *uuid.UUID `json:"impersonator_id,omitempty"`
+
+	IssuedAt  int64 `json:"iat"`
+	ExpiresAt int64 `json:"exp"`
+}
+
+// IsAPIKey reports whether the caller is the API key of an organization
+func (i *Identity) IsAPIKey() bool {
+	return i.Role == APIKeyRole
+}
+
+// Config holds the settings of a Signer
+type Config struct {
+	// Secret is shared by the gateway and the services
+	Secret []byte
+
+	// TTL is how long a signed identity is accepted, which only has to
+	// cover the time the gateway takes to forward the request
+	TTL time.Duration
+}
+
+// ConfigFromEnv returns the configuration from the INTERNAL_IDENTITY_*
+// environment variables
+func ConfigFromEnv() Config {
+	config := Config{
+		Secret: []byte(os.Getenv("INTERNAL_IDENTITY_SECRET")),
+	}
+	if ttl, err := time.ParseDuration(os.Getenv("INTERNAL_IDENTITY_TTL")); err == nil {
+		config.TTL = ttl
+	}
+	return config.withDefaults()
+}
+
+// TrustedProxiesFromEnv returns the addresses of the API gateway in
+// TRUSTED_PROXIES, separated by commas. Services only take the address of
+// the client from the X-Forwarded-For header of requests sent by them, and
+// trust no proxy when it is not set.
+func TrustedProxiesFromEnv() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+// withDefaults fills in the unset settings
+func (c Config) withDefaults() Config {
+	if c.TTL <= 0 {
+		c.TTL = 30 * time.Second
+	}
+	return c
+}
+
+// Signer signs the identities the gateway forwards and verifies them in the
+// services. A signed identity is the base64url encoded JSON of the identity
+// and its HMAC-SHA256, joined by a dot.
+type Signer struct {
+	config Config
+}
+
+// NewSigner creates a signer with config
+func NewSigner(config Config) *Signer {
+	return &Signer{config: config.withDefaults()}
+}
+
+// Configured reports whether a secret is set
+func (s *Signer) Configured() bool {
+	return len(s.config.Secret) > 0
+}
+
+// Sign returns the signed header value for identity, valid for the TTL
+func (s *Signer) Sign(identity Identity) (string, error) {
+	if !s.Configured() {
+		return "", ErrMissingSecret
+	}
+
+	now := time.Now()
+	identity.IssuedAt = now.Unix()
+	identity.ExpiresAt = now.Add(s.config.TTL).Unix()
+
+	payload, err := json.Marshal(identity)
+	if err != nil {
+		return "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(s.mac(encoded)), nil
+}
+
+// Verify checks the signature and lifetime of a signed header value and
+// returns its identity
+func (s *Signer) Verify(value string) (*Identity, error) {
+	if !s.Configured() {
+		return nil, ErrMissingSecret
+	}
+
+	encoded, signature, ok := strings.Cut(value, ".")
+	if !ok {
+		return nil, ErrInvalidIdentity
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, s.mac(encoded)) {
+		return nil, ErrInvalidIdentity
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidIdentity
+	}
+
+	var identity Identity
+	if err := json.Unmarshal(payload, &identity); err != nil || identity.UserID == uuid.Nil {
+		return nil, ErrInvalidIdentity
+	}
+
+	if time.Now().Unix() > identity.ExpiresAt {
+		return nil, ErrExpiredIdentity
+	}
+
+	return &identity, nil
+}
+
+// mac returns the HMAC-SHA256 of an encoded identity
+func (s *Signer) mac(encoded string) []byte {
+	mac := hmac.New(sha256.New, s.config.Secret)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,124 @@
+package claims
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSigner(secret string) *Signer {
+	return NewSigner(Config{Secret: []byte(secret), TTL: time.Minute})
+}
+
+func TestSigner_SignAndVerify(t *testing.T) {
+	signer := newTestSigner("internal-secret")
+	organizationID := uuid.New()
+	identity := Identity{
+		UserID:         uuid.New(),
+		Role:           APIKeyRole,
+		OrganizationID: &organizationID,
+		Scopes:         []string{"events:read", "bookings:create"},
+	}
+
+	value, err := signer.Sign(identity)
+	require.NoError(t, err)
+
+	verified, err := signer.Verify(value)
+	require.NoError(t, err)
+	assert.Equal(t, identity.UserID, verified.UserID)
+	assert.True(t, verified.IsAPIKey())
+	assert.Equal(t, &organizationID, verified.OrganizationID)
+	assert.Equal(t, identity.Scopes, verified.Scopes)
+	assert.Greater(t, verified.ExpiresAt, verified.IssuedAt)
+}
+
+func TestSigner_RejectsForgedIdentity(t *testing.T) {
+	signer := newTestSigner("internal-secret")
+	value, err := signer.Sign(Identity{UserID: uuid.New(), Role: "user"})
+	require.NoError(t, err)
+
+	t.Run("other secret", func(t *testing.T) {
+		_, err := newTestSigner("other-secret").Verify(value)
+		assert.Equal(t, ErrInvalidIdentity, err)
+	})
+
+	t.Run("changed role", func(t *testing.T) {
+		encoded, signature, _ := strings.Cut(value, ".")
+		payload, err := base64.RawURLEncoding.DecodeString(encoded)
+		require.NoError(t, err)
+		forged := strings.Replace(string(payload), `"role":"user"`, `"role":"admin"`, 1)
+
+		_, err = signer.Verify(base64.RawURLEncoding.EncodeToString([]byte(forged)) + "." + signature)
+		assert.Equal(t, ErrInvalidIdentity, err)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		for _, value := range []string{"", "no-signature", "a.b.c", value + "x"} {
+			_, err := signer.Verify(value)
+			assert.Equal(t, ErrInvalidIdentity, err, value)
+		}
+	})
+}
+
+func TestSigner_RejectsExpiredIdentity(t *testing.T) {
+	// Identities signed a minute ago have expired
+	signer := newTestSigner("internal-secret")
+	signer.config.TTL = -time.Minute
+
+	value, err := signer.Sign(Identity{UserID: uuid.New(), Role: "user"})
+	require.NoError(t, err)
+
+	_, err = signer.Verify(value)
+	assert.Equal(t, ErrExpiredIdentity, err)
+}
+
+func TestSigner_RequiresSecret(t *testing.T) {
+	signer := NewSigner(Config{})
+	assert.False(t, signer.Configured())
+
+	_, err := signer.Sign(Identity{UserID: uuid.New()})
+	assert.Equal(t, ErrMissingSecret, err)
+
+	_, err = signer.Verify("a.b")
+	assert.Equal(t, ErrMissingSecret, err)
+}
+
+func TestTrustedProxiesFromEnv(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "")
+	assert.Empty(t, TrustedProxiesFromEnv())
+
+	t.Setenv("TRUSTED_PROXIES", "172.28.0.10, 10.0.0.0/8")
+	assert.Equal(t, []string{"172.28.0.10", "10.0.0.0/8"}, TrustedProxiesFromEnv())
+}
+
+func TestClaims_Identity(t *testing.T) {
+	userID, sessionID, adminID := uuid.New(), uuid.New(), uuid.New()
+	claims := Claims{
+		UserID:    userID,
+		SessionID: sessionID,
+		Email:     "budi@example.com",
+		Role:      "organizer",
+		Verified:  true,
+		MFA:       true,
+		Actor:     &Actor{Subject: adminID},
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject: userID.String(),
+		},
+	}
+
+	identity := claims.Identity()
+	assert.Equal(t, userID, identity.UserID)
+	assert.Equal(t, sessionID, identity.SessionID)
+	assert.Equal(t, "organizer", identity.Role)
+	assert.True(t, identity.Verified)
+	assert.True(t, identity.MFA)
+	assert.False(t, identity.IsAPIKey())
+	require.NotNil(t, identity.ImpersonatorID)
+	assert.Equal(t, adminID, *identity.ImpersonatorID)
+}
@@ -0,0 +1,131 @@
+package contracts
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Booking event types, published by the event ticket service on ticket_events
+const (
+	TypeBookingCreated          = "booking.created"
+	TypeBookingUpdated          = "booking.updated"
+	TypeBookingCancelled        = "booking.cancelled"
+	TypeBookingAmended          = "booking.amended"
+	TypeBookingConfirmed        = "booking.confirmed"
+	TypeBookingPaymentRequested = "booking.payment_requested"
+	TypeBookingRefundRequested  = "booking.refund_requested"
+)
+
+// BookingState is the state of a booking carried by the booking events
+type BookingState struct {
+	BookingID uuid.UUID `json:"booking_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	EventID   uuid.UUID `json:"event_id"`
+	Status    string    `json:"status"`
+}
+
+// BookingCreated is published when tickets are reserved for a new booking
+type BookingCreated struct {
+	BookingState
+}
+
+// EventType implements Event
+func (BookingCreated) EventType() string { return TypeBookingCreated }
+
+// EventVersion implements Event
+func (BookingCreated) EventVersion() int { return 1 }
+
+// BookingUpdated is published when the status of a booking changes
+type BookingUpdated struct {
+	BookingState
+}
+
+// EventType implements Event
+func (BookingUpdated) EventType() string { return TypeBookingUpdated }
+
+// EventVersion implements Event
+func (BookingUpdated) EventVersion() int { return 1 }
+
+// BookingCancelled is published when a booking is cancelled, with the refund
+// allowed by the refund policy of its event
+type BookingCancelled struct {
+	BookingState
+	RefundAmount     float64 `json:"refund_amount"`
+	RefundPercentage float64 `json:"refund_percentage"`
+	NonRefundableFee float64 `json:"non_refundable_fee"`
+	RefundReason     string  `json:"refund_reason"`
+}
+
+// EventType implements Event
+func (BookingCancelled) EventType() string { return TypeBookingCancelled }
+
+// EventVersion implements Event
+func (BookingCancelled) EventVersion() int { return 1 }
+
+// BookingAmended is published when the tickets of a booking change, with the
+// payment action that settles the price difference
+type BookingAmended struct {
+	BookingState
+	AmendmentID      uuid.UUID `json:"amendment_id"`
+	AmendmentStatus  string    `json:"amendment_status"`
+	PaymentAction    string    `json:"payment_action"`
+	PreviousTotal    float64   `json:"previous_total"`
+	NewTotal         float64   `json:"new_total"`
+	AmountDifference float64   `json:"amount_difference"`
+}
+
+// EventType implements Event
+func (BookingAmended) EventType() string { return TypeBookingAmended }
+
+// EventVersion implements Event
+func (BookingAmended) EventVersion() int { return 1 }
+
+// BookingConfirmed is published by the booking saga once a booking is paid
+// and confirmed
+type BookingConfirmed struct {
+	BookingID      uuid.UUID `json:"booking_id"`
+	UserID         uuid.UUID `json:"user_id"`
+	EventID        uuid.UUID `json:"event_id"`
+	PaymentID      uuid.UUID `json:"payment_id"`
+	TotalPrice     float64   `json:"total_price"`
+	TicketCount    int       `json:"ticket_count"`
+	EventName      string    `json:"event_name"`
+	EventStartDate time.Time `json:"event_start_date"`
+	EventLocation  string    `json:"event_location"`
+}
+
+// EventType implements Event
+func (BookingConfirmed) EventType() string { return TypeBookingConfirmed }
+
+// EventVersion implements Event
+func (BookingConfirmed) EventVersion() int { return 1 }
+
+// BookingPaymentRequested asks the payment service to create the payment of
+// a new booking
+type BookingPaymentRequested struct {
+	BookingID uuid.UUID `json:"booking_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Amount    float64   `json:"amount"`
+}
+
+// EventType implements Event
+func (BookingPaymentRequested) EventType() string { return TypeBookingPaymentRequested }
+
+// EventVersion implements Event
+func (BookingPaymentRequested) EventVersion() int { return 1 }
+
+// BookingRefundRequested asks the payment service to refund a payment the
+// booking saga captured for a booking it gave up on
+type BookingRefundRequested struct {
+	BookingID    uuid.UUID `json:"booking_id"`
+	PaymentID    uuid.UUID `json:"payment_id"`
+	RefundAmount float64   `json:"refund_amount"`
+	RefundReason string    `json:"refund_reason"`
+}
+
+// EventType implements Event
+func (BookingRefundRequested) EventType() string { return TypeBookingRefundRequested }
+
+// EventVersion implements Event
+func (BookingRefundRequested) EventVersion() int { return 1 }
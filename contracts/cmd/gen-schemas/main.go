@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/yourusername/ticket-system/contracts"
+)
+
+// gen-schemas writes the JSON Schema of every event of the contracts to a
+// directory, one file per event type and version.
+//
+// Usage:
+//
+//	go run ./cmd/gen-schemas [-dir schemas]
+func main() {
+	dir := flag.String("dir", "schemas", "directory to write the schemas to")
+	flag.Parse()
+
+	if err := os.MkdirAll(*dir, 0o755); err != nil {
+		log.Fatalf("Failed to create schema directory: %v", err)
+	}
+
+	for _, event := range contracts.All() {
+		schema, err := json.MarshalIndent(contracts.Schema(event), "", "  ")
+		if err != nil {
+			log.Fatalf("Failed to marshal schema of %s: %v", event.EventType(), err)
+		}
+
+		path := filepath.Join(*dir, contracts.SchemaName(event))
+		if err := os.WriteFile(path, append(schema, '\n'), 0o644); err != nil {
+			log.Fatalf("Failed to write %s: %v", path, err)
+		}
+		log.Printf("Wrote %s", path)
+	}
+}
@@ -0,0 +1,146 @@
+package contracts
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The committed schemas are what consumers are built against. A producer
+// that changes an event without regenerating them fails here.
+func TestSchemasAreUpToDate(t *testing.T) {
+	for _, event := range All() {
+		t.Run(SchemaName(event), func(t *testing.T) {
+			generated, err := json.MarshalIndent(Schema(event), "", "  ")
+			require.NoError(t, err)
+
+			committed, err := os.ReadFile(filepath.Join("schemas", SchemaName(event)))
+			require.NoError(t, err, "schema missing, run go run ./cmd/gen-schemas")
+
+			assert.Equal(t, string(committed), string(generated)+"\n",
+				"schema is out of date, run go run ./cmd/gen-schemas and bump the event version if the change is breaking")
+		})
+	}
+}
+
+// The fixtures are the messages consumers expect. A schema regenerated for
+// a renamed, removed or retyped field no longer accepts them, which means
+// the event needs a new version.
+func TestFixturesMatchContracts(t *testing.T) {
+	for _, event := range All() {
+		t.Run(SchemaName(event), func(t *testing.T) {
+			body, err := os.ReadFile(filepath.Join("testdata", SchemaName(event)))
+			require.NoError(t, err, "every event needs a fixture")
+
+			require.NoError(t, Validate(body))
+
+			// Decoding and encoding the data again must not lose or add fields
+			decoded, err := Lookup(event.EventType(), event.EventVersion())
+			require.NoError(t, err)
+			envelope, err := Unmarshal(body, decoded)
+			require.NoError(t, err)
+
+			encoded, err := json.Marshal(decoded)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(envelope.Data), string(encoded))
+		})
+	}
+}
+
+func TestEnvelope_RoundTrip(t *testing.T) {
+	event := BookingPaymentRequested{
+		BookingID: uuid.New(),
+		UserID:    uuid.New(),
+		Amount:    150,
+	}
+
+	body, err := Marshal("message-1", event.BookingID.String(), event)
+	require.NoError(t, err)
+	require.NoError(t, Validate(body))
+
+	var decoded BookingPaymentRequested
+	envelope, err := Unmarshal(body, &decoded)
+	require.NoError(t, err)
+
+	assert.Equal(t, "message-1", envelope.ID)
+	assert.Equal(t, TypeBookingPaymentRequested, envelope.Type)
+	assert.Equal(t, 1, envelope.Version)
+	assert.Equal(t, event.BookingID.String(), envelope.CorrelationID)
+	assert.False(t, envelope.Timestamp.IsZero())
+	assert.Equal(t, event, decoded)
+}
+
+func TestEnvelope_DecodeRejectsOtherTypeAndVersion(t *testing.T) {
+	body, err := Marshal("message-1", "", PaymentFailed{PaymentState{PaymentID: uuid.New()}})
+	require.NoError(t, err)
+
+	envelope, err := Parse(body)
+	require.NoError(t, err)
+
+	err = envelope.Decode(&PaymentCompleted{})
+	assert.EqualError(t, err, "unexpected event type payment.failed, want payment.completed")
+
+	envelope.Version = 2
+	err = envelope.Decode(&PaymentFailed{})
+	assert.EqualError(t, err, "unsupported payment.failed event version 2, want 1")
+}
+
+func TestParse_RejectsMessagesWithoutEnvelope(t *testing.T) {
+	// The flat payloads published before the contracts have no type or version
+	_, err := Parse([]byte(`{"event_type": "booking.created", "booking_id": "7d3f1c2a-5b6e-4a8f-9c0d-1e2f3a4b5c6d"}`))
+	assert.EqualError(t, err, "invalid event envelope: missing type")
+}
+
+func TestValidate_ReportsViolations(t *testing.T) {
+	body, err := os.ReadFile(filepath.Join("testdata", "payment.completed.v1.json"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		modify func(data map[string]interface{})
+		err    string
+	}{
+		{
+			name:   "missing field",
+			modify: func(data map[string]interface{}) { delete(data, "booking_id") },
+			err:    "message.data.booking_id: is required",
+		},
+		{
+			name:   "unknown field",
+			modify: func(data map[string]interface{}) { data["email"] = "budi@example.com" },
+			err:    "message.data.email: is not allowed",
+		},
+		{
+			name:   "wrong type",
+			modify: func(data map[string]interface{}) { data["amount"] = "150" },
+			err:    "message.data.amount: must be a number",
+		},
+		{
+			name:   "invalid uuid",
+			modify: func(data map[string]interface{}) { data["payment_id"] = "42" },
+			err:    "message.data.payment_id: must be a uuid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var message map[string]interface{}
+			require.NoError(t, json.Unmarshal(body, &message))
+			tt.modify(message["data"].(map[string]interface{}))
+
+			modified, err := json.Marshal(message)
+			require.NoError(t, err)
+			assert.EqualError(t, Validate(modified), tt.err)
+		})
+	}
+}
+
+func TestLookup_UnknownEvent(t *testing.T) {
+	_, err := Lookup("booking.created", 99)
+	assert.EqualError(t, err, "unknown event booking.created version 99")
+}
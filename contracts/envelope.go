@@ -0,0 +1,105 @@
+// Package contracts defines the events the services of the ticket system
+// publish on RabbitMQ. Every event is sent in an Envelope that names its type
+// and version, and its data is one of the typed events of this package.
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Event is the data of a versioned event
+type Event interface {
+	// EventType returns the type of the event, which is also its routing key
+	EventType() string
+	// EventVersion returns the version of the data of the event. It changes
+	// whenever a field is removed, renamed or changes meaning.
+	EventVersion() int
+}
+
+// Envelope wraps the data of an event with its metadata
+type Envelope struct {
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`
+	Version       int             `json:"version"`
+	Timestamp     time.Time       `json:"timestamp"`
+	CorrelationID string          `json:"correlation_id,omitempty"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// NewEnvelope wraps event in an envelope with the given message ID. The
+// correlation ID ties together the events of one flow, such as a booking and
+// its payments.
+func NewEnvelope(id, correlationID string, event Event) (*Envelope, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s event: %w", event.EventType(), err)
+	}
+
+	return &Envelope{
+		ID:            id,
+		Type:          event.EventType(),
+		Version:       event.EventVersion(),
+		Timestamp:     time.Now().UTC(),
+		CorrelationID: correlationID,
+		Data:          data,
+	}, nil
+}
+
+// Marshal wraps event in an envelope and encodes it to JSON
+func Marshal(id, correlationID string, event Event) ([]byte, error) {
+	envelope, err := NewEnvelope(id, correlationID, event)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(envelope)
+}
+
+// Parse decodes the envelope of a message without decoding its data
+func Parse(body []byte) (*Envelope, error) {
+	var envelope Envelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to parse event envelope: %w", err)
+	}
+
+	if envelope.Type == "" {
+		return nil, fmt.Errorf("invalid event envelope: missing type")
+	}
+
+	if envelope.Version < 1 {
+		return nil, fmt.Errorf("invalid event envelope: missing version")
+	}
+
+	return &envelope, nil
+}
+
+// Decode decodes the data of the envelope into event, which must have the
+// type and version of the envelope
+func (e *Envelope) Decode(event Event) error {
+	if e.Type != event.EventType() {
+		return fmt.Errorf("unexpected event type %s, want %s", e.Type, event.EventType())
+	}
+
+	if e.Version != event.EventVersion() {
+		return fmt.Errorf("unsupported %s event version %d, want %d", e.Type, e.Version, event.EventVersion())
+	}
+
+	if err := json.Unmarshal(e.Data, event); err != nil {
+		return fmt.Errorf("failed to parse %s event: %w", e.Type, err)
+	}
+	return nil
+}
+
+// Unmarshal decodes a message into its envelope and the data into event
+func Unmarshal(body []byte, event Event) (*Envelope, error) {
+	envelope, err := Parse(body)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := envelope.Decode(event); err != nil {
+		return nil, err
+	}
+	return envelope, nil
+}
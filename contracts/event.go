@@ -0,0 +1,49 @@
+package contracts
+
+import "github.com/google/uuid"
+
+// Event event types, published by the event ticket service on ticket_events
+const (
+	TypeEventCreated = "event.created"
+	TypeEventUpdated = "event.updated"
+	TypeEventDeleted = "event.deleted"
+)
+
+// EventState is the state of an event carried by the event events
+type EventState struct {
+	EventID uuid.UUID `json:"event_id"`
+	Name    string    `json:"name"`
+}
+
+// EventCreated is published when an event is created
+type EventCreated struct {
+	EventState
+}
+
+// EventType implements Event
+func (EventCreated) EventType() string { return TypeEventCreated }
+
+// EventVersion implements Event
+func (EventCreated) EventVersion() int { return 1 }
+
+// EventUpdated is published when an event changes
+type EventUpdated struct {
+	EventState
+}
+
+// EventType implements Event
+func (EventUpdated) EventType() string { return TypeEventUpdated }
+
+// EventVersion implements Event
+func (EventUpdated) EventVersion() int { return 1 }
+
+// EventDeleted is published when an event is deleted
+type EventDeleted struct {
+	EventState
+}
+
+// EventType implements Event
+func (EventDeleted) EventType() string { return TypeEventDeleted }
+
+// EventVersion implements Event
+func (EventDeleted) EventVersion() int { return 1 }
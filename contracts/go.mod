@@ -0,0 +1,14 @@
+module github.com/yourusername/ticket-system/contracts
+
+go 1.19
+
+require (
+	github.com/google/uuid v1.3.1
+	github.com/stretchr/testify v1.8.4
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
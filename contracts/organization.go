@@ -0,0 +1,73 @@
+package contracts
+
+import "github.com/google/uuid"
+
+// Organization event types, published by the user service on user_events
+const (
+	TypeOrganizationMemberAdded       = "organization.member_added"
+	TypeOrganizationMemberRoleChanged = "organization.member_role_changed"
+	TypeOrganizationMemberRemoved     = "organization.member_removed"
+)
+
+// Roles of the members of an organization
+const (
+	OrganizationRoleOwner     = "owner"
+	OrganizationRoleManager   = "manager"
+	OrganizationRoleBoxOffice = "box_office"
+	OrganizationRoleScanner   = "scanner"
+)
+
+// OrganizationRoles returns every role a member of an organization can have
+func OrganizationRoles() []string {
+	return []string{
+		OrganizationRoleOwner,
+		OrganizationRoleManager,
+		OrganizationRoleBoxOffice,
+		OrganizationRoleScanner,
+	}
+}
+
+// OrganizationMemberState is the membership of a user in an organization
+// carried by the organization events
+type OrganizationMemberState struct {
+	OrganizationID uuid.UUID `json:"organization_id"`
+	UserID         uuid.UUID `json:"user_id"`
+	Role           string    `json:"role"`
+}
+
+// OrganizationMemberAdded is published when a user joins an organization,
+// including the owner who creates it
+type OrganizationMemberAdded struct {
+	OrganizationMemberState
+}
+
+// EventType implements Event
+func (OrganizationMemberAdded) EventType() string { return TypeOrganizationMemberAdded }
+
+// EventVersion implements Event
+func (OrganizationMemberAdded) EventVersion() int { return 1 }
+
+// OrganizationMemberRoleChanged is published when a member of an
+// organization is given another role
+type OrganizationMemberRoleChanged struct {
+	OrganizationMemberState
+}
+
+// EventType implements Event
+func (OrganizationMemberRoleChanged) EventType() string { return TypeOrganizationMemberRoleChanged }
+
+// EventVersion implements Event
+func (OrganizationMemberRoleChanged) EventVersion() int { return 1 }
+
+// OrganizationMemberRemoved is published when a user leaves or is removed
+// from an organization
+type OrganizationMemberRemoved struct {
+	OrganizationID uuid.UUID `json:"organization_id"`
+	UserID         uuid.UUID `json:"user_id"`
+}
+
+// EventType implements Event
+func (OrganizationMemberRemoved) EventType() string { return TypeOrganizationMemberRemoved }
+
+// EventVersion implements Event
+func (OrganizationMemberRemoved) EventVersion() int { return 1 }
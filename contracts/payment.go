@@ -0,0 +1,108 @@
+package contracts
+
+import "github.com/google/uuid"
+
+// Payment event types, published by the payment service on payment_events
+const (
+	TypePaymentCreated           = "payment.created"
+	TypePaymentUpdated           = "payment.updated"
+	TypePaymentCompleted         = "payment.completed"
+	TypePaymentFailed            = "payment.failed"
+	TypePaymentRefunded          = "payment.refunded"
+	TypePaymentPartiallyRefunded = "payment.partially_refunded"
+)
+
+// PaymentState is the state of a payment carried by the payment events.
+// Supplementary payments of a booking amendment have an amendment ID.
+type PaymentState struct {
+	PaymentID     uuid.UUID  `json:"payment_id"`
+	UserID        uuid.UUID  `json:"user_id"`
+	BookingID     uuid.UUID  `json:"booking_id"`
+	AmendmentID   *uuid.UUID `json:"amendment_id,omitempty"`
+	Amount        float64    `json:"amount"`
+	Currency      string     `json:"currency"`
+	Status        string     `json:"status"`
+	PaymentMethod string     `json:"payment_method"`
+}
+
+// PaymentCreated is published when a payment waiting to be processed is created
+type PaymentCreated struct {
+	PaymentState
+}
+
+// EventType implements Event
+func (PaymentCreated) EventType() string { return TypePaymentCreated }
+
+// EventVersion implements Event
+func (PaymentCreated) EventVersion() int { return 1 }
+
+// PaymentUpdated is published when the amount or status of a payment changes
+type PaymentUpdated struct {
+	PaymentState
+}
+
+// EventType implements Event
+func (PaymentUpdated) EventType() string { return TypePaymentUpdated }
+
+// EventVersion implements Event
+func (PaymentUpdated) EventVersion() int { return 1 }
+
+// PaymentCompleted is published when a payment is captured
+type PaymentCompleted struct {
+	PaymentState
+}
+
+// EventType implements Event
+func (PaymentCompleted) EventType() string { return TypePaymentCompleted }
+
+// EventVersion implements Event
+func (PaymentCompleted) EventVersion() int { return 1 }
+
+// PaymentFailed is published when the payment provider declines a payment
+type PaymentFailed struct {
+	PaymentState
+}
+
+// EventType implements Event
+func (PaymentFailed) EventType() string { return TypePaymentFailed }
+
+// EventVersion implements Event
+func (PaymentFailed) EventVersion() int { return 1 }
+
+// RefundState is a refund of a payment with the state of the payment after it
+type RefundState struct {
+	PaymentID      uuid.UUID  `json:"payment_id"`
+	RefundID       uuid.UUID  `json:"refund_id"`
+	UserID         uuid.UUID  `json:"user_id"`
+	BookingID      uuid.UUID  `json:"booking_id"`
+	AmendmentID    *uuid.UUID `json:"amendment_id,omitempty"`
+	Amount         float64    `json:"amount"`
+	RefundAmount   float64    `json:"refund_amount"`
+	RefundedAmount float64    `json:"refunded_amount"`
+	Currency       string     `json:"currency"`
+	Status         string     `json:"status"`
+	Reason         string     `json:"reason"`
+}
+
+// PaymentRefunded is published when the whole amount of a payment is refunded
+type PaymentRefunded struct {
+	RefundState
+}
+
+// EventType implements Event
+func (PaymentRefunded) EventType() string { return TypePaymentRefunded }
+
+// EventVersion implements Event
+func (PaymentRefunded) EventVersion() int { return 1 }
+
+// PaymentPartiallyRefunded is published when part of a payment is refunded,
+// and for every refund of the supplementary payment of an amendment
+type PaymentPartiallyRefunded struct {
+	RefundState
+}
+
+// EventType implements Event
+func (PaymentPartiallyRefunded) EventType() string { return TypePaymentPartiallyRefunded }
+
+// EventVersion implements Event
+func (PaymentPartiallyRefunded) EventVersion() int { return 1 }
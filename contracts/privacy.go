@@ -0,0 +1,72 @@
+package contracts
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+// Privacy event types. The user service publishes data requests on
+// user_events, and every service holding personal data answers them on
+// privacy_events.
+const (
+	TypeUserDataRequested    = "user.data_requested"
+	TypePrivacyDataCollected = "privacy.data_collected"
+	TypePrivacyDataErased    = "privacy.data_erased"
+)
+
+// Modes of a data request
+const (
+	// DataRequestExport collects the personal data of a user
+	DataRequestExport = "export"
+	// DataRequestErasure anonymises the personal data of a user, keeping the
+	// records required for accounting
+	DataRequestErasure = "erasure"
+)
+
+// UserDataRequested is published when an admin starts a data export or
+// erasure of a user. Every service holding personal data answers with
+// PrivacyDataCollected or PrivacyDataErased.
+type UserDataRequested struct {
+	RequestID uuid.UUID `json:"request_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Mode      string    `json:"mode"`
+}
+
+// EventType implements Event
+func (UserDataRequested) EventType() string { return TypeUserDataRequested }
+
+// EventVersion implements Event
+func (UserDataRequested) EventVersion() int { return 1 }
+
+// PrivacyDataCollected is published by a service with the personal data it
+// holds about the user of an export
+type PrivacyDataCollected struct {
+	RequestID uuid.UUID       `json:"request_id"`
+	UserID    uuid.UUID       `json:"user_id"`
+	Service   string          `json:"service"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// EventType implements Event
+func (PrivacyDataCollected) EventType() string { return TypePrivacyDataCollected }
+
+// EventVersion implements Event
+func (PrivacyDataCollected) EventVersion() int { return 1 }
+
+// PrivacyDataErased is published by a service once it has erased the
+// personal data of the user of an erasure. Erased counts the records that
+// were deleted or anonymised, Retained the records kept for accounting.
+type PrivacyDataErased struct {
+	RequestID uuid.UUID `json:"request_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Service   string    `json:"service"`
+	Erased    int       `json:"erased"`
+	Retained  int       `json:"retained"`
+}
+
+// EventType implements Event
+func (PrivacyDataErased) EventType() string { return TypePrivacyDataErased }
+
+// EventVersion implements Event
+func (PrivacyDataErased) EventVersion() int { return 1 }
@@ -0,0 +1,59 @@
+package contracts
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// All returns every event of the contracts, in the order of their schemas
+func All() []Event {
+	return []Event{
+		&BookingCreated{},
+		&BookingUpdated{},
+		&BookingCancelled{},
+		&BookingAmended{},
+		&BookingConfirmed{},
+		&BookingPaymentRequested{},
+		&BookingRefundRequested{},
+		&PaymentCreated{},
+		&PaymentUpdated{},
+		&PaymentCompleted{},
+		&PaymentFailed{},
+		&PaymentRefunded{},
+		&PaymentPartiallyRefunded{},
+		&UserCreated{},
+		&UserUpdated{},
+		&UserLogin{},
+		&UserPasswordChanged{},
+		&UserSuspended{},
+		&UserDeleted{},
+		&UserLocked{},
+		&UserVerificationRequested{},
+		&UserPasswordResetRequested{},
+		&UserInvited{},
+		&UserDataRequested{},
+		&OrganizationMemberAdded{},
+		&OrganizationMemberRoleChanged{},
+		&OrganizationMemberRemoved{},
+		&EventCreated{},
+		&EventUpdated{},
+		&EventDeleted{},
+		&PrivacyDataCollected{},
+		&PrivacyDataErased{},
+	}
+}
+
+// Lookup returns a new, empty event of the given type and version
+func Lookup(eventType string, version int) (Event, error) {
+	for _, event := range All() {
+		if event.EventType() == eventType && event.EventVersion() == version {
+			return reflect.New(reflect.TypeOf(event).Elem()).Interface().(Event), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown event %s version %d", eventType, version)
+}
+
+// SchemaName returns the file name of the JSON Schema of an event
+func SchemaName(event Event) string {
+	return fmt.Sprintf("%s.v%d.json", event.EventType(), event.EventVersion())
+}
@@ -0,0 +1,119 @@
+package contracts
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// schemaDialect is the JSON Schema dialect of the generated schemas
+const schemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+var (
+	uuidType       = reflect.TypeOf(uuid.UUID{})
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// Schema generates the JSON Schema of a message carrying event, envelope
+// included. Fields are required unless they are pointers or omitempty, and
+// unknown fields are rejected so that renamed fields are caught.
+func Schema(event Event) map[string]interface{} {
+	data := typeSchema(reflect.TypeOf(event))
+
+	return map[string]interface{}{
+		"$schema": schemaDialect,
+		"$id":     SchemaName(event),
+		"title":   event.EventType(),
+		"type":    "object",
+		"properties": map[string]interface{}{
+			"id":             map[string]interface{}{"type": "string"},
+			"type":           map[string]interface{}{"const": event.EventType()},
+			"version":        map[string]interface{}{"const": event.EventVersion()},
+			"timestamp":      map[string]interface{}{"type": "string", "format": "date-time"},
+			"correlation_id": map[string]interface{}{"type": "string"},
+			"data":           data,
+		},
+		"required":             []string{"id", "type", "version", "timestamp", "data"},
+		"additionalProperties": false,
+	}
+}
+
+// typeSchema generates the schema of a Go type
+func typeSchema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case uuidType:
+		return map[string]interface{}{"type": "string", "format": "uuid"}
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case rawMessageType:
+		// Raw messages carry documents of their own, which are only
+		// required to be objects
+		return map[string]interface{}{"type": "object"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+		required := []string{}
+		addFields(t, properties, &required)
+		return map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             required,
+			"additionalProperties": false,
+		}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// addFields adds the JSON fields of a struct to properties, flattening
+// embedded structs as encoding/json does
+func addFields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			addFields(field.Type, properties, required)
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = typeSchema(field.Type)
+		if field.Type.Kind() != reflect.Ptr && !strings.Contains(options, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package contracts
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// User event types, published by the user service on user_events
+const (
+	TypeUserCreated         = "user.created"
+	TypeUserUpdated         = "user.updated"
+	TypeUserLogin           = "user.login"
+	TypeUserPasswordChanged = "user.password_changed"
+	TypeUserSuspended       = "user.suspended"
+	TypeUserDeleted         = "user.deleted"
+	TypeUserLocked          = "user.locked"
+)
+
+// Account email event types, published by the user service on account_emails.
+// Their links carry single use tokens, so only the notification service
+// consumes the exchange and the events are never written to an outbox.
+const (
+	TypeUserVerificationRequested  = "user.verification_requested"
+	TypeUserPasswordResetRequested = "user.password_reset_requested"
+	TypeUserInvited                = "user.invited"
+)
+
+// UserState is the state of a user carried by the user events
+type UserState struct {
+	UserID uuid.UUID `json:"user_id"`
+	Email  string    `json:"email"`
+}
+
+// UserCreated is published when a user registers
+type UserCreated struct {
+	UserState
+}
+
+// EventType implements Event
+func (UserCreated) EventType() string { return TypeUserCreated }
+
+// EventVersion implements Event
+func (UserCreated) EventVersion() int { return 1 }
+
+// UserUpdated is published when the profile of a user changes
+type UserUpdated struct {
+	UserState
+}
+
+// EventType implements Event
+func (UserUpdated) EventType() string { return TypeUserUpdated }
+
+// EventVersion implements Event
+func (UserUpdated) EventVersion() int { return 1 }
+
+// UserLogin is published when a user logs in
+type UserLogin struct {
+	UserState
+}
+
+// EventType implements Event
+func (UserLogin) EventType() string { return TypeUserLogin }
+
+// EventVersion implements Event
+func (UserLogin) EventVersion() int { return 1 }
+
+// UserPasswordChanged is published when a user changes their password
+type UserPasswordChanged struct {
+	UserState
+}
+
+// EventType implements Event
+func (UserPasswordChanged) EventType() string { return TypeUserPasswordChanged }
+
+// EventVersion implements Event
+func (UserPasswordChanged) EventVersion() int { return 1 }
+
+// UserSuspended is published when a user is suspended
+type UserSuspended struct {
+	UserID uuid.UUID `json:"user_id"`
+}
+
+// EventType implements Event
+func (UserSuspended) EventType() string { return TypeUserSuspended }
+
+// EventVersion implements Event
+func (UserSuspended) EventVersion() int { return 1 }
+
+// UserDeleted is published when a user is deleted
+type UserDeleted struct {
+	UserID uuid.UUID `json:"user_id"`
+}
+
+// EventType implements Event
+func (UserDeleted) EventType() string { return TypeUserDeleted }
+
+// EventVersion implements Event
+func (UserDeleted) EventVersion() int { return 1 }
+
+// UserVerificationRequested is published when a user needs to verify their
+// email address, after registering or on request. The URL carries a single
+// use token, so the event is only published on account_emails.
+type UserVerificationRequested struct {
+	UserState
+	VerificationURL string    `json:"verification_url"`
+	ExpiresAt       time.Time `json:"expires_at"`
+}
+
+// EventType implements Event
+func (UserVerificationRequested) EventType() string { return TypeUserVerificationRequested }
+
+// EventVersion implements Event
+func (UserVerificationRequested) EventVersion() int { return 1 }
+
+// UserPasswordResetRequested is published when a user asks to reset their
+// password. The URL carries a single use token, so the event is only
+// published on account_emails.
+type UserPasswordResetRequested struct {
+	UserState
+	ResetURL  string    `json:"reset_url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// EventType implements Event
+func (UserPasswordResetRequested) EventType() string { return TypeUserPasswordResetRequested }
+
+// EventVersion implements Event
+func (UserPasswordResetRequested) EventVersion() int { return 1 }
+
+// UserLocked is published when logins to the account of a user are locked
+// after too many failed attempts, so that the user can be alerted
+type UserLocked struct {
+	UserState
+	FailedAttempts int       `json:"failed_attempts"`
+	IPAddress      string    `json:"ip_address"`
+	LockedUntil    time.Time `json:"locked_until"`
+}
+
+// EventType implements Event
+func (UserLocked) EventType() string { return TypeUserLocked }
+
+// EventVersion implements Event
+func (UserLocked) EventVersion() int { return 1 }
+
+// UserInvited is published when an admin creates an account for a staff
+// member, who sets their password through the URL. The URL carries a single
+// use token, so the event is only published on account_emails.
+type UserInvited struct {
+	UserState
+	Role      string    `json:"role"`
+	SetupURL  string    `json:"setup_url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// EventType implements Event
+func (UserInvited) EventType() string { return TypeUserInvited }
+
+// EventVersion implements Event
+func (UserInvited) EventVersion() int { return 1 }
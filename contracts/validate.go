@@ -0,0 +1,143 @@
+package contracts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Validate checks a message against the schema of its event type and
+// version. It supports the subset of JSON Schema that Schema generates.
+func Validate(body []byte) error {
+	envelope, err := Parse(body)
+	if err != nil {
+		return err
+	}
+
+	event, err := Lookup(envelope.Type, envelope.Version)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var message interface{}
+	if err := decoder.Decode(&message); err != nil {
+		return fmt.Errorf("failed to parse message: %w", err)
+	}
+
+	return validateValue(Schema(event), message, "message")
+}
+
+// validateValue checks value against schema and returns the first violation
+func validateValue(schema map[string]interface{}, value interface{}, path string) error {
+	if expected, ok := schema["const"]; ok {
+		if fmt.Sprint(expected) != fmt.Sprint(value) {
+			return fmt.Errorf("%s: must be %v", path, expected)
+		}
+	}
+
+	switch schema["type"] {
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: must be a string", path)
+		}
+		return validateFormat(schema, s, path)
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: must be a boolean", path)
+		}
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok || strings.ContainsAny(n.String(), ".eE") {
+			return fmt.Errorf("%s: must be an integer", path)
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			return fmt.Errorf("%s: must be a number", path)
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: must be an array", path)
+		}
+		itemSchema, _ := schema["items"].(map[string]interface{})
+		for i, item := range items {
+			if err := validateValue(itemSchema, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: must be an object", path)
+		}
+		return validateObject(schema, object, path)
+	}
+
+	return nil
+}
+
+// validateObject checks the required, known and additional fields of an object
+func validateObject(schema map[string]interface{}, object map[string]interface{}, path string) error {
+	required, _ := schema["required"].([]string)
+	for _, name := range required {
+		if _, ok := object[name]; !ok {
+			return fmt.Errorf("%s.%s: is required", path, name)
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	// Check fields in a stable order so that the same violation is reported
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fieldPath := path + "." + name
+		if property, ok := properties[name].(map[string]interface{}); ok {
+			if err := validateValue(property, object[name], fieldPath); err != nil {
+				return err
+			}
+			continue
+		}
+
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				return fmt.Errorf("%s: is not allowed", fieldPath)
+			}
+		case map[string]interface{}:
+			if err := validateValue(additional, object[name], fieldPath); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateFormat checks the format of a string
+func validateFormat(schema map[string]interface{}, value, path string) error {
+	switch schema["format"] {
+	case "uuid":
+		if _, err := uuid.Parse(value); err != nil {
+			return fmt.Errorf("%s: must be a uuid", path)
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			return fmt.Errorf("%s: must be an RFC 3339 date-time", path)
+		}
+	}
+	return nil
+}
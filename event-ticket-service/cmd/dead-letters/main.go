@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/joho/godotenv"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/event-ticket-service/service"
+	"github.com/yourusername/ticket-system/messaging"
+)
+
+// dead-letters inspects, edits and replays the dead-lettered messages of a
+// consumer queue using the same RabbitMQ settings as the service.
+//
+// Usage:
+//
+//	dead-letters -queue notification_payment_events [-limit 50]
+//	dead-letters -queue notification_payment_events -replay <message-id> [-body-file body.json]
+//	dead-letters -queue notification_payment_events -discard <message-id>
+func main() {
+	queueName := flag.String("queue", "", "consumer queue whose dead letters to manage")
+	limit := flag.Int("limit", 50, "maximum number of dead letters to list")
+	replayID := flag.String("replay", "", "ID of the message to replay")
+	bodyFile := flag.String("body-file", "", "file with the edited JSON body to replay the message with")
+	discardID := flag.String("discard", "", "ID of the message to discard")
+	flag.Parse()
+
+	if *queueName == "" || (*replayID != "" && *discardID != "") {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Load environment variables
+	if err := godotenv.Load(); err != nil {
+		logrus.Warn("Error loading .env file, using environment variables")
+	}
+
+	// Connect to RabbitMQ
+	broker, err := messaging.NewRabbitMQ(messaging.ConfigFromEnv())
+	if err != nil {
+		logrus.Fatalf("Failed to connect to RabbitMQ: %v", err)
+	}
+	defer broker.Close(context.Background())
+
+	deadLetterService := service.NewDeadLetterService(broker)
+
+	switch {
+	case *replayID != "":
+		// Read edited body
+		var body []byte
+		if *bodyFile != "" {
+			body, err = os.ReadFile(*bodyFile)
+			if err != nil {
+				logrus.Fatalf("Failed to read body file: %v", err)
+			}
+		}
+
+		if err := deadLetterService.ReplayDeadLetter(*queueName, *replayID, body); err != nil {
+			logrus.Fatalf("Failed to replay dead letter: %v", err)
+		}
+		fmt.Printf("Replayed message %s\n", *replayID)
+
+	case *discardID != "":
+		if err := deadLetterService.DiscardDeadLetter(*queueName, *discardID); err != nil {
+			logrus.Fatalf("Failed to discard dead letter: %v", err)
+		}
+		fmt.Printf("Discarded message %s\n", *discardID)
+
+	default:
+		deadLetters, err := deadLetterService.ListDeadLetters(*queueName, *limit)
+		if err != nil {
+			logrus.Fatalf("Failed to list dead letters: %v", err)
+		}
+
+		// Print dead letters
+		report, _ := json.MarshalIndent(deadLetters, "", "  ")
+		fmt.Println(string(report))
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/event-ticket-service/config"
+	"github.com/yourusername/ticket-system/event-ticket-service/repository"
+	"github.com/yourusername/ticket-system/event-ticket-service/service"
+	"github.com/yourusername/ticket-system/messaging"
+)
+
+// import-events bulk imports events from a CSV or JSON lines file using the
+// same database settings as the service. Event created and updated events are
+// written to the outbox and published by the relay of the running service.
+//
+// Usage: import-events -file events.csv [-format csv|jsonl] [-dry-run]
+func main() {
+	filePath := flag.String("file", "", "path of the CSV or JSON lines file to import")
+	format := flag.String("format", "", "file format, csv or jsonl (default: from the file extension)")
+	dryRun := flag.Bool("dry-run", false, "validate and report the changes without writing them")
+	flag.Parse()
+
+	if *filePath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Load environment variables
+	if err := godotenv.Load(); err != nil {
+		logrus.Warn("Error loading .env file, using environment variables")
+	}
+
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*filePath)), ".")
+	}
+
+	// Open import file
+	file, err := os.Open(*filePath)
+	if err != nil {
+		logrus.Fatalf("Failed to open import file: %v", err)
+	}
+	defer file.Close()
+
+	// Connect to PostgreSQL
+	db, err := config.NewPostgresDB()
+	if err != nil {
+		logrus.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	// Initialize services
+	eventRepo := repository.NewEventRepository(db)
+	ticketRepo := repository.NewTicketRepository(db)
+	reportRepo := repository.NewReportRepository(db)
+	outboxRepo := messaging.NewOutboxRepository(db)
+	eventService := service.NewEventService(eventRepo, ticketRepo, reportRepo, outboxRepo, db)
+
+	// Import events
+	result, err := eventService.ImportEvents(file, *format, *dryRun)
+	if err != nil {
+		logrus.Fatalf("Failed to import events: %v", err)
+	}
+
+	// Print report
+	report, _ := json.MarshalIndent(result, "", "  ")
+	fmt.Println(string(report))
+
+	if len(result.Errors) > 0 {
+		os.Exit(1)
+	}
+}
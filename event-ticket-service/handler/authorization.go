@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yourusername/ticket-system/event-ticket-service/model"
+	"github.com/yourusername/ticket-system/event-ticket-service/service"
+)
+
+// currentActor returns the user or API key the request is made by, as set by
+// IdentityAuth
+func currentActor(c *gin.Context) (model.Actor, bool) {
+	id, err := uuid.Parse(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return model.Actor{}, false
+	}
+
+	actor := model.Actor{UserID: id, Role: c.GetString("userRole"), Scopes: c.GetStringSlice("scopes")}
+	if organizationID, ok := c.Get("organization_id"); ok {
+		if organizationID, ok := organizationID.(*uuid.UUID); ok {
+			actor.OrganizationID = organizationID
+		}
+	}
+	return actor, true
+}
+
+// authorize checks that the current user holds permission in an
+// organization, and writes the error response otherwise
+func authorize(c *gin.Context, organizationService service.OrganizationService, organizationID *uuid.UUID, permission string) bool {
+	actor, ok := currentActor(c)
+	if !ok {
+		return false
+	}
+
+	return handleAuthorizationError(c, organizationService.Authorize(actor, organizationID, permission))
+}
+
+// authorizeEvent checks that the current user holds permission on an event,
+// and writes the error response otherwise
+func authorizeEvent(c *gin.Context, organizationService service.OrganizationService, eventID uuid.UUID, permission string) bool {
+	actor, ok := currentActor(c)
+	if !ok {
+		return false
+	}
+
+	return handleAuthorizationError(c, organizationService.AuthorizeEvent(actor, eventID, permission))
+}
+
+// authorizeTicket checks that the current user holds permission on the
+// event of a ticket, and writes the error response otherwise
+func authorizeTicket(c *gin.Context, organizationService service.OrganizationService, ticketID uuid.UUID, permission string) bool {
+	actor, ok := currentActor(c)
+	if !ok {
+		return false
+	}
+
+	return handleAuthorizationError(c, organizationService.AuthorizeTicket(actor, ticketID, permission))
+}
+
+// handleAuthorizationError writes the response of a failed authorization and
+// reports whether the authorization succeeded
+func handleAuthorizationError(c *gin.Context, err error) bool {
+	if err == nil {
+		return true
+	}
+
+	switch err.Error() {
+	case "permission denied":
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case "event not found", "ticket not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+	return false
+}
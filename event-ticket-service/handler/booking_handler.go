@@ -12,29 +12,23 @@ import (
 
 // BookingHandler handles HTTP requests related to bookings
 type BookingHandler struct {
-	bookingService service.BookingService
+	bookingService      service.BookingService
+	organizationService service.OrganizationService
 }
 
 // NewBookingHandler creates a new booking handler
-func NewBookingHandler(bookingService service.BookingService) *BookingHandler {
+func NewBookingHandler(bookingService service.BookingService, organizationService service.OrganizationService) *BookingHandler {
 	return &BookingHandler{
-		bookingService: bookingService,
+		bookingService:      bookingService,
+		organizationService: organizationService,
 	}
 }
 
 // CreateBooking handles the creation of a new booking
 func (h *BookingHandler) CreateBooking(c *gin.Context) {
-	// Get user ID from context
-	userID, exists := c.Get("userID")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-		return
-	}
-
-	// Parse user ID
-	userUUID, err := uuid.Parse(userID.(string))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+	// Get user or API key from context
+	actor, ok := currentActor(c)
+	if !ok {
 		return
 	}
 
@@ -56,8 +50,13 @@ func (h *BookingHandler) CreateBooking(c *gin.Context) {
 		return
 	}
 
+	// API keys can only book events of their own organization
+	if actor.IsAPIKey() && !authorizeEvent(c, h.organizationService, req.EventID, model.PermissionCreateBookings) {
+		return
+	}
+
 	// Create booking
-	booking, err := h.bookingService.CreateBooking(userUUID, req)
+	booking, err := h.bookingService.CreateBooking(actor.UserID, req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -98,7 +97,7 @@ func (h *BookingHandler) GetBooking(c *gin.Context) {
 
 	// Check if user owns the booking or is an admin
 	userRole, _ := c.Get("userRole")
-	if userID.(string) != booking.UserID && userRole != "admin" {
+	if userID.(string) != booking.UserID.String() && userRole != "admin" {
 		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
 		return
 	}
@@ -239,7 +238,7 @@ func (h *BookingHandler) CancelBooking(c *gin.Context) {
 
 	// Check if user owns the booking or is an admin
 	userRole, _ := c.Get("userRole")
-	if userID.(string) != booking.UserID && userRole != "admin" {
+	if userID.(string) != booking.UserID.String() && userRole != "admin" {
 		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
 		return
 	}
@@ -254,16 +253,191 @@ func (h *BookingHandler) CancelBooking(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "booking cancelled successfully"})
 }
 
+// GetRefundQuote handles the retrieval of the refund a booking would get if it was cancelled now
+func (h *BookingHandler) GetRefundQuote(c *gin.Context) {
+	// Parse booking ID
+	bookingUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid booking ID"})
+		return
+	}
+
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	// Get booking
+	booking, err := h.bookingService.GetBookingByID(bookingUUID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Check if user owns the booking or is an admin
+	userRole, _ := c.Get("userRole")
+	if userID.(string) != booking.UserID.String() && userRole != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+
+	// Get refund quote
+	quote, err := h.bookingService.GetRefundQuote(bookingUUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, quote)
+}
+
+// CheckInTicket handles checking in a ticket at the venue
+func (h *BookingHandler) CheckInTicket(c *gin.Context) {
+	// Parse ticket ID
+	ticketUUID, err := uuid.Parse(c.Param("ticketId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ticket ID"})
+		return
+	}
+
+	// Check if user may check in tickets of the event
+	if !authorizeTicket(c, h.organizationService, ticketUUID, model.PermissionCheckInTickets) {
+		return
+	}
+
+	// Check in ticket
+	ticket, err := h.bookingService.CheckInTicket(ticketUUID)
+	if err != nil {
+		switch err.Error() {
+		case "ticket not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "ticket is not valid for check-in", "ticket already checked in":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, ticket)
+}
+
+// AmendBooking handles adding, removing or upgrading tickets of a booking
+func (h *BookingHandler) AmendBooking(c *gin.Context) {
+	// Parse booking ID
+	bookingUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid booking ID"})
+		return
+	}
+
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	// Parse user ID
+	userUUID, err := uuid.Parse(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+
+	// Parse request body
+	var req model.AmendBookingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Get booking
+	booking, err := h.bookingService.GetBookingByID(bookingUUID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Check if user owns the booking or is an admin
+	userRole, _ := c.Get("userRole")
+	if userUUID != booking.UserID && userRole != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+
+	// Amend booking
+	amendment, err := h.bookingService.AmendBooking(bookingUUID, userUUID, req)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Amendments waiting for a supplementary payment are not applied yet
+	if amendment.Status == model.AmendmentPendingPayment {
+		c.JSON(http.StatusAccepted, amendment)
+		return
+	}
+
+	c.JSON(http.StatusOK, amendment)
+}
+
+// GetBookingAmendments handles the retrieval of the amendment history of a booking
+func (h *BookingHandler) GetBookingAmendments(c *gin.Context) {
+	// Parse booking ID
+	bookingUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid booking ID"})
+		return
+	}
+
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	// Get booking
+	booking, err := h.bookingService.GetBookingByID(bookingUUID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Check if user owns the booking or is an admin
+	userRole, _ := c.Get("userRole")
+	if userID.(string) != booking.UserID.String() && userRole != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+
+	// Get amendments
+	amendments, err := h.bookingService.GetBookingAmendments(bookingUUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"amendments": amendments})
+}
+
 // SetupRoutes sets up the booking routes
-func (h *BookingHandler) SetupRoutes(router *gin.Engine, authMiddleware gin.HandlerFunc) {
+func (h *BookingHandler) SetupRoutes(router *gin.Engine, authMiddleware, verifiedEmailMiddleware, idempotencyMiddleware gin.HandlerFunc) {
 	// Create booking routes group
 	bookingRoutes := router.Group("/api/bookings")
 	bookingRoutes.Use(authMiddleware)
 
 	// Set up routes
-	bookingRoutes.POST("", h.CreateBooking)
+	bookingRoutes.POST("", verifiedEmailMiddleware, idempotencyMiddleware, h.CreateBooking)
 	bookingRoutes.GET("/user", h.GetUserBookings)
 	bookingRoutes.GET("/:id", h.GetBooking)
 	bookingRoutes.PUT("/:id/status", h.UpdateBookingStatus)
 	bookingRoutes.POST("/:id/cancel", h.CancelBooking)
+	bookingRoutes.GET("/:id/refund-quote", h.GetRefundQuote)
+	bookingRoutes.POST("/:id/amendments", h.AmendBooking)
+	bookingRoutes.GET("/:id/amendments", h.GetBookingAmendments)
+	bookingRoutes.POST("/tickets/:ticketId/check-in", h.CheckInTicket)
 }
\ No newline at end of file
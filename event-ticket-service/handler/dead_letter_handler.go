@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yourusername/ticket-system/event-ticket-service/model"
+	"github.com/yourusername/ticket-system/event-ticket-service/service"
+)
+
+// DeadLetterHandler handles HTTP requests for inspecting and replaying dead-lettered messages
+type DeadLetterHandler struct {
+	deadLetterService service.DeadLetterService
+}
+
+// NewDeadLetterHandler creates a new dead letter handler
+func NewDeadLetterHandler(deadLetterService service.DeadLetterService) *DeadLetterHandler {
+	return &DeadLetterHandler{
+		deadLetterService: deadLetterService,
+	}
+}
+
+// ListDeadLetters handles listing the dead letters of a consumer queue
+func (h *DeadLetterHandler) ListDeadLetters(c *gin.Context) {
+	// Check if user is admin
+	userRole, exists := c.Get("userRole")
+	if !exists || userRole != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only admins can inspect dead letters"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+	deadLetters, err := h.deadLetterService.ListDeadLetters(c.Query("queue"), limit)
+	if err != nil {
+		if err.Error() == "queue is required" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  deadLetters,
+		"total": len(deadLetters),
+	})
+}
+
+// ReplayDeadLetter handles replaying a dead letter, optionally with an edited body
+func (h *DeadLetterHandler) ReplayDeadLetter(c *gin.Context) {
+	// Check if user is admin
+	userRole, exists := c.Get("userRole")
+	if !exists || userRole != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only admins can replay dead letters"})
+		return
+	}
+
+	// Parse request body, which is optional
+	var req model.ReplayDeadLetterRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	err := h.deadLetterService.ReplayDeadLetter(c.Query("queue"), c.Param("messageId"), req.Body)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "dead letter replayed"})
+}
+
+// DiscardDeadLetter handles removing a dead letter without replaying it
+func (h *DeadLetterHandler) DiscardDeadLetter(c *gin.Context) {
+	// Check if user is admin
+	userRole, exists := c.Get("userRole")
+	if !exists || userRole != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only admins can discard dead letters"})
+		return
+	}
+
+	err := h.deadLetterService.DiscardDeadLetter(c.Query("queue"), c.Param("messageId"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "dead letter discarded"})
+}
+
+// handleError maps dead letter service errors to HTTP responses
+func (h *DeadLetterHandler) handleError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "dead letter not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "queue is required", "body must be valid JSON":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// SetupRoutes sets up the routes for the dead letter handler
+func (h *DeadLetterHandler) SetupRoutes(router *gin.Engine, authMiddleware gin.HandlerFunc) {
+	// Create dead letter routes group
+	deadLetterRoutes := router.Group("/api/admin/dead-letters")
+	deadLetterRoutes.Use(authMiddleware)
+
+	// Set up routes
+	deadLetterRoutes.GET("", h.ListDeadLetters)
+	deadLetterRoutes.POST("/:messageId/replay", h.ReplayDeadLetter)
+	deadLetterRoutes.DELETE("/:messageId", h.DiscardDeadLetter)
+}
@@ -2,7 +2,9 @@ package handler
 
 import (
 	"net/http"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -13,25 +15,20 @@ import (
 
 // EventHandler handles HTTP requests related to events
 type EventHandler struct {
-	eventService service.EventService
+	eventService        service.EventService
+	organizationService service.OrganizationService
 }
 
 // NewEventHandler creates a new event handler
-func NewEventHandler(eventService service.EventService) *EventHandler {
+func NewEventHandler(eventService service.EventService, organizationService service.OrganizationService) *EventHandler {
 	return &EventHandler{
-		eventService: eventService,
+		eventService:        eventService,
+		organizationService: organizationService,
 	}
 }
 
 // CreateEvent handles the creation of a new event
 func (h *EventHandler) CreateEvent(c *gin.Context) {
-	// Check if user is admin
-	userRole, exists := c.Get("userRole")
-	if !exists || userRole != "admin" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "only admins can create events"})
-		return
-	}
-
 	// Parse request body
 	var req model.CreateEventRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -39,6 +36,11 @@ func (h *EventHandler) CreateEvent(c *gin.Context) {
 		return
 	}
 
+	// Check if user may create events for the organization
+	if !authorize(c, h.organizationService, req.OrganizationID, model.PermissionManageEvents) {
+		return
+	}
+
 	// Validate request
 	if req.Name == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
@@ -118,13 +120,6 @@ func (h *EventHandler) GetEvent(c *gin.Context) {
 
 // UpdateEvent handles the update of an event
 func (h *EventHandler) UpdateEvent(c *gin.Context) {
-	// Check if user is admin
-	userRole, exists := c.Get("userRole")
-	if !exists || userRole != "admin" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "only admins can update events"})
-		return
-	}
-
 	// Get event ID from URL
 	eventID := c.Param("id")
 	if eventID == "" {
@@ -139,6 +134,11 @@ func (h *EventHandler) UpdateEvent(c *gin.Context) {
 		return
 	}
 
+	// Check if user may manage the event
+	if !authorizeEvent(c, h.organizationService, eventUUID, model.PermissionManageEvents) {
+		return
+	}
+
 	// Parse request body
 	var req model.UpdateEventRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -158,13 +158,6 @@ func (h *EventHandler) UpdateEvent(c *gin.Context) {
 
 // DeleteEvent handles the deletion of an event
 func (h *EventHandler) DeleteEvent(c *gin.Context) {
-	// Check if user is admin
-	userRole, exists := c.Get("userRole")
-	if !exists || userRole != "admin" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "only admins can delete events"})
-		return
-	}
-
 	// Get event ID from URL
 	eventID := c.Param("id")
 	if eventID == "" {
@@ -179,6 +172,11 @@ func (h *EventHandler) DeleteEvent(c *gin.Context) {
 		return
 	}
 
+	// Check if user may manage the event
+	if !authorizeEvent(c, h.organizationService, eventUUID, model.PermissionManageEvents) {
+		return
+	}
+
 	// Delete event
 	err = h.eventService.DeleteEvent(eventUUID)
 	if err != nil {
@@ -281,6 +279,58 @@ func (h *EventHandler) GetAllEvents(c *gin.Context) {
 	})
 }
 
+// ImportEvents handles a bulk import of events from a CSV or JSON lines file
+func (h *EventHandler) ImportEvents(c *gin.Context) {
+	// Check if user is admin
+	userRole, exists := c.Get("userRole")
+	if !exists || userRole != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only admins can import events"})
+		return
+	}
+
+	// Parse dry run flag
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dry_run"})
+		return
+	}
+
+	// Read the file from a multipart upload or the raw request body
+	format := c.Query("format")
+	body := c.Request.Body
+	if file, err := c.FormFile("file"); err == nil {
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(file.Filename)), ".")
+		}
+		upload, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+			return
+		}
+		defer upload.Close()
+		body = upload
+	} else if format == "" && strings.Contains(c.ContentType(), "csv") {
+		format = model.ImportFormatCSV
+	} else if format == "" {
+		format = model.ImportFormatJSONL
+	}
+
+	// Import events
+	result, err := h.eventService.ImportEvents(body, format, dryRun)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Invalid rows reject the whole import
+	if len(result.Errors) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, result)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 // SetupRoutes sets up the event routes
 func (h *EventHandler) SetupRoutes(router *gin.Engine, authMiddleware gin.HandlerFunc) {
 	// Create public event routes group
@@ -297,6 +347,7 @@ func (h *EventHandler) SetupRoutes(router *gin.Engine, authMiddleware gin.Handle
 
 	// Set up protected routes
 	protectedEventRoutes.POST("", h.CreateEvent)
+	protectedEventRoutes.POST("/import", h.ImportEvents)
 	protectedEventRoutes.PUT("/:id", h.UpdateEvent)
 	protectedEventRoutes.DELETE("/:id", h.DeleteEvent)
 }
\ No newline at end of file
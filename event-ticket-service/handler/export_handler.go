@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/event-ticket-service/model"
+	"github.com/yourusername/ticket-system/event-ticket-service/service"
+)
+
+// exportContentTypes maps export formats to their content types
+var exportContentTypes = map[string]string{
+	model.ExportFormatCSV:  "text/csv; charset=utf-8",
+	model.ExportFormatXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// ExportHandler handles HTTP requests related to data exports
+type ExportHandler struct {
+	exportService       service.ExportService
+	organizationService service.OrganizationService
+}
+
+// NewExportHandler creates a new export handler
+func NewExportHandler(exportService service.ExportService, organizationService service.OrganizationService) *ExportHandler {
+	return &ExportHandler{
+		exportService:       exportService,
+		organizationService: organizationService,
+	}
+}
+
+// ExportEventData handles a streaming export of an event dataset
+func (h *ExportHandler) ExportEventData(c *gin.Context) {
+	// Parse event ID
+	eventUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event ID"})
+		return
+	}
+
+	// Check if user may export the data of the event
+	if !authorizeEvent(c, h.organizationService, eventUUID, model.PermissionExportData) {
+		return
+	}
+
+	// Parse query parameters
+	var req model.ExportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Validate before anything is written to the response
+	dataset := c.Param("dataset")
+	if err := h.exportService.ValidateExport(eventUUID, dataset, req); err != nil {
+		if err.Error() == "event not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Stream export
+	filename := fmt.Sprintf("%s-%s.%s", dataset, eventUUID, req.Format)
+	c.Header("Content-Type", exportContentTypes[req.Format])
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	// Headers are already sent, so a failure can only be logged
+	if _, err := h.exportService.Export(c.Writer, eventUUID, dataset, req); err != nil {
+		logrus.WithError(err).Errorf("Failed to stream %s export for event %s", dataset, eventUUID)
+	}
+}
+
+// CreateExportJob handles the creation of a background export of an event dataset
+func (h *ExportHandler) CreateExportJob(c *gin.Context) {
+	// Get user ID from context
+	userID, _ := c.Get("userID")
+	requestedBy, _ := userID.(string)
+
+	// Parse event ID
+	eventUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event ID"})
+		return
+	}
+
+	// Check if user may export the data of the event
+	if !authorizeEvent(c, h.organizationService, eventUUID, model.PermissionExportData) {
+		return
+	}
+
+	// Parse query parameters
+	var req model.ExportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Create export job
+	job, err := h.exportService.CreateExportJob(eventUUID, c.Param("dataset"), req, requestedBy)
+	if err != nil {
+		if err.Error() == "event not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetExportJob handles the retrieval of the status of an export job
+func (h *ExportHandler) GetExportJob(c *gin.Context) {
+	job, ok := h.findAccessibleJob(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, job.ToResponse())
+}
+
+// DownloadExportJob handles the download of the artifact of a completed export job
+func (h *ExportHandler) DownloadExportJob(c *gin.Context) {
+	job, ok := h.findAccessibleJob(c)
+	if !ok {
+		return
+	}
+
+	// Artifacts are deleted after their retention
+	if job.Status == model.ExportJobExpired {
+		c.JSON(http.StatusGone, gin.H{"error": "export job artifact has expired"})
+		return
+	}
+
+	// Check if job is completed
+	if job.Status != model.ExportJobCompleted {
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("export job is %s", job.Status)})
+		return
+	}
+
+	filename := fmt.Sprintf("%s-%s.%s", job.Dataset, job.EventID, job.Format)
+	c.Header("Content-Type", exportContentTypes[job.Format])
+	c.FileAttachment(job.FilePath, filename)
+}
+
+// findAccessibleJob loads the export job in the URL if the user may access it,
+// writing the error response otherwise
+func (h *ExportHandler) findAccessibleJob(c *gin.Context) (*model.ExportJob, bool) {
+	// Parse job ID
+	jobUUID, err := uuid.Parse(c.Param("jobId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid export job ID"})
+		return nil, false
+	}
+
+	// Get export job
+	job, err := h.exportService.GetExportJob(jobUUID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return nil, false
+	}
+
+	// Only the requester or an admin can access the job
+	userID, _ := c.Get("userID")
+	userRole, _ := c.Get("userRole")
+	if userID != job.RequestedBy && userRole != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return nil, false
+	}
+
+	return job, true
+}
+
+// SetupRoutes sets up the export routes
+func (h *ExportHandler) SetupRoutes(router *gin.Engine, authMiddleware gin.HandlerFunc) {
+	// Create export routes group
+	exportRoutes := router.Group("/api/exports")
+	exportRoutes.Use(authMiddleware)
+
+	// Set up routes
+	exportRoutes.GET("/events/:id/:dataset", h.ExportEventData)
+	exportRoutes.POST("/events/:id/:dataset/jobs", h.CreateExportJob)
+	exportRoutes.GET("/jobs/:jobId", h.GetExportJob)
+	exportRoutes.GET("/jobs/:jobId/download", h.DownloadExportJob)
+}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yourusername/ticket-system/event-ticket-service/model"
+	"github.com/yourusername/ticket-system/event-ticket-service/service"
+)
+
+// RefundPolicyHandler handles HTTP requests related to event refund policies
+type RefundPolicyHandler struct {
+	refundPolicyService service.RefundPolicyService
+	organizationService service.OrganizationService
+}
+
+// NewRefundPolicyHandler creates a new refund policy handler
+func NewRefundPolicyHandler(refundPolicyService service.RefundPolicyService, organizationService service.OrganizationService) *RefundPolicyHandler {
+	return &RefundPolicyHandler{
+		refundPolicyService: refundPolicyService,
+		organizationService: organizationService,
+	}
+}
+
+// GetRefundPolicy handles the retrieval of the refund policy of an event
+func (h *RefundPolicyHandler) GetRefundPolicy(c *gin.Context) {
+	// Parse event ID
+	eventUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event ID"})
+		return
+	}
+
+	// Get refund policy
+	policy, err := h.refundPolicyService.GetRefundPolicy(eventUUID)
+	if err != nil {
+		if err.Error() == "refund policy not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// SetRefundPolicy handles creating or replacing the refund policy of an event
+func (h *RefundPolicyHandler) SetRefundPolicy(c *gin.Context) {
+	// Parse event ID
+	eventUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event ID"})
+		return
+	}
+
+	// Check if user may manage the event
+	if !authorizeEvent(c, h.organizationService, eventUUID, model.PermissionManageEvents) {
+		return
+	}
+
+	// Parse request body
+	var req model.RefundPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Set refund policy
+	policy, err := h.refundPolicyService.SetRefundPolicy(eventUUID, req)
+	if err != nil {
+		if err.Error() == "event not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// DeleteRefundPolicy handles the removal of the refund policy of an event
+func (h *RefundPolicyHandler) DeleteRefundPolicy(c *gin.Context) {
+	// Parse event ID
+	eventUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event ID"})
+		return
+	}
+
+	// Check if user may manage the event
+	if !authorizeEvent(c, h.organizationService, eventUUID, model.PermissionManageEvents) {
+		return
+	}
+
+	// Delete refund policy
+	if err := h.refundPolicyService.DeleteRefundPolicy(eventUUID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "refund policy deleted successfully"})
+}
+
+// SetupRoutes sets up the refund policy routes
+func (h *RefundPolicyHandler) SetupRoutes(router *gin.Engine, authMiddleware gin.HandlerFunc) {
+	// Set up public routes
+	router.GET("/api/events/:id/refund-policy", h.GetRefundPolicy)
+
+	// Create protected refund policy routes group
+	policyRoutes := router.Group("/api/events/:id/refund-policy")
+	policyRoutes.Use(authMiddleware)
+
+	// Set up protected routes
+	policyRoutes.PUT("", h.SetRefundPolicy)
+	policyRoutes.DELETE("", h.DeleteRefundPolicy)
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yourusername/ticket-system/event-ticket-service/model"
+	"github.com/yourusername/ticket-system/event-ticket-service/service"
+)
+
+// ReportHandler handles HTTP requests related to sales reports
+type ReportHandler struct {
+	reportService       service.ReportService
+	organizationService service.OrganizationService
+}
+
+// NewReportHandler creates a new report handler
+func NewReportHandler(reportService service.ReportService, organizationService service.OrganizationService) *ReportHandler {
+	return &ReportHandler{
+		reportService:       reportService,
+		organizationService: organizationService,
+	}
+}
+
+// GetEventSalesReport handles the retrieval of the sales report of an event
+func (h *ReportHandler) GetEventSalesReport(c *gin.Context) {
+	// Get event ID from URL
+	eventID := c.Param("id")
+	if eventID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "event ID is required"})
+		return
+	}
+
+	// Parse event ID
+	eventUUID, err := uuid.Parse(eventID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event ID"})
+		return
+	}
+
+	// Check if user may view the reports of the event
+	if !authorizeEvent(c, h.organizationService, eventUUID, model.PermissionViewReports) {
+		return
+	}
+
+	// Parse query parameters
+	var req model.SalesReportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Get report
+	report, err := h.reportService.GetEventSalesReport(eventUUID, req)
+	if err != nil {
+		switch {
+		case err.Error() == "event not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case strings.HasPrefix(err.Error(), "invalid bucket"):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// RefreshEventSalesReport handles an on-demand rebuild of the rollups of an event
+func (h *ReportHandler) RefreshEventSalesReport(c *gin.Context) {
+	// Check if user is admin
+	userRole, exists := c.Get("userRole")
+	if !exists || userRole != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only admins can refresh sales reports"})
+		return
+	}
+
+	// Parse event ID
+	eventUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event ID"})
+		return
+	}
+
+	// Refresh report
+	if err := h.reportService.RefreshEventReport(eventUUID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "report refreshed successfully"})
+}
+
+// SetupRoutes sets up the report routes
+func (h *ReportHandler) SetupRoutes(router *gin.Engine, authMiddleware gin.HandlerFunc) {
+	// Create report routes group
+	reportRoutes := router.Group("/api/reports")
+	reportRoutes.Use(authMiddleware)
+
+	// Set up routes
+	reportRoutes.GET("/events/:id", h.GetEventSalesReport)
+	reportRoutes.POST("/events/:id/refresh", h.RefreshEventSalesReport)
+}
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/yourusername/ticket-system/event-ticket-service/service"
+)
+
+// SagaHandler handles HTTP requests for inspecting booking sagas
+type SagaHandler struct {
+	sagaService service.SagaService
+}
+
+// NewSagaHandler creates a new saga handler
+func NewSagaHandler(sagaService service.SagaService) *SagaHandler {
+	return &SagaHandler{
+		sagaService: sagaService,
+	}
+}
+
+// ListStuckSagas handles listing the sagas that failed or are waiting for a
+// step past its timeout
+func (h *SagaHandler) ListStuckSagas(c *gin.Context) {
+	// Check if user is admin
+	userRole, exists := c.Get("userRole")
+	if !exists || userRole != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only admins can inspect sagas"})
+		return
+	}
+
+	// Get pagination parameters
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", "10"))
+	if err != nil || pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	sagas, total, err := h.sagaService.GetStuckSagas(page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sagas":    sagas,
+		"total":    total,
+		"page":     page,
+		"pageSize": pageSize,
+	})
+}
+
+// GetSaga handles getting the saga of a booking with its steps
+func (h *SagaHandler) GetSaga(c *gin.Context) {
+	// Check if user is admin
+	userRole, exists := c.Get("userRole")
+	if !exists || userRole != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only admins can inspect sagas"})
+		return
+	}
+
+	bookingID, err := uuid.Parse(c.Param("bookingId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid booking ID"})
+		return
+	}
+
+	saga, err := h.sagaService.GetSaga(bookingID)
+	if err != nil {
+		if err.Error() == "saga not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, saga)
+}
+
+// SetupRoutes sets up the routes for the saga handler
+func (h *SagaHandler) SetupRoutes(router *gin.Engine, authMiddleware gin.HandlerFunc) {
+	// Create saga routes group
+	sagaRoutes := router.Group("/api/admin/sagas")
+	sagaRoutes.Use(authMiddleware)
+
+	// Set up routes
+	sagaRoutes.GET("", h.ListStuckSagas)
+	sagaRoutes.GET("/:bookingId", h.GetSaga)
+}
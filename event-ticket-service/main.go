@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
@@ -15,11 +14,17 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/claims"
+	"github.com/yourusername/ticket-system/contracts"
 	"github.com/yourusername/ticket-system/event-ticket-service/config"
 	"github.com/yourusername/ticket-system/event-ticket-service/handler"
 	"github.com/yourusername/ticket-system/event-ticket-service/middleware"
+	"github.com/yourusername/ticket-system/event-ticket-service/model"
 	"github.com/yourusername/ticket-system/event-ticket-service/repository"
 	"github.com/yourusername/ticket-system/event-ticket-service/service"
+	"github.com/yourusername/ticket-system/idempotency"
+	"github.com/yourusername/ticket-system/messaging"
+	"gorm.io/gorm"
 )
 
 func main() {
@@ -49,16 +54,15 @@ func main() {
 	logrus.Info("Connected to PostgreSQL database")
 
 	// Connect to RabbitMQ
-	rmq, err := config.NewRabbitMQ()
+	broker, err := messaging.NewRabbitMQ(messaging.ConfigFromEnv())
 	if err != nil {
 		logrus.Fatalf("Failed to connect to RabbitMQ: %v", err)
 	}
-	logrus.Info("Connected to RabbitMQ")
 
 	// Declare exchanges
-	exchanges := []string{"user_events", "ticket_events", "payment_events", "notification_events"}
+	exchanges := []string{"user_events", "ticket_events", "payment_events", "notification_events", "privacy_events"}
 	for _, exchange := range exchanges {
-		err = rmq.DeclareExchange(exchange)
+		err = broker.DeclareExchange(exchange)
 		if err != nil {
 			logrus.Fatalf("Failed to declare exchange %s: %v", exchange, err)
 		}
@@ -68,17 +72,73 @@ func main() {
 	eventRepo := repository.NewEventRepositoryImpl(db)
 	ticketRepo := repository.NewTicketRepositoryImpl(db)
 	bookingRepo := repository.NewBookingRepositoryImpl(db)
+	reportRepo := repository.NewReportRepository(db)
+	exportRepo := repository.NewExportRepository(db)
+	amendmentRepo := repository.NewAmendmentRepository(db)
+	refundPolicyRepo := repository.NewRefundPolicyRepository(db)
+	idempotencyRepo := idempotency.NewRepository(db)
+	outboxRepo := messaging.NewOutboxRepository(db)
+	inboxRepo := messaging.NewInboxRepository(db)
+	sagaRepo := repository.NewSagaRepository(db)
+	organizationRepo := repository.NewOrganizationRepository(db)
+	transactionRepo := repository.NewTransactionRepository(db)
 
 	// Initialize services
-	eventService := service.NewEventService(eventRepo, ticketRepo, rmq)
-	bookingService := service.NewBookingService(bookingRepo, eventRepo, ticketRepo, db, rmq)
+	eventService := service.NewEventService(eventRepo, ticketRepo, reportRepo, outboxRepo, db)
+	bookingService := service.NewBookingService(bookingRepo, eventRepo, ticketRepo, reportRepo, amendmentRepo, refundPolicyRepo, outboxRepo, sagaRepo, db)
+	reportService := service.NewReportService(reportRepo, eventRepo)
+	refundPolicyService := service.NewRefundPolicyService(refundPolicyRepo, eventRepo)
+	outboxRelay := messaging.NewOutboxRelay(outboxRepo, broker)
+	inbox := messaging.NewInbox(inboxRepo)
+	deadLetterService := service.NewDeadLetterService(broker)
+	organizationService := service.NewOrganizationService(organizationRepo, eventRepo, ticketRepo)
+	privacyService := service.NewPrivacyService(bookingRepo, amendmentRepo, organizationRepo, outboxRepo, db)
+	transactionService := service.NewTransactionService(transactionRepo, bookingRepo, reportRepo)
+
+	// Give up on booking saga steps that take longer than their timeout
+	sagaTimeouts := service.DefaultSagaTimeouts()
+	for step, env := range map[string]string{
+		model.SagaStepCreatePayment:  "SAGA_CREATE_PAYMENT_TIMEOUT",
+		model.SagaStepCapturePayment: "SAGA_CAPTURE_PAYMENT_TIMEOUT",
+		model.SagaStepRefundPayment:  "SAGA_REFUND_PAYMENT_TIMEOUT",
+	} {
+		if timeout, err := time.ParseDuration(os.Getenv(env)); err == nil && timeout > 0 {
+			sagaTimeouts[step] = timeout
+		}
+	}
+	sagaService := service.NewSagaService(sagaRepo, outboxRepo, bookingService, sagaTimeouts, db)
+
+	exportDir := os.Getenv("EXPORT_DIR")
+	if exportDir == "" {
+		exportDir = "exports"
+	}
+	exportJobLease, err := time.ParseDuration(os.Getenv("EXPORT_JOB_LEASE"))
+	if err != nil || exportJobLease <= 0 {
+		exportJobLease = 5 * time.Minute
+	}
+	exportRetention, err := time.ParseDuration(os.Getenv("EXPORT_RETENTION"))
+	if err != nil || exportRetention <= 0 {
+		exportRetention = 7 * 24 * time.Hour
+	}
+	exportService := service.NewExportService(exportRepo, eventRepo, exportDir, exportJobLease, exportRetention)
 
 	// Initialize handlers
-	eventHandler := handler.NewEventHandler(eventService)
-	bookingHandler := handler.NewBookingHandler(bookingService)
+	eventHandler := handler.NewEventHandler(eventService, organizationService)
+	bookingHandler := handler.NewBookingHandler(bookingService, organizationService)
+	reportHandler := handler.NewReportHandler(reportService, organizationService)
+	exportHandler := handler.NewExportHandler(exportService, organizationService)
+	refundPolicyHandler := handler.NewRefundPolicyHandler(refundPolicyService, organizationService)
+	deadLetterHandler := handler.NewDeadLetterHandler(deadLetterService)
+	sagaHandler := handler.NewSagaHandler(sagaService)
 
 	// Initialize Gin router
 	router := gin.New()
+
+	// Only the API gateway may report the address of the client
+	if err := router.SetTrustedProxies(claims.TrustedProxiesFromEnv()); err != nil {
+		logrus.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
 	router.Use(middleware.Logger())
 	router.Use(middleware.Metrics())
 
@@ -90,87 +150,168 @@ func main() {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
 
+	// Replay retried requests sent with an Idempotency-Key header
+	idempotencyTTL, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_TTL"))
+	if err != nil || idempotencyTTL <= 0 {
+		idempotencyTTL = 24 * time.Hour
+	}
+	idempotencyLockTimeout, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_LOCK_TIMEOUT"))
+	if err != nil || idempotencyLockTimeout <= 0 {
+		idempotencyLockTimeout = 30 * time.Second
+	}
+	idempotencyMiddleware := idempotency.Middleware(idempotencyRepo, idempotencyTTL, idempotencyLockTimeout, middleware.UserID)
+
+	// Trust the identity the gateway verified the access token of
+	identitySigner := claims.NewSigner(claims.ConfigFromEnv())
+	if !identitySigner.Configured() {
+		logrus.Warn("INTERNAL_IDENTITY_SECRET is not set, authenticated requests are rejected")
+	}
+	authMiddleware := middleware.IdentityAuth(identitySigner)
+
+	// Only users who verified their email address may book when required
+	verifiedEmail := middleware.RequireVerifiedEmail(os.Getenv("REQUIRE_VERIFIED_EMAIL") == "true")
+
 	// Set up routes
-	eventHandler.SetupRoutes(router, middleware.JWTAuth())
-	bookingHandler.SetupRoutes(router, middleware.JWTAuth())
+	eventHandler.SetupRoutes(router, authMiddleware)
+	bookingHandler.SetupRoutes(router, authMiddleware, verifiedEmail, idempotencyMiddleware)
+	reportHandler.SetupRoutes(router, authMiddleware)
+	exportHandler.SetupRoutes(router, authMiddleware)
+	refundPolicyHandler.SetupRoutes(router, authMiddleware)
+	deadLetterHandler.SetupRoutes(router, authMiddleware)
+	sagaHandler.SetupRoutes(router, authMiddleware)
+
+	// Start background workers
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	// Rebuild stale sales report rollups
+	reportInterval, err := time.ParseDuration(os.Getenv("REPORT_REFRESH_INTERVAL"))
+	if err != nil || reportInterval <= 0 {
+		reportInterval = 30 * time.Second
+	}
+	go reportService.StartRollupWorker(workerCtx, reportInterval)
+
+	// Generate background export artifacts
+	exportInterval, err := time.ParseDuration(os.Getenv("EXPORT_POLL_INTERVAL"))
+	if err != nil || exportInterval <= 0 {
+		exportInterval = 5 * time.Second
+	}
+	go exportService.StartExportWorker(workerCtx, exportInterval)
+
+	// Publish events written to the outbox
+	outboxInterval, err := time.ParseDuration(os.Getenv("OUTBOX_POLL_INTERVAL"))
+	if err != nil || outboxInterval <= 0 {
+		outboxInterval = time.Second
+	}
+	go outboxRelay.Start(workerCtx, outboxInterval)
+
+	// Delete expired idempotency records
+	go idempotency.StartCleanupWorker(workerCtx, idempotencyRepo, time.Hour)
+
+	// Delete old processed message IDs
+	go inbox.StartCleanup(workerCtx, time.Hour)
+
+	// Compensate booking sagas whose step timed out
+	sagaInterval, err := time.ParseDuration(os.Getenv("SAGA_TIMEOUT_CHECK_INTERVAL"))
+	if err != nil || sagaInterval <= 0 {
+		sagaInterval = 30 * time.Second
+	}
+	go sagaService.StartTimeoutWorker(workerCtx, sagaInterval)
 
 	// Set up consumer for payment events
-	go func() {
-		queueName := "event_ticket_payment_events"
-		routingKey := "payment.#"
-
-		err := rmq.ConsumeMessages("payment_events", queueName, routingKey, func(message []byte) error {
-			logrus.Infof("Received payment event: %s", string(message))
-			
-			// Parse payment event
-			var paymentEvent map[string]interface{}
-			if err := json.Unmarshal(message, &paymentEvent); err != nil {
-				logrus.WithError(err).Error("Failed to parse payment event")
-				return err
-			}
-			
-			// Process based on event type
-			eventType, ok := paymentEvent["event_type"].(string)
-			if !ok {
-				logrus.Error("Invalid event type in payment event")
-				return fmt.Errorf("invalid event type")
-			}
-			
-			switch eventType {
-			case "payment.completed":
-				return handlePaymentCompleted(paymentEvent, bookingService)
-			case "payment.failed":
-				return handlePaymentFailed(paymentEvent, bookingService)
-			case "payment.refunded":
-				return handlePaymentRefunded(paymentEvent, bookingService)
+	paymentQueue := "event_ticket_payment_events"
+	err = broker.Consume(workerCtx, messaging.ConsumerOptions{
+		Queue:       paymentQueue,
+		Exchange:    "payment_events",
+		RoutingKeys: []string{"payment.#"},
+	}, func(msg messaging.Message) error {
+		logrus.Infof("Received payment event %s %s", msg.RoutingKey, msg.ID)
+
+		// Parse the envelope of the payment event
+		envelope, err := contracts.Parse(msg.Body)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to parse payment event")
+			return err
+		}
+
+		// Apply the event once, in the transaction that records it as processed
+		return inbox.Handle(paymentQueue, msg.ID, func(tx *gorm.DB) error {
+			txBookingService := bookingService.WithTx(tx)
+			txSagaService := sagaService.WithTx(tx)
+			txTransactionService := transactionService.WithTx(tx)
+
+			switch envelope.Type {
+			case contracts.TypePaymentCreated:
+				return handlePaymentCreated(envelope, txSagaService)
+			case contracts.TypePaymentCompleted:
+				return handlePaymentCompleted(envelope, txBookingService, txSagaService, txTransactionService)
+			case contracts.TypePaymentFailed:
+				return handlePaymentFailed(envelope, txBookingService, txSagaService)
+			case contracts.TypePaymentRefunded:
+				return handlePaymentRefunded(envelope, txSagaService, txTransactionService)
+			case contracts.TypePaymentPartiallyRefunded:
+				var event contracts.PaymentPartiallyRefunded
+				if err := envelope.Decode(&event); err != nil {
+					return err
+				}
+				if err := txTransactionService.RecordRefund(event.RefundState, envelope.Timestamp); err != nil {
+					logrus.WithError(err).Errorf("Failed to record refund of booking %s", event.BookingID)
+					return err
+				}
+				logrus.Infof("Partial refund of %.2f processed for booking %s", event.RefundAmount, event.BookingID)
+				return nil
 			default:
-				logrus.Warnf("Unknown payment event type: %s", eventType)
+				logrus.Warnf("Unknown payment event type: %s", envelope.Type)
 				return nil
 			}
 		})
+	})
+	if err != nil {
+		logrus.Fatalf("Failed to consume payment events: %v", err)
+	}
 
+	// Set up consumer for user and organization events
+	userQueue := "event_ticket_user_events"
+	err = broker.Consume(workerCtx, messaging.ConsumerOptions{
+		Queue:       userQueue,
+		Exchange:    "user_events",
+		RoutingKeys: []string{"user.#", "organization.#"},
+	}, func(msg messaging.Message) error {
+		logrus.Infof("Received user event %s %s", msg.RoutingKey, msg.ID)
+
+		// Parse the envelope of the user event
+		envelope, err := contracts.Parse(msg.Body)
 		if err != nil {
-			logrus.Errorf("Failed to consume payment events: %v", err)
+			logrus.WithError(err).Error("Failed to parse user event")
+			return err
 		}
-	}()
 
-	// Set up consumer for user events
-	go func() {
-		queueName := "event_ticket_user_events"
-		routingKey := "user.#"
-
-		err := rmq.ConsumeMessages("user_events", queueName, routingKey, func(message []byte) error {
-			logrus.Infof("Received user event: %s", string(message))
-			
-			// Parse user event
-			var userEvent map[string]interface{}
-			if err := json.Unmarshal(message, &userEvent); err != nil {
-				logrus.WithError(err).Error("Failed to parse user event")
-				return err
-			}
-			
-			// Process based on event type
-			eventType, ok := userEvent["event_type"].(string)
-			if !ok {
-				logrus.Error("Invalid event type in user event")
-				return fmt.Errorf("invalid event type")
-			}
-			
-			switch eventType {
-			case "user.deleted":
-				return handleUserDeleted(userEvent, bookingService)
-			case "user.suspended":
-				return handleUserSuspended(userEvent, bookingService)
+		// Apply the event once, in the transaction that records it as processed
+		return inbox.Handle(userQueue, msg.ID, func(tx *gorm.DB) error {
+			txBookingService := bookingService.WithTx(tx)
+			txOrganizationService := organizationService.WithTx(tx)
+			txPrivacyService := privacyService.WithTx(tx)
+
+			switch envelope.Type {
+			case contracts.TypeUserDeleted:
+				return handleUserDeleted(envelope, txBookingService, txOrganizationService)
+			case contracts.TypeUserDataRequested:
+				return handleUserDataRequested(envelope, txPrivacyService)
+			case contracts.TypeUserSuspended:
+				return handleUserSuspended(envelope, txBookingService)
+			case contracts.TypeOrganizationMemberAdded, contracts.TypeOrganizationMemberRoleChanged:
+				return handleOrganizationMemberSaved(envelope, txOrganizationService)
+			case contracts.TypeOrganizationMemberRemoved:
+				return handleOrganizationMemberRemoved(envelope, txOrganizationService)
 			default:
-				logrus.Warnf("Unknown user event type: %s", eventType)
+				logrus.Warnf("Unknown user event type: %s", envelope.Type)
 				return nil
 			}
 		})
-
-		if err != nil {
-			logrus.Errorf("Failed to consume user events: %v", err)
-		}
-	}()
+	})
+	if err != nil {
+		logrus.Fatalf("Failed to consume user events: %v", err)
+	}
 
 	// Start HTTP server
 	port := os.Getenv("SERVER_PORT")
@@ -206,102 +347,171 @@ func main() {
 		logrus.Fatalf("Server forced to shutdown: %v", err)
 	}
 
-	// Close RabbitMQ connection
-	rmq.Close()
+	// Stop background workers
+	stopWorkers()
+
+	// Let the consumers finish the messages in progress and close the RabbitMQ connection
+	if err := broker.Close(ctx); err != nil {
+		logrus.Errorf("Failed to close RabbitMQ connection: %v", err)
+	}
 
 	logrus.Info("Server exited properly")
 }
 
-// handlePaymentCompleted handles payment completed events
-func handlePaymentCompleted(paymentEvent map[string]interface{}, bookingService service.BookingService) error {
-	// Extract booking ID from payment event
-	bookingIDStr, ok := paymentEvent["booking_id"].(string)
-	if !ok {
-		logrus.Error("Missing booking_id in payment completed event")
-		return fmt.Errorf("missing booking_id")
+// handlePaymentCreated handles payment created events
+func handlePaymentCreated(envelope *contracts.Envelope, sagaService service.SagaService) error {
+	var event contracts.PaymentCreated
+	if err := envelope.Decode(&event); err != nil {
+		logrus.WithError(err).Error("Invalid payment created event")
+		return err
+	}
+
+	// Supplementary payments of an amendment are not part of the booking saga
+	if event.AmendmentID != nil {
+		return nil
 	}
 
-	bookingID, err := uuid.Parse(bookingIDStr)
+	// Move the booking saga on to wait for the payment to be captured
+	err := sagaService.HandlePaymentCreated(event.BookingID, event.PaymentID)
 	if err != nil {
-		logrus.WithError(err).Error("Invalid booking_id in payment completed event")
+		logrus.WithError(err).Errorf("Failed to advance saga of booking %s", event.BookingID)
 		return err
 	}
 
-	// Update booking status to confirmed
-	err = bookingService.UpdateBookingStatus(bookingID, "confirmed")
+	return nil
+}
+
+// handlePaymentCompleted handles payment completed events
+func handlePaymentCompleted(envelope *contracts.Envelope, bookingService service.BookingService, sagaService service.SagaService, transactionService service.TransactionService) error {
+	var event contracts.PaymentCompleted
+	if err := envelope.Decode(&event); err != nil {
+		logrus.WithError(err).Error("Invalid payment completed event")
+		return err
+	}
+
+	// Record the payment for the transaction exports
+	if err := transactionService.RecordPayment(event.PaymentState, envelope.Timestamp); err != nil {
+		logrus.WithError(err).Errorf("Failed to record payment of booking %s", event.BookingID)
+		return err
+	}
+
+	// Supplementary payments of an amendment only complete the amendment
+	if event.AmendmentID != nil {
+		return handleAmendmentPayment(*event.AmendmentID, event.PaymentID, bookingService, true)
+	}
+
+	// Confirm the booking through its saga
+	err := sagaService.HandlePaymentCompleted(event.BookingID, event.PaymentID, event.Amount)
 	if err != nil {
-		logrus.WithError(err).Errorf("Failed to confirm booking %s", bookingID)
+		logrus.WithError(err).Errorf("Failed to confirm booking %s", event.BookingID)
 		return err
 	}
 
-	logrus.Infof("Booking %s confirmed after payment completion", bookingID)
+	logrus.Infof("Booking %s confirmed after payment completion", event.BookingID)
 	return nil
 }
 
 // handlePaymentFailed handles payment failed events
-func handlePaymentFailed(paymentEvent map[string]interface{}, bookingService service.BookingService) error {
-	// Extract booking ID from payment event
-	bookingIDStr, ok := paymentEvent["booking_id"].(string)
-	if !ok {
-		logrus.Error("Missing booking_id in payment failed event")
-		return fmt.Errorf("missing booking_id")
+func handlePaymentFailed(envelope *contracts.Envelope, bookingService service.BookingService, sagaService service.SagaService) error {
+	var event contracts.PaymentFailed
+	if err := envelope.Decode(&event); err != nil {
+		logrus.WithError(err).Error("Invalid payment failed event")
+		return err
 	}
 
-	bookingID, err := uuid.Parse(bookingIDStr)
+	// A failed supplementary payment only fails the amendment, not the booking
+	if event.AmendmentID != nil {
+		return handleAmendmentPayment(*event.AmendmentID, event.PaymentID, bookingService, false)
+	}
+
+	// Release the tickets of the booking through its saga
+	err := sagaService.HandlePaymentFailed(event.BookingID)
 	if err != nil {
-		logrus.WithError(err).Error("Invalid booking_id in payment failed event")
+		logrus.WithError(err).Errorf("Failed to cancel booking %s after payment failure", event.BookingID)
 		return err
 	}
 
-	// Cancel booking due to payment failure
-	err = bookingService.CancelBooking(bookingID)
+	logrus.Infof("Booking %s cancelled after payment failure", event.BookingID)
+	return nil
+}
+
+// handleAmendmentPayment completes or fails the amendment of a supplementary payment
+func handleAmendmentPayment(amendmentID uuid.UUID, paymentID uuid.UUID, bookingService service.BookingService, completed bool) error {
+	if !completed {
+		err := bookingService.FailAmendment(amendmentID, "supplementary payment failed")
+		if err != nil {
+			logrus.WithError(err).Errorf("Failed to fail amendment %s", amendmentID)
+			return err
+		}
+
+		logrus.Infof("Amendment %s failed after payment failure", amendmentID)
+		return nil
+	}
+
+	err := bookingService.CompleteAmendment(amendmentID, paymentID)
 	if err != nil {
-		logrus.WithError(err).Errorf("Failed to cancel booking %s after payment failure", bookingID)
+		logrus.WithError(err).Errorf("Failed to complete amendment %s", amendmentID)
 		return err
 	}
 
-	logrus.Infof("Booking %s cancelled after payment failure", bookingID)
+	logrus.Infof("Amendment %s applied after payment completion", amendmentID)
 	return nil
 }
 
 // handlePaymentRefunded handles payment refunded events
-func handlePaymentRefunded(paymentEvent map[string]interface{}, bookingService service.BookingService) error {
-	// Extract booking ID from payment event
-	bookingIDStr, ok := paymentEvent["booking_id"].(string)
-	if !ok {
-		logrus.Error("Missing booking_id in payment refunded event")
-		return fmt.Errorf("missing booking_id")
+func handlePaymentRefunded(envelope *contracts.Envelope, sagaService service.SagaService, transactionService service.TransactionService) error {
+	var event contracts.PaymentRefunded
+	if err := envelope.Decode(&event); err != nil {
+		logrus.WithError(err).Error("Invalid payment refunded event")
+		return err
 	}
 
-	bookingID, err := uuid.Parse(bookingIDStr)
-	if err != nil {
-		logrus.WithError(err).Error("Invalid booking_id in payment refunded event")
+	// Record the refund for the transaction exports
+	if err := transactionService.RecordRefund(event.RefundState, envelope.Timestamp); err != nil {
+		logrus.WithError(err).Errorf("Failed to record refund of booking %s", event.BookingID)
 		return err
 	}
 
-	// Update booking status to refunded
-	err = bookingService.UpdateBookingStatus(bookingID, "refunded")
+	// Update booking status to refunded and complete a saga waiting for the refund
+	err := sagaService.HandlePaymentRefunded(event.BookingID)
 	if err != nil {
-		logrus.WithError(err).Errorf("Failed to update booking %s to refunded", bookingID)
+		logrus.WithError(err).Errorf("Failed to update booking %s to refunded", event.BookingID)
 		return err
 	}
 
-	logrus.Infof("Booking %s marked as refunded", bookingID)
+	logrus.Infof("Booking %s marked as refunded", event.BookingID)
+	return nil
+}
+
+// handleUserDataRequested handles the data export and erasure requests of
+// the user service
+func handleUserDataRequested(envelope *contracts.Envelope, privacyService service.PrivacyService) error {
+	var event contracts.UserDataRequested
+	if err := envelope.Decode(&event); err != nil {
+		logrus.WithError(err).Error("Invalid user data requested event")
+		return err
+	}
+
+	if err := privacyService.HandleDataRequest(event); err != nil {
+		logrus.WithError(err).Errorf("Failed to answer data request %s", event.RequestID)
+		return err
+	}
 	return nil
 }
 
 // handleUserDeleted handles user deleted events
-func handleUserDeleted(userEvent map[string]interface{}, bookingService service.BookingService) error {
-	// Extract user ID from user event
-	userIDStr, ok := userEvent["user_id"].(string)
-	if !ok {
-		logrus.Error("Missing user_id in user deleted event")
-		return fmt.Errorf("missing user_id")
+func handleUserDeleted(envelope *contracts.Envelope, bookingService service.BookingService, organizationService service.OrganizationService) error {
+	var event contracts.UserDeleted
+	if err := envelope.Decode(&event); err != nil {
+		logrus.WithError(err).Error("Invalid user deleted event")
+		return err
 	}
 
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		logrus.WithError(err).Error("Invalid user_id in user deleted event")
+	userID := event.UserID
+
+	// Deleted users no longer act for their organizations
+	if err := organizationService.RemoveUser(userID); err != nil {
+		logrus.WithError(err).Errorf("Failed to remove deleted user %s from organizations", userID)
 		return err
 	}
 
@@ -328,20 +538,15 @@ func handleUserDeleted(userEvent map[string]interface{}, bookingService service.
 }
 
 // handleUserSuspended handles user suspended events
-func handleUserSuspended(userEvent map[string]interface{}, bookingService service.BookingService) error {
-	// Extract user ID from user event
-	userIDStr, ok := userEvent["user_id"].(string)
-	if !ok {
-		logrus.Error("Missing user_id in user suspended event")
-		return fmt.Errorf("missing user_id")
-	}
-
-	userID, err := uuid.Parse(userIDStr)
-	if err != nil {
-		logrus.WithError(err).Error("Invalid user_id in user suspended event")
+func handleUserSuspended(envelope *contracts.Envelope, bookingService service.BookingService) error {
+	var event contracts.UserSuspended
+	if err := envelope.Decode(&event); err != nil {
+		logrus.WithError(err).Error("Invalid user suspended event")
 		return err
 	}
 
+	userID := event.UserID
+
 	// Get all pending bookings for the user
 	bookings, err := bookingService.GetBookingsByUserID(userID)
 	if err != nil {
@@ -362,4 +567,39 @@ func handleUserSuspended(userEvent map[string]interface{}, bookingService servic
 	}
 
 	return nil
+}
+
+// handleOrganizationMemberSaved handles organization member added and role
+// changed events
+func handleOrganizationMemberSaved(envelope *contracts.Envelope, organizationService service.OrganizationService) error {
+	var state contracts.OrganizationMemberState
+	switch envelope.Type {
+	case contracts.TypeOrganizationMemberAdded:
+		var event contracts.OrganizationMemberAdded
+		if err := envelope.Decode(&event); err != nil {
+			logrus.WithError(err).Error("Invalid organization member added event")
+			return err
+		}
+		state = event.OrganizationMemberState
+	default:
+		var event contracts.OrganizationMemberRoleChanged
+		if err := envelope.Decode(&event); err != nil {
+			logrus.WithError(err).Error("Invalid organization member role changed event")
+			return err
+		}
+		state = event.OrganizationMemberState
+	}
+
+	return organizationService.SaveMember(state.OrganizationID, state.UserID, state.Role)
+}
+
+// handleOrganizationMemberRemoved handles organization member removed events
+func handleOrganizationMemberRemoved(envelope *contracts.Envelope, organizationService service.OrganizationService) error {
+	var event contracts.OrganizationMemberRemoved
+	if err := envelope.Decode(&event); err != nil {
+		logrus.WithError(err).Error("Invalid organization member removed event")
+		return err
+	}
+
+	return organizationService.RemoveMember(event.OrganizationID, event.UserID)
 }
\ No newline at end of file
@@ -0,0 +1,91 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Amendment statuses
+const (
+	AmendmentPendingPayment = "pending_payment"
+	AmendmentCompleted      = "completed"
+	AmendmentFailed         = "failed"
+)
+
+// Amendment item actions
+const (
+	AmendmentActionAdd     = "add"
+	AmendmentActionRemove  = "remove"
+	AmendmentActionUpgrade = "upgrade"
+)
+
+// Amendment payment actions
+const (
+	AmendmentPaymentNone   = "none"
+	AmendmentPaymentAdjust = "adjust" // the booking is not paid yet, so its payment amount changes
+	AmendmentPaymentCharge = "charge"
+	AmendmentPaymentRefund = "refund"
+)
+
+// BookingAmendment represents a change to the tickets of an existing booking
+type BookingAmendment struct {
+	ID              uuid.UUID              `gorm:"type:uuid;primary_key" json:"id"`
+	BookingID       uuid.UUID              `gorm:"type:uuid;not null;index" json:"booking_id"`
+	RequestedBy     uuid.UUID              `gorm:"type:uuid" json:"requested_by"`
+	Status          string                 `gorm:"size:50;not null" json:"status"`         // pending_payment, completed, failed
+	PaymentAction   string                 `gorm:"size:50;not null" json:"payment_action"` // none, adjust, charge, refund
+	PreviousTotal   float64                `gorm:"not null" json:"previous_total"`
+	NewTotal        float64                `gorm:"not null" json:"new_total"`
+	PriceDifference float64                `gorm:"not null" json:"price_difference"`
+	PaymentID       uuid.UUID              `gorm:"type:uuid" json:"payment_id,omitempty"`
+	Error           string                 `gorm:"type:text" json:"error,omitempty"`
+	CreatedAt       time.Time              `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt       time.Time              `gorm:"autoUpdateTime" json:"updated_at"`
+	CompletedAt     *time.Time             `json:"completed_at,omitempty"`
+	Items           []BookingAmendmentItem `gorm:"foreignKey:AmendmentID" json:"items"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (a *BookingAmendment) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+// BookingAmendmentItem is a single ticket change of an amendment. Upgrades
+// record both the released and the new ticket.
+type BookingAmendmentItem struct {
+	ID                 uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	AmendmentID        uuid.UUID `gorm:"type:uuid;not null;index" json:"-"`
+	Action             string    `gorm:"size:50;not null" json:"action"` // add, remove, upgrade
+	TicketID           uuid.UUID `gorm:"type:uuid" json:"ticket_id,omitempty"`
+	TicketType         string    `gorm:"size:100" json:"ticket_type,omitempty"`
+	Price              float64   `json:"price"`
+	PreviousTicketID   uuid.UUID `gorm:"type:uuid" json:"previous_ticket_id,omitempty"`
+	PreviousTicketType string    `gorm:"size:100" json:"previous_ticket_type,omitempty"`
+	PreviousPrice      float64   `json:"previous_price"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (i *BookingAmendmentItem) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return nil
+}
+
+// AmendBookingRequest is the request format for amending a booking
+type AmendBookingRequest struct {
+	Add []struct {
+		Type     string `json:"type" binding:"required"`
+		Quantity int    `json:"quantity" binding:"required"`
+	} `json:"add"`
+	Remove  []uuid.UUID `json:"remove"`
+	Upgrade []struct {
+		TicketID uuid.UUID `json:"ticket_id" binding:"required"`
+		Type     string    `json:"type" binding:"required"`
+	} `json:"upgrade"`
+}
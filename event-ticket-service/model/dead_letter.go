@@ -0,0 +1,26 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// DeadLetter is a message that failed all its delivery attempts and was moved
+// to the dead-letter queue of its consumer
+type DeadLetter struct {
+	MessageID      string          `json:"message_id"`
+	Queue          string          `json:"queue"` // consumer queue the message failed in
+	Exchange       string          `json:"exchange"`
+	RoutingKey     string          `json:"routing_key"`
+	Attempts       int             `json:"attempts"`
+	LastError      string          `json:"last_error"`
+	PublishedAt    time.Time       `json:"published_at"`
+	DeadLetteredAt *time.Time      `json:"dead_lettered_at,omitempty"`
+	Body           json.RawMessage `json:"body"`
+}
+
+// ReplayDeadLetterRequest represents the request body for replaying a dead
+// letter. Body replaces the body of the message when it is set.
+type ReplayDeadLetterRequest struct {
+	Body json.RawMessage `json:"body"`
+}
@@ -10,6 +10,12 @@ import (
 // Event represents an event in the system
 type Event struct {
 	ID          uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	ExternalRef *string   `gorm:"size:255;uniqueIndex" json:"external_ref,omitempty"`
+
+	// OrganizationID is the organization that owns the event, whose members
+	// manage it. Events without one can only be managed by admins.
+	OrganizationID *uuid.UUID `gorm:"type:uuid;index" json:"organization_id,omitempty"`
+
 	Name        string    `gorm:"size:255;not null" json:"name"`
 	Description string    `gorm:"type:text" json:"description"`
 	Location    string    `gorm:"size:255;not null" json:"location"`
@@ -35,6 +41,8 @@ func (e *Event) BeforeCreate(tx *gorm.DB) error {
 // EventResponse is the response format for events
 type EventResponse struct {
 	ID          uuid.UUID      `json:"id"`
+	ExternalRef *string        `json:"external_ref,omitempty"`
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty"`
 	Name        string         `json:"name"`
 	Description string         `json:"description"`
 	Location    string         `json:"location"`
@@ -77,6 +85,10 @@ func (e *Event) ToResponse() EventResponse {
 
 	return EventResponse{
 		ID:          e.ID,
+		ExternalRef: e.ExternalRef,
+
+		OrganizationID: e.OrganizationID,
+
 		Name:        e.Name,
 		Description: e.Description,
 		Location:    e.Location,
@@ -94,6 +106,10 @@ func (e *Event) ToResponse() EventResponse {
 
 // CreateEventRequest is the request format for creating an event
 type CreateEventRequest struct {
+	// OrganizationID is the organization to own the event, required unless
+	// an admin creates the event
+	OrganizationID *uuid.UUID `json:"organization_id"`
+
 	Name        string    `json:"name" binding:"required"`
 	Description string    `json:"description"`
 	Location    string    `json:"location" binding:"required"`
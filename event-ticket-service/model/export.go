@@ -0,0 +1,161 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Export datasets
+const (
+	ExportDatasetAttendees    = "attendees"
+	ExportDatasetBookings     = "bookings"
+	ExportDatasetTransactions = "transactions"
+)
+
+// Export file formats
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatXLSX = "xlsx"
+)
+
+// Export job statuses
+const (
+	ExportJobPending   = "pending"
+	ExportJobRunning   = "running"
+	ExportJobCompleted = "completed"
+	ExportJobFailed    = "failed"
+	ExportJobExpired   = "expired"
+)
+
+// ExportColumns lists the columns available per dataset, in default order
+var ExportColumns = map[string][]string{
+	ExportDatasetAttendees: {
+		"ticket_id", "booking_id", "user_id", "ticket_type", "price", "status", "checked_in_at", "booked_at",
+	},
+	ExportDatasetBookings: {
+		"booking_id", "user_id", "status", "tickets", "ticket_types", "total_price", "payment_id", "created_at", "updated_at",
+	},
+	ExportDatasetTransactions: {
+		"transaction_id", "booking_id", "payment_id", "amendment_id", "user_id", "type", "amount", "currency",
+		"reason", "booking_status", "date",
+	},
+}
+
+// ExportRequest holds the format, columns and filters of an export
+type ExportRequest struct {
+	Format     string    `form:"format,default=csv" json:"format"`
+	Columns    string    `form:"columns" json:"columns,omitempty"` // comma separated
+	Status     string    `form:"status" json:"status,omitempty"`
+	TicketType string    `form:"ticket_type" json:"ticket_type,omitempty"`
+	From       time.Time `form:"from" time_format:"2006-01-02" json:"from,omitempty"`
+	To         time.Time `form:"to" time_format:"2006-01-02" json:"to,omitempty"`
+}
+
+// AttendeeExportRow is a single ticket holder of an event
+type AttendeeExportRow struct {
+	TicketID    uuid.UUID
+	BookingID   uuid.UUID
+	UserID      uuid.UUID
+	TicketType  string
+	Price       float64
+	Status      string
+	CheckedInAt *time.Time
+	BookedAt    time.Time
+}
+
+// BookingExportRow is a single booking of an event with its ticket summary
+type BookingExportRow struct {
+	BookingID   uuid.UUID
+	UserID      uuid.UUID
+	Status      string
+	Tickets     int
+	TicketTypes string
+	TotalPrice  float64
+	PaymentID   uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// TransactionExportRow is a single recorded payment or refund of an event
+// with the status of its booking
+type TransactionExportRow struct {
+	TransactionID uuid.UUID
+	BookingID     uuid.UUID
+	PaymentID     uuid.UUID
+	AmendmentID   *uuid.UUID
+	UserID        uuid.UUID
+	Type          string
+	Amount        float64
+	Currency      string
+	Reason        string
+	BookingStatus string
+	OccurredAt    time.Time
+}
+
+// ExportJob represents an export that is generated in the background
+type ExportJob struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	EventID        uuid.UUID  `gorm:"type:uuid;not null;index" json:"event_id"`
+	RequestedBy    string     `gorm:"size:100" json:"requested_by"`
+	Dataset        string     `gorm:"size:50;not null" json:"dataset"`
+	Format         string     `gorm:"size:10;not null" json:"format"`
+	Params         string     `gorm:"type:text" json:"-"`                                     // JSON encoded ExportRequest
+	Status         string     `gorm:"size:50;not null;default:'pending';index" json:"status"` // pending, running, completed, failed, expired
+	Attempts       int        `gorm:"not null;default:0" json:"-"`
+	LeaseExpiresAt *time.Time `json:"-"` // a running job whose lease expired is claimed again
+	FilePath       string     `gorm:"size:500" json:"-"`
+	RowCount       int        `json:"row_count"`
+	Error          string     `gorm:"type:text" json:"error,omitempty"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt      *time.Time `gorm:"index" json:"expires_at,omitempty"` // when the artifact is deleted
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (j *ExportJob) BeforeCreate(tx *gorm.DB) error {
+	if j.ID == uuid.Nil {
+		j.ID = uuid.New()
+	}
+	return nil
+}
+
+// ExportJobResponse is the response format for export jobs
+type ExportJobResponse struct {
+	ID          uuid.UUID  `json:"id"`
+	EventID     uuid.UUID  `json:"event_id"`
+	Dataset     string     `json:"dataset"`
+	Format      string     `json:"format"`
+	Status      string     `json:"status"`
+	RowCount    int        `json:"row_count"`
+	Error       string     `json:"error,omitempty"`
+	DownloadURL string     `json:"download_url,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// ToResponse converts an ExportJob to ExportJobResponse
+func (j *ExportJob) ToResponse() ExportJobResponse {
+	response := ExportJobResponse{
+		ID:          j.ID,
+		EventID:     j.EventID,
+		Dataset:     j.Dataset,
+		Format:      j.Format,
+		Status:      j.Status,
+		RowCount:    j.RowCount,
+		Error:       j.Error,
+		CreatedAt:   j.CreatedAt,
+		CompletedAt: j.CompletedAt,
+		ExpiresAt:   j.ExpiresAt,
+	}
+
+	// Only completed jobs can be downloaded
+	if j.Status == ExportJobCompleted {
+		response.DownloadURL = "/api/exports/jobs/" + j.ID.String() + "/download"
+	}
+
+	return response
+}
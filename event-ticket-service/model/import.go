@@ -0,0 +1,101 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Import file formats
+const (
+	ImportFormatCSV   = "csv"
+	ImportFormatJSONL = "jsonl"
+)
+
+// Import row actions
+const (
+	ImportActionCreate    = "create"
+	ImportActionUpdate    = "update"
+	ImportActionUnchanged = "unchanged"
+)
+
+// ImportCSVColumns lists the columns of a CSV import file. Tickets are
+// encoded as type:price:quantity entries separated by semicolons.
+var ImportCSVColumns = []string{
+	"external_ref", "name", "description", "location", "start_date", "end_date",
+	"category", "organizer", "image_url", "status", "tickets",
+}
+
+// ImportTicketType is a ticket type of an imported event
+type ImportTicketType struct {
+	Type     string  `json:"type"`
+	Price    float64 `json:"price"`
+	Quantity int     `json:"quantity"`
+}
+
+// ImportEventRecord is a single event of an import file
+type ImportEventRecord struct {
+	ExternalRef string             `json:"external_ref"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Location    string             `json:"location"`
+	StartDate   time.Time          `json:"start_date"`
+	EndDate     time.Time          `json:"end_date"`
+	Category    string             `json:"category"`
+	Organizer   string             `json:"organizer"`
+	ImageURL    string             `json:"image_url"`
+	Status      string             `json:"status"`
+	Tickets     []ImportTicketType `json:"tickets"`
+}
+
+// ImportRowError lists the problems found in a single row of an import file
+type ImportRowError struct {
+	Row         int      `json:"row"`
+	ExternalRef string   `json:"external_ref,omitempty"`
+	Errors      []string `json:"errors"`
+}
+
+// ImportRowResult is the outcome of a single row of an import file
+type ImportRowResult struct {
+	Row         int       `json:"row"`
+	ExternalRef string    `json:"external_ref"`
+	EventID     uuid.UUID `json:"event_id,omitempty"`
+	Action      string    `json:"action"` // create, update, unchanged
+}
+
+// ImportResult is the response format for an event import
+type ImportResult struct {
+	DryRun    bool              `json:"dry_run"`
+	Applied   bool              `json:"applied"`
+	Total     int               `json:"total"`
+	Created   int               `json:"created"`
+	Updated   int               `json:"updated"`
+	Unchanged int               `json:"unchanged"`
+	Rows      []ImportRowResult `json:"rows,omitempty"`
+	Errors    []ImportRowError  `json:"errors,omitempty"`
+}
+
+// TicketInventory holds the ticket counts of a ticket type of an event
+type TicketInventory struct {
+	EventID   uuid.UUID
+	Type      string
+	Price     float64
+	Total     int
+	Available int
+}
+
+// ImportTicketChange describes how the tickets of a type are brought to the imported quantity
+type ImportTicketChange struct {
+	Type    string
+	Price   float64
+	Add     int
+	Remove  int
+	Reprice bool
+}
+
+// ImportEventChange is a validated event upsert waiting to be applied
+type ImportEventChange struct {
+	Event   *Event
+	Create  bool
+	Tickets []ImportTicketChange
+}
@@ -0,0 +1,99 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/ticket-system/contracts"
+)
+
+// Permissions members of an organization can hold on its events
+const (
+	PermissionManageEvents   = "events:manage"    // create, update and delete events and their refund policies
+	PermissionViewReports    = "reports:view"     // view sales reports
+	PermissionExportData     = "data:export"      // export attendee and booking data
+	PermissionCheckInTickets = "tickets:check_in" // check in tickets at the venue
+	PermissionCreateBookings = "bookings:create"  // book tickets on behalf of customers, only held by API keys
+)
+
+// Scopes the API keys of an organization can be granted
+const (
+	APIKeyScopeEventsRead     = "events:read"
+	APIKeyScopeBookingsCreate = "bookings:create"
+	APIKeyScopeTicketsScan    = "tickets:scan"
+)
+
+// APIKeyRole is the role of the access tokens the user service issues for
+// API keys
+const APIKeyRole = "api_key"
+
+// scopePermissions are the permissions each API key scope grants on the
+// events of the organization of the key
+var scopePermissions = map[string][]string{
+	APIKeyScopeBookingsCreate: {PermissionCreateBookings},
+	APIKeyScopeTicketsScan:    {PermissionCheckInTickets},
+}
+
+// rolePermissions are the permissions of each role of organization members
+var rolePermissions = map[string][]string{
+	contracts.OrganizationRoleOwner:     {PermissionManageEvents, PermissionViewReports, PermissionExportData, PermissionCheckInTickets},
+	contracts.OrganizationRoleManager:   {PermissionManageEvents, PermissionViewReports, PermissionExportData, PermissionCheckInTickets},
+	contracts.OrganizationRoleBoxOffice: {PermissionViewReports, PermissionCheckInTickets},
+	contracts.OrganizationRoleScanner:   {PermissionCheckInTickets},
+}
+
+// HasPermission reports whether members with role hold permission
+func HasPermission(role, permission string) bool {
+	for _, granted := range rolePermissions[role] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// OrganizationMember is the membership of a user in an organization, kept
+// in sync with the organization events of the user service
+type OrganizationMember struct {
+	OrganizationID uuid.UUID `gorm:"type:uuid;primary_key" json:"organization_id"`
+	UserID         uuid.UUID `gorm:"type:uuid;primary_key;index" json:"user_id"`
+	Role           string    `gorm:"size:20;not null" json:"role"`
+	UpdatedAt      time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// Actor is the user or API key a request is made by. OrganizationID and
+// Scopes are only set for API keys.
+type Actor struct {
+	UserID         uuid.UUID
+	Role           string
+	OrganizationID *uuid.UUID
+	Scopes         []string
+}
+
+// IsAdmin reports whether the actor is a platform admin, who holds every
+// permission on every event
+func (a Actor) IsAdmin() bool {
+	return a.Role == "admin"
+}
+
+// IsAPIKey reports whether the actor is an API key of an organization
+func (a Actor) IsAPIKey() bool {
+	return a.Role == APIKeyRole
+}
+
+// HasScopePermission reports whether the scopes of an API key grant
+// permission on the events of organizationID
+func (a Actor) HasScopePermission(organizationID uuid.UUID, permission string) bool {
+	if !a.IsAPIKey() || a.OrganizationID == nil || *a.OrganizationID != organizationID {
+		return false
+	}
+
+	for _, scope := range a.Scopes {
+		for _, granted := range scopePermissions[scope] {
+			if granted == permission {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// PrivacyService is the name the event and ticket service answers data
+// requests of the user service with
+const PrivacyService = "event-ticket-service"
+
+// PrivacyDataExport is the personal data the event and ticket service holds
+// about a user
+type PrivacyDataExport struct {
+	Bookings      []BookingResponse    `json:"bookings"`
+	Amendments    []BookingAmendment   `json:"amendments"`
+	Organizations []OrganizationMember `json:"organizations"`
+	ExportedAt    time.Time            `json:"exported_at"`
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RefundPolicy is the organiser-defined refund policy of an event, evaluated
+// when a booking is cancelled. Without a policy bookings are fully refunded.
+type RefundPolicy struct {
+	ID               uuid.UUID          `gorm:"type:uuid;primary_key" json:"id"`
+	EventID          uuid.UUID          `gorm:"type:uuid;not null;uniqueIndex" json:"event_id"`
+	DeadlineHours    int                `gorm:"not null;default:0" json:"deadline_hours"`     // no refund when cancelled later than this many hours before the event
+	NonRefundableFee float64            `gorm:"not null;default:0" json:"non_refundable_fee"` // kept per ticket
+	Tiers            []RefundPolicyTier `gorm:"foreignKey:PolicyID" json:"tiers"`
+	CreatedAt        time.Time          `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt        time.Time          `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (p *RefundPolicy) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+// RefundPolicyTier refunds a percentage of the booking when it is cancelled
+// at least HoursBeforeEvent hours before the event starts
+type RefundPolicyTier struct {
+	ID               uuid.UUID `gorm:"type:uuid;primary_key" json:"-"`
+	PolicyID         uuid.UUID `gorm:"type:uuid;not null;index" json:"-"`
+	HoursBeforeEvent int       `gorm:"not null" json:"hours_before_event"`
+	Percentage       float64   `gorm:"not null" json:"percentage"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (t *RefundPolicyTier) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
+
+// RefundPolicyRequest is the request format for setting the refund policy of an event
+type RefundPolicyRequest struct {
+	DeadlineHours    int     `json:"deadline_hours" binding:"min=0"`
+	NonRefundableFee float64 `json:"non_refundable_fee" binding:"min=0"`
+	Tiers            []struct {
+		HoursBeforeEvent int     `json:"hours_before_event" binding:"min=0"`
+		Percentage       float64 `json:"percentage" binding:"min=0,max=100"`
+	} `json:"tiers" binding:"required,min=1,dive"`
+}
+
+// RefundQuote is the refund a booking gets when it is cancelled
+type RefundQuote struct {
+	BookingID        uuid.UUID `json:"booking_id"`
+	EventID          uuid.UUID `json:"event_id"`
+	TotalPrice       float64   `json:"total_price"`
+	NonRefundableFee float64   `json:"non_refundable_fee"`
+	Percentage       float64   `json:"percentage"`
+	RefundAmount     float64   `json:"refund_amount"`
+	Reason           string    `json:"reason"`
+}
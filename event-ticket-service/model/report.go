@@ -0,0 +1,111 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Report time buckets
+const (
+	ReportBucketDay   = "day"
+	ReportBucketWeek  = "week"
+	ReportBucketMonth = "month"
+)
+
+// EventReportState tracks whether the rollups of an event need to be rebuilt
+type EventReportState struct {
+	EventID     uuid.UUID `gorm:"type:uuid;primary_key" json:"event_id"`
+	Stale       bool      `gorm:"not null;default:true;index" json:"stale"`
+	MarkedAt    time.Time `json:"marked_at"`
+	RefreshedAt time.Time `json:"refreshed_at"`
+}
+
+// TicketTypeRollup holds pre-aggregated inventory counts for a ticket type of an event
+type TicketTypeRollup struct {
+	EventID     uuid.UUID `gorm:"type:uuid;primary_key" json:"event_id"`
+	TicketType  string    `gorm:"size:100;primary_key" json:"ticket_type"`
+	Price       float64   `gorm:"not null" json:"price"`
+	Total       int       `gorm:"not null" json:"total"`
+	Available   int       `gorm:"not null" json:"available"`
+	Reserved    int       `gorm:"not null" json:"reserved"`
+	Sold        int       `gorm:"not null" json:"sold"`
+	CheckedIn   int       `gorm:"not null" json:"checked_in"`
+	SoldRevenue float64   `gorm:"not null" json:"sold_revenue"`
+}
+
+// RevenueRollup holds pre-aggregated booking figures for an event on a single day
+type RevenueRollup struct {
+	EventID        uuid.UUID `gorm:"type:uuid;primary_key" json:"event_id"`
+	Day            time.Time `gorm:"type:date;primary_key" json:"day"`
+	Bookings       int       `gorm:"not null" json:"bookings"`
+	Confirmed      int       `gorm:"not null" json:"confirmed"`
+	Cancelled      int       `gorm:"not null" json:"cancelled"`
+	Refunded       int       `gorm:"not null" json:"refunded"`
+	GrossRevenue   float64   `gorm:"not null" json:"gross_revenue"`
+	RefundedAmount float64   `gorm:"not null" json:"refunded_amount"`
+}
+
+// SalesReportRequest is the request format for an event sales report
+type SalesReportRequest struct {
+	Bucket string    `form:"bucket,default=day"`
+	From   time.Time `form:"from" time_format:"2006-01-02"`
+	To     time.Time `form:"to" time_format:"2006-01-02"`
+}
+
+// TicketTypeSales is the sales breakdown of a single ticket type
+type TicketTypeSales struct {
+	Type        string  `json:"type"`
+	Price       float64 `json:"price"`
+	Total       int     `json:"total"`
+	Available   int     `json:"available"`
+	Reserved    int     `json:"reserved"`
+	Sold        int     `json:"sold"`
+	CheckedIn   int     `json:"checked_in"`
+	SoldRevenue float64 `json:"sold_revenue"`
+}
+
+// RevenueBucket is the revenue of an event within one time bucket
+type RevenueBucket struct {
+	Start          time.Time `json:"start"`
+	Bookings       int       `json:"bookings"`
+	GrossRevenue   float64   `json:"gross_revenue"`
+	RefundedAmount float64   `json:"refunded_amount"`
+	NetRevenue     float64   `json:"net_revenue"`
+}
+
+// SalesTotals sums up inventory and revenue over all ticket types
+type SalesTotals struct {
+	Tickets        int     `json:"tickets"`
+	Available      int     `json:"available"`
+	Reserved       int     `json:"reserved"`
+	Sold           int     `json:"sold"`
+	CheckedIn      int     `json:"checked_in"`
+	Bookings       int     `json:"bookings"`
+	Cancelled      int     `json:"cancelled"`
+	Refunded       int     `json:"refunded"`
+	GrossRevenue   float64 `json:"gross_revenue"`
+	RefundedAmount float64 `json:"refunded_amount"`
+	NetRevenue     float64 `json:"net_revenue"`
+}
+
+// SalesRates holds the ratios derived from the totals
+type SalesRates struct {
+	SellThrough  float64 `json:"sell_through"`
+	Cancellation float64 `json:"cancellation"`
+	Refund       float64 `json:"refund"`
+	CheckIn      float64 `json:"check_in"`
+}
+
+// EventSalesReport is the response format for an event sales report
+type EventSalesReport struct {
+	EventID     uuid.UUID         `json:"event_id"`
+	EventName   string            `json:"event_name"`
+	Bucket      string            `json:"bucket"`
+	TicketTypes []TicketTypeSales `json:"ticket_types"`
+	Revenue     []RevenueBucket   `json:"revenue"`
+	Totals      SalesTotals       `json:"totals"`
+	Rates       SalesRates        `json:"rates"`
+	Stale       bool              `json:"stale"`
+	RefreshedAt time.Time         `json:"refreshed_at"`
+}
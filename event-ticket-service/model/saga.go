@@ -0,0 +1,80 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Booking saga statuses
+const (
+	SagaRunning      = "running"
+	SagaCompleted    = "completed"
+	SagaCompensating = "compensating"
+	SagaCompensated  = "compensated"
+	SagaFailed       = "failed"
+)
+
+// Booking saga steps, in the order they run. Compensation releases the
+// tickets and, when the payment was captured anyway, refunds it.
+const (
+	SagaStepReserveTickets = "reserve_tickets"
+	SagaStepCreatePayment  = "create_payment"
+	SagaStepCapturePayment = "capture_payment"
+	SagaStepConfirmBooking = "confirm_booking"
+	SagaStepNotify         = "notify"
+	SagaStepReleaseTickets = "release_tickets"
+	SagaStepRefundPayment  = "refund_payment"
+)
+
+// Booking saga step outcomes
+const (
+	SagaStepCompleted   = "completed"
+	SagaStepFailed      = "failed"
+	SagaStepCompensated = "compensated"
+)
+
+// BookingSaga tracks the steps that take a booking from reserved tickets to
+// a confirmed, paid booking
+type BookingSaga struct {
+	ID            uuid.UUID         `gorm:"type:uuid;primary_key" json:"id"`
+	BookingID     uuid.UUID         `gorm:"type:uuid;not null;uniqueIndex" json:"booking_id"`
+	Status        string            `gorm:"size:50;not null;index" json:"status"` // running, completed, compensating, compensated, failed
+	Step          string            `gorm:"size:50" json:"step,omitempty"`        // the step waiting to complete
+	StepStartedAt time.Time         `gorm:"index" json:"step_started_at"`
+	StepDeadline  *time.Time        `gorm:"-" json:"step_deadline,omitempty"`
+	PaymentID     *uuid.UUID        `gorm:"type:uuid" json:"payment_id,omitempty"`
+	Amount        float64           `gorm:"not null" json:"amount"`
+	LastError     string            `gorm:"type:text" json:"last_error,omitempty"`
+	CreatedAt     time.Time         `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt     time.Time         `gorm:"autoUpdateTime" json:"updated_at"`
+	CompletedAt   *time.Time        `json:"completed_at,omitempty"`
+	Steps         []BookingSagaStep `gorm:"foreignKey:SagaID" json:"steps,omitempty"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (s *BookingSaga) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+// BookingSagaStep records the outcome of a step of a booking saga
+type BookingSagaStep struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	SagaID    uuid.UUID `gorm:"type:uuid;not null;index" json:"saga_id"`
+	Step      string    `gorm:"size:50;not null" json:"step"`
+	Status    string    `gorm:"size:50;not null" json:"status"` // completed, failed, compensated
+	Detail    string    `gorm:"type:text" json:"detail,omitempty"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (s *BookingSagaStep) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
@@ -9,15 +9,16 @@ import (
 
 // Ticket represents a ticket for an event
 type Ticket struct {
-	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
-	EventID   uuid.UUID `gorm:"type:uuid;not null" json:"event_id"`
-	Type      string    `gorm:"size:100;not null" json:"type"`
-	Price     float64   `gorm:"not null" json:"price"`
-	Status    string    `gorm:"size:50;not null;default:'available'" json:"status"` // available, reserved, sold, cancelled
-	UserID    uuid.UUID `gorm:"type:uuid" json:"user_id"`
-	BookingID uuid.UUID `gorm:"type:uuid" json:"booking_id"`
-	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	EventID     uuid.UUID  `gorm:"type:uuid;not null" json:"event_id"`
+	Type        string     `gorm:"size:100;not null" json:"type"`
+	Price       float64    `gorm:"not null" json:"price"`
+	Status      string     `gorm:"size:50;not null;default:'available'" json:"status"` // available, reserved, sold, cancelled
+	UserID      uuid.UUID  `gorm:"type:uuid" json:"user_id"`
+	BookingID   uuid.UUID  `gorm:"type:uuid" json:"booking_id"`
+	CheckedInAt *time.Time `json:"checked_in_at"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
 }
 
 // BeforeCreate will set a UUID rather than numeric ID
@@ -30,29 +31,31 @@ func (t *Ticket) BeforeCreate(tx *gorm.DB) error {
 
 // TicketResponse is the response format for tickets
 type TicketResponse struct {
-	ID        uuid.UUID `json:"id"`
-	EventID   uuid.UUID `json:"event_id"`
-	Type      string    `json:"type"`
-	Price     float64   `json:"price"`
-	Status    string    `json:"status"`
-	UserID    uuid.UUID `json:"user_id,omitempty"`
-	BookingID uuid.UUID `json:"booking_id,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID          uuid.UUID  `json:"id"`
+	EventID     uuid.UUID  `json:"event_id"`
+	Type        string     `json:"type"`
+	Price       float64    `json:"price"`
+	Status      string     `json:"status"`
+	UserID      uuid.UUID  `json:"user_id,omitempty"`
+	BookingID   uuid.UUID  `json:"booking_id,omitempty"`
+	CheckedInAt *time.Time `json:"checked_in_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
 }
 
 // ToResponse converts a Ticket to TicketResponse
 func (t *Ticket) ToResponse() TicketResponse {
 	return TicketResponse{
-		ID:        t.ID,
-		EventID:   t.EventID,
-		Type:      t.Type,
-		Price:     t.Price,
-		Status:    t.Status,
-		UserID:    t.UserID,
-		BookingID: t.BookingID,
-		CreatedAt: t.CreatedAt,
-		UpdatedAt: t.UpdatedAt,
+		ID:          t.ID,
+		EventID:     t.EventID,
+		Type:        t.Type,
+		Price:       t.Price,
+		Status:      t.Status,
+		UserID:      t.UserID,
+		BookingID:   t.BookingID,
+		CheckedInAt: t.CheckedInAt,
+		CreatedAt:   t.CreatedAt,
+		UpdatedAt:   t.UpdatedAt,
 	}
 }
 
@@ -128,4 +131,4 @@ type CreateBookingRequest struct {
 // UpdateBookingStatusRequest is the request format for updating a booking status
 type UpdateBookingStatusRequest struct {
 	Status string `json:"status" binding:"required"`
-}
\ No newline at end of file
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Booking transaction types
+const (
+	TransactionTypePayment = "payment"
+	TransactionTypeRefund  = "refund"
+)
+
+// BookingTransaction is a captured payment or a refund of a booking, recorded
+// from the events of the payment service. The ID is the ID of the payment or
+// refund, so a redelivered event is recorded once.
+type BookingTransaction struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	Type        string     `gorm:"size:20;not null" json:"type"` // payment, refund
+	BookingID   uuid.UUID  `gorm:"type:uuid;not null;index" json:"booking_id"`
+	EventID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"event_id"`
+	UserID      uuid.UUID  `gorm:"type:uuid" json:"user_id"`
+	PaymentID   uuid.UUID  `gorm:"type:uuid;not null" json:"payment_id"`
+	AmendmentID *uuid.UUID `gorm:"type:uuid" json:"amendment_id,omitempty"`
+	Amount      float64    `gorm:"not null" json:"amount"` // negative for refunds
+	Currency    string     `gorm:"size:3" json:"currency"`
+	Reason      string     `gorm:"size:255" json:"reason,omitempty"`
+	OccurredAt  time.Time  `gorm:"not null;index" json:"occurred_at"`
+}
@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/ticket-system/event-ticket-service/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AmendmentRepository defines the interface for booking amendment repository operations
+type AmendmentRepository interface {
+	Create(booking *model.Booking, amendment *model.BookingAmendment) error
+	Complete(booking *model.Booking, amendment *model.BookingAmendment) error
+	Fail(amendment *model.BookingAmendment) error
+	FindByID(id uuid.UUID) (*model.BookingAmendment, error)
+	FindByIDForUpdate(id uuid.UUID) (*model.BookingAmendment, error)
+	FindByBookingID(bookingID uuid.UUID) ([]model.BookingAmendment, error)
+	FindPendingByBookingID(bookingID uuid.UUID) (*model.BookingAmendment, error)
+	WithTx(tx *gorm.DB) AmendmentRepository
+}
+
+// amendmentRepository implements AmendmentRepository interface
+type amendmentRepository struct {
+	db *gorm.DB
+}
+
+// NewAmendmentRepository creates a new amendment repository
+func NewAmendmentRepository(db *gorm.DB) AmendmentRepository {
+	// Auto migrate the models
+	db.AutoMigrate(&model.BookingAmendment{}, &model.BookingAmendmentItem{})
+
+	return &amendmentRepository{
+		db: db,
+	}
+}
+
+// WithTx returns a repository that runs its operations in tx
+func (r *amendmentRepository) WithTx(tx *gorm.DB) AmendmentRepository {
+	return &amendmentRepository{
+		db: tx,
+	}
+}
+
+// Create holds the new tickets of an amendment, which the caller locked, and
+// saves it with its items. Completed amendments are applied to the booking in
+// the same transaction.
+func (r *amendmentRepository) Create(booking *model.Booking, amendment *model.BookingAmendment) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Hold the ticket chosen for every new ticket
+		for _, item := range amendment.Items {
+			if item.Action == model.AmendmentActionRemove {
+				continue
+			}
+
+			result := tx.Model(&model.Ticket{}).
+				Where("id = ? AND status = 'available'", item.TicketID).
+				Updates(map[string]interface{}{
+					"status":     "reserved",
+					"user_id":    booking.UserID,
+					"booking_id": booking.ID,
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return fmt.Errorf("not enough tickets available for type %s", item.TicketType)
+			}
+		}
+
+		if err := tx.Create(amendment).Error; err != nil {
+			return err
+		}
+
+		if amendment.Status == model.AmendmentCompleted {
+			return applyAmendment(tx, booking, amendment)
+		}
+		return nil
+	})
+}
+
+// Complete applies a held amendment to its booking
+func (r *amendmentRepository) Complete(booking *model.Booking, amendment *model.BookingAmendment) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return applyAmendment(tx, booking, amendment)
+	})
+}
+
+// Fail releases the tickets held for an amendment and saves it
+func (r *amendmentRepository) Fail(amendment *model.BookingAmendment) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if held := amendmentTicketIDs(amendment, false); len(held) > 0 {
+			if err := tx.Model(&model.Ticket{}).
+				Where("id IN ? AND booking_id = ? AND status = 'reserved'", held, amendment.BookingID).
+				Updates(map[string]interface{}{
+					"status":        "available",
+					"user_id":       uuid.Nil,
+					"booking_id":    uuid.Nil,
+					"checked_in_at": nil,
+				}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Omit("Items").Save(amendment).Error
+	})
+}
+
+// FindByID finds an amendment by ID
+func (r *amendmentRepository) FindByID(id uuid.UUID) (*model.BookingAmendment, error) {
+	var amendment model.BookingAmendment
+	result := r.db.Preload("Items").First(&amendment, "id = ?", id)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &amendment, nil
+}
+
+// FindByIDForUpdate finds an amendment by ID and locks its row until the
+// transaction ends
+func (r *amendmentRepository) FindByIDForUpdate(id uuid.UUID) (*model.BookingAmendment, error) {
+	var amendment model.BookingAmendment
+	result := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Items").First(&amendment, "id = ?", id)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &amendment, nil
+}
+
+// FindByBookingID finds the amendments of a booking, oldest first
+func (r *amendmentRepository) FindByBookingID(bookingID uuid.UUID) ([]model.BookingAmendment, error) {
+	var amendments []model.BookingAmendment
+	result := r.db.Preload("Items").Where("booking_id = ?", bookingID).Order("created_at ASC").Find(&amendments)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return amendments, nil
+}
+
+// FindPendingByBookingID finds the amendment of a booking that is waiting for payment
+func (r *amendmentRepository) FindPendingByBookingID(bookingID uuid.UUID) (*model.BookingAmendment, error) {
+	var amendment model.BookingAmendment
+	result := r.db.Preload("Items").
+		Where("booking_id = ? AND status = ?", bookingID, model.AmendmentPendingPayment).
+		First(&amendment)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &amendment, nil
+}
+
+// applyAmendment moves the held tickets into the booking, releases the
+// replaced ones and updates the booking total
+func applyAmendment(tx *gorm.DB, booking *model.Booking, amendment *model.BookingAmendment) error {
+	// New tickets follow the status of the booking
+	ticketStatus := "reserved"
+	if booking.Status == "confirmed" {
+		ticketStatus = "sold"
+	}
+
+	if added := amendmentTicketIDs(amendment, false); len(added) > 0 {
+		result := tx.Model(&model.Ticket{}).
+			Where("id IN ? AND booking_id = ? AND status = 'reserved'", added, booking.ID).
+			Update("status", ticketStatus)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != int64(len(added)) {
+			return fmt.Errorf("tickets held for amendment %s are no longer reserved", amendment.ID)
+		}
+	}
+
+	if released := amendmentTicketIDs(amendment, true); len(released) > 0 {
+		if err := tx.Model(&model.Ticket{}).
+			Where("id IN ? AND booking_id = ?", released, booking.ID).
+			Updates(map[string]interface{}{
+				"status":        "available",
+				"user_id":       uuid.Nil,
+				"booking_id":    uuid.Nil,
+				"checked_in_at": nil,
+			}).Error; err != nil {
+			return err
+		}
+	}
+
+	booking.TotalPrice = amendment.NewTotal
+	if err := tx.Omit("Tickets").Save(booking).Error; err != nil {
+		return err
+	}
+
+	return tx.Omit("Items").Save(amendment).Error
+}
+
+// amendmentTicketIDs returns the released tickets of an amendment, or the new ones
+func amendmentTicketIDs(amendment *model.BookingAmendment, released bool) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(amendment.Items))
+	for _, item := range amendment.Items {
+		switch {
+		case released && item.Action != model.AmendmentActionAdd:
+			ids = append(ids, item.PreviousTicketID)
+		case !released && item.Action != model.AmendmentActionRemove:
+			ids = append(ids, item.TicketID)
+		}
+	}
+	return ids
+}
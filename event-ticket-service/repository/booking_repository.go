@@ -4,16 +4,19 @@ import (
 	"github.com/google/uuid"
 	"github.com/yourusername/ticket-system/event-ticket-service/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // BookingRepository defines the interface for booking repository operations
 type BookingRepository interface {
 	Create(booking *model.Booking) error
 	FindByID(id uuid.UUID) (*model.Booking, error)
+	FindByIDForUpdate(id uuid.UUID) (*model.Booking, error)
 	FindByUserID(userID uuid.UUID, page, pageSize int) ([]model.Booking, int64, error)
 	FindByEventID(eventID uuid.UUID, page, pageSize int) ([]model.Booking, int64, error)
 	Update(booking *model.Booking) error
 	Delete(id uuid.UUID) error
+	WithTx(tx *gorm.DB) BookingRepository
 }
 
 // bookingRepository implements BookingRepository interface
@@ -31,6 +34,13 @@ func NewBookingRepository(db *gorm.DB) BookingRepository {
 	}
 }
 
+// WithTx returns a repository that runs its operations in tx
+func (r *bookingRepository) WithTx(tx *gorm.DB) BookingRepository {
+	return &bookingRepository{
+		db: tx,
+	}
+}
+
 // Create creates a new booking
 func (r *bookingRepository) Create(booking *model.Booking) error {
 	return r.db.Create(booking).Error
@@ -49,6 +59,25 @@ func (r *bookingRepository) FindByID(id uuid.UUID) (*model.Booking, error) {
 	return &booking, nil
 }
 
+// FindByIDForUpdate finds a booking by ID and locks it until the end of the
+// transaction, so that concurrent changes of the booking run one after another
+func (r *bookingRepository) FindByIDForUpdate(id uuid.UUID) (*model.Booking, error) {
+	var booking model.Booking
+	result := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&booking, "id = ?", id)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+
+	// Load the tickets after the lock is held
+	if err := r.db.Where("booking_id = ?", id).Find(&booking.Tickets).Error; err != nil {
+		return nil, err
+	}
+	return &booking, nil
+}
+
 // FindByUserID finds bookings by user ID with pagination
 func (r *bookingRepository) FindByUserID(userID uuid.UUID, page, pageSize int) ([]model.Booking, int64, error) {
 	var bookings []model.Booking
@@ -17,6 +17,10 @@ type EventRepository interface {
 	Delete(id uuid.UUID) error
 	Search(keyword, category, location string, startDate, endDate time.Time, page, pageSize int) ([]model.Event, int64, error)
 	FindAll(page, pageSize int) ([]model.Event, int64, error)
+	FindByExternalRefs(refs []string) ([]model.Event, error)
+	FindTicketInventory(eventIDs []uuid.UUID) ([]model.TicketInventory, error)
+	ApplyImport(changes []model.ImportEventChange) error
+	WithTx(tx *gorm.DB) EventRepository
 }
 
 // eventRepository implements EventRepository interface
@@ -34,6 +38,13 @@ func NewEventRepository(db *gorm.DB) EventRepository {
 	}
 }
 
+// WithTx returns a repository that runs its operations in tx
+func (r *eventRepository) WithTx(tx *gorm.DB) EventRepository {
+	return &eventRepository{
+		db: tx,
+	}
+}
+
 // Create creates a new event
 func (r *eventRepository) Create(event *model.Event) error {
 	return r.db.Create(event).Error
@@ -120,4 +131,94 @@ func (r *eventRepository) FindAll(page, pageSize int) ([]model.Event, int64, err
 	}
 
 	return events, total, nil
-}
\ No newline at end of file
+}
+
+// FindByExternalRefs finds events by their external reference IDs
+func (r *eventRepository) FindByExternalRefs(refs []string) ([]model.Event, error) {
+	var events []model.Event
+	if len(refs) == 0 {
+		return events, nil
+	}
+	result := r.db.Where("external_ref IN ?", refs).Find(&events)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return events, nil
+}
+
+// FindTicketInventory counts the tickets per type of the given events
+func (r *eventRepository) FindTicketInventory(eventIDs []uuid.UUID) ([]model.TicketInventory, error) {
+	var inventory []model.TicketInventory
+	if len(eventIDs) == 0 {
+		return inventory, nil
+	}
+	result := r.db.Model(&model.Ticket{}).
+		Select(`event_id, type, MAX(price) AS price, COUNT(*) AS total,
+			SUM(CASE WHEN status = 'available' THEN 1 ELSE 0 END) AS available`).
+		Where("event_id IN ?", eventIDs).
+		Group("event_id, type").
+		Scan(&inventory)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return inventory, nil
+}
+
+// ApplyImport creates or updates the imported events and their tickets in a single transaction
+func (r *eventRepository) ApplyImport(changes []model.ImportEventChange) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, change := range changes {
+			// Save event
+			if change.Create {
+				if err := tx.Create(change.Event).Error; err != nil {
+					return fmt.Errorf("failed to create event %s: %w", *change.Event.ExternalRef, err)
+				}
+			} else if err := tx.Omit("Tickets").Save(change.Event).Error; err != nil {
+				return fmt.Errorf("failed to update event %s: %w", *change.Event.ExternalRef, err)
+			}
+
+			for _, ticketChange := range change.Tickets {
+				// Reprice the tickets that have not been sold yet
+				if ticketChange.Reprice {
+					err := tx.Model(&model.Ticket{}).
+						Where("event_id = ? AND type = ? AND status = 'available'", change.Event.ID, ticketChange.Type).
+						Update("price", ticketChange.Price).Error
+					if err != nil {
+						return fmt.Errorf("failed to reprice %s tickets: %w", ticketChange.Type, err)
+					}
+				}
+
+				// Add missing tickets
+				if ticketChange.Add > 0 {
+					tickets := make([]*model.Ticket, ticketChange.Add)
+					for i := range tickets {
+						tickets[i] = &model.Ticket{
+							EventID: change.Event.ID,
+							Type:    ticketChange.Type,
+							Price:   ticketChange.Price,
+							Status:  "available",
+						}
+					}
+					if err := tx.CreateInBatches(tickets, 500).Error; err != nil {
+						return fmt.Errorf("failed to create %s tickets: %w", ticketChange.Type, err)
+					}
+				}
+
+				// Remove surplus tickets, only ever touching available ones
+				if ticketChange.Remove > 0 {
+					result := tx.Where(`id IN (SELECT id FROM tickets
+						WHERE event_id = ? AND type = ? AND status = 'available' LIMIT ?)`,
+						change.Event.ID, ticketChange.Type, ticketChange.Remove).
+						Delete(&model.Ticket{})
+					if result.Error != nil {
+						return fmt.Errorf("failed to remove %s tickets: %w", ticketChange.Type, result.Error)
+					}
+					if result.RowsAffected != int64(ticketChange.Remove) {
+						return fmt.Errorf("not enough available %s tickets to remove for event %s", ticketChange.Type, *change.Event.ExternalRef)
+					}
+				}
+			}
+		}
+		return nil
+	})
+}
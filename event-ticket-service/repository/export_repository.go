@@ -0,0 +1,269 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/ticket-system/event-ticket-service/model"
+	"gorm.io/gorm"
+)
+
+// ExportRepository defines the interface for export repository operations
+type ExportRepository interface {
+	StreamAttendees(eventID uuid.UUID, filter model.ExportRequest, fn func(row model.AttendeeExportRow) error) error
+	StreamBookings(eventID uuid.UUID, filter model.ExportRequest, fn func(row model.BookingExportRow) error) error
+	StreamTransactions(eventID uuid.UUID, filter model.ExportRequest, fn func(row model.TransactionExportRow) error) error
+	CreateJob(job *model.ExportJob) error
+	FindJobByID(id uuid.UUID) (*model.ExportJob, error)
+	FindRunnableJobIDs(now time.Time, limit int) ([]uuid.UUID, error)
+	ClaimJob(id uuid.UUID, now time.Time, leaseUntil time.Time) (bool, error)
+	RenewJobLease(id uuid.UUID, attempt int, leaseUntil time.Time) (bool, error)
+	FinishJob(job *model.ExportJob, attempt int) (bool, error)
+	FindExpiredJobs(now time.Time, limit int) ([]model.ExportJob, error)
+	ExpireJob(id uuid.UUID) error
+}
+
+// exportRepository implements ExportRepository interface
+type exportRepository struct {
+	db *gorm.DB
+}
+
+// NewExportRepository creates a new export repository
+func NewExportRepository(db *gorm.DB) ExportRepository {
+	// Auto migrate the models
+	db.AutoMigrate(&model.ExportJob{})
+
+	return &exportRepository{
+		db: db,
+	}
+}
+
+// StreamAttendees calls fn for every booked ticket of an event, one row at a time
+func (r *exportRepository) StreamAttendees(eventID uuid.UUID, filter model.ExportRequest, fn func(row model.AttendeeExportRow) error) error {
+	query := r.db.Table("tickets AS t").
+		Select(`t.id AS ticket_id, t.booking_id, t.user_id, t.type AS ticket_type, t.price, t.status,
+			t.checked_in_at, b.created_at AS booked_at`).
+		Joins("JOIN bookings AS b ON b.id = t.booking_id").
+		Where("t.event_id = ?", eventID)
+
+	// Apply filters
+	if filter.Status != "" {
+		query = query.Where("t.status = ?", filter.Status)
+	} else {
+		query = query.Where("t.status IN ?", []string{"reserved", "sold"})
+	}
+	if filter.TicketType != "" {
+		query = query.Where("t.type = ?", filter.TicketType)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("b.created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("b.created_at < ?", filter.To.AddDate(0, 0, 1))
+	}
+
+	rows, err := query.Order("b.created_at ASC, t.id ASC").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row model.AttendeeExportRow
+		if err := r.db.ScanRows(rows, &row); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// StreamBookings calls fn for every booking of an event, one row at a time
+func (r *exportRepository) StreamBookings(eventID uuid.UUID, filter model.ExportRequest, fn func(row model.BookingExportRow) error) error {
+	query := r.db.Table("bookings AS b").
+		Select(`b.id AS booking_id, b.user_id, b.status, COUNT(t.id) AS tickets,
+			COALESCE(STRING_AGG(DISTINCT t.type, ';'), '') AS ticket_types,
+			b.total_price, b.payment_id, b.created_at, b.updated_at`).
+		Joins("LEFT JOIN tickets AS t ON t.booking_id = b.id").
+		Where("b.event_id = ?", eventID)
+
+	// Apply filters
+	if filter.Status != "" {
+		query = query.Where("b.status = ?", filter.Status)
+	}
+	if filter.TicketType != "" {
+		query = query.Where("EXISTS (SELECT 1 FROM tickets WHERE tickets.booking_id = b.id AND tickets.type = ?)", filter.TicketType)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("b.created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("b.created_at < ?", filter.To.AddDate(0, 0, 1))
+	}
+
+	rows, err := query.Group("b.id").Order("b.created_at ASC").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row model.BookingExportRow
+		if err := r.db.ScanRows(rows, &row); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// StreamTransactions calls fn for every recorded payment and refund of an
+// event, one row at a time
+func (r *exportRepository) StreamTransactions(eventID uuid.UUID, filter model.ExportRequest, fn func(row model.TransactionExportRow) error) error {
+	query := r.db.Table("booking_transactions AS bt").
+		Select(`bt.id AS transaction_id, bt.booking_id, bt.payment_id, bt.amendment_id, bt.user_id, bt.type,
+			bt.amount, bt.currency, bt.reason, b.status AS booking_status, bt.occurred_at`).
+		Joins("JOIN bookings AS b ON b.id = bt.booking_id").
+		Where("bt.event_id = ?", eventID)
+
+	// Apply filters
+	if filter.Status != "" {
+		query = query.Where("b.status = ?", filter.Status)
+	}
+	if filter.TicketType != "" {
+		query = query.Where("EXISTS (SELECT 1 FROM tickets WHERE tickets.booking_id = bt.booking_id AND tickets.type = ?)", filter.TicketType)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("bt.occurred_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("bt.occurred_at < ?", filter.To.AddDate(0, 0, 1))
+	}
+
+	rows, err := query.Order("bt.occurred_at ASC, bt.id ASC").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row model.TransactionExportRow
+		if err := r.db.ScanRows(rows, &row); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// CreateJob creates a new export job
+func (r *exportRepository) CreateJob(job *model.ExportJob) error {
+	return r.db.Create(job).Error
+}
+
+// FindJobByID finds an export job by ID
+func (r *exportRepository) FindJobByID(id uuid.UUID) (*model.ExportJob, error) {
+	var job model.ExportJob
+	result := r.db.First(&job, "id = ?", id)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &job, nil
+}
+
+// FindRunnableJobIDs finds export jobs waiting to be processed and running
+// jobs whose lease expired because their worker stopped, oldest first
+func (r *exportRepository) FindRunnableJobIDs(now time.Time, limit int) ([]uuid.UUID, error) {
+	var jobIDs []uuid.UUID
+	result := r.db.Model(&model.ExportJob{}).
+		Where("status = ? OR (status = ? AND lease_expires_at < ?)", model.ExportJobPending, model.ExportJobRunning, now).
+		Order("created_at ASC").
+		Limit(limit).
+		Pluck("id", &jobIDs)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return jobIDs, nil
+}
+
+// ClaimJob moves a pending export job, or a running job whose lease expired,
+// to running with a lease until leaseUntil and counts the attempt. It reports
+// whether this caller won the job.
+func (r *exportRepository) ClaimJob(id uuid.UUID, now time.Time, leaseUntil time.Time) (bool, error) {
+	result := r.db.Model(&model.ExportJob{}).
+		Where("id = ? AND (status = ? OR (status = ? AND lease_expires_at < ?))", id, model.ExportJobPending, model.ExportJobRunning, now).
+		Updates(map[string]interface{}{
+			"status":           model.ExportJobRunning,
+			"attempts":         gorm.Expr("attempts + 1"),
+			"lease_expires_at": leaseUntil,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// RenewJobLease extends the lease of a running export job, reporting false
+// when the attempt no longer owns the job
+func (r *exportRepository) RenewJobLease(id uuid.UUID, attempt int, leaseUntil time.Time) (bool, error) {
+	result := r.db.Model(&model.ExportJob{}).
+		Where("id = ? AND status = ? AND attempts = ?", id, model.ExportJobRunning, attempt).
+		Update("lease_expires_at", leaseUntil)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// FinishJob stores the outcome of an attempt of a running export job,
+// reporting false when another attempt took the job over in the meantime
+func (r *exportRepository) FinishJob(job *model.ExportJob, attempt int) (bool, error) {
+	result := r.db.Model(&model.ExportJob{}).
+		Where("id = ? AND status = ? AND attempts = ?", job.ID, model.ExportJobRunning, attempt).
+		Updates(map[string]interface{}{
+			"status":           job.Status,
+			"file_path":        job.FilePath,
+			"row_count":        job.RowCount,
+			"error":            job.Error,
+			"completed_at":     job.CompletedAt,
+			"expires_at":       job.ExpiresAt,
+			"lease_expires_at": nil,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// FindExpiredJobs finds completed export jobs whose artifact expired
+func (r *exportRepository) FindExpiredJobs(now time.Time, limit int) ([]model.ExportJob, error) {
+	var jobs []model.ExportJob
+	result := r.db.
+		Where("status = ? AND expires_at < ?", model.ExportJobCompleted, now).
+		Order("expires_at ASC").
+		Limit(limit).
+		Find(&jobs)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return jobs, nil
+}
+
+// ExpireJob marks a completed export job as expired after its artifact was deleted
+func (r *exportRepository) ExpireJob(id uuid.UUID) error {
+	return r.db.Model(&model.ExportJob{}).
+		Where("id = ? AND status = ?", id, model.ExportJobCompleted).
+		Updates(map[string]interface{}{
+			"status":    model.ExportJobExpired,
+			"file_path": "",
+		}).Error
+}
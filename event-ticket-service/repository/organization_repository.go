@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"github.com/yourusername/ticket-system/event-ticket-service/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OrganizationRepository defines the interface for organization member repository operations
+type OrganizationRepository interface {
+	FindMember(organizationID, userID uuid.UUID) (*model.OrganizationMember, error)
+	FindMembersByUserID(userID uuid.UUID) ([]model.OrganizationMember, error)
+	SaveMember(member *model.OrganizationMember) error
+	DeleteMember(organizationID, userID uuid.UUID) error
+	DeleteMembersByUserID(userID uuid.UUID) error
+	WithTx(tx *gorm.DB) OrganizationRepository
+}
+
+// organizationRepository implements OrganizationRepository interface
+type organizationRepository struct {
+	db *gorm.DB
+}
+
+// NewOrganizationRepository creates a new organization repository
+func NewOrganizationRepository(db *gorm.DB) OrganizationRepository {
+	// Auto migrate the model
+	db.AutoMigrate(&model.OrganizationMember{})
+
+	return &organizationRepository{
+		db: db,
+	}
+}
+
+// WithTx returns a repository that runs its operations in tx
+func (r *organizationRepository) WithTx(tx *gorm.DB) OrganizationRepository {
+	return &organizationRepository{db: tx}
+}
+
+// FindMember finds the membership of a user in an organization
+func (r *organizationRepository) FindMember(organizationID, userID uuid.UUID) (*model.OrganizationMember, error) {
+	var member model.OrganizationMember
+	result := r.db.First(&member, "organization_id = ? AND user_id = ?", organizationID, userID)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &member, nil
+}
+
+// FindMembersByUserID finds the memberships of a user in all organizations
+func (r *organizationRepository) FindMembersByUserID(userID uuid.UUID) ([]model.OrganizationMember, error) {
+	var members []model.OrganizationMember
+	if err := r.db.Where("user_id = ?", userID).Find(&members).Error; err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// SaveMember creates a membership or replaces the role of an existing one
+func (r *organizationRepository) SaveMember(member *model.OrganizationMember) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "organization_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role", "updated_at"}),
+	}).Create(member).Error
+}
+
+// DeleteMember removes a user from an organization
+func (r *organizationRepository) DeleteMember(organizationID, userID uuid.UUID) error {
+	return r.db.Where("organization_id = ? AND user_id = ?", organizationID, userID).Delete(&model.OrganizationMember{}).Error
+}
+
+// DeleteMembersByUserID removes a user from all organizations
+func (r *organizationRepository) DeleteMembersByUserID(userID uuid.UUID) error {
+	return r.db.Where("user_id = ?", userID).Delete(&model.OrganizationMember{}).Error
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"github.com/yourusername/ticket-system/event-ticket-service/model"
+	"gorm.io/gorm"
+)
+
+// RefundPolicyRepository defines the interface for refund policy repository operations
+type RefundPolicyRepository interface {
+	FindByEventID(eventID uuid.UUID) (*model.RefundPolicy, error)
+	Save(policy *model.RefundPolicy) error
+	Delete(eventID uuid.UUID) error
+	WithTx(tx *gorm.DB) RefundPolicyRepository
+}
+
+// refundPolicyRepository implements RefundPolicyRepository interface
+type refundPolicyRepository struct {
+	db *gorm.DB
+}
+
+// NewRefundPolicyRepository creates a new refund policy repository
+func NewRefundPolicyRepository(db *gorm.DB) RefundPolicyRepository {
+	// Auto migrate the models
+	db.AutoMigrate(&model.RefundPolicy{}, &model.RefundPolicyTier{})
+
+	return &refundPolicyRepository{
+		db: db,
+	}
+}
+
+// WithTx returns a repository that runs its operations in tx
+func (r *refundPolicyRepository) WithTx(tx *gorm.DB) RefundPolicyRepository {
+	return &refundPolicyRepository{
+		db: tx,
+	}
+}
+
+// FindByEventID finds the refund policy of an event with its tiers, highest first
+func (r *refundPolicyRepository) FindByEventID(eventID uuid.UUID) (*model.RefundPolicy, error) {
+	var policy model.RefundPolicy
+	result := r.db.Preload("Tiers", func(db *gorm.DB) *gorm.DB {
+		return db.Order("hours_before_event DESC")
+	}).First(&policy, "event_id = ?", eventID)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &policy, nil
+}
+
+// Save creates or replaces the refund policy of an event with its tiers
+func (r *refundPolicyRepository) Save(policy *model.RefundPolicy) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var existing model.RefundPolicy
+		err := tx.Where("event_id = ?", policy.EventID).First(&existing).Error
+		switch {
+		case err == nil:
+			policy.ID = existing.ID
+			policy.CreatedAt = existing.CreatedAt
+			if err := tx.Where("policy_id = ?", existing.ID).Delete(&model.RefundPolicyTier{}).Error; err != nil {
+				return err
+			}
+		case err != gorm.ErrRecordNotFound:
+			return err
+		}
+
+		if err := tx.Omit("Tiers").Save(policy).Error; err != nil {
+			return err
+		}
+
+		for i := range policy.Tiers {
+			policy.Tiers[i].ID = uuid.Nil
+			policy.Tiers[i].PolicyID = policy.ID
+		}
+		if len(policy.Tiers) > 0 {
+			return tx.Create(&policy.Tiers).Error
+		}
+		return nil
+	})
+}
+
+// Delete deletes the refund policy of an event
+func (r *refundPolicyRepository) Delete(eventID uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var policy model.RefundPolicy
+		if err := tx.Where("event_id = ?", eventID).First(&policy).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil
+			}
+			return err
+		}
+
+		if err := tx.Where("policy_id = ?", policy.ID).Delete(&model.RefundPolicyTier{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&policy).Error
+	})
+}
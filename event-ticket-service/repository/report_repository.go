@@ -0,0 +1,273 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/ticket-system/event-ticket-service/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReportRepository defines the interface for sales report repository operations
+type ReportRepository interface {
+	MarkStale(eventID uuid.UUID) error
+	FindStaleEventIDs(limit int) ([]uuid.UUID, error)
+	FindState(eventID uuid.UUID) (*model.EventReportState, error)
+	RefreshEventRollups(eventID uuid.UUID) error
+	FindTicketTypeRollups(eventID uuid.UUID) ([]model.TicketTypeRollup, error)
+	FindRevenueRollups(eventID uuid.UUID, from, to time.Time) ([]model.RevenueRollup, error)
+	WithTx(tx *gorm.DB) ReportRepository
+}
+
+// reportRepository implements ReportRepository interface
+type reportRepository struct {
+	db *gorm.DB
+}
+
+// NewReportRepository creates a new report repository
+func NewReportRepository(db *gorm.DB) ReportRepository {
+	// Auto migrate the models
+	db.AutoMigrate(&model.EventReportState{}, &model.TicketTypeRollup{}, &model.RevenueRollup{})
+
+	return &reportRepository{
+		db: db,
+	}
+}
+
+// WithTx returns a repository that runs its operations in tx
+func (r *reportRepository) WithTx(tx *gorm.DB) ReportRepository {
+	return &reportRepository{
+		db: tx,
+	}
+}
+
+// MarkStale flags the rollups of an event for rebuilding
+func (r *reportRepository) MarkStale(eventID uuid.UUID) error {
+	state := model.EventReportState{
+		EventID:  eventID,
+		Stale:    true,
+		MarkedAt: time.Now(),
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "event_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"stale", "marked_at"}),
+	}).Create(&state).Error
+}
+
+// FindStaleEventIDs finds events whose rollups need rebuilding, oldest first
+func (r *reportRepository) FindStaleEventIDs(limit int) ([]uuid.UUID, error) {
+	var eventIDs []uuid.UUID
+	result := r.db.Model(&model.EventReportState{}).
+		Where("stale = ?", true).
+		Order("marked_at ASC").
+		Limit(limit).
+		Pluck("event_id", &eventIDs)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return eventIDs, nil
+}
+
+// FindState finds the report state of an event
+func (r *reportRepository) FindState(eventID uuid.UUID) (*model.EventReportState, error) {
+	var state model.EventReportState
+	result := r.db.First(&state, "event_id = ?", eventID)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &state, nil
+}
+
+// RefreshEventRollups rebuilds the rollups of an event from its tickets,
+// bookings and recorded refunds
+func (r *reportRepository) RefreshEventRollups(eventID uuid.UUID) error {
+	startedAt := time.Now()
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Aggregate ticket inventory per type
+		var ticketRollups []model.TicketTypeRollup
+		err := tx.Raw(`
+			SELECT type AS ticket_type,
+				MIN(price) AS price,
+				COUNT(*) AS total,
+				SUM(CASE WHEN status = 'available' THEN 1 ELSE 0 END) AS available,
+				SUM(CASE WHEN status = 'reserved' THEN 1 ELSE 0 END) AS reserved,
+				SUM(CASE WHEN status = 'sold' THEN 1 ELSE 0 END) AS sold,
+				SUM(CASE WHEN checked_in_at IS NOT NULL THEN 1 ELSE 0 END) AS checked_in,
+				SUM(CASE WHEN status = 'sold' THEN price ELSE 0 END) AS sold_revenue
+			FROM tickets
+			WHERE event_id = ?
+			GROUP BY type`, eventID).Scan(&ticketRollups).Error
+		if err != nil {
+			return err
+		}
+
+		// Aggregate bookings per day
+		var dailyRows []struct {
+			Day          sqlDate
+			Bookings     int
+			Confirmed    int
+			Cancelled    int
+			Refunded     int
+			GrossRevenue float64
+		}
+		err = tx.Raw(`
+			SELECT DATE(created_at) AS day,
+				COUNT(*) AS bookings,
+				SUM(CASE WHEN status = 'confirmed' THEN 1 ELSE 0 END) AS confirmed,
+				SUM(CASE WHEN status = 'cancelled' THEN 1 ELSE 0 END) AS cancelled,
+				SUM(CASE WHEN status = 'refunded' THEN 1 ELSE 0 END) AS refunded,
+				SUM(CASE WHEN status IN ('confirmed', 'refunded') THEN total_price ELSE 0 END) AS gross_revenue
+			FROM bookings
+			WHERE event_id = ?
+			GROUP BY DATE(created_at)`, eventID).Scan(&dailyRows).Error
+		if err != nil {
+			return err
+		}
+
+		// Aggregate the recorded refunds per day they were made, as bookings
+		// are refunded in part and after the day they were made
+		var refundRows []struct {
+			Day    sqlDate
+			Amount float64
+		}
+		err = tx.Raw(`
+			SELECT DATE(occurred_at) AS day,
+				-SUM(amount) AS amount
+			FROM booking_transactions
+			WHERE event_id = ? AND type = ?
+			GROUP BY DATE(occurred_at)`, eventID, model.TransactionTypeRefund).Scan(&refundRows).Error
+		if err != nil {
+			return err
+		}
+
+		revenueRollups := make([]model.RevenueRollup, 0, len(dailyRows)+len(refundRows))
+		days := make(map[string]int, len(dailyRows))
+		for _, row := range dailyRows {
+			days[row.Day.Format("2006-01-02")] = len(revenueRollups)
+			revenueRollups = append(revenueRollups, model.RevenueRollup{
+				EventID:      eventID,
+				Day:          row.Day.Time,
+				Bookings:     row.Bookings,
+				Confirmed:    row.Confirmed,
+				Cancelled:    row.Cancelled,
+				Refunded:     row.Refunded,
+				GrossRevenue: row.GrossRevenue,
+			})
+		}
+
+		for _, row := range refundRows {
+			i, ok := days[row.Day.Format("2006-01-02")]
+			if !ok {
+				i = len(revenueRollups)
+				revenueRollups = append(revenueRollups, model.RevenueRollup{EventID: eventID, Day: row.Day.Time})
+			}
+			revenueRollups[i].RefundedAmount = row.Amount
+		}
+
+		// Replace the existing rollups
+		if err := tx.Delete(&model.TicketTypeRollup{}, "event_id = ?", eventID).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&model.RevenueRollup{}, "event_id = ?", eventID).Error; err != nil {
+			return err
+		}
+
+		for i := range ticketRollups {
+			ticketRollups[i].EventID = eventID
+		}
+		if len(ticketRollups) > 0 {
+			if err := tx.Create(&ticketRollups).Error; err != nil {
+				return err
+			}
+		}
+
+		if len(revenueRollups) > 0 {
+			if err := tx.Create(&revenueRollups).Error; err != nil {
+				return err
+			}
+		}
+
+		// Make sure a state row exists, then clear the stale flag unless the
+		// event was marked again while the rollups were being rebuilt
+		state := model.EventReportState{EventID: eventID, MarkedAt: startedAt}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&state).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.EventReportState{}).
+			Where("event_id = ? AND marked_at <= ?", eventID, startedAt).
+			Updates(map[string]interface{}{
+				"stale":        false,
+				"refreshed_at": startedAt,
+			}).Error
+	})
+}
+
+// FindTicketTypeRollups finds the ticket type rollups of an event
+func (r *reportRepository) FindTicketTypeRollups(eventID uuid.UUID) ([]model.TicketTypeRollup, error) {
+	var rollups []model.TicketTypeRollup
+	result := r.db.Where("event_id = ?", eventID).Order("ticket_type ASC").Find(&rollups)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return rollups, nil
+}
+
+// FindRevenueRollups finds the daily revenue rollups of an event within an optional date range
+func (r *reportRepository) FindRevenueRollups(eventID uuid.UUID, from, to time.Time) ([]model.RevenueRollup, error) {
+	var rollups []model.RevenueRollup
+	query := r.db.Where("event_id = ?", eventID)
+	if !from.IsZero() {
+		query = query.Where("day >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("day <= ?", to)
+	}
+	result := query.Order("day ASC").Find(&rollups)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return rollups, nil
+}
+
+// sqlDate scans the result of DATE(), which Postgres returns as a time and
+// SQLite as text
+type sqlDate struct {
+	time.Time
+}
+
+// Scan implements sql.Scanner
+func (d *sqlDate) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case time.Time:
+		d.Time = v
+		return nil
+	case string:
+		return d.parse(v)
+	case []byte:
+		return d.parse(string(v))
+	default:
+		return fmt.Errorf("cannot scan %T into a date", value)
+	}
+}
+
+// Value implements driver.Valuer
+func (d sqlDate) Value() (driver.Value, error) {
+	return d.Time, nil
+}
+
+// parse parses a date in ISO 8601 format
+func (d *sqlDate) parse(value string) error {
+	day, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return fmt.Errorf("invalid date %q: %w", value, err)
+	}
+	d.Time = day
+	return nil
+}
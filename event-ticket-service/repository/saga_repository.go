@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/ticket-system/event-ticket-service/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SagaRepository defines the interface for booking saga repository operations
+type SagaRepository interface {
+	Create(saga *model.BookingSaga) error
+	Update(saga *model.BookingSaga) error
+	AddStep(step *model.BookingSagaStep) error
+	FindByBookingID(bookingID uuid.UUID) (*model.BookingSaga, error)
+	LockByBookingID(bookingID uuid.UUID) (*model.BookingSaga, error)
+	FindTimedOut(step string, startedBefore time.Time, limit int) ([]model.BookingSaga, error)
+	FindStuck(startedBefore map[string]time.Time, page, pageSize int) ([]model.BookingSaga, int64, error)
+	WithTx(tx *gorm.DB) SagaRepository
+}
+
+// sagaRepository implements SagaRepository interface
+type sagaRepository struct {
+	db *gorm.DB
+}
+
+// NewSagaRepository creates a new saga repository
+func NewSagaRepository(db *gorm.DB) SagaRepository {
+	// Auto migrate the models
+	db.AutoMigrate(&model.BookingSaga{}, &model.BookingSagaStep{})
+
+	return &sagaRepository{
+		db: db,
+	}
+}
+
+// WithTx returns a repository that runs its operations in tx
+func (r *sagaRepository) WithTx(tx *gorm.DB) SagaRepository {
+	return &sagaRepository{
+		db: tx,
+	}
+}
+
+// Create saves a new saga with its first steps
+func (r *sagaRepository) Create(saga *model.BookingSaga) error {
+	return r.db.Create(saga).Error
+}
+
+// Update saves the state of a saga, without its steps
+func (r *sagaRepository) Update(saga *model.BookingSaga) error {
+	return r.db.Omit("Steps").Save(saga).Error
+}
+
+// AddStep records the outcome of a saga step
+func (r *sagaRepository) AddStep(step *model.BookingSagaStep) error {
+	return r.db.Create(step).Error
+}
+
+// FindByBookingID finds the saga of a booking with its steps, oldest first
+func (r *sagaRepository) FindByBookingID(bookingID uuid.UUID) (*model.BookingSaga, error) {
+	var saga model.BookingSaga
+	result := r.db.Preload("Steps", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at ASC")
+	}).First(&saga, "booking_id = ?", bookingID)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &saga, nil
+}
+
+// LockByBookingID finds the saga of a booking and locks it until the end of
+// the transaction, so that events and timeouts of a saga apply one at a time
+func (r *sagaRepository) LockByBookingID(bookingID uuid.UUID) (*model.BookingSaga, error) {
+	var saga model.BookingSaga
+	result := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&saga, "booking_id = ?", bookingID)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &saga, nil
+}
+
+// FindTimedOut finds unfinished sagas that have been waiting for step since
+// before startedBefore
+func (r *sagaRepository) FindTimedOut(step string, startedBefore time.Time, limit int) ([]model.BookingSaga, error) {
+	var sagas []model.BookingSaga
+	result := r.db.
+		Where("status IN ? AND step = ? AND step_started_at < ?", []string{model.SagaRunning, model.SagaCompensating}, step, startedBefore).
+		Order("step_started_at ASC").
+		Limit(limit).
+		Find(&sagas)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return sagas, nil
+}
+
+// FindStuck finds failed sagas and unfinished sagas that have been waiting
+// for their step since before its entry in startedBefore, oldest first
+func (r *sagaRepository) FindStuck(startedBefore map[string]time.Time, page, pageSize int) ([]model.BookingSaga, int64, error) {
+	var sagas []model.BookingSaga
+	var total int64
+
+	stuck := r.db.Where("status = ?", model.SagaFailed)
+	for step, before := range startedBefore {
+		stuck = stuck.Or("status IN ? AND step = ? AND step_started_at < ?", []string{model.SagaRunning, model.SagaCompensating}, step, before)
+	}
+
+	// Count total results
+	if err := r.db.Model(&model.BookingSaga{}).Where(stuck).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Apply pagination
+	offset := (page - 1) * pageSize
+	result := r.db.Where(stuck).Order("step_started_at ASC").Offset(offset).Limit(pageSize).Find(&sagas)
+	if result.Error != nil {
+		return nil, 0, result.Error
+	}
+
+	return sagas, total, nil
+}
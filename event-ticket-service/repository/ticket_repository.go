@@ -4,6 +4,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/yourusername/ticket-system/event-ticket-service/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // TicketRepository defines the interface for ticket repository operations
@@ -11,12 +12,15 @@ type TicketRepository interface {
 	Create(ticket *model.Ticket) error
 	CreateBatch(tickets []*model.Ticket) error
 	FindByID(id uuid.UUID) (*model.Ticket, error)
+	FindByIDForUpdate(id uuid.UUID) (*model.Ticket, error)
 	FindByEventID(eventID uuid.UUID) ([]model.Ticket, error)
 	FindAvailableByEventID(eventID uuid.UUID, ticketType string) ([]model.Ticket, error)
+	LockAvailableByEventID(eventID uuid.UUID, ticketType string, limit int) ([]model.Ticket, error)
 	FindByBookingID(bookingID uuid.UUID) ([]model.Ticket, error)
 	Update(ticket *model.Ticket) error
 	UpdateBatch(tickets []*model.Ticket) error
 	Delete(id uuid.UUID) error
+	WithTx(tx *gorm.DB) TicketRepository
 }
 
 // ticketRepository implements TicketRepository interface
@@ -34,6 +38,13 @@ func NewTicketRepository(db *gorm.DB) TicketRepository {
 	}
 }
 
+// WithTx returns a repository that runs its operations in tx
+func (r *ticketRepository) WithTx(tx *gorm.DB) TicketRepository {
+	return &ticketRepository{
+		db: tx,
+	}
+}
+
 // Create creates a new ticket
 func (r *ticketRepository) Create(ticket *model.Ticket) error {
 	return r.db.Create(ticket).Error
@@ -57,6 +68,20 @@ func (r *ticketRepository) FindByID(id uuid.UUID) (*model.Ticket, error) {
 	return &ticket, nil
 }
 
+// FindByIDForUpdate finds a ticket by ID and locks it until the end of the
+// transaction, so that concurrent check-ins of the ticket run one after another
+func (r *ticketRepository) FindByIDForUpdate(id uuid.UUID) (*model.Ticket, error) {
+	var ticket model.Ticket
+	result := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&ticket, "id = ?", id)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &ticket, nil
+}
+
 // FindByEventID finds tickets by event ID
 func (r *ticketRepository) FindByEventID(eventID uuid.UUID) ([]model.Ticket, error) {
 	var tickets []model.Ticket
@@ -81,6 +106,21 @@ func (r *ticketRepository) FindAvailableByEventID(eventID uuid.UUID, ticketType
 	return tickets, nil
 }
 
+// LockAvailableByEventID locks up to limit available tickets of a type until
+// the end of the transaction, skipping tickets locked by other transactions
+func (r *ticketRepository) LockAvailableByEventID(eventID uuid.UUID, ticketType string, limit int) ([]model.Ticket, error) {
+	var tickets []model.Ticket
+	result := r.db.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("event_id = ? AND type = ? AND status = 'available'", eventID, ticketType).
+		Order("price ASC, id ASC").
+		Limit(limit).
+		Find(&tickets)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return tickets, nil
+}
+
 // FindByBookingID finds tickets by booking ID
 func (r *ticketRepository) FindByBookingID(bookingID uuid.UUID) ([]model.Ticket, error) {
 	var tickets []model.Ticket
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"github.com/yourusername/ticket-system/event-ticket-service/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TransactionRepository defines the interface for booking transaction repository operations
+type TransactionRepository interface {
+	Record(transaction *model.BookingTransaction) (bool, error)
+	WithTx(tx *gorm.DB) TransactionRepository
+}
+
+// transactionRepository implements TransactionRepository interface
+type transactionRepository struct {
+	db *gorm.DB
+}
+
+// NewTransactionRepository creates a new booking transaction repository
+func NewTransactionRepository(db *gorm.DB) TransactionRepository {
+	// Auto migrate the model
+	db.AutoMigrate(&model.BookingTransaction{})
+
+	return &transactionRepository{
+		db: db,
+	}
+}
+
+// WithTx returns a repository that runs its operations in tx
+func (r *transactionRepository) WithTx(tx *gorm.DB) TransactionRepository {
+	return &transactionRepository{
+		db: tx,
+	}
+}
+
+// Record stores a transaction unless one with the same ID is already
+// stored, reporting whether it was stored
+func (r *transactionRepository) Record(transaction *model.BookingTransaction) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(transaction)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
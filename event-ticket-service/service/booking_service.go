@@ -1,15 +1,15 @@
 package service
 
 import (
-	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
-	"github.com/yourusername/ticket-system/event-ticket-service/config"
+	"github.com/yourusername/ticket-system/contracts"
 	"github.com/yourusername/ticket-system/event-ticket-service/model"
 	"github.com/yourusername/ticket-system/event-ticket-service/repository"
+	"github.com/yourusername/ticket-system/messaging"
 	"gorm.io/gorm"
 )
 
@@ -20,15 +20,26 @@ type BookingService interface {
 	GetUserBookings(userID uuid.UUID, page, pageSize int) ([]model.BookingResponse, int64, error)
 	UpdateBookingStatus(id uuid.UUID, status string) (*model.BookingResponse, error)
 	CancelBooking(id uuid.UUID) error
+	GetRefundQuote(id uuid.UUID) (*model.RefundQuote, error)
+	CheckInTicket(ticketID uuid.UUID) (*model.TicketResponse, error)
+	AmendBooking(id uuid.UUID, requestedBy uuid.UUID, req model.AmendBookingRequest) (*model.BookingAmendment, error)
+	GetBookingAmendments(id uuid.UUID) ([]model.BookingAmendment, error)
+	CompleteAmendment(amendmentID uuid.UUID, paymentID uuid.UUID) error
+	FailAmendment(amendmentID uuid.UUID, reason string) error
+	WithTx(tx *gorm.DB) BookingService
 }
 
 // bookingService implements BookingService interface
 type bookingService struct {
-	bookingRepo repository.BookingRepository
-	eventRepo   repository.EventRepository
-	ticketRepo  repository.TicketRepository
-	db          *gorm.DB
-	rmq         *config.RabbitMQ
+	bookingRepo      repository.BookingRepository
+	eventRepo        repository.EventRepository
+	ticketRepo       repository.TicketRepository
+	reportRepo       repository.ReportRepository
+	amendmentRepo    repository.AmendmentRepository
+	refundPolicyRepo repository.RefundPolicyRepository
+	outboxRepo       messaging.OutboxRepository
+	sagaRepo         repository.SagaRepository
+	db               *gorm.DB
 }
 
 // NewBookingService creates a new booking service
@@ -36,15 +47,39 @@ func NewBookingService(
 	bookingRepo repository.BookingRepository,
 	eventRepo repository.EventRepository,
 	ticketRepo repository.TicketRepository,
+	reportRepo repository.ReportRepository,
+	amendmentRepo repository.AmendmentRepository,
+	refundPolicyRepo repository.RefundPolicyRepository,
+	outboxRepo messaging.OutboxRepository,
+	sagaRepo repository.SagaRepository,
 	db *gorm.DB,
-	rmq *config.RabbitMQ,
 ) BookingService {
 	return &bookingService{
-		bookingRepo: bookingRepo,
-		eventRepo:   eventRepo,
-		ticketRepo:  ticketRepo,
-		db:          db,
-		rmq:         rmq,
+		bookingRepo:      bookingRepo,
+		eventRepo:        eventRepo,
+		ticketRepo:       ticketRepo,
+		reportRepo:       reportRepo,
+		amendmentRepo:    amendmentRepo,
+		refundPolicyRepo: refundPolicyRepo,
+		outboxRepo:       outboxRepo,
+		sagaRepo:         sagaRepo,
+		db:               db,
+	}
+}
+
+// WithTx returns a service that runs its operations in tx, so that consumers
+// can apply a message in the transaction that records it as processed
+func (s *bookingService) WithTx(tx *gorm.DB) BookingService {
+	return &bookingService{
+		bookingRepo:      s.bookingRepo.WithTx(tx),
+		eventRepo:        s.eventRepo.WithTx(tx),
+		ticketRepo:       s.ticketRepo.WithTx(tx),
+		reportRepo:       s.reportRepo.WithTx(tx),
+		amendmentRepo:    s.amendmentRepo.WithTx(tx),
+		refundPolicyRepo: s.refundPolicyRepo.WithTx(tx),
+		outboxRepo:       s.outboxRepo,
+		sagaRepo:         s.sagaRepo.WithTx(tx),
+		db:               tx,
 	}
 }
 
@@ -87,7 +122,7 @@ func (s *bookingService) CreateBooking(userID uuid.UUID, req model.CreateBooking
 
 		for _, ticketReq := range req.Tickets {
 			// Find available tickets of the requested type
-			availableTickets, err := s.ticketRepo.FindAvailableByEventID(req.EventID, ticketReq.Type)
+			availableTickets, err := s.ticketRepo.WithTx(tx).FindAvailableByEventID(req.EventID, ticketReq.Type)
 			if err != nil {
 				return fmt.Errorf("failed to find available tickets: %w", err)
 			}
@@ -111,7 +146,7 @@ func (s *bookingService) CreateBooking(userID uuid.UUID, req model.CreateBooking
 		booking.TotalPrice = totalPrice
 
 		// Save booking to database
-		if err := s.bookingRepo.Create(booking); err != nil {
+		if err := s.bookingRepo.WithTx(tx).Create(booking); err != nil {
 			return fmt.Errorf("failed to create booking: %w", err)
 		}
 
@@ -122,7 +157,7 @@ func (s *bookingService) CreateBooking(userID uuid.UUID, req model.CreateBooking
 		}
 
 		// Update tickets in database
-		if err := s.ticketRepo.UpdateBatch(selectedTickets); err != nil {
+		if err := s.ticketRepo.WithTx(tx).UpdateBatch(selectedTickets); err != nil {
 			return fmt.Errorf("failed to update tickets: %w", err)
 		}
 
@@ -132,15 +167,20 @@ func (s *bookingService) CreateBooking(userID uuid.UUID, req model.CreateBooking
 			booking.Tickets[i] = *ticket
 		}
 
-		return nil
+		// Publish booking created event
+		if err := s.publishBookingEvent(tx, "booking.created", booking); err != nil {
+			return err
+		}
+
+		// Take the booking through payment and confirmation
+		return startBookingSaga(s.sagaRepo, s.outboxRepo, tx, booking)
 	})
 
 	if err != nil {
 		return nil, err
 	}
 
-	// Publish booking created event
-	s.publishBookingEvent("booking.created", booking)
+	s.markReportStale(booking.EventID)
 
 	// Return booking response
 	bookingResponse := booking.ToResponse(false)
@@ -206,12 +246,37 @@ func (s *bookingService) UpdateBookingStatus(id uuid.UUID, status string) (*mode
 		return nil, fmt.Errorf("booking not found")
 	}
 
+	// Update booking and tickets with the event in one transaction
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		return s.applyBookingStatus(tx, booking, status)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.markReportStale(booking.EventID)
+
+	// Return booking response
+	bookingResponse := booking.ToResponse(false)
+	return &bookingResponse, nil
+}
+
+// applyBookingStatus updates the status of a booking and its tickets in tx
+// and writes the booking updated event
+func (s *bookingService) applyBookingStatus(tx *gorm.DB, booking *model.Booking, status string) error {
+	// An amendment of an inactive booking can no longer be paid
+	if status == "cancelled" || status == "refunded" {
+		if err := s.failPendingAmendment(tx, booking, "booking is no longer active"); err != nil {
+			return err
+		}
+	}
+
 	// Update booking status
 	booking.Status = status
 
 	// Save booking to database
-	if err := s.bookingRepo.Update(booking); err != nil {
-		return nil, fmt.Errorf("failed to update booking: %w", err)
+	if err := s.bookingRepo.WithTx(tx).Update(booking); err != nil {
+		return fmt.Errorf("failed to update booking: %w", err)
 	}
 
 	// Update ticket status based on booking status
@@ -226,9 +291,9 @@ func (s *bookingService) UpdateBookingStatus(id uuid.UUID, status string) (*mode
 	}
 
 	// Find tickets for booking
-	tickets, err := s.ticketRepo.FindByBookingID(id)
+	tickets, err := s.ticketRepo.WithTx(tx).FindByBookingID(booking.ID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find tickets: %w", err)
+		return fmt.Errorf("failed to find tickets: %w", err)
 	}
 
 	// Update ticket status
@@ -237,6 +302,7 @@ func (s *bookingService) UpdateBookingStatus(id uuid.UUID, status string) (*mode
 		if ticketStatus == "available" {
 			tickets[i].UserID = uuid.Nil
 			tickets[i].BookingID = uuid.Nil
+			tickets[i].CheckedInAt = nil
 		}
 	}
 
@@ -247,61 +313,596 @@ func (s *bookingService) UpdateBookingStatus(id uuid.UUID, status string) (*mode
 	}
 
 	// Update tickets in database
-	if err := s.ticketRepo.UpdateBatch(ticketPtrs); err != nil {
-		return nil, fmt.Errorf("failed to update tickets: %w", err)
+	if err := s.ticketRepo.WithTx(tx).UpdateBatch(ticketPtrs); err != nil {
+		return fmt.Errorf("failed to update tickets: %w", err)
 	}
 
 	// Publish booking updated event
-	s.publishBookingEvent("booking.updated", booking)
-
-	// Return booking response
-	bookingResponse := booking.ToResponse(false)
-	return &bookingResponse, nil
+	return s.publishBookingEvent(tx, "booking.updated", booking)
 }
 
-// CancelBooking cancels a booking
+// CancelBooking cancels a booking and publishes the refund allowed by the
+// refund policy of its event. Cancelling a booking that is already cancelled
+// or refunded changes nothing.
 func (s *bookingService) CancelBooking(id uuid.UUID) error {
-	// Update booking status to cancelled
-	_, err := s.UpdateBookingStatus(id, "cancelled")
+	var booking *model.Booking
+	cancelled := false
+
+	// Lock the booking, so that only one of concurrent cancellations sees it
+	// active and refunds it
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		booking, err = s.bookingRepo.WithTx(tx).FindByIDForUpdate(id)
+		if err != nil {
+			return fmt.Errorf("failed to find booking: %w", err)
+		}
+
+		if booking == nil {
+			return fmt.Errorf("booking not found")
+		}
+
+		// Cancelling twice must not refund twice
+		if booking.Status == "cancelled" || booking.Status == "refunded" {
+			return nil
+		}
+
+		// Evaluate refund before the booking loses its paid status
+		quote, err := s.quoteRefund(tx, booking)
+		if err != nil {
+			return err
+		}
+
+		// Update booking status to cancelled with the refund to make
+		if err := s.applyBookingStatus(tx, booking, "cancelled"); err != nil {
+			return err
+		}
+		cancelled = true
+
+		// Publish booking cancelled event
+		return s.publishCancellationEvent(tx, booking, quote)
+	})
 	if err != nil {
 		return err
 	}
 
-	// Publish booking cancelled event
+	if cancelled {
+		s.markReportStale(booking.EventID)
+	}
+	return nil
+}
+
+// GetRefundQuote gets the refund a booking would get if it was cancelled now
+func (s *bookingService) GetRefundQuote(id uuid.UUID) (*model.RefundQuote, error) {
+	// Find booking by ID
 	booking, err := s.bookingRepo.FindByID(id)
 	if err != nil {
-		return fmt.Errorf("failed to find booking: %w", err)
+		return nil, fmt.Errorf("failed to find booking: %w", err)
+	}
+
+	if booking == nil {
+		return nil, fmt.Errorf("booking not found")
+	}
+
+	return s.quoteRefund(s.db, booking)
+}
+
+// quoteRefund evaluates the refund policy of the event of a booking in tx
+func (s *bookingService) quoteRefund(tx *gorm.DB, booking *model.Booking) (*model.RefundQuote, error) {
+	event, err := s.eventRepo.WithTx(tx).FindByID(booking.EventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find event: %w", err)
+	}
+
+	if event == nil {
+		return nil, fmt.Errorf("event not found")
+	}
+
+	policy, err := s.refundPolicyRepo.WithTx(tx).FindByEventID(booking.EventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find refund policy: %w", err)
+	}
+
+	quote := evaluateRefundPolicy(policy, booking, event, time.Now())
+	return &quote, nil
+}
+
+// CheckInTicket marks a sold ticket as checked in at the venue. The ticket is
+// locked, so that a ticket scanned at two gates at once is admitted only once.
+func (s *bookingService) CheckInTicket(ticketID uuid.UUID) (*model.TicketResponse, error) {
+	var ticket *model.Ticket
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		ticket, err = s.ticketRepo.WithTx(tx).FindByIDForUpdate(ticketID)
+		if err != nil {
+			return fmt.Errorf("failed to find ticket: %w", err)
+		}
+
+		if ticket == nil {
+			return fmt.Errorf("ticket not found")
+		}
+
+		// Only paid tickets can be used for entry
+		if ticket.Status != "sold" {
+			return fmt.Errorf("ticket is not valid for check-in")
+		}
+
+		if ticket.CheckedInAt != nil {
+			return fmt.Errorf("ticket already checked in")
+		}
+
+		// Update ticket
+		now := time.Now()
+		ticket.CheckedInAt = &now
+		if err := s.ticketRepo.WithTx(tx).Update(ticket); err != nil {
+			return fmt.Errorf("failed to update ticket: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.markReportStale(ticket.EventID)
+
+	// Return ticket response
+	ticketResponse := ticket.ToResponse()
+	return &ticketResponse, nil
+}
+
+// AmendBooking adds, removes or upgrades tickets of a pending or confirmed booking.
+// Amendments that cost more on a paid booking hold the new tickets until the
+// supplementary payment completes; all other amendments are applied at once.
+func (s *bookingService) AmendBooking(id uuid.UUID, requestedBy uuid.UUID, req model.AmendBookingRequest) (*model.BookingAmendment, error) {
+	var booking *model.Booking
+	var amendment *model.BookingAmendment
+
+	// Plan and save the amendment while the booking and the new tickets are
+	// locked, so that concurrent amendments and bookings cannot take the same
+	// tickets
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		booking, err = s.bookingRepo.WithTx(tx).FindByIDForUpdate(id)
+		if err != nil {
+			return fmt.Errorf("failed to find booking: %w", err)
+		}
+
+		if booking == nil {
+			return fmt.Errorf("booking not found")
+		}
+
+		amendment, err = s.planAmendment(tx, booking, requestedBy, req)
+		if err != nil {
+			return err
+		}
+
+		if err := s.amendmentRepo.WithTx(tx).Create(booking, amendment); err != nil {
+			return fmt.Errorf("failed to amend booking: %w", err)
+		}
+
+		// Publish booking amended event
+		return s.publishAmendmentEvent(tx, booking, amendment)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.markReportStale(booking.EventID)
+
+	return amendment, nil
+}
+
+// planAmendment checks an amendment of a locked booking and builds it with
+// the new tickets locked in tx
+func (s *bookingService) planAmendment(tx *gorm.DB, booking *model.Booking, requestedBy uuid.UUID, req model.AmendBookingRequest) (*model.BookingAmendment, error) {
+	// Check if booking can be amended
+	if booking.Status != "pending" && booking.Status != "confirmed" {
+		return nil, fmt.Errorf("only pending or confirmed bookings can be amended")
+	}
+
+	pending, err := s.amendmentRepo.WithTx(tx).FindPendingByBookingID(booking.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find amendments: %w", err)
+	}
+
+	if pending != nil {
+		return nil, fmt.Errorf("booking already has an amendment waiting for payment")
+	}
+
+	// Find event by ID
+	event, err := s.eventRepo.WithTx(tx).FindByID(booking.EventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find event: %w", err)
+	}
+
+	if event == nil {
+		return nil, fmt.Errorf("event not found")
+	}
+
+	if event.Status != "active" {
+		return nil, fmt.Errorf("event is not active")
+	}
+
+	if event.StartDate.Before(time.Now()) {
+		return nil, fmt.Errorf("event has already started")
+	}
+
+	// Count the new tickets needed of every type
+	needed := make(map[string]int)
+	for _, add := range req.Add {
+		if add.Quantity <= 0 {
+			return nil, fmt.Errorf("quantity for type %s must be positive", add.Type)
+		}
+		needed[add.Type] += add.Quantity
+	}
+	for _, upgrade := range req.Upgrade {
+		needed[upgrade.Type]++
+	}
+
+	// Lock the new tickets; other transactions skip them until this one ends
+	available := make(map[string][]model.Ticket, len(needed))
+	for ticketType, count := range needed {
+		tickets, err := s.ticketRepo.WithTx(tx).LockAvailableByEventID(booking.EventID, ticketType, count)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find available tickets: %w", err)
+		}
+		if len(tickets) < count {
+			return nil, fmt.Errorf("not enough tickets available for type %s", ticketType)
+		}
+		available[ticketType] = tickets
+	}
+
+	// takeTicket takes the next locked ticket of a type
+	takeTicket := func(ticketType string) model.Ticket {
+		ticket := available[ticketType][0]
+		available[ticketType] = available[ticketType][1:]
+		return ticket
+	}
+
+	bookingTickets := make(map[uuid.UUID]*model.Ticket, len(booking.Tickets))
+	for i := range booking.Tickets {
+		bookingTickets[booking.Tickets[i].ID] = &booking.Tickets[i]
+	}
+
+	// findBookingTicket checks that a ticket can be released from the booking
+	changed := make(map[uuid.UUID]bool)
+	findBookingTicket := func(ticketID uuid.UUID) (*model.Ticket, error) {
+		ticket, ok := bookingTickets[ticketID]
+		if !ok {
+			return nil, fmt.Errorf("ticket %s does not belong to this booking", ticketID)
+		}
+		if changed[ticketID] {
+			return nil, fmt.Errorf("ticket %s is changed more than once", ticketID)
+		}
+		if ticket.CheckedInAt != nil {
+			return nil, fmt.Errorf("ticket %s is already checked in", ticketID)
+		}
+		changed[ticketID] = true
+		return ticket, nil
+	}
+
+	// Build amendment items
+	items := make([]model.BookingAmendmentItem, 0)
+	for _, add := range req.Add {
+		for i := 0; i < add.Quantity; i++ {
+			ticket := takeTicket(add.Type)
+			items = append(items, model.BookingAmendmentItem{
+				Action:     model.AmendmentActionAdd,
+				TicketID:   ticket.ID,
+				TicketType: add.Type,
+				Price:      ticket.Price,
+			})
+		}
+	}
+
+	for _, ticketID := range req.Remove {
+		ticket, err := findBookingTicket(ticketID)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, model.BookingAmendmentItem{
+			Action:             model.AmendmentActionRemove,
+			PreviousTicketID:   ticket.ID,
+			PreviousTicketType: ticket.Type,
+			PreviousPrice:      ticket.Price,
+		})
+	}
+
+	for _, upgrade := range req.Upgrade {
+		ticket, err := findBookingTicket(upgrade.TicketID)
+		if err != nil {
+			return nil, err
+		}
+		if ticket.Type == upgrade.Type {
+			return nil, fmt.Errorf("ticket %s is already of type %s", ticket.ID, upgrade.Type)
+		}
+		newTicket := takeTicket(upgrade.Type)
+		items = append(items, model.BookingAmendmentItem{
+			Action:             model.AmendmentActionUpgrade,
+			TicketID:           newTicket.ID,
+			TicketType:         upgrade.Type,
+			Price:              newTicket.Price,
+			PreviousTicketID:   ticket.ID,
+			PreviousTicketType: ticket.Type,
+			PreviousPrice:      ticket.Price,
+		})
+	}
+
+	if len(items) == 0 {
+		return nil, fmt.Errorf("amendment has no changes")
+	}
+
+	// Compute price difference and remaining tickets
+	difference := 0.0
+	remaining := len(booking.Tickets) - len(req.Remove)
+	for _, item := range items {
+		difference += item.Price - item.PreviousPrice
+		if item.Action == model.AmendmentActionAdd {
+			remaining++
+		}
+	}
+
+	if remaining <= 0 {
+		return nil, fmt.Errorf("amendment would leave the booking without tickets, cancel it instead")
+	}
+
+	amendment := &model.BookingAmendment{
+		BookingID:       booking.ID,
+		RequestedBy:     requestedBy,
+		Status:          model.AmendmentCompleted,
+		PaymentAction:   model.AmendmentPaymentNone,
+		PreviousTotal:   booking.TotalPrice,
+		NewTotal:        booking.TotalPrice + difference,
+		PriceDifference: difference,
+		Items:           items,
+	}
+
+	// Decide how the difference is settled
+	switch {
+	case difference == 0:
+	case booking.Status == "pending":
+		amendment.PaymentAction = model.AmendmentPaymentAdjust
+	case difference > 0:
+		amendment.PaymentAction = model.AmendmentPaymentCharge
+		amendment.Status = model.AmendmentPendingPayment
+	default:
+		amendment.PaymentAction = model.AmendmentPaymentRefund
+	}
+
+	if amendment.Status == model.AmendmentCompleted {
+		now := time.Now()
+		amendment.CompletedAt = &now
+	}
+
+	return amendment, nil
+}
+
+// GetBookingAmendments gets the amendment history of a booking
+func (s *bookingService) GetBookingAmendments(id uuid.UUID) ([]model.BookingAmendment, error) {
+	// Find amendments by booking ID
+	amendments, err := s.amendmentRepo.FindByBookingID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find amendments: %w", err)
 	}
 
-	if booking != nil {
-		s.publishBookingEvent("booking.cancelled", booking)
+	return amendments, nil
+}
+
+// CompleteAmendment applies an amendment once its supplementary payment has
+// completed. When the booking was cancelled before, the amendment fails and
+// the supplementary payment is refunded instead.
+func (s *bookingService) CompleteAmendment(amendmentID uuid.UUID, paymentID uuid.UUID) error {
+	var booking *model.Booking
+	applied := false
+
+	// Lock the booking and the amendment, so that a concurrent cancellation
+	// either sees the amendment applied or fails it first
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var amendment *model.BookingAmendment
+		var err error
+		booking, amendment, err = s.lockAmendment(tx, amendmentID)
+		if err != nil {
+			return err
+		}
+
+		if amendment.Status == model.AmendmentCompleted {
+			return fmt.Errorf("amendment is not waiting for payment")
+		}
+
+		amendment.PaymentID = paymentID
+		if amendment.Status == model.AmendmentFailed || !bookingActive(booking) {
+			if amendment.Status == model.AmendmentPendingPayment {
+				amendment.Status = model.AmendmentFailed
+				amendment.Error = "booking is no longer active"
+			}
+			if err := s.amendmentRepo.WithTx(tx).Fail(amendment); err != nil {
+				return fmt.Errorf("failed to fail amendment: %w", err)
+			}
+
+			// Publish booking amended event refunding the supplementary payment
+			return s.publishAmendmentRefund(tx, booking, amendment)
+		}
+
+		// Apply amendment
+		now := time.Now()
+		amendment.Status = model.AmendmentCompleted
+		amendment.CompletedAt = &now
+		if err := s.amendmentRepo.WithTx(tx).Complete(booking, amendment); err != nil {
+			return fmt.Errorf("failed to complete amendment: %w", err)
+		}
+		applied = true
+
+		// Publish booking updated event
+		return s.publishBookingEvent(tx, "booking.updated", booking)
+	})
+	if err != nil {
+		return err
 	}
 
+	if applied {
+		s.markReportStale(booking.EventID)
+	}
 	return nil
 }
 
-// publishBookingEvent publishes a booking event to RabbitMQ
-func (s *bookingService) publishBookingEvent(eventType string, booking *model.Booking) {
-	// Create event payload
-	payload := map[string]interface{}{
-		"event_type": eventType,
-		"booking_id": booking.ID.String(),
-		"user_id":    booking.UserID.String(),
-		"event_id":   booking.EventID.String(),
-		"status":     booking.Status,
-		"timestamp":  time.Now(),
+// FailAmendment releases the tickets held for an amendment whose supplementary payment failed
+func (s *bookingService) FailAmendment(amendmentID uuid.UUID, reason string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		_, amendment, err := s.lockAmendment(tx, amendmentID)
+		if err != nil {
+			return err
+		}
+
+		// A cancellation already failed the amendment and released its tickets
+		if amendment.Status == model.AmendmentFailed {
+			return nil
+		}
+
+		if amendment.Status != model.AmendmentPendingPayment {
+			return fmt.Errorf("amendment is not waiting for payment")
+		}
+
+		// Release held tickets
+		amendment.Status = model.AmendmentFailed
+		amendment.Error = reason
+		if err := s.amendmentRepo.WithTx(tx).Fail(amendment); err != nil {
+			return fmt.Errorf("failed to fail amendment: %w", err)
+		}
+		return nil
+	})
+}
+
+// lockAmendment finds an amendment and its booking and locks both in tx,
+// the booking first like the other booking changes
+func (s *bookingService) lockAmendment(tx *gorm.DB, amendmentID uuid.UUID) (*model.Booking, *model.BookingAmendment, error) {
+	// Find amendment by ID
+	amendment, err := s.amendmentRepo.WithTx(tx).FindByID(amendmentID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find amendment: %w", err)
+	}
+
+	if amendment == nil {
+		return nil, nil, fmt.Errorf("amendment not found")
 	}
 
-	// Convert payload to JSON
-	payloadJSON, err := json.Marshal(payload)
+	booking, err := s.bookingRepo.WithTx(tx).FindByIDForUpdate(amendment.BookingID)
 	if err != nil {
-		logrus.WithError(err).Error("Failed to marshal booking event")
-		return
+		return nil, nil, fmt.Errorf("failed to find booking: %w", err)
 	}
 
-	// Publish event to RabbitMQ
-	err = s.rmq.PublishMessage("ticket_events", eventType, payloadJSON)
+	if booking == nil {
+		return nil, nil, fmt.Errorf("booking not found")
+	}
+
+	// Read the amendment again under the lock, as it may have changed meanwhile
+	amendment, err = s.amendmentRepo.WithTx(tx).FindByIDForUpdate(amendmentID)
 	if err != nil {
-		logrus.WithError(err).Error("Failed to publish booking event")
+		return nil, nil, fmt.Errorf("failed to find amendment: %w", err)
+	}
+
+	if amendment == nil {
+		return nil, nil, fmt.Errorf("amendment not found")
+	}
+
+	return booking, amendment, nil
+}
+
+// failPendingAmendment fails the amendment of a locked booking that is
+// waiting for payment and releases its held tickets in tx
+func (s *bookingService) failPendingAmendment(tx *gorm.DB, booking *model.Booking, reason string) error {
+	amendment, err := s.amendmentRepo.WithTx(tx).FindPendingByBookingID(booking.ID)
+	if err != nil {
+		return fmt.Errorf("failed to find amendments: %w", err)
+	}
+
+	if amendment == nil {
+		return nil
+	}
+
+	amendment.Status = model.AmendmentFailed
+	amendment.Error = reason
+	if err := s.amendmentRepo.WithTx(tx).Fail(amendment); err != nil {
+		return fmt.Errorf("failed to fail amendment: %w", err)
+	}
+	return nil
+}
+
+// bookingActive reports whether a booking is pending or confirmed
+func bookingActive(booking *model.Booking) bool {
+	return booking.Status == "pending" || booking.Status == "confirmed"
+}
+
+// markReportStale flags the sales report rollups of an event for rebuilding
+func (s *bookingService) markReportStale(eventID uuid.UUID) {
+	if err := s.reportRepo.MarkStale(eventID); err != nil {
+		logrus.WithError(err).Errorf("Failed to mark report of event %s as stale", eventID)
+	}
+}
+
+// publishBookingEvent writes a booking created or updated event to the outbox in tx
+func (s *bookingService) publishBookingEvent(tx *gorm.DB, eventType string, booking *model.Booking) error {
+	var event contracts.Event
+	switch eventType {
+	case contracts.TypeBookingCreated:
+		event = contracts.BookingCreated{BookingState: bookingState(booking)}
+	case contracts.TypeBookingUpdated:
+		event = contracts.BookingUpdated{BookingState: bookingState(booking)}
+	default:
+		return fmt.Errorf("unknown booking event type %s", eventType)
+	}
+
+	return messaging.EnqueueEvent(s.outboxRepo, tx, "ticket_events", booking.ID.String(), event)
+}
+
+// publishCancellationEvent writes a booking cancelled event with its refund to the outbox in tx
+func (s *bookingService) publishCancellationEvent(tx *gorm.DB, booking *model.Booking, quote *model.RefundQuote) error {
+	event := contracts.BookingCancelled{
+		BookingState:     bookingState(booking),
+		RefundAmount:     quote.RefundAmount,
+		RefundPercentage: quote.Percentage,
+		NonRefundableFee: quote.NonRefundableFee,
+		RefundReason:     quote.Reason,
+	}
+
+	return messaging.EnqueueEvent(s.outboxRepo, tx, "ticket_events", booking.ID.String(), event)
+}
+
+// publishAmendmentEvent writes a booking amended event to the outbox in tx
+func (s *bookingService) publishAmendmentEvent(tx *gorm.DB, booking *model.Booking, amendment *model.BookingAmendment) error {
+	event := contracts.BookingAmended{
+		BookingState:     bookingState(booking),
+		AmendmentID:      amendment.ID,
+		AmendmentStatus:  amendment.Status,
+		PaymentAction:    amendment.PaymentAction,
+		PreviousTotal:    amendment.PreviousTotal,
+		NewTotal:         amendment.NewTotal,
+		AmountDifference: amendment.PriceDifference,
 	}
-}
\ No newline at end of file
+
+	return messaging.EnqueueEvent(s.outboxRepo, tx, "ticket_events", booking.ID.String(), event)
+}
+
+// publishAmendmentRefund writes a booking amended event to the outbox in tx
+// that refunds the supplementary payment of a failed amendment
+func (s *bookingService) publishAmendmentRefund(tx *gorm.DB, booking *model.Booking, amendment *model.BookingAmendment) error {
+	event := contracts.BookingAmended{
+		BookingState:     bookingState(booking),
+		AmendmentID:      amendment.ID,
+		AmendmentStatus:  amendment.Status,
+		PaymentAction:    model.AmendmentPaymentRefund,
+		PreviousTotal:    amendment.PreviousTotal,
+		NewTotal:         amendment.PreviousTotal,
+		AmountDifference: -amendment.PriceDifference,
+	}
+
+	return messaging.EnqueueEvent(s.outboxRepo, tx, "ticket_events", booking.ID.String(), event)
+}
+
+// bookingState returns the state of a booking carried by the booking events
+func bookingState(booking *model.Booking) contracts.BookingState {
+	return contracts.BookingState{
+		BookingID: booking.ID,
+		UserID:    booking.UserID,
+		EventID:   booking.EventID,
+		Status:    booking.Status,
+	}
+}
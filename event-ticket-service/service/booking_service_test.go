@@ -0,0 +1,310 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/ticket-system/contracts"
+	"github.com/yourusername/ticket-system/event-ticket-service/model"
+	"github.com/yourusername/ticket-system/event-ticket-service/repository"
+	"github.com/yourusername/ticket-system/messaging"
+	"gorm.io/gorm"
+)
+
+// setupBookingService creates a booking service on the test database
+func setupBookingService(t *testing.T, db *gorm.DB) BookingService {
+	return NewBookingService(
+		repository.NewBookingRepository(db),
+		repository.NewEventRepository(db),
+		repository.NewTicketRepository(db),
+		repository.NewReportRepository(db),
+		repository.NewAmendmentRepository(db),
+		repository.NewRefundPolicyRepository(db),
+		messaging.NewOutboxRepository(db),
+		repository.NewSagaRepository(db),
+		db,
+	)
+}
+
+// amendRequest decodes an amendment request from JSON
+func amendRequest(t *testing.T, body string) model.AmendBookingRequest {
+	var req model.AmendBookingRequest
+	require.NoError(t, json.Unmarshal([]byte(body), &req))
+	return req
+}
+
+// findBooking loads a booking with its tickets
+func findBooking(t *testing.T, db *gorm.DB, id uuid.UUID) *model.Booking {
+	booking, err := repository.NewBookingRepository(db).FindByID(id)
+	require.NoError(t, err)
+	require.NotNil(t, booking)
+	return booking
+}
+
+func TestBookingService_AmendPaidBookingHoldsTicketsUntilPayment(t *testing.T) {
+	db := setupTestDB(t)
+	bookingService := setupBookingService(t, db)
+
+	event := createTestEvent(t, db, 30*24*time.Hour, 2, map[string]float64{"regular": 100, "vip": 250})
+	booking := sellTickets(t, db, event, "regular", 1, "confirmed", time.Now())
+
+	amendment, err := bookingService.AmendBooking(booking.ID, booking.UserID, amendRequest(t, `{"add":[{"type":"vip","quantity":1}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, model.AmendmentPendingPayment, amendment.Status)
+	assert.Equal(t, model.AmendmentPaymentCharge, amendment.PaymentAction)
+	assert.Equal(t, 250.0, amendment.PriceDifference)
+	assert.Equal(t, 350.0, amendment.NewTotal)
+
+	// The new ticket is held but the booking is unchanged until payment
+	require.Len(t, amendment.Items, 1)
+	var held model.Ticket
+	require.NoError(t, db.First(&held, "id = ?", amendment.Items[0].TicketID).Error)
+	assert.Equal(t, "reserved", held.Status)
+	assert.Equal(t, booking.ID, held.BookingID)
+	assert.Equal(t, 100.0, findBooking(t, db, booking.ID).TotalPrice)
+
+	// Only one amendment may wait for payment
+	_, err = bookingService.AmendBooking(booking.ID, booking.UserID, amendRequest(t, `{"add":[{"type":"vip","quantity":1}]}`))
+	assert.EqualError(t, err, "booking already has an amendment waiting for payment")
+
+	paymentID := uuid.New()
+	require.NoError(t, bookingService.CompleteAmendment(amendment.ID, paymentID))
+
+	amended := findBooking(t, db, booking.ID)
+	assert.Equal(t, 350.0, amended.TotalPrice)
+	assert.Len(t, amended.Tickets, 2)
+	require.NoError(t, db.First(&held, "id = ?", amendment.Items[0].TicketID).Error)
+	assert.Equal(t, "sold", held.Status)
+}
+
+func TestBookingService_AmendPendingBookingAppliesAtOnce(t *testing.T) {
+	db := setupTestDB(t)
+	bookingService := setupBookingService(t, db)
+
+	event := createTestEvent(t, db, 30*24*time.Hour, 2, map[string]float64{"regular": 100, "vip": 250})
+	booking := sellTickets(t, db, event, "regular", 2, "pending", time.Now())
+	booking = findBooking(t, db, booking.ID)
+	upgraded, removed := booking.Tickets[0], booking.Tickets[1]
+
+	body, err := json.Marshal(map[string]interface{}{
+		"remove":  []uuid.UUID{removed.ID},
+		"upgrade": []map[string]interface{}{{"ticket_id": upgraded.ID, "type": "vip"}},
+	})
+	require.NoError(t, err)
+
+	amendment, err := bookingService.AmendBooking(booking.ID, booking.UserID, amendRequest(t, string(body)))
+	require.NoError(t, err)
+	assert.Equal(t, model.AmendmentCompleted, amendment.Status)
+	assert.Equal(t, model.AmendmentPaymentAdjust, amendment.PaymentAction)
+	assert.Equal(t, 50.0, amendment.PriceDifference)
+
+	amended := findBooking(t, db, booking.ID)
+	assert.Equal(t, 250.0, amended.TotalPrice)
+	require.Len(t, amended.Tickets, 1)
+	assert.Equal(t, "vip", amended.Tickets[0].Type)
+
+	// Released tickets are available again
+	var released []model.Ticket
+	require.NoError(t, db.Where("id IN ?", []uuid.UUID{upgraded.ID, removed.ID}).Find(&released).Error)
+	for _, ticket := range released {
+		assert.Equal(t, "available", ticket.Status)
+		assert.Equal(t, uuid.Nil, ticket.BookingID)
+	}
+}
+
+func TestBookingService_AmendChecksAvailability(t *testing.T) {
+	db := setupTestDB(t)
+	bookingService := setupBookingService(t, db)
+
+	event := createTestEvent(t, db, 30*24*time.Hour, 1, map[string]float64{"regular": 100, "vip": 250})
+	first := sellTickets(t, db, event, "regular", 1, "confirmed", time.Now())
+
+	// The last VIP ticket is held for the first amendment
+	_, err := bookingService.AmendBooking(first.ID, first.UserID, amendRequest(t, `{"add":[{"type":"vip","quantity":1}]}`))
+	require.NoError(t, err)
+
+	other := &model.Booking{UserID: uuid.New(), EventID: event.ID, Status: "confirmed"}
+	require.NoError(t, db.Create(other).Error)
+	_, err = bookingService.AmendBooking(other.ID, other.UserID, amendRequest(t, `{"add":[{"type":"vip","quantity":1}]}`))
+	assert.EqualError(t, err, "not enough tickets available for type vip")
+
+	// A failed amendment changes nothing
+	var amendments int64
+	require.NoError(t, db.Model(&model.BookingAmendment{}).Where("booking_id = ?", other.ID).Count(&amendments).Error)
+	assert.Equal(t, int64(0), amendments)
+}
+
+func TestBookingService_AmendRejectsInvalidChanges(t *testing.T) {
+	db := setupTestDB(t)
+	bookingService := setupBookingService(t, db)
+
+	event := createTestEvent(t, db, 30*24*time.Hour, 2, map[string]float64{"regular": 100})
+	booking := findBooking(t, db, sellTickets(t, db, event, "regular", 1, "confirmed", time.Now()).ID)
+	ticketID := booking.Tickets[0].ID
+
+	_, err := bookingService.AmendBooking(booking.ID, booking.UserID, amendRequest(t, `{}`))
+	assert.EqualError(t, err, "amendment has no changes")
+
+	_, err = bookingService.AmendBooking(booking.ID, booking.UserID, amendRequest(t, `{"remove":["`+ticketID.String()+`"]}`))
+	assert.EqualError(t, err, "amendment would leave the booking without tickets, cancel it instead")
+
+	_, err = bookingService.AmendBooking(booking.ID, booking.UserID, amendRequest(t, `{"upgrade":[{"ticket_id":"`+ticketID.String()+`","type":"regular"}]}`))
+	assert.EqualError(t, err, "ticket "+ticketID.String()+" is already of type regular")
+
+	_, err = bookingService.AmendBooking(booking.ID, booking.UserID, amendRequest(t, `{"remove":["`+uuid.NewString()+`"],"add":[{"type":"regular","quantity":1}]}`))
+	assert.ErrorContains(t, err, "does not belong to this booking")
+
+	cancelled := sellTickets(t, db, event, "regular", 1, "cancelled", time.Now())
+	_, err = bookingService.AmendBooking(cancelled.ID, cancelled.UserID, amendRequest(t, `{"add":[{"type":"regular","quantity":1}]}`))
+	assert.EqualError(t, err, "only pending or confirmed bookings can be amended")
+
+	_, err = bookingService.AmendBooking(uuid.New(), booking.UserID, amendRequest(t, `{}`))
+	assert.EqualError(t, err, "booking not found")
+}
+
+// cancellationEvents decodes the booking cancelled events written to the outbox
+func cancellationEvents(t *testing.T, db *gorm.DB, bookingID uuid.UUID) []contracts.BookingCancelled {
+	var messages []messaging.OutboxMessage
+	require.NoError(t, db.Where("routing_key = ?", contracts.TypeBookingCancelled).Find(&messages).Error)
+
+	var events []contracts.BookingCancelled
+	for _, message := range messages {
+		envelope, err := contracts.Parse(message.Payload)
+		require.NoError(t, err)
+
+		var event contracts.BookingCancelled
+		require.NoError(t, envelope.Decode(&event))
+		if event.BookingID == bookingID {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func TestBookingService_CancelBookingRefundsByPolicy(t *testing.T) {
+	db := setupTestDB(t)
+	bookingService := setupBookingService(t, db)
+	refundPolicyService := NewRefundPolicyService(repository.NewRefundPolicyRepository(db), repository.NewEventRepository(db))
+
+	event := createTestEvent(t, db, 100*time.Hour, 2, map[string]float64{"regular": 100})
+	_, err := refundPolicyService.SetRefundPolicy(event.ID, refundPolicyRequest(t,
+		`{"deadline_hours":24,"non_refundable_fee":5,"tiers":[{"hours_before_event":168,"percentage":100},{"hours_before_event":72,"percentage":50}]}`))
+	require.NoError(t, err)
+	booking := sellTickets(t, db, event, "regular", 2, "confirmed", time.Now())
+
+	quote, err := bookingService.GetRefundQuote(booking.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 50.0, quote.Percentage)
+	assert.Equal(t, 10.0, quote.NonRefundableFee)
+	assert.Equal(t, 95.0, quote.RefundAmount)
+
+	require.NoError(t, bookingService.CancelBooking(booking.ID))
+
+	cancelled := findBooking(t, db, booking.ID)
+	assert.Equal(t, "cancelled", cancelled.Status)
+	assert.Empty(t, cancelled.Tickets)
+
+	events := cancellationEvents(t, db, booking.ID)
+	require.Len(t, events, 1)
+	assert.Equal(t, 95.0, events[0].RefundAmount)
+	assert.Equal(t, 50.0, events[0].RefundPercentage)
+}
+
+func TestBookingService_CancelBookingTwiceRefundsOnce(t *testing.T) {
+	db := setupTestDB(t)
+	bookingService := setupBookingService(t, db)
+
+	event := createTestEvent(t, db, 30*24*time.Hour, 1, map[string]float64{"regular": 100})
+	booking := sellTickets(t, db, event, "regular", 1, "confirmed", time.Now())
+
+	require.NoError(t, bookingService.CancelBooking(booking.ID))
+	require.NoError(t, bookingService.CancelBooking(booking.ID))
+
+	// A cancellation inside a consumer transaction sees the first one too
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		return bookingService.WithTx(tx).CancelBooking(booking.ID)
+	}))
+
+	events := cancellationEvents(t, db, booking.ID)
+	require.Len(t, events, 1)
+	assert.Equal(t, 100.0, events[0].RefundAmount)
+
+	// Refunded bookings are not refunded again either
+	refunded := sellTickets(t, db, createTestEvent(t, db, 30*24*time.Hour, 1, map[string]float64{"regular": 100}), "regular", 1, "refunded", time.Now())
+	require.NoError(t, bookingService.CancelBooking(refunded.ID))
+	assert.Empty(t, cancellationEvents(t, db, refunded.ID))
+
+	assert.EqualError(t, bookingService.CancelBooking(uuid.New()), "booking not found")
+}
+
+func TestBookingService_CancelBookingFailsPendingAmendment(t *testing.T) {
+	db := setupTestDB(t)
+	bookingService := setupBookingService(t, db)
+
+	event := createTestEvent(t, db, 30*24*time.Hour, 1, map[string]float64{"regular": 100, "vip": 250})
+	booking := sellTickets(t, db, event, "regular", 1, "confirmed", time.Now())
+
+	amendment, err := bookingService.AmendBooking(booking.ID, booking.UserID, amendRequest(t, `{"add":[{"type":"vip","quantity":1}]}`))
+	require.NoError(t, err)
+	require.NoError(t, bookingService.CancelBooking(booking.ID))
+
+	// The held ticket is released with the booking
+	var held model.Ticket
+	require.NoError(t, db.First(&held, "id = ?", amendment.Items[0].TicketID).Error)
+	assert.Equal(t, "available", held.Status)
+	assert.Equal(t, uuid.Nil, held.BookingID)
+
+	failed, err := repository.NewAmendmentRepository(db).FindByID(amendment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.AmendmentFailed, failed.Status)
+
+	// A supplementary payment completing afterwards is refunded, not applied
+	require.NoError(t, bookingService.CompleteAmendment(amendment.ID, uuid.New()))
+	cancelled := findBooking(t, db, booking.ID)
+	assert.Equal(t, "cancelled", cancelled.Status)
+	assert.Equal(t, 100.0, cancelled.TotalPrice)
+	assert.Empty(t, cancelled.Tickets)
+
+	var messages []messaging.OutboxMessage
+	require.NoError(t, db.Where("routing_key = ?", contracts.TypeBookingAmended).Order("created_at ASC").Find(&messages).Error)
+	require.Len(t, messages, 2)
+	envelope, err := contracts.Parse(messages[1].Payload)
+	require.NoError(t, err)
+	var refund contracts.BookingAmended
+	require.NoError(t, envelope.Decode(&refund))
+	assert.Equal(t, model.AmendmentPaymentRefund, refund.PaymentAction)
+	assert.Equal(t, -250.0, refund.AmountDifference)
+
+	// A failed supplementary payment has nothing left to release
+	require.NoError(t, bookingService.FailAmendment(amendment.ID, "payment failed"))
+}
+
+func TestBookingService_CheckInTicketAdmitsOnce(t *testing.T) {
+	db := setupTestDB(t)
+	bookingService := setupBookingService(t, db)
+
+	event := createTestEvent(t, db, 30*24*time.Hour, 2, map[string]float64{"regular": 100})
+	booking := sellTickets(t, db, event, "regular", 1, "confirmed", time.Now())
+	ticketID := findBooking(t, db, booking.ID).Tickets[0].ID
+
+	ticket, err := bookingService.CheckInTicket(ticketID)
+	require.NoError(t, err)
+	require.NotNil(t, ticket.CheckedInAt)
+
+	// A ticket scanned twice is rejected the second time
+	_, err = bookingService.CheckInTicket(ticketID)
+	assert.EqualError(t, err, "ticket already checked in")
+
+	// Unsold tickets cannot be used for entry
+	var available model.Ticket
+	require.NoError(t, db.Where("event_id = ? AND status = ?", event.ID, "available").First(&available).Error)
+	_, err = bookingService.CheckInTicket(available.ID)
+	assert.EqualError(t, err, "ticket is not valid for check-in")
+
+	_, err = bookingService.CheckInTicket(uuid.New())
+	assert.EqualError(t, err, "ticket not found")
+}
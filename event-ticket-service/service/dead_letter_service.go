@@ -0,0 +1,121 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/yourusername/ticket-system/event-ticket-service/model"
+	"github.com/yourusername/ticket-system/messaging"
+)
+
+// Dead letter listing limits
+const (
+	defaultDeadLetterLimit = 50
+	maxDeadLetterLimit     = 500
+)
+
+// DeadLetterService defines the interface for inspecting and replaying dead letters
+type DeadLetterService interface {
+	ListDeadLetters(queueName string, limit int) ([]model.DeadLetter, error)
+	ReplayDeadLetter(queueName, messageID string, body json.RawMessage) error
+	DiscardDeadLetter(queueName, messageID string) error
+}
+
+// deadLetterService implements DeadLetterService interface
+type deadLetterService struct {
+	broker messaging.Broker
+}
+
+// NewDeadLetterService creates a new dead letter service
+func NewDeadLetterService(broker messaging.Broker) DeadLetterService {
+	return &deadLetterService{
+		broker: broker,
+	}
+}
+
+// ListDeadLetters lists the dead letters of a consumer queue, oldest first
+func (s *deadLetterService) ListDeadLetters(queueName string, limit int) ([]model.DeadLetter, error) {
+	if queueName == "" {
+		return nil, fmt.Errorf("queue is required")
+	}
+
+	if limit <= 0 {
+		limit = defaultDeadLetterLimit
+	}
+	if limit > maxDeadLetterLimit {
+		limit = maxDeadLetterLimit
+	}
+
+	messages, err := s.broker.DeadLetters(queueName, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead letters: %w", err)
+	}
+
+	deadLetters := make([]model.DeadLetter, len(messages))
+	for i, msg := range messages {
+		deadLetters[i] = toDeadLetter(queueName, msg)
+	}
+	return deadLetters, nil
+}
+
+// ReplayDeadLetter publishes a dead letter again, optionally with an edited body
+func (s *deadLetterService) ReplayDeadLetter(queueName, messageID string, body json.RawMessage) error {
+	if queueName == "" {
+		return fmt.Errorf("queue is required")
+	}
+
+	var replacement []byte
+	if len(body) > 0 {
+		if !json.Valid(body) {
+			return fmt.Errorf("body must be valid JSON")
+		}
+		replacement = body
+	}
+
+	if err := s.broker.ReplayDeadLetter(queueName, messageID, replacement); err != nil {
+		if errors.Is(err, messaging.ErrDeadLetterNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to replay dead letter: %w", err)
+	}
+	return nil
+}
+
+// DiscardDeadLetter removes a dead letter without replaying it
+func (s *deadLetterService) DiscardDeadLetter(queueName, messageID string) error {
+	if queueName == "" {
+		return fmt.Errorf("queue is required")
+	}
+
+	if err := s.broker.DiscardDeadLetter(queueName, messageID); err != nil {
+		if errors.Is(err, messaging.ErrDeadLetterNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to discard dead letter: %w", err)
+	}
+	return nil
+}
+
+// toDeadLetter converts a message of a dead-letter queue to a dead letter
+func toDeadLetter(queueName string, msg messaging.Message) model.DeadLetter {
+	deadLetter := model.DeadLetter{
+		MessageID:      msg.ID,
+		Queue:          queueName,
+		Exchange:       msg.Exchange,
+		RoutingKey:     msg.RoutingKey,
+		Attempts:       msg.Attempts,
+		LastError:      msg.LastError,
+		PublishedAt:    msg.Timestamp,
+		DeadLetteredAt: msg.DeadLetteredAt,
+		Body:           json.RawMessage(msg.Body),
+	}
+
+	// Keep bodies that are not JSON readable in the response
+	if !json.Valid(msg.Body) {
+		quoted, _ := json.Marshal(string(msg.Body))
+		deadLetter.Body = quoted
+	}
+
+	return deadLetter
+}
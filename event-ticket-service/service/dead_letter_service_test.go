@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/ticket-system/messaging"
+)
+
+const deadLetterTestQueue = "event_ticket_payment_events"
+
+// setupDeadLetterService creates a dead letter service on a broker whose
+// payment consumer fails on messages without a booking ID
+func setupDeadLetterService(t *testing.T) (DeadLetterService, *messaging.MemoryBroker, *[]json.RawMessage) {
+	broker := messaging.NewMemoryBroker()
+	require.NoError(t, broker.DeclareExchange("payment_events"))
+	t.Cleanup(func() { broker.Close(context.Background()) })
+
+	var handled []json.RawMessage
+	err := broker.Consume(context.Background(), messaging.ConsumerOptions{
+		Queue:       deadLetterTestQueue,
+		Exchange:    "payment_events",
+		RoutingKeys: []string{"payment.#"},
+	}, func(msg messaging.Message) error {
+		var event struct {
+			BookingID string `json:"booking_id"`
+		}
+		if err := json.Unmarshal(msg.Body, &event); err != nil {
+			return err
+		}
+		if event.BookingID == "" {
+			return errors.New("booking_id is required")
+		}
+		handled = append(handled, msg.Body)
+		return nil
+	})
+	require.NoError(t, err)
+
+	return NewDeadLetterService(broker), broker, &handled
+}
+
+// publishPayment publishes a payment completed message with a body
+func publishPayment(t *testing.T, broker *messaging.MemoryBroker, id, body string) {
+	require.NoError(t, broker.Publish(context.Background(), "payment_events", "payment.completed", messaging.Message{
+		ID:          id,
+		ContentType: "application/json",
+		Body:        []byte(body),
+	}))
+}
+
+func TestDeadLetterService_PoisonMessageIsDeadLetteredAndReplayed(t *testing.T) {
+	deadLetterService, broker, handled := setupDeadLetterService(t)
+
+	publishPayment(t, broker, "message-1", `{"amount":100}`)
+	publishPayment(t, broker, "message-2", `{"booking_id":"b-2"}`)
+	assert.Len(t, *handled, 1)
+
+	deadLetters, err := deadLetterService.ListDeadLetters(deadLetterTestQueue, 0)
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 1)
+	assert.Equal(t, "message-1", deadLetters[0].MessageID)
+	assert.Equal(t, deadLetterTestQueue, deadLetters[0].Queue)
+	assert.Equal(t, "payment_events", deadLetters[0].Exchange)
+	assert.Equal(t, "payment.completed", deadLetters[0].RoutingKey)
+	assert.Equal(t, messaging.MaxDeliveryAttempts, deadLetters[0].Attempts)
+	assert.Equal(t, "booking_id is required", deadLetters[0].LastError)
+	assert.NotNil(t, deadLetters[0].DeadLetteredAt)
+	assert.JSONEq(t, `{"amount":100}`, string(deadLetters[0].Body))
+
+	// An edited body must still be JSON
+	err = deadLetterService.ReplayDeadLetter(deadLetterTestQueue, "message-1", json.RawMessage(`{"booking_id":`))
+	assert.EqualError(t, err, "body must be valid JSON")
+
+	require.NoError(t, deadLetterService.ReplayDeadLetter(deadLetterTestQueue, "message-1", json.RawMessage(`{"booking_id":"b-1","amount":100}`)))
+	require.Len(t, *handled, 2)
+	assert.JSONEq(t, `{"booking_id":"b-1","amount":100}`, string((*handled)[1]))
+
+	deadLetters, err = deadLetterService.ListDeadLetters(deadLetterTestQueue, 0)
+	require.NoError(t, err)
+	assert.Empty(t, deadLetters)
+
+	// A replayed message is gone from the dead-letter queue
+	err = deadLetterService.ReplayDeadLetter(deadLetterTestQueue, "message-1", nil)
+	assert.ErrorIs(t, err, messaging.ErrDeadLetterNotFound)
+}
+
+func TestDeadLetterService_DiscardAndListLimits(t *testing.T) {
+	deadLetterService, broker, _ := setupDeadLetterService(t)
+
+	publishPayment(t, broker, "message-1", `not json`)
+	publishPayment(t, broker, "message-2", `{}`)
+
+	// Bodies that are not JSON are listed as strings
+	deadLetters, err := deadLetterService.ListDeadLetters(deadLetterTestQueue, 1)
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 1)
+	assert.JSONEq(t, `"not json"`, string(deadLetters[0].Body))
+
+	require.NoError(t, deadLetterService.DiscardDeadLetter(deadLetterTestQueue, "message-1"))
+	assert.ErrorIs(t, deadLetterService.DiscardDeadLetter(deadLetterTestQueue, "message-1"), messaging.ErrDeadLetterNotFound)
+
+	deadLetters, err = deadLetterService.ListDeadLetters(deadLetterTestQueue, 1000)
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 1)
+	assert.Equal(t, "message-2", deadLetters[0].MessageID)
+
+	_, err = deadLetterService.ListDeadLetters("", 10)
+	assert.EqualError(t, err, "queue is required")
+}
@@ -1,15 +1,23 @@
 package service
 
 import (
+	"bufio"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
-	"github.com/yourusername/ticket-system/event-ticket-service/config"
+	"github.com/yourusername/ticket-system/contracts"
 	"github.com/yourusername/ticket-system/event-ticket-service/model"
 	"github.com/yourusername/ticket-system/event-ticket-service/repository"
+	"github.com/yourusername/ticket-system/messaging"
+	"gorm.io/gorm"
 )
 
 // EventService defines the interface for event service operations
@@ -20,21 +28,26 @@ type EventService interface {
 	DeleteEvent(id uuid.UUID) error
 	SearchEvents(req model.SearchEventRequest) ([]model.EventResponse, int64, error)
 	GetAllEvents(page, pageSize int) ([]model.EventResponse, int64, error)
+	ImportEvents(r io.Reader, format string, dryRun bool) (*model.ImportResult, error)
 }
 
 // eventService implements EventService interface
 type eventService struct {
 	eventRepo  repository.EventRepository
 	ticketRepo repository.TicketRepository
-	rmq        *config.RabbitMQ
+	reportRepo repository.ReportRepository
+	outboxRepo messaging.OutboxRepository
+	db         *gorm.DB
 }
 
 // NewEventService creates a new event service
-func NewEventService(eventRepo repository.EventRepository, ticketRepo repository.TicketRepository, rmq *config.RabbitMQ) EventService {
+func NewEventService(eventRepo repository.EventRepository, ticketRepo repository.TicketRepository, reportRepo repository.ReportRepository, outboxRepo messaging.OutboxRepository, db *gorm.DB) EventService {
 	return &eventService{
 		eventRepo:  eventRepo,
 		ticketRepo: ticketRepo,
-		rmq:        rmq,
+		reportRepo: reportRepo,
+		outboxRepo: outboxRepo,
+		db:         db,
 	}
 }
 
@@ -42,48 +55,59 @@ func NewEventService(eventRepo repository.EventRepository, ticketRepo repository
 func (s *eventService) CreateEvent(req model.CreateEventRequest) (*model.EventResponse, error) {
 	// Create event
 	event := &model.Event{
-		Name:        req.Name,
-		Description: req.Description,
-		Location:    req.Location,
-		StartDate:   req.StartDate,
-		EndDate:     req.EndDate,
-		Category:    req.Category,
-		Organizer:   req.Organizer,
-		ImageURL:    req.ImageURL,
-		Status:      "active",
-	}
-
-	// Save event to database
-	if err := s.eventRepo.Create(event); err != nil {
-		return nil, fmt.Errorf("failed to create event: %w", err)
-	}
-
-	// Create tickets for the event
-	tickets := make([]*model.Ticket, 0)
-	for _, ticketReq := range req.Tickets {
-		for i := 0; i < ticketReq.Quantity; i++ {
-			tickets = append(tickets, &model.Ticket{
-				EventID: event.ID,
-				Type:    ticketReq.Type,
-				Price:   ticketReq.Price,
-				Status:  "available",
-			})
-		}
+		OrganizationID: req.OrganizationID,
+		Name:           req.Name,
+		Description:    req.Description,
+		Location:       req.Location,
+		StartDate:      req.StartDate,
+		EndDate:        req.EndDate,
+		Category:       req.Category,
+		Organizer:      req.Organizer,
+		ImageURL:       req.ImageURL,
+		Status:         "active",
 	}
 
-	// Save tickets to database
-	if err := s.ticketRepo.CreateBatch(tickets); err != nil {
-		return nil, fmt.Errorf("failed to create tickets: %w", err)
-	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Save event to database
+		if err := s.eventRepo.WithTx(tx).Create(event); err != nil {
+			return fmt.Errorf("failed to create event: %w", err)
+		}
+
+		// Create tickets for the event
+		tickets := make([]*model.Ticket, 0)
+		for _, ticketReq := range req.Tickets {
+			for i := 0; i < ticketReq.Quantity; i++ {
+				tickets = append(tickets, &model.Ticket{
+					EventID: event.ID,
+					Type:    ticketReq.Type,
+					Price:   ticketReq.Price,
+					Status:  "available",
+				})
+			}
+		}
+
+		// Save tickets to database
+		if err := s.ticketRepo.WithTx(tx).CreateBatch(tickets); err != nil {
+			return fmt.Errorf("failed to create tickets: %w", err)
+		}
+
+		// Set tickets in event
+		event.Tickets = make([]model.Ticket, len(tickets))
+		for i, ticket := range tickets {
+			event.Tickets[i] = *ticket
+		}
 
-	// Set tickets in event
-	event.Tickets = make([]model.Ticket, len(tickets))
-	for i, ticket := range tickets {
-		event.Tickets[i] = *ticket
+		// Publish event created event
+		return s.publishEventEvent(tx, "event.created", event)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// Publish event created event
-	s.publishEventEvent("event.created", event)
+	// Flag the sales report for its first rollup
+	if err := s.reportRepo.MarkStale(event.ID); err != nil {
+		logrus.WithError(err).Errorf("Failed to mark report of event %s as stale", event.ID)
+	}
 
 	// Return event response
 	eventResponse := event.ToResponse()
@@ -148,13 +172,18 @@ func (s *eventService) UpdateEvent(id uuid.UUID, req model.UpdateEventRequest) (
 		event.Status = req.Status
 	}
 
-	// Save event to database
-	if err := s.eventRepo.Update(event); err != nil {
-		return nil, fmt.Errorf("failed to update event: %w", err)
-	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// Save event to database
+		if err := s.eventRepo.WithTx(tx).Update(event); err != nil {
+			return fmt.Errorf("failed to update event: %w", err)
+		}
 
-	// Publish event updated event
-	s.publishEventEvent("event.updated", event)
+		// Publish event updated event
+		return s.publishEventEvent(tx, "event.updated", event)
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	// Return event response
 	eventResponse := event.ToResponse()
@@ -173,15 +202,15 @@ func (s *eventService) DeleteEvent(id uuid.UUID) error {
 		return fmt.Errorf("event not found")
 	}
 
-	// Delete event from database
-	if err := s.eventRepo.Delete(id); err != nil {
-		return fmt.Errorf("failed to delete event: %w", err)
-	}
-
-	// Publish event deleted event
-	s.publishEventEvent("event.deleted", event)
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// Delete event from database
+		if err := s.eventRepo.WithTx(tx).Delete(id); err != nil {
+			return fmt.Errorf("failed to delete event: %w", err)
+		}
 
-	return nil
+		// Publish event deleted event
+		return s.publishEventEvent(tx, "event.deleted", event)
+	})
 }
 
 // SearchEvents searches for events based on criteria
@@ -218,26 +247,508 @@ func (s *eventService) GetAllEvents(page, pageSize int) ([]model.EventResponse,
 	return responses, total, nil
 }
 
-// publishEventEvent publishes an event event to RabbitMQ
-func (s *eventService) publishEventEvent(eventType string, event *model.Event) {
-	// Create event payload
-	payload := map[string]interface{}{
-		"event_type": eventType,
-		"event_id":   event.ID.String(),
-		"name":       event.Name,
-		"timestamp":  time.Now(),
+// publishEventEvent writes an event event to the outbox in tx
+func (s *eventService) publishEventEvent(tx *gorm.DB, eventType string, event *model.Event) error {
+	state := contracts.EventState{
+		EventID: event.ID,
+		Name:    event.Name,
+	}
+
+	var message contracts.Event
+	switch eventType {
+	case contracts.TypeEventCreated:
+		message = contracts.EventCreated{EventState: state}
+	case contracts.TypeEventUpdated:
+		message = contracts.EventUpdated{EventState: state}
+	case contracts.TypeEventDeleted:
+		message = contracts.EventDeleted{EventState: state}
+	default:
+		return fmt.Errorf("unknown event event type %s", eventType)
+	}
+
+	return messaging.EnqueueEvent(s.outboxRepo, tx, "ticket_events", event.ID.String(), message)
+}
+
+// importRow is a parsed row of an import file with the problems found in it
+type importRow struct {
+	row    int
+	record model.ImportEventRecord
+	errors []string
+}
+
+// ImportEvents validates every row of a CSV or JSON lines file and, unless
+// any row is invalid or dryRun is set, upserts the events by external reference ID
+func (s *eventService) ImportEvents(r io.Reader, format string, dryRun bool) (*model.ImportResult, error) {
+	// Parse rows
+	var rows []importRow
+	var err error
+	switch format {
+	case model.ImportFormatCSV:
+		rows, err = parseImportCSV(r)
+	case model.ImportFormatJSONL:
+		rows, err = parseImportJSONL(r)
+	default:
+		return nil, fmt.Errorf("invalid import format %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate rows on their own
+	seen := make(map[string]int)
+	refs := make([]string, 0, len(rows))
+	for i := range rows {
+		row := &rows[i]
+		row.errors = append(row.errors, validateImportRecord(row.record)...)
+
+		ref := row.record.ExternalRef
+		if ref == "" {
+			continue
+		}
+		if first, exists := seen[ref]; exists {
+			row.errors = append(row.errors, fmt.Sprintf("external_ref %s is already used in row %d", ref, first))
+			continue
+		}
+		seen[ref] = row.row
+		refs = append(refs, ref)
+	}
+
+	// Load the events that already exist
+	existingEvents, err := s.eventRepo.FindByExternalRefs(refs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find events: %w", err)
+	}
+
+	existing := make(map[string]*model.Event, len(existingEvents))
+	eventIDs := make([]uuid.UUID, 0, len(existingEvents))
+	for i := range existingEvents {
+		existing[*existingEvents[i].ExternalRef] = &existingEvents[i]
+		eventIDs = append(eventIDs, existingEvents[i].ID)
 	}
 
-	// Convert payload to JSON
-	payloadJSON, err := json.Marshal(payload)
+	inventory, err := s.eventRepo.FindTicketInventory(eventIDs)
 	if err != nil {
-		logrus.WithError(err).Error("Failed to marshal event event")
-		return
+		return nil, fmt.Errorf("failed to find ticket inventory: %w", err)
+	}
+
+	inventoryByEvent := make(map[uuid.UUID]map[string]model.TicketInventory)
+	for _, inv := range inventory {
+		if inventoryByEvent[inv.EventID] == nil {
+			inventoryByEvent[inv.EventID] = make(map[string]model.TicketInventory)
+		}
+		inventoryByEvent[inv.EventID][inv.Type] = inv
+	}
+
+	// Plan the changes of every row
+	result := &model.ImportResult{
+		DryRun: dryRun,
+		Total:  len(rows),
+		Rows:   make([]model.ImportRowResult, 0, len(rows)),
 	}
+	changes := make([]model.ImportEventChange, 0, len(rows))
+	changeRows := make([]int, 0, len(rows))
+
+	for _, row := range rows {
+		if len(row.errors) == 0 {
+			change, errs := planImportChange(row.record, existing[row.record.ExternalRef], inventoryByEvent)
+			if len(errs) > 0 {
+				row.errors = errs
+			} else {
+				rowResult := model.ImportRowResult{
+					Row:         row.row,
+					ExternalRef: row.record.ExternalRef,
+					Action:      model.ImportActionUnchanged,
+				}
+				if existingEvent := existing[row.record.ExternalRef]; existingEvent != nil {
+					rowResult.EventID = existingEvent.ID
+				}
+
+				if change != nil {
+					rowResult.Action = model.ImportActionUpdate
+					if change.Create {
+						rowResult.Action = model.ImportActionCreate
+					}
+					changes = append(changes, *change)
+					changeRows = append(changeRows, len(result.Rows))
+				}
+				result.Rows = append(result.Rows, rowResult)
+				continue
+			}
+		}
 
-	// Publish event to RabbitMQ
-	err = s.rmq.PublishMessage("ticket_events", eventType, payloadJSON)
+		result.Errors = append(result.Errors, model.ImportRowError{
+			Row:         row.row,
+			ExternalRef: row.record.ExternalRef,
+			Errors:      row.errors,
+		})
+	}
+
+	// Count actions
+	for _, rowResult := range result.Rows {
+		switch rowResult.Action {
+		case model.ImportActionCreate:
+			result.Created++
+		case model.ImportActionUpdate:
+			result.Updated++
+		default:
+			result.Unchanged++
+		}
+	}
+
+	// Nothing is written unless every row is valid
+	if len(result.Errors) > 0 || dryRun {
+		return result, nil
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.eventRepo.WithTx(tx).ApplyImport(changes); err != nil {
+			return fmt.Errorf("failed to import events: %w", err)
+		}
+
+		// Publish event created or updated events
+		for _, change := range changes {
+			eventType := "event.updated"
+			if change.Create {
+				eventType = "event.created"
+			}
+			if err := s.publishEventEvent(tx, eventType, change.Event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		logrus.WithError(err).Error("Failed to publish event event")
+		return nil, err
+	}
+	result.Applied = true
+
+	for i, change := range changes {
+		result.Rows[changeRows[i]].EventID = change.Event.ID
+
+		// Inventory changed, so the sales report needs a rebuild
+		if err := s.reportRepo.MarkStale(change.Event.ID); err != nil {
+			logrus.WithError(err).Errorf("Failed to mark report of event %s as stale", change.Event.ID)
+		}
 	}
-}
\ No newline at end of file
+
+	return result, nil
+}
+
+// planImportChange works out how an imported record changes the stored event,
+// returning nil when nothing changes
+func planImportChange(record model.ImportEventRecord, event *model.Event, inventory map[uuid.UUID]map[string]model.TicketInventory) (*model.ImportEventChange, []string) {
+	status := record.Status
+
+	// Create a new event
+	if event == nil {
+		if status == "" {
+			status = "active"
+		}
+		ref := record.ExternalRef
+		change := &model.ImportEventChange{
+			Event: &model.Event{
+				ExternalRef: &ref,
+				Name:        record.Name,
+				Description: record.Description,
+				Location:    record.Location,
+				StartDate:   record.StartDate,
+				EndDate:     record.EndDate,
+				Category:    record.Category,
+				Organizer:   record.Organizer,
+				ImageURL:    record.ImageURL,
+				Status:      status,
+			},
+			Create: true,
+		}
+		for _, ticket := range record.Tickets {
+			change.Tickets = append(change.Tickets, model.ImportTicketChange{
+				Type:  ticket.Type,
+				Price: ticket.Price,
+				Add:   ticket.Quantity,
+			})
+		}
+		return change, nil
+	}
+
+	// Update an existing event
+	if status == "" {
+		status = event.Status
+	}
+	changed := event.Name != record.Name ||
+		event.Description != record.Description ||
+		event.Location != record.Location ||
+		!event.StartDate.Equal(record.StartDate) ||
+		!event.EndDate.Equal(record.EndDate) ||
+		event.Category != record.Category ||
+		event.Organizer != record.Organizer ||
+		event.ImageURL != record.ImageURL ||
+		event.Status != status
+
+	event.Name = record.Name
+	event.Description = record.Description
+	event.Location = record.Location
+	event.StartDate = record.StartDate
+	event.EndDate = record.EndDate
+	event.Category = record.Category
+	event.Organizer = record.Organizer
+	event.ImageURL = record.ImageURL
+	event.Status = status
+
+	// Bring every imported ticket type to its quantity. Ticket types that are
+	// not in the import are left alone.
+	change := &model.ImportEventChange{Event: event}
+	var errs []string
+	for _, ticket := range record.Tickets {
+		inv := inventory[event.ID][ticket.Type]
+		ticketChange := model.ImportTicketChange{
+			Type:    ticket.Type,
+			Price:   ticket.Price,
+			Reprice: inv.Available > 0 && inv.Price != ticket.Price,
+		}
+
+		booked := inv.Total - inv.Available
+		switch {
+		case ticket.Quantity > inv.Total:
+			ticketChange.Add = ticket.Quantity - inv.Total
+		case ticket.Quantity < booked:
+			errs = append(errs, fmt.Sprintf("cannot reduce %s tickets to %d, %d are already booked", ticket.Type, ticket.Quantity, booked))
+			continue
+		case ticket.Quantity < inv.Total:
+			ticketChange.Remove = inv.Total - ticket.Quantity
+		}
+
+		if ticketChange.Add > 0 || ticketChange.Remove > 0 || ticketChange.Reprice {
+			change.Tickets = append(change.Tickets, ticketChange)
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	if !changed && len(change.Tickets) == 0 {
+		return nil, nil
+	}
+	return change, nil
+}
+
+// validateImportRecord checks the fields of an imported record
+func validateImportRecord(record model.ImportEventRecord) []string {
+	var errs []string
+
+	// Validate required fields
+	if record.ExternalRef == "" {
+		errs = append(errs, "external_ref is required")
+	} else if len(record.ExternalRef) > 255 {
+		errs = append(errs, "external_ref must be at most 255 characters")
+	}
+	if record.Name == "" {
+		errs = append(errs, "name is required")
+	}
+	if record.Location == "" {
+		errs = append(errs, "location is required")
+	}
+	if record.Category == "" {
+		errs = append(errs, "category is required")
+	}
+	if record.Organizer == "" {
+		errs = append(errs, "organizer is required")
+	}
+
+	// Validate dates
+	if record.StartDate.IsZero() {
+		errs = append(errs, "start_date is required")
+	}
+	if record.EndDate.IsZero() {
+		errs = append(errs, "end_date is required")
+	}
+	if !record.StartDate.IsZero() && !record.EndDate.IsZero() && record.StartDate.After(record.EndDate) {
+		errs = append(errs, "start_date must be before end_date")
+	}
+
+	// Validate status
+	switch record.Status {
+	case "", "active", "cancelled", "completed":
+	default:
+		errs = append(errs, fmt.Sprintf("invalid status %s", record.Status))
+	}
+
+	// Validate ticket types
+	if len(record.Tickets) == 0 {
+		errs = append(errs, "at least one ticket type is required")
+	}
+	types := make(map[string]bool)
+	for _, ticket := range record.Tickets {
+		if ticket.Type == "" {
+			errs = append(errs, "ticket type is required")
+			continue
+		}
+		if types[ticket.Type] {
+			errs = append(errs, fmt.Sprintf("ticket type %s is listed more than once", ticket.Type))
+		}
+		types[ticket.Type] = true
+		if ticket.Price < 0 {
+			errs = append(errs, fmt.Sprintf("price of %s tickets must not be negative", ticket.Type))
+		}
+		if ticket.Quantity < 0 {
+			errs = append(errs, fmt.Sprintf("quantity of %s tickets must not be negative", ticket.Type))
+		}
+	}
+
+	return errs
+}
+
+// parseImportCSV parses a CSV import file with a header row. Rows are
+// numbered by their record position, counting the header as row 1.
+func parseImportCSV(r io.Reader) ([]importRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	// Read header
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+
+	index := make(map[string]int, len(header))
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(column))
+		if !containsString(model.ImportCSVColumns, column) {
+			return nil, fmt.Errorf("unknown csv column %s", column)
+		}
+		index[column] = i
+	}
+
+	rows := make([]importRow, 0)
+	for rowNumber := 2; ; rowNumber++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		row := importRow{row: rowNumber}
+		if err != nil {
+			// A wrong number of fields only affects this row
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) || parseErr.Err != csv.ErrFieldCount {
+				return nil, fmt.Errorf("failed to read csv: %w", err)
+			}
+			row.errors = append(row.errors, parseErr.Err.Error())
+		}
+
+		get := func(column string) string {
+			if i, ok := index[column]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		row.record = model.ImportEventRecord{
+			ExternalRef: get("external_ref"),
+			Name:        get("name"),
+			Description: get("description"),
+			Location:    get("location"),
+			Category:    get("category"),
+			Organizer:   get("organizer"),
+			ImageURL:    get("image_url"),
+			Status:      get("status"),
+		}
+
+		// Parse dates
+		if value := get("start_date"); value != "" {
+			if row.record.StartDate, err = parseImportTime(value); err != nil {
+				row.errors = append(row.errors, fmt.Sprintf("invalid start_date %s", value))
+			}
+		}
+		if value := get("end_date"); value != "" {
+			if row.record.EndDate, err = parseImportTime(value); err != nil {
+				row.errors = append(row.errors, fmt.Sprintf("invalid end_date %s", value))
+			}
+		}
+
+		// Parse tickets
+		tickets, errs := parseImportTickets(get("tickets"))
+		row.record.Tickets = tickets
+		row.errors = append(row.errors, errs...)
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// parseImportJSONL parses a JSON lines import file. Blank lines are skipped
+// and rows are numbered by their line.
+func parseImportJSONL(r io.Reader) ([]importRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	rows := make([]importRow, 0)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		row := importRow{row: lineNumber}
+		decoder := json.NewDecoder(strings.NewReader(line))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&row.record); err != nil {
+			row.errors = append(row.errors, fmt.Sprintf("invalid json: %v", err))
+		}
+
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read jsonl: %w", err)
+	}
+
+	return rows, nil
+}
+
+// parseImportTickets parses ticket types encoded as type:price:quantity
+// entries separated by semicolons
+func parseImportTickets(value string) ([]model.ImportTicketType, []string) {
+	var tickets []model.ImportTicketType
+	var errs []string
+
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.Split(entry, ":")
+		if len(parts) != 3 {
+			errs = append(errs, fmt.Sprintf("invalid ticket %s, expected type:price:quantity", entry))
+			continue
+		}
+
+		price, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("invalid price in ticket %s", entry))
+			continue
+		}
+
+		quantity, err := strconv.Atoi(strings.TrimSpace(parts[2]))
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("invalid quantity in ticket %s", entry))
+			continue
+		}
+
+		tickets = append(tickets, model.ImportTicketType{
+			Type:     strings.TrimSpace(parts[0]),
+			Price:    price,
+			Quantity: quantity,
+		})
+	}
+
+	return tickets, errs
+}
+
+// parseImportTime parses an RFC 3339 timestamp or a "2006-01-02 15:04" local time in UTC
+func parseImportTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02 15:04", value)
+}
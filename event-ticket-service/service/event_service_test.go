@@ -0,0 +1,189 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/ticket-system/event-ticket-service/model"
+	"github.com/yourusername/ticket-system/event-ticket-service/repository"
+	"github.com/yourusername/ticket-system/messaging"
+	"gorm.io/gorm"
+)
+
+// setupEventService creates an event service on the test database
+func setupEventService(t *testing.T, db *gorm.DB) EventService {
+	return NewEventService(
+		repository.NewEventRepository(db),
+		repository.NewTicketRepository(db),
+		repository.NewReportRepository(db),
+		messaging.NewOutboxRepository(db),
+		db,
+	)
+}
+
+// importedEvent loads an imported event by its external reference ID
+func importedEvent(t *testing.T, db *gorm.DB, ref string) *model.Event {
+	var event model.Event
+	require.NoError(t, db.Where("external_ref = ?", ref).First(&event).Error)
+	return &event
+}
+
+// countTickets counts the tickets of a type of an event with a status, or
+// with any status when status is empty
+func countTickets(t *testing.T, db *gorm.DB, event *model.Event, ticketType, status string) int64 {
+	query := db.Model(&model.Ticket{}).Where("event_id = ? AND type = ?", event.ID, ticketType)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var count int64
+	require.NoError(t, query.Count(&count).Error)
+	return count
+}
+
+const importCSVHeader = "external_ref,name,location,start_date,end_date,category,organizer,tickets\n"
+
+func TestEventService_ImportEventsCSV(t *testing.T) {
+	db := setupTestDB(t)
+	eventService := setupEventService(t, db)
+
+	file := importCSVHeader +
+		"jkt-01,Konser Jakarta,Jakarta,2026-12-01 19:00,2026-12-01 23:00,music,Promotor,regular:100:3;vip:250:1\n" +
+		"bdg-01,Festival Bandung,Bandung,2026-12-05T10:00:00Z,2026-12-05T22:00:00Z,festival,Promotor,regular:50:2\n"
+
+	// A dry run reports the changes without writing them
+	result, err := eventService.ImportEvents(strings.NewReader(file), model.ImportFormatCSV, true)
+	require.NoError(t, err)
+	assert.True(t, result.DryRun)
+	assert.False(t, result.Applied)
+	assert.Equal(t, 2, result.Total)
+	assert.Equal(t, 2, result.Created)
+	assert.Empty(t, result.Errors)
+
+	var events int64
+	require.NoError(t, db.Model(&model.Event{}).Count(&events).Error)
+	assert.Equal(t, int64(0), events)
+
+	result, err = eventService.ImportEvents(strings.NewReader(file), model.ImportFormatCSV, false)
+	require.NoError(t, err)
+	assert.True(t, result.Applied)
+	assert.Equal(t, 2, result.Created)
+	require.Len(t, result.Rows, 2)
+	assert.Equal(t, model.ImportActionCreate, result.Rows[0].Action)
+	assert.NotEmpty(t, result.Rows[0].EventID)
+
+	event := importedEvent(t, db, "jkt-01")
+	assert.Equal(t, "Konser Jakarta", event.Name)
+	assert.Equal(t, "active", event.Status)
+	assert.True(t, event.StartDate.Equal(time.Date(2026, 12, 1, 19, 0, 0, 0, time.UTC)))
+	assert.Equal(t, int64(3), countTickets(t, db, event, "regular", "available"))
+	assert.Equal(t, int64(1), countTickets(t, db, event, "vip", "available"))
+
+	// Every imported event is announced through the outbox
+	var messages int64
+	require.NoError(t, db.Model(&messaging.OutboxMessage{}).Where("routing_key = ?", "event.created").Count(&messages).Error)
+	assert.Equal(t, int64(2), messages)
+}
+
+func TestEventService_ImportRejectsInvalidRows(t *testing.T) {
+	db := setupTestDB(t)
+	eventService := setupEventService(t, db)
+
+	file := importCSVHeader +
+		"jkt-01,Konser Jakarta,Jakarta,2026-12-01 19:00,2026-12-01 23:00,music,Promotor,regular:100:3\n" +
+		"jkt-02,,Jakarta,2026-12-02 19:00,2026-12-01 23:00,music,Promotor,regular:abc:3\n" +
+		"jkt-01,Konser Jakarta,Jakarta,2026-12-01 19:00,2026-12-01 23:00,music,Promotor,regular:100:3\n" +
+		"jkt-03,Konser Jakarta\n"
+
+	result, err := eventService.ImportEvents(strings.NewReader(file), model.ImportFormatCSV, false)
+	require.NoError(t, err)
+	assert.False(t, result.Applied)
+	assert.Equal(t, 4, result.Total)
+	require.Len(t, result.Errors, 3)
+
+	assert.Equal(t, 3, result.Errors[0].Row)
+	assert.Contains(t, result.Errors[0].Errors, "invalid price in ticket regular:abc:3")
+	assert.Contains(t, result.Errors[0].Errors, "name is required")
+	assert.Contains(t, result.Errors[0].Errors, "start_date must be before end_date")
+	assert.Equal(t, []string{"external_ref jkt-01 is already used in row 2"}, result.Errors[1].Errors)
+	assert.Equal(t, 5, result.Errors[2].Row)
+	assert.Contains(t, result.Errors[2].Errors, "wrong number of fields")
+
+	// Nothing is written while any row is invalid
+	var events int64
+	require.NoError(t, db.Model(&model.Event{}).Count(&events).Error)
+	assert.Equal(t, int64(0), events)
+
+	// Unknown columns reject the whole file
+	_, err = eventService.ImportEvents(strings.NewReader("external_ref,venue\n"), model.ImportFormatCSV, false)
+	assert.EqualError(t, err, "unknown csv column venue")
+
+	_, err = eventService.ImportEvents(strings.NewReader(file), "xml", false)
+	assert.EqualError(t, err, "invalid import format xml")
+}
+
+func TestEventService_ImportUpdatesEventsByExternalRef(t *testing.T) {
+	db := setupTestDB(t)
+	eventService := setupEventService(t, db)
+
+	file := importCSVHeader +
+		"jkt-01,Konser Jakarta,Jakarta,2026-12-01 19:00,2026-12-01 23:00,music,Promotor,regular:100:4\n" +
+		"bdg-01,Festival Bandung,Bandung,2026-12-05 10:00,2026-12-05 22:00,festival,Promotor,regular:50:2\n"
+	_, err := eventService.ImportEvents(strings.NewReader(file), model.ImportFormatCSV, false)
+	require.NoError(t, err)
+
+	event := importedEvent(t, db, "jkt-01")
+	sellTickets(t, db, event, "regular", 2, "confirmed", time.Now())
+
+	// Rename the first event, reprice its unsold tickets and add a type;
+	// the second event stays as it is
+	file = importCSVHeader +
+		"jkt-01,Konser Jakarta Malam,Jakarta,2026-12-01 19:00,2026-12-01 23:00,music,Promotor,regular:120:3;vip:300:2\n" +
+		"bdg-01,Festival Bandung,Bandung,2026-12-05 10:00,2026-12-05 22:00,festival,Promotor,regular:50:2\n"
+	result, err := eventService.ImportEvents(strings.NewReader(file), model.ImportFormatCSV, false)
+	require.NoError(t, err)
+	require.Empty(t, result.Errors)
+	assert.Equal(t, 1, result.Updated)
+	assert.Equal(t, 1, result.Unchanged)
+	assert.Equal(t, event.ID, result.Rows[0].EventID)
+
+	event = importedEvent(t, db, "jkt-01")
+	assert.Equal(t, "Konser Jakarta Malam", event.Name)
+	assert.Equal(t, int64(3), countTickets(t, db, event, "regular", ""))
+	assert.Equal(t, int64(2), countTickets(t, db, event, "regular", "sold"))
+	assert.Equal(t, int64(2), countTickets(t, db, event, "vip", "available"))
+
+	var repriced int64
+	require.NoError(t, db.Model(&model.Ticket{}).Where("event_id = ? AND type = ? AND price = ?", event.ID, "regular", 120.0).Count(&repriced).Error)
+	assert.Equal(t, int64(1), repriced)
+
+	// Booked tickets are never removed
+	file = importCSVHeader +
+		"jkt-01,Konser Jakarta Malam,Jakarta,2026-12-01 19:00,2026-12-01 23:00,music,Promotor,regular:120:1\n"
+	result, err = eventService.ImportEvents(strings.NewReader(file), model.ImportFormatCSV, false)
+	require.NoError(t, err)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, []string{"cannot reduce regular tickets to 1, 2 are already booked"}, result.Errors[0].Errors)
+	assert.Equal(t, int64(3), countTickets(t, db, event, "regular", ""))
+}
+
+func TestEventService_ImportEventsJSONL(t *testing.T) {
+	db := setupTestDB(t)
+	eventService := setupEventService(t, db)
+
+	file := `{"external_ref":"sby-01","name":"Pameran Surabaya","location":"Surabaya","start_date":"2026-12-10T09:00:00Z","end_date":"2026-12-10T17:00:00Z","category":"exhibition","organizer":"Promotor","tickets":[{"type":"regular","price":25,"quantity":2}]}
+
+{"external_ref":"sby-02","venue":"Surabaya"}
+`
+	result, err := eventService.ImportEvents(strings.NewReader(file), model.ImportFormatJSONL, true)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Total)
+	assert.Equal(t, 1, result.Created)
+	require.Len(t, result.Errors, 1)
+
+	// Blank lines are skipped but still counted
+	assert.Equal(t, 3, result.Errors[0].Row)
+	assert.Contains(t, result.Errors[0].Errors[0], "invalid json")
+}
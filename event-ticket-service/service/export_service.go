@@ -0,0 +1,537 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/event-ticket-service/model"
+	"github.com/yourusername/ticket-system/event-ticket-service/repository"
+	"github.com/yourusername/ticket-system/event-ticket-service/utils"
+)
+
+// exportJobMaxAttempts is how often an export job is claimed before it is
+// failed, so an export that crashes its worker is not retried forever
+const exportJobMaxAttempts = 3
+
+// ExportService defines the interface for export operations
+type ExportService interface {
+	ValidateExport(eventID uuid.UUID, dataset string, req model.ExportRequest) error
+	Export(w io.Writer, eventID uuid.UUID, dataset string, req model.ExportRequest) (int, error)
+	CreateExportJob(eventID uuid.UUID, dataset string, req model.ExportRequest, requestedBy string) (*model.ExportJobResponse, error)
+	GetExportJob(id uuid.UUID) (*model.ExportJob, error)
+	StartExportWorker(ctx context.Context, interval time.Duration)
+}
+
+// exportService implements ExportService interface
+type exportService struct {
+	exportRepo repository.ExportRepository
+	eventRepo  repository.EventRepository
+	exportDir  string
+	jobLease   time.Duration
+	retention  time.Duration
+}
+
+// NewExportService creates a new export service that stores job artifacts in
+// exportDir and deletes them after retention. A worker holds a job for
+// jobLease and renews the lease while it runs; when the worker stops, the
+// job is claimed again once the lease expired.
+func NewExportService(exportRepo repository.ExportRepository, eventRepo repository.EventRepository, exportDir string, jobLease, retention time.Duration) ExportService {
+	return &exportService{
+		exportRepo: exportRepo,
+		eventRepo:  eventRepo,
+		exportDir:  exportDir,
+		jobLease:   jobLease,
+		retention:  retention,
+	}
+}
+
+// exportWriter writes rows in one of the export formats
+type exportWriter interface {
+	Write(record []string) error
+	Close() error
+}
+
+// csvExportWriter adapts csv.Writer to exportWriter
+type csvExportWriter struct {
+	w *csv.Writer
+}
+
+// Write writes a single CSV record
+func (c *csvExportWriter) Write(record []string) error {
+	return c.w.Write(record)
+}
+
+// Close flushes the buffered records
+func (c *csvExportWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// ValidateExport checks the event, dataset, format, columns and filters of an export
+func (s *exportService) ValidateExport(eventID uuid.UUID, dataset string, req model.ExportRequest) error {
+	if _, err := resolveExportColumns(dataset, req); err != nil {
+		return err
+	}
+
+	// Find event by ID
+	event, err := s.eventRepo.FindByID(eventID)
+	if err != nil {
+		return fmt.Errorf("failed to find event: %w", err)
+	}
+
+	if event == nil {
+		return fmt.Errorf("event not found")
+	}
+
+	return nil
+}
+
+// Export streams the rows of a dataset to w and returns the number of data rows written
+func (s *exportService) Export(w io.Writer, eventID uuid.UUID, dataset string, req model.ExportRequest) (int, error) {
+	columns, err := resolveExportColumns(dataset, req)
+	if err != nil {
+		return 0, err
+	}
+
+	// Create writer for the requested format
+	var writer exportWriter
+	if req.Format == model.ExportFormatXLSX {
+		writer, err = utils.NewXLSXWriter(w, dataset)
+		if err != nil {
+			return 0, fmt.Errorf("failed to create xlsx writer: %w", err)
+		}
+	} else {
+		writer = &csvExportWriter{w: csv.NewWriter(w)}
+	}
+
+	// Write header
+	if err := writer.Write(columns); err != nil {
+		return 0, fmt.Errorf("failed to write header: %w", err)
+	}
+
+	count := 0
+	record := make([]string, len(columns))
+	writeRow := func(value func(column string) string) error {
+		for i, column := range columns {
+			record[i] = value(column)
+		}
+		count++
+		return writer.Write(record)
+	}
+
+	// Stream rows
+	switch dataset {
+	case model.ExportDatasetAttendees:
+		err = s.exportRepo.StreamAttendees(eventID, req, func(row model.AttendeeExportRow) error {
+			return writeRow(func(column string) string { return attendeeValue(row, column) })
+		})
+	case model.ExportDatasetBookings:
+		err = s.exportRepo.StreamBookings(eventID, req, func(row model.BookingExportRow) error {
+			return writeRow(func(column string) string { return bookingValue(row, column) })
+		})
+	case model.ExportDatasetTransactions:
+		err = s.exportRepo.StreamTransactions(eventID, req, func(row model.TransactionExportRow) error {
+			return writeRow(func(column string) string { return transactionValue(row, column) })
+		})
+	}
+	if err != nil {
+		return count, fmt.Errorf("failed to export %s: %w", dataset, err)
+	}
+
+	if err := writer.Close(); err != nil {
+		return count, fmt.Errorf("failed to finish export: %w", err)
+	}
+
+	return count, nil
+}
+
+// CreateExportJob queues an export to be generated in the background
+func (s *exportService) CreateExportJob(eventID uuid.UUID, dataset string, req model.ExportRequest, requestedBy string) (*model.ExportJobResponse, error) {
+	if err := s.ValidateExport(eventID, dataset, req); err != nil {
+		return nil, err
+	}
+
+	params, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal export params: %w", err)
+	}
+
+	job := &model.ExportJob{
+		EventID:     eventID,
+		RequestedBy: requestedBy,
+		Dataset:     dataset,
+		Format:      req.Format,
+		Params:      string(params),
+		Status:      model.ExportJobPending,
+	}
+
+	if err := s.exportRepo.CreateJob(job); err != nil {
+		return nil, fmt.Errorf("failed to create export job: %w", err)
+	}
+
+	response := job.ToResponse()
+	return &response, nil
+}
+
+// GetExportJob gets an export job by ID
+func (s *exportService) GetExportJob(id uuid.UUID) (*model.ExportJob, error) {
+	job, err := s.exportRepo.FindJobByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find export job: %w", err)
+	}
+
+	if job == nil {
+		return nil, fmt.Errorf("export job not found")
+	}
+
+	return job, nil
+}
+
+// StartExportWorker periodically generates the artifacts of pending export
+// jobs and deletes expired artifacts
+func (s *exportService) StartExportWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.runPendingJobs(ctx)
+			s.deleteExpiredArtifacts()
+		}
+	}
+}
+
+// runPendingJobs claims and runs pending export jobs and jobs whose worker stopped
+func (s *exportService) runPendingJobs(ctx context.Context) {
+	jobIDs, err := s.exportRepo.FindRunnableJobIDs(time.Now(), 10)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to find pending export jobs")
+		return
+	}
+
+	for _, jobID := range jobIDs {
+		// Another instance may have picked up the job already
+		now := time.Now()
+		claimed, err := s.exportRepo.ClaimJob(jobID, now, now.Add(s.jobLease))
+		if err != nil {
+			logrus.WithError(err).Errorf("Failed to claim export job %s", jobID)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		s.runExportJob(ctx, jobID)
+	}
+}
+
+// runExportJob generates the artifact of a claimed export job and records
+// the outcome, unless another worker took the job over in the meantime
+func (s *exportService) runExportJob(ctx context.Context, jobID uuid.UUID) {
+	job, err := s.exportRepo.FindJobByID(jobID)
+	if err != nil || job == nil {
+		logrus.WithError(err).Errorf("Failed to load export job %s", jobID)
+		return
+	}
+	attempt := job.Attempts
+
+	var rowCount int
+	var filePath string
+	if attempt > exportJobMaxAttempts {
+		err = fmt.Errorf("export did not finish after %d attempts", exportJobMaxAttempts)
+	} else {
+		// Artifacts of earlier attempts that stopped are never completed
+		s.removeJobArtifacts(job, attempt)
+
+		stopRenewal := s.renewJobLease(ctx, job.ID, attempt)
+		rowCount, filePath, err = s.writeJobArtifact(job, attempt)
+		stopRenewal()
+	}
+
+	now := time.Now()
+	job.CompletedAt = &now
+	job.RowCount = rowCount
+	if err != nil {
+		logrus.WithError(err).Errorf("Export job %s failed", jobID)
+		job.Status = model.ExportJobFailed
+		job.Error = err.Error()
+	} else {
+		expiresAt := now.Add(s.retention)
+		job.Status = model.ExportJobCompleted
+		job.FilePath = filePath
+		job.ExpiresAt = &expiresAt
+	}
+
+	finished, err := s.exportRepo.FinishJob(job, attempt)
+	if err != nil {
+		logrus.WithError(err).Errorf("Failed to update export job %s", jobID)
+	}
+	if !finished && filePath != "" {
+		logrus.Warnf("Export job %s was taken over, discarding attempt %d", jobID, attempt)
+		os.Remove(filePath)
+	}
+}
+
+// renewJobLease keeps extending the lease of a running attempt until the
+// returned function is called
+func (s *exportService) renewJobLease(ctx context.Context, jobID uuid.UUID, attempt int) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		ticker := time.NewTicker(s.jobLease / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				renewed, err := s.exportRepo.RenewJobLease(jobID, attempt, time.Now().Add(s.jobLease))
+				if err != nil {
+					logrus.WithError(err).Errorf("Failed to renew lease of export job %s", jobID)
+				} else if !renewed {
+					return
+				}
+			}
+		}
+	}()
+	return cancel
+}
+
+// deleteExpiredArtifacts deletes the artifacts of completed export jobs after
+// their retention and marks the jobs as expired
+func (s *exportService) deleteExpiredArtifacts() {
+	jobs, err := s.exportRepo.FindExpiredJobs(time.Now(), 100)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to find expired export jobs")
+		return
+	}
+
+	for _, job := range jobs {
+		if job.FilePath != "" {
+			if err := os.Remove(job.FilePath); err != nil && !os.IsNotExist(err) {
+				logrus.WithError(err).Errorf("Failed to delete artifact of export job %s", job.ID)
+				continue
+			}
+		}
+
+		if err := s.exportRepo.ExpireJob(job.ID); err != nil {
+			logrus.WithError(err).Errorf("Failed to expire export job %s", job.ID)
+			continue
+		}
+		logrus.Infof("Deleted expired artifact of export job %s", job.ID)
+	}
+}
+
+// writeJobArtifact writes the export of an attempt of a job to a file in the
+// export directory
+func (s *exportService) writeJobArtifact(job *model.ExportJob, attempt int) (int, string, error) {
+	var req model.ExportRequest
+	if err := json.Unmarshal([]byte(job.Params), &req); err != nil {
+		return 0, "", fmt.Errorf("failed to unmarshal export params: %w", err)
+	}
+
+	if err := os.MkdirAll(s.exportDir, 0o755); err != nil {
+		return 0, "", fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	filePath := jobArtifactPath(s.exportDir, job, attempt)
+	file, err := os.Create(filePath)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer file.Close()
+
+	rowCount, err := s.Export(file, job.EventID, job.Dataset, req)
+	if err != nil {
+		os.Remove(filePath)
+		return rowCount, "", err
+	}
+
+	return rowCount, filePath, nil
+}
+
+// removeJobArtifacts deletes the files left by earlier attempts of a job
+func (s *exportService) removeJobArtifacts(job *model.ExportJob, attempt int) {
+	for earlier := 1; earlier < attempt; earlier++ {
+		if err := os.Remove(jobArtifactPath(s.exportDir, job, earlier)); err != nil && !os.IsNotExist(err) {
+			logrus.WithError(err).Warnf("Failed to delete artifact of attempt %d of export job %s", earlier, job.ID)
+		}
+	}
+}
+
+// jobArtifactPath returns the file an attempt of a job writes its artifact to
+func jobArtifactPath(exportDir string, job *model.ExportJob, attempt int) string {
+	return filepath.Join(exportDir, fmt.Sprintf("%s-%d.%s", job.ID, attempt, job.Format))
+}
+
+// resolveExportColumns validates an export request and returns the columns to write
+func resolveExportColumns(dataset string, req model.ExportRequest) ([]string, error) {
+	available, ok := model.ExportColumns[dataset]
+	if !ok {
+		return nil, fmt.Errorf("invalid dataset %s", dataset)
+	}
+
+	// Validate format
+	if req.Format != model.ExportFormatCSV && req.Format != model.ExportFormatXLSX {
+		return nil, fmt.Errorf("invalid format %s", req.Format)
+	}
+
+	// Validate status filter
+	if req.Status != "" {
+		statuses := []string{"pending", "confirmed", "cancelled", "refunded"}
+		if dataset == model.ExportDatasetAttendees {
+			statuses = []string{"reserved", "sold"}
+		}
+		if !containsString(statuses, req.Status) {
+			return nil, fmt.Errorf("invalid status %s for %s", req.Status, dataset)
+		}
+	}
+
+	// Validate date range
+	if !req.From.IsZero() && !req.To.IsZero() && req.To.Before(req.From) {
+		return nil, fmt.Errorf("to must not be before from")
+	}
+
+	// Use all columns unless a selection is given
+	if strings.TrimSpace(req.Columns) == "" {
+		return available, nil
+	}
+
+	var columns []string
+	for _, column := range strings.Split(req.Columns, ",") {
+		column = strings.TrimSpace(column)
+		if !containsString(available, column) {
+			return nil, fmt.Errorf("invalid column %s for %s", column, dataset)
+		}
+		columns = append(columns, column)
+	}
+
+	return columns, nil
+}
+
+// attendeeValue returns the value of a column of an attendee row
+func attendeeValue(row model.AttendeeExportRow, column string) string {
+	switch column {
+	case "ticket_id":
+		return row.TicketID.String()
+	case "booking_id":
+		return row.BookingID.String()
+	case "user_id":
+		return formatExportID(row.UserID)
+	case "ticket_type":
+		return row.TicketType
+	case "price":
+		return formatExportAmount(row.Price)
+	case "status":
+		return row.Status
+	case "checked_in_at":
+		if row.CheckedInAt == nil {
+			return ""
+		}
+		return formatExportTime(*row.CheckedInAt)
+	case "booked_at":
+		return formatExportTime(row.BookedAt)
+	}
+	return ""
+}
+
+// bookingValue returns the value of a column of a booking row
+func bookingValue(row model.BookingExportRow, column string) string {
+	switch column {
+	case "booking_id":
+		return row.BookingID.String()
+	case "user_id":
+		return formatExportID(row.UserID)
+	case "status":
+		return row.Status
+	case "tickets":
+		return strconv.Itoa(row.Tickets)
+	case "ticket_types":
+		return row.TicketTypes
+	case "total_price":
+		return formatExportAmount(row.TotalPrice)
+	case "payment_id":
+		return formatExportID(row.PaymentID)
+	case "created_at":
+		return formatExportTime(row.CreatedAt)
+	case "updated_at":
+		return formatExportTime(row.UpdatedAt)
+	}
+	return ""
+}
+
+// transactionValue returns the value of a column of a recorded payment or refund
+func transactionValue(row model.TransactionExportRow, column string) string {
+	switch column {
+	case "transaction_id":
+		return row.TransactionID.String()
+	case "booking_id":
+		return row.BookingID.String()
+	case "payment_id":
+		return formatExportID(row.PaymentID)
+	case "amendment_id":
+		if row.AmendmentID == nil {
+			return ""
+		}
+		return row.AmendmentID.String()
+	case "user_id":
+		return formatExportID(row.UserID)
+	case "type":
+		return row.Type
+	case "amount":
+		return formatExportAmount(row.Amount)
+	case "currency":
+		return row.Currency
+	case "reason":
+		return row.Reason
+	case "booking_status":
+		return row.BookingStatus
+	case "date":
+		return formatExportTime(row.OccurredAt)
+	}
+	return ""
+}
+
+// formatExportID formats an ID, leaving unset IDs empty
+func formatExportID(id uuid.UUID) string {
+	if id == uuid.Nil {
+		return ""
+	}
+	return id.String()
+}
+
+// formatExportAmount formats a monetary amount with two decimals
+func formatExportAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+// formatExportTime formats a timestamp as RFC 3339
+func formatExportTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
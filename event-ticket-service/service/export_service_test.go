@@ -0,0 +1,210 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/ticket-system/contracts"
+	"github.com/yourusername/ticket-system/event-ticket-service/model"
+	"github.com/yourusername/ticket-system/event-ticket-service/repository"
+	"gorm.io/gorm"
+)
+
+// setupExportService creates an export service writing to a temporary directory
+func setupExportService(t *testing.T, db *gorm.DB, retention time.Duration) (*exportService, repository.ExportRepository) {
+	exportRepo := repository.NewExportRepository(db)
+	service := NewExportService(exportRepo, repository.NewEventRepository(db), t.TempDir(), time.Minute, retention)
+	return service.(*exportService), exportRepo
+}
+
+// readCSV parses an exported CSV file into records
+func readCSV(t *testing.T, data []byte) [][]string {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	require.NoError(t, err)
+	return records
+}
+
+func TestExportService_ExportsRecordedTransactions(t *testing.T) {
+	db := setupTestDB(t)
+	exportService, _ := setupExportService(t, db, time.Hour)
+	transactionService := NewTransactionService(repository.NewTransactionRepository(db), repository.NewBookingRepository(db), repository.NewReportRepository(db))
+
+	event := createTestEvent(t, db, 30*24*time.Hour, 2, map[string]float64{"regular": 100})
+	booking := sellTickets(t, db, event, "regular", 2, "confirmed", time.Now())
+	paidAt := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+
+	payment := contracts.PaymentState{PaymentID: uuid.New(), UserID: booking.UserID, BookingID: booking.ID, Amount: 200, Currency: "IDR"}
+	require.NoError(t, transactionService.RecordPayment(payment, paidAt))
+
+	// A redelivered event is recorded once
+	require.NoError(t, transactionService.RecordPayment(payment, paidAt))
+
+	refund := contracts.RefundState{
+		PaymentID:    payment.PaymentID,
+		RefundID:     uuid.New(),
+		UserID:       booking.UserID,
+		BookingID:    booking.ID,
+		Amount:       200,
+		RefundAmount: 50,
+		Currency:     "IDR",
+		Reason:       "amendment",
+	}
+	require.NoError(t, transactionService.RecordRefund(refund, paidAt.Add(24*time.Hour)))
+
+	// Payments of unknown bookings are skipped
+	require.NoError(t, transactionService.RecordPayment(contracts.PaymentState{PaymentID: uuid.New(), BookingID: uuid.New(), Amount: 10}, paidAt))
+
+	var buf bytes.Buffer
+	count, err := exportService.Export(&buf, event.ID, model.ExportDatasetTransactions, model.ExportRequest{
+		Format:  model.ExportFormatCSV,
+		Columns: "transaction_id,type,amount,currency,reason,booking_status,date",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	records := readCSV(t, buf.Bytes())
+	require.Len(t, records, 3)
+	assert.Equal(t, []string{payment.PaymentID.String(), "payment", "200.00", "IDR", "", "confirmed", "2026-10-01T09:00:00Z"}, records[1])
+	assert.Equal(t, []string{refund.RefundID.String(), "refund", "-50.00", "IDR", "amendment", "confirmed", "2026-10-02T09:00:00Z"}, records[2])
+
+	// The date filter applies to the transaction date
+	buf.Reset()
+	count, err = exportService.Export(&buf, event.ID, model.ExportDatasetTransactions, model.ExportRequest{
+		Format: model.ExportFormatCSV,
+		From:   time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestExportService_RunsPendingJob(t *testing.T) {
+	db := setupTestDB(t)
+	exportService, exportRepo := setupExportService(t, db, time.Hour)
+
+	event := createTestEvent(t, db, 30*24*time.Hour, 3, map[string]float64{"regular": 100})
+	sellTickets(t, db, event, "regular", 2, "confirmed", time.Now())
+
+	response, err := exportService.CreateExportJob(event.ID, model.ExportDatasetAttendees, model.ExportRequest{Format: model.ExportFormatCSV}, "organizer")
+	require.NoError(t, err)
+	assert.Empty(t, response.DownloadURL)
+
+	exportService.runPendingJobs(context.Background())
+
+	job, err := exportRepo.FindJobByID(response.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.ExportJobCompleted, job.Status)
+	assert.Equal(t, 2, job.RowCount)
+	assert.Equal(t, 1, job.Attempts)
+	assert.Nil(t, job.LeaseExpiresAt)
+	require.NotNil(t, job.ExpiresAt)
+
+	data, err := os.ReadFile(job.FilePath)
+	require.NoError(t, err)
+	assert.Len(t, readCSV(t, data), 3)
+}
+
+func TestExportService_ReclaimsJobAfterLeaseExpired(t *testing.T) {
+	db := setupTestDB(t)
+	exportService, exportRepo := setupExportService(t, db, time.Hour)
+
+	event := createTestEvent(t, db, 30*24*time.Hour, 1, map[string]float64{"regular": 100})
+	response, err := exportService.CreateExportJob(event.ID, model.ExportDatasetAttendees, model.ExportRequest{Format: model.ExportFormatCSV}, "organizer")
+	require.NoError(t, err)
+
+	// A worker claims the job and stops without finishing it
+	now := time.Now()
+	claimed, err := exportRepo.ClaimJob(response.ID, now, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	// The job is not claimed again while the lease is held
+	claimed, err = exportRepo.ClaimJob(response.ID, now, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, claimed)
+
+	jobIDs, err := exportRepo.FindRunnableJobIDs(now.Add(2*time.Minute), 10)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{response.ID}, jobIDs)
+
+	require.NoError(t, db.Model(&model.ExportJob{}).Where("id = ?", response.ID).
+		Update("lease_expires_at", now.Add(-time.Second)).Error)
+
+	exportService.runPendingJobs(context.Background())
+
+	job, err := exportRepo.FindJobByID(response.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.ExportJobCompleted, job.Status)
+	assert.Equal(t, 2, job.Attempts)
+
+	// The first attempt can no longer overwrite the outcome
+	stale := *job
+	stale.Status = model.ExportJobFailed
+	finished, err := exportRepo.FinishJob(&stale, 1)
+	require.NoError(t, err)
+	assert.False(t, finished)
+
+	renewed, err := exportRepo.RenewJobLease(response.ID, 1, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, renewed)
+
+	job, err = exportRepo.FindJobByID(response.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.ExportJobCompleted, job.Status)
+}
+
+func TestExportService_FailsJobAfterMaxAttempts(t *testing.T) {
+	db := setupTestDB(t)
+	exportService, exportRepo := setupExportService(t, db, time.Hour)
+
+	event := createTestEvent(t, db, 30*24*time.Hour, 1, map[string]float64{"regular": 100})
+	response, err := exportService.CreateExportJob(event.ID, model.ExportDatasetAttendees, model.ExportRequest{Format: model.ExportFormatCSV}, "organizer")
+	require.NoError(t, err)
+
+	// Every earlier attempt stopped its worker
+	require.NoError(t, db.Model(&model.ExportJob{}).Where("id = ?", response.ID).Updates(map[string]interface{}{
+		"status":           model.ExportJobRunning,
+		"attempts":         exportJobMaxAttempts,
+		"lease_expires_at": time.Now().Add(-time.Second),
+	}).Error)
+
+	exportService.runPendingJobs(context.Background())
+
+	job, err := exportRepo.FindJobByID(response.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.ExportJobFailed, job.Status)
+	assert.Equal(t, "export did not finish after 3 attempts", job.Error)
+	assert.Empty(t, job.FilePath)
+}
+
+func TestExportService_DeletesExpiredArtifacts(t *testing.T) {
+	db := setupTestDB(t)
+	exportService, exportRepo := setupExportService(t, db, -time.Minute)
+
+	event := createTestEvent(t, db, 30*24*time.Hour, 1, map[string]float64{"regular": 100})
+	response, err := exportService.CreateExportJob(event.ID, model.ExportDatasetAttendees, model.ExportRequest{Format: model.ExportFormatCSV}, "organizer")
+	require.NoError(t, err)
+
+	exportService.runPendingJobs(context.Background())
+
+	job, err := exportRepo.FindJobByID(response.ID)
+	require.NoError(t, err)
+	require.Equal(t, model.ExportJobCompleted, job.Status)
+	filePath := job.FilePath
+	assert.FileExists(t, filePath)
+
+	exportService.deleteExpiredArtifacts()
+
+	job, err = exportRepo.FindJobByID(response.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.ExportJobExpired, job.Status)
+	assert.Empty(t, job.FilePath)
+	assert.NoFileExists(t, filePath)
+	assert.Empty(t, job.ToResponse().DownloadURL)
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/contracts"
+	"github.com/yourusername/ticket-system/event-ticket-service/model"
+	"github.com/yourusername/ticket-system/event-ticket-service/repository"
+	"github.com/yourusername/ticket-system/messaging"
+	"gorm.io/gorm"
+)
+
+// privacyExportPageSize is the number of bookings read at once for an export
+const privacyExportPageSize = 100
+
+// PrivacyService defines the interface for answering the data export and
+// erasure requests of the user service
+type PrivacyService interface {
+	HandleDataRequest(event contracts.UserDataRequested) error
+	WithTx(tx *gorm.DB) PrivacyService
+}
+
+// privacyService implements PrivacyService interface
+type privacyService struct {
+	bookingRepo      repository.BookingRepository
+	amendmentRepo    repository.AmendmentRepository
+	organizationRepo repository.OrganizationRepository
+	outboxRepo       messaging.OutboxRepository
+	db               *gorm.DB
+}
+
+// NewPrivacyService creates a new privacy service
+func NewPrivacyService(
+	bookingRepo repository.BookingRepository,
+	amendmentRepo repository.AmendmentRepository,
+	organizationRepo repository.OrganizationRepository,
+	outboxRepo messaging.OutboxRepository,
+	db *gorm.DB,
+) PrivacyService {
+	return &privacyService{
+		bookingRepo:      bookingRepo,
+		amendmentRepo:    amendmentRepo,
+		organizationRepo: organizationRepo,
+		outboxRepo:       outboxRepo,
+		db:               db,
+	}
+}
+
+// WithTx returns a service that runs its operations in tx, so that consumers
+// can apply a message in the transaction that records it as processed
+func (s *privacyService) WithTx(tx *gorm.DB) PrivacyService {
+	return s.withTx(tx)
+}
+
+// withTx returns a copy of the service whose repositories run in tx
+func (s *privacyService) withTx(tx *gorm.DB) *privacyService {
+	return &privacyService{
+		bookingRepo:      s.bookingRepo.WithTx(tx),
+		amendmentRepo:    s.amendmentRepo.WithTx(tx),
+		organizationRepo: s.organizationRepo.WithTx(tx),
+		outboxRepo:       s.outboxRepo,
+		db:               tx,
+	}
+}
+
+// HandleDataRequest collects or erases the personal data of a user and
+// answers the user service. The erasure and the answer are written in one
+// transaction, so that the answer is only published once the data it reports
+// is gone; within a consumer the transaction is nested in the one that
+// records the request as processed.
+func (s *privacyService) HandleDataRequest(event contracts.UserDataRequested) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return s.withTx(tx).handleDataRequest(tx, event)
+	})
+}
+
+// handleDataRequest collects or erases the personal data of a user and
+// writes the answer to the outbox in tx
+func (s *privacyService) handleDataRequest(tx *gorm.DB, event contracts.UserDataRequested) error {
+	var answer contracts.Event
+	switch event.Mode {
+	case contracts.DataRequestExport:
+		data, err := s.exportUserData(event.UserID)
+		if err != nil {
+			return err
+		}
+		answer = contracts.PrivacyDataCollected{
+			RequestID: event.RequestID,
+			UserID:    event.UserID,
+			Service:   model.PrivacyService,
+			Data:      data,
+		}
+	case contracts.DataRequestErasure:
+		erased, retained, err := s.eraseUserData(event.UserID)
+		if err != nil {
+			return err
+		}
+		answer = contracts.PrivacyDataErased{
+			RequestID: event.RequestID,
+			UserID:    event.UserID,
+			Service:   model.PrivacyService,
+			Erased:    erased,
+			Retained:  retained,
+		}
+	default:
+		logrus.Warnf("Ignoring data request with unknown mode: %s", event.Mode)
+		return nil
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"request_id": event.RequestID,
+		"user_id":    event.UserID,
+		"mode":       event.Mode,
+	}).Info("Answering data request")
+
+	return messaging.EnqueueEvent(s.outboxRepo, tx, "privacy_events", event.RequestID.String(), answer)
+}
+
+// exportUserData collects the bookings, amendments and organization
+// memberships of a user as a JSON document
+func (s *privacyService) exportUserData(userID uuid.UUID) (json.RawMessage, error) {
+	export := model.PrivacyDataExport{
+		Bookings:   []model.BookingResponse{},
+		Amendments: []model.BookingAmendment{},
+		ExportedAt: time.Now(),
+	}
+
+	for page := 1; ; page++ {
+		bookings, total, err := s.bookingRepo.FindByUserID(userID, page, privacyExportPageSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find bookings: %w", err)
+		}
+
+		for _, booking := range bookings {
+			export.Bookings = append(export.Bookings, booking.ToResponse(false))
+
+			amendments, err := s.amendmentRepo.FindByBookingID(booking.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to find booking amendments: %w", err)
+			}
+			export.Amendments = append(export.Amendments, amendments...)
+		}
+
+		if len(bookings) == 0 || int64(page*privacyExportPageSize) >= total {
+			break
+		}
+	}
+
+	members, err := s.organizationRepo.FindMembersByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find organization memberships: %w", err)
+	}
+	export.Organizations = members
+
+	return json.Marshal(export)
+}
+
+// eraseUserData removes a user from all organizations. Bookings, tickets
+// and amendments are kept for accounting; they only refer to the user by
+// ID, which the erasure in the user service no longer links to a person.
+// It returns how many records were erased and retained.
+func (s *privacyService) eraseUserData(userID uuid.UUID) (int, int, error) {
+	members, err := s.organizationRepo.FindMembersByUserID(userID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to find organization memberships: %w", err)
+	}
+	if err := s.organizationRepo.DeleteMembersByUserID(userID); err != nil {
+		return 0, 0, fmt.Errorf("failed to delete organization memberships: %w", err)
+	}
+
+	_, bookings, err := s.bookingRepo.FindByUserID(userID, 1, 1)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count bookings: %w", err)
+	}
+
+	return len(members), int(bookings), nil
+}
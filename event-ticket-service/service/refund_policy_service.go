@@ -0,0 +1,149 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/ticket-system/event-ticket-service/model"
+	"github.com/yourusername/ticket-system/event-ticket-service/repository"
+)
+
+// RefundPolicyService defines the interface for refund policy operations
+type RefundPolicyService interface {
+	GetRefundPolicy(eventID uuid.UUID) (*model.RefundPolicy, error)
+	SetRefundPolicy(eventID uuid.UUID, req model.RefundPolicyRequest) (*model.RefundPolicy, error)
+	DeleteRefundPolicy(eventID uuid.UUID) error
+}
+
+// refundPolicyService implements RefundPolicyService interface
+type refundPolicyService struct {
+	refundPolicyRepo repository.RefundPolicyRepository
+	eventRepo        repository.EventRepository
+}
+
+// NewRefundPolicyService creates a new refund policy service
+func NewRefundPolicyService(refundPolicyRepo repository.RefundPolicyRepository, eventRepo repository.EventRepository) RefundPolicyService {
+	return &refundPolicyService{
+		refundPolicyRepo: refundPolicyRepo,
+		eventRepo:        eventRepo,
+	}
+}
+
+// GetRefundPolicy gets the refund policy of an event
+func (s *refundPolicyService) GetRefundPolicy(eventID uuid.UUID) (*model.RefundPolicy, error) {
+	policy, err := s.refundPolicyRepo.FindByEventID(eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find refund policy: %w", err)
+	}
+
+	if policy == nil {
+		return nil, fmt.Errorf("refund policy not found")
+	}
+
+	return policy, nil
+}
+
+// SetRefundPolicy creates or replaces the refund policy of an event
+func (s *refundPolicyService) SetRefundPolicy(eventID uuid.UUID, req model.RefundPolicyRequest) (*model.RefundPolicy, error) {
+	// Check if event exists
+	event, err := s.eventRepo.FindByID(eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find event: %w", err)
+	}
+
+	if event == nil {
+		return nil, fmt.Errorf("event not found")
+	}
+
+	// Build policy with its tiers, highest first
+	policy := &model.RefundPolicy{
+		EventID:          eventID,
+		DeadlineHours:    req.DeadlineHours,
+		NonRefundableFee: req.NonRefundableFee,
+	}
+
+	seen := make(map[int]bool)
+	for _, tier := range req.Tiers {
+		if seen[tier.HoursBeforeEvent] {
+			return nil, fmt.Errorf("duplicate tier for %d hours before the event", tier.HoursBeforeEvent)
+		}
+		seen[tier.HoursBeforeEvent] = true
+
+		policy.Tiers = append(policy.Tiers, model.RefundPolicyTier{
+			HoursBeforeEvent: tier.HoursBeforeEvent,
+			Percentage:       tier.Percentage,
+		})
+	}
+
+	sort.Slice(policy.Tiers, func(i, j int) bool {
+		return policy.Tiers[i].HoursBeforeEvent > policy.Tiers[j].HoursBeforeEvent
+	})
+
+	// Save policy to database
+	if err := s.refundPolicyRepo.Save(policy); err != nil {
+		return nil, fmt.Errorf("failed to save refund policy: %w", err)
+	}
+
+	return policy, nil
+}
+
+// DeleteRefundPolicy deletes the refund policy of an event
+func (s *refundPolicyService) DeleteRefundPolicy(eventID uuid.UUID) error {
+	if err := s.refundPolicyRepo.Delete(eventID); err != nil {
+		return fmt.Errorf("failed to delete refund policy: %w", err)
+	}
+
+	return nil
+}
+
+// evaluateRefundPolicy works out the refund of a booking cancelled at now.
+// Only paid bookings are refunded, cancelled events and events without a
+// policy are refunded in full.
+func evaluateRefundPolicy(policy *model.RefundPolicy, booking *model.Booking, event *model.Event, now time.Time) model.RefundQuote {
+	quote := model.RefundQuote{
+		BookingID:  booking.ID,
+		EventID:    booking.EventID,
+		TotalPrice: booking.TotalPrice,
+	}
+
+	switch {
+	case booking.Status != "confirmed":
+		quote.Reason = "booking is not paid"
+		return quote
+	case event.Status == "cancelled":
+		quote.Percentage = 100
+		quote.Reason = "event cancelled"
+	case policy == nil:
+		quote.Percentage = 100
+		quote.Reason = "event has no refund policy"
+	default:
+		hours := event.StartDate.Sub(now).Hours()
+		if hours < float64(policy.DeadlineHours) {
+			quote.Reason = "refund deadline has passed"
+			return quote
+		}
+
+		// Tiers are sorted highest first, so the first match is the best one
+		for _, tier := range policy.Tiers {
+			if hours >= float64(tier.HoursBeforeEvent) {
+				quote.Percentage = tier.Percentage
+				break
+			}
+		}
+
+		if quote.Percentage == 0 {
+			quote.Reason = "no refund tier applies"
+			return quote
+		}
+
+		quote.NonRefundableFee = policy.NonRefundableFee * float64(len(booking.Tickets))
+		quote.Reason = fmt.Sprintf("cancelled %.0f hours before the event", hours)
+	}
+
+	refundable := math.Max(booking.TotalPrice-quote.NonRefundableFee, 0)
+	quote.RefundAmount = math.Round(refundable*quote.Percentage) / 100
+	return quote
+}
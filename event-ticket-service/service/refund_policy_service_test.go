@@ -0,0 +1,100 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/ticket-system/event-ticket-service/model"
+	"github.com/yourusername/ticket-system/event-ticket-service/repository"
+)
+
+// refundPolicyRequest decodes a refund policy request from JSON
+func refundPolicyRequest(t *testing.T, body string) model.RefundPolicyRequest {
+	var req model.RefundPolicyRequest
+	require.NoError(t, json.Unmarshal([]byte(body), &req))
+	return req
+}
+
+func TestEvaluateRefundPolicy(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	policy := &model.RefundPolicy{
+		DeadlineHours:    24,
+		NonRefundableFee: 5,
+		Tiers: []model.RefundPolicyTier{
+			{HoursBeforeEvent: 168, Percentage: 100},
+			{HoursBeforeEvent: 72, Percentage: 50},
+			{HoursBeforeEvent: 48, Percentage: 25},
+		},
+	}
+	booking := &model.Booking{
+		ID:         uuid.New(),
+		Status:     "confirmed",
+		TotalPrice: 210,
+		Tickets:    []model.Ticket{{Type: "regular"}, {Type: "regular"}},
+	}
+
+	tests := []struct {
+		name        string
+		policy      *model.RefundPolicy
+		status      string
+		eventStatus string
+		startsIn    time.Duration
+		percentage  float64
+		fee         float64
+		amount      float64
+		reason      string
+	}{
+		{"highest tier", policy, "confirmed", "active", 200 * time.Hour, 100, 10, 200, "cancelled 200 hours before the event"},
+		{"tier boundary", policy, "confirmed", "active", 72 * time.Hour, 50, 10, 100, "cancelled 72 hours before the event"},
+		{"lowest tier", policy, "confirmed", "active", 50 * time.Hour, 25, 10, 50, "cancelled 50 hours before the event"},
+		{"below every tier", policy, "confirmed", "active", 30 * time.Hour, 0, 0, 0, "no refund tier applies"},
+		{"after deadline", policy, "confirmed", "active", 10 * time.Hour, 0, 0, 0, "refund deadline has passed"},
+		{"event cancelled", policy, "confirmed", "cancelled", 10 * time.Hour, 100, 0, 210, "event cancelled"},
+		{"no policy", nil, "confirmed", "active", time.Hour, 100, 0, 210, "event has no refund policy"},
+		{"not paid", policy, "pending", "active", 200 * time.Hour, 0, 0, 0, "booking is not paid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			booking.Status = tt.status
+			event := &model.Event{Status: tt.eventStatus, StartDate: now.Add(tt.startsIn)}
+
+			quote := evaluateRefundPolicy(tt.policy, booking, event, now)
+			assert.Equal(t, tt.percentage, quote.Percentage)
+			assert.Equal(t, tt.fee, quote.NonRefundableFee)
+			assert.Equal(t, tt.amount, quote.RefundAmount)
+			assert.Equal(t, tt.reason, quote.Reason)
+			assert.Equal(t, 210.0, quote.TotalPrice)
+		})
+	}
+}
+
+func TestRefundPolicyService_SetRefundPolicy(t *testing.T) {
+	db := setupTestDB(t)
+	refundPolicyService := NewRefundPolicyService(repository.NewRefundPolicyRepository(db), repository.NewEventRepository(db))
+	event := createTestEvent(t, db, 30*24*time.Hour, 1, map[string]float64{"regular": 100})
+
+	policy, err := refundPolicyService.SetRefundPolicy(event.ID, refundPolicyRequest(t,
+		`{"deadline_hours":24,"tiers":[{"hours_before_event":48,"percentage":25},{"hours_before_event":168,"percentage":100}]}`))
+	require.NoError(t, err)
+
+	// Tiers are stored highest first
+	require.Len(t, policy.Tiers, 2)
+	assert.Equal(t, 168, policy.Tiers[0].HoursBeforeEvent)
+	assert.Equal(t, 48, policy.Tiers[1].HoursBeforeEvent)
+
+	stored, err := refundPolicyService.GetRefundPolicy(event.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 24, stored.DeadlineHours)
+
+	_, err = refundPolicyService.SetRefundPolicy(event.ID, refundPolicyRequest(t,
+		`{"tiers":[{"hours_before_event":48,"percentage":25},{"hours_before_event":48,"percentage":50}]}`))
+	assert.EqualError(t, err, "duplicate tier for 48 hours before the event")
+
+	_, err = refundPolicyService.SetRefundPolicy(uuid.New(), refundPolicyRequest(t, `{"tiers":[{"hours_before_event":0,"percentage":100}]}`))
+	assert.EqualError(t, err, "event not found")
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/event-ticket-service/model"
+	"github.com/yourusername/ticket-system/event-ticket-service/repository"
+)
+
+// ReportService defines the interface for sales report operations
+type ReportService interface {
+	GetEventSalesReport(eventID uuid.UUID, req model.SalesReportRequest) (*model.EventSalesReport, error)
+	RefreshEventReport(eventID uuid.UUID) error
+	StartRollupWorker(ctx context.Context, interval time.Duration)
+}
+
+// reportService implements ReportService interface
+type reportService struct {
+	reportRepo repository.ReportRepository
+	eventRepo  repository.EventRepository
+}
+
+// NewReportService creates a new report service
+func NewReportService(reportRepo repository.ReportRepository, eventRepo repository.EventRepository) ReportService {
+	return &reportService{
+		reportRepo: reportRepo,
+		eventRepo:  eventRepo,
+	}
+}
+
+// GetEventSalesReport builds the sales report of an event from its rollups
+func (s *reportService) GetEventSalesReport(eventID uuid.UUID, req model.SalesReportRequest) (*model.EventSalesReport, error) {
+	// Validate bucket
+	switch req.Bucket {
+	case "":
+		req.Bucket = model.ReportBucketDay
+	case model.ReportBucketDay, model.ReportBucketWeek, model.ReportBucketMonth:
+	default:
+		return nil, fmt.Errorf("invalid bucket %s", req.Bucket)
+	}
+
+	// Find event by ID
+	event, err := s.eventRepo.FindByID(eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find event: %w", err)
+	}
+
+	if event == nil {
+		return nil, fmt.Errorf("event not found")
+	}
+
+	// Build the rollups on first access so the report is never empty
+	state, err := s.reportRepo.FindState(eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find report state: %w", err)
+	}
+
+	if state == nil || state.RefreshedAt.IsZero() {
+		if err := s.RefreshEventReport(eventID); err != nil {
+			return nil, err
+		}
+		if state, err = s.reportRepo.FindState(eventID); err != nil {
+			return nil, fmt.Errorf("failed to find report state: %w", err)
+		}
+	}
+
+	ticketRollups, err := s.reportRepo.FindTicketTypeRollups(eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find ticket rollups: %w", err)
+	}
+
+	revenueRollups, err := s.reportRepo.FindRevenueRollups(eventID, req.From, req.To)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find revenue rollups: %w", err)
+	}
+
+	report := &model.EventSalesReport{
+		EventID:     event.ID,
+		EventName:   event.Name,
+		Bucket:      req.Bucket,
+		TicketTypes: make([]model.TicketTypeSales, 0, len(ticketRollups)),
+		Revenue:     make([]model.RevenueBucket, 0),
+	}
+	if state != nil {
+		report.Stale = state.Stale
+		report.RefreshedAt = state.RefreshedAt
+	}
+
+	// Inventory per ticket type
+	for _, rollup := range ticketRollups {
+		report.TicketTypes = append(report.TicketTypes, model.TicketTypeSales{
+			Type:        rollup.TicketType,
+			Price:       rollup.Price,
+			Total:       rollup.Total,
+			Available:   rollup.Available,
+			Reserved:    rollup.Reserved,
+			Sold:        rollup.Sold,
+			CheckedIn:   rollup.CheckedIn,
+			SoldRevenue: rollup.SoldRevenue,
+		})
+		report.Totals.Tickets += rollup.Total
+		report.Totals.Available += rollup.Available
+		report.Totals.Reserved += rollup.Reserved
+		report.Totals.Sold += rollup.Sold
+		report.Totals.CheckedIn += rollup.CheckedIn
+	}
+
+	// Revenue per time bucket
+	confirmed := 0
+	for _, rollup := range revenueRollups {
+		start := bucketStart(rollup.Day, req.Bucket)
+		last := len(report.Revenue) - 1
+		if last < 0 || !report.Revenue[last].Start.Equal(start) {
+			report.Revenue = append(report.Revenue, model.RevenueBucket{Start: start})
+			last++
+		}
+
+		bucket := &report.Revenue[last]
+		bucket.Bookings += rollup.Bookings
+		bucket.GrossRevenue += rollup.GrossRevenue
+		bucket.RefundedAmount += rollup.RefundedAmount
+		bucket.NetRevenue = bucket.GrossRevenue - bucket.RefundedAmount
+
+		confirmed += rollup.Confirmed
+		report.Totals.Bookings += rollup.Bookings
+		report.Totals.Cancelled += rollup.Cancelled
+		report.Totals.Refunded += rollup.Refunded
+		report.Totals.GrossRevenue += rollup.GrossRevenue
+		report.Totals.RefundedAmount += rollup.RefundedAmount
+	}
+	report.Totals.NetRevenue = report.Totals.GrossRevenue - report.Totals.RefundedAmount
+
+	// Derived rates
+	report.Rates = model.SalesRates{
+		SellThrough:  ratio(report.Totals.Sold, report.Totals.Tickets),
+		Cancellation: ratio(report.Totals.Cancelled, report.Totals.Bookings),
+		Refund:       ratio(report.Totals.Refunded, confirmed+report.Totals.Refunded),
+		CheckIn:      ratio(report.Totals.CheckedIn, report.Totals.Sold),
+	}
+
+	return report, nil
+}
+
+// RefreshEventReport rebuilds the rollups of an event
+func (s *reportService) RefreshEventReport(eventID uuid.UUID) error {
+	if err := s.reportRepo.RefreshEventRollups(eventID); err != nil {
+		return fmt.Errorf("failed to refresh report rollups: %w", err)
+	}
+	return nil
+}
+
+// StartRollupWorker periodically rebuilds the rollups of events marked as stale
+func (s *reportService) StartRollupWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			eventIDs, err := s.reportRepo.FindStaleEventIDs(100)
+			if err != nil {
+				logrus.WithError(err).Error("Failed to find stale report rollups")
+				continue
+			}
+
+			for _, eventID := range eventIDs {
+				if err := s.RefreshEventReport(eventID); err != nil {
+					logrus.WithError(err).Errorf("Failed to refresh report for event %s", eventID)
+				}
+			}
+		}
+	}
+}
+
+// bucketStart truncates a day to the start of its report bucket
+func bucketStart(day time.Time, bucket string) time.Time {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	switch bucket {
+	case model.ReportBucketWeek:
+		// Weeks start on Monday
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	case model.ReportBucketMonth:
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+// ratio returns part/total, or 0 when total is 0
+func ratio(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(part) / float64(total)
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/ticket-system/contracts"
+	"github.com/yourusername/ticket-system/event-ticket-service/model"
+	"github.com/yourusername/ticket-system/event-ticket-service/repository"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestDB opens an in-memory database with the booking tables
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	// Every connection opens its own in-memory database
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	repository.NewEventRepository(db)
+	repository.NewTicketRepository(db)
+	repository.NewBookingRepository(db)
+	repository.NewTransactionRepository(db)
+	return db
+}
+
+// createTestEvent creates an event starting in startsIn with quantity
+// available tickets of each type at the given prices
+func createTestEvent(t *testing.T, db *gorm.DB, startsIn time.Duration, quantity int, prices map[string]float64) *model.Event {
+	event := &model.Event{
+		Name:      "Konser Akhir Tahun",
+		Location:  "Jakarta",
+		StartDate: time.Now().Add(startsIn),
+		EndDate:   time.Now().Add(startsIn + 4*time.Hour),
+		Category:  "music",
+		Organizer: "Promotor",
+		Status:    "active",
+	}
+	for ticketType, price := range prices {
+		for i := 0; i < quantity; i++ {
+			event.Tickets = append(event.Tickets, model.Ticket{Type: ticketType, Price: price, Status: "available"})
+		}
+	}
+	require.NoError(t, db.Create(event).Error)
+	return event
+}
+
+// sellTickets marks count available tickets of a type as sold in a new
+// booking with the given status, created at createdAt
+func sellTickets(t *testing.T, db *gorm.DB, event *model.Event, ticketType string, count int, status string, createdAt time.Time) *model.Booking {
+	var tickets []model.Ticket
+	require.NoError(t, db.Where("event_id = ? AND type = ? AND status = ?", event.ID, ticketType, "available").Limit(count).Find(&tickets).Error)
+	require.Len(t, tickets, count)
+
+	booking := &model.Booking{UserID: uuid.New(), EventID: event.ID, Status: status, CreatedAt: createdAt}
+	for _, ticket := range tickets {
+		booking.TotalPrice += ticket.Price
+	}
+	require.NoError(t, db.Create(booking).Error)
+
+	ticketStatus := "sold"
+	if status == "cancelled" || status == "refunded" {
+		ticketStatus = "available"
+	}
+	for _, ticket := range tickets {
+		require.NoError(t, db.Model(&model.Ticket{}).Where("id = ?", ticket.ID).Updates(map[string]interface{}{
+			"status":     ticketStatus,
+			"booking_id": booking.ID,
+			"user_id":    booking.UserID,
+		}).Error)
+	}
+	return booking
+}
+
+func TestReportService_GetEventSalesReport(t *testing.T) {
+	db := setupTestDB(t)
+	reportService := NewReportService(repository.NewReportRepository(db), repository.NewEventRepository(db))
+
+	event := createTestEvent(t, db, 30*24*time.Hour, 4, map[string]float64{"regular": 100, "vip": 250})
+	monday := time.Date(2026, 10, 12, 10, 0, 0, 0, time.UTC)
+	sellTickets(t, db, event, "regular", 2, "confirmed", monday)
+	sellTickets(t, db, event, "vip", 1, "confirmed", monday.AddDate(0, 0, 2))
+	refunded := sellTickets(t, db, event, "regular", 1, "refunded", monday.AddDate(0, 0, 7))
+	sellTickets(t, db, event, "vip", 1, "cancelled", monday.AddDate(0, 0, 8))
+
+	// Refunds count on the day they were made, not the day of the booking
+	transactionService := NewTransactionService(repository.NewTransactionRepository(db), repository.NewBookingRepository(db), repository.NewReportRepository(db))
+	refund := contracts.RefundState{PaymentID: uuid.New(), RefundID: uuid.New(), BookingID: refunded.ID, RefundAmount: 60, Currency: "IDR"}
+	require.NoError(t, transactionService.RecordRefund(refund, monday.AddDate(0, 0, 8)))
+	refund = contracts.RefundState{PaymentID: refund.PaymentID, RefundID: uuid.New(), BookingID: refunded.ID, RefundAmount: 40, Currency: "IDR"}
+	require.NoError(t, transactionService.RecordRefund(refund, monday.AddDate(0, 0, 14)))
+
+	// One of the sold tickets was scanned at the venue
+	var sold model.Ticket
+	require.NoError(t, db.Where("event_id = ? AND status = ?", event.ID, "sold").First(&sold).Error)
+	_, err := setupBookingService(t, db).CheckInTicket(sold.ID)
+	require.NoError(t, err)
+
+	report, err := reportService.GetEventSalesReport(event.ID, model.SalesReportRequest{Bucket: model.ReportBucketWeek})
+	require.NoError(t, err)
+
+	// Inventory per ticket type, ordered by type
+	require.Len(t, report.TicketTypes, 2)
+	assert.Equal(t, "regular", report.TicketTypes[0].Type)
+	assert.Equal(t, 2, report.TicketTypes[0].Sold)
+	assert.Equal(t, 2, report.TicketTypes[0].Available)
+	assert.Equal(t, 200.0, report.TicketTypes[0].SoldRevenue)
+	assert.Equal(t, "vip", report.TicketTypes[1].Type)
+	assert.Equal(t, 1, report.TicketTypes[1].Sold)
+	assert.Equal(t, 8, report.Totals.Tickets)
+	assert.Equal(t, 3, report.Totals.Sold)
+	assert.Equal(t, 1, report.Totals.CheckedIn)
+
+	// Bookings of the first and second week, and the last refund a week later
+	assert.True(t, report.Revenue[0].Start.Equal(time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, 2, report.Revenue[0].Bookings)
+	assert.Equal(t, 450.0, report.Revenue[0].GrossRevenue)
+	require.Len(t, report.Revenue, 3)
+	assert.True(t, report.Revenue[1].Start.Equal(time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, 100.0, report.Revenue[1].GrossRevenue)
+	assert.Equal(t, 60.0, report.Revenue[1].RefundedAmount)
+	assert.Equal(t, 40.0, report.Revenue[1].NetRevenue)
+	assert.True(t, report.Revenue[2].Start.Equal(time.Date(2026, 10, 26, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, 0, report.Revenue[2].Bookings)
+	assert.Equal(t, 40.0, report.Revenue[2].RefundedAmount)
+
+	assert.Equal(t, 4, report.Totals.Bookings)
+	assert.Equal(t, 450.0, report.Totals.NetRevenue)
+	assert.InDelta(t, 3.0/8, report.Rates.SellThrough, 0.0001)
+	assert.InDelta(t, 0.25, report.Rates.Cancellation, 0.0001)
+	assert.InDelta(t, 1.0/3, report.Rates.Refund, 0.0001)
+	assert.InDelta(t, 1.0/3, report.Rates.CheckIn, 0.0001)
+	assert.False(t, report.Stale)
+}
+
+func TestReportService_RefreshesStaleRollups(t *testing.T) {
+	db := setupTestDB(t)
+	reportRepo := repository.NewReportRepository(db)
+	reportService := NewReportService(reportRepo, repository.NewEventRepository(db))
+
+	event := createTestEvent(t, db, 30*24*time.Hour, 2, map[string]float64{"regular": 100})
+	report, err := reportService.GetEventSalesReport(event.ID, model.SalesReportRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, 0, report.Totals.Sold)
+
+	// Rollups are not rebuilt on every sale, only once marked stale
+	sellTickets(t, db, event, "regular", 1, "confirmed", time.Now())
+	report, err = reportService.GetEventSalesReport(event.ID, model.SalesReportRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, 0, report.Totals.Sold)
+
+	require.NoError(t, reportRepo.MarkStale(event.ID))
+	report, err = reportService.GetEventSalesReport(event.ID, model.SalesReportRequest{})
+	require.NoError(t, err)
+	assert.True(t, report.Stale)
+
+	staleIDs, err := reportRepo.FindStaleEventIDs(10)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{event.ID}, staleIDs)
+
+	require.NoError(t, reportService.RefreshEventReport(event.ID))
+	report, err = reportService.GetEventSalesReport(event.ID, model.SalesReportRequest{})
+	require.NoError(t, err)
+	assert.False(t, report.Stale)
+	assert.Equal(t, 1, report.Totals.Sold)
+}
+
+func TestReportService_RejectsUnknownBucket(t *testing.T) {
+	db := setupTestDB(t)
+	reportService := NewReportService(repository.NewReportRepository(db), repository.NewEventRepository(db))
+
+	_, err := reportService.GetEventSalesReport(uuid.New(), model.SalesReportRequest{Bucket: "year"})
+	assert.EqualError(t, err, "invalid bucket year")
+
+	_, err = reportService.GetEventSalesReport(uuid.New(), model.SalesReportRequest{})
+	assert.EqualError(t, err, "event not found")
+}
+
+func TestBucketStart(t *testing.T) {
+	// Thursday 15 October 2026
+	day := time.Date(2026, 10, 15, 18, 30, 0, 0, time.UTC)
+
+	assert.Equal(t, time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC), bucketStart(day, model.ReportBucketDay))
+	assert.Equal(t, time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC), bucketStart(day, model.ReportBucketWeek))
+	assert.Equal(t, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), bucketStart(day, model.ReportBucketMonth))
+}
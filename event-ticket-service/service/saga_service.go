@@ -0,0 +1,474 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/contracts"
+	"github.com/yourusername/ticket-system/event-ticket-service/model"
+	"github.com/yourusername/ticket-system/event-ticket-service/repository"
+	"github.com/yourusername/ticket-system/messaging"
+	"gorm.io/gorm"
+)
+
+// sagaTimeoutBatchSize is how many timed out sagas are handled per step in one pass
+const sagaTimeoutBatchSize = 100
+
+// sagaTimeoutsTotal tracks the number of saga steps that timed out
+var sagaTimeoutsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "booking_saga_timeouts_total",
+		Help: "Total number of booking saga steps that timed out",
+	},
+	[]string{"step"},
+)
+
+// DefaultSagaTimeouts returns how long a booking saga waits for each step
+// before it gives up on the booking
+func DefaultSagaTimeouts() map[string]time.Duration {
+	return map[string]time.Duration{
+		model.SagaStepCreatePayment:  5 * time.Minute,
+		model.SagaStepCapturePayment: 15 * time.Minute,
+		model.SagaStepRefundPayment:  30 * time.Minute,
+	}
+}
+
+// SagaService defines the interface for the booking saga orchestrator
+type SagaService interface {
+	GetSaga(bookingID uuid.UUID) (*model.BookingSaga, error)
+	GetStuckSagas(page, pageSize int) ([]model.BookingSaga, int64, error)
+	HandlePaymentCreated(bookingID, paymentID uuid.UUID) error
+	HandlePaymentCompleted(bookingID, paymentID uuid.UUID, amount float64) error
+	HandlePaymentFailed(bookingID uuid.UUID) error
+	HandlePaymentRefunded(bookingID uuid.UUID) error
+	StartTimeoutWorker(ctx context.Context, interval time.Duration)
+	WithTx(tx *gorm.DB) SagaService
+}
+
+// sagaService implements SagaService interface
+type sagaService struct {
+	sagaRepo       repository.SagaRepository
+	outboxRepo     messaging.OutboxRepository
+	bookingService BookingService
+	timeouts       map[string]time.Duration
+	db             *gorm.DB
+}
+
+// NewSagaService creates a new saga service
+func NewSagaService(
+	sagaRepo repository.SagaRepository,
+	outboxRepo messaging.OutboxRepository,
+	bookingService BookingService,
+	timeouts map[string]time.Duration,
+	db *gorm.DB,
+) SagaService {
+	return &sagaService{
+		sagaRepo:       sagaRepo,
+		outboxRepo:     outboxRepo,
+		bookingService: bookingService,
+		timeouts:       timeouts,
+		db:             db,
+	}
+}
+
+// WithTx returns a service that runs its operations in tx, so that consumers
+// can apply a message in the transaction that records it as processed
+func (s *sagaService) WithTx(tx *gorm.DB) SagaService {
+	return &sagaService{
+		sagaRepo:       s.sagaRepo.WithTx(tx),
+		outboxRepo:     s.outboxRepo,
+		bookingService: s.bookingService.WithTx(tx),
+		timeouts:       s.timeouts,
+		db:             tx,
+	}
+}
+
+// startBookingSaga starts the saga of a booking whose tickets were reserved
+// in tx and asks the payment service for its payment
+func startBookingSaga(sagaRepo repository.SagaRepository, outboxRepo messaging.OutboxRepository, tx *gorm.DB, booking *model.Booking) error {
+	saga := &model.BookingSaga{
+		BookingID:     booking.ID,
+		Status:        model.SagaRunning,
+		Step:          model.SagaStepCreatePayment,
+		StepStartedAt: time.Now(),
+		Amount:        booking.TotalPrice,
+		Steps: []model.BookingSagaStep{
+			{
+				Step:   model.SagaStepReserveTickets,
+				Status: model.SagaStepCompleted,
+				Detail: fmt.Sprintf("%d tickets reserved", len(booking.Tickets)),
+			},
+		},
+	}
+
+	if err := sagaRepo.WithTx(tx).Create(saga); err != nil {
+		return fmt.Errorf("failed to create booking saga: %w", err)
+	}
+
+	command := contracts.BookingPaymentRequested{
+		BookingID: booking.ID,
+		UserID:    booking.UserID,
+		Amount:    booking.TotalPrice,
+	}
+
+	return messaging.EnqueueEvent(outboxRepo, tx, "ticket_events", booking.ID.String(), command)
+}
+
+// GetSaga gets the saga of a booking with its steps
+func (s *sagaService) GetSaga(bookingID uuid.UUID) (*model.BookingSaga, error) {
+	saga, err := s.sagaRepo.FindByBookingID(bookingID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find saga: %w", err)
+	}
+
+	if saga == nil {
+		return nil, fmt.Errorf("saga not found")
+	}
+
+	s.setStepDeadline(saga)
+	return saga, nil
+}
+
+// GetStuckSagas gets the sagas that failed or are waiting for a step past its timeout
+func (s *sagaService) GetStuckSagas(page, pageSize int) ([]model.BookingSaga, int64, error) {
+	now := time.Now()
+	startedBefore := make(map[string]time.Time, len(s.timeouts))
+	for step, timeout := range s.timeouts {
+		startedBefore[step] = now.Add(-timeout)
+	}
+
+	sagas, total, err := s.sagaRepo.FindStuck(startedBefore, page, pageSize)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to find stuck sagas: %w", err)
+	}
+
+	for i := range sagas {
+		s.setStepDeadline(&sagas[i])
+	}
+
+	return sagas, total, nil
+}
+
+// HandlePaymentCreated moves a saga on to wait for its payment to be captured
+func (s *sagaService) HandlePaymentCreated(bookingID, paymentID uuid.UUID) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		saga, err := s.sagaRepo.WithTx(tx).LockByBookingID(bookingID)
+		if err != nil {
+			return fmt.Errorf("failed to find saga: %w", err)
+		}
+
+		if saga == nil || saga.Status != model.SagaRunning || saga.Step != model.SagaStepCreatePayment {
+			return nil
+		}
+
+		if err := s.recordStep(tx, saga, model.SagaStepCreatePayment, model.SagaStepCompleted, fmt.Sprintf("payment %s created", paymentID)); err != nil {
+			return err
+		}
+
+		saga.PaymentID = &paymentID
+		s.moveTo(saga, model.SagaStepCapturePayment)
+		return s.sagaRepo.WithTx(tx).Update(saga)
+	})
+}
+
+// HandlePaymentCompleted confirms the booking of a running saga and notifies
+// the user. A payment captured after the saga gave up on the booking is refunded.
+func (s *sagaService) HandlePaymentCompleted(bookingID, paymentID uuid.UUID, amount float64) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		saga, err := s.sagaRepo.WithTx(tx).LockByBookingID(bookingID)
+		if err != nil {
+			return fmt.Errorf("failed to find saga: %w", err)
+		}
+
+		// Bookings created before the saga was introduced are confirmed directly
+		if saga == nil {
+			_, err := s.bookingService.WithTx(tx).UpdateBookingStatus(bookingID, "confirmed")
+			return err
+		}
+
+		switch saga.Status {
+		case model.SagaRunning:
+			saga.PaymentID = &paymentID
+			saga.Amount = amount
+			return s.confirmBooking(tx, saga)
+		case model.SagaCompensated:
+			saga.PaymentID = &paymentID
+			saga.Amount = amount
+			return s.refundPayment(tx, saga, "payment captured after the booking was cancelled")
+		default:
+			return nil
+		}
+	})
+}
+
+// HandlePaymentFailed releases the tickets of a running saga
+func (s *sagaService) HandlePaymentFailed(bookingID uuid.UUID) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		saga, err := s.sagaRepo.WithTx(tx).LockByBookingID(bookingID)
+		if err != nil {
+			return fmt.Errorf("failed to find saga: %w", err)
+		}
+
+		// Bookings created before the saga was introduced are cancelled directly
+		if saga == nil {
+			return s.bookingService.WithTx(tx).CancelBooking(bookingID)
+		}
+
+		if saga.Status != model.SagaRunning {
+			return nil
+		}
+
+		return s.compensate(tx, saga, "payment failed")
+	})
+}
+
+// HandlePaymentRefunded marks a booking as refunded and completes the
+// compensation of a saga that was waiting for its refund
+func (s *sagaService) HandlePaymentRefunded(bookingID uuid.UUID) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := s.bookingService.WithTx(tx).UpdateBookingStatus(bookingID, "refunded"); err != nil {
+			return err
+		}
+
+		saga, err := s.sagaRepo.WithTx(tx).LockByBookingID(bookingID)
+		if err != nil {
+			return fmt.Errorf("failed to find saga: %w", err)
+		}
+
+		if saga == nil || saga.Step != model.SagaStepRefundPayment {
+			return nil
+		}
+
+		if err := s.recordStep(tx, saga, model.SagaStepRefundPayment, model.SagaStepCompensated, "payment refunded"); err != nil {
+			return err
+		}
+
+		s.finish(saga, model.SagaCompensated)
+		return s.sagaRepo.WithTx(tx).Update(saga)
+	})
+}
+
+// StartTimeoutWorker periodically gives up on sagas whose step timed out
+// until ctx is cancelled
+func (s *sagaService) StartTimeoutWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.handleTimeouts(ctx)
+		}
+	}
+}
+
+// handleTimeouts gives up on the sagas that have been waiting for a step
+// longer than its timeout
+func (s *sagaService) handleTimeouts(ctx context.Context) {
+	for step, timeout := range s.timeouts {
+		sagas, err := s.sagaRepo.FindTimedOut(step, time.Now().Add(-timeout), sagaTimeoutBatchSize)
+		if err != nil {
+			logrus.WithError(err).Errorf("Failed to find sagas timed out at %s", step)
+			continue
+		}
+
+		for _, saga := range sagas {
+			if ctx.Err() != nil {
+				return
+			}
+
+			if err := s.timeOut(saga.BookingID, step, timeout); err != nil {
+				logrus.WithError(err).Errorf("Failed to time out saga of booking %s", saga.BookingID)
+			}
+		}
+	}
+}
+
+// timeOut compensates a running saga whose step timed out. A refund that
+// times out fails the saga, which then needs an operator.
+func (s *sagaService) timeOut(bookingID uuid.UUID, step string, timeout time.Duration) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		saga, err := s.sagaRepo.WithTx(tx).LockByBookingID(bookingID)
+		if err != nil {
+			return fmt.Errorf("failed to find saga: %w", err)
+		}
+
+		// The step may have completed since the saga was found
+		if saga == nil || saga.Step != step || time.Since(saga.StepStartedAt) < timeout {
+			return nil
+		}
+
+		cause := fmt.Sprintf("%s timed out after %s", step, timeout)
+		sagaTimeoutsTotal.WithLabelValues(step).Inc()
+
+		switch saga.Status {
+		case model.SagaRunning:
+			logrus.Warnf("Saga of booking %s: %s, releasing tickets", bookingID, cause)
+			return s.compensate(tx, saga, cause)
+		case model.SagaCompensating:
+			logrus.Errorf("Saga of booking %s failed: %s", bookingID, cause)
+			if err := s.recordStep(tx, saga, step, model.SagaStepFailed, cause); err != nil {
+				return err
+			}
+
+			saga.Status = model.SagaFailed
+			saga.LastError = cause
+			return s.sagaRepo.WithTx(tx).Update(saga)
+		default:
+			return nil
+		}
+	})
+}
+
+// confirmBooking runs the steps that follow the capture of the payment
+func (s *sagaService) confirmBooking(tx *gorm.DB, saga *model.BookingSaga) error {
+	if saga.Step == model.SagaStepCreatePayment {
+		if err := s.recordStep(tx, saga, model.SagaStepCreatePayment, model.SagaStepCompleted, fmt.Sprintf("payment %s created", saga.PaymentID)); err != nil {
+			return err
+		}
+	}
+
+	if err := s.recordStep(tx, saga, model.SagaStepCapturePayment, model.SagaStepCompleted, fmt.Sprintf("payment %s captured", saga.PaymentID)); err != nil {
+		return err
+	}
+
+	// The user may have cancelled the booking while the payment was processed
+	current, err := s.bookingService.WithTx(tx).GetBookingByID(saga.BookingID)
+	if err != nil {
+		return err
+	}
+
+	if current.Status == "cancelled" || current.Status == "refunded" {
+		if err := s.recordStep(tx, saga, model.SagaStepReleaseTickets, model.SagaStepCompensated, "booking cancelled before payment"); err != nil {
+			return err
+		}
+		return s.refundPayment(tx, saga, "payment captured after the booking was cancelled")
+	}
+
+	booking, err := s.bookingService.WithTx(tx).UpdateBookingStatus(saga.BookingID, "confirmed")
+	if err != nil {
+		return err
+	}
+
+	if err := s.recordStep(tx, saga, model.SagaStepConfirmBooking, model.SagaStepCompleted, "booking confirmed"); err != nil {
+		return err
+	}
+
+	event := contracts.BookingConfirmed{
+		BookingID:   booking.ID,
+		UserID:      booking.UserID,
+		EventID:     booking.EventID,
+		PaymentID:   *saga.PaymentID,
+		TotalPrice:  booking.TotalPrice,
+		TicketCount: len(booking.Tickets),
+	}
+
+	// Notifications name the event the tickets are for
+	if current.Event != nil {
+		event.EventName = current.Event.Name
+		event.EventStartDate = current.Event.StartDate
+		event.EventLocation = current.Event.Location
+	}
+
+	if err := messaging.EnqueueEvent(s.outboxRepo, tx, "ticket_events", booking.ID.String(), event); err != nil {
+		return err
+	}
+
+	if err := s.recordStep(tx, saga, model.SagaStepNotify, model.SagaStepCompleted, "booking.confirmed published"); err != nil {
+		return err
+	}
+
+	s.finish(saga, model.SagaCompleted)
+	return s.sagaRepo.WithTx(tx).Update(saga)
+}
+
+// compensate fails the current step of a saga and releases the tickets of its booking
+func (s *sagaService) compensate(tx *gorm.DB, saga *model.BookingSaga, cause string) error {
+	if err := s.recordStep(tx, saga, saga.Step, model.SagaStepFailed, cause); err != nil {
+		return err
+	}
+
+	// Cancelling an unpaid booking also cancels its pending payment
+	if err := s.bookingService.WithTx(tx).CancelBooking(saga.BookingID); err != nil {
+		return err
+	}
+
+	if err := s.recordStep(tx, saga, model.SagaStepReleaseTickets, model.SagaStepCompensated, "tickets released"); err != nil {
+		return err
+	}
+
+	saga.LastError = cause
+	s.finish(saga, model.SagaCompensated)
+	return s.sagaRepo.WithTx(tx).Update(saga)
+}
+
+// refundPayment asks the payment service to refund a payment captured for a
+// booking the saga already gave up on
+func (s *sagaService) refundPayment(tx *gorm.DB, saga *model.BookingSaga, cause string) error {
+	command := contracts.BookingRefundRequested{
+		BookingID:    saga.BookingID,
+		PaymentID:    *saga.PaymentID,
+		RefundAmount: saga.Amount,
+		RefundReason: cause,
+	}
+
+	if err := messaging.EnqueueEvent(s.outboxRepo, tx, "ticket_events", saga.BookingID.String(), command); err != nil {
+		return err
+	}
+
+	logrus.Warnf("Saga of booking %s: %s, refunding %.2f", saga.BookingID, cause, saga.Amount)
+
+	saga.Status = model.SagaCompensating
+	saga.LastError = cause
+	saga.CompletedAt = nil
+	s.moveTo(saga, model.SagaStepRefundPayment)
+	return s.sagaRepo.WithTx(tx).Update(saga)
+}
+
+// recordStep records the outcome of a step of saga in tx
+func (s *sagaService) recordStep(tx *gorm.DB, saga *model.BookingSaga, step, status, detail string) error {
+	err := s.sagaRepo.WithTx(tx).AddStep(&model.BookingSagaStep{
+		SagaID: saga.ID,
+		Step:   step,
+		Status: status,
+		Detail: detail,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record saga step: %w", err)
+	}
+	return nil
+}
+
+// moveTo makes saga wait for step
+func (s *sagaService) moveTo(saga *model.BookingSaga, step string) {
+	saga.Step = step
+	saga.StepStartedAt = time.Now()
+}
+
+// finish ends saga with status
+func (s *sagaService) finish(saga *model.BookingSaga, status string) {
+	now := time.Now()
+	saga.Status = status
+	saga.Step = ""
+	saga.StepStartedAt = now
+	saga.CompletedAt = &now
+}
+
+// setStepDeadline sets when the step a saga is waiting for times out
+func (s *sagaService) setStepDeadline(saga *model.BookingSaga) {
+	if saga.Status != model.SagaRunning && saga.Status != model.SagaCompensating {
+		return
+	}
+
+	if timeout, ok := s.timeouts[saga.Step]; ok {
+		deadline := saga.StepStartedAt.Add(timeout)
+		saga.StepDeadline = &deadline
+	}
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/ticket-system/contracts"
+	"github.com/yourusername/ticket-system/event-ticket-service/model"
+	"github.com/yourusername/ticket-system/event-ticket-service/repository"
+	"github.com/yourusername/ticket-system/messaging"
+	"gorm.io/gorm"
+)
+
+// setupSagaService creates a saga service and the booking service it drives
+func setupSagaService(t *testing.T, db *gorm.DB) (*sagaService, BookingService) {
+	bookingService := setupBookingService(t, db)
+	sagaService := NewSagaService(
+		repository.NewSagaRepository(db),
+		messaging.NewOutboxRepository(db),
+		bookingService,
+		DefaultSagaTimeouts(),
+		db,
+	).(*sagaService)
+	return sagaService, bookingService
+}
+
+// startSaga books a regular ticket of a new event, which starts its saga
+func startSaga(t *testing.T, db *gorm.DB, bookingService BookingService) *model.BookingResponse {
+	event := createTestEvent(t, db, 30*24*time.Hour, 1, map[string]float64{"regular": 100})
+
+	var req model.CreateBookingRequest
+	req.EventID = event.ID
+	req.Tickets = append(req.Tickets, struct {
+		Type     string `json:"type" binding:"required"`
+		Quantity int    `json:"quantity" binding:"required"`
+	}{Type: "regular", Quantity: 1})
+
+	booking, err := bookingService.CreateBooking(uuid.New(), req)
+	require.NoError(t, err)
+	return booking
+}
+
+// sagaSteps lists the recorded steps of a saga as "step:status"
+func sagaSteps(saga *model.BookingSaga) []string {
+	steps := make([]string, 0, len(saga.Steps))
+	for _, step := range saga.Steps {
+		steps = append(steps, step.Step+":"+step.Status)
+	}
+	return steps
+}
+
+// countOutbox counts the messages written to the outbox with a routing key
+func countOutbox(t *testing.T, db *gorm.DB, routingKey string) int64 {
+	var count int64
+	require.NoError(t, db.Model(&messaging.OutboxMessage{}).Where("routing_key = ?", routingKey).Count(&count).Error)
+	return count
+}
+
+// availableTickets counts the available tickets of an event
+func availableTickets(t *testing.T, db *gorm.DB, eventID uuid.UUID) int64 {
+	var count int64
+	require.NoError(t, db.Model(&model.Ticket{}).Where("event_id = ? AND status = ?", eventID, "available").Count(&count).Error)
+	return count
+}
+
+func TestSagaService_PaymentConfirmsBooking(t *testing.T) {
+	db := setupTestDB(t)
+	sagaService, _ := setupSagaService(t, db)
+	booking := startSaga(t, db, sagaService.bookingService)
+	assert.Equal(t, int64(1), countOutbox(t, db, contracts.TypeBookingPaymentRequested))
+
+	saga, err := sagaService.GetSaga(booking.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.SagaRunning, saga.Status)
+	assert.Equal(t, model.SagaStepCreatePayment, saga.Step)
+	require.NotNil(t, saga.StepDeadline)
+
+	paymentID := uuid.New()
+	require.NoError(t, sagaService.HandlePaymentCreated(booking.ID, paymentID))
+	require.NoError(t, sagaService.HandlePaymentCompleted(booking.ID, paymentID, 100))
+
+	saga, err = sagaService.GetSaga(booking.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.SagaCompleted, saga.Status)
+	assert.NotNil(t, saga.CompletedAt)
+	assert.Nil(t, saga.StepDeadline)
+	assert.Equal(t, []string{
+		"reserve_tickets:completed",
+		"create_payment:completed",
+		"capture_payment:completed",
+		"confirm_booking:completed",
+		"notify:completed",
+	}, sagaSteps(saga))
+
+	assert.Equal(t, "confirmed", findBooking(t, db, booking.ID).Status)
+	assert.Equal(t, int64(1), countOutbox(t, db, contracts.TypeBookingConfirmed))
+
+	// A redelivered payment does not confirm the booking twice
+	require.NoError(t, sagaService.HandlePaymentCompleted(booking.ID, paymentID, 100))
+	assert.Equal(t, int64(1), countOutbox(t, db, contracts.TypeBookingConfirmed))
+}
+
+func TestSagaService_FailedPaymentReleasesTickets(t *testing.T) {
+	db := setupTestDB(t)
+	sagaService, _ := setupSagaService(t, db)
+	booking := startSaga(t, db, sagaService.bookingService)
+	assert.Equal(t, int64(0), availableTickets(t, db, booking.EventID))
+
+	require.NoError(t, sagaService.HandlePaymentFailed(booking.ID))
+
+	saga, err := sagaService.GetSaga(booking.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.SagaCompensated, saga.Status)
+	assert.Equal(t, "payment failed", saga.LastError)
+	assert.Equal(t, []string{
+		"reserve_tickets:completed",
+		"create_payment:failed",
+		"release_tickets:compensated",
+	}, sagaSteps(saga))
+
+	assert.Equal(t, "cancelled", findBooking(t, db, booking.ID).Status)
+	assert.Equal(t, int64(1), availableTickets(t, db, booking.EventID))
+
+	// Failing again does not compensate twice
+	require.NoError(t, sagaService.HandlePaymentFailed(booking.ID))
+	saga, err = sagaService.GetSaga(booking.ID)
+	require.NoError(t, err)
+	assert.Len(t, saga.Steps, 3)
+}
+
+func TestSagaService_LatePaymentIsRefunded(t *testing.T) {
+	db := setupTestDB(t)
+	sagaService, _ := setupSagaService(t, db)
+	booking := startSaga(t, db, sagaService.bookingService)
+	require.NoError(t, sagaService.HandlePaymentFailed(booking.ID))
+
+	// The payment is captured after the saga gave up on the booking
+	paymentID := uuid.New()
+	require.NoError(t, sagaService.HandlePaymentCompleted(booking.ID, paymentID, 100))
+
+	saga, err := sagaService.GetSaga(booking.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.SagaCompensating, saga.Status)
+	assert.Equal(t, model.SagaStepRefundPayment, saga.Step)
+	assert.Nil(t, saga.CompletedAt)
+	assert.Equal(t, "cancelled", findBooking(t, db, booking.ID).Status)
+	assert.Equal(t, int64(0), countOutbox(t, db, contracts.TypeBookingConfirmed))
+	assert.Equal(t, int64(1), countOutbox(t, db, contracts.TypeBookingRefundRequested))
+
+	require.NoError(t, sagaService.HandlePaymentRefunded(booking.ID))
+
+	saga, err = sagaService.GetSaga(booking.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.SagaCompensated, saga.Status)
+	assert.Equal(t, "refund_payment:compensated", sagaSteps(saga)[len(saga.Steps)-1])
+	assert.Equal(t, "refunded", findBooking(t, db, booking.ID).Status)
+}
+
+func TestSagaService_TimedOutStepsAreCompensated(t *testing.T) {
+	db := setupTestDB(t)
+	sagaService, bookingService := setupSagaService(t, db)
+	unpaid := startSaga(t, db, bookingService)
+	refunding := startSaga(t, db, bookingService)
+	paid := startSaga(t, db, bookingService)
+
+	require.NoError(t, sagaService.HandlePaymentFailed(refunding.ID))
+	require.NoError(t, sagaService.HandlePaymentCompleted(refunding.ID, uuid.New(), 100))
+
+	// Nothing has timed out yet
+	sagaService.handleTimeouts(context.Background())
+	stuck, total, err := sagaService.GetStuckSagas(1, 10)
+	require.NoError(t, err)
+	assert.Empty(t, stuck)
+	assert.Equal(t, int64(0), total)
+
+	require.NoError(t, db.Model(&model.BookingSaga{}).
+		Where("booking_id IN ?", []uuid.UUID{unpaid.ID, refunding.ID}).
+		Update("step_started_at", time.Now().Add(-time.Hour)).Error)
+
+	stuck, total, err = sagaService.GetStuckSagas(1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	require.Len(t, stuck, 2)
+	assert.NotNil(t, stuck[0].StepDeadline)
+
+	sagaService.handleTimeouts(context.Background())
+
+	// A booking whose payment never arrived gets its tickets back
+	saga, err := sagaService.GetSaga(unpaid.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.SagaCompensated, saga.Status)
+	assert.Equal(t, "create_payment timed out after 5m0s", saga.LastError)
+	assert.Equal(t, "cancelled", findBooking(t, db, unpaid.ID).Status)
+	assert.Equal(t, int64(1), availableTickets(t, db, unpaid.EventID))
+
+	// A refund that never arrived needs an operator
+	saga, err = sagaService.GetSaga(refunding.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.SagaFailed, saga.Status)
+	assert.Equal(t, "refund_payment:failed", sagaSteps(saga)[len(saga.Steps)-1])
+
+	stuck, _, err = sagaService.GetStuckSagas(1, 10)
+	require.NoError(t, err)
+	require.Len(t, stuck, 1)
+	assert.Equal(t, refunding.ID, stuck[0].BookingID)
+
+	saga, err = sagaService.GetSaga(paid.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.SagaRunning, saga.Status)
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/contracts"
+	"github.com/yourusername/ticket-system/event-ticket-service/model"
+	"github.com/yourusername/ticket-system/event-ticket-service/repository"
+	"gorm.io/gorm"
+)
+
+// TransactionService defines the interface for recording the payments and
+// refunds of bookings
+type TransactionService interface {
+	RecordPayment(payment contracts.PaymentState, occurredAt time.Time) error
+	RecordRefund(refund contracts.RefundState, occurredAt time.Time) error
+	WithTx(tx *gorm.DB) TransactionService
+}
+
+// transactionService implements TransactionService interface
+type transactionService struct {
+	transactionRepo repository.TransactionRepository
+	bookingRepo     repository.BookingRepository
+	reportRepo      repository.ReportRepository
+}
+
+// NewTransactionService creates a new transaction service
+func NewTransactionService(transactionRepo repository.TransactionRepository, bookingRepo repository.BookingRepository, reportRepo repository.ReportRepository) TransactionService {
+	return &transactionService{
+		transactionRepo: transactionRepo,
+		bookingRepo:     bookingRepo,
+		reportRepo:      reportRepo,
+	}
+}
+
+// WithTx returns a service that records transactions in tx
+func (s *transactionService) WithTx(tx *gorm.DB) TransactionService {
+	return &transactionService{
+		transactionRepo: s.transactionRepo.WithTx(tx),
+		bookingRepo:     s.bookingRepo.WithTx(tx),
+		reportRepo:      s.reportRepo.WithTx(tx),
+	}
+}
+
+// RecordPayment records a captured payment of a booking
+func (s *transactionService) RecordPayment(payment contracts.PaymentState, occurredAt time.Time) error {
+	return s.record(&model.BookingTransaction{
+		ID:          payment.PaymentID,
+		Type:        model.TransactionTypePayment,
+		BookingID:   payment.BookingID,
+		UserID:      payment.UserID,
+		PaymentID:   payment.PaymentID,
+		AmendmentID: payment.AmendmentID,
+		Amount:      payment.Amount,
+		Currency:    payment.Currency,
+		OccurredAt:  occurredAt,
+	})
+}
+
+// RecordRefund records a full or partial refund of a payment of a booking
+func (s *transactionService) RecordRefund(refund contracts.RefundState, occurredAt time.Time) error {
+	return s.record(&model.BookingTransaction{
+		ID:          refund.RefundID,
+		Type:        model.TransactionTypeRefund,
+		BookingID:   refund.BookingID,
+		UserID:      refund.UserID,
+		PaymentID:   refund.PaymentID,
+		AmendmentID: refund.AmendmentID,
+		Amount:      -refund.RefundAmount,
+		Currency:    refund.Currency,
+		Reason:      refund.Reason,
+		OccurredAt:  occurredAt,
+	})
+}
+
+// record stores a transaction under the event of its booking
+func (s *transactionService) record(transaction *model.BookingTransaction) error {
+	if transaction.ID == uuid.Nil {
+		return fmt.Errorf("transaction of booking %s has no ID", transaction.BookingID)
+	}
+
+	booking, err := s.bookingRepo.FindByID(transaction.BookingID)
+	if err != nil {
+		return fmt.Errorf("failed to find booking: %w", err)
+	}
+
+	// Payments of unknown bookings cannot be attributed to an event
+	if booking == nil {
+		logrus.Warnf("Skipping %s %s of unknown booking %s", transaction.Type, transaction.ID, transaction.BookingID)
+		return nil
+	}
+
+	transaction.EventID = booking.EventID
+	if transaction.OccurredAt.IsZero() {
+		transaction.OccurredAt = time.Now()
+	}
+
+	recorded, err := s.transactionRepo.Record(transaction)
+	if err != nil {
+		return fmt.Errorf("failed to record %s: %w", transaction.Type, err)
+	}
+
+	// Refunds count in the sales report on the day they were made
+	if recorded && transaction.Type == model.TransactionTypeRefund {
+		if err := s.reportRepo.MarkStale(transaction.EventID); err != nil {
+			logrus.WithError(err).Errorf("Failed to mark report of event %s as stale", transaction.EventID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// Static parts of a single-sheet workbook
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
+	xlsxSheetHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetFooter = `</sheetData></worksheet>`
+)
+
+// XLSXWriter writes rows to a single-sheet XLSX workbook without buffering
+// the sheet in memory. All cells are written as inline strings.
+type XLSXWriter struct {
+	zw    *zip.Writer
+	sheet io.Writer
+	rows  int
+}
+
+// NewXLSXWriter creates a new XLSX writer with the given sheet name
+func NewXLSXWriter(w io.Writer, sheetName string) (*XLSXWriter, error) {
+	zw := zip.NewWriter(w)
+
+	name := xmlString(sheetName)
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, name.escape())},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	}
+	for _, part := range parts {
+		pw, err := zw.Create(part.name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", part.name, err)
+		}
+		if _, err := io.WriteString(pw, part.content); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", part.name, err)
+		}
+	}
+
+	// The sheet is the last part, so rows can be streamed into it
+	sheet, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sheet: %w", err)
+	}
+	if _, err := io.WriteString(sheet, xlsxSheetHeader); err != nil {
+		return nil, fmt.Errorf("failed to write sheet: %w", err)
+	}
+
+	return &XLSXWriter{zw: zw, sheet: sheet}, nil
+}
+
+// Write writes a single row to the sheet
+func (x *XLSXWriter) Write(record []string) error {
+	x.rows++
+	if _, err := fmt.Fprintf(x.sheet, `<row r="%d">`, x.rows); err != nil {
+		return err
+	}
+	for _, value := range record {
+		cell := xmlString(value)
+		if _, err := fmt.Fprintf(x.sheet, `<c t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, cell.escape()); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(x.sheet, `</row>`)
+	return err
+}
+
+// Close finishes the sheet and the workbook archive
+func (x *XLSXWriter) Close() error {
+	if _, err := io.WriteString(x.sheet, xlsxSheetFooter); err != nil {
+		return err
+	}
+	return x.zw.Close()
+}
+
+// xmlString is a string that can be escaped for XML text content
+type xmlString string
+
+// escape returns the XML-escaped string
+func (s xmlString) escape() string {
+	var b xmlBuffer
+	xml.EscapeText(&b, []byte(s))
+	return string(b)
+}
+
+// xmlBuffer collects escaped XML text
+type xmlBuffer []byte
+
+// Write appends data to the buffer
+func (b *xmlBuffer) Write(data []byte) (int, error) {
+	*b = append(*b, data...)
+	return len(data), nil
+}
@@ -0,0 +1,215 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// pollInterval is how often a duplicate request checks whether
+// the request holding its key has finished
+const pollInterval = 100 * time.Millisecond
+
+// responseWriter keeps a copy of the response body
+type responseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+// Write writes the response and keeps a copy of it
+func (w *responseWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString writes the response and keeps a copy of it
+func (w *responseWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// UserIDFunc returns the ID of the user a request is made by, which keys
+// are scoped to. Services set the user in their own authentication
+// middleware, so they pass how to read it back.
+type UserIDFunc func(c *gin.Context) string
+
+// Middleware honours the Idempotency-Key header. The first response for a
+// key is stored with a hash of the request body and replayed to retries
+// within ttl, and a retry with a different body is rejected. The first
+// request holds the key with a lock it renews every lockTimeout/3 while it
+// runs, so a slow request is never run twice; the key is only taken over when
+// the lock lapses because the request died. A retry sent while the first
+// request is running waits up to lockTimeout for it.
+func Middleware(repo Repository, ttl, lockTimeout time.Duration, userID UserIDFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		if len(key) > 255 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "idempotency key must be at most 255 characters"})
+			c.Abort()
+			return
+		}
+
+		// Hash the request body and put it back for the handler
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		sum := sha256.Sum256(body)
+
+		// Keys are scoped to the user and the route
+		lockedUntil := time.Now().Add(lockTimeout)
+		record := &Record{
+			Scope:          fmt.Sprintf("%s %s %s", userID(c), c.Request.Method, c.FullPath()),
+			IdempotencyKey: key,
+			RequestHash:    hex.EncodeToString(sum[:]),
+			Status:         StatusProcessing,
+			OwnerToken:     uuid.NewString(),
+			LockedUntil:    &lockedUntil,
+			ExpiresAt:      time.Now().Add(ttl),
+		}
+
+		deadline := time.Now().Add(lockTimeout)
+		for {
+			existing, acquired, err := repo.Acquire(record)
+			if err != nil {
+				logrus.WithError(err).Error("Failed to acquire idempotency key")
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check idempotency key"})
+				c.Abort()
+				return
+			}
+
+			if acquired {
+				break
+			}
+
+			if existing.RequestHash != record.RequestHash {
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "idempotency key was already used with a different request body"})
+				c.Abort()
+				return
+			}
+
+			// Replay the stored response
+			if existing.Status == StatusCompleted {
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(existing.ResponseCode, existing.ContentType, existing.ResponseBody)
+				c.Abort()
+				return
+			}
+
+			// Block until the request holding the key has finished
+			if time.Now().After(deadline) {
+				c.JSON(http.StatusConflict, gin.H{"error": "a request with this idempotency key is still being processed"})
+				c.Abort()
+				return
+			}
+
+			select {
+			case <-c.Request.Context().Done():
+				c.Abort()
+				return
+			case <-time.After(pollInterval):
+			}
+		}
+
+		writer := &responseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		// Process request while holding the key
+		stopRenewal := renewLock(repo, record, lockTimeout)
+		c.Next()
+		stopRenewal()
+
+		// Server errors are not stored so that the request can be retried
+		if writer.Status() >= http.StatusInternalServerError {
+			released, err := repo.Release(record)
+			if err != nil {
+				logrus.WithError(err).Error("Failed to release idempotency key")
+			} else if !released {
+				logrus.Warnf("Idempotency key %s was taken over before it was released", key)
+			}
+			return
+		}
+
+		record.Status = StatusCompleted
+		record.ResponseCode = writer.Status()
+		record.ContentType = writer.Header().Get("Content-Type")
+		record.ResponseBody = writer.body.Bytes()
+		completed, err := repo.Complete(record)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to store idempotent response")
+		} else if !completed {
+			logrus.Warnf("Idempotency key %s was taken over before its response was stored", key)
+		}
+	}
+}
+
+// renewLock keeps extending the lock of a request on its key until the
+// returned function is called. The function waits for a renewal in progress,
+// so that the record is not renewed after it is completed or released.
+func renewLock(repo Repository, record *Record, lockTimeout time.Duration) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(lockTimeout / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				renewed, err := repo.Renew(record, time.Now().Add(lockTimeout))
+				if err != nil {
+					logrus.WithError(err).Error("Failed to renew idempotency key")
+				} else if !renewed {
+					return
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// StartCleanupWorker periodically deletes expired idempotency records
+func StartCleanupWorker(ctx context.Context, repo Repository, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := repo.DeleteExpired()
+			if err != nil {
+				logrus.WithError(err).Error("Failed to delete expired idempotency records")
+				continue
+			}
+			if deleted > 0 {
+				logrus.Infof("Deleted %d expired idempotency records", deleted)
+			}
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package idempotency
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupRepository opens an in-memory database with the idempotency table
+func setupRepository(t *testing.T) Repository {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	// Every connection opens its own in-memory database
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	return NewRepository(db)
+}
+
+// setupRouter serves a booking route behind the idempotency
+// middleware whose handler counts its calls
+func setupRouter(repo Repository, lockTimeout time.Duration, handler gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	userID := func(c *gin.Context) string { return "user-1" }
+	router.POST("/api/bookings", Middleware(repo, time.Hour, lockTimeout, userID), handler)
+	return router
+}
+
+// sendWithKey posts body to the booking route with an idempotency key
+func sendWithKey(router *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/bookings", strings.NewReader(body))
+	req.Header.Set("Idempotency-Key", key)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestMiddleware_ReplaysCompletedResponse(t *testing.T) {
+	var calls int32
+	router := setupRouter(setupRepository(t), time.Second, func(c *gin.Context) {
+		n := atomic.AddInt32(&calls, 1)
+		c.JSON(http.StatusCreated, gin.H{"booking": n})
+	})
+
+	first := sendWithKey(router, "key-1", `{"event_id":"1"}`)
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Empty(t, first.Header().Get("Idempotent-Replayed"))
+
+	retry := sendWithKey(router, "key-1", `{"event_id":"1"}`)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// Another key is a new request
+	other := sendWithKey(router, "key-2", `{"event_id":"1"}`)
+	assert.JSONEq(t, `{"booking":2}`, other.Body.String())
+}
+
+func TestMiddleware_ScopesKeysToUser(t *testing.T) {
+	var calls int32
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	userID := func(c *gin.Context) string { return c.GetHeader("X-User") }
+	router.POST("/api/bookings", Middleware(setupRepository(t), time.Hour, time.Second, userID), func(c *gin.Context) {
+		n := atomic.AddInt32(&calls, 1)
+		c.JSON(http.StatusCreated, gin.H{"booking": n})
+	})
+
+	// send posts the same body with the same key as user
+	send := func(user string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/bookings", strings.NewReader(`{}`))
+		req.Header.Set("Idempotency-Key", "key-1")
+		req.Header.Set("X-User", user)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.JSONEq(t, `{"booking":1}`, send("user-1").Body.String())
+	assert.JSONEq(t, `{"booking":2}`, send("user-2").Body.String())
+	assert.Equal(t, "true", send("user-1").Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestMiddleware_RejectsKeyReusedWithDifferentBody(t *testing.T) {
+	var calls int32
+	router := setupRouter(setupRepository(t), time.Second, func(c *gin.Context) {
+		atomic.AddInt32(&calls, 1)
+		c.JSON(http.StatusCreated, gin.H{})
+	})
+
+	assert.Equal(t, http.StatusCreated, sendWithKey(router, "key-1", `{"event_id":"1"}`).Code)
+
+	conflict := sendWithKey(router, "key-1", `{"event_id":"2"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, conflict.Code)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	tooLong := sendWithKey(router, strings.Repeat("k", 256), `{}`)
+	assert.Equal(t, http.StatusBadRequest, tooLong.Code)
+}
+
+func TestMiddleware_ServerErrorsCanBeRetried(t *testing.T) {
+	var calls int32
+	router := setupRouter(setupRepository(t), time.Second, func(c *gin.Context) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database unavailable"})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{})
+	})
+
+	assert.Equal(t, http.StatusInternalServerError, sendWithKey(router, "key-1", `{}`).Code)
+
+	retry := sendWithKey(router, "key-1", `{}`)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Empty(t, retry.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestMiddleware_SlowRequestIsNotTakenOver(t *testing.T) {
+	lockTimeout := 150 * time.Millisecond
+	var calls int32
+	router := setupRouter(setupRepository(t), lockTimeout, func(c *gin.Context) {
+		atomic.AddInt32(&calls, 1)
+
+		// Runs for several lock timeouts, renewing the lock meanwhile
+		time.Sleep(4 * lockTimeout)
+		c.JSON(http.StatusCreated, gin.H{})
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	var first *httptest.ResponseRecorder
+	go func() {
+		defer wg.Done()
+		first = sendWithKey(router, "key-1", `{}`)
+	}()
+
+	// A retry gives up waiting instead of running the request again
+	time.Sleep(2 * lockTimeout)
+	retry := sendWithKey(router, "key-1", `{}`)
+	assert.Equal(t, http.StatusConflict, retry.Code)
+
+	wg.Wait()
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	replay := sendWithKey(router, "key-1", `{}`)
+	assert.Equal(t, "true", replay.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+// slowRenewRepository blocks every renewal until release is closed
+type slowRenewRepository struct {
+	Repository
+	renewing chan struct{}
+	release  chan struct{}
+}
+
+// Renew signals the renewal and waits for release
+func (r *slowRenewRepository) Renew(record *Record, lockedUntil time.Time) (bool, error) {
+	select {
+	case r.renewing <- struct{}{}:
+	default:
+	}
+	<-r.release
+	return true, nil
+}
+
+func TestRenewLock_StopWaitsForRenewal(t *testing.T) {
+	repo := &slowRenewRepository{renewing: make(chan struct{}, 1), release: make(chan struct{})}
+	stop := renewLock(repo, &Record{}, 30*time.Millisecond)
+	<-repo.renewing
+
+	// Stopping returns only once the renewal in progress is over
+	stopped := make(chan struct{})
+	go func() {
+		stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		t.Fatal("stop returned during a renewal")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(repo.release)
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("stop did not return after the renewal")
+	}
+}
+
+func TestRepository_FencesTakenOverKey(t *testing.T) {
+	repo := setupRepository(t)
+
+	// newRecord builds a processing record of a request locked until lockedUntil
+	newRecord := func(lockedUntil time.Time) *Record {
+		return &Record{
+			Scope:          "user-1 POST /api/bookings",
+			IdempotencyKey: "key-1",
+			RequestHash:    "hash",
+			Status:         StatusProcessing,
+			OwnerToken:     uuid.NewString(),
+			LockedUntil:    &lockedUntil,
+			ExpiresAt:      time.Now().Add(time.Hour),
+		}
+	}
+
+	// The first request stopped renewing its lock
+	stale := newRecord(time.Now().Add(-time.Second))
+	_, acquired, err := repo.Acquire(stale)
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	current := newRecord(time.Now().Add(time.Minute))
+	_, acquired, err = repo.Acquire(current)
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	// The first request can no longer touch the key
+	renewed, err := repo.Renew(stale, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, renewed)
+
+	stale.Status = StatusCompleted
+	completed, err := repo.Complete(stale)
+	require.NoError(t, err)
+	assert.False(t, completed)
+
+	released, err := repo.Release(stale)
+	require.NoError(t, err)
+	assert.False(t, released)
+
+	// The new owner still holds the key and stores its response
+	existing, acquired, err := repo.Acquire(newRecord(time.Now().Add(time.Minute)))
+	require.NoError(t, err)
+	assert.False(t, acquired)
+	assert.Equal(t, current.OwnerToken, existing.OwnerToken)
+
+	current.Status = StatusCompleted
+	current.ResponseCode = http.StatusCreated
+	completed, err = repo.Complete(current)
+	require.NoError(t, err)
+	assert.True(t, completed)
+}
@@ -0,0 +1,46 @@
+package idempotency
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Record statuses
+const (
+	StatusProcessing = "processing"
+	StatusCompleted  = "completed"
+)
+
+// Record stores the response of a request sent with an Idempotency-Key
+// header so that retries of the request can be replayed
+type Record struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	Scope          string     `gorm:"size:512;not null;uniqueIndex:idx_idempotency_scope_key" json:"scope"` // user, method and route
+	IdempotencyKey string     `gorm:"size:255;not null;uniqueIndex:idx_idempotency_scope_key" json:"idempotency_key"`
+	RequestHash    string     `gorm:"size:64;not null" json:"request_hash"`
+	Status         string     `gorm:"size:50;not null" json:"status"` // processing, completed
+	OwnerToken     string     `gorm:"size:36;not null" json:"-"`      // the request processing the key
+	LockedUntil    *time.Time `json:"locked_until,omitempty"`         // renewed while the request is processing
+	ResponseCode   int        `json:"response_code"`
+	ContentType    string     `gorm:"size:255" json:"content_type"`
+	ResponseBody   []byte     `json:"-"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+	ExpiresAt      time.Time  `gorm:"not null;index" json:"expires_at"`
+}
+
+// TableName keeps the table the services created before the record moved
+// into this module
+func (Record) TableName() string {
+	return "idempotency_records"
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (r *Record) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package idempotency
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Repository defines the interface for idempotency record repository operations
+type Repository interface {
+	Acquire(record *Record) (*Record, bool, error)
+	Renew(record *Record, lockedUntil time.Time) (bool, error)
+	Complete(record *Record) (bool, error)
+	Release(record *Record) (bool, error)
+	DeleteExpired() (int64, error)
+}
+
+// repository implements Repository interface
+type repository struct {
+	db *gorm.DB
+}
+
+// NewRepository creates a new idempotency repository
+func NewRepository(db *gorm.DB) Repository {
+	// Auto migrate the model
+	db.AutoMigrate(&Record{})
+
+	return &repository{
+		db: db,
+	}
+}
+
+// Acquire claims an idempotency key for a request. When the key is already
+// taken the existing record is returned instead. Expired records and records
+// whose request stopped renewing its lock are taken over; a request that is
+// still processing keeps its key however long it takes.
+func (r *repository) Acquire(record *Record) (*Record, bool, error) {
+	// Drop a record whose key can be reused
+	now := time.Now()
+	if err := r.db.Where("scope = ? AND idempotency_key = ?", record.Scope, record.IdempotencyKey).
+		Where("expires_at < ? OR (status = ? AND locked_until < ?)", now, StatusProcessing, now).
+		Delete(&Record{}).Error; err != nil {
+		return nil, false, err
+	}
+
+	// The unique index makes only one of concurrent requests win the key
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	if result.Error != nil {
+		return nil, false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return record, true, nil
+	}
+
+	var existing Record
+	err := r.db.Where("scope = ? AND idempotency_key = ?", record.Scope, record.IdempotencyKey).First(&existing).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			// Taken over and released in the meantime, so try again
+			return r.Acquire(record)
+		}
+		return nil, false, err
+	}
+	return &existing, false, nil
+}
+
+// Renew extends the lock of a request on its key, reporting false when the
+// request no longer holds the key
+func (r *repository) Renew(record *Record, lockedUntil time.Time) (bool, error) {
+	result := r.db.Model(&Record{}).
+		Where("id = ? AND owner_token = ? AND status = ?", record.ID, record.OwnerToken, StatusProcessing).
+		Update("locked_until", lockedUntil)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Complete stores the response of a request, reporting false when the
+// request no longer holds the key
+func (r *repository) Complete(record *Record) (bool, error) {
+	result := r.db.Model(&Record{}).
+		Where("id = ? AND owner_token = ? AND status = ?", record.ID, record.OwnerToken, StatusProcessing).
+		Updates(map[string]interface{}{
+			"status":        record.Status,
+			"locked_until":  nil,
+			"response_code": record.ResponseCode,
+			"content_type":  record.ContentType,
+			"response_body": record.ResponseBody,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Release frees the key of a request that could not be completed, reporting
+// false when the request no longer holds the key
+func (r *repository) Release(record *Record) (bool, error) {
+	result := r.db.Where("id = ? AND owner_token = ? AND status = ?", record.ID, record.OwnerToken, StatusProcessing).
+		Delete(&Record{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// DeleteExpired deletes the records past their expiry
+func (r *repository) DeleteExpired() (int64, error) {
+	result := r.db.Where("expires_at < ?", time.Now()).Delete(&Record{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,188 @@
+package jwks
+
+import (
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrKeyNotFound is returned for tokens signed with a key that is not in the key set
+var ErrKeyNotFound = errors.New("signing key not found")
+
+// Config holds the settings of a JWKS client
+type Config struct {
+	URL string
+
+	// RefreshInterval is how long a fetched key set is used before it is
+	// fetched again
+	RefreshInterval time.Duration
+
+	// MinRefreshInterval bounds how often tokens signed with an unknown key
+	// fetch the key set, so that forged key IDs cannot flood the user service
+	MinRefreshInterval time.Duration
+
+	// Timeout bounds a fetch of the key set
+	Timeout time.Duration
+}
+
+// ConfigFromEnv returns the configuration from the JWKS_* environment variables
+func ConfigFromEnv() Config {
+	config := Config{
+		URL: os.Getenv("JWKS_URL"),
+	}
+	if config.URL == "" {
+		config.URL = "http://localhost:8081/.well-known/jwks.json"
+	}
+	if interval, err := time.ParseDuration(os.Getenv("JWKS_REFRESH_INTERVAL")); err == nil {
+		config.RefreshInterval = interval
+	}
+	return config.withDefaults()
+}
+
+// withDefaults fills in the unset settings
+func (c Config) withDefaults() Config {
+	if c.RefreshInterval <= 0 {
+		c.RefreshInterval = 5 * time.Minute
+	}
+	if c.MinRefreshInterval <= 0 {
+		c.MinRefreshInterval = 10 * time.Second
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 5 * time.Second
+	}
+	return c
+}
+
+// cachedKey is a public key of the key set
+type cachedKey struct {
+	algorithm string
+	publicKey crypto.PublicKey
+}
+
+// Client verifies tokens against the key set of the user service. The key
+// set is cached and fetched again when it is older than the refresh
+// interval, or when a token is signed with a key that is not cached yet, so
+// that rotated keys are picked up without a restart.
+type Client struct {
+	config     Config
+	httpClient *http.Client
+
+	mu          sync.Mutex
+	keys        map[string]cachedKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+}
+
+// NewClient creates a JWKS client and fetches the key set. A failed fetch is
+// logged and retried when the first token is verified.
+func NewClient(config Config) *Client {
+	config = config.withDefaults()
+	c := &Client{
+		config:     config,
+		httpClient: &http.Client{Timeout: config.Timeout},
+		keys:       make(map[string]cachedKey),
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.fetch(); err != nil {
+		logrus.WithError(err).Warn("Failed to fetch JWKS")
+	}
+
+	return c
+}
+
+// Keyfunc returns the public key a token was signed with, for jwt.Parse
+func (c *Client) Keyfunc(token *jwt.Token) (interface{}, error) {
+	keyID, _ := token.Header["kid"].(string)
+	if keyID == "" {
+		return nil, errors.New("token has no key ID")
+	}
+
+	key, err := c.key(keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	if token.Method.Alg() != key.algorithm {
+		return nil, fmt.Errorf("token is signed with %s, but key %s is a %s key", token.Method.Alg(), keyID, key.algorithm)
+	}
+
+	return key.publicKey, nil
+}
+
+// key returns a key of the key set, fetching the key set first when it is
+// stale or does not contain the key
+func (c *Client) key(keyID string) (cachedKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key, found := c.keys[keyID]
+	stale := time.Since(c.fetchedAt) >= c.config.RefreshInterval
+	if (!found || stale) && time.Since(c.attemptedAt) >= c.config.MinRefreshInterval {
+		if err := c.fetch(); err != nil {
+			// Keep using the cached keys until the user service is back
+			logrus.WithError(err).Warn("Failed to fetch JWKS")
+		}
+		key, found = c.keys[keyID]
+	}
+
+	if !found {
+		return cachedKey{}, fmt.Errorf("%w: %s", ErrKeyNotFound, keyID)
+	}
+	return key, nil
+}
+
+// fetch fetches the key set and replaces the cached keys. c.mu must be held.
+func (c *Client) fetch() error {
+	c.attemptedAt = time.Now()
+
+	resp, err := c.httpClient.Get(c.config.URL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, c.config.URL)
+	}
+
+	var set Set
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]cachedKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		// Skip encryption keys of identity providers that publish them
+		// alongside their signing keys
+		if jwk.Use == "enc" {
+			continue
+		}
+
+		// Some identity providers omit the algorithm of their RSA keys
+		if jwk.Algorithm == "" && jwk.KeyType == "RSA" {
+			jwk.Algorithm = AlgorithmRS256
+		}
+
+		publicKey, err := jwk.PublicKey()
+		if err != nil {
+			// Skip keys this client cannot use, the others still verify tokens
+			logrus.WithError(err).Warn("Skipping JWKS key")
+			continue
+		}
+		keys[jwk.KeyID] = cachedKey{algorithm: jwk.Algorithm, publicKey: publicKey}
+	}
+
+	c.keys = keys
+	c.fetchedAt = time.Now()
+	return nil
+}
@@ -0,0 +1,166 @@
+package jwks
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testKey is a private signing key and its public JWK
+type testKey struct {
+	jwk     JWK
+	private crypto.Signer
+}
+
+func newRSAKey(t *testing.T, keyID string) testKey {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	jwk, err := NewJWK(keyID, AlgorithmRS256, private.Public())
+	require.NoError(t, err)
+	return testKey{jwk: jwk, private: private}
+}
+
+func newEd25519Key(t *testing.T, keyID string) testKey {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	jwk, err := NewJWK(keyID, AlgorithmEdDSA, private.Public())
+	require.NoError(t, err)
+	return testKey{jwk: jwk, private: private}
+}
+
+func (k testKey) sign(t *testing.T) string {
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(k.jwk.Algorithm), jwt.RegisteredClaims{
+		Subject:   "user",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	})
+	token.Header["kid"] = k.jwk.KeyID
+	signed, err := token.SignedString(k.private)
+	require.NoError(t, err)
+	return signed
+}
+
+// keyServer serves a key set that can be changed, and counts its fetches
+type keyServer struct {
+	mu      sync.Mutex
+	set     Set
+	fetches int
+}
+
+func (s *keyServer) setKeys(keys ...testKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.set = Set{Keys: []JWK{}}
+	for _, key := range keys {
+		s.set.Keys = append(s.set.Keys, key.jwk)
+	}
+}
+
+func (s *keyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fetches++
+	json.NewEncoder(w).Encode(s.set)
+}
+
+func newTestClient(t *testing.T, keys *keyServer) *Client {
+	server := httptest.NewServer(keys)
+	t.Cleanup(server.Close)
+	return NewClient(Config{URL: server.URL, MinRefreshInterval: time.Millisecond})
+}
+
+func parse(client *Client, token string) error {
+	_, err := jwt.Parse(token, client.Keyfunc, jwt.WithValidMethods(Algorithms))
+	return err
+}
+
+func TestClient_VerifiesRSAAndEd25519(t *testing.T) {
+	rsaKey := newRSAKey(t, "rsa-1")
+	edKey := newEd25519Key(t, "ed-1")
+	keys := &keyServer{}
+	keys.setKeys(rsaKey, edKey)
+	client := newTestClient(t, keys)
+
+	assert.NoError(t, parse(client, rsaKey.sign(t)))
+	assert.NoError(t, parse(client, edKey.sign(t)))
+	assert.Equal(t, 1, keys.fetches)
+}
+
+func TestClient_AcceptsRSAKeysWithoutAlgorithm(t *testing.T) {
+	rsaKey := newRSAKey(t, "rsa-1")
+	keys := &keyServer{}
+	keys.setKeys(rsaKey)
+	keys.set.Keys[0].Algorithm = ""
+	client := newTestClient(t, keys)
+
+	assert.NoError(t, parse(client, rsaKey.sign(t)))
+}
+
+func TestClient_PicksUpRotatedKeys(t *testing.T) {
+	oldKey := newEd25519Key(t, "key-1")
+	keys := &keyServer{}
+	keys.setKeys(oldKey)
+	client := newTestClient(t, keys)
+
+	// The user service rotates to a new key and keeps publishing the old one
+	newKey := newEd25519Key(t, "key-2")
+	keys.setKeys(newKey, oldKey)
+	time.Sleep(2 * time.Millisecond)
+
+	assert.NoError(t, parse(client, newKey.sign(t)))
+	assert.NoError(t, parse(client, oldKey.sign(t)))
+	assert.Equal(t, 2, keys.fetches)
+
+	// Once the old key is retired, tokens signed with it are rejected
+	keys.setKeys(newKey)
+	time.Sleep(2 * time.Millisecond)
+	client.mu.Lock()
+	client.fetchedAt = time.Time{}
+	client.mu.Unlock()
+
+	assert.ErrorIs(t, parse(client, oldKey.sign(t)), ErrKeyNotFound)
+}
+
+func TestClient_RejectsUnknownAndMismatchedKeys(t *testing.T) {
+	key := newRSAKey(t, "key-1")
+	keys := &keyServer{}
+	keys.setKeys(key)
+	server := httptest.NewServer(keys)
+	t.Cleanup(server.Close)
+	client := NewClient(Config{URL: server.URL, MinRefreshInterval: time.Hour})
+
+	// Unknown key IDs do not fetch the key set more often than allowed
+	forged := newRSAKey(t, "forged")
+	for i := 0; i < 3; i++ {
+		assert.ErrorIs(t, parse(client, forged.sign(t)), ErrKeyNotFound)
+	}
+	assert.Equal(t, 1, keys.fetches)
+
+	// A token must use the algorithm of its key
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{})
+	token.Header["kid"] = "key-1"
+	signed, err := token.SignedString([]byte("secret"))
+	require.NoError(t, err)
+	assert.Error(t, parse(client, signed))
+}
+
+func TestJWK_RoundTrip(t *testing.T) {
+	for _, key := range []testKey{newRSAKey(t, "rsa"), newEd25519Key(t, "ed")} {
+		publicKey, err := key.jwk.PublicKey()
+		require.NoError(t, err)
+		assert.True(t, key.private.Public().(interface{ Equal(crypto.PublicKey) bool }).Equal(publicKey))
+	}
+
+	_, err := NewJWK("rsa", AlgorithmEdDSA, newRSAKey(t, "rsa").private.Public())
+	assert.ErrorIs(t, err, ErrUnsupportedKey)
+}
@@ -0,0 +1,99 @@
+// Package jwks publishes the public keys that sign access tokens as a JSON
+// Web Key Set and verifies tokens against the key set of the user service.
+package jwks
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Signing algorithms of access tokens
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// Algorithms are the signing algorithms tokens are accepted with
+var Algorithms = []string{AlgorithmRS256, AlgorithmEdDSA}
+
+// ErrUnsupportedKey is returned for keys of an unsupported type or algorithm
+var ErrUnsupportedKey = errors.New("unsupported key")
+
+// JWK is a public JSON Web Key (RFC 7517) of an RSA or Ed25519 key
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+
+	// RSA keys
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// Ed25519 keys
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+// Set is a JSON Web Key Set, as served at /.well-known/jwks.json
+type Set struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewJWK returns the JWK of a public key that signs tokens with algorithm
+func NewJWK(keyID, algorithm string, publicKey crypto.PublicKey) (JWK, error) {
+	jwk := JWK{KeyID: keyID, Algorithm: algorithm, Use: "sig"}
+
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		if algorithm != AlgorithmRS256 {
+			return JWK{}, fmt.Errorf("%w: RSA key for %s", ErrUnsupportedKey, algorithm)
+		}
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+	case ed25519.PublicKey:
+		if algorithm != AlgorithmEdDSA {
+			return JWK{}, fmt.Errorf("%w: Ed25519 key for %s", ErrUnsupportedKey, algorithm)
+		}
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(key)
+	default:
+		return JWK{}, fmt.Errorf("%w: %T", ErrUnsupportedKey, publicKey)
+	}
+
+	return jwk, nil
+}
+
+// PublicKey decodes the public key of a JWK
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	switch {
+	case k.KeyType == "RSA" && k.Algorithm == AlgorithmRS256:
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus of key %s: %w", k.KeyID, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent of key %s: %w", k.KeyID, err)
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case k.KeyType == "OKP" && k.Curve == "Ed25519" && k.Algorithm == AlgorithmEdDSA:
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key %s", k.KeyID)
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("%w: %s key %s for %s", ErrUnsupportedKey, k.KeyType, k.KeyID, k.Algorithm)
+	}
+}
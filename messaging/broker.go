@@ -0,0 +1,111 @@
+// Package messaging publishes and consumes the messages exchanged between the
+// services. Messages that fail are retried with increasing delays and moved
+// to the dead-letter queue of their consumer after their last attempt.
+package messaging
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Errors returned by brokers
+var (
+	ErrClosed             = errors.New("broker is closed")
+	ErrDeadLetterNotFound = errors.New("dead letter not found")
+)
+
+// Message is a message published to or consumed from an exchange
+type Message struct {
+	ID             string
+	Exchange       string // exchange the message was first published to
+	RoutingKey     string // routing key the message was first published with
+	ContentType    string
+	Body           []byte
+	Timestamp      time.Time
+	Attempts       int    // number of times the message failed before
+	LastError      string // error of the last failed attempt
+	DeadLetteredAt *time.Time
+}
+
+// Handler handles a consumed message. A message whose handler returns an
+// error is retried later, or dead-lettered after its last attempt.
+type Handler func(msg Message) error
+
+// ConsumerOptions describe the queue a consumer reads and how many messages
+// it handles at once
+type ConsumerOptions struct {
+	Queue       string
+	Exchange    string
+	RoutingKeys []string
+
+	// Prefetch is the number of unacknowledged messages the broker sends the
+	// consumer ahead, and Concurrency the number of messages handled at the
+	// same time. Zero values use the defaults of the broker.
+	Prefetch    int
+	Concurrency int
+}
+
+// Broker publishes and consumes messages
+type Broker interface {
+	// DeclareExchange declares a durable topic exchange
+	DeclareExchange(name string) error
+
+	// Publish publishes a persistent message and returns once the broker
+	// has accepted it
+	Publish(ctx context.Context, exchange, routingKey string, msg Message) error
+
+	// Consume declares and binds the queue of a consumer, with its retry and
+	// dead-letter queues, and handles its messages until ctx is cancelled
+	Consume(ctx context.Context, opts ConsumerOptions, handler Handler) error
+
+	// DeadLetters returns up to limit messages of the dead-letter queue of a
+	// consumer queue, oldest first, without removing them
+	DeadLetters(queue string, limit int) ([]Message, error)
+
+	// ReplayDeadLetter publishes a dead letter to the exchange and routing key
+	// it was first published to, with a fresh attempt count, and removes it
+	// from the dead-letter queue. A non-nil body replaces its body.
+	ReplayDeadLetter(queue, messageID string, body []byte) error
+
+	// DiscardDeadLetter removes a dead letter without replaying it
+	DiscardDeadLetter(queue, messageID string) error
+
+	// Close stops consuming, waits until the messages being handled are
+	// settled or ctx is done, and disconnects
+	Close(ctx context.Context) error
+}
+
+// retryDelays are the delays before the retries of a failed message. A
+// message is dead-lettered when it fails once more after the last retry.
+var retryDelays = []time.Duration{
+	10 * time.Second,
+	30 * time.Second,
+	90 * time.Second,
+	270 * time.Second,
+}
+
+// MaxDeliveryAttempts is the number of times a message is handled before it
+// is dead-lettered
+var MaxDeliveryAttempts = len(retryDelays) + 1
+
+// RetryQueueName returns the name of the queue holding messages of queueName
+// waiting for their given retry
+func RetryQueueName(queueName string, retry int) string {
+	return fmt.Sprintf("%s.retry.%d", queueName, retry)
+}
+
+// DeadLetterQueueName returns the name of the dead-letter queue of queueName
+func DeadLetterQueueName(queueName string) string {
+	return queueName + ".dlq"
+}
+
+// bodyID identifies a message published without an ID by the hash of its
+// body, which is the same for every redelivery
+func bodyID(body []byte) string {
+	hash := sha256.Sum256(body)
+	return "sha256:" + hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,104 @@
+package messaging
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Config holds the connection and consumer settings of a RabbitMQ broker
+type Config struct {
+	URL string
+
+	// ConnectAttempts is the number of times the first connection is tried
+	// before NewRabbitMQ gives up. A lost connection is retried until the
+	// broker is closed.
+	ConnectAttempts int
+
+	// ReconnectDelay is the delay before the first reconnection attempt,
+	// doubled after every failed attempt up to MaxReconnectDelay
+	ReconnectDelay    time.Duration
+	MaxReconnectDelay time.Duration
+
+	// PublishTimeout bounds how long Publish waits for a confirm when its
+	// context has no deadline
+	PublishTimeout time.Duration
+
+	// Default prefetch and concurrency of consumers
+	Prefetch    int
+	Concurrency int
+}
+
+// ConfigFromEnv returns the configuration from the RABBITMQ_* environment
+// variables
+func ConfigFromEnv() Config {
+	host := getEnv("RABBITMQ_HOST", "localhost")
+	port := getEnv("RABBITMQ_PORT", "5672")
+	user := getEnv("RABBITMQ_USER", "guest")
+	password := getEnv("RABBITMQ_PASSWORD", "guest")
+	vhost := getEnv("RABBITMQ_VHOST", "/")
+
+	config := Config{
+		URL:         fmt.Sprintf("amqp://%s:%s@%s:%s%s", user, password, host, port, vhost),
+		Prefetch:    getEnvInt("RABBITMQ_PREFETCH", 0),
+		Concurrency: getEnvInt("RABBITMQ_CONCURRENCY", 0),
+	}
+	return config.withDefaults()
+}
+
+// withDefaults fills in the unset settings
+func (c Config) withDefaults() Config {
+	if c.ConnectAttempts <= 0 {
+		c.ConnectAttempts = 5
+	}
+	if c.ReconnectDelay <= 0 {
+		c.ReconnectDelay = time.Second
+	}
+	if c.MaxReconnectDelay <= 0 {
+		c.MaxReconnectDelay = 30 * time.Second
+	}
+	if c.PublishTimeout <= 0 {
+		c.PublishTimeout = 5 * time.Second
+	}
+	if c.Prefetch <= 0 {
+		c.Prefetch = 10
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = 1
+	}
+	return c
+}
+
+// withDefaults fills in the prefetch and concurrency of a consumer from the
+// broker configuration. The prefetch is at least the concurrency, so that
+// every worker has a message to handle.
+func (o ConsumerOptions) withDefaults(config Config) ConsumerOptions {
+	if o.Concurrency <= 0 {
+		o.Concurrency = config.Concurrency
+	}
+	if o.Prefetch <= 0 {
+		o.Prefetch = config.Prefetch
+	}
+	if o.Prefetch < o.Concurrency {
+		o.Prefetch = o.Concurrency
+	}
+	return o
+}
+
+// getEnv gets an environment variable or returns a default value
+func getEnv(key, defaultValue string) string {
+	if value, exists := os.LookupEnv(key); exists {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvInt gets an integer environment variable or returns a default value
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
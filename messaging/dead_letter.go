@@ -0,0 +1,244 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Headers describing the delivery history of a retried or dead-lettered message
+const (
+	AttemptsHeader           = "x-attempts"
+	LastErrorHeader          = "x-last-error"
+	OriginalExchangeHeader   = "x-original-exchange"
+	OriginalRoutingKeyHeader = "x-original-routing-key"
+	DeadLetteredAtHeader     = "x-dead-lettered-at"
+)
+
+// declareRetryQueues declares the retry queues and the dead-letter queue of a
+// consumer queue. Messages expire from a retry queue after its delay and are
+// routed back to the consumer queue through the default exchange.
+func declareRetryQueues(ch *amqp.Channel, queueName string) error {
+	for i, delay := range retryDelays {
+		retryQueue := RetryQueueName(queueName, i+1)
+		_, err := ch.QueueDeclare(
+			retryQueue, // name
+			true,       // durable
+			false,      // delete when unused
+			false,      // exclusive
+			false,      // no-wait
+			amqp.Table{
+				"x-message-ttl":             int64(delay / time.Millisecond),
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": queueName,
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to declare retry queue %s: %w", retryQueue, err)
+		}
+	}
+
+	deadLetterQueue := DeadLetterQueueName(queueName)
+	_, err := ch.QueueDeclare(
+		deadLetterQueue, // name
+		true,            // durable
+		false,           // delete when unused
+		false,           // exclusive
+		false,           // no-wait
+		nil,             // arguments
+	)
+	if err != nil {
+		return fmt.Errorf("failed to declare dead-letter queue %s: %w", deadLetterQueue, err)
+	}
+
+	return nil
+}
+
+// retryOrDeadLetter publishes a copy of a failed message to its next retry
+// queue, or to the dead-letter queue when it has used all its attempts. The
+// caller acks the original message once the copy is published.
+func (r *RabbitMQ) retryOrDeadLetter(queueName string, delivery amqp.Delivery, msg Message, cause error) error {
+	attempts := msg.Attempts + 1
+
+	headers := amqp.Table{}
+	for key, value := range delivery.Headers {
+		headers[key] = value
+	}
+	headers[AttemptsHeader] = int32(attempts)
+	headers[LastErrorHeader] = cause.Error()
+	headers[OriginalExchangeHeader] = msg.Exchange
+	headers[OriginalRoutingKeyHeader] = msg.RoutingKey
+
+	target := RetryQueueName(queueName, attempts)
+	if attempts >= MaxDeliveryAttempts {
+		target = DeadLetterQueueName(queueName)
+		headers[DeadLetteredAtHeader] = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	return r.publish(context.Background(), "", target, amqp.Publishing{
+		Headers:      headers,
+		ContentType:  delivery.ContentType,
+		Body:         delivery.Body,
+		DeliveryMode: amqp.Persistent,
+		Timestamp:    delivery.Timestamp,
+		MessageId:    msg.ID,
+	})
+}
+
+// DeadLetters returns up to limit messages of the dead-letter queue of
+// queueName without removing them from the queue
+func (r *RabbitMQ) DeadLetters(queueName string, limit int) ([]Message, error) {
+	var messages []Message
+	err := r.browseDeadLetters(queueName, func(delivery amqp.Delivery) (bool, error) {
+		messages = append(messages, fromDelivery(delivery))
+		return len(messages) >= limit, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// ReplayDeadLetter publishes a dead-lettered message with a fresh attempt
+// count directly to queueName through the default exchange, so that the other
+// queues bound to its original exchange do not receive it again, and removes
+// it from the dead-letter queue. The original exchange and routing key travel
+// in the headers. A non-nil body replaces the body of the message.
+func (r *RabbitMQ) ReplayDeadLetter(queueName, messageID string, body []byte) error {
+	return r.takeDeadLetter(queueName, messageID, func(msg Message) error {
+		if body == nil {
+			body = msg.Body
+		}
+
+		return r.publish(context.Background(), "", queueName, amqp.Publishing{
+			Headers: amqp.Table{
+				OriginalExchangeHeader:   msg.Exchange,
+				OriginalRoutingKeyHeader: msg.RoutingKey,
+			},
+			ContentType:  msg.ContentType,
+			Body:         body,
+			DeliveryMode: amqp.Persistent,
+			Timestamp:    msg.Timestamp,
+			MessageId:    messageID,
+		})
+	})
+}
+
+// DiscardDeadLetter removes a dead-lettered message without replaying it
+func (r *RabbitMQ) DiscardDeadLetter(queueName, messageID string) error {
+	return r.takeDeadLetter(queueName, messageID, func(msg Message) error {
+		return nil
+	})
+}
+
+// takeDeadLetter passes the dead-lettered message with the given ID to fn and
+// removes it from the queue when fn succeeds
+func (r *RabbitMQ) takeDeadLetter(queueName, messageID string, fn func(msg Message) error) error {
+	found := false
+	err := r.browseDeadLetters(queueName, func(delivery amqp.Delivery) (bool, error) {
+		msg := fromDelivery(delivery)
+		if msg.ID != messageID {
+			return false, nil
+		}
+
+		found = true
+		if err := fn(msg); err != nil {
+			return true, err
+		}
+		return true, delivery.Ack(false)
+	})
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		return ErrDeadLetterNotFound
+	}
+	return nil
+}
+
+// browseDeadLetters gets the messages of the dead-letter queue of queueName
+// one by one and passes them to visit until it returns true. Messages that
+// visit does not ack are returned to the queue.
+func (r *RabbitMQ) browseDeadLetters(queueName string, visit func(delivery amqp.Delivery) (bool, error)) error {
+	deadLetterQueue := DeadLetterQueueName(queueName)
+
+	conn, err := r.connection(context.Background())
+	if err != nil {
+		return err
+	}
+
+	// Use a separate channel so that unacked messages are requeued when it closes
+	channel, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("failed to open a channel: %w", err)
+	}
+	defer channel.Close()
+
+	queue, err := channel.QueueInspect(deadLetterQueue)
+	if err != nil {
+		return fmt.Errorf("failed to inspect dead-letter queue %s: %w", deadLetterQueue, err)
+	}
+
+	// Stop after one pass, since requeued messages would be seen again
+	for i := 0; i < queue.Messages; i++ {
+		delivery, ok, err := channel.Get(deadLetterQueue, false)
+		if err != nil {
+			return fmt.Errorf("failed to get message from %s: %w", deadLetterQueue, err)
+		}
+		if !ok {
+			return nil
+		}
+
+		done, err := visit(delivery)
+		if err != nil || done {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fromDelivery returns the message of a delivery with its delivery history.
+// Retried messages come back with the default exchange and the name of their
+// queue, so the original exchange and routing key are taken from the headers.
+func fromDelivery(delivery amqp.Delivery) Message {
+	msg := Message{
+		ID:          delivery.MessageId,
+		Exchange:    delivery.Exchange,
+		RoutingKey:  delivery.RoutingKey,
+		ContentType: delivery.ContentType,
+		Body:        delivery.Body,
+		Timestamp:   delivery.Timestamp,
+	}
+	if msg.ID == "" {
+		msg.ID = bodyID(delivery.Body)
+	}
+
+	switch attempts := delivery.Headers[AttemptsHeader].(type) {
+	case int32:
+		msg.Attempts = int(attempts)
+	case int64:
+		msg.Attempts = int(attempts)
+	case int:
+		msg.Attempts = attempts
+	}
+	if lastError, ok := delivery.Headers[LastErrorHeader].(string); ok {
+		msg.LastError = lastError
+	}
+	if exchange, ok := delivery.Headers[OriginalExchangeHeader].(string); ok {
+		msg.Exchange = exchange
+	}
+	if routingKey, ok := delivery.Headers[OriginalRoutingKeyHeader].(string); ok {
+		msg.RoutingKey = routingKey
+	}
+	if value, ok := delivery.Headers[DeadLetteredAtHeader].(string); ok {
+		if deadLetteredAt, err := time.Parse(time.RFC3339, value); err == nil {
+			msg.DeadLetteredAt = &deadLetteredAt
+		}
+	}
+
+	return msg
+}
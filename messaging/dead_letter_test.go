@@ -0,0 +1,68 @@
+package messaging
+
+import (
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromDelivery_RestoresOriginalRoute(t *testing.T) {
+	// A retried message comes back from the default exchange with the name
+	// of its queue as routing key
+	delivery := amqp.Delivery{
+		MessageId:   "message-1",
+		Exchange:    "",
+		RoutingKey:  "notification_payment_events",
+		ContentType: "application/json",
+		Body:        []byte(`{}`),
+		Headers: amqp.Table{
+			AttemptsHeader:           int32(MaxDeliveryAttempts),
+			LastErrorHeader:          "template not found",
+			OriginalExchangeHeader:   "payment_events",
+			OriginalRoutingKeyHeader: "payment.completed",
+			DeadLetteredAtHeader:     "2026-10-01T09:00:00Z",
+		},
+	}
+
+	msg := fromDelivery(delivery)
+	assert.Equal(t, "message-1", msg.ID)
+	assert.Equal(t, "payment_events", msg.Exchange)
+	assert.Equal(t, "payment.completed", msg.RoutingKey)
+	assert.Equal(t, MaxDeliveryAttempts, msg.Attempts)
+	assert.Equal(t, "template not found", msg.LastError)
+	require.NotNil(t, msg.DeadLetteredAt)
+	assert.True(t, msg.DeadLetteredAt.Equal(time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)))
+}
+
+func TestFromDelivery_FirstDelivery(t *testing.T) {
+	msg := fromDelivery(amqp.Delivery{
+		Exchange:   "payment_events",
+		RoutingKey: "payment.completed",
+		Body:       []byte(`{"id":"1"}`),
+	})
+
+	// Messages without an ID are identified by their body
+	assert.Equal(t, bodyID([]byte(`{"id":"1"}`)), msg.ID)
+	assert.Equal(t, "payment_events", msg.Exchange)
+	assert.Equal(t, "payment.completed", msg.RoutingKey)
+	assert.Zero(t, msg.Attempts)
+	assert.Nil(t, msg.DeadLetteredAt)
+
+	// Headers written by other clients may use other integer types
+	msg = fromDelivery(amqp.Delivery{Headers: amqp.Table{AttemptsHeader: int64(2)}})
+	assert.Equal(t, 2, msg.Attempts)
+}
+
+func TestRetryQueueNames(t *testing.T) {
+	assert.Equal(t, "notification_payment_events.retry.1", RetryQueueName("notification_payment_events", 1))
+	assert.Equal(t, "notification_payment_events.dlq", DeadLetterQueueName("notification_payment_events"))
+
+	// Every retry before the last attempt has a queue with a growing delay
+	assert.Equal(t, len(retryDelays)+1, MaxDeliveryAttempts)
+	for i := 1; i < len(retryDelays); i++ {
+		assert.Greater(t, retryDelays[i], retryDelays[i-1])
+	}
+}
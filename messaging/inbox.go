@@ -0,0 +1,143 @@
+package messaging
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// inboxRetention is how long processed message IDs are kept. Redeliveries
+// arrive within minutes, so a week leaves a wide margin.
+const inboxRetention = 7 * 24 * time.Hour
+
+// inboxDuplicatesTotal tracks the number of redelivered messages that were skipped
+var inboxDuplicatesTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "inbox_duplicate_messages_total",
+		Help: "Total number of redelivered messages skipped by consumers",
+	},
+	[]string{"consumer"},
+)
+
+// InboxMessage records a message processed by a consumer so that
+// redeliveries of the message are skipped
+type InboxMessage struct {
+	Consumer    string    `gorm:"size:255;primary_key" json:"consumer"` // queue the message was consumed from
+	MessageID   string    `gorm:"size:255;primary_key" json:"message_id"`
+	ProcessedAt time.Time `gorm:"not null;index" json:"processed_at"`
+}
+
+// InboxRepository defines the interface for inbox repository operations
+type InboxRepository interface {
+	Process(consumer, messageID string, handler func(tx *gorm.DB) error) (bool, error)
+	DeleteProcessedBefore(before time.Time) (int64, error)
+}
+
+// inboxRepository implements InboxRepository interface
+type inboxRepository struct {
+	db *gorm.DB
+}
+
+// NewInboxRepository creates an inbox repository on the database of a
+// service
+func NewInboxRepository(db *gorm.DB) InboxRepository {
+	// Auto migrate the model
+	db.AutoMigrate(&InboxMessage{})
+
+	return &inboxRepository{
+		db: db,
+	}
+}
+
+// Process records the message in the inbox and runs handler in the same
+// transaction. It returns false without running handler when the consumer
+// has already processed the message. A concurrent delivery of the same
+// message waits for the first one to commit or roll back.
+func (r *inboxRepository) Process(consumer, messageID string, handler func(tx *gorm.DB) error) (bool, error) {
+	processed := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&InboxMessage{
+			Consumer:    consumer,
+			MessageID:   messageID,
+			ProcessedAt: time.Now(),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		// Message already processed
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		processed = true
+		return handler(tx)
+	})
+	if err != nil {
+		return false, err
+	}
+	return processed, nil
+}
+
+// DeleteProcessedBefore deletes messages processed before the given time
+func (r *inboxRepository) DeleteProcessedBefore(before time.Time) (int64, error) {
+	result := r.db.Where("processed_at < ?", before).Delete(&InboxMessage{})
+	return result.RowsAffected, result.Error
+}
+
+// Inbox deduplicates the messages consumed by the consumers of a service
+type Inbox struct {
+	inboxRepo InboxRepository
+}
+
+// NewInbox creates an inbox that records processed messages in inboxRepo
+func NewInbox(inboxRepo InboxRepository) *Inbox {
+	return &Inbox{
+		inboxRepo: inboxRepo,
+	}
+}
+
+// Handle runs handler in a transaction that records the message as processed
+// by consumer, and skips messages the consumer has already processed. When
+// handler fails nothing is recorded, so the message is processed again on
+// redelivery.
+func (i *Inbox) Handle(consumer, messageID string, handler func(tx *gorm.DB) error) error {
+	processed, err := i.inboxRepo.Process(consumer, messageID, handler)
+	if err != nil {
+		return err
+	}
+
+	if !processed {
+		inboxDuplicatesTotal.WithLabelValues(consumer).Inc()
+		logrus.Infof("Skipping message %s already processed by %s", messageID, consumer)
+	}
+	return nil
+}
+
+// StartCleanup periodically deletes processed message IDs older than the
+// retention until ctx is cancelled
+func (i *Inbox) StartCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := i.inboxRepo.DeleteProcessedBefore(time.Now().Add(-inboxRetention))
+			if err != nil {
+				logrus.WithError(err).Error("Failed to delete processed inbox messages")
+				continue
+			}
+			if deleted > 0 {
+				logrus.Infof("Deleted %d processed inbox messages", deleted)
+			}
+		}
+	}
+}
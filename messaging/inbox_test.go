@@ -0,0 +1,96 @@
+package messaging
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/ticket-system/contracts"
+	"gorm.io/gorm"
+)
+
+// setupInbox opens an in-memory database with the inbox and outbox tables
+func setupInbox(t *testing.T) (*Inbox, InboxRepository, OutboxRepository, *gorm.DB) {
+	outboxRepo, db := setupOutboxRepository(t)
+	inboxRepo := NewInboxRepository(db)
+	return NewInbox(inboxRepo), inboxRepo, outboxRepo, db
+}
+
+// countRows counts the rows of a model
+func countRows(t *testing.T, db *gorm.DB, value interface{}) int64 {
+	var count int64
+	require.NoError(t, db.Model(value).Count(&count).Error)
+	return count
+}
+
+// enqueueIn writes an event to the outbox in tx as the side effect of a
+// handled message
+func enqueueIn(outboxRepo OutboxRepository) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		event := contracts.UserCreated{UserState: contracts.UserState{UserID: uuid.New()}}
+		return EnqueueEvent(outboxRepo, tx, "user_events", "", event)
+	}
+}
+
+func TestInbox_SkipsRedeliveredMessages(t *testing.T) {
+	inbox, _, outboxRepo, db := setupInbox(t)
+
+	calls := 0
+	handler := func(tx *gorm.DB) error {
+		calls++
+		return enqueueIn(outboxRepo)(tx)
+	}
+
+	require.NoError(t, inbox.Handle("event_ticket_payment_events", "message-1", handler))
+	require.NoError(t, inbox.Handle("event_ticket_payment_events", "message-1", handler))
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, int64(1), countRows(t, db, &OutboxMessage{}))
+
+	// Every consumer processes the message once
+	require.NoError(t, inbox.Handle("event_ticket_user_events", "message-1", handler))
+	require.NoError(t, inbox.Handle("event_ticket_payment_events", "message-2", handler))
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, int64(3), countRows(t, db, &InboxMessage{}))
+}
+
+func TestInbox_FailedMessageIsProcessedAgain(t *testing.T) {
+	inbox, _, outboxRepo, db := setupInbox(t)
+
+	// The side effect and the inbox record are rolled back together
+	err := inbox.Handle("event_ticket_payment_events", "message-1", func(tx *gorm.DB) error {
+		if err := enqueueIn(outboxRepo)(tx); err != nil {
+			return err
+		}
+		return errors.New("booking not found")
+	})
+	assert.EqualError(t, err, "booking not found")
+	assert.Equal(t, int64(0), countRows(t, db, &OutboxMessage{}))
+	assert.Equal(t, int64(0), countRows(t, db, &InboxMessage{}))
+
+	require.NoError(t, inbox.Handle("event_ticket_payment_events", "message-1", enqueueIn(outboxRepo)))
+	assert.Equal(t, int64(1), countRows(t, db, &OutboxMessage{}))
+}
+
+func TestInboxRepository_DeleteProcessedBefore(t *testing.T) {
+	_, inboxRepo, _, db := setupInbox(t)
+
+	noop := func(tx *gorm.DB) error { return nil }
+	_, err := inboxRepo.Process("event_ticket_payment_events", "message-1", noop)
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&InboxMessage{}).Where("message_id = ?", "message-1").
+		Update("processed_at", time.Now().Add(-8*24*time.Hour)).Error)
+	_, err = inboxRepo.Process("event_ticket_payment_events", "message-2", noop)
+	require.NoError(t, err)
+
+	deleted, err := inboxRepo.DeleteProcessedBefore(time.Now().Add(-inboxRetention))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	// Only a message redelivered after the retention is processed again
+	processed, err := inboxRepo.Process("event_ticket_payment_events", "message-2", noop)
+	require.NoError(t, err)
+	assert.False(t, processed)
+}
@@ -0,0 +1,280 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MemoryBroker is an in-memory Broker for tests. Published messages are
+// delivered synchronously to the consumers of the queues bound to their
+// exchange, failed messages are retried immediately and dead-lettered after
+// their last attempt.
+type MemoryBroker struct {
+	mu        sync.Mutex
+	exchanges map[string]bool
+	queues    map[string]*memoryQueue
+	published []Message
+	closed    bool
+}
+
+// memoryQueue is a queue of the in-memory broker
+type memoryQueue struct {
+	exchange    string
+	routingKeys []string
+	handler     Handler
+	consumer    int       // incremented by every Consume of the queue
+	pending     []Message // messages published before the queue is consumed
+	deadLetters []Message
+}
+
+// NewMemoryBroker creates an in-memory broker
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		exchanges: make(map[string]bool),
+		queues:    make(map[string]*memoryQueue),
+	}
+}
+
+// DeclareExchange declares an exchange
+func (b *MemoryBroker) DeclareExchange(name string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return ErrClosed
+	}
+	b.exchanges[name] = true
+	return nil
+}
+
+// Publish records a message and delivers it to the queues bound to the
+// exchange with a matching routing key
+func (b *MemoryBroker) Publish(ctx context.Context, exchange, routingKey string, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrClosed
+	}
+	if !b.exchanges[exchange] {
+		b.mu.Unlock()
+		return fmt.Errorf("exchange %s is not declared", exchange)
+	}
+
+	msg.Exchange = exchange
+	msg.RoutingKey = routingKey
+	if msg.ID == "" {
+		msg.ID = bodyID(msg.Body)
+	}
+	if msg.ContentType == "" {
+		msg.ContentType = "application/json"
+	}
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = time.Now()
+	}
+	b.published = append(b.published, msg)
+
+	var targets []string
+	for name, queue := range b.queues {
+		if queue.bound(exchange, routingKey) {
+			targets = append(targets, name)
+		}
+	}
+	b.mu.Unlock()
+
+	for _, name := range targets {
+		b.deliver(name, msg)
+	}
+	return nil
+}
+
+// bound reports whether the queue receives messages published to exchange
+// with routingKey
+func (q *memoryQueue) bound(exchange, routingKey string) bool {
+	if q.exchange != exchange {
+		return false
+	}
+	for _, pattern := range q.routingKeys {
+		if matchRoutingKey(pattern, routingKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// deliver passes a message to the consumer of a queue until it succeeds or
+// has used all its attempts, or keeps it until the queue is consumed
+func (b *MemoryBroker) deliver(queueName string, msg Message) {
+	b.mu.Lock()
+	queue := b.queues[queueName]
+	handler := queue.handler
+	if handler == nil {
+		queue.pending = append(queue.pending, msg)
+		b.mu.Unlock()
+		return
+	}
+	b.mu.Unlock()
+
+	for {
+		err := handler(msg)
+		if err == nil {
+			return
+		}
+
+		msg.Attempts++
+		msg.LastError = err.Error()
+		if msg.Attempts >= MaxDeliveryAttempts {
+			deadLetteredAt := time.Now().UTC()
+			msg.DeadLetteredAt = &deadLetteredAt
+
+			b.mu.Lock()
+			queue.deadLetters = append(queue.deadLetters, msg)
+			b.mu.Unlock()
+			return
+		}
+	}
+}
+
+// Consume binds a queue and passes its messages to handler, starting with the
+// messages published before, until ctx is cancelled. Prefetch and concurrency
+// are ignored since messages are delivered synchronously.
+func (b *MemoryBroker) Consume(ctx context.Context, opts ConsumerOptions, handler Handler) error {
+	if opts.Queue == "" {
+		return fmt.Errorf("queue is required")
+	}
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrClosed
+	}
+
+	queue := b.queue(opts.Queue)
+	queue.exchange = opts.Exchange
+	queue.routingKeys = opts.RoutingKeys
+	queue.handler = handler
+	queue.consumer++
+	consumer := queue.consumer
+	pending := queue.pending
+	queue.pending = nil
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		if queue.consumer == consumer {
+			queue.handler = nil
+		}
+		b.mu.Unlock()
+	}()
+
+	for _, msg := range pending {
+		b.deliver(opts.Queue, msg)
+	}
+	return nil
+}
+
+// queue returns the queue with the given name, creating it if needed. The
+// caller holds mu.
+func (b *MemoryBroker) queue(name string) *memoryQueue {
+	queue, ok := b.queues[name]
+	if !ok {
+		queue = &memoryQueue{}
+		b.queues[name] = queue
+	}
+	return queue
+}
+
+// Published returns the messages published so far, in order
+func (b *MemoryBroker) Published() []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]Message(nil), b.published...)
+}
+
+// DeadLetters returns up to limit messages of the dead-letter queue of
+// queueName without removing them
+func (b *MemoryBroker) DeadLetters(queueName string, limit int) ([]Message, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	queue, ok := b.queues[queueName]
+	if !ok {
+		return nil, nil
+	}
+
+	messages := queue.deadLetters
+	if len(messages) > limit {
+		messages = messages[:limit]
+	}
+	return append([]Message(nil), messages...), nil
+}
+
+// ReplayDeadLetter delivers a dead-lettered message again to its queue only,
+// keeping the exchange and routing key it was originally published to, and
+// removes it from the dead-letter queue. A non-nil body replaces the body of
+// the message.
+func (b *MemoryBroker) ReplayDeadLetter(queueName, messageID string, body []byte) error {
+	msg, err := b.takeDeadLetter(queueName, messageID)
+	if err != nil {
+		return err
+	}
+
+	if body != nil {
+		msg.Body = body
+	}
+	msg.Attempts = 0
+	msg.LastError = ""
+	msg.DeadLetteredAt = nil
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrClosed
+	}
+	b.published = append(b.published, msg)
+	b.mu.Unlock()
+
+	b.deliver(queueName, msg)
+	return nil
+}
+
+// DiscardDeadLetter removes a dead-lettered message without replaying it
+func (b *MemoryBroker) DiscardDeadLetter(queueName, messageID string) error {
+	_, err := b.takeDeadLetter(queueName, messageID)
+	return err
+}
+
+// takeDeadLetter removes the dead-lettered message with the given ID
+func (b *MemoryBroker) takeDeadLetter(queueName, messageID string) (Message, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if queue, ok := b.queues[queueName]; ok {
+		for i, msg := range queue.deadLetters {
+			if msg.ID == messageID {
+				queue.deadLetters = append(queue.deadLetters[:i], queue.deadLetters[i+1:]...)
+				return msg, nil
+			}
+		}
+	}
+	return Message{}, ErrDeadLetterNotFound
+}
+
+// Close stops delivering messages
+func (b *MemoryBroker) Close(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for _, queue := range b.queues {
+		queue.handler = nil
+	}
+	return nil
+}
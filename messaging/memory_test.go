@@ -0,0 +1,195 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ Broker = (*RabbitMQ)(nil)
+	_ Broker = (*MemoryBroker)(nil)
+)
+
+func newTestBroker(t *testing.T) *MemoryBroker {
+	broker := NewMemoryBroker()
+	require.NoError(t, broker.DeclareExchange("ticket_events"))
+	t.Cleanup(func() { broker.Close(context.Background()) })
+	return broker
+}
+
+func TestMemoryBroker_DeliversToBoundQueues(t *testing.T) {
+	broker := newTestBroker(t)
+
+	var bookings, payments []Message
+	require.NoError(t, broker.Consume(context.Background(), ConsumerOptions{
+		Queue:       "booking_queue",
+		Exchange:    "ticket_events",
+		RoutingKeys: []string{"booking.#"},
+	}, func(msg Message) error {
+		bookings = append(bookings, msg)
+		return nil
+	}))
+	require.NoError(t, broker.Consume(context.Background(), ConsumerOptions{
+		Queue:       "payment_queue",
+		Exchange:    "ticket_events",
+		RoutingKeys: []string{"booking.payment_requested"},
+	}, func(msg Message) error {
+		payments = append(payments, msg)
+		return nil
+	}))
+
+	require.NoError(t, broker.Publish(context.Background(), "ticket_events", "booking.created", Message{ID: "1", Body: []byte(`{}`)}))
+	require.NoError(t, broker.Publish(context.Background(), "ticket_events", "booking.payment_requested", Message{ID: "2", Body: []byte(`{}`)}))
+
+	require.Len(t, bookings, 2)
+	assert.Equal(t, "booking.created", bookings[0].RoutingKey)
+	require.Len(t, payments, 1)
+	assert.Equal(t, "2", payments[0].ID)
+	assert.Equal(t, "ticket_events", payments[0].Exchange)
+	assert.Len(t, broker.Published(), 2)
+}
+
+func TestMemoryBroker_PublishToUndeclaredExchange(t *testing.T) {
+	broker := newTestBroker(t)
+
+	err := broker.Publish(context.Background(), "payment_events", "payment.completed", Message{Body: []byte(`{}`)})
+	assert.Error(t, err)
+	assert.Empty(t, broker.Published())
+}
+
+func TestMemoryBroker_KeepsMessagesUntilConsumed(t *testing.T) {
+	broker := newTestBroker(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var received []Message
+	require.NoError(t, broker.Consume(ctx, ConsumerOptions{
+		Queue:       "booking_queue",
+		Exchange:    "ticket_events",
+		RoutingKeys: []string{"booking.*"},
+	}, func(msg Message) error {
+		received = append(received, msg)
+		return nil
+	}))
+
+	// Stop consuming, messages published meanwhile wait in the queue
+	cancel()
+	assert.Eventually(t, func() bool {
+		broker.mu.Lock()
+		defer broker.mu.Unlock()
+		return broker.queues["booking_queue"].handler == nil
+	}, time.Second, time.Millisecond)
+
+	require.NoError(t, broker.Publish(context.Background(), "ticket_events", "booking.created", Message{Body: []byte(`{"a":1}`)}))
+	assert.Empty(t, received)
+
+	require.NoError(t, broker.Consume(context.Background(), ConsumerOptions{
+		Queue:       "booking_queue",
+		Exchange:    "ticket_events",
+		RoutingKeys: []string{"booking.*"},
+	}, func(msg Message) error {
+		received = append(received, msg)
+		return nil
+	}))
+	require.Len(t, received, 1)
+	assert.Equal(t, bodyID([]byte(`{"a":1}`)), received[0].ID)
+}
+
+func TestMemoryBroker_RetriesThenDeadLetters(t *testing.T) {
+	broker := newTestBroker(t)
+
+	calls := 0
+	failing := true
+	require.NoError(t, broker.Consume(context.Background(), ConsumerOptions{
+		Queue:       "booking_queue",
+		Exchange:    "ticket_events",
+		RoutingKeys: []string{"#"},
+	}, func(msg Message) error {
+		calls++
+		if failing {
+			return errors.New("boom")
+		}
+		return nil
+	}))
+
+	// Another queue bound to the exchange handles the message at once
+	otherCalls := 0
+	require.NoError(t, broker.Consume(context.Background(), ConsumerOptions{
+		Queue:       "audit_queue",
+		Exchange:    "ticket_events",
+		RoutingKeys: []string{"#"},
+	}, func(msg Message) error {
+		otherCalls++
+		return nil
+	}))
+
+	require.NoError(t, broker.Publish(context.Background(), "ticket_events", "booking.created", Message{ID: "1", Body: []byte(`{}`)}))
+	assert.Equal(t, MaxDeliveryAttempts, calls)
+
+	deadLetters, err := broker.DeadLetters("booking_queue", 10)
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 1)
+	assert.Equal(t, MaxDeliveryAttempts, deadLetters[0].Attempts)
+	assert.Equal(t, "boom", deadLetters[0].LastError)
+	assert.NotNil(t, deadLetters[0].DeadLetteredAt)
+
+	// Replaying delivers the message again with a fresh attempt count, to
+	// the queue that dead-lettered it only
+	failing = false
+	require.NoError(t, broker.ReplayDeadLetter("booking_queue", "1", []byte(`{"fixed":true}`)))
+	assert.Equal(t, MaxDeliveryAttempts+1, calls)
+	assert.Equal(t, 1, otherCalls)
+
+	deadLetters, err = broker.DeadLetters("booking_queue", 10)
+	require.NoError(t, err)
+	assert.Empty(t, deadLetters)
+
+	published := broker.Published()
+	require.Len(t, published, 2)
+	assert.Equal(t, "booking.created", published[1].RoutingKey)
+	assert.JSONEq(t, `{"fixed":true}`, string(published[1].Body))
+}
+
+func TestMemoryBroker_DiscardDeadLetter(t *testing.T) {
+	broker := newTestBroker(t)
+
+	require.NoError(t, broker.Consume(context.Background(), ConsumerOptions{
+		Queue:       "booking_queue",
+		Exchange:    "ticket_events",
+		RoutingKeys: []string{"#"},
+	}, func(msg Message) error {
+		return errors.New("boom")
+	}))
+	require.NoError(t, broker.Publish(context.Background(), "ticket_events", "booking.created", Message{ID: "1", Body: []byte(`{}`)}))
+
+	require.NoError(t, broker.DiscardDeadLetter("booking_queue", "1"))
+	assert.ErrorIs(t, broker.DiscardDeadLetter("booking_queue", "1"), ErrDeadLetterNotFound)
+
+	deadLetters, err := broker.DeadLetters("booking_queue", 10)
+	require.NoError(t, err)
+	assert.Empty(t, deadLetters)
+}
+
+func TestMemoryBroker_Closed(t *testing.T) {
+	broker := newTestBroker(t)
+	require.NoError(t, broker.Close(context.Background()))
+
+	err := broker.Publish(context.Background(), "ticket_events", "booking.created", Message{Body: []byte(`{}`)})
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestConsumerOptions_WithDefaults(t *testing.T) {
+	config := Config{}.withDefaults()
+
+	opts := ConsumerOptions{Concurrency: 20}.withDefaults(config)
+	assert.Equal(t, 20, opts.Concurrency)
+	assert.Equal(t, 20, opts.Prefetch, "prefetch must cover every worker")
+
+	opts = ConsumerOptions{}.withDefaults(config)
+	assert.Equal(t, config.Concurrency, opts.Concurrency)
+	assert.Equal(t, config.Prefetch, opts.Prefetch)
+}
@@ -0,0 +1,179 @@
+package messaging
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/ticket-system/contracts"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Outbox message statuses
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+)
+
+// OutboxMessage is an event written in the transaction of the state change
+// it describes and published to RabbitMQ afterwards by the outbox relay
+type OutboxMessage struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	Exchange      string     `gorm:"size:255;not null" json:"exchange"`
+	RoutingKey    string     `gorm:"size:255;not null" json:"routing_key"`
+	Payload       []byte     `gorm:"not null" json:"payload"`
+	Status        string     `gorm:"size:50;not null;index:idx_outbox_due" json:"status"` // pending, sent
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	LastError     string     `gorm:"type:text" json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `gorm:"not null;index:idx_outbox_due" json:"next_attempt_at"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (m *OutboxMessage) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+	}
+	return nil
+}
+
+// OutboxRepository defines the interface for outbox repository operations
+type OutboxRepository interface {
+	Enqueue(tx *gorm.DB, message *OutboxMessage) error
+	ClaimDue(limit int, lease time.Duration) ([]OutboxMessage, error)
+	MarkSent(message *OutboxMessage) error
+	MarkFailed(message *OutboxMessage, reason string, nextAttemptAt time.Time) error
+	Stats() (int64, *time.Time, error)
+	DeleteSentBefore(before time.Time) (int64, error)
+}
+
+// outboxRepository implements OutboxRepository interface
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+// NewOutboxRepository creates an outbox repository on the database of a
+// service
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	// Auto migrate the model
+	db.AutoMigrate(&OutboxMessage{})
+
+	return &outboxRepository{
+		db: db,
+	}
+}
+
+// Enqueue writes a message to the outbox within tx, or on its own when tx is nil
+func (r *outboxRepository) Enqueue(tx *gorm.DB, message *OutboxMessage) error {
+	if tx == nil {
+		tx = r.db
+	}
+	return tx.Create(message).Error
+}
+
+// EnqueueEvent writes an event to the outbox in tx, the transaction of the
+// state change it describes, from where the outbox relay publishes it. The
+// event is wrapped in the envelope of the event contracts, which shares its
+// ID with the outbox message.
+func EnqueueEvent(outboxRepo OutboxRepository, tx *gorm.DB, exchange, correlationID string, event contracts.Event) error {
+	message := &OutboxMessage{
+		ID:            uuid.New(),
+		Exchange:      exchange,
+		RoutingKey:    event.EventType(),
+		Status:        OutboxPending,
+		NextAttemptAt: time.Now(),
+	}
+
+	payload, err := contracts.Marshal(message.ID.String(), correlationID, event)
+	if err != nil {
+		return err
+	}
+	message.Payload = payload
+
+	if err := outboxRepo.Enqueue(tx, message); err != nil {
+		return fmt.Errorf("failed to write %s event to outbox: %w", message.RoutingKey, err)
+	}
+	return nil
+}
+
+// ClaimDue claims pending messages that are due by pushing their next attempt
+// lease into the future, so that relays of other instances skip them
+func (r *outboxRepository) ClaimDue(limit int, lease time.Duration) ([]OutboxMessage, error) {
+	var messages []OutboxMessage
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", OutboxPending, time.Now()).
+			Order("created_at ASC").
+			Limit(limit).
+			Find(&messages).Error; err != nil {
+			return err
+		}
+
+		if len(messages) == 0 {
+			return nil
+		}
+
+		ids := make([]interface{}, len(messages))
+		for i := range messages {
+			ids[i] = messages[i].ID
+		}
+		return tx.Model(&OutboxMessage{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", time.Now().Add(lease)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// MarkSent marks a message as published
+func (r *outboxRepository) MarkSent(message *OutboxMessage) error {
+	now := time.Now()
+	message.Attempts++
+	message.Status = OutboxSent
+	message.SentAt = &now
+	return r.db.Model(message).Updates(map[string]interface{}{
+		"status":   message.Status,
+		"sent_at":  message.SentAt,
+		"attempts": message.Attempts,
+	}).Error
+}
+
+// MarkFailed records a failed publish and when to try again
+func (r *outboxRepository) MarkFailed(message *OutboxMessage, reason string, nextAttemptAt time.Time) error {
+	message.Attempts++
+	message.LastError = reason
+	message.NextAttemptAt = nextAttemptAt
+	return r.db.Model(message).Updates(map[string]interface{}{
+		"attempts":        message.Attempts,
+		"last_error":      message.LastError,
+		"next_attempt_at": message.NextAttemptAt,
+	}).Error
+}
+
+// Stats returns the number of pending messages and when the oldest was written
+func (r *outboxRepository) Stats() (int64, *time.Time, error) {
+	var pending int64
+	if err := r.db.Model(&OutboxMessage{}).Where("status = ?", OutboxPending).Count(&pending).Error; err != nil {
+		return 0, nil, err
+	}
+
+	if pending == 0 {
+		return 0, nil, nil
+	}
+
+	var oldest OutboxMessage
+	if err := r.db.Where("status = ?", OutboxPending).Order("created_at ASC").First(&oldest).Error; err != nil {
+		return 0, nil, err
+	}
+	return pending, &oldest.CreatedAt, nil
+}
+
+// DeleteSentBefore deletes messages published before the given time
+func (r *outboxRepository) DeleteSentBefore(before time.Time) (int64, error) {
+	result := r.db.Where("status = ? AND sent_at < ?", OutboxSent, before).Delete(&OutboxMessage{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,169 @@
+package messaging
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+)
+
+// Outbox relay settings
+const (
+	outboxBatchSize      = 100
+	outboxLease          = 30 * time.Second
+	outboxConfirmTimeout = 5 * time.Second
+	outboxMaxBackoff     = 5 * time.Minute
+	outboxRetention      = 7 * 24 * time.Hour
+)
+
+// Outbox metrics
+var (
+	// outboxPendingMessages tracks the number of messages waiting to be published
+	outboxPendingMessages = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "outbox_pending_messages",
+			Help: "Number of outbox messages waiting to be published",
+		},
+	)
+
+	// outboxOldestPendingAge tracks how long the oldest pending message has been waiting
+	outboxOldestPendingAge = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "outbox_oldest_pending_age_seconds",
+			Help: "Age of the oldest outbox message waiting to be published in seconds",
+		},
+	)
+
+	// outboxPublishedTotal tracks the number of published messages
+	outboxPublishedTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "outbox_published_total",
+			Help: "Total number of outbox messages published",
+		},
+		[]string{"routing_key"},
+	)
+
+	// outboxPublishFailuresTotal tracks the number of failed publish attempts
+	outboxPublishFailuresTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "outbox_publish_failures_total",
+			Help: "Total number of failed outbox publish attempts",
+		},
+		[]string{"routing_key"},
+	)
+)
+
+// OutboxRelay publishes the messages written to the outbox of a service
+type OutboxRelay struct {
+	outboxRepo OutboxRepository
+	broker     Broker
+}
+
+// NewOutboxRelay creates a relay publishing the messages of outboxRepo to broker
+func NewOutboxRelay(outboxRepo OutboxRepository, broker Broker) *OutboxRelay {
+	return &OutboxRelay{
+		outboxRepo: outboxRepo,
+		broker:     broker,
+	}
+}
+
+// Start periodically publishes due outbox messages until ctx is cancelled
+func (r *OutboxRelay) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	cleanupTicker := time.NewTicker(time.Hour)
+	defer cleanupTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.RelayDueMessages(ctx)
+			r.updateMetrics()
+		case <-cleanupTicker.C:
+			if _, err := r.outboxRepo.DeleteSentBefore(time.Now().Add(-outboxRetention)); err != nil {
+				logrus.WithError(err).Error("Failed to delete published outbox messages")
+			}
+		}
+	}
+}
+
+// RelayDueMessages publishes due messages in batches until none are left
+func (r *OutboxRelay) RelayDueMessages(ctx context.Context) {
+	for ctx.Err() == nil {
+		messages, err := r.outboxRepo.ClaimDue(outboxBatchSize, outboxLease)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to claim outbox messages")
+			return
+		}
+
+		for i := range messages {
+			r.relayMessage(ctx, &messages[i])
+		}
+
+		if len(messages) < outboxBatchSize {
+			return
+		}
+	}
+}
+
+// relayMessage publishes a message and records the outcome
+func (r *OutboxRelay) relayMessage(ctx context.Context, message *OutboxMessage) {
+	publishCtx, cancel := context.WithTimeout(ctx, outboxConfirmTimeout)
+	err := r.broker.Publish(publishCtx, message.Exchange, message.RoutingKey, Message{
+		ID:          message.ID.String(),
+		ContentType: "application/json",
+		Body:        message.Payload,
+	})
+	cancel()
+	if err != nil {
+		outboxPublishFailuresTotal.WithLabelValues(message.RoutingKey).Inc()
+		nextAttemptAt := time.Now().Add(outboxBackoff(message.Attempts + 1))
+		logrus.WithError(err).Warnf("Failed to publish outbox message %s, retrying at %s", message.ID, nextAttemptAt.Format(time.RFC3339))
+
+		if err := r.outboxRepo.MarkFailed(message, err.Error(), nextAttemptAt); err != nil {
+			logrus.WithError(err).Errorf("Failed to record publish failure of outbox message %s", message.ID)
+		}
+		return
+	}
+
+	outboxPublishedTotal.WithLabelValues(message.RoutingKey).Inc()
+	if err := r.outboxRepo.MarkSent(message); err != nil {
+		// The message is published again once its lease expires
+		logrus.WithError(err).Errorf("Failed to mark outbox message %s as sent", message.ID)
+	}
+}
+
+// updateMetrics refreshes the outbox backlog metrics
+func (r *OutboxRelay) updateMetrics() {
+	pending, oldest, err := r.outboxRepo.Stats()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to read outbox stats")
+		return
+	}
+
+	outboxPendingMessages.Set(float64(pending))
+	if oldest == nil {
+		outboxOldestPendingAge.Set(0)
+		return
+	}
+	outboxOldestPendingAge.Set(time.Since(*oldest).Seconds())
+}
+
+// outboxBackoff returns the delay before the given publish attempt, doubling
+// from one second up to outboxMaxBackoff
+func outboxBackoff(attempt int) time.Duration {
+	if attempt > 10 {
+		return outboxMaxBackoff
+	}
+
+	delay := time.Second << uint(attempt-1)
+	if delay > outboxMaxBackoff {
+		return outboxMaxBackoff
+	}
+	return delay
+}
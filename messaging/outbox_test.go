@@ -0,0 +1,173 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/ticket-system/contracts"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupOutboxRepository opens an in-memory database with the outbox table
+func setupOutboxRepository(t *testing.T) (OutboxRepository, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	// Every connection opens its own in-memory database
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	return NewOutboxRepository(db), db
+}
+
+// enqueueMessage writes a pending message due at nextAttemptAt to the outbox
+func enqueueMessage(t *testing.T, outboxRepo OutboxRepository, routingKey string, nextAttemptAt time.Time) *OutboxMessage {
+	message := &OutboxMessage{
+		ID:            uuid.New(),
+		Exchange:      "user_events",
+		RoutingKey:    routingKey,
+		Payload:       []byte(`{"user_id":"1"}`),
+		Status:        OutboxPending,
+		NextAttemptAt: nextAttemptAt,
+	}
+	require.NoError(t, outboxRepo.Enqueue(nil, message))
+	return message
+}
+
+// findOutboxMessage loads an outbox message by ID
+func findOutboxMessage(t *testing.T, db *gorm.DB, id uuid.UUID) OutboxMessage {
+	var message OutboxMessage
+	require.NoError(t, db.First(&message, "id = ?", id).Error)
+	return message
+}
+
+func TestOutboxRelay_RelaysDueMessages(t *testing.T) {
+	outboxRepo, db := setupOutboxRepository(t)
+	broker := NewMemoryBroker()
+	require.NoError(t, broker.DeclareExchange("user_events"))
+
+	var received []Message
+	err := broker.Consume(context.Background(), ConsumerOptions{
+		Queue:       "notification_user_events",
+		Exchange:    "user_events",
+		RoutingKeys: []string{"user.#"},
+	}, func(msg Message) error {
+		received = append(received, msg)
+		return nil
+	})
+	require.NoError(t, err)
+
+	due := enqueueMessage(t, outboxRepo, "user.created", time.Now().Add(-time.Second))
+	later := enqueueMessage(t, outboxRepo, "user.updated", time.Now().Add(time.Hour))
+
+	NewOutboxRelay(outboxRepo, broker).RelayDueMessages(context.Background())
+
+	require.Len(t, received, 1)
+	assert.Equal(t, due.ID.String(), received[0].ID)
+	assert.Equal(t, "user.created", received[0].RoutingKey)
+	assert.JSONEq(t, string(due.Payload), string(received[0].Body))
+
+	sent := findOutboxMessage(t, db, due.ID)
+	assert.Equal(t, OutboxSent, sent.Status)
+	assert.Equal(t, 1, sent.Attempts)
+	assert.NotNil(t, sent.SentAt)
+	assert.Equal(t, OutboxPending, findOutboxMessage(t, db, later.ID).Status)
+
+	pending, oldest, err := outboxRepo.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), pending)
+	require.NotNil(t, oldest)
+
+	// Published messages are deleted after the retention period
+	deleted, err := outboxRepo.DeleteSentBefore(time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
+
+func TestOutboxRelay_BacksOffAfterPublishFailure(t *testing.T) {
+	outboxRepo, db := setupOutboxRepository(t)
+
+	// The exchange is not declared, so the broker rejects the message
+	broker := NewMemoryBroker()
+
+	message := enqueueMessage(t, outboxRepo, "user.created", time.Now().Add(-time.Second))
+	require.NoError(t, db.Model(message).Update("attempts", 2).Error)
+
+	relay := NewOutboxRelay(outboxRepo, broker)
+	relay.RelayDueMessages(context.Background())
+
+	assert.Empty(t, broker.Published())
+	failed := findOutboxMessage(t, db, message.ID)
+	assert.Equal(t, OutboxPending, failed.Status)
+	assert.Equal(t, 3, failed.Attempts)
+	assert.NotEmpty(t, failed.LastError)
+	assert.WithinDuration(t, time.Now().Add(outboxBackoff(3)), failed.NextAttemptAt, time.Second)
+
+	// The message waits for its next attempt even after the exchange exists
+	require.NoError(t, broker.DeclareExchange("user_events"))
+	relay.RelayDueMessages(context.Background())
+	assert.Empty(t, broker.Published())
+}
+
+func TestOutboxRepository_ClaimDueLeasesMessages(t *testing.T) {
+	outboxRepo, db := setupOutboxRepository(t)
+	message := enqueueMessage(t, outboxRepo, "user.created", time.Now().Add(-time.Second))
+
+	claimed, err := outboxRepo.ClaimDue(10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, message.ID, claimed[0].ID)
+
+	// A relay that stopped before publishing leaves the message to the next
+	// claim after the lease
+	claimed, err = outboxRepo.ClaimDue(10, time.Minute)
+	require.NoError(t, err)
+	assert.Empty(t, claimed)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), findOutboxMessage(t, db, message.ID).NextAttemptAt, time.Second)
+}
+
+func TestEnqueueEvent_WritesEnvelopeInTransaction(t *testing.T) {
+	outboxRepo, db := setupOutboxRepository(t)
+	event := contracts.UserCreated{UserState: contracts.UserState{UserID: uuid.New(), Email: "user@example.com"}}
+
+	var message OutboxMessage
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		return EnqueueEvent(outboxRepo, tx, "user_events", event.UserID.String(), event)
+	}))
+	require.NoError(t, db.First(&message).Error)
+	assert.Equal(t, "user_events", message.Exchange)
+	assert.Equal(t, contracts.TypeUserCreated, message.RoutingKey)
+	assert.Equal(t, OutboxPending, message.Status)
+
+	// The envelope shares its ID with the outbox message
+	var decoded contracts.UserCreated
+	envelope, err := contracts.Unmarshal(message.Payload, &decoded)
+	require.NoError(t, err)
+	assert.Equal(t, message.ID.String(), envelope.ID)
+	assert.Equal(t, event.UserID.String(), envelope.CorrelationID)
+	assert.Equal(t, event.Email, decoded.Email)
+
+	// An event of a rolled back state change is never published
+	err = db.Transaction(func(tx *gorm.DB) error {
+		require.NoError(t, EnqueueEvent(outboxRepo, tx, "user_events", "", event))
+		return errors.New("state change failed")
+	})
+	require.Error(t, err)
+	var count int64
+	require.NoError(t, db.Model(&OutboxMessage{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	assert.Equal(t, time.Second, outboxBackoff(1))
+	assert.Equal(t, 4*time.Second, outboxBackoff(3))
+	assert.Equal(t, outboxMaxBackoff, outboxBackoff(10))
+	assert.Equal(t, outboxMaxBackoff, outboxBackoff(50))
+}
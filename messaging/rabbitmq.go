@@ -0,0 +1,567 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/sirupsen/logrus"
+)
+
+// RabbitMQ is a Broker backed by RabbitMQ. It reconnects when the connection
+// is lost, declares its exchanges again and restarts its consumers.
+type RabbitMQ struct {
+	config Config
+
+	mu        sync.Mutex
+	conn      *amqp.Connection
+	ready     chan struct{} // closed while conn is connected
+	exchanges []string
+	consumers []*consumer
+	closed    bool
+	done      chan struct{} // closed by Close to stop reconnecting
+
+	// confirmChannel is a channel in confirm mode shared by all publishes
+	publishMu      sync.Mutex
+	confirmChannel *amqp.Channel
+	confirms       chan amqp.Confirmation
+
+	// handlers counts the consumer workers, so that Close can wait for the
+	// messages being handled
+	handlers sync.WaitGroup
+}
+
+// NewRabbitMQ connects to RabbitMQ
+func NewRabbitMQ(config Config) (*RabbitMQ, error) {
+	r := &RabbitMQ{
+		config: config.withDefaults(),
+		ready:  make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	conn, err := r.dial(r.config.ConnectAttempts)
+	if err != nil {
+		return nil, err
+	}
+	r.connected(conn)
+
+	logrus.Info("Connected to RabbitMQ")
+	return r, nil
+}
+
+// dial connects to RabbitMQ, retrying with exponential backoff up to
+// attempts times, or until the broker is closed when attempts is zero
+func (r *RabbitMQ) dial(attempts int) (*amqp.Connection, error) {
+	delay := r.config.ReconnectDelay
+	for attempt := 1; ; attempt++ {
+		conn, err := amqp.Dial(r.config.URL)
+		if err == nil {
+			return conn, nil
+		}
+
+		if attempts > 0 && attempt >= attempts {
+			return nil, fmt.Errorf("failed to connect to RabbitMQ after %d attempts: %w", attempt, err)
+		}
+
+		logrus.Warnf("Failed to connect to RabbitMQ, retrying in %v: %v", delay, err)
+		select {
+		case <-time.After(delay):
+		case <-r.done:
+			return nil, ErrClosed
+		}
+
+		delay *= 2
+		if delay > r.config.MaxReconnectDelay {
+			delay = r.config.MaxReconnectDelay
+		}
+	}
+}
+
+// connected makes conn the current connection and reconnects once it is lost
+func (r *RabbitMQ) connected(conn *amqp.Connection) {
+	closes := conn.NotifyClose(make(chan *amqp.Error, 1))
+
+	r.mu.Lock()
+	r.conn = conn
+	close(r.ready)
+	r.mu.Unlock()
+
+	go r.reconnectAfter(closes)
+}
+
+// reconnectAfter waits until the connection is closed and, unless the broker
+// was closed, connects again and restores the exchanges and consumers
+func (r *RabbitMQ) reconnectAfter(closes chan *amqp.Error) {
+	reason := <-closes
+	if r.isClosed() {
+		return
+	}
+	logrus.Warnf("Lost connection to RabbitMQ, reconnecting: %v", reason)
+
+	r.mu.Lock()
+	r.ready = make(chan struct{})
+	r.mu.Unlock()
+
+	// The confirm channel closed with the connection
+	r.publishMu.Lock()
+	r.resetConfirmChannel()
+	r.publishMu.Unlock()
+
+	for {
+		conn, err := r.dial(0)
+		if err != nil {
+			return
+		}
+
+		if err := r.restore(conn); err != nil {
+			logrus.WithError(err).Warn("Failed to restore RabbitMQ topology, reconnecting")
+			conn.Close()
+			continue
+		}
+
+		r.connected(conn)
+		logrus.Info("Reconnected to RabbitMQ")
+		return
+	}
+}
+
+// restore declares the exchanges and starts the consumers on a new connection
+func (r *RabbitMQ) restore(conn *amqp.Connection) error {
+	r.mu.Lock()
+	exchanges := append([]string(nil), r.exchanges...)
+	consumers := append([]*consumer(nil), r.consumers...)
+	r.mu.Unlock()
+
+	for _, exchange := range exchanges {
+		if err := declareExchange(conn, exchange); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range consumers {
+		if err := c.start(conn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// connection returns the current connection, waiting for a reconnection
+// until ctx is done
+func (r *RabbitMQ) connection(ctx context.Context) (*amqp.Connection, error) {
+	r.mu.Lock()
+	ready := r.ready
+	r.mu.Unlock()
+
+	select {
+	case <-ready:
+	case <-r.done:
+		return nil, ErrClosed
+	case <-ctx.Done():
+		return nil, fmt.Errorf("not connected to RabbitMQ: %w", ctx.Err())
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.conn, nil
+}
+
+// isClosed reports whether Close was called
+func (r *RabbitMQ) isClosed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.closed
+}
+
+// DeclareExchange declares a durable topic exchange, again after every
+// reconnection
+func (r *RabbitMQ) DeclareExchange(name string) error {
+	conn, err := r.connection(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if err := declareExchange(conn, name); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.exchanges = append(r.exchanges, name)
+	r.mu.Unlock()
+	return nil
+}
+
+// declareExchange declares a durable topic exchange on conn
+func declareExchange(conn *amqp.Connection, name string) error {
+	ch, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("failed to open a channel: %w", err)
+	}
+	defer ch.Close()
+
+	err = ch.ExchangeDeclare(
+		name,    // name
+		"topic", // type
+		true,    // durable
+		false,   // auto-deleted
+		false,   // internal
+		false,   // no-wait
+		nil,     // arguments
+	)
+	if err != nil {
+		return fmt.Errorf("failed to declare exchange %s: %w", name, err)
+	}
+	return nil
+}
+
+// Publish publishes a persistent message and waits until the broker has
+// confirmed it, for at most the publish timeout when ctx has no deadline. A
+// message that is not confirmed must be considered unpublished.
+func (r *RabbitMQ) Publish(ctx context.Context, exchange, routingKey string, msg Message) error {
+	contentType := msg.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+
+	timestamp := msg.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	return r.publish(ctx, exchange, routingKey, amqp.Publishing{
+		ContentType:  contentType,
+		Body:         msg.Body,
+		DeliveryMode: amqp.Persistent,
+		Timestamp:    timestamp,
+		MessageId:    msg.ID,
+	})
+}
+
+// publish publishes on the confirm channel and waits for the confirm
+func (r *RabbitMQ) publish(ctx context.Context, exchange, routingKey string, publishing amqp.Publishing) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.config.PublishTimeout)
+		defer cancel()
+	}
+
+	r.publishMu.Lock()
+	defer r.publishMu.Unlock()
+
+	// Open confirm channel on first use
+	if r.confirmChannel == nil {
+		conn, err := r.connection(ctx)
+		if err != nil {
+			return err
+		}
+
+		ch, err := conn.Channel()
+		if err != nil {
+			return fmt.Errorf("failed to open a confirm channel: %w", err)
+		}
+		if err := ch.Confirm(false); err != nil {
+			ch.Close()
+			return fmt.Errorf("failed to put channel in confirm mode: %w", err)
+		}
+		r.confirmChannel = ch
+		r.confirms = ch.NotifyPublish(make(chan amqp.Confirmation, 1))
+	}
+
+	err := r.confirmChannel.PublishWithContext(
+		ctx,
+		exchange,   // exchange
+		routingKey, // routing key
+		false,      // mandatory
+		false,      // immediate
+		publishing,
+	)
+	if err != nil {
+		r.resetConfirmChannel()
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
+
+	select {
+	case confirm, ok := <-r.confirms:
+		if !ok {
+			r.resetConfirmChannel()
+			return fmt.Errorf("confirm channel closed")
+		}
+		if !confirm.Ack {
+			return fmt.Errorf("message %s was rejected by the broker", publishing.MessageId)
+		}
+		return nil
+	case <-ctx.Done():
+		// A late confirm would be taken for the next message, so start over
+		r.resetConfirmChannel()
+		return fmt.Errorf("timed out waiting for confirm of message %s", publishing.MessageId)
+	}
+}
+
+// resetConfirmChannel closes the confirm channel so that it is reopened on
+// the next publish. The caller holds publishMu.
+func (r *RabbitMQ) resetConfirmChannel() {
+	if r.confirmChannel != nil {
+		r.confirmChannel.Close()
+	}
+	r.confirmChannel = nil
+	r.confirms = nil
+}
+
+// Consume declares and binds the queue of a consumer, with its retry and
+// dead-letter queues, and handles its messages until ctx is cancelled or the
+// broker is closed. The consumer is restarted after a reconnection.
+func (r *RabbitMQ) Consume(ctx context.Context, opts ConsumerOptions, handler Handler) error {
+	if opts.Queue == "" {
+		return fmt.Errorf("queue is required")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	c := &consumer{
+		broker:  r,
+		opts:    opts.withDefaults(r.config),
+		handler: handler,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+
+	conn, err := r.connection(ctx)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	// Register before starting, so that a reconnection in between restarts it
+	r.mu.Lock()
+	r.consumers = append(r.consumers, c)
+	r.mu.Unlock()
+
+	if err := c.start(conn); err != nil {
+		r.removeConsumer(c)
+		cancel()
+		return err
+	}
+
+	logrus.Infof("Started consuming messages from queue %s", opts.Queue)
+	return nil
+}
+
+// removeConsumer forgets a consumer, so that it is not restarted
+func (r *RabbitMQ) removeConsumer(c *consumer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, existing := range r.consumers {
+		if existing == c {
+			r.consumers = append(r.consumers[:i], r.consumers[i+1:]...)
+			return
+		}
+	}
+}
+
+// Close stops the consumers, waits until the messages being handled are
+// acked or ctx is done, and closes the connection. Messages that were
+// prefetched but not handled are redelivered by RabbitMQ.
+func (r *RabbitMQ) Close(ctx context.Context) error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	close(r.done)
+	consumers := r.consumers
+	r.consumers = nil
+	r.mu.Unlock()
+
+	// Stop delivering and drain the workers
+	for _, c := range consumers {
+		c.cancel()
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		r.handlers.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		logrus.Warn("Timed out waiting for RabbitMQ consumers to finish, unacknowledged messages will be redelivered")
+	}
+
+	r.publishMu.Lock()
+	r.resetConfirmChannel()
+	r.publishMu.Unlock()
+
+	r.mu.Lock()
+	conn := r.conn
+	r.mu.Unlock()
+
+	if conn == nil || conn.IsClosed() {
+		return nil
+	}
+	if err := conn.Close(); err != nil && !errors.Is(err, amqp.ErrClosed) {
+		return fmt.Errorf("failed to close RabbitMQ connection: %w", err)
+	}
+	return nil
+}
+
+// consumer consumes a queue on a channel of the current connection
+type consumer struct {
+	broker  *RabbitMQ
+	opts    ConsumerOptions
+	handler Handler
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	mu   sync.Mutex
+	conn *amqp.Connection // connection the consumer runs on
+}
+
+// start declares the queues of the consumer on conn and starts its workers,
+// unless it already runs on conn or was stopped
+func (c *consumer) start(conn *amqp.Connection) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == conn || c.ctx.Err() != nil {
+		return nil
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		return fmt.Errorf("failed to open a channel: %w", err)
+	}
+
+	deliveries, tag, err := c.declareAndConsume(ch)
+	if err != nil {
+		ch.Close()
+		return err
+	}
+	c.conn = conn
+
+	// Stop delivering when the consumer is stopped; deliveries is closed once
+	// the broker has cancelled the consumer or the channel is lost
+	channelClosed := ch.NotifyClose(make(chan *amqp.Error, 1))
+	go func() {
+		select {
+		case <-c.ctx.Done():
+			if err := ch.Cancel(tag, false); err != nil {
+				ch.Close()
+			}
+		case <-channelClosed:
+		}
+	}()
+
+	var workers sync.WaitGroup
+	for i := 0; i < c.opts.Concurrency; i++ {
+		workers.Add(1)
+		c.broker.handlers.Add(1)
+		go func() {
+			defer c.broker.handlers.Done()
+			defer workers.Done()
+
+			for delivery := range deliveries {
+				c.handle(delivery)
+			}
+		}()
+	}
+
+	// Close the channel once the workers have acked their last message
+	go func() {
+		workers.Wait()
+		ch.Close()
+		if c.ctx.Err() != nil {
+			c.broker.removeConsumer(c)
+		}
+	}()
+
+	return nil
+}
+
+// declareAndConsume declares the queue of the consumer with its retry and
+// dead-letter queues, binds it and starts consuming it on ch
+func (c *consumer) declareAndConsume(ch *amqp.Channel) (<-chan amqp.Delivery, string, error) {
+	queueName := c.opts.Queue
+
+	_, err := ch.QueueDeclare(
+		queueName, // name
+		true,      // durable
+		false,     // delete when unused
+		false,     // exclusive
+		false,     // no-wait
+		nil,       // arguments
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to declare queue %s: %w", queueName, err)
+	}
+
+	if err := declareRetryQueues(ch, queueName); err != nil {
+		return nil, "", err
+	}
+
+	for _, routingKey := range c.opts.RoutingKeys {
+		err := ch.QueueBind(
+			queueName,       // queue name
+			routingKey,      // routing key
+			c.opts.Exchange, // exchange
+			false,           // no-wait
+			nil,             // arguments
+		)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to bind queue %s to exchange %s: %w", queueName, c.opts.Exchange, err)
+		}
+	}
+
+	err = ch.Qos(
+		c.opts.Prefetch, // prefetch count
+		0,               // prefetch size
+		false,           // global
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to set QoS: %w", err)
+	}
+
+	tag := fmt.Sprintf("%s-%d", queueName, time.Now().UnixNano())
+	deliveries, err := ch.Consume(
+		queueName, // queue
+		tag,       // consumer
+		false,     // auto-ack
+		false,     // exclusive
+		false,     // no-local
+		false,     // no-wait
+		nil,       // args
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to consume from queue %s: %w", queueName, err)
+	}
+
+	return deliveries, tag, nil
+}
+
+// handle handles a delivery and acks it. A message that failed is retried
+// later, or dead-lettered after its last attempt, and only requeued when the
+// retry cannot be scheduled.
+func (c *consumer) handle(delivery amqp.Delivery) {
+	msg := fromDelivery(delivery)
+
+	if err := c.handler(msg); err != nil {
+		logrus.WithError(err).Errorf("Failed to handle message %s from queue %s", msg.ID, c.opts.Queue)
+
+		if err := c.broker.retryOrDeadLetter(c.opts.Queue, delivery, msg, err); err != nil {
+			logrus.WithError(err).Errorf("Failed to schedule retry of message %s", msg.ID)
+			if err := delivery.Nack(false, true); err != nil {
+				logrus.WithError(err).Errorf("Failed to nack message %s", msg.ID)
+			}
+			return
+		}
+	}
+
+	if err := delivery.Ack(false); err != nil {
+		logrus.WithError(err).Errorf("Failed to ack message %s", msg.ID)
+	}
+}
@@ -0,0 +1,33 @@
+package messaging
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchRoutingKey(t *testing.T) {
+	tests := []struct {
+		pattern    string
+		routingKey string
+		want       bool
+	}{
+		{"booking.created", "booking.created", true},
+		{"booking.created", "booking.cancelled", false},
+		{"booking.*", "booking.created", true},
+		{"booking.*", "booking.payment.requested", false},
+		{"booking.#", "booking", true},
+		{"booking.#", "booking.payment.requested", true},
+		{"booking.#", "payment.completed", false},
+		{"#", "payment.completed", true},
+		{"*.completed", "payment.completed", true},
+		{"#.completed", "payment.refund.completed", true},
+		{"#.completed", "payment.failed", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.routingKey, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchRoutingKey(tt.pattern, tt.routingKey))
+		})
+	}
+}
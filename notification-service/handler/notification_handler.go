@@ -1,13 +1,13 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 
-	"notification-service/middleware"
 	"notification-service/model"
 	"notification-service/service"
 )
@@ -25,10 +25,10 @@ func NewNotificationHandler(service service.NotificationService) *NotificationHa
 }
 
 // SetupRoutes sets up the notification routes
-func (h *NotificationHandler) SetupRoutes(router *gin.Engine) {
+func (h *NotificationHandler) SetupRoutes(router *gin.Engine, authMiddleware gin.HandlerFunc) {
 	// API group with JWT authentication
 	apiGroup := router.Group("/api/v1")
-	apiGroup.Use(middleware.JWTAuth())
+	apiGroup.Use(authMiddleware)
 
 	// Notification routes
 	notifications := apiGroup.Group("/notifications")
@@ -80,6 +80,10 @@ func (h *NotificationHandler) CreateNotification(c *gin.Context) {
 
 	// Create notification
 	notification, err := h.Service.CreateNotification(req)
+	if errors.Is(err, service.ErrNotificationOptedOut) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		logrus.Errorf("Failed to create notification: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create notification: " + err.Error()})
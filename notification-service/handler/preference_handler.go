@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"notification-service/model"
+	"notification-service/service"
+)
+
+// PreferenceHandler handles HTTP requests for notification preferences
+type PreferenceHandler struct {
+	Service service.PreferenceService
+}
+
+// NewPreferenceHandler creates a new preference handler
+func NewPreferenceHandler(service service.PreferenceService) *PreferenceHandler {
+	return &PreferenceHandler{
+		Service: service,
+	}
+}
+
+// SetupRoutes sets up the preference routes. Unsubscribe links are opened
+// from emails without a session, so they are authenticated by their signature.
+func (h *PreferenceHandler) SetupRoutes(router *gin.Engine, authMiddleware gin.HandlerFunc) {
+	// Public unsubscribe routes
+	public := router.Group("/api/v1/notifications")
+	public.GET("/unsubscribe", h.GetUnsubscribe)
+	public.POST("/unsubscribe", h.Unsubscribe)
+
+	// API group with JWT authentication
+	preferences := router.Group("/api/v1/notifications/preferences")
+	preferences.Use(authMiddleware)
+	preferences.GET("", h.GetPreferences)
+	preferences.PUT("", h.UpdatePreferences)
+	preferences.PUT("/marketing", h.UpdateMarketingConsent)
+}
+
+// GetPreferences returns the preferences of the current user
+func (h *PreferenceHandler) GetPreferences(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	preferences, err := h.Service.GetPreferences(userID)
+	if err != nil {
+		logrus.Errorf("Failed to get preferences: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, preferences)
+}
+
+// UpdatePreferences changes the language, timezone and channel preferences
+// of the current user
+func (h *PreferenceHandler) UpdatePreferences(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req model.UpdatePreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	preferences, err := h.Service.UpdatePreferences(userID, req)
+	if errors.Is(err, service.ErrMandatoryNotificationType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		logrus.Errorf("Failed to update preferences: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, preferences)
+}
+
+// UpdateMarketingConsent gives or withdraws the marketing consent of the
+// current user
+func (h *PreferenceHandler) UpdateMarketingConsent(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req model.MarketingConsentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	preferences, err := h.Service.SetMarketingConsent(userID, *req.Consent, model.ConsentSourcePreferences, c.ClientIP())
+	if err != nil {
+		logrus.Errorf("Failed to update marketing consent: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update marketing consent"})
+		return
+	}
+
+	c.JSON(http.StatusOK, preferences)
+}
+
+// GetUnsubscribe describes what an unsubscribe link opts out of without
+// applying it, since mail scanners open links in emails
+func (h *PreferenceHandler) GetUnsubscribe(c *gin.Context) {
+	target, err := h.Service.ResolveUnsubscribe(c.Query("token"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"type":    target.Type,
+		"channel": target.Channel,
+	})
+}
+
+// Unsubscribe applies an unsubscribe link. Mail clients post to it for
+// one-click unsubscribe.
+func (h *PreferenceHandler) Unsubscribe(c *gin.Context) {
+	target, err := h.Service.Unsubscribe(c.Query("token"), c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Unsubscribed successfully",
+		"type":    target.Type,
+		"channel": target.Channel,
+	})
+}
+
+// currentUserID returns the ID of the authenticated user, or writes an error
+// response and returns false
+func currentUserID(c *gin.Context) (uuid.UUID, bool) {
+	value, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return uuid.Nil, false
+	}
+
+	userID, err := uuid.Parse(value.(string))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID"})
+		return uuid.Nil, false
+	}
+	return userID, true
+}
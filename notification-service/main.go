@@ -13,7 +13,9 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
-	"github.com/streadway/amqp"
+	"github.com/yourusername/ticket-system/claims"
+	"github.com/yourusername/ticket-system/messaging"
+	"gorm.io/gorm"
 
 	"notification-service/config"
 	"notification-service/handler"
@@ -41,16 +43,15 @@ func main() {
 	logrus.Info("Connected to PostgreSQL database")
 
 	// Connect to RabbitMQ
-	rabbitMQ, err := config.NewRabbitMQ()
+	broker, err := messaging.NewRabbitMQ(messaging.ConfigFromEnv())
 	if err != nil {
 		logrus.Fatalf("Failed to connect to RabbitMQ: %v", err)
 	}
-	logrus.Info("Connected to RabbitMQ")
 
 	// Declare exchanges
-	exchanges := []string{"notification_events", "payment_events", "ticket_events", "user_events"}
+	exchanges := []string{"notification_events", "payment_events", "ticket_events", "user_events", "privacy_events", "account_emails"}
 	for _, exchange := range exchanges {
-		if err := rabbitMQ.DeclareExchange(exchange); err != nil {
+		if err := broker.DeclareExchange(exchange); err != nil {
 			logrus.Fatalf("Failed to declare exchange %s: %v", exchange, err)
 		}
 	}
@@ -58,10 +59,21 @@ func main() {
 	// Initialize repositories
 	repo := repository.NewNotificationRepositoryImpl(db)
 
+	inboxRepo := messaging.NewInboxRepository(db)
+	contactRepo := repository.NewUserContactRepository(db)
+	preferenceRepo := repository.NewPreferenceRepository(db)
+	outboxRepo := messaging.NewOutboxRepository(db)
+
 	// Run database migrations
 	if err := repo.AutoMigrate(); err != nil {
 		logrus.Fatalf("Failed to run database migrations: %v", err)
 	}
+	if err := contactRepo.AutoMigrate(); err != nil {
+		logrus.Fatalf("Failed to run database migrations: %v", err)
+	}
+	if err := preferenceRepo.AutoMigrate(); err != nil {
+		logrus.Fatalf("Failed to run database migrations: %v", err)
+	}
 	logrus.Info("Database migrations completed successfully")
 
 	// Initialize providers
@@ -69,14 +81,37 @@ func main() {
 	smsProvider := provider.NewSMSProvider()
 	pushProvider := provider.NewPushProvider()
 
+	// Emails that can be opted out of carry signed unsubscribe links
+	unsubscribeSecret := os.Getenv("UNSUBSCRIBE_SECRET")
+	if unsubscribeSecret == "" {
+		logrus.Fatal("UNSUBSCRIBE_SECRET is required to sign unsubscribe links")
+	}
+	unsubscribeURL := os.Getenv("UNSUBSCRIBE_URL")
+	if unsubscribeURL == "" {
+		unsubscribeURL = "http://localhost:8080/api/v1/notifications/unsubscribe"
+	}
+
 	// Initialize services
-	notificationService := service.NewNotificationService(repo, rabbitMQ, emailProvider, smsProvider, pushProvider)
+	preferenceService, err := service.NewPreferenceService(preferenceRepo, []byte(unsubscribeSecret), unsubscribeURL)
+	if err != nil {
+		logrus.Fatalf("Failed to create preference service: %v", err)
+	}
+	notificationService := service.NewNotificationService(repo, contactRepo, outboxRepo, emailProvider, smsProvider, pushProvider, preferenceService)
+	inbox := messaging.NewInbox(inboxRepo)
+	outboxRelay := messaging.NewOutboxRelay(outboxRepo, broker)
 
 	// Initialize handlers
 	notificationHandler := handler.NewNotificationHandler(notificationService)
+	preferenceHandler := handler.NewPreferenceHandler(preferenceService)
 
 	// Initialize Gin router
 	router := gin.New()
+
+	// Only the API gateway may report the address of the client
+	if err := router.SetTrustedProxies(claims.TrustedProxiesFromEnv()); err != nil {
+		logrus.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
 	router.Use(gin.Recovery())
 	router.Use(middleware.Logger())
 	router.Use(middleware.Metrics())
@@ -84,8 +119,16 @@ func main() {
 	// Initialize Prometheus metrics
 	middleware.InitMetrics()
 
+	// Trust the identity the gateway verified the access token of
+	identitySigner := claims.NewSigner(claims.ConfigFromEnv())
+	if !identitySigner.Configured() {
+		logrus.Warn("INTERNAL_IDENTITY_SECRET is not set, authenticated requests are rejected")
+	}
+
 	// Set up routes
-	notificationHandler.SetupRoutes(router)
+	authMiddleware := middleware.IdentityAuth(identitySigner)
+	notificationHandler.SetupRoutes(router, authMiddleware)
+	preferenceHandler.SetupRoutes(router, authMiddleware)
 
 	// Health check endpoint
 	router.GET("/health", func(c *gin.Context) {
@@ -95,8 +138,24 @@ func main() {
 	// Metrics endpoint
 	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
+	// Start background workers
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
 	// Set up RabbitMQ consumers
-	setupConsumers(rabbitMQ, notificationService)
+	if err := setupConsumers(workerCtx, broker, notificationService, inbox); err != nil {
+		logrus.Fatalf("Failed to set up consumers: %v", err)
+	}
+
+	// Delete old processed message IDs
+	go inbox.StartCleanup(workerCtx, time.Hour)
+
+	// Publish events written to the outbox
+	outboxInterval, err := time.ParseDuration(os.Getenv("OUTBOX_POLL_INTERVAL"))
+	if err != nil || outboxInterval <= 0 {
+		outboxInterval = time.Second
+	}
+	go outboxRelay.Start(workerCtx, outboxInterval)
 
 	// Start HTTP server
 	port := os.Getenv("SERVER_PORT")
@@ -132,8 +191,11 @@ func main() {
 		logrus.Fatalf("Server forced to shutdown: %v", err)
 	}
 
+	// Stop the workers and let the consumers finish the messages in progress
+	stopWorkers()
+
 	// Close RabbitMQ connection
-	if err := rabbitMQ.Close(); err != nil {
+	if err := broker.Close(ctx); err != nil {
 		logrus.Errorf("Failed to close RabbitMQ connection: %v", err)
 	}
 
@@ -175,114 +237,41 @@ func initLogging() {
 }
 
 // setupConsumers sets up RabbitMQ consumers
-func setupConsumers(rabbitMQ *config.RabbitMQ, notificationService service.NotificationService) {
-	// Payment events consumer
-	go func() {
-		queueName := "notification_payment_events"
-		if err := rabbitMQ.DeclareQueue(queueName); err != nil {
-			logrus.Fatalf("Failed to declare queue %s: %v", queueName, err)
-		}
-
-		if err := rabbitMQ.BindQueue(queueName, "payment_events", ""); err != nil {
-			logrus.Fatalf("Failed to bind queue %s to exchange: %v", queueName, err)
-		}
-
-		messages, err := rabbitMQ.ConsumeMessages(queueName)
-		if err != nil {
-			logrus.Fatalf("Failed to consume messages from queue %s: %v", queueName, err)
-		}
-
-		logrus.Infof("Started consuming payment events from queue %s", queueName)
-
-		for msg := range messages {
-			logrus.Debugf("Received payment event: %s", string(msg.Body))
-			if err := notificationService.HandlePaymentEvent(msg.Body); err != nil {
-				logrus.Errorf("Failed to handle payment event: %v", err)
-				// Nack the message to requeue it
-				if err := msg.Nack(false, true); err != nil {
-					logrus.Errorf("Failed to nack message: %v", err)
-				}
-			} else {
-				// Ack the message
-				if err := msg.Ack(false); err != nil {
-					logrus.Errorf("Failed to ack message: %v", err)
-				}
-			}
-		}
-	}()
-
-	// Ticket events consumer
-	go func() {
-		queueName := "notification_ticket_events"
-		if err := rabbitMQ.DeclareQueue(queueName); err != nil {
-			logrus.Fatalf("Failed to declare queue %s: %v", queueName, err)
-		}
-
-		if err := rabbitMQ.BindQueue(queueName, "ticket_events", ""); err != nil {
-			logrus.Fatalf("Failed to bind queue %s to exchange: %v", queueName, err)
-		}
-
-		messages, err := rabbitMQ.ConsumeMessages(queueName)
-		if err != nil {
-			logrus.Fatalf("Failed to consume messages from queue %s: %v", queueName, err)
-		}
-
-		logrus.Infof("Started consuming ticket events from queue %s", queueName)
-
-		for msg := range messages {
-			logrus.Debugf("Received ticket event: %s", string(msg.Body))
-			if err := notificationService.HandleTicketEvent(msg.Body); err != nil {
-				logrus.Errorf("Failed to handle ticket event: %v", err)
-				// Nack the message to requeue it
-				if err := msg.Nack(false, true); err != nil {
-					logrus.Errorf("Failed to nack message: %v", err)
-				}
-			} else {
-				// Ack the message
-				if err := msg.Ack(false); err != nil {
-					logrus.Errorf("Failed to ack message: %v", err)
-				}
-			}
-		}
-	}()
-
-	// User events consumer
-	go func() {
-		queueName := "notification_user_events"
-		if err := rabbitMQ.DeclareQueue(queueName); err != nil {
-			logrus.Fatalf("Failed to declare queue %s: %v", queueName, err)
-		}
-
-		if err := rabbitMQ.BindQueue(queueName, "user_events", ""); err != nil {
-			logrus.Fatalf("Failed to bind queue %s to exchange: %v", queueName, err)
-		}
+func setupConsumers(ctx context.Context, broker messaging.Broker, notificationService service.NotificationService, inbox *messaging.Inbox) error {
+	consumers := []struct {
+		queue      string
+		exchange   string
+		routingKey string
+		handle     func(s service.NotificationService, msg []byte) error
+	}{
+		{"notification_payment_events", "payment_events", "payment.#", service.NotificationService.HandlePaymentEvent},
+		{"notification_ticket_events", "ticket_events", "booking.#", service.NotificationService.HandleTicketEvent},
+		{"notification_user_events", "user_events", "user.#", service.NotificationService.HandleUserEvent},
+		{"notification_account_emails", "account_emails", "user.#", service.NotificationService.HandleUserEvent},
+	}
 
-		messages, err := rabbitMQ.ConsumeMessages(queueName)
+	for _, consumer := range consumers {
+		consumer := consumer
+		err := broker.Consume(ctx, messaging.ConsumerOptions{
+			Queue:       consumer.queue,
+			Exchange:    consumer.exchange,
+			RoutingKeys: []string{consumer.routingKey},
+		}, func(msg messaging.Message) error {
+			// Bodies are not logged, account emails carry single use tokens
+			logrus.Debugf("Received %s event %s", msg.RoutingKey, msg.ID)
+			// Handle the message once, in the transaction that records it as processed
+			return inbox.Handle(consumer.queue, msg.ID, func(tx *gorm.DB) error {
+				return consumer.handle(notificationService.WithTx(tx), msg.Body)
+			})
+		})
 		if err != nil {
-			logrus.Fatalf("Failed to consume messages from queue %s: %v", queueName, err)
+			return fmt.Errorf("failed to consume messages from queue %s: %w", consumer.queue, err)
 		}
-
-		logrus.Infof("Started consuming user events from queue %s", queueName)
-
-		for msg := range messages {
-			logrus.Debugf("Received user event: %s", string(msg.Body))
-			if err := notificationService.HandleUserEvent(msg.Body); err != nil {
-				logrus.Errorf("Failed to handle user event: %v", err)
-				// Nack the message to requeue it
-				if err := msg.Nack(false, true); err != nil {
-					logrus.Errorf("Failed to nack message: %v", err)
-				}
-			} else {
-				// Ack the message
-				if err := msg.Ack(false); err != nil {
-					logrus.Errorf("Failed to ack message: %v", err)
-				}
-			}
-		}
-	}()
+	}
 
 	// Create default templates
 	createDefaultTemplates(notificationService)
+	return nil
 }
 
 // createDefaultTemplates creates default notification templates
@@ -294,12 +283,30 @@ func createDefaultTemplates(notificationService service.NotificationService) {
 			Content:     "<h1>Welcome, {{username}}!</h1><p>Thank you for joining our event ticket platform. We're excited to have you on board!</p>",
 			Description: "Welcome email for new users",
 		},
+		{
+			Code:        "email_verification",
+			Title:       "Verify Your Email Address",
+			Content:     "<h1>Verify Your Email Address</h1><p>Hi {{username}}, please confirm your email address by clicking the link below. The link expires on {{expires_at}}.</p><p><a href='{{verification_url}}'>Verify Email</a></p>",
+			Description: "Email address verification",
+		},
 		{
 			Code:        "password_reset",
 			Title:       "Password Reset Request",
 			Content:     "<h1>Password Reset</h1><p>You requested a password reset. Click the link below to reset your password:</p><p><a href='{{reset_url}}'>Reset Password</a></p>",
 			Description: "Password reset email",
 		},
+		{
+			Code:        "account_locked",
+			Title:       "Your Account Has Been Locked",
+			Content:     "<h1>Account Locked</h1><p>Hi {{username}}, we locked sign-ins to your account until {{locked_until}} after {{failed_attempts}} failed login attempts, the last one from {{ip_address}}. If this wasn't you, please reset your password.</p>",
+			Description: "Account lockout alert",
+		},
+		{
+			Code:        "staff_invitation",
+			Title:       "You've Been Invited to Event Ticket Platform",
+			Content:     "<h1>Welcome to the Team</h1><p>Hi {{username}}, an administrator created a {{role}} account for you. Set your password with the link below before {{expires_at}}:</p><p><a href='{{setup_url}}'>Set Password</a></p>",
+			Description: "Invitation for staff accounts created by an admin",
+		},
 		{
 			Code:        "payment_success",
 			Title:       "Payment Successful",
@@ -309,7 +316,7 @@ func createDefaultTemplates(notificationService service.NotificationService) {
 		{
 			Code:        "payment_failed",
 			Title:       "Payment Failed",
-			Content:     "<h1>Payment Failed</h1><p>Your payment for booking {{booking_id}} has failed. Reason: {{reason}}</p>",
+			Content:     "<h1>Payment Failed</h1><p>Your payment for booking {{booking_id}} has failed. Please try again or use another payment method.</p>",
 			Description: "Payment failure notification",
 		},
 		{
@@ -327,7 +334,7 @@ func createDefaultTemplates(notificationService service.NotificationService) {
 		{
 			Code:        "ticket_cancelled",
 			Title:       "Ticket Booking Cancelled",
-			Content:     "<h1>Booking Cancelled</h1><p>Your booking {{booking_id}} for {{event_name}} has been cancelled. Reason: {{reason}}</p>",
+			Content:     "<h1>Booking Cancelled</h1><p>Your booking {{booking_id}} has been cancelled. Refund: {{refund_amount}} ({{reason}})</p>",
 			Description: "Ticket cancellation notification",
 		},
 		{
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/claims"
+)
+
+// IdentityAuth is a middleware that authenticates requests by the identity
+// the gateway verified the access token of and forwards signed in the
+// X-Identity header, so that tokens are not parsed again here
+func IdentityAuth(signer *claims.Signer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get the signed identity from the X-Identity header
+		value := c.GetHeader(claims.Header)
+		if value == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
+			c.Abort()
+			return
+		}
+
+		// Verify the signature and lifetime of the identity
+		identity, err := signer.Verify(value)
+		if err != nil {
+			logrus.Errorf("Failed to verify identity: %v", err)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			c.Abort()
+			return
+		}
+
+		// Set user information in the context
+		c.Set("userID", identity.UserID.String())
+		c.Set("userEmail", identity.Email)
+		c.Set("userRole", identity.Role)
+
+		c.Next()
+	}
+}
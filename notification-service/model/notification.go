@@ -25,6 +25,10 @@ const (
 	NotificationTypeEventCancelled      NotificationType = "event_cancelled"
 	NotificationTypeBookingCancelled    NotificationType = "booking_cancelled"
 	NotificationTypeRefundProcessed     NotificationType = "refund_processed"
+	NotificationTypeWelcome             NotificationType = "welcome"
+	NotificationTypeEmailVerification   NotificationType = "email_verification"
+	NotificationTypePasswordReset       NotificationType = "password_reset"
+	NotificationTypeSecurityAlert       NotificationType = "security_alert"
 	NotificationTypeCustom              NotificationType = "custom"
 )
 
@@ -0,0 +1,99 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// NotificationTypeMarketing is the type of promotional notifications, which
+// are only sent to users who consented to marketing
+const NotificationTypeMarketing NotificationType = "marketing"
+
+// Marketing consent sources
+const (
+	ConsentSourcePreferences = "preferences"
+	ConsentSourceUnsubscribe = "unsubscribe_link"
+)
+
+// NotificationPreference holds the notification settings of a user. Users
+// without preferences get every notification in the default language.
+type NotificationPreference struct {
+	UserID   uuid.UUID `gorm:"type:uuid;primary_key" json:"user_id"`
+	Language string    `gorm:"size:10" json:"language,omitempty"`
+	Timezone string    `gorm:"size:64" json:"timezone,omitempty"`
+
+	// MarketingConsentAt is when the user last gave or withdrew consent
+	MarketingConsent   bool       `gorm:"not null;default:false" json:"marketing_consent"`
+	MarketingConsentAt *time.Time `json:"marketing_consent_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ChannelPreference opts a user in or out of a notification type on a
+// channel. Types and channels without a preference are enabled.
+type ChannelPreference struct {
+	UserID    uuid.UUID           `gorm:"type:uuid;primary_key" json:"-"`
+	Type      NotificationType    `gorm:"size:50;primary_key" json:"type"`
+	Channel   NotificationChannel `gorm:"size:20;primary_key" json:"channel"`
+	Enabled   bool                `gorm:"not null" json:"enabled"`
+	UpdatedAt time.Time           `json:"updated_at"`
+}
+
+// MarketingConsentRecord records a change of the marketing consent of a
+// user, as proof of consent
+type MarketingConsentRecord struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
+	Consent   bool      `gorm:"not null" json:"consent"`
+	Source    string    `gorm:"size:30;not null" json:"source"` // preferences, unsubscribe_link
+	IPAddress string    `gorm:"size:45" json:"ip_address,omitempty"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (r *MarketingConsentRecord) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// PreferencesResponse represents the response for the preferences of a user
+type PreferencesResponse struct {
+	Language           string              `json:"language,omitempty"`
+	Timezone           string              `json:"timezone,omitempty"`
+	MarketingConsent   bool                `json:"marketing_consent"`
+	MarketingConsentAt *time.Time          `json:"marketing_consent_at,omitempty"`
+	Channels           []ChannelPreference `json:"channels"`
+}
+
+// ChannelPreferenceRequest opts in or out of a notification type on a channel
+type ChannelPreferenceRequest struct {
+	Type    NotificationType    `json:"type" binding:"required,max=50"`
+	Channel NotificationChannel `json:"channel" binding:"required,oneof=email sms push"`
+	Enabled *bool               `json:"enabled" binding:"required"`
+}
+
+// UpdatePreferencesRequest represents a request to update the preferences of
+// a user. Unset fields are left as they are.
+type UpdatePreferencesRequest struct {
+	Language *string                    `json:"language" binding:"omitempty,bcp47_language_tag,max=10"`
+	Timezone *string                    `json:"timezone" binding:"omitempty,timezone,max=64"`
+	Channels []ChannelPreferenceRequest `json:"channels" binding:"omitempty,dive"`
+}
+
+// MarketingConsentRequest represents a request to give or withdraw
+// marketing consent
+type MarketingConsentRequest struct {
+	Consent *bool `json:"consent" binding:"required"`
+}
+
+// UnsubscribeTarget is what a signed unsubscribe link opts the user out of
+type UnsubscribeTarget struct {
+	UserID  uuid.UUID           `json:"user_id"`
+	Type    NotificationType    `json:"type"`
+	Channel NotificationChannel `json:"channel"`
+}
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// PrivacyService is the name the notification service answers data requests
+// of the user service with
+const PrivacyService = "notification-service"
+
+// PrivacyDataExport is the personal data the notification service holds
+// about a user
+type PrivacyDataExport struct {
+	Contact        *UserContact             `json:"contact"`
+	Notifications  []Notification           `json:"notifications"`
+	Preferences    *PreferencesResponse     `json:"preferences"`
+	ConsentRecords []MarketingConsentRecord `json:"consent_records"`
+	ExportedAt     time.Time                `json:"exported_at"`
+}
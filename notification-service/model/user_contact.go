@@ -0,0 +1,16 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserContact is the contact address of a user, kept up to date from the
+// user events so that notifications can be sent for events that only carry
+// the user ID
+type UserContact struct {
+	UserID    uuid.UUID `gorm:"type:uuid;primary_key" json:"user_id"`
+	Email     string    `gorm:"size:255;not null" json:"email"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -15,6 +15,7 @@ type NotificationRepository interface {
 	FindByStatus(status model.NotificationStatus, page, pageSize int) ([]*model.Notification, int64, error)
 	Update(notification *model.Notification) error
 	Delete(id uuid.UUID) error
+	DeleteByUserID(userID uuid.UUID) (int64, error)
 
 	// Template operations
 	CreateTemplate(template *model.NotificationTemplate) error
@@ -25,6 +26,9 @@ type NotificationRepository interface {
 	DeleteTemplate(id uuid.UUID) error
 	FindAllTemplates(page, pageSize int) ([]*model.NotificationTemplate, int64, error)
 
+	// Transactions
+	WithTx(tx *gorm.DB) NotificationRepository
+
 	// Auto-migration
 	AutoMigrate() error
 }
@@ -114,6 +118,13 @@ func (r *GormNotificationRepository) Delete(id uuid.UUID) error {
 	return r.db.Delete(&model.Notification{}, id).Error
 }
 
+// DeleteByUserID deletes all notifications of a user, and returns how many
+// were deleted
+func (r *GormNotificationRepository) DeleteByUserID(userID uuid.UUID) (int64, error) {
+	result := r.db.Where("user_id = ?", userID).Delete(&model.Notification{})
+	return result.RowsAffected, result.Error
+}
+
 // CreateTemplate creates a new notification template
 func (r *GormNotificationRepository) CreateTemplate(template *model.NotificationTemplate) error {
 	return r.db.Create(template).Error
@@ -183,6 +194,11 @@ func (r *GormNotificationRepository) FindAllTemplates(page, pageSize int) ([]*mo
 	return templates, total, nil
 }
 
+// WithTx returns a repository that runs its operations in tx
+func (r *GormNotificationRepository) WithTx(tx *gorm.DB) NotificationRepository {
+	return &GormNotificationRepository{db: tx}
+}
+
 // AutoMigrate automatically migrates the notification models
 func (r *GormNotificationRepository) AutoMigrate() error {
 	return r.db.AutoMigrate(&model.Notification{}, &model.NotificationTemplate{})
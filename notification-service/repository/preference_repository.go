@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/ticket-system/notification-service/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PreferenceRepository defines the interface for notification preference repository operations
+type PreferenceRepository interface {
+	Save(preference *model.NotificationPreference) error
+	FindByUserID(userID uuid.UUID) (*model.NotificationPreference, error)
+	SaveChannel(channel *model.ChannelPreference) error
+	FindChannels(userID uuid.UUID) ([]model.ChannelPreference, error)
+	FindChannel(userID uuid.UUID, notificationType model.NotificationType, channel model.NotificationChannel) (*model.ChannelPreference, error)
+	CreateConsentRecord(record *model.MarketingConsentRecord) error
+	FindConsentRecords(userID uuid.UUID) ([]model.MarketingConsentRecord, error)
+	DeleteByUserID(userID uuid.UUID) (int64, error)
+
+	// Transactions
+	WithTx(tx *gorm.DB) PreferenceRepository
+
+	// Auto-migration
+	AutoMigrate() error
+}
+
+// GormPreferenceRepository implements PreferenceRepository using GORM
+type GormPreferenceRepository struct {
+	db *gorm.DB
+}
+
+// NewPreferenceRepository creates a new notification preference repository
+func NewPreferenceRepository(db *gorm.DB) PreferenceRepository {
+	return &GormPreferenceRepository{db: db}
+}
+
+// Save creates the preferences of a user or replaces them
+func (r *GormPreferenceRepository) Save(preference *model.NotificationPreference) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"language", "timezone", "marketing_consent", "marketing_consent_at", "updated_at"}),
+	}).Create(preference).Error
+}
+
+// FindByUserID finds the preferences of a user, or nil when there are none
+func (r *GormPreferenceRepository) FindByUserID(userID uuid.UUID) (*model.NotificationPreference, error) {
+	var preference model.NotificationPreference
+	result := r.db.Where("user_id = ?", userID).First(&preference)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &preference, nil
+}
+
+// SaveChannel creates the preference of a user for a notification type on a
+// channel or replaces it
+func (r *GormPreferenceRepository) SaveChannel(channel *model.ChannelPreference) error {
+	channel.UpdatedAt = time.Now()
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}, {Name: "channel"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled", "updated_at"}),
+	}).Create(channel).Error
+}
+
+// FindChannels finds the channel preferences of a user
+func (r *GormPreferenceRepository) FindChannels(userID uuid.UUID) ([]model.ChannelPreference, error) {
+	var channels []model.ChannelPreference
+	err := r.db.Where("user_id = ?", userID).Order("type, channel").Find(&channels).Error
+	return channels, err
+}
+
+// FindChannel finds the preference of a user for a notification type on a
+// channel, or nil when there is none
+func (r *GormPreferenceRepository) FindChannel(userID uuid.UUID, notificationType model.NotificationType, channel model.NotificationChannel) (*model.ChannelPreference, error) {
+	var preference model.ChannelPreference
+	result := r.db.Where("user_id = ? AND type = ? AND channel = ?", userID, notificationType, channel).First(&preference)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &preference, nil
+}
+
+// CreateConsentRecord records a change of the marketing consent of a user
+func (r *GormPreferenceRepository) CreateConsentRecord(record *model.MarketingConsentRecord) error {
+	return r.db.Create(record).Error
+}
+
+// FindConsentRecords finds the marketing consent history of a user, oldest first
+func (r *GormPreferenceRepository) FindConsentRecords(userID uuid.UUID) ([]model.MarketingConsentRecord, error) {
+	var records []model.MarketingConsentRecord
+	err := r.db.Where("user_id = ?", userID).Order("created_at").Find(&records).Error
+	return records, err
+}
+
+// DeleteByUserID deletes the preferences, channel preferences and consent
+// history of a user, and returns how many records were deleted
+func (r *GormPreferenceRepository) DeleteByUserID(userID uuid.UUID) (int64, error) {
+	var deleted int64
+	for _, record := range []interface{}{&model.NotificationPreference{}, &model.ChannelPreference{}, &model.MarketingConsentRecord{}} {
+		result := r.db.Where("user_id = ?", userID).Delete(record)
+		if result.Error != nil {
+			return deleted, result.Error
+		}
+		deleted += result.RowsAffected
+	}
+	return deleted, nil
+}
+
+// WithTx returns a repository that runs its operations in tx
+func (r *GormPreferenceRepository) WithTx(tx *gorm.DB) PreferenceRepository {
+	return &GormPreferenceRepository{db: tx}
+}
+
+// AutoMigrate automatically migrates the notification preference models
+func (r *GormPreferenceRepository) AutoMigrate() error {
+	return r.db.AutoMigrate(&model.NotificationPreference{}, &model.ChannelPreference{}, &model.MarketingConsentRecord{})
+}
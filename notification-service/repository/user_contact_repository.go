@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/ticket-system/notification-service/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UserContactRepository defines the interface for user contact repository operations
+type UserContactRepository interface {
+	Save(contact *model.UserContact) error
+	FindByUserID(userID uuid.UUID) (*model.UserContact, error)
+	Delete(userID uuid.UUID) error
+
+	// Transactions
+	WithTx(tx *gorm.DB) UserContactRepository
+
+	// Auto-migration
+	AutoMigrate() error
+}
+
+// GormUserContactRepository implements UserContactRepository using GORM
+type GormUserContactRepository struct {
+	db *gorm.DB
+}
+
+// NewUserContactRepository creates a new user contact repository
+func NewUserContactRepository(db *gorm.DB) UserContactRepository {
+	return &GormUserContactRepository{db: db}
+}
+
+// Save creates the contact of a user or replaces its address
+func (r *GormUserContactRepository) Save(contact *model.UserContact) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"email", "updated_at"}),
+	}).Create(contact).Error
+}
+
+// FindByUserID finds the contact of a user, or nil when there is none
+func (r *GormUserContactRepository) FindByUserID(userID uuid.UUID) (*model.UserContact, error) {
+	var contact model.UserContact
+	result := r.db.Where("user_id = ?", userID).First(&contact)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &contact, nil
+}
+
+// Delete deletes the contact of a user
+func (r *GormUserContactRepository) Delete(userID uuid.UUID) error {
+	return r.db.Where("user_id = ?", userID).Delete(&model.UserContact{}).Error
+}
+
+// WithTx returns a repository that runs its operations in tx
+func (r *GormUserContactRepository) WithTx(tx *gorm.DB) UserContactRepository {
+	return &GormUserContactRepository{db: tx}
+}
+
+// AutoMigrate automatically migrates the user contact model
+func (r *GormUserContactRepository) AutoMigrate() error {
+	return r.db.AutoMigrate(&model.UserContact{})
+}
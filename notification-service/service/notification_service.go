@@ -2,14 +2,18 @@ package service
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
-	"github.com/streadway/amqp"
+	"github.com/yourusername/ticket-system/contracts"
+	"github.com/yourusername/ticket-system/messaging"
+	"gorm.io/gorm"
 
-	"notification-service/config"
 	"notification-service/model"
 	"notification-service/provider"
 	"notification-service/repository"
@@ -32,31 +36,58 @@ type NotificationService interface {
 	HandlePaymentEvent(msg []byte) error
 	HandleTicketEvent(msg []byte) error
 	HandleUserEvent(msg []byte) error
+	WithTx(tx *gorm.DB) NotificationService
 }
 
 // NotificationServiceImpl implements NotificationService
 type NotificationServiceImpl struct {
-	Repo         repository.NotificationRepository
-	RabbitMQ     *config.RabbitMQ
+	Repo          repository.NotificationRepository
+	ContactRepo   repository.UserContactRepository
+	OutboxRepo    messaging.OutboxRepository
 	EmailProvider provider.EmailProvider
 	SMSProvider   provider.SMSProvider
 	PushProvider  provider.PushProvider
+	Preferences   PreferenceService
+
+	// Tx is the transaction events are written to the outbox in, or nil
+	// outside of consumers
+	Tx *gorm.DB
 }
 
 // NewNotificationService creates a new notification service
 func NewNotificationService(
 	repo repository.NotificationRepository,
-	rabbitMQ *config.RabbitMQ,
+	contactRepo repository.UserContactRepository,
+	outboxRepo messaging.OutboxRepository,
 	emailProvider provider.EmailProvider,
 	smsProvider provider.SMSProvider,
 	pushProvider provider.PushProvider,
+	preferences PreferenceService,
 ) NotificationService {
 	return &NotificationServiceImpl{
 		Repo:          repo,
-		RabbitMQ:      rabbitMQ,
+		ContactRepo:   contactRepo,
+		OutboxRepo:    outboxRepo,
 		EmailProvider: emailProvider,
 		SMSProvider:   smsProvider,
 		PushProvider:  pushProvider,
+		Preferences:   preferences,
+	}
+}
+
+// WithTx returns a service that stores notifications and writes events to the
+// outbox in tx, so that consumers can handle a message in the transaction that
+// records it as processed
+func (s *NotificationServiceImpl) WithTx(tx *gorm.DB) NotificationService {
+	return &NotificationServiceImpl{
+		Repo:          s.Repo.WithTx(tx),
+		ContactRepo:   s.ContactRepo.WithTx(tx),
+		OutboxRepo:    s.OutboxRepo,
+		EmailProvider: s.EmailProvider,
+		SMSProvider:   s.SMSProvider,
+		PushProvider:  s.PushProvider,
+		Preferences:   s.Preferences.WithTx(tx),
+		Tx:            tx,
 	}
 }
 
@@ -75,9 +106,17 @@ func (s *NotificationServiceImpl) CreateNotification(req model.CreateNotificatio
 		return nil, fmt.Errorf("notification channel is required")
 	}
 
+	// Respect what the user opted out of
+	userID, err := uuid.Parse(req.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+	if err := s.Preferences.CheckAllowed(userID, req.Type, req.Channel); err != nil {
+		return nil, err
+	}
+
 	// If template is specified, get it
 	var template *model.NotificationTemplate
-	var err error
 	if req.TemplateID != "" {
 		template, err = s.GetTemplateByID(req.TemplateID)
 		if err != nil {
@@ -344,82 +383,129 @@ func (s *NotificationServiceImpl) DeleteTemplate(id string) error {
 
 // HandlePaymentEvent handles payment events from RabbitMQ
 func (s *NotificationServiceImpl) HandlePaymentEvent(msg []byte) error {
-	// Parse event
-	var event map[string]interface{}
-	if err := json.Unmarshal(msg, &event); err != nil {
+	envelope, err := contracts.Parse(msg)
+	if err != nil {
 		return fmt.Errorf("failed to parse payment event: %w", err)
 	}
 
-	// Extract event type
-	eventType, ok := event["event_type"].(string)
-	if !ok {
-		return fmt.Errorf("invalid payment event: missing event_type")
-	}
-
 	// Handle different event types
-	switch eventType {
-	case "payment_success":
+	switch envelope.Type {
+	case contracts.TypePaymentCompleted:
+		var event contracts.PaymentCompleted
+		if err := envelope.Decode(&event); err != nil {
+			return fmt.Errorf("failed to parse payment event: %w", err)
+		}
 		return s.handlePaymentSuccess(event)
-	case "payment_failed":
+	case contracts.TypePaymentFailed:
+		var event contracts.PaymentFailed
+		if err := envelope.Decode(&event); err != nil {
+			return fmt.Errorf("failed to parse payment event: %w", err)
+		}
 		return s.handlePaymentFailed(event)
-	case "payment_refunded":
+	case contracts.TypePaymentRefunded:
+		var event contracts.PaymentRefunded
+		if err := envelope.Decode(&event); err != nil {
+			return fmt.Errorf("failed to parse payment event: %w", err)
+		}
 		return s.handlePaymentRefunded(event)
 	default:
-		logrus.Warnf("Unknown payment event type: %s", eventType)
+		logrus.Debugf("Ignoring payment event type: %s", envelope.Type)
 		return nil
 	}
 }
 
 // HandleTicketEvent handles ticket events from RabbitMQ
 func (s *NotificationServiceImpl) HandleTicketEvent(msg []byte) error {
-	// Parse event
-	var event map[string]interface{}
-	if err := json.Unmarshal(msg, &event); err != nil {
+	envelope, err := contracts.Parse(msg)
+	if err != nil {
 		return fmt.Errorf("failed to parse ticket event: %w", err)
 	}
 
-	// Extract event type
-	eventType, ok := event["event_type"].(string)
-	if !ok {
-		return fmt.Errorf("invalid ticket event: missing event_type")
-	}
-
 	// Handle different event types
-	switch eventType {
-	case "ticket_booked":
+	switch envelope.Type {
+	case contracts.TypeBookingConfirmed:
+		var event contracts.BookingConfirmed
+		if err := envelope.Decode(&event); err != nil {
+			return fmt.Errorf("failed to parse ticket event: %w", err)
+		}
 		return s.handleTicketBooked(event)
-	case "ticket_cancelled":
+	case contracts.TypeBookingCancelled:
+		var event contracts.BookingCancelled
+		if err := envelope.Decode(&event); err != nil {
+			return fmt.Errorf("failed to parse ticket event: %w", err)
+		}
 		return s.handleTicketCancelled(event)
-	case "event_reminder":
-		return s.handleEventReminder(event)
 	default:
-		logrus.Warnf("Unknown ticket event type: %s", eventType)
+		logrus.Debugf("Ignoring ticket event type: %s", envelope.Type)
 		return nil
 	}
 }
 
 // HandleUserEvent handles user events from RabbitMQ
 func (s *NotificationServiceImpl) HandleUserEvent(msg []byte) error {
-	// Parse event
-	var event map[string]interface{}
-	if err := json.Unmarshal(msg, &event); err != nil {
+	envelope, err := contracts.Parse(msg)
+	if err != nil {
 		return fmt.Errorf("failed to parse user event: %w", err)
 	}
 
-	// Extract event type
-	eventType, ok := event["event_type"].(string)
-	if !ok {
-		return fmt.Errorf("invalid user event: missing event_type")
-	}
-
 	// Handle different event types
-	switch eventType {
-	case "user_registered":
+	switch envelope.Type {
+	case contracts.TypeUserCreated:
+		var event contracts.UserCreated
+		if err := envelope.Decode(&event); err != nil {
+			return fmt.Errorf("failed to parse user event: %w", err)
+		}
 		return s.handleUserRegistered(event)
-	case "password_reset":
+	case contracts.TypeUserUpdated:
+		var event contracts.UserUpdated
+		if err := envelope.Decode(&event); err != nil {
+			return fmt.Errorf("failed to parse user event: %w", err)
+		}
+		return s.saveUserContact(event.UserState)
+	case contracts.TypeUserVerificationRequested:
+		var event contracts.UserVerificationRequested
+		if err := envelope.Decode(&event); err != nil {
+			return fmt.Errorf("failed to parse user event: %w", err)
+		}
+		return s.handleVerificationRequested(event)
+	case contracts.TypeUserPasswordResetRequested:
+		var event contracts.UserPasswordResetRequested
+		if err := envelope.Decode(&event); err != nil {
+			return fmt.Errorf("failed to parse user event: %w", err)
+		}
 		return s.handlePasswordReset(event)
+	case contracts.TypeUserLocked:
+		var event contracts.UserLocked
+		if err := envelope.Decode(&event); err != nil {
+			return fmt.Errorf("failed to parse user event: %w", err)
+		}
+		return s.handleUserLocked(event)
+	case contracts.TypeUserInvited:
+		var event contracts.UserInvited
+		if err := envelope.Decode(&event); err != nil {
+			return fmt.Errorf("failed to parse user event: %w", err)
+		}
+		return s.handleUserInvited(event)
+	case contracts.TypeUserDeleted:
+		var event contracts.UserDeleted
+		if err := envelope.Decode(&event); err != nil {
+			return fmt.Errorf("failed to parse user event: %w", err)
+		}
+		if err := s.ContactRepo.Delete(event.UserID); err != nil {
+			return fmt.Errorf("failed to delete user contact: %w", err)
+		}
+		if _, err := s.Preferences.DeleteByUserID(event.UserID); err != nil {
+			return err
+		}
+		return nil
+	case contracts.TypeUserDataRequested:
+		var event contracts.UserDataRequested
+		if err := envelope.Decode(&event); err != nil {
+			return fmt.Errorf("failed to parse user event: %w", err)
+		}
+		return s.handleDataRequested(event)
 	default:
-		logrus.Warnf("Unknown user event type: %s", eventType)
+		logrus.Debugf("Ignoring user event type: %s", envelope.Type)
 		return nil
 	}
 }
@@ -447,6 +533,15 @@ func (s *NotificationServiceImpl) sendEmailNotification(notification *model.Noti
 		isHTML = htmlFlag
 	}
 
+	// Add the unsubscribe link unless the template already shows it
+	if unsubscribeURL, ok := metadata["unsubscribe_url"].(string); ok && unsubscribeURL != "" && !strings.Contains(notification.Content, unsubscribeURL) {
+		if isHTML {
+			notification.Content += fmt.Sprintf(`<p style="font-size:12px;color:#888"><a href="%s">Unsubscribe</a></p>`, unsubscribeURL)
+		} else {
+			notification.Content += "\n\nUnsubscribe: " + unsubscribeURL
+		}
+	}
+
 	// Send email
 	var err error
 	if isHTML {
@@ -523,7 +618,7 @@ func applyTemplateVariables(template string, variables map[string]string) string
 	return result
 }
 
-// publishNotificationEvent publishes a notification event to RabbitMQ
+// publishNotificationEvent writes a notification event to the outbox
 func (s *NotificationServiceImpl) publishNotificationEvent(eventType string, data map[string]interface{}) error {
 	// Create event
 	event := map[string]interface{}{
@@ -538,94 +633,74 @@ func (s *NotificationServiceImpl) publishNotificationEvent(eventType string, dat
 		return fmt.Errorf("failed to marshal event: %w", err)
 	}
 
-	// Publish to RabbitMQ
-	if err := s.RabbitMQ.PublishMessage("notification_events", "", amqp.Publishing{
-		ContentType: "application/json",
-		Body:        eventBytes,
-	}); err != nil {
-		return fmt.Errorf("failed to publish event: %w", err)
-	}
-
-	return nil
+	return s.enqueue("notification_events", eventType, eventBytes)
 }
 
-// Event handlers
-
-func (s *NotificationServiceImpl) handlePaymentSuccess(event map[string]interface{}) error {
-	// Extract data
-	data, ok := event["data"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("invalid payment event: missing data")
-	}
-
-	userID, _ := data["user_id"].(string)
-	bookingID, _ := data["booking_id"].(string)
-	amount, _ := data["amount"].(float64)
-	currency, _ := data["currency"].(string)
-	email, _ := data["email"].(string)
+// publishPrivacyEvent answers a data request of the user service. The answer
+// is written to the outbox in the transaction of the consumer, so it is only
+// published when the erasure or export it reports is committed.
+func (s *NotificationServiceImpl) publishPrivacyEvent(correlationID string, event contracts.Event) error {
+	return messaging.EnqueueEvent(s.OutboxRepo, s.Tx, "privacy_events", correlationID, event)
+}
 
-	if userID == "" || bookingID == "" || email == "" {
-		return fmt.Errorf("invalid payment event: missing required fields")
+// enqueue writes a message to the outbox in the transaction of the service,
+// from where the outbox relay publishes it
+func (s *NotificationServiceImpl) enqueue(exchange, routingKey string, body []byte) error {
+	message := &messaging.OutboxMessage{
+		ID:            uuid.New(),
+		Exchange:      exchange,
+		RoutingKey:    routingKey,
+		Payload:       body,
+		Status:        messaging.OutboxPending,
+		NextAttemptAt: time.Now(),
 	}
-
-	// Create notification using template
-	variables := map[string]string{
-		"booking_id": bookingID,
-		"amount":     fmt.Sprintf("%.2f %s", amount, currency),
-	}
-
-	req := model.CreateNotificationRequest{
-		UserID:       userID,
-		Type:         model.NotificationTypePayment,
-		Channel:      model.NotificationChannelEmail,
-		TemplateCode: "payment_success",
-		Variables:    variables,
-		Metadata: map[string]interface{}{
-			"email":   email,
-			"is_html": true,
-		},
+	if err := s.OutboxRepo.Enqueue(s.Tx, message); err != nil {
+		return fmt.Errorf("failed to write %s event to outbox: %w", routingKey, err)
 	}
+	return nil
+}
 
-	notification, err := s.CreateNotification(req)
-	if err != nil {
-		return fmt.Errorf("failed to create notification: %w", err)
+// saveUserContact stores the email address of a user
+func (s *NotificationServiceImpl) saveUserContact(user contracts.UserState) error {
+	contact := &model.UserContact{
+		UserID:    user.UserID,
+		Email:     user.Email,
+		UpdatedAt: time.Now(),
 	}
-
-	// Send notification
-	if err := s.SendNotification(notification.ID); err != nil {
-		return fmt.Errorf("failed to send notification: %w", err)
+	if err := s.ContactRepo.Save(contact); err != nil {
+		return fmt.Errorf("failed to save user contact: %w", err)
 	}
-
 	return nil
 }
 
-func (s *NotificationServiceImpl) handlePaymentFailed(event map[string]interface{}) error {
-	// Extract data
-	data, ok := event["data"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("invalid payment event: missing data")
+// userEmail returns the email address of a user, or an empty string when
+// the user is unknown, e.g. because they registered before the notification
+// service kept contacts
+func (s *NotificationServiceImpl) userEmail(userID uuid.UUID) (string, error) {
+	contact, err := s.ContactRepo.FindByUserID(userID)
+	if err != nil {
+		return "", fmt.Errorf("failed to find user contact: %w", err)
 	}
-
-	userID, _ := data["user_id"].(string)
-	bookingID, _ := data["booking_id"].(string)
-	reason, _ := data["reason"].(string)
-	email, _ := data["email"].(string)
-
-	if userID == "" || bookingID == "" || email == "" {
-		return fmt.Errorf("invalid payment event: missing required fields")
+	if contact == nil {
+		logrus.Warnf("No contact for user %s, skipping notification", userID)
+		return "", nil
 	}
+	return contact.Email, nil
+}
 
-	// Create notification using template
-	variables := map[string]string{
-		"booking_id": bookingID,
-		"reason":     reason,
+// sendEmail creates a notification from a template and sends it to the
+// email address of the user
+func (s *NotificationServiceImpl) sendEmail(userID uuid.UUID, notificationType model.NotificationType, templateCode string, variables map[string]string) error {
+	email, err := s.userEmail(userID)
+	if err != nil || email == "" {
+		return err
 	}
 
 	req := model.CreateNotificationRequest{
-		UserID:       userID,
-		Type:         model.NotificationTypePayment,
+		UserID:       userID.String(),
+		Type:         notificationType,
 		Channel:      model.NotificationChannelEmail,
-		TemplateCode: "payment_failed",
+		TemplateCode: s.localizedTemplateCode(userID, templateCode),
 		Variables:    variables,
 		Metadata: map[string]interface{}{
 			"email":   email,
@@ -633,7 +708,16 @@ func (s *NotificationServiceImpl) handlePaymentFailed(event map[string]interface
 		},
 	}
 
+	if unsubscribeURL := s.Preferences.UnsubscribeURL(userID, notificationType, model.NotificationChannelEmail); unsubscribeURL != "" {
+		variables["unsubscribe_url"] = unsubscribeURL
+		req.Metadata["unsubscribe_url"] = unsubscribeURL
+	}
+
 	notification, err := s.CreateNotification(req)
+	if errors.Is(err, ErrNotificationOptedOut) {
+		logrus.Infof("User %s opted out of %s emails, skipping notification", userID, notificationType)
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create notification: %w", err)
 	}
@@ -646,323 +730,248 @@ func (s *NotificationServiceImpl) handlePaymentFailed(event map[string]interface
 	return nil
 }
 
-func (s *NotificationServiceImpl) handlePaymentRefunded(event map[string]interface{}) error {
-	// Extract data
-	data, ok := event["data"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("invalid payment event: missing data")
+// localizedTemplateCode returns the code of the template in the language of
+// the user, e.g. ticket_booked_id, or templateCode when there is no such
+// template
+func (s *NotificationServiceImpl) localizedTemplateCode(userID uuid.UUID, templateCode string) string {
+	preference, err := s.Preferences.FindPreferences(userID)
+	if err != nil {
+		logrus.Warnf("Failed to find preferences of user %s: %v", userID, err)
+		return templateCode
 	}
-
-	userID, _ := data["user_id"].(string)
-	bookingID, _ := data["booking_id"].(string)
-	amount, _ := data["amount"].(float64)
-	currency, _ := data["currency"].(string)
-	email, _ := data["email"].(string)
-
-	if userID == "" || bookingID == "" || email == "" {
-		return fmt.Errorf("invalid payment event: missing required fields")
+	if preference == nil || preference.Language == "" {
+		return templateCode
 	}
 
-	// Create notification using template
-	variables := map[string]string{
-		"booking_id": bookingID,
-		"amount":     fmt.Sprintf("%.2f %s", amount, currency),
+	localized := templateCode + "_" + strings.ToLower(preference.Language)
+	if _, err := s.GetTemplateByCode(localized); err != nil {
+		return templateCode
 	}
+	return localized
+}
 
-	req := model.CreateNotificationRequest{
-		UserID:       userID,
-		Type:         model.NotificationTypePayment,
-		Channel:      model.NotificationChannelEmail,
-		TemplateCode: "payment_refunded",
-		Variables:    variables,
-		Metadata: map[string]interface{}{
-			"email":   email,
-			"is_html": true,
-		},
+// localTime returns t in the timezone of the user, or t unchanged when the
+// user has no timezone
+func (s *NotificationServiceImpl) localTime(userID uuid.UUID, t time.Time) time.Time {
+	preference, err := s.Preferences.FindPreferences(userID)
+	if err != nil || preference == nil || preference.Timezone == "" {
+		return t
 	}
 
-	notification, err := s.CreateNotification(req)
+	location, err := time.LoadLocation(preference.Timezone)
 	if err != nil {
-		return fmt.Errorf("failed to create notification: %w", err)
+		logrus.Warnf("Invalid timezone %q of user %s: %v", preference.Timezone, userID, err)
+		return t
 	}
-
-	// Send notification
-	if err := s.SendNotification(notification.ID); err != nil {
-		return fmt.Errorf("failed to send notification: %w", err)
-	}
-
-	return nil
+	return t.In(location)
 }
 
-func (s *NotificationServiceImpl) handleTicketBooked(event map[string]interface{}) error {
-	// Extract data
-	data, ok := event["data"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("invalid ticket event: missing data")
-	}
-
-	userID, _ := data["user_id"].(string)
-	bookingID, _ := data["booking_id"].(string)
-	eventName, _ := data["event_name"].(string)
-	eventDate, _ := data["event_date"].(string)
-	eventLocation, _ := data["event_location"].(string)
-	ticketCount, _ := data["ticket_count"].(float64)
-	email, _ := data["email"].(string)
-
-	if userID == "" || bookingID == "" || email == "" {
-		return fmt.Errorf("invalid ticket event: missing required fields")
-	}
+// Event handlers
 
-	// Create notification using template
+func (s *NotificationServiceImpl) handlePaymentSuccess(event contracts.PaymentCompleted) error {
 	variables := map[string]string{
-		"booking_id":     bookingID,
-		"event_name":     eventName,
-		"event_date":     eventDate,
-		"event_location": eventLocation,
-		"ticket_count":   fmt.Sprintf("%.0f", ticketCount),
+		"booking_id": event.BookingID.String(),
+		"amount":     fmt.Sprintf("%.2f %s", event.Amount, event.Currency),
 	}
+	return s.sendEmail(event.UserID, model.NotificationTypePaymentConfirmation, "payment_success", variables)
+}
 
-	req := model.CreateNotificationRequest{
-		UserID:       userID,
-		Type:         model.NotificationTypeTicket,
-		Channel:      model.NotificationChannelEmail,
-		TemplateCode: "ticket_booked",
-		Variables:    variables,
-		Metadata: map[string]interface{}{
-			"email":   email,
-			"is_html": true,
-		},
+func (s *NotificationServiceImpl) handlePaymentFailed(event contracts.PaymentFailed) error {
+	variables := map[string]string{
+		"booking_id": event.BookingID.String(),
 	}
+	return s.sendEmail(event.UserID, model.NotificationTypePaymentFailed, "payment_failed", variables)
+}
 
-	notification, err := s.CreateNotification(req)
-	if err != nil {
-		return fmt.Errorf("failed to create notification: %w", err)
+func (s *NotificationServiceImpl) handlePaymentRefunded(event contracts.PaymentRefunded) error {
+	variables := map[string]string{
+		"booking_id": event.BookingID.String(),
+		"amount":     fmt.Sprintf("%.2f %s", event.RefundAmount, event.Currency),
 	}
+	return s.sendEmail(event.UserID, model.NotificationTypeRefundProcessed, "payment_refunded", variables)
+}
 
-	// Send notification
-	if err := s.SendNotification(notification.ID); err != nil {
-		return fmt.Errorf("failed to send notification: %w", err)
+func (s *NotificationServiceImpl) handleTicketBooked(event contracts.BookingConfirmed) error {
+	variables := map[string]string{
+		"booking_id":     event.BookingID.String(),
+		"event_name":     event.EventName,
+		"event_date":     s.localTime(event.UserID, event.EventStartDate).Format("2006-01-02 15:04 MST"),
+		"event_location": event.EventLocation,
+		"ticket_count":   fmt.Sprintf("%d", event.TicketCount),
 	}
-
-	return nil
+	return s.sendEmail(event.UserID, model.NotificationTypeBookingConfirmation, "ticket_booked", variables)
 }
 
-func (s *NotificationServiceImpl) handleTicketCancelled(event map[string]interface{}) error {
-	// Extract data
-	data, ok := event["data"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("invalid ticket event: missing data")
+func (s *NotificationServiceImpl) handleTicketCancelled(event contracts.BookingCancelled) error {
+	variables := map[string]string{
+		"booking_id":    event.BookingID.String(),
+		"refund_amount": fmt.Sprintf("%.2f", event.RefundAmount),
+		"reason":        event.RefundReason,
 	}
+	return s.sendEmail(event.UserID, model.NotificationTypeBookingCancelled, "ticket_cancelled", variables)
+}
 
-	userID, _ := data["user_id"].(string)
-	bookingID, _ := data["booking_id"].(string)
-	eventName, _ := data["event_name"].(string)
-	reason, _ := data["reason"].(string)
-	email, _ := data["email"].(string)
-
-	if userID == "" || bookingID == "" || email == "" {
-		return fmt.Errorf("invalid ticket event: missing required fields")
+func (s *NotificationServiceImpl) handleUserRegistered(event contracts.UserCreated) error {
+	// Payment and booking events only carry the user ID
+	if err := s.saveUserContact(event.UserState); err != nil {
+		return err
 	}
 
-	// Create notification using template
 	variables := map[string]string{
-		"booking_id": bookingID,
-		"event_name": eventName,
-		"reason":     reason,
-	}
-
-	req := model.CreateNotificationRequest{
-		UserID:       userID,
-		Type:         model.NotificationTypeTicket,
-		Channel:      model.NotificationChannelEmail,
-		TemplateCode: "ticket_cancelled",
-		Variables:    variables,
-		Metadata: map[string]interface{}{
-			"email":   email,
-			"is_html": true,
-		},
+		"username": event.Email,
 	}
+	return s.sendEmail(event.UserID, model.NotificationTypeWelcome, "welcome_email", variables)
+}
 
-	notification, err := s.CreateNotification(req)
-	if err != nil {
-		return fmt.Errorf("failed to create notification: %w", err)
+func (s *NotificationServiceImpl) handleVerificationRequested(event contracts.UserVerificationRequested) error {
+	// The link must reach the address it verifies, which may be newer than
+	// the stored contact
+	if err := s.saveUserContact(event.UserState); err != nil {
+		return err
 	}
 
-	// Send notification
-	if err := s.SendNotification(notification.ID); err != nil {
-		return fmt.Errorf("failed to send notification: %w", err)
+	variables := map[string]string{
+		"username":         event.Email,
+		"verification_url": event.VerificationURL,
+		"expires_at":       s.localTime(event.UserID, event.ExpiresAt).Format(time.RFC1123),
 	}
-
-	return nil
+	return s.sendEmail(event.UserID, model.NotificationTypeEmailVerification, "email_verification", variables)
 }
 
-func (s *NotificationServiceImpl) handleEventReminder(event map[string]interface{}) error {
-	// Extract data
-	data, ok := event["data"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("invalid ticket event: missing data")
+func (s *NotificationServiceImpl) handlePasswordReset(event contracts.UserPasswordResetRequested) error {
+	if err := s.saveUserContact(event.UserState); err != nil {
+		return err
 	}
 
-	userID, _ := data["user_id"].(string)
-	eventName, _ := data["event_name"].(string)
-	eventDate, _ := data["event_date"].(string)
-	eventLocation, _ := data["event_location"].(string)
-	email, _ := data["email"].(string)
-	phone, _ := data["phone"].(string)
-
-	if userID == "" || eventName == "" || eventDate == "" || (email == "" && phone == "") {
-		return fmt.Errorf("invalid event reminder: missing required fields")
-	}
-
-	// Create variables
 	variables := map[string]string{
-		"event_name":     eventName,
-		"event_date":     eventDate,
-		"event_location": eventLocation,
-	}
-
-	// Send email reminder if email is provided
-	if email != "" {
-		emailReq := model.CreateNotificationRequest{
-			UserID:       userID,
-			Type:         model.NotificationTypeReminder,
-			Channel:      model.NotificationChannelEmail,
-			TemplateCode: "event_reminder",
-			Variables:    variables,
-			Metadata: map[string]interface{}{
-				"email":   email,
-				"is_html": true,
-			},
-		}
-
-		notification, err := s.CreateNotification(emailReq)
-		if err != nil {
-			logrus.Errorf("Failed to create email reminder: %v", err)
-		} else {
-			if err := s.SendNotification(notification.ID); err != nil {
-				logrus.Errorf("Failed to send email reminder: %v", err)
-			}
-		}
-	}
-
-	// Send SMS reminder if phone is provided
-	if phone != "" {
-		smsReq := model.CreateNotificationRequest{
-			UserID:       userID,
-			Type:         model.NotificationTypeReminder,
-			Channel:      model.NotificationChannelSMS,
-			TemplateCode: "event_reminder_sms",
-			Variables:    variables,
-			Metadata: map[string]interface{}{
-				"phone": phone,
-			},
-		}
-
-		notification, err := s.CreateNotification(smsReq)
-		if err != nil {
-			logrus.Errorf("Failed to create SMS reminder: %v", err)
-		} else {
-			if err := s.SendNotification(notification.ID); err != nil {
-				logrus.Errorf("Failed to send SMS reminder: %v", err)
-			}
-		}
+		"username":   event.Email,
+		"reset_url":  event.ResetURL,
+		"expires_at": s.localTime(event.UserID, event.ExpiresAt).Format(time.RFC1123),
 	}
-
-	return nil
+	return s.sendEmail(event.UserID, model.NotificationTypePasswordReset, "password_reset", variables)
 }
 
-func (s *NotificationServiceImpl) handleUserRegistered(event map[string]interface{}) error {
-	// Extract data
-	data, ok := event["data"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("invalid user event: missing data")
+// handleUserLocked alerts a user that their account was locked after too
+// many failed logins
+func (s *NotificationServiceImpl) handleUserLocked(event contracts.UserLocked) error {
+	if err := s.saveUserContact(event.UserState); err != nil {
+		return err
 	}
 
-	userID, _ := data["user_id"].(string)
-	username, _ := data["username"].(string)
-	email, _ := data["email"].(string)
-
-	if userID == "" || email == "" {
-		return fmt.Errorf("invalid user event: missing required fields")
-	}
-
-	// Create notification using template
 	variables := map[string]string{
-		"username": username,
-	}
-
-	req := model.CreateNotificationRequest{
-		UserID:       userID,
-		Type:         model.NotificationTypeUser,
-		Channel:      model.NotificationChannelEmail,
-		TemplateCode: "welcome_email",
-		Variables:    variables,
-		Metadata: map[string]interface{}{
-			"email":   email,
-			"is_html": true,
-		},
+		"username":        event.Email,
+		"failed_attempts": strconv.Itoa(event.FailedAttempts),
+		"ip_address":      event.IPAddress,
+		"locked_until":    s.localTime(event.UserID, event.LockedUntil).Format(time.RFC1123),
 	}
+	return s.sendEmail(event.UserID, model.NotificationTypeSecurityAlert, "account_locked", variables)
+}
 
-	notification, err := s.CreateNotification(req)
-	if err != nil {
-		return fmt.Errorf("failed to create notification: %w", err)
+// handleUserInvited sends a staff member whose account an admin created the
+// link to set their password
+func (s *NotificationServiceImpl) handleUserInvited(event contracts.UserInvited) error {
+	if err := s.saveUserContact(event.UserState); err != nil {
+		return err
 	}
 
-	// Send notification
-	if err := s.SendNotification(notification.ID); err != nil {
-		return fmt.Errorf("failed to send notification: %w", err)
+	variables := map[string]string{
+		"username":   event.Email,
+		"role":       event.Role,
+		"setup_url":  event.SetupURL,
+		"expires_at": s.localTime(event.UserID, event.ExpiresAt).Format(time.RFC1123),
 	}
-
-	return nil
+	// The invitation is a password reset link
+	return s.sendEmail(event.UserID, model.NotificationTypePasswordReset, "staff_invitation", variables)
 }
 
-func (s *NotificationServiceImpl) handlePasswordReset(event map[string]interface{}) error {
-	// Extract data
-	data, ok := event["data"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("invalid user event: missing data")
-	}
+// handleDataRequested collects or erases the contact and notifications of a
+// user and answers the user service
+func (s *NotificationServiceImpl) handleDataRequested(event contracts.UserDataRequested) error {
+	var answer contracts.Event
+	switch event.Mode {
+	case contracts.DataRequestExport:
+		export := model.PrivacyDataExport{
+			Notifications: []model.Notification{},
+			ExportedAt:    time.Now(),
+		}
 
-	userID, _ := data["user_id"].(string)
-	email, _ := data["email"].(string)
-	resetToken, _ := data["reset_token"].(string)
-	resetURL, _ := data["reset_url"].(string)
+		contact, err := s.ContactRepo.FindByUserID(event.UserID)
+		if err != nil {
+			return fmt.Errorf("failed to find user contact: %w", err)
+		}
+		export.Contact = contact
 
-	if userID == "" || email == "" || (resetToken == "" && resetURL == "") {
-		return fmt.Errorf("invalid password reset event: missing required fields")
-	}
+		export.Preferences, err = s.Preferences.GetPreferences(event.UserID)
+		if err != nil {
+			return err
+		}
+		export.ConsentRecords, err = s.Preferences.GetConsentRecords(event.UserID)
+		if err != nil {
+			return err
+		}
 
-	// Create reset URL if only token is provided
-	if resetURL == "" && resetToken != "" {
-		resetURL = fmt.Sprintf("https://example.com/reset-password?token=%s", resetToken)
-	}
+		for page := 1; ; page++ {
+			notifications, total, err := s.Repo.FindByUserID(event.UserID, page, 100)
+			if err != nil {
+				return fmt.Errorf("failed to find notifications: %w", err)
+			}
+			for _, notification := range notifications {
+				export.Notifications = append(export.Notifications, *notification)
+			}
+			if len(notifications) == 0 || int64(page*100) >= total {
+				break
+			}
+		}
 
-	// Create notification using template
-	variables := map[string]string{
-		"reset_url": resetURL,
-	}
+		data, err := json.Marshal(export)
+		if err != nil {
+			return fmt.Errorf("failed to marshal data export: %w", err)
+		}
+		answer = contracts.PrivacyDataCollected{
+			RequestID: event.RequestID,
+			UserID:    event.UserID,
+			Service:   model.PrivacyService,
+			Data:      data,
+		}
+	case contracts.DataRequestErasure:
+		contact, err := s.ContactRepo.FindByUserID(event.UserID)
+		if err != nil {
+			return fmt.Errorf("failed to find user contact: %w", err)
+		}
+		if err := s.ContactRepo.Delete(event.UserID); err != nil {
+			return fmt.Errorf("failed to delete user contact: %w", err)
+		}
 
-	req := model.CreateNotificationRequest{
-		UserID:       userID,
-		Type:         model.NotificationTypeUser,
-		Channel:      model.NotificationChannelEmail,
-		TemplateCode: "password_reset",
-		Variables:    variables,
-		Metadata: map[string]interface{}{
-			"email":   email,
-			"is_html": true,
-		},
-	}
+		erased, err := s.Repo.DeleteByUserID(event.UserID)
+		if err != nil {
+			return fmt.Errorf("failed to delete notifications: %w", err)
+		}
+		if contact != nil {
+			erased++
+		}
 
-	notification, err := s.CreateNotification(req)
-	if err != nil {
-		return fmt.Errorf("failed to create notification: %w", err)
-	}
+		deleted, err := s.Preferences.DeleteByUserID(event.UserID)
+		if err != nil {
+			return err
+		}
+		erased += deleted
 
-	// Send notification
-	if err := s.SendNotification(notification.ID); err != nil {
-		return fmt.Errorf("failed to send notification: %w", err)
+		answer = contracts.PrivacyDataErased{
+			RequestID: event.RequestID,
+			UserID:    event.UserID,
+			Service:   model.PrivacyService,
+			Erased:    int(erased),
+		}
+	default:
+		logrus.Warnf("Ignoring data request with unknown mode: %s", event.Mode)
+		return nil
 	}
 
-	return nil
-}
\ No newline at end of file
+	logrus.WithFields(logrus.Fields{
+		"request_id": event.RequestID,
+		"user_id":    event.UserID,
+		"mode":       event.Mode,
+	}).Info("Answering data request")
+
+	return s.publishPrivacyEvent(event.RequestID.String(), answer)
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/ticket-system/contracts"
+	"github.com/yourusername/ticket-system/messaging"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"notification-service/model"
+	"notification-service/provider"
+	"notification-service/repository"
+)
+
+// setupTestDB opens an in-memory database with the notification tables
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	// Every connection opens its own in-memory database
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	require.NoError(t, repository.NewNotificationRepository(db).AutoMigrate())
+	require.NoError(t, repository.NewUserContactRepository(db).AutoMigrate())
+	require.NoError(t, repository.NewPreferenceRepository(db).AutoMigrate())
+	return db
+}
+
+// setupNotificationService creates a notification service that sends through
+// mock providers
+func setupNotificationService(t *testing.T, db *gorm.DB) (NotificationService, *provider.MockEmailProvider) {
+	emailProvider := provider.NewMockEmailProvider()
+	preferenceService := setupPreferenceService(t, db)
+	notificationService := NewNotificationService(
+		repository.NewNotificationRepository(db),
+		repository.NewUserContactRepository(db),
+		messaging.NewOutboxRepository(db),
+		emailProvider,
+		provider.NewMockSMSProvider(),
+		provider.NewMockPushProvider(),
+		preferenceService,
+	)
+	return notificationService, emailProvider
+}
+
+// saveContact stores the email address of a new user
+func saveContact(t *testing.T, db *gorm.DB) uuid.UUID {
+	userID := uuid.New()
+	require.NoError(t, repository.NewUserContactRepository(db).Save(&model.UserContact{
+		UserID:    userID,
+		Email:     "pengguna@example.com",
+		UpdatedAt: time.Now(),
+	}))
+	return userID
+}
+
+// userEvent wraps a user event in the envelope of the event contracts
+func userEvent(t *testing.T, event contracts.Event) []byte {
+	body, err := contracts.Marshal(uuid.NewString(), uuid.NewString(), event)
+	require.NoError(t, err)
+	return body
+}
+
+// outboxMessages returns the messages written to the outbox with a routing key
+func outboxMessages(t *testing.T, db *gorm.DB, routingKey string) []messaging.OutboxMessage {
+	var messages []messaging.OutboxMessage
+	require.NoError(t, db.Where("routing_key = ?", routingKey).Find(&messages).Error)
+	return messages
+}
+
+func TestNotificationService_DataErasureAnswerIsWrittenToOutbox(t *testing.T) {
+	db := setupTestDB(t)
+	notificationService, _ := setupNotificationService(t, db)
+	inbox := messaging.NewInbox(messaging.NewInboxRepository(db))
+
+	userID := saveContact(t, db)
+	request := contracts.UserDataRequested{RequestID: uuid.New(), UserID: userID, Mode: contracts.DataRequestErasure}
+	body := userEvent(t, request)
+
+	handle := func() error {
+		return inbox.Handle("notification_user_events", "message-1", func(tx *gorm.DB) error {
+			return notificationService.WithTx(tx).HandleUserEvent(body)
+		})
+	}
+	require.NoError(t, handle())
+
+	messages := outboxMessages(t, db, contracts.TypePrivacyDataErased)
+	require.Len(t, messages, 1)
+	assert.Equal(t, "privacy_events", messages[0].Exchange)
+	assert.Equal(t, messaging.OutboxPending, messages[0].Status)
+
+	envelope, err := contracts.Parse(messages[0].Payload)
+	require.NoError(t, err)
+	assert.Equal(t, messages[0].ID.String(), envelope.ID)
+	assert.Equal(t, request.RequestID.String(), envelope.CorrelationID)
+
+	var answer contracts.PrivacyDataErased
+	require.NoError(t, envelope.Decode(&answer))
+	assert.Equal(t, request.RequestID, answer.RequestID)
+	assert.Equal(t, 1, answer.Erased)
+
+	// A redelivered request is not answered again
+	require.NoError(t, handle())
+	assert.Len(t, outboxMessages(t, db, contracts.TypePrivacyDataErased), 1)
+}
+
+func TestNotificationService_FailedHandlingDiscardsAnswer(t *testing.T) {
+	db := setupTestDB(t)
+	notificationService, _ := setupNotificationService(t, db)
+	inbox := messaging.NewInbox(messaging.NewInboxRepository(db))
+
+	userID := saveContact(t, db)
+	body := userEvent(t, contracts.UserDataRequested{RequestID: uuid.New(), UserID: userID, Mode: contracts.DataRequestErasure})
+
+	// The consumer fails after the answer was written
+	err := inbox.Handle("notification_user_events", "message-1", func(tx *gorm.DB) error {
+		if err := notificationService.WithTx(tx).HandleUserEvent(body); err != nil {
+			return err
+		}
+		return errors.New("connection reset")
+	})
+	assert.EqualError(t, err, "connection reset")
+
+	// Neither the erasure nor its answer is kept
+	assert.Empty(t, outboxMessages(t, db, contracts.TypePrivacyDataErased))
+	contact, err := repository.NewUserContactRepository(db).FindByUserID(userID)
+	require.NoError(t, err)
+	assert.NotNil(t, contact)
+}
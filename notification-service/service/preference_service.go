@@ -0,0 +1,340 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"notification-service/model"
+	"notification-service/repository"
+)
+
+// ErrNotificationOptedOut is returned when a notification is created for a
+// user who opted out of its type on its channel
+var ErrNotificationOptedOut = errors.New("user opted out of this notification")
+
+// ErrMandatoryNotificationType is returned when a user tries to opt out of a
+// mandatory notification type
+var ErrMandatoryNotificationType = errors.New("notification type cannot be disabled")
+
+// mandatoryNotificationTypes are needed to use and secure an account and
+// cannot be opted out of
+var mandatoryNotificationTypes = map[model.NotificationType]bool{
+	model.NotificationTypeEmailVerification: true,
+	model.NotificationTypePasswordReset:     true,
+	model.NotificationTypeSecurityAlert:     true,
+}
+
+// PreferenceService defines the interface for notification preference operations
+type PreferenceService interface {
+	GetPreferences(userID uuid.UUID) (*model.PreferencesResponse, error)
+	FindPreferences(userID uuid.UUID) (*model.NotificationPreference, error)
+	UpdatePreferences(userID uuid.UUID, req model.UpdatePreferencesRequest) (*model.PreferencesResponse, error)
+	SetMarketingConsent(userID uuid.UUID, consent bool, source, ipAddress string) (*model.PreferencesResponse, error)
+	GetConsentRecords(userID uuid.UUID) ([]model.MarketingConsentRecord, error)
+	CheckAllowed(userID uuid.UUID, notificationType model.NotificationType, channel model.NotificationChannel) error
+	UnsubscribeURL(userID uuid.UUID, notificationType model.NotificationType, channel model.NotificationChannel) string
+	ResolveUnsubscribe(token string) (*model.UnsubscribeTarget, error)
+	Unsubscribe(token, ipAddress string) (*model.UnsubscribeTarget, error)
+	DeleteByUserID(userID uuid.UUID) (int64, error)
+	WithTx(tx *gorm.DB) PreferenceService
+}
+
+// preferenceService implements PreferenceService interface
+type preferenceService struct {
+	repo              repository.PreferenceRepository
+	unsubscribeSecret []byte
+	unsubscribeURL    string
+}
+
+// NewPreferenceService creates a new preference service. Unsubscribe links
+// point to unsubscribeURL and are signed with unsubscribeSecret, which is
+// required, so that every email that can be opted out of carries one.
+func NewPreferenceService(repo repository.PreferenceRepository, unsubscribeSecret []byte, unsubscribeURL string) (PreferenceService, error) {
+	if len(unsubscribeSecret) == 0 {
+		return nil, errors.New("unsubscribe secret is required")
+	}
+
+	return &preferenceService{
+		repo:              repo,
+		unsubscribeSecret: unsubscribeSecret,
+		unsubscribeURL:    unsubscribeURL,
+	}, nil
+}
+
+// WithTx returns a service that stores preferences in tx
+func (s *preferenceService) WithTx(tx *gorm.DB) PreferenceService {
+	return &preferenceService{
+		repo:              s.repo.WithTx(tx),
+		unsubscribeSecret: s.unsubscribeSecret,
+		unsubscribeURL:    s.unsubscribeURL,
+	}
+}
+
+// GetPreferences returns the preferences of a user, which are the defaults
+// when the user never changed them
+func (s *preferenceService) GetPreferences(userID uuid.UUID) (*model.PreferencesResponse, error) {
+	preference, err := s.FindPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	channels, err := s.repo.FindChannels(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find channel preferences: %w", err)
+	}
+
+	response := &model.PreferencesResponse{Channels: channels}
+	if response.Channels == nil {
+		response.Channels = []model.ChannelPreference{}
+	}
+	if preference != nil {
+		response.Language = preference.Language
+		response.Timezone = preference.Timezone
+		response.MarketingConsent = preference.MarketingConsent
+		response.MarketingConsentAt = preference.MarketingConsentAt
+	}
+	return response, nil
+}
+
+// FindPreferences returns the stored preferences of a user, or nil when the
+// user never changed them
+func (s *preferenceService) FindPreferences(userID uuid.UUID) (*model.NotificationPreference, error) {
+	preference, err := s.repo.FindByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find preferences: %w", err)
+	}
+	return preference, nil
+}
+
+// UpdatePreferences changes the language, timezone and channel preferences
+// of a user
+func (s *preferenceService) UpdatePreferences(userID uuid.UUID, req model.UpdatePreferencesRequest) (*model.PreferencesResponse, error) {
+	// Check the whole request before storing any of it
+	for _, channel := range req.Channels {
+		if mandatoryNotificationTypes[channel.Type] {
+			return nil, fmt.Errorf("%w: %s", ErrMandatoryNotificationType, channel.Type)
+		}
+	}
+
+	preference, err := s.preferenceOrDefault(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Language != nil {
+		preference.Language = *req.Language
+	}
+	if req.Timezone != nil {
+		preference.Timezone = *req.Timezone
+	}
+	preference.UpdatedAt = time.Now()
+
+	if err := s.repo.Save(preference); err != nil {
+		return nil, fmt.Errorf("failed to save preferences: %w", err)
+	}
+
+	for _, channel := range req.Channels {
+		if err := s.repo.SaveChannel(&model.ChannelPreference{
+			UserID:  userID,
+			Type:    channel.Type,
+			Channel: channel.Channel,
+			Enabled: *channel.Enabled,
+		}); err != nil {
+			return nil, fmt.Errorf("failed to save channel preference: %w", err)
+		}
+	}
+
+	return s.GetPreferences(userID)
+}
+
+// SetMarketingConsent gives or withdraws the marketing consent of a user and
+// records the change with its source and time
+func (s *preferenceService) SetMarketingConsent(userID uuid.UUID, consent bool, source, ipAddress string) (*model.PreferencesResponse, error) {
+	preference, err := s.preferenceOrDefault(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if preference.MarketingConsentAt == nil || preference.MarketingConsent != consent {
+		now := time.Now()
+		preference.MarketingConsent = consent
+		preference.MarketingConsentAt = &now
+		preference.UpdatedAt = now
+
+		if err := s.repo.Save(preference); err != nil {
+			return nil, fmt.Errorf("failed to save preferences: %w", err)
+		}
+
+		if err := s.repo.CreateConsentRecord(&model.MarketingConsentRecord{
+			UserID:    userID,
+			Consent:   consent,
+			Source:    source,
+			IPAddress: ipAddress,
+			CreatedAt: now,
+		}); err != nil {
+			return nil, fmt.Errorf("failed to record marketing consent: %w", err)
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"user_id": userID,
+			"consent": consent,
+			"source":  source,
+		}).Info("Marketing consent changed")
+	}
+
+	return s.GetPreferences(userID)
+}
+
+// GetConsentRecords returns the marketing consent history of a user
+func (s *preferenceService) GetConsentRecords(userID uuid.UUID) ([]model.MarketingConsentRecord, error) {
+	records, err := s.repo.FindConsentRecords(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find consent records: %w", err)
+	}
+	return records, nil
+}
+
+// CheckAllowed returns ErrNotificationOptedOut when the user opted out of a
+// notification type on a channel. Marketing is only allowed with consent.
+func (s *preferenceService) CheckAllowed(userID uuid.UUID, notificationType model.NotificationType, channel model.NotificationChannel) error {
+	if mandatoryNotificationTypes[notificationType] {
+		return nil
+	}
+
+	if notificationType == model.NotificationTypeMarketing {
+		preference, err := s.FindPreferences(userID)
+		if err != nil {
+			return err
+		}
+		if preference == nil || !preference.MarketingConsent {
+			return ErrNotificationOptedOut
+		}
+	}
+
+	channelPreference, err := s.repo.FindChannel(userID, notificationType, channel)
+	if err != nil {
+		return fmt.Errorf("failed to find channel preference: %w", err)
+	}
+	if channelPreference != nil && !channelPreference.Enabled {
+		return ErrNotificationOptedOut
+	}
+
+	return nil
+}
+
+// UnsubscribeURL returns the one-click link that opts the user out of a
+// notification type on a channel, or an empty string when the type cannot be
+// opted out of
+func (s *preferenceService) UnsubscribeURL(userID uuid.UUID, notificationType model.NotificationType, channel model.NotificationChannel) string {
+	if mandatoryNotificationTypes[notificationType] {
+		return ""
+	}
+
+	payload, err := json.Marshal(model.UnsubscribeTarget{UserID: userID, Type: notificationType, Channel: channel})
+	if err != nil {
+		logrus.Errorf("Failed to marshal unsubscribe target: %v", err)
+		return ""
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	token := encoded + "." + base64.RawURLEncoding.EncodeToString(s.unsubscribeMAC(encoded))
+	return s.unsubscribeURL + "?token=" + url.QueryEscape(token)
+}
+
+// ResolveUnsubscribe checks the signature of an unsubscribe token and returns
+// what it opts out of
+func (s *preferenceService) ResolveUnsubscribe(token string) (*model.UnsubscribeTarget, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, errors.New("invalid unsubscribe link")
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, s.unsubscribeMAC(encoded)) {
+		return nil, errors.New("invalid unsubscribe link")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.New("invalid unsubscribe link")
+	}
+
+	var target model.UnsubscribeTarget
+	if err := json.Unmarshal(payload, &target); err != nil || target.UserID == uuid.Nil {
+		return nil, errors.New("invalid unsubscribe link")
+	}
+	return &target, nil
+}
+
+// Unsubscribe opts the user of an unsubscribe token out of its notification
+// type on its channel. Unsubscribing from marketing also withdraws the
+// marketing consent. Unsubscribing again changes nothing.
+func (s *preferenceService) Unsubscribe(token, ipAddress string) (*model.UnsubscribeTarget, error) {
+	target, err := s.ResolveUnsubscribe(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if target.Type == model.NotificationTypeMarketing {
+		if _, err := s.SetMarketingConsent(target.UserID, false, model.ConsentSourceUnsubscribe, ipAddress); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.SaveChannel(&model.ChannelPreference{
+		UserID:  target.UserID,
+		Type:    target.Type,
+		Channel: target.Channel,
+		Enabled: false,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to save channel preference: %w", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"user_id": target.UserID,
+		"type":    target.Type,
+		"channel": target.Channel,
+	}).Info("User unsubscribed")
+
+	return target, nil
+}
+
+// DeleteByUserID deletes the preferences and consent history of a user
+func (s *preferenceService) DeleteByUserID(userID uuid.UUID) (int64, error) {
+	deleted, err := s.repo.DeleteByUserID(userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete preferences: %w", err)
+	}
+	return deleted, nil
+}
+
+// preferenceOrDefault returns the stored preferences of a user, or new
+// default preferences
+func (s *preferenceService) preferenceOrDefault(userID uuid.UUID) (*model.NotificationPreference, error) {
+	preference, err := s.FindPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+	if preference == nil {
+		preference = &model.NotificationPreference{UserID: userID, CreatedAt: time.Now()}
+	}
+	return preference, nil
+}
+
+// unsubscribeMAC returns the HMAC-SHA256 of an encoded unsubscribe target
+func (s *preferenceService) unsubscribeMAC(encoded string) []byte {
+	mac := hmac.New(sha256.New, s.unsubscribeSecret)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
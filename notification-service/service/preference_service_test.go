@@ -0,0 +1,128 @@
+package service
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"notification-service/model"
+	"notification-service/repository"
+)
+
+// setupPreferenceService creates a preference service signing unsubscribe
+// links with a test secret
+func setupPreferenceService(t *testing.T, db *gorm.DB) PreferenceService {
+	preferenceService, err := NewPreferenceService(repository.NewPreferenceRepository(db), []byte("unsubscribe-secret"), "http://localhost/unsubscribe")
+	require.NoError(t, err)
+	return preferenceService
+}
+
+// channelRequest opts in or out of a notification type on email
+func channelRequest(notificationType model.NotificationType, enabled bool) model.ChannelPreferenceRequest {
+	return model.ChannelPreferenceRequest{Type: notificationType, Channel: model.NotificationChannelEmail, Enabled: &enabled}
+}
+
+// unsubscribeToken returns the token of an unsubscribe link
+func unsubscribeToken(t *testing.T, link string) string {
+	parsed, err := url.Parse(link)
+	require.NoError(t, err)
+	return parsed.Query().Get("token")
+}
+
+func TestNewPreferenceService_RequiresSecret(t *testing.T) {
+	_, err := NewPreferenceService(repository.NewPreferenceRepository(setupTestDB(t)), nil, "http://localhost/unsubscribe")
+	assert.EqualError(t, err, "unsubscribe secret is required")
+}
+
+func TestPreferenceService_SecurityEmailsAreMandatory(t *testing.T) {
+	preferenceService := setupPreferenceService(t, setupTestDB(t))
+	userID := uuid.New()
+
+	for _, notificationType := range []model.NotificationType{
+		model.NotificationTypeEmailVerification,
+		model.NotificationTypePasswordReset,
+		model.NotificationTypeSecurityAlert,
+	} {
+		_, err := preferenceService.UpdatePreferences(userID, model.UpdatePreferencesRequest{
+			Channels: []model.ChannelPreferenceRequest{channelRequest(notificationType, false)},
+		})
+		assert.ErrorIs(t, err, ErrMandatoryNotificationType)
+		assert.NoError(t, preferenceService.CheckAllowed(userID, notificationType, model.NotificationChannelEmail))
+		assert.Empty(t, preferenceService.UnsubscribeURL(userID, notificationType, model.NotificationChannelEmail))
+	}
+
+	// The welcome email is not needed to secure an account
+	_, err := preferenceService.UpdatePreferences(userID, model.UpdatePreferencesRequest{
+		Channels: []model.ChannelPreferenceRequest{channelRequest(model.NotificationTypeWelcome, false)},
+	})
+	require.NoError(t, err)
+	assert.ErrorIs(t, preferenceService.CheckAllowed(userID, model.NotificationTypeWelcome, model.NotificationChannelEmail), ErrNotificationOptedOut)
+	assert.NotEmpty(t, preferenceService.UnsubscribeURL(userID, model.NotificationTypeWelcome, model.NotificationChannelEmail))
+
+	// A request with a mandatory type stores none of its changes
+	_, err = preferenceService.UpdatePreferences(userID, model.UpdatePreferencesRequest{
+		Channels: []model.ChannelPreferenceRequest{
+			channelRequest(model.NotificationTypeWelcome, true),
+			channelRequest(model.NotificationTypePasswordReset, false),
+		},
+	})
+	assert.ErrorIs(t, err, ErrMandatoryNotificationType)
+	assert.ErrorIs(t, preferenceService.CheckAllowed(userID, model.NotificationTypeWelcome, model.NotificationChannelEmail), ErrNotificationOptedOut)
+}
+
+func TestPreferenceService_Unsubscribe(t *testing.T) {
+	preferenceService := setupPreferenceService(t, setupTestDB(t))
+	userID := uuid.New()
+
+	token := unsubscribeToken(t, preferenceService.UnsubscribeURL(userID, model.NotificationTypeBookingConfirmation, model.NotificationChannelEmail))
+	target, err := preferenceService.Unsubscribe(token, "203.0.113.7")
+	require.NoError(t, err)
+	assert.Equal(t, userID, target.UserID)
+	assert.Equal(t, model.NotificationTypeBookingConfirmation, target.Type)
+
+	assert.ErrorIs(t, preferenceService.CheckAllowed(userID, model.NotificationTypeBookingConfirmation, model.NotificationChannelEmail), ErrNotificationOptedOut)
+	assert.NoError(t, preferenceService.CheckAllowed(userID, model.NotificationTypeBookingConfirmation, model.NotificationChannelPush))
+
+	// Unsubscribing again changes nothing
+	_, err = preferenceService.Unsubscribe(token, "203.0.113.7")
+	require.NoError(t, err)
+
+	// Links are signed with the secret of the service
+	_, err = preferenceService.Unsubscribe(token+"x", "203.0.113.7")
+	assert.EqualError(t, err, "invalid unsubscribe link")
+
+	other, err := NewPreferenceService(repository.NewPreferenceRepository(setupTestDB(t)), []byte("other-secret"), "http://localhost/unsubscribe")
+	require.NoError(t, err)
+	_, err = other.ResolveUnsubscribe(token)
+	assert.EqualError(t, err, "invalid unsubscribe link")
+}
+
+func TestPreferenceService_MarketingNeedsConsent(t *testing.T) {
+	preferenceService := setupPreferenceService(t, setupTestDB(t))
+	userID := uuid.New()
+
+	assert.ErrorIs(t, preferenceService.CheckAllowed(userID, model.NotificationTypeMarketing, model.NotificationChannelEmail), ErrNotificationOptedOut)
+
+	_, err := preferenceService.SetMarketingConsent(userID, true, model.ConsentSourcePreferences, "203.0.113.7")
+	require.NoError(t, err)
+	assert.NoError(t, preferenceService.CheckAllowed(userID, model.NotificationTypeMarketing, model.NotificationChannelEmail))
+
+	// Unsubscribing from marketing withdraws the consent
+	token := unsubscribeToken(t, preferenceService.UnsubscribeURL(userID, model.NotificationTypeMarketing, model.NotificationChannelEmail))
+	_, err = preferenceService.Unsubscribe(token, "203.0.113.7")
+	require.NoError(t, err)
+
+	preferences, err := preferenceService.GetPreferences(userID)
+	require.NoError(t, err)
+	assert.False(t, preferences.MarketingConsent)
+
+	records, err := preferenceService.GetConsentRecords(userID)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	sources := []string{records[0].Source, records[1].Source}
+	assert.ElementsMatch(t, []string{model.ConsentSourcePreferences, model.ConsentSourceUnsubscribe}, sources)
+}
@@ -48,17 +48,24 @@ func ValidateNotificationType(notificationType string) error {
 		return fmt.Errorf("notification type is required")
 	}
 
-	validTypes := []string{
-		model.NotificationTypePayment,
-		model.NotificationTypeTicket,
-		model.NotificationTypeUser,
-		model.NotificationTypeReminder,
+	validTypes := []model.NotificationType{
+		model.NotificationTypeBookingConfirmation,
+		model.NotificationTypePaymentConfirmation,
+		model.NotificationTypePaymentFailed,
+		model.NotificationTypeEventReminder,
+		model.NotificationTypeEventCancelled,
+		model.NotificationTypeBookingCancelled,
+		model.NotificationTypeRefundProcessed,
+		model.NotificationTypeWelcome,
+		model.NotificationTypeEmailVerification,
+		model.NotificationTypePasswordReset,
+		model.NotificationTypeSecurityAlert,
 		model.NotificationTypeMarketing,
-		model.NotificationTypeSystem,
+		model.NotificationTypeCustom,
 	}
 
 	for _, validType := range validTypes {
-		if notificationType == validType {
+		if model.NotificationType(notificationType) == validType {
 			return nil
 		}
 	}
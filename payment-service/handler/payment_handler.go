@@ -374,18 +374,66 @@ func (h *PaymentHandler) RefundPayment(c *gin.Context) {
 	c.JSON(http.StatusOK, payment)
 }
 
+// GetPaymentRefunds handles the retrieval of the refunds of a payment
+func (h *PaymentHandler) GetPaymentRefunds(c *gin.Context) {
+	// Get payment ID from URL
+	paymentID := c.Param("id")
+	if paymentID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "payment ID is required"})
+		return
+	}
+
+	// Parse payment ID
+	paymentUUID, err := uuid.Parse(paymentID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payment ID"})
+		return
+	}
+
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	// Get payment
+	payment, err := h.paymentService.GetPaymentByID(paymentUUID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Check if user owns the payment or is an admin
+	userRole, _ := c.Get("userRole")
+	if userID.(string) != payment.UserID.String() && userRole != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+
+	// Get refunds
+	refunds, err := h.paymentService.GetPaymentRefunds(paymentUUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, refunds)
+}
+
 // SetupRoutes sets up the payment routes
-func (h *PaymentHandler) SetupRoutes(router *gin.Engine, authMiddleware gin.HandlerFunc) {
+func (h *PaymentHandler) SetupRoutes(router *gin.Engine, authMiddleware, idempotencyMiddleware gin.HandlerFunc) {
 	// Create payment routes group
 	paymentRoutes := router.Group("/api/payments")
 	paymentRoutes.Use(authMiddleware)
 
 	// Set up routes
 	paymentRoutes.POST("", h.CreatePayment)
-	paymentRoutes.POST("/process", h.ProcessPayment)
+	paymentRoutes.POST("/process", idempotencyMiddleware, h.ProcessPayment)
 	paymentRoutes.GET("/user", h.GetUserPayments)
 	paymentRoutes.GET("/booking/:bookingId", h.GetPaymentByBooking)
 	paymentRoutes.GET("/:id", h.GetPayment)
 	paymentRoutes.PUT("/:id/status", h.UpdatePaymentStatus)
 	paymentRoutes.POST("/:id/refund", h.RefundPayment)
+	paymentRoutes.GET("/:id/refunds", h.GetPaymentRefunds)
 }
\ No newline at end of file
@@ -13,12 +13,16 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/claims"
+	"github.com/yourusername/ticket-system/idempotency"
+	"github.com/yourusername/ticket-system/messaging"
 	"github.com/yourusername/ticket-system/payment-service/config"
 	"github.com/yourusername/ticket-system/payment-service/handler"
 	"github.com/yourusername/ticket-system/payment-service/middleware"
 	"github.com/yourusername/ticket-system/payment-service/provider"
 	"github.com/yourusername/ticket-system/payment-service/repository"
 	"github.com/yourusername/ticket-system/payment-service/service"
+	"gorm.io/gorm"
 )
 
 func main() {
@@ -47,40 +51,68 @@ func main() {
 	defer db.Close()
 
 	// Connect to RabbitMQ
-	ramqConn, err := config.NewRabbitMQConnection()
+	broker, err := messaging.NewRabbitMQ(messaging.ConfigFromEnv())
 	if err != nil {
 		logrus.Fatalf("Failed to connect to RabbitMQ: %v", err)
 	}
-	defer ramqConn.Close()
 
 	// Declare exchanges
-	exchanges := []string{"payment_events", "ticket_events", "notification_events"}
+	exchanges := []string{"payment_events", "ticket_events", "user_events", "notification_events", "privacy_events"}
 	for _, exchange := range exchanges {
-		if err := config.DeclareExchange(ramqConn, exchange); err != nil {
+		if err := broker.DeclareExchange(exchange); err != nil {
 			logrus.Fatalf("Failed to declare exchange %s: %v", exchange, err)
 		}
 	}
 
 	// Initialize repositories
 	paymentRepo := repository.NewPaymentRepository(db)
+	refundRepo := repository.NewRefundRepository(db)
+	idempotencyRepo := idempotency.NewRepository(db)
+	outboxRepo := messaging.NewOutboxRepository(db)
+	inboxRepo := messaging.NewInboxRepository(db)
 
 	// Initialize payment provider
 	paymentProvider := provider.NewPaymentProvider()
 
 	// Initialize services
-	paymentService := service.NewPaymentService(paymentRepo, paymentProvider, ramqConn)
+	paymentService := service.NewPaymentService(paymentRepo, refundRepo, outboxRepo, paymentProvider, db)
+	outboxRelay := messaging.NewOutboxRelay(outboxRepo, broker)
+	inbox := messaging.NewInbox(inboxRepo)
 
 	// Initialize handlers
 	paymentHandler := handler.NewPaymentHandler(paymentService)
 
 	// Initialize Gin router
 	router := gin.New()
+
+	// Only the API gateway may report the address of the client
+	if err := router.SetTrustedProxies(claims.TrustedProxiesFromEnv()); err != nil {
+		logrus.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
 	router.Use(middleware.Logger())
 	router.Use(middleware.Metrics())
 	router.Use(gin.Recovery())
 
+	// Replay retried requests sent with an Idempotency-Key header
+	idempotencyTTL, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_TTL"))
+	if err != nil || idempotencyTTL <= 0 {
+		idempotencyTTL = 24 * time.Hour
+	}
+	idempotencyLockTimeout, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_LOCK_TIMEOUT"))
+	if err != nil || idempotencyLockTimeout <= 0 {
+		idempotencyLockTimeout = 30 * time.Second
+	}
+	idempotencyMiddleware := idempotency.Middleware(idempotencyRepo, idempotencyTTL, idempotencyLockTimeout, middleware.UserID)
+
+	// Trust the identity the gateway verified the access token of
+	identitySigner := claims.NewSigner(claims.ConfigFromEnv())
+	if !identitySigner.Configured() {
+		logrus.Warn("INTERNAL_IDENTITY_SECRET is not set, authenticated requests are rejected")
+	}
+
 	// Set up routes
-	paymentHandler.SetupRoutes(router, middleware.JWTAuth())
+	paymentHandler.SetupRoutes(router, middleware.IdentityAuth(identitySigner), idempotencyMiddleware)
 
 	// Set up Prometheus metrics endpoint
 	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
@@ -90,8 +122,27 @@ func main() {
 		c.JSON(http.StatusOK, gin.H{"status": "UP"})
 	})
 
+	// Start background workers
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
 	// Set up consumers
-	go setupConsumers(ramqConn, paymentService)
+	if err := setupConsumers(workerCtx, broker, paymentService, inbox); err != nil {
+		logrus.Fatalf("Failed to set up consumers: %v", err)
+	}
+
+	// Publish events written to the outbox
+	outboxInterval, err := time.ParseDuration(os.Getenv("OUTBOX_POLL_INTERVAL"))
+	if err != nil || outboxInterval <= 0 {
+		outboxInterval = time.Second
+	}
+	go outboxRelay.Start(workerCtx, outboxInterval)
+
+	// Delete expired idempotency records
+	go idempotency.StartCleanupWorker(workerCtx, idempotencyRepo, time.Hour)
+
+	// Delete old processed message IDs
+	go inbox.StartCleanup(workerCtx, time.Hour)
 
 	// Start server
 	port := os.Getenv("SERVER_PORT")
@@ -127,70 +178,33 @@ func main() {
 		logrus.Fatalf("Server forced to shutdown: %v", err)
 	}
 
+	// Stop the workers and let the consumers finish the messages in progress
+	stopWorkers()
+	if err := broker.Close(ctx); err != nil {
+		logrus.Errorf("Failed to close RabbitMQ connection: %v", err)
+	}
+
 	logrus.Info("Server exiting")
 }
 
 // setupConsumers sets up RabbitMQ consumers
-func setupConsumers(conn *config.RabbitMQConnection, paymentService service.PaymentService) {
+func setupConsumers(ctx context.Context, broker messaging.Broker, paymentService service.PaymentService, inbox *messaging.Inbox) error {
 	// Consume booking events
-	go func() {
-		queueName := "payment_service_booking_events"
-		exchangeName := "ticket_events"
-		routingKey := "booking.#"
-
-		ch, err := conn.Connection.Channel()
-		if err != nil {
-			logrus.Fatalf("Failed to open a channel: %v", err)
-		}
-		defer ch.Close()
-
-		// Declare a queue
-		q, err := ch.QueueDeclare(
-			queueName, // name
-			true,     // durable
-			false,    // delete when unused
-			false,    // exclusive
-			false,    // no-wait
-			nil,      // arguments
-		)
-		if err != nil {
-			logrus.Fatalf("Failed to declare a queue: %v", err)
-		}
-
-		// Bind the queue to the exchange
-		err = ch.QueueBind(
-			q.Name,       // queue name
-			routingKey,   // routing key
-			exchangeName, // exchange
-			false,        // no-wait
-			nil,          // arguments
-		)
-		if err != nil {
-			logrus.Fatalf("Failed to bind a queue: %v", err)
-		}
-
-		// Consume messages
-		msgs, err := ch.Consume(
-			q.Name, // queue
-			"",     // consumer
-			false,  // auto-ack
-			false,  // exclusive
-			false,  // no-local
-			false,  // no-wait
-			nil,    // args
-		)
-		if err != nil {
-			logrus.Fatalf("Failed to register a consumer: %v", err)
-		}
-
-		logrus.Infof("Started consuming messages from queue: %s", queueName)
-
-		// Process messages
-		for msg := range msgs {
-			logrus.Infof("Received a message: %s", msg.RoutingKey)
+	queueName := "payment_service_booking_events"
+	err := broker.Consume(ctx, messaging.ConsumerOptions{
+		Queue:       queueName,
+		Exchange:    "ticket_events",
+		RoutingKeys: []string{"booking.#"},
+	}, func(msg messaging.Message) error {
+		eventType := msg.RoutingKey
+		logrus.Infof("Received a message: %s", eventType)
+
+		// Process the message once, in the transaction that records it as processed
+		return inbox.Handle(queueName, msg.ID, func(tx *gorm.DB) error {
+			txPaymentService := paymentService.WithTx(tx)
 
 			// Process the message based on the routing key
-			switch msg.RoutingKey {
+			switch eventType {
 			case "booking.created":
 				// Handle booking created event
 				// This could trigger payment creation
@@ -199,90 +213,63 @@ func setupConsumers(conn *config.RabbitMQConnection, paymentService service.Paym
 
 			case "booking.cancelled":
 				// Handle booking cancelled event
-				// This could trigger payment refund
+				// This refunds the amount allowed by the refund policy of the event
 				logrus.Info("Processing booking.cancelled event")
-				// paymentService.HandleBookingCancelledEvent(msg.Body)
+				return txPaymentService.HandleBookingCancelled(msg.Body)
+
+			case "booking.payment_requested":
+				// Handle booking payment requested command
+				// This creates the pending payment of a new booking
+				logrus.Info("Processing booking.payment_requested event")
+				return txPaymentService.HandlePaymentRequested(msg.Body)
+
+			case "booking.refund_requested":
+				// Handle booking refund requested command
+				// This refunds a booking the booking saga gave up on
+				logrus.Info("Processing booking.refund_requested event")
+				return txPaymentService.HandleRefundRequested(msg.Body)
+
+			case "booking.amended":
+				// Handle booking amended event
+				// This settles the price difference of the amendment
+				logrus.Info("Processing booking.amended event")
+				return txPaymentService.HandleBookingAmended(msg.Body)
 
 			default:
-				logrus.Warnf("Unknown routing key: %s", msg.RoutingKey)
+				logrus.Warnf("Unknown routing key: %s", eventType)
 			}
-
-			// Acknowledge the message
-			msg.Ack(false)
-		}
-	}()
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
 
 	// Consume user events
-	go func() {
-		queueName := "payment_service_user_events"
-		exchangeName := "user_events"
-		routingKey := "user.#"
-
-		ch, err := conn.Connection.Channel()
-		if err != nil {
-			logrus.Fatalf("Failed to open a channel: %v", err)
-		}
-		defer ch.Close()
-
-		// Declare a queue
-		q, err := ch.QueueDeclare(
-			queueName, // name
-			true,     // durable
-			false,    // delete when unused
-			false,    // exclusive
-			false,    // no-wait
-			nil,      // arguments
-		)
-		if err != nil {
-			logrus.Fatalf("Failed to declare a queue: %v", err)
-		}
-
-		// Bind the queue to the exchange
-		err = ch.QueueBind(
-			q.Name,       // queue name
-			routingKey,   // routing key
-			exchangeName, // exchange
-			false,        // no-wait
-			nil,          // arguments
-		)
-		if err != nil {
-			logrus.Fatalf("Failed to bind a queue: %v", err)
-		}
-
-		// Consume messages
-		msgs, err := ch.Consume(
-			q.Name, // queue
-			"",     // consumer
-			false,  // auto-ack
-			false,  // exclusive
-			false,  // no-local
-			false,  // no-wait
-			nil,    // args
-		)
-		if err != nil {
-			logrus.Fatalf("Failed to register a consumer: %v", err)
+	return broker.Consume(ctx, messaging.ConsumerOptions{
+		Queue:       "payment_service_user_events",
+		Exchange:    "user_events",
+		RoutingKeys: []string{"user.#"},
+	}, func(msg messaging.Message) error {
+		logrus.Infof("Received a message: %s", msg.RoutingKey)
+
+		// Process the message based on the routing key
+		switch msg.RoutingKey {
+		case "user.deleted":
+			// Handle user deleted event
+			// This could trigger payment data anonymization
+			logrus.Info("Processing user.deleted event")
+			// paymentService.HandleUserDeletedEvent(msg.Body)
+
+		case "user.data_requested":
+			// Handle user data requested event
+			// This answers a data export or erasure of the user
+			logrus.Info("Processing user.data_requested event")
+			return paymentService.HandleDataRequested(msg.Body)
+
+		default:
+			logrus.Warnf("Unknown routing key: %s", msg.RoutingKey)
 		}
-
-		logrus.Infof("Started consuming messages from queue: %s", queueName)
-
-		// Process messages
-		for msg := range msgs {
-			logrus.Infof("Received a message: %s", msg.RoutingKey)
-
-			// Process the message based on the routing key
-			switch msg.RoutingKey {
-			case "user.deleted":
-				// Handle user deleted event
-				// This could trigger payment data anonymization
-				logrus.Info("Processing user.deleted event")
-				// paymentService.HandleUserDeletedEvent(msg.Body)
-
-			default:
-				logrus.Warnf("Unknown routing key: %s", msg.RoutingKey)
-			}
-
-			// Acknowledge the message
-			msg.Ack(false)
-		}
-	}()
-}
\ No newline at end of file
+		return nil
+	})
+}
@@ -9,17 +9,19 @@ import (
 
 // Payment represents a payment transaction
 type Payment struct {
-	ID            uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
-	UserID        uuid.UUID `gorm:"type:uuid;index" json:"user_id"`
-	BookingID     uuid.UUID `gorm:"type:uuid;index" json:"booking_id"`
-	Amount        float64   `gorm:"type:decimal(10,2)" json:"amount"`
-	Currency      string    `gorm:"type:varchar(3)" json:"currency"`
-	Status        string    `gorm:"type:varchar(20)" json:"status"` // pending, completed, failed, refunded
-	PaymentMethod string    `gorm:"type:varchar(50)" json:"payment_method"`
-	TransactionID string    `gorm:"type:varchar(100)" json:"transaction_id"`
-	PaymentDate   time.Time `json:"payment_date"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	UserID         uuid.UUID  `gorm:"type:uuid;index" json:"user_id"`
+	BookingID      uuid.UUID  `gorm:"type:uuid;index" json:"booking_id"`
+	AmendmentID    *uuid.UUID `gorm:"type:uuid;index" json:"amendment_id,omitempty"` // set on supplementary payments
+	Amount         float64    `gorm:"type:decimal(10,2)" json:"amount"`
+	RefundedAmount float64    `gorm:"type:decimal(10,2);default:0" json:"refunded_amount"`
+	Currency       string     `gorm:"type:varchar(3)" json:"currency"`
+	Status         string     `gorm:"type:varchar(20)" json:"status"` // pending, completed, failed, cancelled, partially_refunded, refunded
+	PaymentMethod  string     `gorm:"type:varchar(50)" json:"payment_method"`
+	TransactionID  string     `gorm:"type:varchar(100)" json:"transaction_id"`
+	PaymentDate    time.Time  `json:"payment_date"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
 }
 
 // BeforeCreate will set a UUID rather than numeric ID
@@ -32,17 +34,19 @@ func (p *Payment) BeforeCreate(tx *gorm.DB) error {
 
 // PaymentResponse represents the response for a payment
 type PaymentResponse struct {
-	ID            uuid.UUID `json:"id"`
-	UserID        uuid.UUID `json:"user_id"`
-	BookingID     uuid.UUID `json:"booking_id"`
-	Amount        float64   `json:"amount"`
-	Currency      string    `json:"currency"`
-	Status        string    `json:"status"`
-	PaymentMethod string    `json:"payment_method"`
-	TransactionID string    `json:"transaction_id,omitempty"`
-	PaymentDate   time.Time `json:"payment_date,omitempty"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID             uuid.UUID  `json:"id"`
+	UserID         uuid.UUID  `json:"user_id"`
+	BookingID      uuid.UUID  `json:"booking_id"`
+	AmendmentID    *uuid.UUID `json:"amendment_id,omitempty"`
+	Amount         float64    `json:"amount"`
+	RefundedAmount float64    `json:"refunded_amount"`
+	Currency       string     `json:"currency"`
+	Status         string     `json:"status"`
+	PaymentMethod  string     `json:"payment_method"`
+	TransactionID  string     `json:"transaction_id,omitempty"`
+	PaymentDate    time.Time  `json:"payment_date,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
 }
 
 // CreatePaymentRequest represents the request to create a payment
@@ -74,22 +78,25 @@ type ProcessPaymentRequest struct {
 
 // RefundRequest represents the request to refund a payment
 type RefundRequest struct {
-	Reason string `json:"reason,omitempty"`
+	Amount float64 `json:"amount,omitempty"` // defaults to the amount not yet refunded
+	Reason string  `json:"reason,omitempty"`
 }
 
 // ToResponse converts a Payment to a PaymentResponse
 func (p *Payment) ToResponse() PaymentResponse {
 	return PaymentResponse{
-		ID:            p.ID,
-		UserID:        p.UserID,
-		BookingID:     p.BookingID,
-		Amount:        p.Amount,
-		Currency:      p.Currency,
-		Status:        p.Status,
-		PaymentMethod: p.PaymentMethod,
-		TransactionID: p.TransactionID,
-		PaymentDate:   p.PaymentDate,
-		CreatedAt:     p.CreatedAt,
-		UpdatedAt:     p.UpdatedAt,
+		ID:             p.ID,
+		UserID:         p.UserID,
+		BookingID:      p.BookingID,
+		AmendmentID:    p.AmendmentID,
+		Amount:         p.Amount,
+		RefundedAmount: p.RefundedAmount,
+		Currency:       p.Currency,
+		Status:         p.Status,
+		PaymentMethod:  p.PaymentMethod,
+		TransactionID:  p.TransactionID,
+		PaymentDate:    p.PaymentDate,
+		CreatedAt:      p.CreatedAt,
+		UpdatedAt:      p.UpdatedAt,
 	}
-}
\ No newline at end of file
+}
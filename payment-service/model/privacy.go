@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// PrivacyService is the name the payment service answers data requests of
+// the user service with
+const PrivacyService = "payment-service"
+
+// PrivacyDataExport is the personal data the payment service holds about a
+// user
+type PrivacyDataExport struct {
+	Payments   []PaymentResponse `json:"payments"`
+	Refunds    []Refund          `json:"refunds"`
+	ExportedAt time.Time         `json:"exported_at"`
+}
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Refund represents a full or partial refund of a captured payment
+type Refund struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	PaymentID   uuid.UUID  `gorm:"type:uuid;index" json:"payment_id"`
+	BookingID   uuid.UUID  `gorm:"type:uuid;index" json:"booking_id"`
+	AmendmentID *uuid.UUID `gorm:"type:uuid" json:"amendment_id,omitempty"`
+	Source      string     `gorm:"type:varchar(100);index" json:"-"` // event the refund was made for, if any
+	Amount      float64    `gorm:"type:decimal(10,2)" json:"amount"`
+	Currency    string     `gorm:"type:varchar(3)" json:"currency"`
+	Status      string     `gorm:"type:varchar(20)" json:"status"` // pending, completed, failed
+	Reason      string     `gorm:"type:text" json:"reason,omitempty"`
+	Error       string     `gorm:"type:text" json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// BeforeCreate will set a UUID rather than numeric ID
+func (r *Refund) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"os"
 	"time"
@@ -16,7 +17,9 @@ import (
 // PaymentProvider defines the interface for payment provider operations
 type PaymentProvider interface {
 	ProcessPayment(req model.ProcessPaymentRequest) (string, error)
-	RefundPayment(transactionID, reason string) error
+	// RefundPayment refunds a payment. Retries with the same idempotency key
+	// refund it only once.
+	RefundPayment(transactionID string, amount float64, currency, reason, idempotencyKey string) error
 	VerifyPayment(transactionID string) (bool, error)
 }
 
@@ -103,10 +106,11 @@ func (p *mockProvider) ProcessPayment(req model.ProcessPaymentRequest) (string,
 }
 
 // RefundPayment refunds a payment with the mock provider
-func (p *mockProvider) RefundPayment(transactionID, reason string) error {
+func (p *mockProvider) RefundPayment(transactionID string, amount float64, currency, reason, idempotencyKey string) error {
 	// Simulate refund processing
 	logrus.Info("Processing refund with mock provider")
 	logrus.Infof("Transaction ID: %s", transactionID)
+	logrus.Infof("Amount: %f %s", amount, currency)
 	logrus.Infof("Reason: %s", reason)
 
 	// Validate transaction ID
@@ -114,6 +118,11 @@ func (p *mockProvider) RefundPayment(transactionID, reason string) error {
 		return errors.New("invalid transaction ID")
 	}
 
+	// Validate amount
+	if amount <= 0 {
+		return errors.New("invalid amount")
+	}
+
 	// Simulate processing delay
 	time.Sleep(500 * time.Millisecond)
 
@@ -223,14 +232,15 @@ func (p *stripeProvider) ProcessPayment(req model.ProcessPaymentRequest) (string
 }
 
 // RefundPayment refunds a payment with Stripe
-func (p *stripeProvider) RefundPayment(transactionID, reason string) error {
+func (p *stripeProvider) RefundPayment(transactionID string, amount float64, currency, reason, idempotencyKey string) error {
 	if p.secretKey == "" {
 		return errors.New("STRIPE_SECRET_KEY is not set")
 	}
 
-	// Create refund payload
+	// Create refund payload, with the amount in the smallest currency unit
 	payload := map[string]interface{}{
 		"payment_intent": transactionID,
+		"amount":         int64(math.Round(amount * 100)),
 		"reason":         reason,
 	}
 
@@ -248,6 +258,7 @@ func (p *stripeProvider) RefundPayment(transactionID, reason string) error {
 	// Set headers
 	req.Header.Set("Authorization", "Bearer "+p.secretKey)
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", idempotencyKey)
 
 	// Send request
 	client := &http.Client{}
@@ -401,7 +412,7 @@ func (p *paypalProvider) ProcessPayment(req model.ProcessPaymentRequest) (string
 }
 
 // RefundPayment refunds a payment with PayPal
-func (p *paypalProvider) RefundPayment(transactionID, reason string) error {
+func (p *paypalProvider) RefundPayment(transactionID string, amount float64, currency, reason, idempotencyKey string) error {
 	if p.clientID == "" || p.clientSecret == "" {
 		return errors.New("PAYPAL_CLIENT_ID or PAYPAL_CLIENT_SECRET is not set")
 	}
@@ -413,6 +424,10 @@ func (p *paypalProvider) RefundPayment(transactionID, reason string) error {
 
 	// Create refund payload
 	payload := map[string]interface{}{
+		"amount": map[string]interface{}{
+			"value":         fmt.Sprintf("%.2f", amount),
+			"currency_code": currency,
+		},
 		"note_to_payer": reason,
 	}
 
@@ -430,6 +445,7 @@ func (p *paypalProvider) RefundPayment(transactionID, reason string) error {
 	// Set headers
 	req.Header.Set("Authorization", "Bearer "+p.accessToken)
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("PayPal-Request-Id", idempotencyKey)
 
 	// Send request
 	client := &http.Client{}
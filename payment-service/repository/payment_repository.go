@@ -11,9 +11,12 @@ type PaymentRepository interface {
 	Create(payment *model.Payment) error
 	FindByID(id uuid.UUID) (*model.Payment, error)
 	FindByBookingID(bookingID uuid.UUID) (*model.Payment, error)
+	FindPendingByBookingID(bookingID uuid.UUID) (*model.Payment, error)
+	FindRefundableByBookingID(bookingID uuid.UUID) ([]model.Payment, error)
 	FindByUserID(userID uuid.UUID, page, pageSize int) ([]model.Payment, int64, error)
 	Update(payment *model.Payment) error
 	Delete(id uuid.UUID) error
+	WithTx(tx *gorm.DB) PaymentRepository
 }
 
 // paymentRepositoryImpl implements PaymentRepository interface
@@ -31,6 +34,13 @@ func NewPaymentRepositoryImpl(db *gorm.DB) PaymentRepository {
 	}
 }
 
+// WithTx returns a repository that runs its operations in tx
+func (r *paymentRepositoryImpl) WithTx(tx *gorm.DB) PaymentRepository {
+	return &paymentRepositoryImpl{
+		db: tx,
+	}
+}
+
 // Create creates a new payment
 func (r *paymentRepositoryImpl) Create(payment *model.Payment) error {
 	return r.db.Create(payment).Error
@@ -64,6 +74,35 @@ func (r *paymentRepositoryImpl) FindByBookingID(bookingID uuid.UUID) (*model.Pay
 	return &payment, nil
 }
 
+// FindPendingByBookingID finds the payment of a booking that is still waiting to be processed
+func (r *paymentRepositoryImpl) FindPendingByBookingID(bookingID uuid.UUID) (*model.Payment, error) {
+	var payment model.Payment
+	err := r.db.Where("booking_id = ? AND status = ?", bookingID, "pending").
+		Order("created_at ASC").
+		First(&payment).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &payment, nil
+}
+
+// FindRefundableByBookingID finds the captured payments of a booking that are not fully refunded, oldest first
+func (r *paymentRepositoryImpl) FindRefundableByBookingID(bookingID uuid.UUID) ([]model.Payment, error) {
+	var payments []model.Payment
+	err := r.db.Where("booking_id = ? AND status IN ?", bookingID, []string{"completed", "partially_refunded"}).
+		Order("created_at ASC").
+		Find(&payments).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return payments, nil
+}
+
 // FindByUserID finds payments by user ID with pagination
 func (r *paymentRepositoryImpl) FindByUserID(userID uuid.UUID, page, pageSize int) ([]model.Payment, int64, error) {
 	var payments []model.Payment
@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/google/uuid"
+	"github.com/yourusername/ticket-system/payment-service/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RefundRepository defines the interface for refund repository operations
+type RefundRepository interface {
+	Reserve(refund *model.Refund) (*model.Payment, error)
+	Complete(refund *model.Refund) (*model.Payment, error)
+	Fail(refund *model.Refund, reason string) error
+	FindByPaymentID(paymentID uuid.UUID) ([]model.Refund, error)
+	FindBySource(source string) ([]model.Refund, error)
+	WithTx(tx *gorm.DB) RefundRepository
+}
+
+// refundRepositoryImpl implements RefundRepository interface
+type refundRepositoryImpl struct {
+	db *gorm.DB
+}
+
+// NewRefundRepository creates a new refund repository
+func NewRefundRepository(db *gorm.DB) RefundRepository {
+	// Auto migrate the Refund model
+	db.AutoMigrate(&model.Refund{})
+
+	return &refundRepositoryImpl{
+		db: db,
+	}
+}
+
+// WithTx returns a repository that runs its operations in tx
+func (r *refundRepositoryImpl) WithTx(tx *gorm.DB) RefundRepository {
+	return &refundRepositoryImpl{
+		db: tx,
+	}
+}
+
+// Reserve adds a pending refund to its payment. The payment row is locked so
+// that concurrent refunds can never exceed the captured amount.
+func (r *refundRepositoryImpl) Reserve(refund *model.Refund) (*model.Payment, error) {
+	var payment model.Payment
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", refund.PaymentID).
+			First(&payment).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return fmt.Errorf("payment not found")
+			}
+			return err
+		}
+
+		if payment.Status != "completed" && payment.Status != "partially_refunded" {
+			return fmt.Errorf("only completed payments can be refunded")
+		}
+
+		refundable := roundAmount(payment.Amount - payment.RefundedAmount)
+		if refund.Amount > refundable {
+			return fmt.Errorf("refund amount %.2f exceeds the refundable amount %.2f", refund.Amount, refundable)
+		}
+
+		payment.RefundedAmount = roundAmount(payment.RefundedAmount + refund.Amount)
+		if err := tx.Save(&payment).Error; err != nil {
+			return err
+		}
+
+		refund.BookingID = payment.BookingID
+		refund.Currency = payment.Currency
+		refund.Status = "pending"
+		return tx.Create(refund).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &payment, nil
+}
+
+// Complete marks a reserved refund as completed and updates the payment
+// status from its completed refunds, as pending ones may still fail
+func (r *refundRepositoryImpl) Complete(refund *model.Refund) (*model.Payment, error) {
+	var payment model.Payment
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", refund.PaymentID).
+			First(&payment).Error; err != nil {
+			return err
+		}
+
+		refund.Status = "completed"
+		if err := tx.Save(refund).Error; err != nil {
+			return err
+		}
+
+		var completed float64
+		if err := tx.Model(&model.Refund{}).
+			Where("payment_id = ? AND status = 'completed'", payment.ID).
+			Select("COALESCE(SUM(amount), 0)").
+			Scan(&completed).Error; err != nil {
+			return err
+		}
+
+		payment.Status = "partially_refunded"
+		if roundAmount(completed) >= payment.Amount {
+			payment.Status = "refunded"
+		}
+		return tx.Save(&payment).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &payment, nil
+}
+
+// Fail marks a reserved refund as failed and releases its amount on the
+// payment. Refunds that are no longer pending are left unchanged.
+func (r *refundRepositoryImpl) Fail(refund *model.Refund, reason string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.Refund{}).
+			Where("id = ? AND status = 'pending'", refund.ID).
+			Updates(map[string]interface{}{
+				"status":     "failed",
+				"error":      reason,
+				"updated_at": refund.UpdatedAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		refund.Status = "failed"
+		refund.Error = reason
+		return tx.Model(&model.Payment{}).
+			Where("id = ?", refund.PaymentID).
+			Update("refunded_amount", gorm.Expr("refunded_amount - ?", refund.Amount)).Error
+	})
+}
+
+// FindByPaymentID finds the refunds of a payment, oldest first
+func (r *refundRepositoryImpl) FindByPaymentID(paymentID uuid.UUID) ([]model.Refund, error) {
+	var refunds []model.Refund
+	err := r.db.Where("payment_id = ?", paymentID).
+		Order("created_at ASC").
+		Find(&refunds).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return refunds, nil
+}
+
+// FindBySource finds the refunds made for an event, oldest first
+func (r *refundRepositoryImpl) FindBySource(source string) ([]model.Refund, error) {
+	var refunds []model.Refund
+	err := r.db.Where("source = ?", source).
+		Order("created_at ASC").
+		Find(&refunds).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return refunds, nil
+}
+
+// roundAmount rounds a monetary amount to cents
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
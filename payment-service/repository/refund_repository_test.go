@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/ticket-system/payment-service/model"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestDB opens an in-memory database with the payment tables
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	// Every connection opens its own in-memory database
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	NewPaymentRepositoryImpl(db)
+	return db
+}
+
+// createCapturedPayment creates a completed payment of amount
+func createCapturedPayment(t *testing.T, db *gorm.DB, amount float64) *model.Payment {
+	payment := &model.Payment{
+		UserID:        uuid.New(),
+		BookingID:     uuid.New(),
+		Amount:        amount,
+		Currency:      "IDR",
+		Status:        "completed",
+		TransactionID: "txn_" + uuid.NewString(),
+		PaymentDate:   time.Now(),
+	}
+	require.NoError(t, db.Create(payment).Error)
+	return payment
+}
+
+// findPayment loads a payment by ID
+func findPayment(t *testing.T, db *gorm.DB, id uuid.UUID) *model.Payment {
+	var payment model.Payment
+	require.NoError(t, db.First(&payment, "id = ?", id).Error)
+	return &payment
+}
+
+func TestRefundRepository_ReserveRejectsOverRefund(t *testing.T) {
+	db := setupTestDB(t)
+	refundRepo := NewRefundRepository(db)
+	payment := createCapturedPayment(t, db, 100)
+
+	_, err := refundRepo.Reserve(&model.Refund{PaymentID: payment.ID, Amount: 60})
+	require.NoError(t, err)
+
+	// The pending refund already counts against the captured amount
+	_, err = refundRepo.Reserve(&model.Refund{PaymentID: payment.ID, Amount: 50})
+	assert.EqualError(t, err, "refund amount 50.00 exceeds the refundable amount 40.00")
+	assert.Equal(t, 60.0, findPayment(t, db, payment.ID).RefundedAmount)
+
+	var refunds int64
+	require.NoError(t, db.Model(&model.Refund{}).Where("payment_id = ?", payment.ID).Count(&refunds).Error)
+	assert.Equal(t, int64(1), refunds)
+}
+
+func TestRefundRepository_ConcurrentReservationsStayWithinCapturedAmount(t *testing.T) {
+	db := setupTestDB(t)
+	refundRepo := NewRefundRepository(db)
+	payment := createCapturedPayment(t, db, 100)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := refundRepo.Reserve(&model.Refund{PaymentID: payment.ID, Amount: 30})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	reserved := 0
+	for err := range errs {
+		if err == nil {
+			reserved++
+		}
+	}
+	assert.Equal(t, 3, reserved)
+	assert.Equal(t, 90.0, findPayment(t, db, payment.ID).RefundedAmount)
+}
+
+func TestRefundRepository_FailReleasesReservedAmount(t *testing.T) {
+	db := setupTestDB(t)
+	refundRepo := NewRefundRepository(db)
+	payment := createCapturedPayment(t, db, 100)
+
+	refund := &model.Refund{PaymentID: payment.ID, Amount: 100}
+	_, err := refundRepo.Reserve(refund)
+	require.NoError(t, err)
+
+	require.NoError(t, refundRepo.Fail(refund, "provider unavailable"))
+	failed := findPayment(t, db, payment.ID)
+	assert.Equal(t, 0.0, failed.RefundedAmount)
+	assert.Equal(t, "completed", failed.Status)
+
+	// Failing the refund again does not release its amount twice
+	require.NoError(t, refundRepo.Fail(refund, "provider unavailable"))
+	assert.Equal(t, 0.0, findPayment(t, db, payment.ID).RefundedAmount)
+
+	// The released amount can be refunded again
+	_, err = refundRepo.Reserve(&model.Refund{PaymentID: payment.ID, Amount: 100})
+	assert.NoError(t, err)
+}
+
+func TestRefundRepository_CompleteSetsStatusFromCompletedRefunds(t *testing.T) {
+	db := setupTestDB(t)
+	refundRepo := NewRefundRepository(db)
+	payment := createCapturedPayment(t, db, 100)
+
+	first := &model.Refund{PaymentID: payment.ID, Amount: 40}
+	_, err := refundRepo.Reserve(first)
+	require.NoError(t, err)
+	second := &model.Refund{PaymentID: payment.ID, Amount: 60}
+	_, err = refundRepo.Reserve(second)
+	require.NoError(t, err)
+
+	// The whole amount is reserved but only part of it refunded
+	completed, err := refundRepo.Complete(first)
+	require.NoError(t, err)
+	assert.Equal(t, "partially_refunded", completed.Status)
+
+	completed, err = refundRepo.Complete(second)
+	require.NoError(t, err)
+	assert.Equal(t, "refunded", completed.Status)
+	assert.Equal(t, 100.0, completed.RefundedAmount)
+}
@@ -3,14 +3,17 @@ package service
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
-	"github.com/yourusername/ticket-system/payment-service/config"
+	"github.com/yourusername/ticket-system/contracts"
+	"github.com/yourusername/ticket-system/messaging"
 	"github.com/yourusername/ticket-system/payment-service/model"
 	"github.com/yourusername/ticket-system/payment-service/repository"
 	"github.com/yourusername/ticket-system/payment-service/provider"
+	"gorm.io/gorm"
 )
 
 // PaymentService defines the interface for payment service operations
@@ -22,25 +25,54 @@ type PaymentService interface {
 	GetUserPayments(userID uuid.UUID, page, pageSize int) ([]model.PaymentResponse, int64, error)
 	UpdatePaymentStatus(id uuid.UUID, req model.UpdatePaymentStatusRequest) (*model.PaymentResponse, error)
 	RefundPayment(id uuid.UUID, req model.RefundRequest) (*model.PaymentResponse, error)
+	GetPaymentRefunds(id uuid.UUID) ([]model.Refund, error)
+	HandleBookingAmended(body []byte) error
+	HandleBookingCancelled(body []byte) error
+	HandlePaymentRequested(body []byte) error
+	HandleRefundRequested(body []byte) error
+	HandleDataRequested(body []byte) error
+	WithTx(tx *gorm.DB) PaymentService
 }
 
 // paymentService implements PaymentService interface
 type paymentService struct {
 	paymentRepo    repository.PaymentRepository
+	refundRepo     repository.RefundRepository
+	outboxRepo     messaging.OutboxRepository
 	paymentProvider provider.PaymentProvider
-	rmq            *config.RabbitMQ
+	db             *gorm.DB
+	refundDB       *gorm.DB // refunds commit outside of the transaction of WithTx
 }
 
 // NewPaymentService creates a new payment service
 func NewPaymentService(
 	paymentRepo repository.PaymentRepository,
+	refundRepo repository.RefundRepository,
+	outboxRepo messaging.OutboxRepository,
 	paymentProvider provider.PaymentProvider,
-	rmq *config.RabbitMQ,
+	db *gorm.DB,
 ) PaymentService {
 	return &paymentService{
 		paymentRepo:    paymentRepo,
+		refundRepo:     refundRepo,
+		outboxRepo:     outboxRepo,
 		paymentProvider: paymentProvider,
-		rmq:            rmq,
+		db:             db,
+		refundDB:       db,
+	}
+}
+
+// WithTx returns a service that runs its operations in tx, so that consumers
+// can apply a message in the transaction that records it as processed.
+// Refunds still commit on their own, as the provider cannot roll them back.
+func (s *paymentService) WithTx(tx *gorm.DB) PaymentService {
+	return &paymentService{
+		paymentRepo:     s.paymentRepo.WithTx(tx),
+		refundRepo:      s.refundRepo,
+		outboxRepo:      s.outboxRepo,
+		paymentProvider: s.paymentProvider,
+		db:              tx,
+		refundDB:        s.refundDB,
 	}
 }
 
@@ -68,13 +100,18 @@ func (s *paymentService) CreatePayment(userID uuid.UUID, req model.CreatePayment
 		UpdatedAt:     time.Now(),
 	}
 
-	// Save payment to database
-	if err := s.paymentRepo.Create(payment); err != nil {
-		return nil, fmt.Errorf("failed to create payment: %w", err)
-	}
+	// Save payment and its created event to database
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.paymentRepo.WithTx(tx).Create(payment); err != nil {
+			return fmt.Errorf("failed to create payment: %w", err)
+		}
 
-	// Publish payment created event
-	s.publishPaymentEvent("payment.created", payment)
+		// Publish payment created event
+		return s.publishPaymentEvent(tx, "payment.created", payment)
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	// Return payment response
 	paymentResponse := payment.ToResponse()
@@ -83,21 +120,34 @@ func (s *paymentService) CreatePayment(userID uuid.UUID, req model.CreatePayment
 
 // ProcessPayment processes a payment
 func (s *paymentService) ProcessPayment(userID uuid.UUID, req model.ProcessPaymentRequest) (*model.PaymentResponse, error) {
-	// Check if payment already exists for booking
-	existingPayment, err := s.paymentRepo.FindByBookingID(req.BookingID)
+	// Check if a payment is waiting to be processed for the booking
+	payment, err := s.paymentRepo.FindPendingByBookingID(req.BookingID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check existing payment: %w", err)
 	}
 
-	var payment *model.Payment
+	if payment != nil {
+		// Charge the amount that is due, which changes when a booking is amended
+		req.Amount = payment.Amount
 
-	if existingPayment != nil {
-		// Use existing payment if it's in pending status
-		if existingPayment.Status != "pending" {
-			return nil, fmt.Errorf("payment already processed for this booking")
+		// Payments requested by the booking saga get their details when they are processed
+		if payment.Currency == "" {
+			payment.Currency = req.Currency
+		}
+		if payment.PaymentMethod == "" {
+			payment.PaymentMethod = req.PaymentMethod
 		}
-		payment = existingPayment
 	} else {
+		// Check if payment already exists for booking
+		existingPayment, err := s.paymentRepo.FindByBookingID(req.BookingID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check existing payment: %w", err)
+		}
+
+		if existingPayment != nil {
+			return nil, fmt.Errorf("payment already processed for this booking")
+		}
+
 		// Create new payment
 		payment = &model.Payment{
 			UserID:        userID,
@@ -123,14 +173,19 @@ func (s *paymentService) ProcessPayment(userID uuid.UUID, req model.ProcessPayme
 		payment.Status = "failed"
 		payment.UpdatedAt = time.Now()
 
-		// Save payment to database
-		if updateErr := s.paymentRepo.Update(payment); updateErr != nil {
+		// Save payment and its failed event to database
+		updateErr := s.db.Transaction(func(tx *gorm.DB) error {
+			if err := s.paymentRepo.WithTx(tx).Update(payment); err != nil {
+				return err
+			}
+
+			// Publish payment failed event
+			return s.publishPaymentEvent(tx, "payment.failed", payment)
+		})
+		if updateErr != nil {
 			logrus.WithError(updateErr).Error("Failed to update payment status to failed")
 		}
 
-		// Publish payment failed event
-		s.publishPaymentEvent("payment.failed", payment)
-
 		return nil, fmt.Errorf("failed to process payment: %w", err)
 	}
 
@@ -140,13 +195,18 @@ func (s *paymentService) ProcessPayment(userID uuid.UUID, req model.ProcessPayme
 	payment.PaymentDate = time.Now()
 	payment.UpdatedAt = time.Now()
 
-	// Save payment to database
-	if err := s.paymentRepo.Update(payment); err != nil {
-		return nil, fmt.Errorf("failed to update payment: %w", err)
-	}
+	// Save payment and its completed event to database
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.paymentRepo.WithTx(tx).Update(payment); err != nil {
+			return fmt.Errorf("failed to update payment: %w", err)
+		}
 
-	// Publish payment completed event
-	s.publishPaymentEvent("payment.completed", payment)
+		// Publish payment completed event
+		return s.publishPaymentEvent(tx, "payment.completed", payment)
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	// Return payment response
 	paymentResponse := payment.ToResponse()
@@ -230,20 +290,25 @@ func (s *paymentService) UpdatePaymentStatus(id uuid.UUID, req model.UpdatePayme
 		payment.PaymentDate = req.PaymentDate
 	}
 
-	// Save payment to database
-	if err := s.paymentRepo.Update(payment); err != nil {
-		return nil, fmt.Errorf("failed to update payment: %w", err)
-	}
+	// Save payment and its updated event to database
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.paymentRepo.WithTx(tx).Update(payment); err != nil {
+			return fmt.Errorf("failed to update payment: %w", err)
+		}
 
-	// Publish payment updated event
-	s.publishPaymentEvent("payment.updated", payment)
+		// Publish payment updated event
+		return s.publishPaymentEvent(tx, "payment.updated", payment)
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	// Return payment response
 	paymentResponse := payment.ToResponse()
 	return &paymentResponse, nil
 }
 
-// RefundPayment refunds a payment
+// RefundPayment refunds a payment, in full or in part
 func (s *paymentService) RefundPayment(id uuid.UUID, req model.RefundRequest) (*model.PaymentResponse, error) {
 	// Find payment by ID
 	payment, err := s.paymentRepo.FindByID(id)
@@ -255,59 +320,494 @@ func (s *paymentService) RefundPayment(id uuid.UUID, req model.RefundRequest) (*
 		return nil, fmt.Errorf("payment not found")
 	}
 
-	// Check if payment is completed
-	if payment.Status != "completed" {
-		return nil, fmt.Errorf("only completed payments can be refunded")
+	// Refund the amount not yet refunded unless an amount is given
+	amount := req.Amount
+	if amount == 0 {
+		amount = payment.Amount - payment.RefundedAmount
 	}
 
-	// Process refund with payment provider
-	err = s.paymentProvider.RefundPayment(payment.TransactionID, req.Reason)
-	if err != nil {
-		return nil, fmt.Errorf("failed to process refund: %w", err)
+	if amount <= 0 {
+		return nil, fmt.Errorf("refund amount must be greater than zero")
 	}
 
-	// Update payment status to refunded
-	payment.Status = "refunded"
-	payment.UpdatedAt = time.Now()
-
-	// Save payment to database
-	if err := s.paymentRepo.Update(payment); err != nil {
-		return nil, fmt.Errorf("failed to update payment: %w", err)
+	payment, _, err = s.refund(payment.ID, amount, req.Reason, nil, "")
+	if err != nil {
+		return nil, err
 	}
 
-	// Publish payment refunded event
-	s.publishPaymentEvent("payment.refunded", payment)
-
 	// Return payment response
 	paymentResponse := payment.ToResponse()
 	return &paymentResponse, nil
 }
 
-// publishPaymentEvent publishes a payment event to RabbitMQ
-func (s *paymentService) publishPaymentEvent(eventType string, payment *model.Payment) {
-	// Create event payload
-	payload := map[string]interface{}{
-		"event_type":     eventType,
-		"payment_id":     payment.ID.String(),
-		"user_id":        payment.UserID.String(),
-		"booking_id":     payment.BookingID.String(),
-		"amount":         payment.Amount,
-		"currency":       payment.Currency,
-		"status":         payment.Status,
-		"payment_method": payment.PaymentMethod,
-		"timestamp":      time.Now(),
-	}
-
-	// Convert payload to JSON
-	payloadJSON, err := json.Marshal(payload)
+// GetPaymentRefunds gets the refunds of a payment
+func (s *paymentService) GetPaymentRefunds(id uuid.UUID) ([]model.Refund, error) {
+	refunds, err := s.refundRepo.FindByPaymentID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find refunds: %w", err)
+	}
+
+	return refunds, nil
+}
+
+// HandleBookingCancelled refunds a cancelled booking the amount allowed by
+// the refund policy of its event
+func (s *paymentService) HandleBookingCancelled(body []byte) error {
+	// Parse event
+	var event contracts.BookingCancelled
+	if _, err := contracts.Unmarshal(body, &event); err != nil {
+		return fmt.Errorf("failed to parse booking cancelled event: %w", err)
+	}
+
+	// Pending payments of a cancelled booking can no longer be processed
+	if err := s.cancelPendingPayments(event.BookingID); err != nil {
+		return err
+	}
+
+	// Nothing is refunded when the booking was not paid or the policy allows no refund
+	if event.RefundAmount <= 0 {
+		return nil
+	}
+
+	reason := event.RefundReason
+	if reason == "" {
+		reason = "booking cancelled"
+	}
+
+	return s.refundBookingAmount(event.BookingID, event.RefundAmount, reason, nil, contracts.TypeBookingCancelled+":"+event.BookingID.String())
+}
+
+// HandlePaymentRequested creates the pending payment of a new booking, which
+// the user then processes
+func (s *paymentService) HandlePaymentRequested(body []byte) error {
+	// Parse event
+	var event contracts.BookingPaymentRequested
+	if _, err := contracts.Unmarshal(body, &event); err != nil {
+		return fmt.Errorf("failed to parse booking payment requested event: %w", err)
+	}
+
+	// The user may have processed the payment before the request arrived
+	existingPayment, err := s.paymentRepo.FindByBookingID(event.BookingID)
+	if err != nil {
+		return fmt.Errorf("failed to check existing payment: %w", err)
+	}
+
+	if existingPayment != nil {
+		return nil
+	}
+
+	payment := &model.Payment{
+		UserID:    event.UserID,
+		BookingID: event.BookingID,
+		Amount:    event.Amount,
+		Status:    "pending",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.paymentRepo.WithTx(tx).Create(payment); err != nil {
+			return fmt.Errorf("failed to create payment: %w", err)
+		}
+
+		return s.publishPaymentEvent(tx, "payment.created", payment)
+	})
+}
+
+// HandleRefundRequested refunds a booking the booking saga gave up on after
+// its payment was captured
+func (s *paymentService) HandleRefundRequested(body []byte) error {
+	// Parse event
+	var event contracts.BookingRefundRequested
+	if _, err := contracts.Unmarshal(body, &event); err != nil {
+		return fmt.Errorf("failed to parse booking refund requested event: %w", err)
+	}
+
+	if event.RefundAmount <= 0 {
+		return nil
+	}
+
+	reason := event.RefundReason
+	if reason == "" {
+		reason = "booking saga compensation"
+	}
+
+	return s.refundBookingAmount(event.BookingID, event.RefundAmount, reason, nil, contracts.TypeBookingRefundRequested+":"+event.BookingID.String())
+}
+
+// cancelPendingPayments cancels the payments of a booking that are still
+// waiting to be processed
+func (s *paymentService) cancelPendingPayments(bookingID uuid.UUID) error {
+	for {
+		payment, err := s.paymentRepo.FindPendingByBookingID(bookingID)
+		if err != nil {
+			return fmt.Errorf("failed to find payment: %w", err)
+		}
+
+		if payment == nil {
+			return nil
+		}
+
+		payment.Status = "cancelled"
+		payment.UpdatedAt = time.Now()
+		err = s.db.Transaction(func(tx *gorm.DB) error {
+			if err := s.paymentRepo.WithTx(tx).Update(payment); err != nil {
+				return fmt.Errorf("failed to update payment: %w", err)
+			}
+
+			return s.publishPaymentEvent(tx, "payment.updated", payment)
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// HandleBookingAmended settles the price difference of an amended booking
+func (s *paymentService) HandleBookingAmended(body []byte) error {
+	// Parse event
+	var event contracts.BookingAmended
+	if _, err := contracts.Unmarshal(body, &event); err != nil {
+		return fmt.Errorf("failed to parse booking amended event: %w", err)
+	}
+
+	switch event.PaymentAction {
+	case "adjust":
+		// The booking is not paid yet, so its pending payment is charged the new total
+		payment, err := s.paymentRepo.FindPendingByBookingID(event.BookingID)
+		if err != nil {
+			return fmt.Errorf("failed to find payment: %w", err)
+		}
+
+		if payment == nil {
+			return nil
+		}
+
+		payment.Amount = event.NewTotal
+		payment.UpdatedAt = time.Now()
+		return s.db.Transaction(func(tx *gorm.DB) error {
+			if err := s.paymentRepo.WithTx(tx).Update(payment); err != nil {
+				return fmt.Errorf("failed to update payment: %w", err)
+			}
+
+			return s.publishPaymentEvent(tx, "payment.updated", payment)
+		})
+
+	case "charge":
+		// Request a supplementary payment with the details of the original one
+		original, err := s.paymentRepo.FindByBookingID(event.BookingID)
+		if err != nil {
+			return fmt.Errorf("failed to find payment: %w", err)
+		}
+
+		if original == nil {
+			return fmt.Errorf("payment not found")
+		}
+
+		amendmentID := event.AmendmentID
+		payment := &model.Payment{
+			UserID:        event.UserID,
+			BookingID:     event.BookingID,
+			AmendmentID:   &amendmentID,
+			Amount:        event.AmountDifference,
+			Currency:      original.Currency,
+			Status:        "pending",
+			PaymentMethod: original.PaymentMethod,
+			CreatedAt:     time.Now(),
+			UpdatedAt:     time.Now(),
+		}
+
+		return s.db.Transaction(func(tx *gorm.DB) error {
+			if err := s.paymentRepo.WithTx(tx).Create(payment); err != nil {
+				return fmt.Errorf("failed to create payment: %w", err)
+			}
+
+			return s.publishPaymentEvent(tx, "payment.created", payment)
+		})
+
+	case "refund":
+		amendmentID := event.AmendmentID
+		return s.refundBookingAmount(event.BookingID, -event.AmountDifference, "booking amended", &amendmentID, contracts.TypeBookingAmended+":"+amendmentID.String())
+	}
+
+	return nil
+}
+
+// refundBookingAmount refunds part of what was captured for a booking,
+// spreading the amount over its payments from the oldest. Source names the
+// event the refund is made for, so that a redelivered event resumes its
+// refunds instead of refunding again.
+func (s *paymentService) refundBookingAmount(bookingID uuid.UUID, amount float64, reason string, amendmentID *uuid.UUID, source string) error {
+	remaining := roundAmount(amount)
+
+	// Refunds of an earlier delivery were committed before the provider was
+	// called, so finish the pending ones and count them as made
+	refunds, err := s.refundRepo.FindBySource(source)
+	if err != nil {
+		return fmt.Errorf("failed to find refunds: %w", err)
+	}
+
+	for i := range refunds {
+		refund := &refunds[i]
+		switch refund.Status {
+		case "pending":
+			if _, err := s.processRefund(refund); err != nil {
+				return err
+			}
+		case "failed":
+			continue
+		}
+
+		remaining = roundAmount(remaining - refund.Amount)
+	}
+
+	if remaining <= 0 {
+		return nil
+	}
+
+	payments, err := s.paymentRepo.FindRefundableByBookingID(bookingID)
+	if err != nil {
+		return fmt.Errorf("failed to find payments: %w", err)
+	}
+
+	// Refuse the refund up front rather than refunding only part of it
+	refundable := 0.0
+	for _, payment := range payments {
+		refundable += payment.Amount - payment.RefundedAmount
+	}
+
+	if remaining > roundAmount(refundable) {
+		return fmt.Errorf("refund of %.2f exceeds the captured amount of booking %s", amount, bookingID)
+	}
+
+	for _, payment := range payments {
+		if remaining <= 0 {
+			break
+		}
+
+		part := math.Min(remaining, roundAmount(payment.Amount-payment.RefundedAmount))
+		if part <= 0 {
+			continue
+		}
+
+		if _, _, err := s.refund(payment.ID, part, reason, amendmentID, source); err != nil {
+			return err
+		}
+
+		remaining = roundAmount(remaining - part)
+	}
+
+	return nil
+}
+
+// refund refunds an amount of a captured payment. The amount is reserved on
+// the payment and committed before the provider is called, so refunds never
+// exceed the captured total even when the caller's transaction rolls back.
+func (s *paymentService) refund(paymentID uuid.UUID, amount float64, reason string, amendmentID *uuid.UUID, source string) (*model.Payment, *model.Refund, error) {
+	refund := &model.Refund{
+		PaymentID:   paymentID,
+		AmendmentID: amendmentID,
+		Source:      source,
+		Amount:      roundAmount(amount),
+		Reason:      reason,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	// Reserve refund amount on the payment
+	if _, err := s.refundRepo.Reserve(refund); err != nil {
+		return nil, nil, fmt.Errorf("failed to reserve refund: %w", err)
+	}
+
+	payment, err := s.processRefund(refund)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return payment, refund, nil
+}
+
+// processRefund refunds a reserved refund with the payment provider, keyed
+// by the refund ID so that a retry is not refunded twice, and completes it,
+// or releases its amount again when the provider fails
+func (s *paymentService) processRefund(refund *model.Refund) (*model.Payment, error) {
+	payment, err := s.paymentRepo.FindByID(refund.PaymentID)
 	if err != nil {
-		logrus.WithError(err).Error("Failed to marshal payment event")
-		return
+		return nil, fmt.Errorf("failed to find payment: %w", err)
+	}
+
+	if payment == nil {
+		return nil, fmt.Errorf("payment not found")
+	}
+
+	// Process refund with payment provider
+	if err := s.paymentProvider.RefundPayment(payment.TransactionID, refund.Amount, payment.Currency, refund.Reason, refund.ID.String()); err != nil {
+		refund.UpdatedAt = time.Now()
+		if failErr := s.refundRepo.Fail(refund, err.Error()); failErr != nil {
+			logrus.WithError(failErr).Errorf("Failed to release refund %s", refund.ID)
+		}
+		return nil, fmt.Errorf("failed to process refund: %w", err)
 	}
 
-	// Publish event to RabbitMQ
-	err = s.rmq.PublishMessage("payment_events", eventType, payloadJSON)
+	// Complete refund and write its event in the same transaction
+	refund.UpdatedAt = time.Now()
+	err = s.refundDB.Transaction(func(tx *gorm.DB) error {
+		completed, err := s.refundRepo.WithTx(tx).Complete(refund)
+		if err != nil {
+			return fmt.Errorf("failed to complete refund: %w", err)
+		}
+		payment = completed
+
+		// A full refund marks the booking refunded, except for amendments which keep it
+		eventType := "payment.partially_refunded"
+		if payment.Status == "refunded" && refund.AmendmentID == nil {
+			eventType = "payment.refunded"
+		}
+		return s.publishRefundEvent(tx, eventType, payment, refund)
+	})
 	if err != nil {
-		logrus.WithError(err).Error("Failed to publish payment event")
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+// publishRefundEvent writes a refund of a payment to the outbox in tx
+func (s *paymentService) publishRefundEvent(tx *gorm.DB, eventType string, payment *model.Payment, refund *model.Refund) error {
+	state := contracts.RefundState{
+		PaymentID:      payment.ID,
+		RefundID:       refund.ID,
+		UserID:         payment.UserID,
+		BookingID:      payment.BookingID,
+		AmendmentID:    refund.AmendmentID,
+		Amount:         payment.Amount,
+		RefundAmount:   refund.Amount,
+		RefundedAmount: payment.RefundedAmount,
+		Currency:       payment.Currency,
+		Status:         payment.Status,
+		Reason:         refund.Reason,
+	}
+
+	var event contracts.Event
+	switch eventType {
+	case contracts.TypePaymentRefunded:
+		event = contracts.PaymentRefunded{RefundState: state}
+	case contracts.TypePaymentPartiallyRefunded:
+		event = contracts.PaymentPartiallyRefunded{RefundState: state}
+	default:
+		return fmt.Errorf("unknown refund event type %s", eventType)
+	}
+
+	return messaging.EnqueueEvent(s.outboxRepo, tx, "payment_events", payment.BookingID.String(), event)
+}
+
+// HandleDataRequested answers a data export or erasure request of the user
+// service. Payments and refunds are financial records kept for accounting,
+// so an erasure retains them; they only refer to the user by ID, which the
+// erasure in the user service no longer links to a person.
+func (s *paymentService) HandleDataRequested(body []byte) error {
+	// Parse event
+	var event contracts.UserDataRequested
+	if _, err := contracts.Unmarshal(body, &event); err != nil {
+		return fmt.Errorf("failed to parse user data requested event: %w", err)
 	}
-}
\ No newline at end of file
+
+	export := model.PrivacyDataExport{
+		Payments:   []model.PaymentResponse{},
+		Refunds:    []model.Refund{},
+		ExportedAt: time.Now(),
+	}
+	for page := 1; ; page++ {
+		payments, total, err := s.paymentRepo.FindByUserID(event.UserID, page, 100)
+		if err != nil {
+			return fmt.Errorf("failed to find payments: %w", err)
+		}
+
+		for _, payment := range payments {
+			export.Payments = append(export.Payments, payment.ToResponse())
+
+			refunds, err := s.refundRepo.FindByPaymentID(payment.ID)
+			if err != nil {
+				return fmt.Errorf("failed to find refunds: %w", err)
+			}
+			export.Refunds = append(export.Refunds, refunds...)
+		}
+
+		if len(payments) == 0 || int64(page*100) >= total {
+			break
+		}
+	}
+
+	var answer contracts.Event
+	switch event.Mode {
+	case contracts.DataRequestExport:
+		data, err := json.Marshal(export)
+		if err != nil {
+			return err
+		}
+		answer = contracts.PrivacyDataCollected{
+			RequestID: event.RequestID,
+			UserID:    event.UserID,
+			Service:   model.PrivacyService,
+			Data:      data,
+		}
+	case contracts.DataRequestErasure:
+		answer = contracts.PrivacyDataErased{
+			RequestID: event.RequestID,
+			UserID:    event.UserID,
+			Service:   model.PrivacyService,
+			Retained:  len(export.Payments) + len(export.Refunds),
+		}
+	default:
+		logrus.Warnf("Ignoring data request with unknown mode: %s", event.Mode)
+		return nil
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"request_id": event.RequestID,
+		"user_id":    event.UserID,
+		"mode":       event.Mode,
+	}).Info("Answering data request")
+
+	// Payments and refunds are only read, so the answer is the single write
+	// of the request and needs no transaction of its own. A redelivered
+	// request answers again, and the user service ignores answers to parts
+	// it already completed.
+	return messaging.EnqueueEvent(s.outboxRepo, s.db, "privacy_events", event.RequestID.String(), answer)
+}
+
+// roundAmount rounds a monetary amount to cents
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// publishPaymentEvent writes a payment event to the outbox in tx
+func (s *paymentService) publishPaymentEvent(tx *gorm.DB, eventType string, payment *model.Payment) error {
+	// Supplementary payments carry the amendment of the booking they belong to
+	state := contracts.PaymentState{
+		PaymentID:     payment.ID,
+		UserID:        payment.UserID,
+		BookingID:     payment.BookingID,
+		AmendmentID:   payment.AmendmentID,
+		Amount:        payment.Amount,
+		Currency:      payment.Currency,
+		Status:        payment.Status,
+		PaymentMethod: payment.PaymentMethod,
+	}
+
+	var event contracts.Event
+	switch eventType {
+	case contracts.TypePaymentCreated:
+		event = contracts.PaymentCreated{PaymentState: state}
+	case contracts.TypePaymentUpdated:
+		event = contracts.PaymentUpdated{PaymentState: state}
+	case contracts.TypePaymentCompleted:
+		event = contracts.PaymentCompleted{PaymentState: state}
+	case contracts.TypePaymentFailed:
+		event = contracts.PaymentFailed{PaymentState: state}
+	default:
+		return fmt.Errorf("unknown payment event type %s", eventType)
+	}
+
+	return messaging.EnqueueEvent(s.outboxRepo, tx, "payment_events", payment.BookingID.String(), event)
+}
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"github.com/yourusername/ticket-system/user-service/service"
+)
+
+// AccountHandler handles HTTP requests for email verification and password reset
+type AccountHandler struct {
+	accountService service.AccountService
+}
+
+// NewAccountHandler creates a new account handler
+func NewAccountHandler(accountService service.AccountService) *AccountHandler {
+	return &AccountHandler{
+		accountService: accountService,
+	}
+}
+
+// RequestVerification handles sending a new email verification link
+func (h *AccountHandler) RequestVerification(c *gin.Context) {
+	var req model.EmailRequest
+
+	// Bind JSON request to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Call service to send the verification email
+	if err := h.accountService.RequestVerification(req.Email); err != nil {
+		logrus.WithError(err).Error("Failed to request email verification")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Return the same response whether or not the email was sent
+	c.JSON(http.StatusAccepted, gin.H{"message": "If the email address needs verification, a verification link has been sent"})
+}
+
+// VerifyEmail handles verifying an email address with a token
+func (h *AccountHandler) VerifyEmail(c *gin.Context) {
+	var req model.VerifyEmailRequest
+
+	// Bind JSON request to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Call service to verify the email address
+	user, err := h.accountService.VerifyEmail(req.Token)
+	if err != nil {
+		switch err.Error() {
+		case "invalid or expired token":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			logrus.WithError(err).Error("Failed to verify email")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	// Return success response
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Email verified successfully",
+		"user":    user,
+	})
+}
+
+// RequestPasswordReset handles sending a password reset link
+func (h *AccountHandler) RequestPasswordReset(c *gin.Context) {
+	var req model.EmailRequest
+
+	// Bind JSON request to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Call service to send the password reset email
+	if err := h.accountService.RequestPasswordReset(req.Email); err != nil {
+		logrus.WithError(err).Error("Failed to request password reset")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Return the same response whether or not the email was sent
+	c.JSON(http.StatusAccepted, gin.H{"message": "If an account exists for the email address, a password reset link has been sent"})
+}
+
+// ResetPassword handles setting a new password with a password reset token
+func (h *AccountHandler) ResetPassword(c *gin.Context) {
+	var req model.ResetPasswordRequest
+
+	// Bind JSON request to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Call service to reset the password
+	if err := h.accountService.ResetPassword(req); err != nil {
+		switch err.Error() {
+		case "invalid or expired token":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			logrus.WithError(err).Error("Failed to reset password")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	// Return success response
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+}
+
+// SetupRoutes sets up the account routes, all of which are public
+func (h *AccountHandler) SetupRoutes(router *gin.Engine) {
+	public := router.Group("/api/users")
+	{
+		public.POST("/verify-email/request", h.RequestVerification)
+		public.POST("/verify-email/confirm", h.VerifyEmail)
+		public.POST("/password-reset/request", h.RequestPasswordReset)
+		public.POST("/password-reset/confirm", h.ResetPassword)
+	}
+}
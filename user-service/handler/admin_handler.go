@@ -0,0 +1,350 @@
+package handler
+
+import (
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"github.com/yourusername/ticket-system/user-service/service"
+)
+
+// AdminHandler handles HTTP requests for managing user accounts
+type AdminHandler struct {
+	userService  service.UserService
+	mfaService   service.MFAService
+	adminService service.AdminService
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(userService service.UserService, mfaService service.MFAService, adminService service.AdminService) *AdminHandler {
+	return &AdminHandler{
+		userService:  userService,
+		mfaService:   mfaService,
+		adminService: adminService,
+	}
+}
+
+// SearchUsers handles searching users by email, name, phone number and role
+func (h *AdminHandler) SearchUsers(c *gin.Context) {
+	if _, ok := currentAdmin(c); !ok {
+		return
+	}
+
+	var req model.UserSearchRequest
+
+	// Bind query parameters to struct
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Call service to search users
+	users, total, err := h.adminService.SearchUsers(req)
+	if err != nil {
+		h.handleError(c, err, "Failed to search users")
+		return
+	}
+
+	// Return success response
+	c.JSON(http.StatusOK, gin.H{
+		"users":     users,
+		"total":     total,
+		"page":      req.Page,
+		"page_size": req.PageSize,
+	})
+}
+
+// GetActivity handles getting the activity summary of a user
+func (h *AdminHandler) GetActivity(c *gin.Context) {
+	userID, ok := h.targetUserID(c)
+	if !ok {
+		return
+	}
+
+	// Call service to summarise activity
+	activity, err := h.adminService.GetActivity(userID)
+	if err != nil {
+		h.handleError(c, err, "Failed to get user activity")
+		return
+	}
+
+	// Return success response
+	c.JSON(http.StatusOK, activity)
+}
+
+// ChangeRole handles changing the role of a user
+func (h *AdminHandler) ChangeRole(c *gin.Context) {
+	userID, ok := h.targetUserID(c)
+	if !ok {
+		return
+	}
+
+	var req model.ChangeRoleRequest
+
+	// Bind JSON request to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Call service to change role
+	adminID, _ := c.Get("user_id")
+	user, err := h.adminService.ChangeRole(adminID.(uuid.UUID), userID, req.Role, clientInfo(c))
+	if err != nil {
+		h.handleError(c, err, "Failed to change role")
+		return
+	}
+
+	// Return success response
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Role changed successfully",
+		"user":    user,
+	})
+}
+
+// Impersonate handles signing an admin in as a user
+func (h *AdminHandler) Impersonate(c *gin.Context) {
+	userID, ok := h.targetUserID(c)
+	if !ok {
+		return
+	}
+
+	var req model.ImpersonateRequest
+
+	// Bind JSON request to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Call service to impersonate user
+	adminID, _ := c.Get("user_id")
+	response, err := h.adminService.Impersonate(adminID.(uuid.UUID), userID, req.Reason, clientInfo(c))
+	if err != nil {
+		h.handleError(c, err, "Failed to impersonate user")
+		return
+	}
+
+	// Return success response
+	c.JSON(http.StatusOK, response)
+}
+
+// ImportStaff handles a bulk import of staff accounts from a CSV or JSON
+// lines file
+func (h *AdminHandler) ImportStaff(c *gin.Context) {
+	adminID, ok := currentAdmin(c)
+	if !ok {
+		return
+	}
+
+	// Parse dry run flag
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dry_run"})
+		return
+	}
+
+	// Read the file from a multipart upload or the raw request body
+	format := c.Query("format")
+	body := c.Request.Body
+	if file, err := c.FormFile("file"); err == nil {
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(file.Filename)), ".")
+		}
+		upload, err := file.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+			return
+		}
+		defer upload.Close()
+		body = upload
+	} else if format == "" && strings.Contains(c.ContentType(), "csv") {
+		format = model.ImportFormatCSV
+	} else if format == "" {
+		format = model.ImportFormatJSONL
+	}
+
+	// Import staff accounts
+	result, err := h.adminService.ImportStaff(adminID, body, format, dryRun, clientInfo(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Invalid rows reject the whole import
+	if len(result.Errors) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, result)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// SuspendUser handles suspending a user
+func (h *AdminHandler) SuspendUser(c *gin.Context) {
+	userID, ok := h.targetUserID(c)
+	if !ok {
+		return
+	}
+
+	// Call service to suspend user
+	user, err := h.userService.SuspendUser(userID)
+	if err != nil {
+		h.handleError(c, err, "Failed to suspend user")
+		return
+	}
+
+	// Return success response
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User suspended successfully",
+		"user":    user,
+	})
+}
+
+// ReactivateUser handles lifting the suspension of a user
+func (h *AdminHandler) ReactivateUser(c *gin.Context) {
+	userID, ok := h.targetUserID(c)
+	if !ok {
+		return
+	}
+
+	// Call service to reactivate user
+	user, err := h.userService.ReactivateUser(userID)
+	if err != nil {
+		h.handleError(c, err, "Failed to reactivate user")
+		return
+	}
+
+	// Return success response
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User reactivated successfully",
+		"user":    user,
+	})
+}
+
+// UnlockUser handles lifting the lockout of a user after too many failed
+// logins
+func (h *AdminHandler) UnlockUser(c *gin.Context) {
+	userID, ok := h.targetUserID(c)
+	if !ok {
+		return
+	}
+
+	// Call service to unlock user
+	if err := h.userService.UnlockUser(userID); err != nil {
+		h.handleError(c, err, "Failed to unlock user")
+		return
+	}
+
+	// Return success response
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User unlocked successfully",
+	})
+}
+
+// DeleteUser handles deleting a user
+func (h *AdminHandler) DeleteUser(c *gin.Context) {
+	userID, ok := h.targetUserID(c)
+	if !ok {
+		return
+	}
+
+	// Call service to delete user
+	if err := h.userService.DeleteUser(userID); err != nil {
+		h.handleError(c, err, "Failed to delete user")
+		return
+	}
+
+	// Return success response
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User deleted successfully",
+	})
+}
+
+// ResetMFA handles removing the two-factor authentication of a user who has
+// lost their authenticator and recovery codes
+func (h *AdminHandler) ResetMFA(c *gin.Context) {
+	userID, ok := h.targetUserID(c)
+	if !ok {
+		return
+	}
+
+	// Call service to reset two-factor authentication
+	if err := h.mfaService.Reset(userID); err != nil {
+		h.handleError(c, err, "Failed to reset MFA")
+		return
+	}
+
+	// Return success response
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Two-factor authentication reset successfully",
+	})
+}
+
+// targetUserID checks that the caller is an admin and returns the ID of the
+// user to manage. Admins cannot manage their own account, so that they do
+// not lock themselves out.
+func (h *AdminHandler) targetUserID(c *gin.Context) (uuid.UUID, bool) {
+	// Check if user is admin
+	role, exists := c.Get("role")
+	if !exists || role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only admins can manage users"})
+		return uuid.Nil, false
+	}
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return uuid.Nil, false
+	}
+
+	if adminID, _ := c.Get("user_id"); adminID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "admins cannot manage their own account"})
+		return uuid.Nil, false
+	}
+
+	return userID, true
+}
+
+// handleError maps user service errors to HTTP responses
+func (h *AdminHandler) handleError(c *gin.Context, err error, message string) {
+	switch err.Error() {
+	case "user not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "invalid role":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case "admins cannot be impersonated":
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case "user is already suspended", "user is not suspended", "two-factor authentication is not enabled",
+		"user already has this role", "user is suspended":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		logrus.WithError(err).Error(message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// SetupRoutes sets up the admin routes, which need a second factor when the
+// admin role requires one
+func (h *AdminHandler) SetupRoutes(router *gin.Engine, authMiddleware, mfaMiddleware gin.HandlerFunc) {
+	// Create admin routes group
+	adminRoutes := router.Group("/api/admin/users")
+	adminRoutes.Use(authMiddleware, mfaMiddleware)
+
+	// Set up routes
+	adminRoutes.GET("", h.SearchUsers)
+	adminRoutes.POST("/import", h.ImportStaff)
+	adminRoutes.GET("/:id/activity", h.GetActivity)
+	adminRoutes.PUT("/:id/role", h.ChangeRole)
+	adminRoutes.POST("/:id/impersonate", h.Impersonate)
+	adminRoutes.POST("/:id/suspend", h.SuspendUser)
+	adminRoutes.POST("/:id/reactivate", h.ReactivateUser)
+	adminRoutes.POST("/:id/unlock", h.UnlockUser)
+	adminRoutes.DELETE("/:id", h.DeleteUser)
+	adminRoutes.DELETE("/:id/mfa", h.ResetMFA)
+}
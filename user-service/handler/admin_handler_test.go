@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/yourusername/ticket-system/user-service/middleware"
+	"github.com/yourusername/ticket-system/user-service/model"
+)
+
+// withCaller sets the user the request is made by, like the JWT middleware
+func withCaller(userID uuid.UUID, role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Set("role", role)
+		c.Next()
+	}
+}
+
+func TestAdminHandler_SuspendUser(t *testing.T) {
+	adminID := uuid.New()
+	userID := uuid.New()
+
+	t.Run("successful suspension", func(t *testing.T) {
+		mockService := new(MockUserService)
+		mockService.On("SuspendUser", userID).Return(&model.UserResponse{ID: userID, Active: false}, nil)
+
+		router := setupTestRouter()
+		NewAdminHandler(mockService, nil, nil).SetupRoutes(router, withCaller(adminID, "admin"), middleware.RequireMFA(nil))
+
+		request := httptest.NewRequest(http.MethodPost, "/api/admin/users/"+userID.String()+"/suspend", nil)
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusOK, response.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("not an admin", func(t *testing.T) {
+		mockService := new(MockUserService)
+
+		router := setupTestRouter()
+		NewAdminHandler(mockService, nil, nil).SetupRoutes(router, withCaller(adminID, "user"), middleware.RequireMFA(nil))
+
+		request := httptest.NewRequest(http.MethodPost, "/api/admin/users/"+userID.String()+"/suspend", nil)
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusForbidden, response.Code)
+		mockService.AssertNotCalled(t, "SuspendUser", userID)
+	})
+
+	t.Run("own account", func(t *testing.T) {
+		mockService := new(MockUserService)
+
+		router := setupTestRouter()
+		NewAdminHandler(mockService, nil, nil).SetupRoutes(router, withCaller(adminID, "admin"), middleware.RequireMFA(nil))
+
+		request := httptest.NewRequest(http.MethodPost, "/api/admin/users/"+adminID.String()+"/suspend", nil)
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+		mockService.AssertNotCalled(t, "SuspendUser", adminID)
+	})
+
+	t.Run("already suspended", func(t *testing.T) {
+		mockService := new(MockUserService)
+		mockService.On("SuspendUser", userID).Return(nil, errors.New("user is already suspended"))
+
+		router := setupTestRouter()
+		NewAdminHandler(mockService, nil, nil).SetupRoutes(router, withCaller(adminID, "admin"), middleware.RequireMFA(nil))
+
+		request := httptest.NewRequest(http.MethodPost, "/api/admin/users/"+userID.String()+"/suspend", nil)
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusConflict, response.Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestAdminHandler_DeleteUser(t *testing.T) {
+	adminID := uuid.New()
+
+	t.Run("user not found", func(t *testing.T) {
+		userID := uuid.New()
+		mockService := new(MockUserService)
+		mockService.On("DeleteUser", userID).Return(errors.New("user not found"))
+
+		router := setupTestRouter()
+		NewAdminHandler(mockService, nil, nil).SetupRoutes(router, withCaller(adminID, "admin"), middleware.RequireMFA(nil))
+
+		request := httptest.NewRequest(http.MethodDelete, "/api/admin/users/"+userID.String(), nil)
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+
+		assert.Equal(t, http.StatusNotFound, response.Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestAdminHandler_RequiresMFA(t *testing.T) {
+	adminID := uuid.New()
+	userID := uuid.New()
+
+	mockService := new(MockUserService)
+
+	router := setupTestRouter()
+	requiredRoles := map[string]bool{"admin": true}
+	NewAdminHandler(mockService, nil, nil).SetupRoutes(router, withCaller(adminID, "admin"), middleware.RequireMFA(requiredRoles))
+
+	request := httptest.NewRequest(http.MethodPost, "/api/admin/users/"+userID.String()+"/suspend", nil)
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	assert.Equal(t, http.StatusForbidden, response.Code)
+	mockService.AssertNotCalled(t, "SuspendUser", userID)
+}
+
+func TestAdminHandler_ImpersonationCannotChangePassword(t *testing.T) {
+	userID := uuid.New()
+	mockService := new(MockUserService)
+
+	impersonated := func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Set("role", "user")
+		c.Set("impersonator_id", uuid.New())
+		c.Next()
+	}
+
+	router := setupTestRouter()
+	NewUserHandler(mockService).SetupRoutes(router, impersonated)
+
+	request := httptest.NewRequest(http.MethodPost, "/api/users/change-password", strings.NewReader(`{"current_password":"password123","new_password":"password456"}`))
+	request.Header.Set("Content-Type", "application/json")
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, request)
+
+	assert.Equal(t, http.StatusForbidden, response.Code)
+	mockService.AssertNotCalled(t, "ChangePassword", mock.Anything, mock.Anything)
+}
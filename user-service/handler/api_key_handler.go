@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/user-service/middleware"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"github.com/yourusername/ticket-system/user-service/service"
+)
+
+// APIKeyHandler handles HTTP requests related to the API keys of
+// organizations
+type APIKeyHandler struct {
+	apiKeyService service.APIKeyService
+}
+
+// NewAPIKeyHandler creates a new API key handler
+func NewAPIKeyHandler(apiKeyService service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+	}
+}
+
+// CreateAPIKey handles creating an API key for an organization. The secret
+// of the key is only returned in this response.
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	userID, organizationID, ok := h.organizationParams(c)
+	if !ok {
+		return
+	}
+
+	var req model.CreateAPIKeyRequest
+
+	// Bind JSON request to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Call service to create API key
+	apiKey, err := h.apiKeyService.CreateAPIKey(userID, organizationID, req)
+	if err != nil {
+		h.handleError(c, err, "Failed to create API key")
+		return
+	}
+
+	// Return success response
+	c.JSON(http.StatusCreated, apiKey)
+}
+
+// ListAPIKeys handles listing the API keys of an organization
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	userID, organizationID, ok := h.organizationParams(c)
+	if !ok {
+		return
+	}
+
+	// Call service to list API keys
+	apiKeys, err := h.apiKeyService.GetAPIKeys(userID, organizationID)
+	if err != nil {
+		h.handleError(c, err, "Failed to list API keys")
+		return
+	}
+
+	// Return success response
+	c.JSON(http.StatusOK, gin.H{
+		"api_keys": apiKeys,
+	})
+}
+
+// RotateAPIKey handles replacing an API key by a new one. The old key keeps
+// working for a grace period.
+func (h *APIKeyHandler) RotateAPIKey(c *gin.Context) {
+	userID, organizationID, ok := h.organizationParams(c)
+	if !ok {
+		return
+	}
+
+	keyID, err := uuid.Parse(c.Param("keyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID"})
+		return
+	}
+
+	// Call service to rotate API key
+	apiKey, err := h.apiKeyService.RotateAPIKey(userID, organizationID, keyID)
+	if err != nil {
+		h.handleError(c, err, "Failed to rotate API key")
+		return
+	}
+
+	// Return success response
+	c.JSON(http.StatusCreated, apiKey)
+}
+
+// RevokeAPIKey handles revoking an API key
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	userID, organizationID, ok := h.organizationParams(c)
+	if !ok {
+		return
+	}
+
+	keyID, err := uuid.Parse(c.Param("keyId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID"})
+		return
+	}
+
+	// Call service to revoke API key
+	if err := h.apiKeyService.RevokeAPIKey(userID, organizationID, keyID); err != nil {
+		h.handleError(c, err, "Failed to revoke API key")
+		return
+	}
+
+	// Return success response
+	c.JSON(http.StatusOK, gin.H{
+		"message": "API key revoked successfully",
+	})
+}
+
+// IssueToken handles exchanging an API key for a short-lived access token,
+// for the API gateway
+func (h *APIKeyHandler) IssueToken(c *gin.Context) {
+	var req model.APIKeyTokenRequest
+
+	// Bind JSON request to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Call service to issue token
+	token, err := h.apiKeyService.IssueToken(req.Key)
+	if err != nil {
+		h.handleError(c, err, "Failed to issue API key token")
+		return
+	}
+
+	// Return success response
+	c.JSON(http.StatusOK, token)
+}
+
+// organizationParams returns the ID of the current user and of the
+// organization in the URL
+func (h *APIKeyHandler) organizationParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	userID, _, ok := currentSession(c)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return userID, organizationID, true
+}
+
+// handleError maps API key service errors to HTTP responses
+func (h *APIKeyHandler) handleError(c *gin.Context, err error, message string) {
+	switch err.Error() {
+	case "organization not found", "API key not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "invalid API key scope", "expiry must be in the future":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case "invalid or expired API key":
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case "insufficient organization permissions":
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		logrus.WithError(err).Error(message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// SetupRoutes sets up the API key routes. Admins impersonating a user cannot
+// manage the API keys of the organizations of the user.
+func (h *APIKeyHandler) SetupRoutes(router *gin.Engine, authMiddleware gin.HandlerFunc) {
+	apiKeyRoutes := router.Group("/api/organizations/:id/api-keys")
+	apiKeyRoutes.Use(authMiddleware, middleware.RejectImpersonation())
+	{
+		apiKeyRoutes.POST("", h.CreateAPIKey)
+		apiKeyRoutes.GET("", h.ListAPIKeys)
+		apiKeyRoutes.POST("/:keyId/rotate", h.RotateAPIKey)
+		apiKeyRoutes.DELETE("/:keyId", h.RevokeAPIKey)
+	}
+
+	// Internal routes, not exposed by the API gateway
+	internal := router.Group("/internal/api-keys")
+	{
+		internal.POST("/token", h.IssueToken)
+	}
+}
@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"github.com/yourusername/ticket-system/user-service/service"
+)
+
+// DataRequestHandler handles HTTP requests for exporting and erasing the
+// personal data of users
+type DataRequestHandler struct {
+	dataRequestService service.DataRequestService
+}
+
+// NewDataRequestHandler creates a new data request handler
+func NewDataRequestHandler(dataRequestService service.DataRequestService) *DataRequestHandler {
+	return &DataRequestHandler{
+		dataRequestService: dataRequestService,
+	}
+}
+
+// CreateDataRequest handles starting a data export or erasure of a user
+func (h *DataRequestHandler) CreateDataRequest(c *gin.Context) {
+	adminID, ok := currentAdmin(c)
+	if !ok {
+		return
+	}
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	if userID == adminID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "admins cannot manage their own account"})
+		return
+	}
+
+	var req model.CreateDataRequestRequest
+
+	// Bind JSON request to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Call service to start data request
+	request, err := h.dataRequestService.CreateRequest(userID, adminID, req.Mode)
+	if err != nil {
+		h.handleError(c, err, "Failed to create data request")
+		return
+	}
+
+	// Return success response
+	c.JSON(http.StatusAccepted, request)
+}
+
+// ListDataRequests handles listing the data requests of a user
+func (h *DataRequestHandler) ListDataRequests(c *gin.Context) {
+	if _, ok := currentAdmin(c); !ok {
+		return
+	}
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	// Call service to list data requests
+	requests, err := h.dataRequestService.GetUserRequests(userID)
+	if err != nil {
+		h.handleError(c, err, "Failed to list data requests")
+		return
+	}
+
+	// Return success response
+	c.JSON(http.StatusOK, gin.H{
+		"data_requests": requests,
+	})
+}
+
+// GetDataRequest handles getting a data request with the status of every
+// service
+func (h *DataRequestHandler) GetDataRequest(c *gin.Context) {
+	requestID, ok := h.requestID(c)
+	if !ok {
+		return
+	}
+
+	// Call service to get data request
+	request, err := h.dataRequestService.GetRequest(requestID)
+	if err != nil {
+		h.handleError(c, err, "Failed to get data request")
+		return
+	}
+
+	// Return success response
+	c.JSON(http.StatusOK, request)
+}
+
+// DownloadArchive handles downloading the ZIP archive of a completed export
+func (h *DataRequestHandler) DownloadArchive(c *gin.Context) {
+	requestID, ok := h.requestID(c)
+	if !ok {
+		return
+	}
+
+	// Call service to build archive
+	archive, err := h.dataRequestService.Archive(requestID)
+	if err != nil {
+		h.handleError(c, err, "Failed to build data export archive")
+		return
+	}
+
+	// Return archive as attachment
+	c.Header("Content-Disposition", `attachment; filename="data-export-`+requestID.String()+`.zip"`)
+	c.Data(http.StatusOK, "application/zip", archive)
+}
+
+// requestID checks that the caller is an admin and returns the ID of the
+// data request in the path
+func (h *DataRequestHandler) requestID(c *gin.Context) (uuid.UUID, bool) {
+	if _, ok := currentAdmin(c); !ok {
+		return uuid.Nil, false
+	}
+
+	requestID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid data request ID"})
+		return uuid.Nil, false
+	}
+	return requestID, true
+}
+
+// handleError maps data request service errors to HTTP responses
+func (h *DataRequestHandler) handleError(c *gin.Context, err error, message string) {
+	switch err.Error() {
+	case "user not found", "data request not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "invalid data request mode", "data request is not an export":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case "data request is already pending", "data export is not completed":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case "data export has expired":
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	default:
+		logrus.WithError(err).Error(message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// SetupRoutes sets up the data request routes, which need a second factor
+// when the admin role requires one
+func (h *DataRequestHandler) SetupRoutes(router *gin.Engine, authMiddleware, mfaMiddleware gin.HandlerFunc) {
+	userRoutes := router.Group("/api/admin/users/:id/data-requests")
+	userRoutes.Use(authMiddleware, mfaMiddleware)
+	{
+		userRoutes.POST("", h.CreateDataRequest)
+		userRoutes.GET("", h.ListDataRequests)
+	}
+
+	requestRoutes := router.Group("/api/admin/data-requests")
+	requestRoutes.Use(authMiddleware, mfaMiddleware)
+	{
+		requestRoutes.GET("/:id", h.GetDataRequest)
+		requestRoutes.GET("/:id/archive", h.DownloadArchive)
+	}
+}
+
+// currentAdmin returns the ID of the admin making the request
+func currentAdmin(c *gin.Context) (uuid.UUID, bool) {
+	role, exists := c.Get("role")
+	if !exists || role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only admins can manage users"})
+		return uuid.Nil, false
+	}
+
+	adminID, ok := c.Get("user_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return uuid.Nil, false
+	}
+	return adminID.(uuid.UUID), true
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/user-service/service"
+)
+
+// JWKSHandler publishes the public keys that verify access tokens
+type JWKSHandler struct {
+	keyService service.KeyService
+}
+
+// NewJWKSHandler creates a new JWKS handler
+func NewJWKSHandler(keyService service.KeyService) *JWKSHandler {
+	return &JWKSHandler{
+		keyService: keyService,
+	}
+}
+
+// GetJWKS handles getting the JSON Web Key Set of the signing keys
+func (h *JWKSHandler) GetJWKS(c *gin.Context) {
+	// Call service to get the public keys
+	set, err := h.keyService.JWKS()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to get JWKS")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Clients refetch the key set when they see a new key ID, so it can be
+	// cached for a short while
+	c.Header("Cache-Control", "public, max-age=60")
+
+	// Return success response
+	c.JSON(http.StatusOK, set)
+}
+
+// SetupRoutes sets up the JWKS routes
+func (h *JWKSHandler) SetupRoutes(router *gin.Engine) {
+	router.GET("/.well-known/jwks.json", h.GetJWKS)
+}
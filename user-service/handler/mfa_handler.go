@@ -0,0 +1,190 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/user-service/middleware"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"github.com/yourusername/ticket-system/user-service/service"
+)
+
+// MFAHandler handles HTTP requests for two-factor authentication
+type MFAHandler struct {
+	mfaService service.MFAService
+}
+
+// NewMFAHandler creates a new MFA handler
+func NewMFAHandler(mfaService service.MFAService) *MFAHandler {
+	return &MFAHandler{
+		mfaService: mfaService,
+	}
+}
+
+// GetStatus handles getting the two-factor authentication status of the
+// current user
+func (h *MFAHandler) GetStatus(c *gin.Context) {
+	userID, _, ok := currentSession(c)
+	if !ok {
+		return
+	}
+
+	// Call service to get the status
+	status, err := h.mfaService.Status(userID)
+	if err != nil {
+		h.handleError(c, err, "Failed to get MFA status")
+		return
+	}
+
+	// Return success response
+	c.JSON(http.StatusOK, gin.H{
+		"mfa": status,
+	})
+}
+
+// Enroll handles generating a new TOTP secret for the current user
+func (h *MFAHandler) Enroll(c *gin.Context) {
+	userID, _, ok := currentSession(c)
+	if !ok {
+		return
+	}
+
+	// Call service to generate a secret
+	enrollment, err := h.mfaService.Enroll(userID)
+	if err != nil {
+		h.handleError(c, err, "Failed to enroll MFA")
+		return
+	}
+
+	// Secrets must not end up in caches
+	c.Header("Cache-Control", "no-store")
+
+	// Return success response
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// Confirm handles enabling two-factor authentication with a first code
+func (h *MFAHandler) Confirm(c *gin.Context) {
+	userID, sessionID, ok := currentSession(c)
+	if !ok {
+		return
+	}
+
+	var req model.MFACodeRequest
+
+	// Bind JSON request to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Call service to enable two-factor authentication
+	recoveryCodes, tokens, err := h.mfaService.Confirm(userID, sessionID, req.Code)
+	if err != nil {
+		h.handleError(c, err, "Failed to confirm MFA")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+
+	// Return success response
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Two-factor authentication enabled successfully",
+		"recovery_codes": recoveryCodes,
+		"token":          tokens.Token,
+		"expires_in":     tokens.ExpiresIn,
+	})
+}
+
+// RegenerateRecoveryCodes handles replacing the recovery codes of the
+// current user
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, _, ok := currentSession(c)
+	if !ok {
+		return
+	}
+
+	var req model.MFACodeRequest
+
+	// Bind JSON request to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Call service to replace the recovery codes
+	recoveryCodes, err := h.mfaService.RegenerateRecoveryCodes(userID, req.Code)
+	if err != nil {
+		h.handleError(c, err, "Failed to regenerate recovery codes")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+
+	// Return success response
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Recovery codes regenerated successfully",
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+// Disable handles turning off two-factor authentication for the current user
+func (h *MFAHandler) Disable(c *gin.Context) {
+	userID, _, ok := currentSession(c)
+	if !ok {
+		return
+	}
+
+	var req model.DisableMFARequest
+
+	// Bind JSON request to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Call service to disable two-factor authentication
+	if err := h.mfaService.Disable(userID, req); err != nil {
+		h.handleError(c, err, "Failed to disable MFA")
+		return
+	}
+
+	// Return success response
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Two-factor authentication disabled successfully",
+	})
+}
+
+// handleError maps MFA service errors to HTTP responses
+func (h *MFAHandler) handleError(c *gin.Context, err error, message string) {
+	switch err.Error() {
+	case "user not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "invalid code", "password is incorrect":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case "two-factor authentication is required for your role":
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case "two-factor authentication is already enabled", "two-factor authentication is not enabled", "two-factor authentication is not enrolled":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		logrus.WithError(err).Error(message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// SetupRoutes sets up the MFA routes. They only need a password login, so
+// that users whose role requires two-factor authentication can set it up.
+// Admins impersonating a user cannot change the second factor of the user.
+func (h *MFAHandler) SetupRoutes(router *gin.Engine, authMiddleware gin.HandlerFunc) {
+	// Protected routes
+	protected := router.Group("/api/users/mfa")
+	protected.Use(authMiddleware, middleware.RejectImpersonation())
+	{
+		protected.GET("", h.GetStatus)
+		protected.POST("/enroll", h.Enroll)
+		protected.POST("/confirm", h.Confirm)
+		protected.POST("/recovery-codes", h.RegenerateRecoveryCodes)
+		protected.DELETE("", h.Disable)
+	}
+}
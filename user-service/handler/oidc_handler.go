@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"github.com/yourusername/ticket-system/user-service/service"
+)
+
+// OIDCHandler handles HTTP requests for signing in with OpenID Connect
+// identity providers
+type OIDCHandler struct {
+	userService service.UserService
+	oidcService service.OIDCService
+}
+
+// NewOIDCHandler creates a new OIDC handler
+func NewOIDCHandler(userService service.UserService, oidcService service.OIDCService) *OIDCHandler {
+	return &OIDCHandler{
+		userService: userService,
+		oidcService: oidcService,
+	}
+}
+
+// ListProviders handles listing the identity providers users can sign in with
+func (h *OIDCHandler) ListProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"providers": h.oidcService.Providers(),
+	})
+}
+
+// Authorize handles starting a login by redirecting to the identity provider
+func (h *OIDCHandler) Authorize(c *gin.Context) {
+	// Call service to start the login
+	authURL, err := h.oidcService.StartLogin(c.Param("provider"))
+	if err != nil {
+		h.handleError(c, err, "Failed to start OIDC login")
+		return
+	}
+
+	// Redirect to the identity provider
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback handles completing a login with the code the identity provider
+// returned. Providers redirect to it with a query, or post it as a form.
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	// Identity providers report errors like a denied consent in the callback
+	if providerError := callbackParam(c, "error"); providerError != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "identity provider returned " + providerError})
+		return
+	}
+
+	var req model.OIDCCallbackRequest
+
+	// Bind query or form to struct
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Call service to sign the user in
+	response, err := h.userService.LoginOIDC(c.Param("provider"), req, clientInfo(c))
+	if err != nil {
+		h.handleError(c, err, "Failed to complete OIDC login")
+		return
+	}
+
+	writeLoginResponse(c, response)
+}
+
+// handleError maps OIDC service errors to HTTP responses
+func (h *OIDCHandler) handleError(c *gin.Context, err error, message string) {
+	switch err.Error() {
+	case "unknown identity provider":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "invalid or expired login state", "identity provider login failed", "user account is suspended", "user not found":
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case "email address is not verified by the identity provider":
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case "an account with this email address exists but is not verified":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		logrus.WithError(err).Error(message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// callbackParam returns a parameter of the callback from the query or the form
+func callbackParam(c *gin.Context, key string) string {
+	if value := c.Query(key); value != "" {
+		return value
+	}
+	return c.PostForm(key)
+}
+
+// SetupRoutes sets up the OIDC routes, all of which are public
+func (h *OIDCHandler) SetupRoutes(router *gin.Engine) {
+	public := router.Group("/api/users/oidc")
+	{
+		public.GET("/providers", h.ListProviders)
+		public.GET("/:provider/authorize", h.Authorize)
+		public.GET("/:provider/callback", h.Callback)
+		public.POST("/:provider/callback", h.Callback)
+	}
+}
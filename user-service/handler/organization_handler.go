@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"github.com/yourusername/ticket-system/user-service/service"
+)
+
+// OrganizationHandler handles HTTP requests related to organizations and
+// their members
+type OrganizationHandler struct {
+	organizationService service.OrganizationService
+}
+
+// NewOrganizationHandler creates a new organization handler
+func NewOrganizationHandler(organizationService service.OrganizationService) *OrganizationHandler {
+	return &OrganizationHandler{
+		organizationService: organizationService,
+	}
+}
+
+// CreateOrganization handles creating an organization owned by the current
+// user. Only organizers and admins can create organizations.
+func (h *OrganizationHandler) CreateOrganization(c *gin.Context) {
+	userID, _, ok := currentSession(c)
+	if !ok {
+		return
+	}
+
+	if role, _ := c.Get("role"); role != "organizer" && role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only organizers can create organizations"})
+		return
+	}
+
+	var req model.CreateOrganizationRequest
+
+	// Bind JSON request to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Call service to create organization
+	organization, err := h.organizationService.CreateOrganization(userID, req)
+	if err != nil {
+		h.handleError(c, err, "Failed to create organization")
+		return
+	}
+
+	// Return success response
+	c.JSON(http.StatusCreated, organization)
+}
+
+// ListOrganizations handles listing the organizations of the current user
+func (h *OrganizationHandler) ListOrganizations(c *gin.Context) {
+	userID, _, ok := currentSession(c)
+	if !ok {
+		return
+	}
+
+	// Call service to list organizations
+	organizations, err := h.organizationService.GetOrganizations(userID)
+	if err != nil {
+		h.handleError(c, err, "Failed to list organizations")
+		return
+	}
+
+	// Return success response
+	c.JSON(http.StatusOK, gin.H{
+		"organizations": organizations,
+	})
+}
+
+// ListMembers handles listing the members of an organization
+func (h *OrganizationHandler) ListMembers(c *gin.Context) {
+	userID, organizationID, ok := h.organizationParams(c)
+	if !ok {
+		return
+	}
+
+	// Call service to list members
+	members, err := h.organizationService.GetMembers(userID, organizationID)
+	if err != nil {
+		h.handleError(c, err, "Failed to list organization members")
+		return
+	}
+
+	// Return success response
+	c.JSON(http.StatusOK, gin.H{
+		"members": members,
+	})
+}
+
+// AddMember handles adding a user to an organization
+func (h *OrganizationHandler) AddMember(c *gin.Context) {
+	userID, organizationID, ok := h.organizationParams(c)
+	if !ok {
+		return
+	}
+
+	var req model.AddOrganizationMemberRequest
+
+	// Bind JSON request to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Call service to add member
+	member, err := h.organizationService.AddMember(userID, organizationID, req)
+	if err != nil {
+		h.handleError(c, err, "Failed to add organization member")
+		return
+	}
+
+	// Return success response
+	c.JSON(http.StatusCreated, member)
+}
+
+// UpdateMember handles changing the role of a member of an organization
+func (h *OrganizationHandler) UpdateMember(c *gin.Context) {
+	userID, organizationID, ok := h.organizationParams(c)
+	if !ok {
+		return
+	}
+
+	memberID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req model.UpdateOrganizationMemberRequest
+
+	// Bind JSON request to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Call service to update member
+	member, err := h.organizationService.UpdateMember(userID, organizationID, memberID, req)
+	if err != nil {
+		h.handleError(c, err, "Failed to update organization member")
+		return
+	}
+
+	// Return success response
+	c.JSON(http.StatusOK, member)
+}
+
+// RemoveMember handles removing a member from an organization
+func (h *OrganizationHandler) RemoveMember(c *gin.Context) {
+	userID, organizationID, ok := h.organizationParams(c)
+	if !ok {
+		return
+	}
+
+	memberID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	// Call service to remove member
+	if err := h.organizationService.RemoveMember(userID, organizationID, memberID); err != nil {
+		h.handleError(c, err, "Failed to remove organization member")
+		return
+	}
+
+	// Return success response
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Member removed successfully",
+	})
+}
+
+// organizationParams returns the ID of the current user and of the
+// organization in the URL
+func (h *OrganizationHandler) organizationParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	userID, _, ok := currentSession(c)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	organizationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return userID, organizationID, true
+}
+
+// handleError maps organization service errors to HTTP responses
+func (h *OrganizationHandler) handleError(c *gin.Context, err error, message string) {
+	switch err.Error() {
+	case "organization not found", "member not found", "user not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "invalid organization role":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case "insufficient organization permissions":
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case "organization must keep an owner", "user is already a member of the organization":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		logrus.WithError(err).Error(message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// SetupRoutes sets up the organization routes
+func (h *OrganizationHandler) SetupRoutes(router *gin.Engine, authMiddleware gin.HandlerFunc) {
+	organizationRoutes := router.Group("/api/organizations")
+	organizationRoutes.Use(authMiddleware)
+	{
+		organizationRoutes.POST("", h.CreateOrganization)
+		organizationRoutes.GET("", h.ListOrganizations)
+		organizationRoutes.GET("/:id/members", h.ListMembers)
+		organizationRoutes.POST("/:id/members", h.AddMember)
+		organizationRoutes.PUT("/:id/members/:userId", h.UpdateMember)
+		organizationRoutes.DELETE("/:id/members/:userId", h.RemoveMember)
+	}
+}
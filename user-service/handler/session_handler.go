@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"github.com/yourusername/ticket-system/user-service/service"
+)
+
+// SessionHandler handles HTTP requests related to sessions and their tokens
+type SessionHandler struct {
+	sessionService service.SessionService
+}
+
+// NewSessionHandler creates a new session handler
+func NewSessionHandler(sessionService service.SessionService) *SessionHandler {
+	return &SessionHandler{
+		sessionService: sessionService,
+	}
+}
+
+// Refresh handles redeeming a refresh token for new tokens
+func (h *SessionHandler) Refresh(c *gin.Context) {
+	var req model.RefreshRequest
+
+	// Bind JSON request to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Call service to refresh tokens
+	tokens, err := h.sessionService.Refresh(req.RefreshToken, clientInfo(c))
+	if err != nil {
+		switch err.Error() {
+		case "invalid refresh token", "refresh token has already been used":
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		default:
+			logrus.WithError(err).Error("Failed to refresh token")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	// Return success response
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout handles signing out of the current session
+func (h *SessionHandler) Logout(c *gin.Context) {
+	userID, sessionID, ok := currentSession(c)
+	if !ok {
+		return
+	}
+
+	// Call service to revoke the current session
+	if err := h.sessionService.RevokeSession(userID, sessionID, model.SessionRevokedLogout); err != nil {
+		h.handleError(c, err, "Failed to logout")
+		return
+	}
+
+	// Return success response
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Logged out successfully",
+	})
+}
+
+// ListSessions handles listing the active sessions of the current user
+func (h *SessionHandler) ListSessions(c *gin.Context) {
+	userID, sessionID, ok := currentSession(c)
+	if !ok {
+		return
+	}
+
+	// Call service to list sessions
+	sessions, err := h.sessionService.ListSessions(userID, sessionID)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to list sessions")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Return success response
+	c.JSON(http.StatusOK, gin.H{
+		"sessions": sessions,
+	})
+}
+
+// RevokeSession handles signing out of another session of the current user
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	userID, _, ok := currentSession(c)
+	if !ok {
+		return
+	}
+
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	// Call service to revoke session
+	if err := h.sessionService.RevokeSession(userID, sessionID, model.SessionRevokedLogout); err != nil {
+		h.handleError(c, err, "Failed to revoke session")
+		return
+	}
+
+	// Return success response
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Session revoked successfully",
+	})
+}
+
+// RevokeAllSessions handles signing the current user out everywhere
+func (h *SessionHandler) RevokeAllSessions(c *gin.Context) {
+	userID, _, ok := currentSession(c)
+	if !ok {
+		return
+	}
+
+	// Call service to revoke all sessions
+	revoked, err := h.sessionService.RevokeAllSessions(nil, userID, model.SessionRevokedLogoutAll)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to revoke sessions")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Return success response
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Signed out of all sessions successfully",
+		"revoked": revoked,
+	})
+}
+
+// RevokedSessions handles listing the revoked sessions whose access tokens
+// may still be valid, for the API gateway
+func (h *SessionHandler) RevokedSessions(c *gin.Context) {
+	// Call service to list revoked sessions
+	sessions, err := h.sessionService.RevokedSessions()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to list revoked sessions")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Return success response
+	c.JSON(http.StatusOK, gin.H{
+		"sessions": sessions,
+	})
+}
+
+// handleError maps session service errors to HTTP responses
+func (h *SessionHandler) handleError(c *gin.Context, err error, message string) {
+	switch err.Error() {
+	case "session not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		logrus.WithError(err).Error(message)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// SetupRoutes sets up the session routes
+func (h *SessionHandler) SetupRoutes(router *gin.Engine, authMiddleware gin.HandlerFunc) {
+	// Public routes
+	public := router.Group("/api/users")
+	{
+		public.POST("/refresh", h.Refresh)
+	}
+
+	// Protected routes
+	protected := router.Group("/api/users")
+	protected.Use(authMiddleware)
+	{
+		protected.POST("/logout", h.Logout)
+		protected.GET("/sessions", h.ListSessions)
+		protected.DELETE("/sessions", h.RevokeAllSessions)
+		protected.DELETE("/sessions/:id", h.RevokeSession)
+	}
+
+	// Internal routes, not exposed by the API gateway
+	internal := router.Group("/internal/sessions")
+	{
+		internal.GET("/revoked", h.RevokedSessions)
+	}
+}
+
+// currentSession returns the user and session of the access token of the
+// request, as set by the JWT middleware
+func currentSession(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	userID, userOK := c.Get("user_id")
+	sessionID, sessionOK := c.Get("session_id")
+	if !userOK || !sessionOK {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID.(uuid.UUID), sessionID.(uuid.UUID), true
+}
+
+// clientInfo returns the client a request is made from
+func clientInfo(c *gin.Context) model.ClientInfo {
+	return model.ClientInfo{
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
+	}
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/ticket-system/claims"
+)
+
+func TestClientInfo_TrustsForwardedForOnlyFromGateway(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "172.28.0.10")
+	router := setupTestRouter()
+	require.NoError(t, router.SetTrustedProxies(claims.TrustedProxiesFromEnv()))
+	router.GET("/client", func(c *gin.Context) {
+		c.String(http.StatusOK, clientInfo(c).IPAddress)
+	})
+
+	clientIP := func(remoteAddr string) string {
+		request := httptest.NewRequest(http.MethodGet, "/client", nil)
+		request.RemoteAddr = remoteAddr
+		request.Header.Set("X-Forwarded-For", "203.0.113.7")
+		response := httptest.NewRecorder()
+		router.ServeHTTP(response, request)
+		return response.Body.String()
+	}
+
+	// The gateway reports the address of the client
+	assert.Equal(t, "203.0.113.7", clientIP("172.28.0.10:40000"))
+
+	// A client calling the service directly cannot choose its address
+	assert.Equal(t, "198.51.100.4", clientIP("198.51.100.4:40000"))
+}
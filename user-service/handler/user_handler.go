@@ -1,11 +1,15 @@
 package handler
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/user-service/middleware"
 	"github.com/yourusername/ticket-system/user-service/model"
 	"github.com/yourusername/ticket-system/user-service/service"
 )
@@ -70,19 +74,76 @@ func (h *UserHandler) Login(c *gin.Context) {
 	}
 
 	// Call service to login user
-	response, err := h.userService.Login(req)
+	response, err := h.userService.Login(req, clientInfo(c))
 	if err != nil {
+		var throttled *service.LoginThrottledError
+		if errors.As(err, &throttled) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+			return
+		}
+
 		logrus.WithError(err).Error("Failed to login user")
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
 
+	writeLoginResponse(c, response)
+}
+
+// LoginMFA handles completing a login with a TOTP or recovery code
+func (h *UserHandler) LoginMFA(c *gin.Context) {
+	var req model.MFALoginRequest
+
+	// Bind JSON request to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Call service to complete the login
+	response, err := h.userService.LoginMFA(req, clientInfo(c))
+	if err != nil {
+		switch err.Error() {
+		case "invalid code", "invalid or expired MFA token", "two-factor authentication is not enabled":
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		default:
+			logrus.WithError(err).Error("Failed to complete MFA login")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	writeLoginResponse(c, response)
+}
+
+// writeLoginResponse returns the tokens of a new session, or the MFA token
+// to exchange for them with a second factor
+func writeLoginResponse(c *gin.Context, response *model.LoginResponse) {
+	// Ask for a second factor before issuing tokens
+	if response.MFARequired {
+		c.JSON(http.StatusOK, gin.H{
+			"message":      "Two-factor authentication required",
+			"mfa_required": true,
+			"mfa_token":    response.MFAToken,
+			"expires_in":   response.ExpiresIn,
+		})
+		return
+	}
+
+	body := gin.H{
+		"message":       "Login successful",
+		"token":         response.Token,
+		"refresh_token": response.RefreshToken,
+		"expires_in":    response.ExpiresIn,
+		"user":          response.User,
+	}
+	if response.MFAEnrollmentRequired {
+		body["mfa_enrollment_required"] = true
+	}
+
 	// Return success response
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Login successful",
-		"token":   response.Token,
-		"user":    response.User,
-	})
+	c.JSON(http.StatusOK, body)
 }
 
 // GetProfile handles getting user profile
@@ -197,6 +258,7 @@ func (h *UserHandler) SetupRoutes(router *gin.Engine, authMiddleware gin.Handler
 	{
 		public.POST("/register", h.Register)
 		public.POST("/login", h.Login)
+		public.POST("/login/mfa", h.LoginMFA)
 	}
 
 	// Protected routes
@@ -205,6 +267,6 @@ func (h *UserHandler) SetupRoutes(router *gin.Engine, authMiddleware gin.Handler
 	{
 		protected.GET("/profile", h.GetProfile)
 		protected.PUT("/profile", h.UpdateProfile)
-		protected.POST("/change-password", h.ChangePassword)
+		protected.POST("/change-password", middleware.RejectImpersonation(), h.ChangePassword)
 	}
 }
\ No newline at end of file
@@ -28,8 +28,24 @@ func (m *MockUserService) Register(req model.RegisterRequest) (*model.UserRespon
 	return args.Get(0).(*model.UserResponse), args.Error(1)
 }
 
-func (m *MockUserService) Login(req model.LoginRequest) (*model.LoginResponse, error) {
-	args := m.Called(req)
+func (m *MockUserService) Login(req model.LoginRequest, client model.ClientInfo) (*model.LoginResponse, error) {
+	args := m.Called(req, client)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.LoginResponse), args.Error(1)
+}
+
+func (m *MockUserService) LoginMFA(req model.MFALoginRequest, client model.ClientInfo) (*model.LoginResponse, error) {
+	args := m.Called(req, client)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.LoginResponse), args.Error(1)
+}
+
+func (m *MockUserService) LoginOIDC(provider string, req model.OIDCCallbackRequest, client model.ClientInfo) (*model.LoginResponse, error) {
+	args := m.Called(provider, req, client)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -57,6 +73,32 @@ func (m *MockUserService) ChangePassword(id uuid.UUID, req model.ChangePasswordR
 	return args.Error(0)
 }
 
+func (m *MockUserService) UnlockUser(id uuid.UUID) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockUserService) SuspendUser(id uuid.UUID) (*model.UserResponse, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.UserResponse), args.Error(1)
+}
+
+func (m *MockUserService) ReactivateUser(id uuid.UUID) (*model.UserResponse, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.UserResponse), args.Error(1)
+}
+
+func (m *MockUserService) DeleteUser(id uuid.UUID) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
 func setupTestRouter() *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
@@ -164,7 +206,7 @@ func TestUserHandler_Login(t *testing.T) {
 			},
 		}
 
-		mockService.On("Login", req).Return(expectedResponse, nil)
+		mockService.On("Login", req, mock.AnythingOfType("model.ClientInfo")).Return(expectedResponse, nil)
 
 		router.POST("/login", handler.Login)
 
@@ -186,7 +228,7 @@ func TestUserHandler_Login(t *testing.T) {
 		}
 
 		mockService := new(MockUserService)
-		mockService.On("Login", req).Return(nil, errors.New("invalid credentials"))
+		mockService.On("Login", req, mock.AnythingOfType("model.ClientInfo")).Return(nil, errors.New("invalid credentials"))
 		handler := NewUserHandler(mockService)
 
 		router := setupTestRouter()
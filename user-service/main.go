@@ -13,9 +13,13 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/claims"
+	"github.com/yourusername/ticket-system/jwks"
+	"github.com/yourusername/ticket-system/messaging"
 	"github.com/yourusername/ticket-system/user-service/config"
 	"github.com/yourusername/ticket-system/user-service/handler"
 	"github.com/yourusername/ticket-system/user-service/middleware"
+	"github.com/yourusername/ticket-system/user-service/oidc"
 	"github.com/yourusername/ticket-system/user-service/repository"
 	"github.com/yourusername/ticket-system/user-service/service"
 )
@@ -48,23 +52,99 @@ func main() {
 	}
 
 	// Initialize RabbitMQ connection
-	rmq, err := config.NewRabbitMQ()
+	broker, err := messaging.NewRabbitMQ(messaging.ConfigFromEnv())
 	if err != nil {
 		logrus.WithError(err).Fatal("Failed to connect to RabbitMQ")
 	}
-	defer rmq.Close()
+
+	// Declare exchanges
+	for _, exchange := range []string{"user_events", "ticket_events", "payment_events", "notification_events", "privacy_events", "account_emails"} {
+		if err := broker.DeclareExchange(exchange); err != nil {
+			logrus.WithError(err).Fatal("Failed to declare exchange")
+		}
+	}
 
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
+	outboxRepo := messaging.NewOutboxRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	signingKeyRepo := repository.NewSigningKeyRepository(db)
+	userTokenRepo := repository.NewUserTokenRepository(db)
+	mfaRepo := repository.NewMFARepository(db)
+	oidcRepo := repository.NewOIDCRepository(db)
+	organizationRepo := repository.NewOrganizationRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	dataRequestRepo := repository.NewDataRequestRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+
+	// Initialize the store of failed login attempts, which instances share in
+	// the database unless a single instance keeps them in memory
+	var loginAttemptStore repository.LoginAttemptStore
+	if os.Getenv("LOGIN_ATTEMPT_STORE") == "memory" {
+		loginAttemptStore = repository.NewMemoryLoginAttemptStore()
+	} else {
+		loginAttemptStore = repository.NewPostgresLoginAttemptStore(db)
+	}
+
+	// Initialize signing keys
+	signingAlgorithm := os.Getenv("JWT_SIGNING_ALGORITHM")
+	if signingAlgorithm == "" {
+		signingAlgorithm = jwks.AlgorithmRS256
+	}
+	keyRotationInterval, err := time.ParseDuration(os.Getenv("JWT_KEY_ROTATION_INTERVAL"))
+	if err != nil || keyRotationInterval <= 0 {
+		keyRotationInterval = 30 * 24 * time.Hour
+	}
+	keyService, err := service.NewKeyService(signingKeyRepo, signingAlgorithm, keyRotationInterval)
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to initialize signing keys")
+	}
+
+	// Initialize the key that encrypts TOTP secrets
+	mfaEncryptionKey := os.Getenv("MFA_ENCRYPTION_KEY")
+	if mfaEncryptionKey == "" {
+		logrus.Fatal("MFA_ENCRYPTION_KEY is required to encrypt TOTP secrets")
+	}
+
+	// Initialize identity providers users can sign in with
+	var oidcProviders []*oidc.Provider
+	for _, config := range oidc.ConfigsFromEnv() {
+		oidcProviders = append(oidcProviders, oidc.NewProvider(config))
+	}
 
 	// Initialize services
-	userService := service.NewUserService(userRepo, rmq)
+	sessionService := service.NewSessionService(sessionRepo, userRepo, keyService, db)
+	accountService := service.NewAccountService(userRepo, userTokenRepo, outboxRepo, broker, sessionService, db)
+	mfaService := service.NewMFAService(userRepo, mfaRepo, sessionService, db, mfaEncryptionKey)
+	oidcService := service.NewOIDCService(oidcProviders, userRepo, oidcRepo, outboxRepo, db)
+	loginThrottleService := service.NewLoginThrottleService(loginAttemptStore, outboxRepo)
+	userService := service.NewUserService(userRepo, outboxRepo, sessionService, accountService, mfaService, oidcService, loginThrottleService, db)
+	organizationService := service.NewOrganizationService(organizationRepo, userRepo, outboxRepo, db)
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, organizationRepo, keyService, db)
+	adminService := service.NewAdminService(userRepo, sessionRepo, mfaRepo, oidcRepo, organizationRepo, auditRepo, userTokenRepo, outboxRepo, broker, sessionService, db)
+	dataRequestService := service.NewDataRequestService(dataRequestRepo, userRepo, sessionRepo, mfaRepo, oidcRepo, organizationRepo, outboxRepo, sessionService, loginThrottleService, db)
+	outboxRelay := messaging.NewOutboxRelay(outboxRepo, broker)
 
 	// Initialize handlers
 	userHandler := handler.NewUserHandler(userService)
+	sessionHandler := handler.NewSessionHandler(sessionService)
+	accountHandler := handler.NewAccountHandler(accountService)
+	mfaHandler := handler.NewMFAHandler(mfaService)
+	oidcHandler := handler.NewOIDCHandler(userService, oidcService)
+	organizationHandler := handler.NewOrganizationHandler(organizationService)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService)
+	adminHandler := handler.NewAdminHandler(userService, mfaService, adminService)
+	dataRequestHandler := handler.NewDataRequestHandler(dataRequestService)
+	jwksHandler := handler.NewJWKSHandler(keyService)
 
 	// Initialize Gin router
 	router := gin.New()
+
+	// Only the API gateway may report the address of the client
+	if err := router.SetTrustedProxies(claims.TrustedProxiesFromEnv()); err != nil {
+		logrus.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
 	router.Use(gin.Recovery())
 	router.Use(middleware.Logger())
 	router.Use(middleware.Metrics())
@@ -78,7 +158,63 @@ func main() {
 	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
 	// Setup API routes
-	userHandler.SetupRoutes(router, middleware.JWTAuth())
+	jwksHandler.SetupRoutes(router)
+	authMiddleware := middleware.JWTAuth(keyService.Keyfunc, userRepo, sessionRepo)
+	userHandler.SetupRoutes(router, authMiddleware)
+	sessionHandler.SetupRoutes(router, authMiddleware)
+	accountHandler.SetupRoutes(router)
+	mfaHandler.SetupRoutes(router, authMiddleware)
+	oidcHandler.SetupRoutes(router)
+	organizationHandler.SetupRoutes(router, authMiddleware)
+	apiKeyHandler.SetupRoutes(router, authMiddleware)
+	adminHandler.SetupRoutes(router, authMiddleware, middleware.RequireMFA(middleware.MFARequiredRoles()))
+	dataRequestHandler.SetupRoutes(router, authMiddleware, middleware.RequireMFA(middleware.MFARequiredRoles()))
+
+	// Start background workers
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	// Publish events written to the outbox
+	outboxInterval, err := time.ParseDuration(os.Getenv("OUTBOX_POLL_INTERVAL"))
+	if err != nil || outboxInterval <= 0 {
+		outboxInterval = time.Second
+	}
+	go outboxRelay.Start(workerCtx, outboxInterval)
+
+	// Delete expired sessions and refresh tokens
+	go sessionService.StartCleanup(workerCtx, time.Hour)
+
+	// Delete expired email verification and password reset tokens
+	go accountService.StartCleanup(workerCtx, time.Hour)
+
+	// Delete expired MFA challenges
+	go mfaService.StartCleanup(workerCtx, time.Hour)
+
+	// Delete expired OIDC login states
+	go oidcService.StartCleanup(workerCtx, time.Hour)
+
+	// Delete failed login attempts that are not counted anymore
+	go loginThrottleService.StartCleanup(workerCtx, time.Hour)
+
+	// Delete the data of expired data exports
+	go dataRequestService.StartCleanup(workerCtx, time.Hour)
+
+	// Complete data requests with the answers of the other services. Parts
+	// are only completed once, so redelivered answers need no inbox.
+	err = broker.Consume(workerCtx, messaging.ConsumerOptions{
+		Queue:       "user_service_privacy_events",
+		Exchange:    "privacy_events",
+		RoutingKeys: []string{"privacy.#"},
+	}, func(msg messaging.Message) error {
+		logrus.Infof("Received privacy event: %s", msg.RoutingKey)
+		return dataRequestService.HandlePrivacyEvent(msg.Body)
+	})
+	if err != nil {
+		logrus.WithError(err).Fatal("Failed to consume privacy events")
+	}
+
+	// Rotate signing keys and pick up keys rotated by other instances
+	go keyService.StartRotation(workerCtx)
 
 	// Get server port from environment or use default
 	port := os.Getenv("PORT")
@@ -115,5 +251,11 @@ func main() {
 		logrus.WithError(err).Fatal("Server forced to shutdown")
 	}
 
+	// Stop the background workers and disconnect from RabbitMQ
+	stopWorkers()
+	if err := broker.Close(ctx); err != nil {
+		logrus.WithError(err).Error("Failed to close RabbitMQ connection")
+	}
+
 	logrus.Info("Server exiting")
 }
\ No newline at end of file
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RejectImpersonation is a middleware that rejects requests made with the
+// token of an admin impersonating a user, for actions only the user may
+// take themselves. It must run after JWTAuth.
+func RejectImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, impersonated := c.Get("impersonator_id"); impersonated {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed while impersonating a user"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -2,7 +2,6 @@ package middleware
 
 import (
 	"errors"
-	"fmt"
 	"net/http"
 	"os"
 	"strings"
@@ -12,49 +11,55 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/claims"
+	"github.com/yourusername/ticket-system/jwks"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"github.com/yourusername/ticket-system/user-service/repository"
 )
 
-// JWTClaims represents the claims in the JWT token
-type JWTClaims struct {
-	UserID uuid.UUID `json:"user_id"`
-	Email  string    `json:"email"`
-	Role   string    `json:"role"`
-	jwt.RegisteredClaims
-}
-
-// GenerateToken generates a new JWT token for a user
-func GenerateToken(userID uuid.UUID, email, role string) (string, error) {
-	// Get JWT secret from environment variable
-	jwtSecret := getJWTSecret()
-
-	// Create claims with user information
-	claims := JWTClaims{
-		UserID: userID,
-		Email:  email,
-		Role:   role,
+// NewClaims returns the claims of a short-lived access token for a session of a user
+func NewClaims(userID, sessionID uuid.UUID, email, role string, verified, mfa bool) claims.Claims {
+	now := time.Now()
+	return claims.Claims{
+		UserID:    userID,
+		SessionID: sessionID,
+		Email:     email,
+		Role:      role,
+		Verified:  verified,
+		MFA:       mfa,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)), // Token expires in 24 hours
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(now.Add(AccessTokenTTL())),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    "ticket-system",
 			Subject:   userID.String(),
 		},
 	}
+}
 
-	// Create token with claims
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	// Sign token with secret
-	tokenString, err := token.SignedString([]byte(jwtSecret))
-	if err != nil {
-		return "", fmt.Errorf("failed to sign token: %w", err)
+// NewAPIKeyClaims returns the claims of a short-lived access token for an
+// API key of an organization
+func NewAPIKeyClaims(keyID, organizationID uuid.UUID, scopes []string, ttl time.Duration) claims.Claims {
+	now := time.Now()
+	return claims.Claims{
+		UserID:         keyID,
+		Role:           model.APIKeyRole,
+		OrganizationID: &organizationID,
+		Scopes:         scopes,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			Issuer:    "ticket-system",
+			Subject:   keyID.String(),
+		},
 	}
-
-	return tokenString, nil
 }
 
-// JWTAuth is a middleware that validates JWT tokens
-func JWTAuth() gin.HandlerFunc {
+// JWTAuth is a middleware that validates JWT tokens against the keys
+// returned by keyfunc. Tokens of revoked sessions and of users that were
+// suspended or deleted after the token was issued are rejected.
+func JWTAuth(keyfunc jwt.Keyfunc, userRepo repository.UserRepository, sessionRepo repository.SessionRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get token from Authorization header
 		tokenString := extractToken(c)
@@ -65,7 +70,7 @@ func JWTAuth() gin.HandlerFunc {
 		}
 
 		// Parse and validate token
-		claims, err := validateToken(tokenString)
+		tokenClaims, err := validateToken(tokenString, keyfunc)
 		if err != nil {
 			logrus.WithError(err).Warn("Invalid token")
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
@@ -73,10 +78,51 @@ func JWTAuth() gin.HandlerFunc {
 			return
 		}
 
+		// API keys have no session and cannot manage users
+		if tokenClaims.Role == model.APIKeyRole {
+			c.JSON(http.StatusForbidden, gin.H{"error": "API keys cannot access this resource"})
+			c.Abort()
+			return
+		}
+
+		// Check that the session has not been revoked
+		session, err := sessionRepo.FindByID(tokenClaims.SessionID)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to find session of token")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate token"})
+			c.Abort()
+			return
+		}
+		if session == nil || session.RevokedAt != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
+			c.Abort()
+			return
+		}
+
+		// Check that the user still exists and is active
+		user, err := userRepo.FindByID(tokenClaims.UserID)
+		if err != nil {
+			logrus.WithError(err).Error("Failed to find user of token")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate token"})
+			c.Abort()
+			return
+		}
+		if user == nil || !user.Active {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User account is suspended or deleted"})
+			c.Abort()
+			return
+		}
+
 		// Set user information in context
-		c.Set("user_id", claims.UserID)
-		c.Set("email", claims.Email)
-		c.Set("role", claims.Role)
+		c.Set("user_id", tokenClaims.UserID)
+		c.Set("session_id", tokenClaims.SessionID)
+		c.Set("email", tokenClaims.Email)
+		c.Set("role", tokenClaims.Role)
+		c.Set("email_verified", user.Verified)
+		c.Set("mfa", session.MFA)
+		if tokenClaims.Actor != nil {
+			c.Set("impersonator_id", tokenClaims.Actor.Subject)
+		}
 
 		c.Next()
 	}
@@ -92,37 +138,27 @@ func extractToken(c *gin.Context) string {
 }
 
 // validateToken validates the token and returns the claims
-func validateToken(tokenString string) (*JWTClaims, error) {
-	// Get JWT secret from environment variable
-	jwtSecret := getJWTSecret()
-
-	// Parse token
-	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		// Validate signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(jwtSecret), nil
-	})
-
+func validateToken(tokenString string, keyfunc jwt.Keyfunc) (*claims.Claims, error) {
+	// Parse token, only asymmetric signing methods are accepted
+	token, err := jwt.ParseWithClaims(tokenString, &claims.Claims{}, keyfunc, jwt.WithValidMethods(jwks.Algorithms))
 	if err != nil {
 		return nil, err
 	}
 
 	// Extract claims
-	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
-		return claims, nil
+	if tokenClaims, ok := token.Claims.(*claims.Claims); ok && token.Valid {
+		return tokenClaims, nil
 	}
 
 	return nil, errors.New("invalid token claims")
 }
 
-// getJWTSecret gets the JWT secret from environment variable or returns a default
-func getJWTSecret() string {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		// In production, you should ensure a proper secret is set
-		return "your-default-jwt-secret-for-development-only"
+// AccessTokenTTL returns how long access tokens are valid, from the
+// ACCESS_TOKEN_TTL environment variable or 15 minutes by default
+func AccessTokenTTL() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv("ACCESS_TOKEN_TTL"))
+	if err != nil || ttl <= 0 {
+		return 15 * time.Minute
 	}
-	return secret
+	return ttl
 }
\ No newline at end of file
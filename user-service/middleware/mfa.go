@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultMFARequiredRoles are the roles that must use two-factor
+// authentication when MFA_REQUIRED_ROLES is not set
+const defaultMFARequiredRoles = "admin,organizer"
+
+// MFARequiredRoles returns the roles that must use two-factor
+// authentication, from the comma separated MFA_REQUIRED_ROLES environment
+// variable. Setting it to "none" requires it of no role.
+func MFARequiredRoles() map[string]bool {
+	value, ok := os.LookupEnv("MFA_REQUIRED_ROLES")
+	if !ok {
+		value = defaultMFARequiredRoles
+	}
+
+	roles := make(map[string]bool)
+	for _, role := range strings.Split(value, ",") {
+		role = strings.TrimSpace(role)
+		if role != "" && role != "none" {
+			roles[role] = true
+		}
+	}
+	return roles
+}
+
+// RequireMFA is a middleware that rejects users whose role requires
+// two-factor authentication when they have not proven a second factor in
+// their session. It must run after JWTAuth.
+func RequireMFA(requiredRoles map[string]bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, _ := c.Get("role")
+		roleName, _ := role.(string)
+		if requiredRoles[roleName] && !c.GetBool("mfa") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication required"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,138 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UserRoles lists the roles a user can have
+var UserRoles = []string{"user", "organizer", "admin"}
+
+// Admin audit actions
+const (
+	AuditActionRoleChanged  = "role_changed"
+	AuditActionImpersonated = "impersonated"
+	AuditActionUserImported = "user_imported"
+)
+
+// AdminAuditEntry records an action an admin took on the account of a user
+type AdminAuditEntry struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	AdminID      uuid.UUID `gorm:"type:uuid;not null;index" json:"admin_id"`
+	TargetUserID uuid.UUID `gorm:"type:uuid;not null;index" json:"target_user_id"`
+	Action       string    `gorm:"type:varchar(30);not null" json:"action"`
+	Details      string    `gorm:"type:text" json:"details,omitempty"`
+	IPAddress    string    `gorm:"type:varchar(45)" json:"ip_address"`
+	CreatedAt    time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName keeps the audit log in a single table
+func (AdminAuditEntry) TableName() string {
+	return "admin_audit_log"
+}
+
+// BeforeCreate is a GORM hook that runs before creating a new audit entry
+func (e *AdminAuditEntry) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
+
+// UserSearchRequest represents the query parameters for searching users.
+// Query matches the email address, name and phone number at once.
+type UserSearchRequest struct {
+	Query    string `form:"q"`
+	Email    string `form:"email"`
+	Name     string `form:"name"`
+	Phone    string `form:"phone"`
+	Role     string `form:"role" binding:"omitempty,oneof=user organizer admin"`
+	Page     int    `form:"page,default=1" binding:"min=1"`
+	PageSize int    `form:"page_size,default=20" binding:"min=1,max=100"`
+}
+
+// UserActivity summarises the recent activity of a user for admins
+type UserActivity struct {
+	User              UserResponse      `json:"user"`
+	ActiveSessions    int               `json:"active_sessions"`
+	LastLoginAt       *time.Time        `json:"last_login_at,omitempty"`
+	LastSeenAt        *time.Time        `json:"last_seen_at,omitempty"`
+	LastIPAddress     string            `json:"last_ip_address,omitempty"`
+	MFAEnabled        bool              `json:"mfa_enabled"`
+	IdentityProviders []string          `json:"identity_providers"`
+	Organizations     int               `json:"organizations"`
+	AuditLog          []AdminAuditEntry `json:"audit_log"`
+}
+
+// ChangeRoleRequest represents the request structure for changing the role
+// of a user
+type ChangeRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=user organizer admin"`
+}
+
+// ImpersonateRequest represents the request structure for impersonating a
+// user. The reason is kept in the audit log.
+type ImpersonateRequest struct {
+	Reason string `json:"reason" binding:"required,max=255"`
+}
+
+// ImpersonationResponse represents the response structure for an
+// impersonation. The access token cannot be refreshed.
+type ImpersonationResponse struct {
+	Token          string       `json:"token"`
+	ExpiresIn      int64        `json:"expires_in"` // Seconds until the access token expires
+	User           UserResponse `json:"user"`
+	ImpersonatorID uuid.UUID    `json:"impersonator_id"`
+}
+
+// Staff import file formats
+const (
+	ImportFormatCSV   = "csv"
+	ImportFormatJSONL = "jsonl"
+)
+
+// Staff import row actions
+const (
+	ImportActionCreate   = "create"
+	ImportActionExisting = "existing"
+)
+
+// ImportCSVColumns lists the columns of a staff import CSV file
+var ImportCSVColumns = []string{"email", "first_name", "last_name", "phone", "role"}
+
+// StaffImportRecord is a single staff account of an import file
+type StaffImportRecord struct {
+	Email     string `json:"email"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Phone     string `json:"phone"`
+	Role      string `json:"role"`
+}
+
+// ImportRowError lists the problems found in a single row of an import file
+type ImportRowError struct {
+	Row    int      `json:"row"`
+	Email  string   `json:"email,omitempty"`
+	Errors []string `json:"errors"`
+}
+
+// ImportRowResult is the outcome of a single row of an import file
+type ImportRowResult struct {
+	Row    int       `json:"row"`
+	Email  string    `json:"email"`
+	UserID uuid.UUID `json:"user_id,omitempty"`
+	Action string    `json:"action"` // create, existing
+}
+
+// ImportResult is the response format for a staff import
+type ImportResult struct {
+	DryRun   bool              `json:"dry_run"`
+	Applied  bool              `json:"applied"`
+	Total    int               `json:"total"`
+	Created  int               `json:"created"`
+	Existing int               `json:"existing"`
+	Rows     []ImportRowResult `json:"rows,omitempty"`
+	Errors   []ImportRowError  `json:"errors,omitempty"`
+}
@@ -0,0 +1,130 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Scopes an API key can be granted
+const (
+	APIKeyScopeEventsRead     = "events:read"
+	APIKeyScopeBookingsCreate = "bookings:create"
+	APIKeyScopeTicketsScan    = "tickets:scan"
+)
+
+// APIKeyRole is the role of the access tokens issued for API keys
+const APIKeyRole = "api_key"
+
+// APIKeyScopes returns every scope an API key can be granted
+func APIKeyScopes() []string {
+	return []string{
+		APIKeyScopeEventsRead,
+		APIKeyScopeBookingsCreate,
+		APIKeyScopeTicketsScan,
+	}
+}
+
+// APIKey lets a partner system of an organization call the API without a
+// user. Only the hash of the key is stored, the key itself is shown once.
+type APIKey struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	OrganizationID uuid.UUID  `gorm:"type:uuid;not null;index" json:"organization_id"`
+	Name           string     `gorm:"type:varchar(100);not null" json:"name"`
+	Prefix         string     `gorm:"type:varchar(16);not null" json:"prefix"`
+	KeyHash        string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	Scopes         string     `gorm:"type:varchar(255);not null" json:"-"`
+	RateLimit      int        `gorm:"not null" json:"rate_limit"`
+	CreatedBy      uuid.UUID  `gorm:"type:uuid;not null" json:"created_by"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	LastUsedAt     *time.Time `json:"last_used_at"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// BeforeCreate is a GORM hook that runs before creating a new API key
+func (k *APIKey) BeforeCreate(tx *gorm.DB) error {
+	if k.ID == uuid.Nil {
+		k.ID = uuid.New()
+	}
+	return nil
+}
+
+// ScopeList returns the scopes granted to the key
+func (k *APIKey) ScopeList() []string {
+	if k.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(k.Scopes, " ")
+}
+
+// Active reports whether the key can still be used at now
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// ToResponse converts an APIKey to an APIKeyResponse
+func (k *APIKey) ToResponse() APIKeyResponse {
+	return APIKeyResponse{
+		ID:             k.ID,
+		OrganizationID: k.OrganizationID,
+		Name:           k.Name,
+		Prefix:         k.Prefix,
+		Scopes:         k.ScopeList(),
+		RateLimit:      k.RateLimit,
+		ExpiresAt:      k.ExpiresAt,
+		LastUsedAt:     k.LastUsedAt,
+		RevokedAt:      k.RevokedAt,
+		CreatedAt:      k.CreatedAt,
+	}
+}
+
+// APIKeyResponse is an API key without its secret
+type APIKeyResponse struct {
+	ID             uuid.UUID  `json:"id"`
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	Name           string     `json:"name"`
+	Prefix         string     `json:"prefix"`
+	Scopes         []string   `json:"scopes"`
+	RateLimit      int        `json:"rate_limit"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	LastUsedAt     *time.Time `json:"last_used_at"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// CreatedAPIKeyResponse is a new API key together with its secret, which is
+// not shown again
+type CreatedAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+// CreateAPIKeyRequest represents the request structure for creating an API
+// key. The rate limit is in requests per minute.
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	RateLimit int        `json:"rate_limit" binding:"omitempty,min=1,max=6000"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// APIKeyTokenRequest represents the request structure for exchanging an API
+// key for an access token
+type APIKeyTokenRequest struct {
+	Key string `json:"key" binding:"required"`
+}
+
+// APIKeyTokenResponse is a short-lived access token issued for an API key,
+// together with the limits the API gateway enforces for the key
+type APIKeyTokenResponse struct {
+	Token          string    `json:"token"`
+	ExpiresIn      int64     `json:"expires_in"`
+	KeyID          uuid.UUID `json:"key_id"`
+	OrganizationID uuid.UUID `json:"organization_id"`
+	Scopes         []string  `json:"scopes"`
+	RateLimit      int       `json:"rate_limit"`
+}
@@ -0,0 +1,86 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Data request statuses
+const (
+	DataRequestPending   = "pending"
+	DataRequestCompleted = "completed"
+	// DataRequestExpired is set once the collected data of an export has
+	// been deleted
+	DataRequestExpired = "expired"
+)
+
+// Services holding personal data, which take part in every data request
+const (
+	DataRequestServiceUser         = "user-service"
+	DataRequestServiceEventTicket  = "event-ticket-service"
+	DataRequestServicePayment      = "payment-service"
+	DataRequestServiceNotification = "notification-service"
+)
+
+// DataRequestServices returns every service taking part in a data request
+func DataRequestServices() []string {
+	return []string{
+		DataRequestServiceUser,
+		DataRequestServiceEventTicket,
+		DataRequestServicePayment,
+		DataRequestServiceNotification,
+	}
+}
+
+// DataRequest is a data export or erasure of a user, started by an admin
+// for a subject access or deletion request. Every service completes its
+// part on its own.
+type DataRequest struct {
+	ID          uuid.UUID         `gorm:"type:uuid;primary_key" json:"id"`
+	UserID      uuid.UUID         `gorm:"type:uuid;not null;index" json:"user_id"`
+	Mode        string            `gorm:"type:varchar(20);not null" json:"mode"`                     // export, erasure
+	Status      string            `gorm:"type:varchar(20);not null;default:'pending'" json:"status"` // pending, completed, expired
+	RequestedBy uuid.UUID         `gorm:"type:uuid;not null" json:"requested_by"`
+	CreatedAt   time.Time         `json:"created_at"`
+	CompletedAt *time.Time        `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time        `gorm:"index" json:"expires_at,omitempty"` // set on completed exports
+	Parts       []DataRequestPart `gorm:"foreignKey:RequestID" json:"parts"`
+}
+
+// BeforeCreate is a GORM hook that runs before creating a new data request
+func (r *DataRequest) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// DataRequestPart tracks the part of a data request one service completes.
+// Data holds the JSON document the service collected for an export.
+type DataRequestPart struct {
+	RequestID   uuid.UUID  `gorm:"type:uuid;primary_key" json:"-"`
+	Service     string     `gorm:"type:varchar(50);primary_key" json:"service"`
+	Status      string     `gorm:"type:varchar(20);not null;default:'pending'" json:"status"` // pending, completed
+	Data        []byte     `json:"-"`
+	Erased      int        `gorm:"not null;default:0" json:"erased"`
+	Retained    int        `gorm:"not null;default:0" json:"retained"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// CreateDataRequestRequest represents the request structure for starting a
+// data export or erasure of a user
+type CreateDataRequestRequest struct {
+	Mode string `json:"mode" binding:"required,oneof=export erasure"`
+}
+
+// UserDataExport is the personal data the user service holds about a user
+type UserDataExport struct {
+	Profile       UserResponse         `json:"profile"`
+	Sessions      []Session            `json:"sessions"`
+	Identities    []OIDCIdentity       `json:"identities"`
+	MFA           *MFAFactor           `json:"mfa,omitempty"`
+	Organizations []OrganizationMember `json:"organizations"`
+	ExportedAt    time.Time            `json:"exported_at"`
+}
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// LoginAttempt counts the failed logins for an account or from an IP
+// address. Key is the email address or IP address with a prefix telling
+// them apart.
+type LoginAttempt struct {
+	Key           string     `gorm:"type:varchar(320);primary_key" json:"key"`
+	Failures      int        `gorm:"not null" json:"failures"`
+	LastFailureAt time.Time  `gorm:"not null;index" json:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until"`
+}
+
+// Blocked reports whether logins for the key are blocked at now, and until
+// when
+func (a *LoginAttempt) Blocked(now time.Time) (time.Time, bool) {
+	if a == nil || a.LockedUntil == nil || !now.Before(*a.LockedUntil) {
+		return time.Time{}, false
+	}
+	return *a.LockedUntil, true
+}
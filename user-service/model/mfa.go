@@ -0,0 +1,104 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MFAFactor is the TOTP authenticator of a user. The secret is stored
+// encrypted, and the factor only protects logins once it has been confirmed
+// with a first code.
+type MFAFactor struct {
+	UserID      uuid.UUID  `gorm:"type:uuid;primary_key" json:"user_id"`
+	Secret      string     `gorm:"type:text;not null" json:"-"`
+	ConfirmedAt *time.Time `json:"confirmed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+
+	// LastUsedStep is the time step of the last accepted code, so that a
+	// code cannot be replayed within its validity window
+	LastUsedStep int64 `gorm:"not null;default:0" json:"-"`
+}
+
+// Enabled reports whether the factor has been confirmed
+func (f *MFAFactor) Enabled() bool {
+	return f != nil && f.ConfirmedAt != nil
+}
+
+// RecoveryCode is a single use backup code that replaces a TOTP code when
+// the user has lost their authenticator. Only a hash of the code is stored.
+type RecoveryCode struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	CodeHash  string     `gorm:"type:varchar(64);unique;not null" json:"-"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// BeforeCreate is a GORM hook that runs before creating a new recovery code
+func (r *RecoveryCode) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// MFAChallenge is issued when a user with two-factor authentication enabled
+// logs in with their password. The session is only started once the
+// challenge token is presented with a valid code. Only a hash of the token is
+// stored.
+type MFAChallenge struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	TokenHash string     `gorm:"type:varchar(64);unique;not null" json:"-"`
+	Attempts  int        `gorm:"not null;default:0" json:"attempts"`
+	ExpiresAt time.Time  `gorm:"not null;index" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// BeforeCreate is a GORM hook that runs before creating a new MFA challenge
+func (c *MFAChallenge) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
+
+// MFACodeRequest represents the request structure for confirming a TOTP code
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// MFALoginRequest represents the request structure for completing a login
+// with a TOTP or recovery code
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// DisableMFARequest represents the request structure for disabling
+// two-factor authentication
+type DisableMFARequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// MFAEnrollmentResponse represents the response structure for a new TOTP
+// secret, to be added to an authenticator app
+type MFAEnrollmentResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+	QRCode     string `json:"qr_code"` // PNG data URI of the otpauth URI
+}
+
+// MFAStatusResponse represents the response structure for the two-factor
+// authentication status of a user
+type MFAStatusResponse struct {
+	Enabled           bool       `json:"enabled"`
+	Required          bool       `json:"required"`
+	ConfirmedAt       *time.Time `json:"confirmed_at,omitempty"`
+	RecoveryCodesLeft int64      `json:"recovery_codes_left"`
+}
@@ -0,0 +1,73 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Organization is a company or team that runs events. Users act for an
+// organization through their role as its member.
+type Organization struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	Name      string    `gorm:"type:varchar(100);not null" json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// BeforeCreate is a GORM hook that runs before creating a new organization
+func (o *Organization) BeforeCreate(tx *gorm.DB) error {
+	if o.ID == uuid.Nil {
+		o.ID = uuid.New()
+	}
+	return nil
+}
+
+// OrganizationMember is the membership of a user in an organization, with
+// the role that decides what the user may do for it
+type OrganizationMember struct {
+	OrganizationID uuid.UUID `gorm:"type:uuid;primary_key" json:"organization_id"`
+	UserID         uuid.UUID `gorm:"type:uuid;primary_key;index" json:"user_id"`
+	Role           string    `gorm:"type:varchar(20);not null" json:"role"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// OrganizationResponse is an organization together with the role of the
+// user who asked for it
+type OrganizationResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// OrganizationMemberResponse is a member of an organization
+type OrganizationMemberResponse struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Email     string    `json:"email"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateOrganizationRequest represents the request structure for creating an
+// organization
+type CreateOrganizationRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// AddOrganizationMemberRequest represents the request structure for adding
+// a registered user to an organization
+type AddOrganizationMemberRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required"`
+}
+
+// UpdateOrganizationMemberRequest represents the request structure for
+// changing the role of a member
+type UpdateOrganizationMemberRequest struct {
+	Role string `json:"role" binding:"required"`
+}
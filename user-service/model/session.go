@@ -0,0 +1,127 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Session revocation reasons
+const (
+	SessionRevokedLogout        = "logout"
+	SessionRevokedLogoutAll     = "logout_all"
+	SessionRevokedReuseDetected = "reuse_detected"
+	SessionRevokedSuspended     = "suspended"
+	SessionRevokedDeleted       = "deleted"
+	SessionRevokedPasswordReset = "password_reset"
+	SessionRevokedMFAReset      = "mfa_reset"
+	SessionRevokedRoleChanged   = "role_changed"
+)
+
+// Session is a sign in of a user on one device. All refresh tokens issued
+// for a session form one token family, which is revoked as a whole.
+type Session struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	UserID        uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	UserAgent     string     `gorm:"type:varchar(255)" json:"user_agent"`
+	IPAddress     string     `gorm:"type:varchar(45)" json:"ip_address"`
+	CreatedAt     time.Time  `json:"created_at"`
+	LastUsedAt    time.Time  `json:"last_used_at"`
+	ExpiresAt     time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt     *time.Time `gorm:"index" json:"revoked_at,omitempty"`
+	RevokedReason string     `gorm:"type:varchar(20)" json:"revoked_reason,omitempty"`
+
+	// MFA is set once the user has proven a second factor in the session
+	MFA bool `gorm:"not null;default:false" json:"mfa"`
+
+	// ImpersonatorID is set when an admin signed in as the user. These
+	// sessions have no refresh token and end with their access token.
+	ImpersonatorID *uuid.UUID `gorm:"type:uuid;index" json:"impersonator_id,omitempty"`
+}
+
+// BeforeCreate is a GORM hook that runs before creating a new session
+func (s *Session) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+// Active reports whether the session can still be used
+func (s *Session) Active() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
+
+// RefreshToken is a single use token to renew the access token of a session.
+// Only a hash of the token is stored.
+type RefreshToken struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	SessionID uuid.UUID  `gorm:"type:uuid;not null;index" json:"session_id"`
+	TokenHash string     `gorm:"type:varchar(64);unique;not null" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// BeforeCreate is a GORM hook that runs before creating a new refresh token
+func (t *RefreshToken) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
+
+// ClientInfo describes the client a session is started from
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+}
+
+// RefreshRequest represents the request structure for refreshing an access token
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// TokenResponse represents the response structure for issued tokens
+type TokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int64  `json:"expires_in"` // Seconds until the access token expires
+}
+
+// SessionResponse represents the response structure for a session
+type SessionResponse struct {
+	ID         uuid.UUID `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+
+	ImpersonatorID *uuid.UUID `json:"impersonator_id,omitempty"`
+}
+
+// ToResponse converts a Session to a SessionResponse
+func (s *Session) ToResponse(currentSessionID uuid.UUID) SessionResponse {
+	return SessionResponse{
+		ID:         s.ID,
+		UserAgent:  s.UserAgent,
+		IPAddress:  s.IPAddress,
+		CreatedAt:  s.CreatedAt,
+		LastUsedAt: s.LastUsedAt,
+		ExpiresAt:  s.ExpiresAt,
+		Current:    s.ID == currentSessionID,
+
+		ImpersonatorID: s.ImpersonatorID,
+	}
+}
+
+// RevokedSession is a revoked session whose access tokens may not have
+// expired yet. Access tokens of the session must be rejected until ExpiresAt.
+type RevokedSession struct {
+	ID        uuid.UUID `json:"id"`
+	RevokedAt time.Time `json:"revoked_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,17 @@
+package model
+
+import (
+	"time"
+)
+
+// SigningKey is a private key that signs access tokens. The newest key that
+// is not retired signs new tokens, retired keys are still published until
+// the tokens they signed have expired.
+type SigningKey struct {
+	ID         string     `gorm:"type:varchar(64);primary_key" json:"kid"`
+	Algorithm  string     `gorm:"type:varchar(10);not null" json:"alg"`
+	PrivateKey []byte     `gorm:"not null" json:"-"` // PKCS #8, DER encoded
+	CreatedAt  time.Time  `json:"created_at"`
+	RetiredAt  *time.Time `json:"retired_at,omitempty"`
+	ExpiresAt  *time.Time `gorm:"index" json:"expires_at,omitempty"`
+}
@@ -20,6 +20,17 @@ type User struct {
 	Active    bool      `gorm:"default:true" json:"active"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	// Verified is set once the user has proven they own their email address
+	Verified   bool       `gorm:"not null;default:false" json:"verified"`
+	VerifiedAt *time.Time `json:"verified_at,omitempty"`
+
+	// SuspendedAt is set while an admin has suspended the user
+	SuspendedAt *time.Time `json:"suspended_at,omitempty"`
+
+	// DeletedAt is set when the user is deleted. Deleted users are excluded
+	// from all queries, so they can neither log in nor use their tokens.
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 // BeforeCreate is a GORM hook that runs before creating a new user
@@ -69,7 +80,11 @@ type UserResponse struct {
 	LastName  string    `json:"last_name"`
 	Phone     string    `json:"phone"`
 	Role      string    `json:"role"`
+	Active    bool      `json:"active"`
 	CreatedAt time.Time `json:"created_at"`
+
+	Verified    bool       `json:"verified"`
+	SuspendedAt *time.Time `json:"suspended_at,omitempty"`
 }
 
 // ToResponse converts a User to a UserResponse
@@ -81,7 +96,11 @@ func (u *User) ToResponse() UserResponse {
 		LastName:  u.LastName,
 		Phone:     u.Phone,
 		Role:      u.Role,
+		Active:    u.Active,
 		CreatedAt: u.CreatedAt,
+
+		Verified:    u.Verified,
+		SuspendedAt: u.SuspendedAt,
 	}
 }
 
@@ -113,8 +132,19 @@ type ChangePasswordRequest struct {
 	NewPassword     string `json:"new_password" binding:"required,min=8"`
 }
 
-// LoginResponse represents the response structure for user login
+// LoginResponse represents the response structure for user login. Users with
+// two-factor authentication enabled get an MFA token instead of tokens, to be
+// exchanged for tokens together with a code.
 type LoginResponse struct {
-	Token string       `json:"token"`
-	User  UserResponse `json:"user"`
+	Token        string       `json:"token"`
+	RefreshToken string       `json:"refresh_token"`
+	ExpiresIn    int64        `json:"expires_in"` // Seconds until the access token or MFA token expires
+	User         UserResponse `json:"user"`
+
+	MFARequired bool   `json:"mfa_required,omitempty"`
+	MFAToken    string `json:"mfa_token,omitempty"`
+
+	// MFAEnrollmentRequired is set when the role of the user requires
+	// two-factor authentication that the user has not set up yet
+	MFAEnrollmentRequired bool `json:"mfa_enrollment_required,omitempty"`
 }
\ No newline at end of file
@@ -0,0 +1,100 @@
+package oidc
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// wellKnownProviders are the defaults of providers that do not need
+// OIDC_<NAME>_ISSUER to be set
+var wellKnownProviders = map[string]Config{
+	"google": {IssuerURL: "https://accounts.google.com"},
+
+	// Apple posts the callback as a form when the email scope is requested,
+	// and does not support the profile scope
+	"apple": {IssuerURL: "https://appleid.apple.com", Scopes: []string{"openid", "email", "name"}, ResponseMode: "form_post"},
+}
+
+// Config holds the settings of an identity provider
+type Config struct {
+	// Name identifies the provider in URLs and linked identities
+	Name string
+
+	// IssuerURL is the issuer whose discovery document is served at
+	// IssuerURL/.well-known/openid-configuration
+	IssuerURL string
+
+	ClientID     string
+	ClientSecret string
+
+	// RedirectURL is the callback URL registered with the provider
+	RedirectURL string
+
+	Scopes []string
+
+	// ResponseMode is sent as response_mode when set, form_post makes the
+	// provider post the callback instead of redirecting to it
+	ResponseMode string
+
+	// Timeout bounds requests to the provider
+	Timeout time.Duration
+}
+
+// ConfigsFromEnv returns the configuration of the providers named in the
+// comma separated OIDC_PROVIDERS environment variable, each configured with
+// OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID, OIDC_<NAME>_CLIENT_SECRET,
+// OIDC_<NAME>_REDIRECT_URL, OIDC_<NAME>_SCOPES and OIDC_<NAME>_RESPONSE_MODE.
+// Providers without a client ID, redirect URL or known issuer are skipped.
+func ConfigsFromEnv() []Config {
+	var configs []Config
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		config := Config{
+			Name:         name,
+			IssuerURL:    os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:       strings.Fields(os.Getenv(prefix + "SCOPES")),
+			ResponseMode: os.Getenv(prefix + "RESPONSE_MODE"),
+		}
+		if known, ok := wellKnownProviders[name]; ok {
+			if config.IssuerURL == "" {
+				config.IssuerURL = known.IssuerURL
+			}
+			if len(config.Scopes) == 0 {
+				config.Scopes = known.Scopes
+			}
+			if config.ResponseMode == "" {
+				config.ResponseMode = known.ResponseMode
+			}
+		}
+
+		if config.IssuerURL == "" || config.ClientID == "" || config.RedirectURL == "" {
+			logrus.WithField("provider", name).Warnf("Skipping identity provider, %sISSUER, %sCLIENT_ID and %sREDIRECT_URL are required", prefix, prefix, prefix)
+			continue
+		}
+		configs = append(configs, config.withDefaults())
+	}
+	return configs
+}
+
+// withDefaults fills in the unset settings
+func (c Config) withDefaults() Config {
+	c.IssuerURL = strings.TrimRight(c.IssuerURL, "/")
+	if len(c.Scopes) == 0 {
+		c.Scopes = []string{"openid", "email", "profile"}
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 10 * time.Second
+	}
+	return c
+}
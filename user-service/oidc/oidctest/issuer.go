@@ -0,0 +1,210 @@
+// Package oidctest runs a fake OpenID Connect issuer for tests. It serves a
+// discovery document, a key set and a token endpoint that checks PKCE, and
+// signs users in without a login page.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/yourusername/ticket-system/jwks"
+)
+
+// keyID is the ID of the signing key of the issuer
+const keyID = "oidctest-1"
+
+// User is the account a user signs in to the issuer with
+type User struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	GivenName     string
+	FamilyName    string
+}
+
+// authorization is an issued authorization code waiting to be redeemed
+type authorization struct {
+	user          User
+	redirectURI   string
+	nonce         string
+	codeChallenge string
+}
+
+// Issuer is a fake OpenID Connect issuer
+type Issuer struct {
+	// URL is the issuer URL, for oidc.Config.IssuerURL
+	URL string
+
+	// ClientID is the only client the issuer issues tokens to
+	ClientID string
+
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu     sync.Mutex
+	codes  map[string]authorization
+	issuer string // Issuer claim of ID tokens, defaults to URL
+}
+
+// NewIssuer starts a fake issuer for a client. It must be closed with Close.
+func NewIssuer(clientID string) (*Issuer, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	i := &Issuer{
+		ClientID: clientID,
+		key:      key,
+		codes:    make(map[string]authorization),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", i.serveDiscovery)
+	mux.HandleFunc("/jwks", i.serveJWKS)
+	mux.HandleFunc("/token", i.serveToken)
+	i.server = httptest.NewServer(mux)
+	i.URL = i.server.URL
+	return i, nil
+}
+
+// Close shuts the issuer down
+func (i *Issuer) Close() {
+	i.server.Close()
+}
+
+// SetIssuerClaim makes the issuer sign ID tokens with another issuer claim,
+// to test that relying parties reject them
+func (i *Issuer) SetIssuerClaim(issuer string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.issuer = issuer
+}
+
+// Authorize signs user in at an authorization URL returned by a relying
+// party and returns the code and state the issuer redirects back with
+func (i *Issuer) Authorize(authURL string, user User) (string, string, error) {
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		return "", "", err
+	}
+	query := parsed.Query()
+
+	if query.Get("client_id") != i.ClientID {
+		return "", "", fmt.Errorf("unknown client %s", query.Get("client_id"))
+	}
+	if query.Get("response_type") != "code" {
+		return "", "", errors.New("response type must be code")
+	}
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		return "", "", errors.New("S256 code challenge required")
+	}
+
+	code := make([]byte, 16)
+	if _, err := rand.Read(code); err != nil {
+		return "", "", err
+	}
+	codeValue := base64.RawURLEncoding.EncodeToString(code)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.codes[codeValue] = authorization{
+		user:          user,
+		redirectURI:   query.Get("redirect_uri"),
+		nonce:         query.Get("nonce"),
+		codeChallenge: query.Get("code_challenge"),
+	}
+	return codeValue, query.Get("state"), nil
+}
+
+// serveDiscovery serves the discovery document
+func (i *Issuer) serveDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                           i.URL,
+		"authorization_endpoint":           i.URL + "/authorize",
+		"token_endpoint":                   i.URL + "/token",
+		"jwks_uri":                         i.URL + "/jwks",
+		"code_challenge_methods_supported": []string{"S256"},
+	})
+}
+
+// serveJWKS serves the public key that signs ID tokens
+func (i *Issuer) serveJWKS(w http.ResponseWriter, r *http.Request) {
+	jwk, err := jwks.NewJWK(keyID, jwks.AlgorithmRS256, i.key.Public())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, jwks.Set{Keys: []jwks.JWK{jwk}})
+}
+
+// serveToken redeems an authorization code and its PKCE code verifier for
+// an ID token
+func (i *Issuer) serveToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	i.mu.Lock()
+	auth, found := i.codes[r.PostForm.Get("code")]
+	delete(i.codes, r.PostForm.Get("code")) // Codes are single use
+	issuer := i.issuer
+	i.mu.Unlock()
+
+	challenge := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !found ||
+		r.PostForm.Get("client_id") != i.ClientID ||
+		r.PostForm.Get("redirect_uri") != auth.redirectURI ||
+		base64.RawURLEncoding.EncodeToString(challenge[:]) != auth.codeChallenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	if issuer == "" {
+		issuer = i.URL
+	}
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            issuer,
+		"sub":            auth.user.Subject,
+		"aud":            i.ClientID,
+		"exp":            now.Add(time.Hour).Unix(),
+		"iat":            now.Unix(),
+		"nonce":          auth.nonce,
+		"email":          auth.user.Email,
+		"email_verified": auth.user.EmailVerified,
+		"given_name":     auth.user.GivenName,
+		"family_name":    auth.user.FamilyName,
+	})
+	token.Header["kid"] = keyID
+	idToken, err := token.SignedString(i.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{
+		"access_token": "oidctest-access-token",
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+	})
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,27 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+)
+
+// randomValueBytes is the entropy of code verifiers, states and nonces
+const randomValueBytes = 32
+
+// RandomValue returns a random URL safe value, for states, nonces and PKCE
+// code verifiers
+func RandomValue() (string, error) {
+	value := make([]byte, randomValueBytes)
+	if _, err := rand.Read(value); err != nil {
+		return "", fmt.Errorf("failed to generate random value: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(value), nil
+}
+
+// CodeChallenge returns the S256 PKCE challenge of a code verifier
+func CodeChallenge(codeVerifier string) string {
+	hash := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
@@ -0,0 +1,258 @@
+// Package oidc signs users in with OpenID Connect identity providers, using
+// the authorization code flow with PKCE. Provider metadata is discovered from
+// the issuer, and ID tokens are verified against the keys the issuer
+// publishes.
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/yourusername/ticket-system/jwks"
+)
+
+// ErrInvalidIDToken is returned for ID tokens that fail verification
+var ErrInvalidIDToken = errors.New("invalid ID token")
+
+// Metadata is the part of the discovery document of an issuer the relying
+// party uses
+type Metadata struct {
+	Issuer                        string   `json:"issuer"`
+	AuthorizationEndpoint         string   `json:"authorization_endpoint"`
+	TokenEndpoint                 string   `json:"token_endpoint"`
+	JWKSURI                       string   `json:"jwks_uri"`
+	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported"`
+}
+
+// StringBool is a boolean claim that some providers, like Apple, send as a
+// "true" or "false" string
+type StringBool bool
+
+// UnmarshalJSON decodes a boolean or a boolean string
+func (b *StringBool) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case bool:
+		*b = StringBool(v)
+	case string:
+		*b = StringBool(v == "true")
+	default:
+		*b = false
+	}
+	return nil
+}
+
+// IDTokenClaims are the claims of a verified ID token
+type IDTokenClaims struct {
+	Email           string     `json:"email"`
+	EmailVerified   StringBool `json:"email_verified"`
+	Name            string     `json:"name"`
+	GivenName       string     `json:"given_name"`
+	FamilyName      string     `json:"family_name"`
+	Nonce           string     `json:"nonce"`
+	AuthorizedParty string     `json:"azp"`
+	jwt.RegisteredClaims
+}
+
+// tokenResponse is the response of the token endpoint
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Provider is an OpenID Connect identity provider users sign in with. The
+// discovery document is fetched on first use and cached, so that a provider
+// being down does not stop the service from starting.
+type Provider struct {
+	config     Config
+	httpClient *http.Client
+
+	mu       sync.Mutex
+	metadata *Metadata
+	keys     *jwks.Client
+}
+
+// NewProvider creates an identity provider
+func NewProvider(config Config) *Provider {
+	config = config.withDefaults()
+	return &Provider{
+		config:     config,
+		httpClient: &http.Client{Timeout: config.Timeout},
+	}
+}
+
+// Name returns the name of the provider
+func (p *Provider) Name() string {
+	return p.config.Name
+}
+
+// AuthCodeURL returns the URL of the provider to send the user to for
+// signing in. state and nonce bind the callback and the ID token to this
+// login, and codeChallenge is the S256 PKCE challenge of its code verifier.
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	metadata, _, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {p.config.RedirectURL},
+		"scope":                 {strings.Join(p.config.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+	if p.config.ResponseMode != "" {
+		query.Set("response_mode", p.config.ResponseMode)
+	}
+
+	separator := "?"
+	if strings.Contains(metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return metadata.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange redeems an authorization code and the PKCE code verifier of its
+// login for an ID token
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier string) (string, error) {
+	metadata, _, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"client_id":     {p.config.ClientID},
+		"code_verifier": {codeVerifier},
+	}
+	if p.config.ClientSecret != "" {
+		form.Set("client_secret", p.config.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call token endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var token tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to decode token response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK || token.Error != "" {
+		return "", fmt.Errorf("token endpoint returned status %d: %s %s", resp.StatusCode, token.Error, token.ErrorDescription)
+	}
+	if token.IDToken == "" {
+		return "", errors.New("token response has no ID token")
+	}
+	return token.IDToken, nil
+}
+
+// VerifyIDToken verifies the signature, issuer, audience, expiry and nonce
+// of an ID token and returns its claims
+func (p *Provider) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*IDTokenClaims, error) {
+	metadata, keys, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims IDTokenClaims
+	_, err = jwt.ParseWithClaims(rawIDToken, &claims, keys.Keyfunc,
+		jwt.WithValidMethods(jwks.Algorithms),
+		jwt.WithIssuer(metadata.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	if claims.ExpiresAt == nil {
+		return nil, fmt.Errorf("%w: no expiry", ErrInvalidIDToken)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidIDToken)
+	}
+	if claims.Nonce != nonce {
+		return nil, fmt.Errorf("%w: nonce does not match", ErrInvalidIDToken)
+	}
+
+	// A token issued to several clients must name this one as its party
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.config.ClientID {
+		return nil, fmt.Errorf("%w: authorized party does not match", ErrInvalidIDToken)
+	}
+
+	return &claims, nil
+}
+
+// discover returns the metadata and key set of the issuer, fetching the
+// discovery document on first use
+func (p *Provider) discover(ctx context.Context) (*Metadata, *jwks.Client, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.metadata != nil {
+		return p.metadata, p.keys, nil
+	}
+
+	discoveryURL := p.config.IssuerURL + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discoveryURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch discovery document: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("unexpected status %d from %s", resp.StatusCode, discoveryURL)
+	}
+
+	var metadata Metadata
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode discovery document: %w", err)
+	}
+
+	// The issuer must be the one the document was fetched from, so that a
+	// provider cannot vouch for another
+	if strings.TrimRight(metadata.Issuer, "/") != p.config.IssuerURL {
+		return nil, nil, fmt.Errorf("discovery document is for issuer %s, expected %s", metadata.Issuer, p.config.IssuerURL)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, nil, errors.New("discovery document is missing endpoints")
+	}
+
+	p.metadata = &metadata
+	p.keys = jwks.NewClient(jwks.Config{URL: metadata.JWKSURI, Timeout: p.config.Timeout})
+	return p.metadata, p.keys, nil
+}
@@ -0,0 +1,85 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/ticket-system/user-service/oidc/oidctest"
+)
+
+// setupProvider starts a fake issuer and a provider that signs in with it
+func setupProvider(t *testing.T) (*Provider, *oidctest.Issuer) {
+	issuer, err := oidctest.NewIssuer("ticket-system")
+	require.NoError(t, err)
+	t.Cleanup(issuer.Close)
+
+	provider := NewProvider(Config{
+		Name:        "test",
+		IssuerURL:   issuer.URL,
+		ClientID:    issuer.ClientID,
+		RedirectURL: "http://localhost:8080/api/v1/users/oidc/test/callback",
+	})
+	return provider, issuer
+}
+
+// signIn runs the authorization code flow for a user and returns the raw ID
+// token and the nonce of the login
+func signIn(t *testing.T, provider *Provider, issuer *oidctest.Issuer, user oidctest.User) (string, string) {
+	ctx := context.Background()
+	state, err := RandomValue()
+	require.NoError(t, err)
+	nonce, err := RandomValue()
+	require.NoError(t, err)
+	codeVerifier, err := RandomValue()
+	require.NoError(t, err)
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, CodeChallenge(codeVerifier))
+	require.NoError(t, err)
+
+	code, returnedState, err := issuer.Authorize(authURL, user)
+	require.NoError(t, err)
+	assert.Equal(t, state, returnedState)
+
+	// The code cannot be redeemed without the code verifier
+	_, err = provider.Exchange(ctx, code, "wrong-verifier")
+	assert.Error(t, err)
+
+	code, _, err = issuer.Authorize(authURL, user)
+	require.NoError(t, err)
+	rawIDToken, err := provider.Exchange(ctx, code, codeVerifier)
+	require.NoError(t, err)
+	return rawIDToken, nonce
+}
+
+func TestProvider_AuthorizationCodeFlow(t *testing.T) {
+	provider, issuer := setupProvider(t)
+	user := oidctest.User{Subject: "subject-1", Email: "oidc@example.com", EmailVerified: true, GivenName: "Ada"}
+
+	rawIDToken, nonce := signIn(t, provider, issuer, user)
+
+	claims, err := provider.VerifyIDToken(context.Background(), rawIDToken, nonce)
+	require.NoError(t, err)
+	assert.Equal(t, "subject-1", claims.Subject)
+	assert.Equal(t, "oidc@example.com", claims.Email)
+	assert.True(t, bool(claims.EmailVerified))
+	assert.Equal(t, "Ada", claims.GivenName)
+}
+
+func TestProvider_RejectsInvalidIDTokens(t *testing.T) {
+	provider, issuer := setupProvider(t)
+	user := oidctest.User{Subject: "subject-1", Email: "oidc@example.com", EmailVerified: true}
+
+	// ID tokens of another login
+	rawIDToken, _ := signIn(t, provider, issuer, user)
+	_, err := provider.VerifyIDToken(context.Background(), rawIDToken, "other-nonce")
+	assert.True(t, errors.Is(err, ErrInvalidIDToken))
+
+	// ID tokens of another issuer
+	issuer.SetIssuerClaim("https://attacker.example.com")
+	rawIDToken, nonce := signIn(t, provider, issuer, user)
+	_, err = provider.VerifyIDToken(context.Background(), rawIDToken, nonce)
+	assert.True(t, errors.Is(err, ErrInvalidIDToken))
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"gorm.io/gorm"
+)
+
+// APIKeyRepository defines the interface for API key repository operations
+type APIKeyRepository interface {
+	Create(key *model.APIKey) error
+	FindByID(id uuid.UUID) (*model.APIKey, error)
+	FindByHash(hash string) (*model.APIKey, error)
+	FindByOrganizationID(organizationID uuid.UUID) ([]model.APIKey, error)
+	UpdateExpiry(key *model.APIKey) error
+	Revoke(key *model.APIKey) error
+	MarkUsed(id uuid.UUID, at time.Time) error
+	WithTx(tx *gorm.DB) APIKeyRepository
+}
+
+// apiKeyRepository implements APIKeyRepository interface
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+// NewAPIKeyRepository creates a new API key repository
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	// Auto migrate the API key model
+	if err := db.AutoMigrate(&model.APIKey{}); err != nil {
+		logrus.WithError(err).Fatal("Failed to migrate API key model")
+	}
+
+	return &apiKeyRepository{db: db}
+}
+
+// WithTx returns a repository that runs its operations in tx
+func (r *apiKeyRepository) WithTx(tx *gorm.DB) APIKeyRepository {
+	return &apiKeyRepository{db: tx}
+}
+
+// Create creates a new API key
+func (r *apiKeyRepository) Create(key *model.APIKey) error {
+	result := r.db.Create(key)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to create API key")
+		return result.Error
+	}
+	return nil
+}
+
+// FindByID finds an API key by ID
+func (r *apiKeyRepository) FindByID(id uuid.UUID) (*model.APIKey, error) {
+	var key model.APIKey
+	result := r.db.First(&key, "id = ?", id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logrus.WithError(result.Error).Error("Failed to find API key")
+		return nil, result.Error
+	}
+	return &key, nil
+}
+
+// FindByHash finds an API key by the hash of its secret
+func (r *apiKeyRepository) FindByHash(hash string) (*model.APIKey, error) {
+	var key model.APIKey
+	result := r.db.First(&key, "key_hash = ?", hash)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logrus.WithError(result.Error).Error("Failed to find API key")
+		return nil, result.Error
+	}
+	return &key, nil
+}
+
+// FindByOrganizationID finds the API keys of an organization, newest first
+func (r *apiKeyRepository) FindByOrganizationID(organizationID uuid.UUID) ([]model.APIKey, error) {
+	var keys []model.APIKey
+	result := r.db.Where("organization_id = ?", organizationID).Order("created_at DESC").Find(&keys)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to find API keys")
+		return nil, result.Error
+	}
+	return keys, nil
+}
+
+// UpdateExpiry saves the expiry of an API key
+func (r *apiKeyRepository) UpdateExpiry(key *model.APIKey) error {
+	result := r.db.Model(key).Update("expires_at", key.ExpiresAt)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to update API key")
+		return result.Error
+	}
+	return nil
+}
+
+// Revoke marks an API key as revoked
+func (r *apiKeyRepository) Revoke(key *model.APIKey) error {
+	now := time.Now()
+	result := r.db.Model(key).Where("revoked_at IS NULL").Update("revoked_at", now)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to revoke API key")
+		return result.Error
+	}
+	if key.RevokedAt == nil {
+		key.RevokedAt = &now
+	}
+	return nil
+}
+
+// MarkUsed records when an API key was last used
+func (r *apiKeyRepository) MarkUsed(id uuid.UUID, at time.Time) error {
+	result := r.db.Model(&model.APIKey{}).Where("id = ?", id).Update("last_used_at", at)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to mark API key as used")
+		return result.Error
+	}
+	return nil
+}
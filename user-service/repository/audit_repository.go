@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"gorm.io/gorm"
+)
+
+// AuditRepository defines the interface for admin audit log repository
+// operations
+type AuditRepository interface {
+	Create(entry *model.AdminAuditEntry) error
+	FindByTargetUserID(userID uuid.UUID, limit int) ([]model.AdminAuditEntry, error)
+	WithTx(tx *gorm.DB) AuditRepository
+}
+
+// auditRepository implements AuditRepository interface
+type auditRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditRepository creates a new admin audit log repository
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	// Auto migrate the audit log model
+	if err := db.AutoMigrate(&model.AdminAuditEntry{}); err != nil {
+		logrus.WithError(err).Fatal("Failed to migrate admin audit log model")
+	}
+
+	return &auditRepository{db: db}
+}
+
+// WithTx returns a repository that runs its operations in tx
+func (r *auditRepository) WithTx(tx *gorm.DB) AuditRepository {
+	return &auditRepository{db: tx}
+}
+
+// Create records an admin action
+func (r *auditRepository) Create(entry *model.AdminAuditEntry) error {
+	result := r.db.Create(entry)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to create admin audit entry")
+		return result.Error
+	}
+	return nil
+}
+
+// FindByTargetUserID finds the latest admin actions on the account of a
+// user, newest first
+func (r *auditRepository) FindByTargetUserID(userID uuid.UUID, limit int) ([]model.AdminAuditEntry, error) {
+	var entries []model.AdminAuditEntry
+	result := r.db.Where("target_user_id = ?", userID).Order("created_at DESC").Limit(limit).Find(&entries)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to find admin audit entries")
+		return nil, result.Error
+	}
+	return entries, nil
+}
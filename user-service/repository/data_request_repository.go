@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"gorm.io/gorm"
+)
+
+// DataRequestRepository defines the interface for data request repository operations
+type DataRequestRepository interface {
+	Create(request *model.DataRequest) error
+	FindByID(id uuid.UUID) (*model.DataRequest, error)
+	FindByUserID(userID uuid.UUID) ([]model.DataRequest, error)
+	FindPendingByUserID(userID uuid.UUID, mode string) (*model.DataRequest, error)
+	CompletePart(part *model.DataRequestPart) (bool, error)
+	MarkCompleted(request *model.DataRequest) error
+	ExpireBefore(before time.Time) (int64, error)
+	WithTx(tx *gorm.DB) DataRequestRepository
+}
+
+// dataRequestRepository implements DataRequestRepository interface
+type dataRequestRepository struct {
+	db *gorm.DB
+}
+
+// NewDataRequestRepository creates a new data request repository
+func NewDataRequestRepository(db *gorm.DB) DataRequestRepository {
+	// Auto migrate the data request models
+	if err := db.AutoMigrate(&model.DataRequest{}, &model.DataRequestPart{}); err != nil {
+		logrus.WithError(err).Fatal("Failed to migrate data request models")
+	}
+
+	return &dataRequestRepository{db: db}
+}
+
+// WithTx returns a repository that runs its operations in tx
+func (r *dataRequestRepository) WithTx(tx *gorm.DB) DataRequestRepository {
+	return &dataRequestRepository{db: tx}
+}
+
+// Create creates a new data request together with its parts
+func (r *dataRequestRepository) Create(request *model.DataRequest) error {
+	result := r.db.Create(request)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to create data request")
+		return result.Error
+	}
+	return nil
+}
+
+// FindByID finds a data request by ID together with its parts
+func (r *dataRequestRepository) FindByID(id uuid.UUID) (*model.DataRequest, error) {
+	var request model.DataRequest
+	result := r.db.Preload("Parts").First(&request, "id = ?", id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logrus.WithError(result.Error).Error("Failed to find data request")
+		return nil, result.Error
+	}
+	return &request, nil
+}
+
+// FindByUserID finds the data requests of a user, newest first
+func (r *dataRequestRepository) FindByUserID(userID uuid.UUID) ([]model.DataRequest, error) {
+	var requests []model.DataRequest
+	result := r.db.Preload("Parts").Where("user_id = ?", userID).Order("created_at DESC").Find(&requests)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to find data requests")
+		return nil, result.Error
+	}
+	return requests, nil
+}
+
+// FindPendingByUserID finds the pending data request of a user in a mode
+func (r *dataRequestRepository) FindPendingByUserID(userID uuid.UUID, mode string) (*model.DataRequest, error) {
+	var request model.DataRequest
+	result := r.db.First(&request, "user_id = ? AND mode = ? AND status = ?", userID, mode, model.DataRequestPending)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logrus.WithError(result.Error).Error("Failed to find data request")
+		return nil, result.Error
+	}
+	return &request, nil
+}
+
+// CompletePart saves the result of a pending part of a data request. It
+// returns false when the part does not exist or was already completed, so
+// that redelivered answers are ignored.
+func (r *dataRequestRepository) CompletePart(part *model.DataRequestPart) (bool, error) {
+	result := r.db.Model(&model.DataRequestPart{}).
+		Where("request_id = ? AND service = ? AND status = ?", part.RequestID, part.Service, model.DataRequestPending).
+		Updates(map[string]interface{}{
+			"status":       model.DataRequestCompleted,
+			"data":         part.Data,
+			"erased":       part.Erased,
+			"retained":     part.Retained,
+			"completed_at": part.CompletedAt,
+		})
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to complete data request part")
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// MarkCompleted saves the completion of a data request
+func (r *dataRequestRepository) MarkCompleted(request *model.DataRequest) error {
+	result := r.db.Model(request).Updates(map[string]interface{}{
+		"status":       model.DataRequestCompleted,
+		"completed_at": request.CompletedAt,
+		"expires_at":   request.ExpiresAt,
+	})
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to complete data request")
+		return result.Error
+	}
+	return nil
+}
+
+// ExpireBefore deletes the collected data of the exports that expired
+// before a time, and returns how many exports expired
+func (r *dataRequestRepository) ExpireBefore(before time.Time) (int64, error) {
+	var expired int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		expiredIDs := tx.Model(&model.DataRequest{}).Select("id").
+			Where("status = ? AND expires_at < ?", model.DataRequestCompleted, before)
+
+		if err := tx.Model(&model.DataRequestPart{}).Where("request_id IN (?)", expiredIDs).Update("data", nil).Error; err != nil {
+			return err
+		}
+
+		result := tx.Model(&model.DataRequest{}).
+			Where("status = ? AND expires_at < ?", model.DataRequestCompleted, before).
+			Update("status", model.DataRequestExpired)
+		if result.Error != nil {
+			return result.Error
+		}
+		expired = result.RowsAffected
+		return nil
+	})
+	if err != nil {
+		logrus.WithError(err).Error("Failed to expire data exports")
+		return 0, err
+	}
+	return expired, nil
+}
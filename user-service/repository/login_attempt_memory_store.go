@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"sync"
+	"time"
+
+	"github.com/yourusername/ticket-system/user-service/model"
+)
+
+// memoryLoginAttemptStore implements LoginAttemptStore in memory, for a
+// single instance and for tests
+type memoryLoginAttemptStore struct {
+	attempts map[string]model.LoginAttempt
+	mutex    sync.Mutex
+}
+
+// NewMemoryLoginAttemptStore creates a login attempt store in memory
+func NewMemoryLoginAttemptStore() LoginAttemptStore {
+	return &memoryLoginAttemptStore{
+		attempts: make(map[string]model.LoginAttempt),
+	}
+}
+
+// Find finds the failed login attempts of a key
+func (s *memoryLoginAttemptStore) Find(key string) (*model.LoginAttempt, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok {
+		return nil, nil
+	}
+	return &attempt, nil
+}
+
+// RecordFailure counts a failed login attempt of a key. The count starts
+// over when the last failure is older than window.
+func (s *memoryLoginAttemptStore) RecordFailure(key string, at time.Time, window time.Duration) (*model.LoginAttempt, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	attempt, ok := s.attempts[key]
+	if !ok {
+		attempt = model.LoginAttempt{Key: key}
+	}
+	if attempt.LastFailureAt.Before(at.Add(-window)) {
+		attempt.Failures = 0
+	}
+	attempt.Failures++
+	attempt.LastFailureAt = at
+	s.attempts[key] = attempt
+
+	return &attempt, nil
+}
+
+// Lock blocks the logins of a key until a time
+func (s *memoryLoginAttemptStore) Lock(key string, until time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if attempt, ok := s.attempts[key]; ok {
+		attempt.LockedUntil = &until
+		s.attempts[key] = attempt
+	}
+	return nil
+}
+
+// Delete forgets the failed login attempts of a key
+func (s *memoryLoginAttemptStore) Delete(key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.attempts, key)
+	return nil
+}
+
+// DeleteExpiredBefore deletes the login attempts that failed last and are
+// not locked anymore before a time, and returns how many were deleted
+func (s *memoryLoginAttemptStore) DeleteExpiredBefore(before time.Time) (int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var deleted int64
+	for key, attempt := range s.attempts {
+		if attempt.LastFailureAt.Before(before) && (attempt.LockedUntil == nil || attempt.LockedUntil.Before(before)) {
+			delete(s.attempts, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
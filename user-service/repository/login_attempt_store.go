@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoginAttemptStore defines the interface for the stores of failed login
+// attempts. A single instance can keep them in memory, while instances
+// behind a load balancer need to share them in the database.
+type LoginAttemptStore interface {
+	Find(key string) (*model.LoginAttempt, error)
+	RecordFailure(key string, at time.Time, window time.Duration) (*model.LoginAttempt, error)
+	Lock(key string, until time.Time) error
+	Delete(key string) error
+	DeleteExpiredBefore(before time.Time) (int64, error)
+}
+
+// postgresLoginAttemptStore implements LoginAttemptStore in the database
+type postgresLoginAttemptStore struct {
+	db *gorm.DB
+}
+
+// NewPostgresLoginAttemptStore creates a login attempt store in the database
+func NewPostgresLoginAttemptStore(db *gorm.DB) LoginAttemptStore {
+	// Auto migrate the login attempt model
+	if err := db.AutoMigrate(&model.LoginAttempt{}); err != nil {
+		logrus.WithError(err).Fatal("Failed to migrate login attempt model")
+	}
+
+	return &postgresLoginAttemptStore{db: db}
+}
+
+// Find finds the failed login attempts of a key
+func (r *postgresLoginAttemptStore) Find(key string) (*model.LoginAttempt, error) {
+	var attempt model.LoginAttempt
+	result := r.db.First(&attempt, "key = ?", key)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logrus.WithError(result.Error).Error("Failed to find login attempts")
+		return nil, result.Error
+	}
+	return &attempt, nil
+}
+
+// RecordFailure counts a failed login attempt of a key in a single upsert,
+// so that concurrent attempts are all counted. The count starts over when
+// the last failure is older than window.
+func (r *postgresLoginAttemptStore) RecordFailure(key string, at time.Time, window time.Duration) (*model.LoginAttempt, error) {
+	attempt := model.LoginAttempt{Key: key, Failures: 1, LastFailureAt: at}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "key"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"failures":        gorm.Expr("CASE WHEN login_attempts.last_failure_at < ? THEN 1 ELSE login_attempts.failures + 1 END", at.Add(-window)),
+				"last_failure_at": at,
+			}),
+		}).Create(&attempt)
+		if result.Error != nil {
+			return result.Error
+		}
+		return tx.First(&attempt, "key = ?", key).Error
+	})
+	if err != nil {
+		logrus.WithError(err).Error("Failed to record login attempt")
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+// Lock blocks the logins of a key until a time
+func (r *postgresLoginAttemptStore) Lock(key string, until time.Time) error {
+	result := r.db.Model(&model.LoginAttempt{}).Where("key = ?", key).Update("locked_until", until)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to lock login attempts")
+		return result.Error
+	}
+	return nil
+}
+
+// Delete forgets the failed login attempts of a key
+func (r *postgresLoginAttemptStore) Delete(key string) error {
+	result := r.db.Where("key = ?", key).Delete(&model.LoginAttempt{})
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to delete login attempts")
+		return result.Error
+	}
+	return nil
+}
+
+// DeleteExpiredBefore deletes the login attempts that failed last and are
+// not locked anymore before a time, and returns how many were deleted
+func (r *postgresLoginAttemptStore) DeleteExpiredBefore(before time.Time) (int64, error) {
+	result := r.db.
+		Where("last_failure_at < ? AND (locked_until IS NULL OR locked_until < ?)", before, before).
+		Delete(&model.LoginAttempt{})
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to delete expired login attempts")
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
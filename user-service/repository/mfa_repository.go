@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"gorm.io/gorm"
+)
+
+// MFARepository defines the interface for two-factor authentication
+// repository operations
+type MFARepository interface {
+	FindFactor(userID uuid.UUID) (*model.MFAFactor, error)
+	SaveFactor(factor *model.MFAFactor) error
+	UseStep(userID uuid.UUID, step int64) (bool, error)
+	DeleteFactor(userID uuid.UUID) error
+	ReplaceRecoveryCodes(userID uuid.UUID, codes []model.RecoveryCode) error
+	UseRecoveryCode(userID uuid.UUID, hash string) (bool, error)
+	CountRecoveryCodes(userID uuid.UUID) (int64, error)
+	CreateChallenge(challenge *model.MFAChallenge) error
+	FindChallengeByHash(hash string) (*model.MFAChallenge, error)
+	AddChallengeAttempt(challenge *model.MFAChallenge) error
+	MarkChallengeUsed(challenge *model.MFAChallenge) (bool, error)
+	DeleteExpiredChallengesBefore(before time.Time) (int64, error)
+	WithTx(tx *gorm.DB) MFARepository
+}
+
+// mfaRepository implements MFARepository interface
+type mfaRepository struct {
+	db *gorm.DB
+}
+
+// NewMFARepository creates a new MFA repository
+func NewMFARepository(db *gorm.DB) MFARepository {
+	// Auto migrate the MFA models
+	if err := db.AutoMigrate(&model.MFAFactor{}, &model.RecoveryCode{}, &model.MFAChallenge{}); err != nil {
+		logrus.WithError(err).Fatal("Failed to migrate MFA models")
+	}
+
+	return &mfaRepository{db: db}
+}
+
+// WithTx returns a repository that runs its operations in tx
+func (r *mfaRepository) WithTx(tx *gorm.DB) MFARepository {
+	return &mfaRepository{db: tx}
+}
+
+// FindFactor finds the TOTP factor of a user
+func (r *mfaRepository) FindFactor(userID uuid.UUID) (*model.MFAFactor, error) {
+	var factor model.MFAFactor
+	result := r.db.First(&factor, "user_id = ?", userID)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logrus.WithError(result.Error).Error("Failed to find MFA factor")
+		return nil, result.Error
+	}
+	return &factor, nil
+}
+
+// SaveFactor creates or replaces the TOTP factor of a user
+func (r *mfaRepository) SaveFactor(factor *model.MFAFactor) error {
+	result := r.db.Save(factor)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to save MFA factor")
+		return result.Error
+	}
+	return nil
+}
+
+// UseStep records that the code of a time step was accepted and reports
+// whether the step is later than the last accepted one, so that each code
+// can only be used once even when it is presented concurrently
+func (r *mfaRepository) UseStep(userID uuid.UUID, step int64) (bool, error) {
+	result := r.db.Model(&model.MFAFactor{}).
+		Where("user_id = ? AND last_used_step < ?", userID, step).
+		Update("last_used_step", step)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to use MFA time step")
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// DeleteFactor deletes the TOTP factor and the recovery codes of a user
+func (r *mfaRepository) DeleteFactor(userID uuid.UUID) error {
+	if err := r.db.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+		logrus.WithError(err).Error("Failed to delete recovery codes")
+		return err
+	}
+	if err := r.db.Where("user_id = ?", userID).Delete(&model.MFAFactor{}).Error; err != nil {
+		logrus.WithError(err).Error("Failed to delete MFA factor")
+		return err
+	}
+	return nil
+}
+
+// ReplaceRecoveryCodes deletes the recovery codes of a user and creates the
+// given ones
+func (r *mfaRepository) ReplaceRecoveryCodes(userID uuid.UUID, codes []model.RecoveryCode) error {
+	if err := r.db.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+		logrus.WithError(err).Error("Failed to delete recovery codes")
+		return err
+	}
+	if err := r.db.Create(&codes).Error; err != nil {
+		logrus.WithError(err).Error("Failed to create recovery codes")
+		return err
+	}
+	return nil
+}
+
+// UseRecoveryCode marks a recovery code of a user as used and reports
+// whether it existed and was still unused
+func (r *mfaRepository) UseRecoveryCode(userID uuid.UUID, hash string) (bool, error) {
+	result := r.db.Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to use recovery code")
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// CountRecoveryCodes counts the unused recovery codes of a user
+func (r *mfaRepository) CountRecoveryCodes(userID uuid.UUID) (int64, error) {
+	var count int64
+	result := r.db.Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to count recovery codes")
+		return 0, result.Error
+	}
+	return count, nil
+}
+
+// CreateChallenge creates a new MFA challenge
+func (r *mfaRepository) CreateChallenge(challenge *model.MFAChallenge) error {
+	result := r.db.Create(challenge)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to create MFA challenge")
+		return result.Error
+	}
+	return nil
+}
+
+// FindChallengeByHash finds an MFA challenge by the hash of its token
+func (r *mfaRepository) FindChallengeByHash(hash string) (*model.MFAChallenge, error) {
+	var challenge model.MFAChallenge
+	result := r.db.First(&challenge, "token_hash = ?", hash)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logrus.WithError(result.Error).Error("Failed to find MFA challenge")
+		return nil, result.Error
+	}
+	return &challenge, nil
+}
+
+// AddChallengeAttempt counts a wrong code presented for an MFA challenge
+func (r *mfaRepository) AddChallengeAttempt(challenge *model.MFAChallenge) error {
+	result := r.db.Model(&model.MFAChallenge{}).
+		Where("id = ?", challenge.ID).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to count MFA challenge attempt")
+		return result.Error
+	}
+	challenge.Attempts++
+	return nil
+}
+
+// MarkChallengeUsed marks an MFA challenge as used and reports whether it
+// was still unused
+func (r *mfaRepository) MarkChallengeUsed(challenge *model.MFAChallenge) (bool, error) {
+	now := time.Now()
+	result := r.db.Model(&model.MFAChallenge{}).
+		Where("id = ? AND used_at IS NULL", challenge.ID).
+		Update("used_at", now)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to mark MFA challenge as used")
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	challenge.UsedAt = &now
+	return true, nil
+}
+
+// DeleteExpiredChallengesBefore deletes MFA challenges that expired before
+// the given time
+func (r *mfaRepository) DeleteExpiredChallengesBefore(before time.Time) (int64, error) {
+	result := r.db.Where("expires_at < ?", before).Delete(&model.MFAChallenge{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"gorm.io/gorm"
+)
+
+// OIDCRepository defines the interface for OIDC login repository operations
+type OIDCRepository interface {
+	CreateState(state *model.OIDCLoginState) error
+	FindStateByHash(hash string) (*model.OIDCLoginState, error)
+	MarkStateUsed(state *model.OIDCLoginState) (bool, error)
+	DeleteExpiredStatesBefore(before time.Time) (int64, error)
+	FindIdentity(provider, subject string) (*model.OIDCIdentity, error)
+	CreateIdentity(identity *model.OIDCIdentity) error
+	UpdateLastLogin(identity *model.OIDCIdentity) error
+	FindIdentitiesByUserID(userID uuid.UUID) ([]model.OIDCIdentity, error)
+	DeleteIdentitiesByUserID(userID uuid.UUID) (int64, error)
+	WithTx(tx *gorm.DB) OIDCRepository
+}
+
+// oidcRepository implements OIDCRepository interface
+type oidcRepository struct {
+	db *gorm.DB
+}
+
+// NewOIDCRepository creates a new OIDC repository
+func NewOIDCRepository(db *gorm.DB) OIDCRepository {
+	// Auto migrate the OIDC models
+	if err := db.AutoMigrate(&model.OIDCIdentity{}, &model.OIDCLoginState{}); err != nil {
+		logrus.WithError(err).Fatal("Failed to migrate OIDC models")
+	}
+
+	return &oidcRepository{db: db}
+}
+
+// WithTx returns a repository that runs its operations in tx
+func (r *oidcRepository) WithTx(tx *gorm.DB) OIDCRepository {
+	return &oidcRepository{db: tx}
+}
+
+// CreateState creates a new OIDC login state
+func (r *oidcRepository) CreateState(state *model.OIDCLoginState) error {
+	result := r.db.Create(state)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to create OIDC login state")
+		return result.Error
+	}
+	return nil
+}
+
+// FindStateByHash finds an OIDC login state by the hash of its state
+func (r *oidcRepository) FindStateByHash(hash string) (*model.OIDCLoginState, error) {
+	var state model.OIDCLoginState
+	result := r.db.First(&state, "state_hash = ?", hash)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logrus.WithError(result.Error).Error("Failed to find OIDC login state")
+		return nil, result.Error
+	}
+	return &state, nil
+}
+
+// MarkStateUsed marks an OIDC login state as used and reports whether it
+// was still unused, so that a callback can only complete a login once
+func (r *oidcRepository) MarkStateUsed(state *model.OIDCLoginState) (bool, error) {
+	now := time.Now()
+	result := r.db.Model(&model.OIDCLoginState{}).
+		Where("id = ? AND used_at IS NULL", state.ID).
+		Update("used_at", now)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to mark OIDC login state as used")
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	state.UsedAt = &now
+	return true, nil
+}
+
+// DeleteExpiredStatesBefore deletes OIDC login states that expired before
+// the given time
+func (r *oidcRepository) DeleteExpiredStatesBefore(before time.Time) (int64, error) {
+	result := r.db.Where("expires_at < ?", before).Delete(&model.OIDCLoginState{})
+	return result.RowsAffected, result.Error
+}
+
+// FindIdentity finds the identity of a subject at a provider
+func (r *oidcRepository) FindIdentity(provider, subject string) (*model.OIDCIdentity, error) {
+	var identity model.OIDCIdentity
+	result := r.db.First(&identity, "provider = ? AND subject = ?", provider, subject)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logrus.WithError(result.Error).Error("Failed to find OIDC identity")
+		return nil, result.Error
+	}
+	return &identity, nil
+}
+
+// CreateIdentity creates a new OIDC identity
+func (r *oidcRepository) CreateIdentity(identity *model.OIDCIdentity) error {
+	result := r.db.Create(identity)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to create OIDC identity")
+		return result.Error
+	}
+	return nil
+}
+
+// UpdateLastLogin records that a user signed in with an identity
+func (r *oidcRepository) UpdateLastLogin(identity *model.OIDCIdentity) error {
+	result := r.db.Model(&model.OIDCIdentity{}).
+		Where("id = ?", identity.ID).
+		Updates(map[string]interface{}{
+			"email":         identity.Email,
+			"last_login_at": identity.LastLoginAt,
+		})
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to update OIDC identity")
+		return result.Error
+	}
+	return nil
+}
+
+// FindIdentitiesByUserID finds the identities linked to a user
+func (r *oidcRepository) FindIdentitiesByUserID(userID uuid.UUID) ([]model.OIDCIdentity, error) {
+	var identities []model.OIDCIdentity
+	result := r.db.Where("user_id = ?", userID).Order("created_at").Find(&identities)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to find OIDC identities")
+		return nil, result.Error
+	}
+	return identities, nil
+}
+
+// DeleteIdentitiesByUserID unlinks all identities from a user, and returns
+// how many were deleted
+func (r *oidcRepository) DeleteIdentitiesByUserID(userID uuid.UUID) (int64, error) {
+	result := r.db.Where("user_id = ?", userID).Delete(&model.OIDCIdentity{})
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to delete OIDC identities")
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"gorm.io/gorm"
+)
+
+// OrganizationRepository defines the interface for organization repository
+// operations
+type OrganizationRepository interface {
+	Create(organization *model.Organization) error
+	FindByID(id uuid.UUID) (*model.Organization, error)
+	FindByUserID(userID uuid.UUID) ([]model.Organization, []model.OrganizationMember, error)
+	FindMember(organizationID, userID uuid.UUID) (*model.OrganizationMember, error)
+	FindMembers(organizationID uuid.UUID) ([]model.OrganizationMember, error)
+	CountMembersWithRole(organizationID uuid.UUID, role string) (int64, error)
+	CreateMember(member *model.OrganizationMember) error
+	UpdateMemberRole(member *model.OrganizationMember) error
+	DeleteMember(organizationID, userID uuid.UUID) error
+	WithTx(tx *gorm.DB) OrganizationRepository
+}
+
+// organizationRepository implements OrganizationRepository interface
+type organizationRepository struct {
+	db *gorm.DB
+}
+
+// NewOrganizationRepository creates a new organization repository
+func NewOrganizationRepository(db *gorm.DB) OrganizationRepository {
+	// Auto migrate the organization models
+	if err := db.AutoMigrate(&model.Organization{}, &model.OrganizationMember{}); err != nil {
+		logrus.WithError(err).Fatal("Failed to migrate organization models")
+	}
+
+	return &organizationRepository{db: db}
+}
+
+// WithTx returns a repository that runs its operations in tx
+func (r *organizationRepository) WithTx(tx *gorm.DB) OrganizationRepository {
+	return &organizationRepository{db: tx}
+}
+
+// Create creates a new organization
+func (r *organizationRepository) Create(organization *model.Organization) error {
+	result := r.db.Create(organization)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to create organization")
+		return result.Error
+	}
+	return nil
+}
+
+// FindByID finds an organization by ID
+func (r *organizationRepository) FindByID(id uuid.UUID) (*model.Organization, error) {
+	var organization model.Organization
+	result := r.db.First(&organization, "id = ?", id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logrus.WithError(result.Error).Error("Failed to find organization")
+		return nil, result.Error
+	}
+	return &organization, nil
+}
+
+// FindByUserID finds the organizations a user is a member of, together with
+// the memberships of the user in the same order
+func (r *organizationRepository) FindByUserID(userID uuid.UUID) ([]model.Organization, []model.OrganizationMember, error) {
+	var members []model.OrganizationMember
+	result := r.db.Where("user_id = ?", userID).Order("created_at").Find(&members)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to find organization memberships")
+		return nil, nil, result.Error
+	}
+
+	organizations := make([]model.Organization, 0, len(members))
+	for _, member := range members {
+		organization, err := r.FindByID(member.OrganizationID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if organization != nil {
+			organizations = append(organizations, *organization)
+		}
+	}
+	return organizations, members, nil
+}
+
+// FindMember finds the membership of a user in an organization
+func (r *organizationRepository) FindMember(organizationID, userID uuid.UUID) (*model.OrganizationMember, error) {
+	var member model.OrganizationMember
+	result := r.db.First(&member, "organization_id = ? AND user_id = ?", organizationID, userID)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logrus.WithError(result.Error).Error("Failed to find organization member")
+		return nil, result.Error
+	}
+	return &member, nil
+}
+
+// FindMembers finds the members of an organization whose user still exists
+func (r *organizationRepository) FindMembers(organizationID uuid.UUID) ([]model.OrganizationMember, error) {
+	var members []model.OrganizationMember
+	result := r.db.
+		Joins("JOIN users ON users.id = organization_members.user_id AND users.deleted_at IS NULL").
+		Where("organization_members.organization_id = ?", organizationID).
+		Order("organization_members.created_at").
+		Find(&members)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to find organization members")
+		return nil, result.Error
+	}
+	return members, nil
+}
+
+// CountMembersWithRole counts the members of an organization with a role
+// whose user still exists
+func (r *organizationRepository) CountMembersWithRole(organizationID uuid.UUID, role string) (int64, error) {
+	var count int64
+	result := r.db.Model(&model.OrganizationMember{}).
+		Joins("JOIN users ON users.id = organization_members.user_id AND users.deleted_at IS NULL").
+		Where("organization_members.organization_id = ? AND organization_members.role = ?", organizationID, role).
+		Count(&count)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to count organization members")
+		return 0, result.Error
+	}
+	return count, nil
+}
+
+// CreateMember adds a user to an organization
+func (r *organizationRepository) CreateMember(member *model.OrganizationMember) error {
+	result := r.db.Create(member)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to create organization member")
+		return result.Error
+	}
+	return nil
+}
+
+// UpdateMemberRole saves the role of a member
+func (r *organizationRepository) UpdateMemberRole(member *model.OrganizationMember) error {
+	result := r.db.Model(&model.OrganizationMember{}).
+		Where("organization_id = ? AND user_id = ?", member.OrganizationID, member.UserID).
+		Update("role", member.Role)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to update organization member")
+		return result.Error
+	}
+	return nil
+}
+
+// DeleteMember removes a user from an organization
+func (r *organizationRepository) DeleteMember(organizationID, userID uuid.UUID) error {
+	result := r.db.Where("organization_id = ? AND user_id = ?", organizationID, userID).Delete(&model.OrganizationMember{})
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to delete organization member")
+		return result.Error
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"gorm.io/gorm"
+)
+
+// SessionRepository defines the interface for session repository operations
+type SessionRepository interface {
+	Create(session *model.Session) error
+	FindByID(id uuid.UUID) (*model.Session, error)
+	FindActiveByUserID(userID uuid.UUID) ([]model.Session, error)
+	FindByUserID(userID uuid.UUID) ([]model.Session, error)
+	FindRevokedSince(since time.Time) ([]model.Session, error)
+	Renew(session *model.Session) (bool, error)
+	MarkMFA(id uuid.UUID) (bool, error)
+	Revoke(id uuid.UUID, reason string) (bool, error)
+	RevokeAllByUserID(userID uuid.UUID, reason string) (int64, error)
+	AnonymizeByUserID(userID uuid.UUID) (int64, error)
+	CreateRefreshToken(token *model.RefreshToken) error
+	FindRefreshTokenByHash(hash string) (*model.RefreshToken, error)
+	MarkRefreshTokenUsed(token *model.RefreshToken) (bool, error)
+	DeleteExpiredBefore(before time.Time) (int64, error)
+	WithTx(tx *gorm.DB) SessionRepository
+}
+
+// sessionRepository implements SessionRepository interface
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+// NewSessionRepository creates a new session repository
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	// Auto migrate the session models
+	if err := db.AutoMigrate(&model.Session{}, &model.RefreshToken{}); err != nil {
+		logrus.WithError(err).Fatal("Failed to migrate session models")
+	}
+
+	return &sessionRepository{db: db}
+}
+
+// WithTx returns a repository that runs its operations in tx
+func (r *sessionRepository) WithTx(tx *gorm.DB) SessionRepository {
+	return &sessionRepository{db: tx}
+}
+
+// Create creates a new session
+func (r *sessionRepository) Create(session *model.Session) error {
+	result := r.db.Create(session)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to create session")
+		return result.Error
+	}
+	return nil
+}
+
+// FindByID finds a session by ID
+func (r *sessionRepository) FindByID(id uuid.UUID) (*model.Session, error) {
+	var session model.Session
+	result := r.db.First(&session, "id = ?", id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logrus.WithError(result.Error).Error("Failed to find session by ID")
+		return nil, result.Error
+	}
+	return &session, nil
+}
+
+// FindActiveByUserID finds the sessions of a user that are neither revoked
+// nor expired, most recently used first
+func (r *sessionRepository) FindActiveByUserID(userID uuid.UUID) ([]model.Session, error) {
+	var sessions []model.Session
+	result := r.db.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to find sessions by user ID")
+		return nil, result.Error
+	}
+	return sessions, nil
+}
+
+// FindByUserID finds all sessions of a user that have not been deleted yet,
+// including revoked and expired ones, newest first
+func (r *sessionRepository) FindByUserID(userID uuid.UUID) ([]model.Session, error) {
+	var sessions []model.Session
+	result := r.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&sessions)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to find sessions by user ID")
+		return nil, result.Error
+	}
+	return sessions, nil
+}
+
+// FindRevokedSince finds the sessions revoked at or after since
+func (r *sessionRepository) FindRevokedSince(since time.Time) ([]model.Session, error) {
+	var sessions []model.Session
+	result := r.db.Where("revoked_at >= ?", since).Order("revoked_at ASC").Find(&sessions)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to find revoked sessions")
+		return nil, result.Error
+	}
+	return sessions, nil
+}
+
+// Renew saves the new expiry and client of a session after its refresh token
+// was rotated and reports whether the session was still active
+func (r *sessionRepository) Renew(session *model.Session) (bool, error) {
+	result := r.db.Model(&model.Session{}).
+		Where("id = ? AND revoked_at IS NULL", session.ID).
+		Updates(map[string]interface{}{
+			"user_agent":   session.UserAgent,
+			"ip_address":   session.IPAddress,
+			"last_used_at": session.LastUsedAt,
+			"expires_at":   session.ExpiresAt,
+		})
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to renew session")
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// MarkMFA records that the user proved a second factor in a session and
+// reports whether the session was still active
+func (r *sessionRepository) MarkMFA(id uuid.UUID) (bool, error) {
+	result := r.db.Model(&model.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("mfa", true)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to mark session as MFA")
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Revoke revokes a session and reports whether it was still active
+func (r *sessionRepository) Revoke(id uuid.UUID, reason string) (bool, error) {
+	result := r.db.Model(&model.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"revoked_at":     time.Now(),
+			"revoked_reason": reason,
+		})
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to revoke session")
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// RevokeAllByUserID revokes all sessions of a user and returns how many were
+// still active
+func (r *sessionRepository) RevokeAllByUserID(userID uuid.UUID, reason string) (int64, error) {
+	result := r.db.Model(&model.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Updates(map[string]interface{}{
+			"revoked_at":     time.Now(),
+			"revoked_reason": reason,
+		})
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to revoke sessions of user")
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+// AnonymizeByUserID clears the user agent and IP address of all sessions of
+// a user, and returns how many sessions were anonymised
+func (r *sessionRepository) AnonymizeByUserID(userID uuid.UUID) (int64, error) {
+	result := r.db.Model(&model.Session{}).
+		Where("user_id = ?", userID).
+		Updates(map[string]interface{}{
+			"user_agent": "",
+			"ip_address": "",
+		})
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to anonymize sessions of user")
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+// CreateRefreshToken creates a new refresh token
+func (r *sessionRepository) CreateRefreshToken(token *model.RefreshToken) error {
+	result := r.db.Create(token)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to create refresh token")
+		return result.Error
+	}
+	return nil
+}
+
+// FindRefreshTokenByHash finds a refresh token by the hash of its value
+func (r *sessionRepository) FindRefreshTokenByHash(hash string) (*model.RefreshToken, error) {
+	var token model.RefreshToken
+	result := r.db.First(&token, "token_hash = ?", hash)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logrus.WithError(result.Error).Error("Failed to find refresh token")
+		return nil, result.Error
+	}
+	return &token, nil
+}
+
+// MarkRefreshTokenUsed marks a refresh token as used and reports whether it
+// was still unused, so that a token can only be redeemed once even when it
+// is presented concurrently
+func (r *sessionRepository) MarkRefreshTokenUsed(token *model.RefreshToken) (bool, error) {
+	now := time.Now()
+	result := r.db.Model(&model.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL", token.ID).
+		Update("used_at", now)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to mark refresh token as used")
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	token.UsedAt = &now
+	return true, nil
+}
+
+// DeleteExpiredBefore deletes sessions and refresh tokens that expired before
+// the given time
+func (r *sessionRepository) DeleteExpiredBefore(before time.Time) (int64, error) {
+	var deleted int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at < ?", before).Delete(&model.RefreshToken{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Where("expires_at < ?", before).Delete(&model.Session{})
+		deleted = result.RowsAffected
+		return result.Error
+	})
+	return deleted, err
+}
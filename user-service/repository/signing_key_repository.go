@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"gorm.io/gorm"
+)
+
+// SigningKeyRepository defines the interface for signing key repository operations
+type SigningKeyRepository interface {
+	Create(key *model.SigningKey) error
+	FindUnexpired() ([]model.SigningKey, error)
+	RetireAllExcept(id string, retiredAt, expiresAt time.Time) error
+	DeleteExpiredBefore(before time.Time) (int64, error)
+}
+
+// signingKeyRepository implements SigningKeyRepository interface
+type signingKeyRepository struct {
+	db *gorm.DB
+}
+
+// NewSigningKeyRepository creates a new signing key repository
+func NewSigningKeyRepository(db *gorm.DB) SigningKeyRepository {
+	// Auto migrate the signing key model
+	if err := db.AutoMigrate(&model.SigningKey{}); err != nil {
+		logrus.WithError(err).Fatal("Failed to migrate signing key model")
+	}
+
+	return &signingKeyRepository{db: db}
+}
+
+// Create creates a new signing key
+func (r *signingKeyRepository) Create(key *model.SigningKey) error {
+	result := r.db.Create(key)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to create signing key")
+		return result.Error
+	}
+	return nil
+}
+
+// FindUnexpired finds the signing keys that have not expired, newest first
+func (r *signingKeyRepository) FindUnexpired() ([]model.SigningKey, error) {
+	var keys []model.SigningKey
+	result := r.db.
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Order("created_at DESC").
+		Find(&keys)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to find signing keys")
+		return nil, result.Error
+	}
+	return keys, nil
+}
+
+// RetireAllExcept retires all signing keys but the given one, so that they
+// no longer sign tokens and expire at expiresAt
+func (r *signingKeyRepository) RetireAllExcept(id string, retiredAt, expiresAt time.Time) error {
+	result := r.db.Model(&model.SigningKey{}).
+		Where("id <> ? AND retired_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"retired_at": retiredAt,
+			"expires_at": expiresAt,
+		})
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to retire signing keys")
+		return result.Error
+	}
+	return nil
+}
+
+// DeleteExpiredBefore deletes signing keys that expired before the given time
+func (r *signingKeyRepository) DeleteExpiredBefore(before time.Time) (int64, error) {
+	result := r.db.Where("expires_at < ?", before).Delete(&model.SigningKey{})
+	return result.RowsAffected, result.Error
+}
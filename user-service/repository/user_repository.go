@@ -2,6 +2,8 @@ package repository
 
 import (
 	"errors"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
@@ -13,9 +15,13 @@ import (
 type UserRepository interface {
 	Create(user *model.User) error
 	FindByID(id uuid.UUID) (*model.User, error)
+	FindByIDWithDeleted(id uuid.UUID) (*model.User, error)
 	FindByEmail(email string) (*model.User, error)
+	Search(req model.UserSearchRequest) ([]model.User, int64, error)
 	Update(user *model.User) error
 	Delete(id uuid.UUID) error
+	Anonymize(user *model.User) error
+	WithTx(tx *gorm.DB) UserRepository
 }
 
 // userRepository implements UserRepository interface
@@ -33,6 +39,11 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 	return &userRepository{db: db}
 }
 
+// WithTx returns a repository that runs its operations in tx
+func (r *userRepository) WithTx(tx *gorm.DB) UserRepository {
+	return &userRepository{db: tx}
+}
+
 // Create creates a new user
 func (r *userRepository) Create(user *model.User) error {
 	result := r.db.Create(user)
@@ -57,6 +68,20 @@ func (r *userRepository) FindByID(id uuid.UUID) (*model.User, error) {
 	return &user, nil
 }
 
+// FindByIDWithDeleted finds a user by ID, including deleted users
+func (r *userRepository) FindByIDWithDeleted(id uuid.UUID) (*model.User, error) {
+	var user model.User
+	result := r.db.Unscoped().First(&user, "id = ?", id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logrus.WithError(result.Error).Error("Failed to find user by ID")
+		return nil, result.Error
+	}
+	return &user, nil
+}
+
 // FindByEmail finds a user by email
 func (r *userRepository) FindByEmail(email string) (*model.User, error) {
 	var user model.User
@@ -71,6 +96,58 @@ func (r *userRepository) FindByEmail(email string) (*model.User, error) {
 	return &user, nil
 }
 
+// Search finds the users matching all given filters, ordered by email.
+// Text filters match case-insensitively anywhere in the field.
+func (r *userRepository) Search(req model.UserSearchRequest) ([]model.User, int64, error) {
+	var users []model.User
+	var total int64
+
+	// Build query
+	query := r.db.Model(&model.User{})
+
+	// Apply filters
+	if req.Query != "" {
+		pattern := likePattern(req.Query)
+		query = query.Where(
+			"LOWER(email) LIKE ? OR LOWER(first_name || ' ' || last_name) LIKE ? OR phone LIKE ?",
+			pattern, pattern, pattern,
+		)
+	}
+	if req.Email != "" {
+		query = query.Where("LOWER(email) LIKE ?", likePattern(req.Email))
+	}
+	if req.Name != "" {
+		query = query.Where("LOWER(first_name || ' ' || last_name) LIKE ?", likePattern(req.Name))
+	}
+	if req.Phone != "" {
+		query = query.Where("phone LIKE ?", likePattern(req.Phone))
+	}
+	if req.Role != "" {
+		query = query.Where("role = ?", req.Role)
+	}
+
+	// Count total results
+	if err := query.Count(&total).Error; err != nil {
+		logrus.WithError(err).Error("Failed to count users")
+		return nil, 0, err
+	}
+
+	// Apply pagination
+	offset := (req.Page - 1) * req.PageSize
+	result := query.Order("email").Offset(offset).Limit(req.PageSize).Find(&users)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to search users")
+		return nil, 0, result.Error
+	}
+
+	return users, total, nil
+}
+
+// likePattern returns a LIKE pattern that matches value anywhere, ignoring case
+func likePattern(value string) string {
+	return "%" + strings.ToLower(strings.TrimSpace(value)) + "%"
+}
+
 // Update updates a user
 func (r *userRepository) Update(user *model.User) error {
 	result := r.db.Save(user)
@@ -89,4 +166,29 @@ func (r *userRepository) Delete(id uuid.UUID) error {
 		return result.Error
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+// Anonymize saves the erased email address and password of a user, clears
+// the other personal fields and deletes the user, unless it is deleted
+// already. Deleted users are anonymised as well.
+func (r *userRepository) Anonymize(user *model.User) error {
+	result := r.db.Unscoped().Model(&model.User{}).
+		Where("id = ?", user.ID).
+		Updates(map[string]interface{}{
+			"email":        user.Email,
+			"password":     user.Password,
+			"first_name":   "",
+			"last_name":    "",
+			"phone":        "",
+			"active":       false,
+			"verified":     false,
+			"verified_at":  nil,
+			"suspended_at": nil,
+			"deleted_at":   gorm.Expr("COALESCE(deleted_at, ?)", time.Now()),
+		})
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to anonymize user")
+		return result.Error
+	}
+	return nil
+}
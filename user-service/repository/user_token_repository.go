@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"gorm.io/gorm"
+)
+
+// UserTokenRepository defines the interface for user token repository operations
+type UserTokenRepository interface {
+	Create(token *model.UserToken) error
+	FindByHash(purpose, hash string) (*model.UserToken, error)
+	CountCreatedSince(userID uuid.UUID, purpose string, since time.Time) (int64, error)
+	MarkUsed(token *model.UserToken) (bool, error)
+	InvalidateByUserID(userID uuid.UUID, purpose string) error
+	DeleteExpiredBefore(before time.Time) (int64, error)
+	WithTx(tx *gorm.DB) UserTokenRepository
+}
+
+// userTokenRepository implements UserTokenRepository interface
+type userTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewUserTokenRepository creates a new user token repository
+func NewUserTokenRepository(db *gorm.DB) UserTokenRepository {
+	// Auto migrate the user token model
+	if err := db.AutoMigrate(&model.UserToken{}); err != nil {
+		logrus.WithError(err).Fatal("Failed to migrate user token model")
+	}
+
+	return &userTokenRepository{db: db}
+}
+
+// WithTx returns a repository that runs its operations in tx
+func (r *userTokenRepository) WithTx(tx *gorm.DB) UserTokenRepository {
+	return &userTokenRepository{db: tx}
+}
+
+// Create creates a new user token
+func (r *userTokenRepository) Create(token *model.UserToken) error {
+	result := r.db.Create(token)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to create user token")
+		return result.Error
+	}
+	return nil
+}
+
+// FindByHash finds a user token of a purpose by the hash of its value
+func (r *userTokenRepository) FindByHash(purpose, hash string) (*model.UserToken, error) {
+	var token model.UserToken
+	result := r.db.First(&token, "purpose = ? AND token_hash = ?", purpose, hash)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		logrus.WithError(result.Error).Error("Failed to find user token")
+		return nil, result.Error
+	}
+	return &token, nil
+}
+
+// CountCreatedSince counts the tokens of a purpose created for a user since
+// the given time
+func (r *userTokenRepository) CountCreatedSince(userID uuid.UUID, purpose string, since time.Time) (int64, error) {
+	var count int64
+	result := r.db.Model(&model.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND created_at >= ?", userID, purpose, since).
+		Count(&count)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to count user tokens")
+		return 0, result.Error
+	}
+	return count, nil
+}
+
+// MarkUsed marks a user token as used and reports whether it was still
+// unused, so that a token can only be redeemed once even when it is
+// presented concurrently
+func (r *userTokenRepository) MarkUsed(token *model.UserToken) (bool, error) {
+	now := time.Now()
+	result := r.db.Model(&model.UserToken{}).
+		Where("id = ? AND used_at IS NULL", token.ID).
+		Update("used_at", now)
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to mark user token as used")
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	token.UsedAt = &now
+	return true, nil
+}
+
+// InvalidateByUserID marks all unused tokens of a purpose of a user as used
+func (r *userTokenRepository) InvalidateByUserID(userID uuid.UUID, purpose string) error {
+	result := r.db.Model(&model.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		logrus.WithError(result.Error).Error("Failed to invalidate user tokens")
+		return result.Error
+	}
+	return nil
+}
+
+// DeleteExpiredBefore deletes user tokens that expired before the given time
+func (r *userTokenRepository) DeleteExpiredBefore(before time.Time) (int64, error) {
+	result := r.db.Where("expires_at < ?", before).Delete(&model.UserToken{})
+	return result.RowsAffected, result.Error
+}
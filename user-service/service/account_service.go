@@ -0,0 +1,385 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/contracts"
+	"github.com/yourusername/ticket-system/messaging"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"github.com/yourusername/ticket-system/user-service/repository"
+	"gorm.io/gorm"
+)
+
+// Account email settings
+const (
+	userTokenBytes              = 32
+	defaultVerificationTTL      = 24 * time.Hour
+	defaultPasswordResetTTL     = time.Hour
+	defaultAppURL               = "http://localhost:3000"
+	maxAccountEmailsPerWindow   = 3
+	accountEmailRateLimitWindow = time.Hour
+	accountEmailPublishTimeout  = 5 * time.Second
+)
+
+// accountEmailsExchange receives the events carrying single use tokens. Only
+// the notification service consumes it.
+const accountEmailsExchange = "account_emails"
+
+// errInvalidUserToken is returned for unknown, expired and used tokens alike
+var errInvalidUserToken = errors.New("invalid or expired token")
+
+// AccountService defines the interface for email verification and password
+// reset operations
+type AccountService interface {
+	SendVerification(user *model.User) error
+	RequestVerification(email string) error
+	VerifyEmail(token string) (*model.UserResponse, error)
+	RequestPasswordReset(email string) error
+	ResetPassword(req model.ResetPasswordRequest) error
+	StartCleanup(ctx context.Context, interval time.Duration)
+}
+
+// accountService implements AccountService interface
+type accountService struct {
+	userRepo       repository.UserRepository
+	tokenRepo      repository.UserTokenRepository
+	outboxRepo     messaging.OutboxRepository
+	broker         messaging.Broker
+	sessionService SessionService
+	db             *gorm.DB
+}
+
+// NewAccountService creates a new account service
+func NewAccountService(userRepo repository.UserRepository, tokenRepo repository.UserTokenRepository, outboxRepo messaging.OutboxRepository, broker messaging.Broker, sessionService SessionService, db *gorm.DB) AccountService {
+	return &accountService{
+		userRepo:       userRepo,
+		tokenRepo:      tokenRepo,
+		outboxRepo:     outboxRepo,
+		broker:         broker,
+		sessionService: sessionService,
+		db:             db,
+	}
+}
+
+// SendVerification issues an email verification token for a user and
+// publishes the event that makes the notification service email its link.
+// Earlier verification tokens of the user stop working.
+func (s *accountService) SendVerification(user *model.User) error {
+	token, expiresAt, err := s.issueToken(user.ID, model.TokenPurposeEmailVerification, verificationTTL())
+	if err != nil {
+		return err
+	}
+
+	// Publish verification requested event
+	event := contracts.UserVerificationRequested{
+		UserState:       contracts.UserState{UserID: user.ID, Email: user.Email},
+		VerificationURL: appLink("/verify-email", token),
+		ExpiresAt:       expiresAt,
+	}
+	return publishAccountEmail(s.broker, user.ID.String(), event)
+}
+
+// RequestVerification sends a new verification email to a user who has not
+// verified their address yet. Unknown and verified addresses are ignored, so
+// that the response does not reveal whether an account exists.
+func (s *accountService) RequestVerification(email string) error {
+	user, err := s.findEmailRecipient(email, model.TokenPurposeEmailVerification)
+	if err != nil || user == nil || user.Verified {
+		return err
+	}
+
+	return s.SendVerification(user)
+}
+
+// VerifyEmail redeems an email verification token and marks the address of
+// its user as verified
+func (s *accountService) VerifyEmail(token string) (*model.UserResponse, error) {
+	userToken, user, err := s.redeemableToken(model.TokenPurposeEmailVerification, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify user
+	now := time.Now()
+	if !user.Verified {
+		user.Verified = true
+		user.VerifiedAt = &now
+	}
+
+	// Use token and save user and its updated event to database
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := useUserToken(s.tokenRepo.WithTx(tx), userToken); err != nil {
+			return err
+		}
+
+		if err := s.userRepo.WithTx(tx).Update(user); err != nil {
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+
+		// Publish user updated event
+		event := contracts.UserUpdated{UserState: contracts.UserState{UserID: user.ID, Email: user.Email}}
+		return messaging.EnqueueEvent(s.outboxRepo, tx, "user_events", user.ID.String(), event)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Return user response
+	userResponse := user.ToResponse()
+	return &userResponse, nil
+}
+
+// RequestPasswordReset sends a password reset email to a user. Unknown
+// addresses are ignored, so that the response does not reveal whether an
+// account exists.
+func (s *accountService) RequestPasswordReset(email string) error {
+	user, err := s.findEmailRecipient(email, model.TokenPurposePasswordReset)
+	if err != nil || user == nil {
+		return err
+	}
+
+	token, expiresAt, err := s.issueToken(user.ID, model.TokenPurposePasswordReset, passwordResetTTL())
+	if err != nil {
+		return err
+	}
+
+	// Publish password reset requested event
+	event := contracts.UserPasswordResetRequested{
+		UserState: contracts.UserState{UserID: user.ID, Email: user.Email},
+		ResetURL:  appLink("/reset-password", token),
+		ExpiresAt: expiresAt,
+	}
+	return publishAccountEmail(s.broker, user.ID.String(), event)
+}
+
+// ResetPassword redeems a password reset token and sets a new password. All
+// sessions of the user are revoked, and because the token was received by
+// email, the address of the user is verified as well.
+func (s *accountService) ResetPassword(req model.ResetPasswordRequest) error {
+	userToken, user, err := s.redeemableToken(model.TokenPurposePasswordReset, req.Token)
+	if err != nil {
+		return err
+	}
+
+	// Update password
+	now := time.Now()
+	user.Password = req.NewPassword // Will be hashed by GORM hook
+	if !user.Verified {
+		user.Verified = true
+		user.VerifiedAt = &now
+	}
+
+	// Use token and save user and its password changed event to database
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		txTokenRepo := s.tokenRepo.WithTx(tx)
+		if err := useUserToken(txTokenRepo, userToken); err != nil {
+			return err
+		}
+		if err := txTokenRepo.InvalidateByUserID(user.ID, model.TokenPurposePasswordReset); err != nil {
+			return fmt.Errorf("failed to invalidate password reset tokens: %w", err)
+		}
+
+		if err := s.userRepo.WithTx(tx).Update(user); err != nil {
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+
+		// Sign user out everywhere, the old password may have been compromised
+		if _, err := s.sessionService.RevokeAllSessions(tx, user.ID, model.SessionRevokedPasswordReset); err != nil {
+			return err
+		}
+
+		// Publish password changed event
+		event := contracts.UserPasswordChanged{UserState: contracts.UserState{UserID: user.ID, Email: user.Email}}
+		return messaging.EnqueueEvent(s.outboxRepo, tx, "user_events", user.ID.String(), event)
+	})
+}
+
+// StartCleanup periodically deletes expired user tokens until ctx is cancelled
+func (s *accountService) StartCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := s.tokenRepo.DeleteExpiredBefore(time.Now())
+			if err != nil {
+				logrus.WithError(err).Error("Failed to delete expired user tokens")
+				continue
+			}
+			if deleted > 0 {
+				logrus.Infof("Deleted %d expired user tokens", deleted)
+			}
+		}
+	}
+}
+
+// findEmailRecipient finds the active user an account email for purpose may
+// be sent to. It returns nil for unknown and suspended users, and for users
+// who were sent too many of these emails recently.
+func (s *accountService) findEmailRecipient(email, purpose string) (*model.User, error) {
+	user, err := s.userRepo.FindByEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	if user == nil || !user.Active {
+		return nil, nil
+	}
+
+	sent, err := s.tokenRepo.CountCreatedSince(user.ID, purpose, time.Now().Add(-accountEmailRateLimitWindow))
+	if err != nil {
+		return nil, fmt.Errorf("failed to count user tokens: %w", err)
+	}
+
+	if sent >= maxAccountEmailsPerWindow {
+		logrus.WithFields(logrus.Fields{
+			"user_id": user.ID,
+			"purpose": purpose,
+		}).Warn("Too many account emails requested, ignoring request")
+		return nil, nil
+	}
+
+	return user, nil
+}
+
+// redeemableToken finds an unused, unexpired token of purpose and its user
+func (s *accountService) redeemableToken(purpose, token string) (*model.UserToken, *model.User, error) {
+	userToken, err := s.tokenRepo.FindByHash(purpose, hashToken(token))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find token: %w", err)
+	}
+
+	if userToken == nil || userToken.UsedAt != nil || time.Now().After(userToken.ExpiresAt) {
+		return nil, nil, errInvalidUserToken
+	}
+
+	user, err := s.userRepo.FindByID(userToken.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	if user == nil {
+		return nil, nil, errInvalidUserToken
+	}
+
+	return userToken, user, nil
+}
+
+// issueToken issues a token of purpose for a user in its own transaction
+func (s *accountService) issueToken(userID uuid.UUID, purpose string, ttl time.Duration) (string, time.Time, error) {
+	var token string
+	var expiresAt time.Time
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		token, expiresAt, err = issueUserToken(s.tokenRepo.WithTx(tx), userID, purpose, ttl)
+		return err
+	})
+	return token, expiresAt, err
+}
+
+// issueUserToken invalidates the unused tokens of purpose of a user, creates
+// a new one and returns its value and expiry
+func issueUserToken(tokenRepo repository.UserTokenRepository, userID uuid.UUID, purpose string, ttl time.Duration) (string, time.Time, error) {
+	if err := tokenRepo.InvalidateByUserID(userID, purpose); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to invalidate user tokens: %w", err)
+	}
+
+	value := make([]byte, userTokenBytes)
+	if _, err := rand.Read(value); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(value)
+
+	userToken := &model.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := tokenRepo.Create(userToken); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to create user token: %w", err)
+	}
+	return token, userToken.ExpiresAt, nil
+}
+
+// useUserToken marks a user token as used, failing when it was used
+// concurrently
+func useUserToken(tokenRepo repository.UserTokenRepository, token *model.UserToken) error {
+	unused, err := tokenRepo.MarkUsed(token)
+	if err != nil {
+		return fmt.Errorf("failed to mark token as used: %w", err)
+	}
+	if !unused {
+		return errInvalidUserToken
+	}
+	return nil
+}
+
+// publishAccountEmail publishes an event whose link carries a single use
+// token to the account emails exchange. The event is published directly
+// rather than through the outbox, so that the token is never stored, and
+// after the token was saved, so that the link works once it is emailed. A
+// failed publish leaves an unused token behind, which the next request for
+// the same purpose invalidates.
+func publishAccountEmail(broker messaging.Broker, correlationID string, event contracts.Event) error {
+	id := uuid.NewString()
+	body, err := contracts.Marshal(id, correlationID, event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), accountEmailPublishTimeout)
+	defer cancel()
+
+	err = broker.Publish(ctx, accountEmailsExchange, event.EventType(), messaging.Message{
+		ID:          id,
+		ContentType: "application/json",
+		Body:        body,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish %s event: %w", event.EventType(), err)
+	}
+	return nil
+}
+
+// appLink returns a link to a page of the web app carrying a token
+func appLink(path, token string) string {
+	appURL := strings.TrimRight(os.Getenv("APP_URL"), "/")
+	if appURL == "" {
+		appURL = defaultAppURL
+	}
+	return appURL + path + "?token=" + url.QueryEscape(token)
+}
+
+// verificationTTL returns how long an email verification link is valid, from
+// the EMAIL_VERIFICATION_TTL environment variable or 24 hours by default
+func verificationTTL() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv("EMAIL_VERIFICATION_TTL"))
+	if err != nil || ttl <= 0 {
+		return defaultVerificationTTL
+	}
+	return ttl
+}
+
+// passwordResetTTL returns how long a password reset link is valid, from the
+// PASSWORD_RESET_TTL environment variable or 1 hour by default
+func passwordResetTTL() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv("PASSWORD_RESET_TTL"))
+	if err != nil || ttl <= 0 {
+		return defaultPasswordResetTTL
+	}
+	return ttl
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/ticket-system/contracts"
+	"github.com/yourusername/ticket-system/messaging"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"github.com/yourusername/ticket-system/user-service/repository"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupAccountService creates an account service on an in-memory database
+// and broker with one active, unverified user
+func setupAccountService(t *testing.T) (AccountService, *model.User, *gorm.DB, *messaging.MemoryBroker) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	// Every connection opens its own in-memory database
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	userRepo := repository.NewUserRepository(db)
+	user := &model.User{
+		Email:    "account@example.com",
+		Password: "password123",
+		Role:     "user",
+		Active:   true,
+	}
+	require.NoError(t, userRepo.Create(user))
+
+	broker := messaging.NewMemoryBroker()
+	require.NoError(t, broker.DeclareExchange(accountEmailsExchange))
+
+	sessionService := NewSessionService(repository.NewSessionRepository(db), userRepo, nil, db)
+	accountService := NewAccountService(userRepo, repository.NewUserTokenRepository(db), messaging.NewOutboxRepository(db), broker, sessionService, db)
+	return accountService, user, db, broker
+}
+
+// accountEmails returns the published messages of the given type
+func accountEmails(broker *messaging.MemoryBroker, eventType string) []messaging.Message {
+	var messages []messaging.Message
+	for _, message := range broker.Published() {
+		if message.RoutingKey == eventType {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+// lastEmailedToken returns the token of the link in the last published
+// message of the given type
+func lastEmailedToken(t *testing.T, broker *messaging.MemoryBroker, eventType string) string {
+	messages := accountEmails(broker, eventType)
+	require.NotEmpty(t, messages)
+	message := messages[len(messages)-1]
+	assert.Equal(t, accountEmailsExchange, message.Exchange)
+
+	envelope, err := contracts.Parse(message.Body)
+	require.NoError(t, err)
+
+	var link string
+	switch eventType {
+	case contracts.TypeUserVerificationRequested:
+		var event contracts.UserVerificationRequested
+		require.NoError(t, envelope.Decode(&event))
+		link = event.VerificationURL
+	case contracts.TypeUserPasswordResetRequested:
+		var event contracts.UserPasswordResetRequested
+		require.NoError(t, envelope.Decode(&event))
+		link = event.ResetURL
+	}
+
+	parsed, err := url.Parse(link)
+	require.NoError(t, err)
+	return parsed.Query().Get("token")
+}
+
+func TestAccountService_VerifyEmail(t *testing.T) {
+	accountService, user, db, broker := setupAccountService(t)
+
+	require.NoError(t, accountService.RequestVerification(user.Email))
+	token := lastEmailedToken(t, broker, contracts.TypeUserVerificationRequested)
+
+	// Tokens are never stored in the outbox
+	var stored int64
+	require.NoError(t, db.Model(&messaging.OutboxMessage{}).Count(&stored).Error)
+	assert.Zero(t, stored)
+
+	verified, err := accountService.VerifyEmail(token)
+	require.NoError(t, err)
+	assert.True(t, verified.Verified)
+
+	// Tokens are single use
+	_, err = accountService.VerifyEmail(token)
+	assert.EqualError(t, err, "invalid or expired token")
+}
+
+func TestAccountService_ResetPassword(t *testing.T) {
+	accountService, user, db, broker := setupAccountService(t)
+
+	// Only the latest link works
+	require.NoError(t, accountService.RequestPasswordReset(user.Email))
+	oldToken := lastEmailedToken(t, broker, contracts.TypeUserPasswordResetRequested)
+	require.NoError(t, accountService.RequestPasswordReset(user.Email))
+	token := lastEmailedToken(t, broker, contracts.TypeUserPasswordResetRequested)
+
+	err := accountService.ResetPassword(model.ResetPasswordRequest{Token: oldToken, NewPassword: "new-password"})
+	assert.EqualError(t, err, "invalid or expired token")
+
+	require.NoError(t, accountService.ResetPassword(model.ResetPasswordRequest{Token: token, NewPassword: "new-password"}))
+
+	var updated model.User
+	require.NoError(t, db.First(&updated, "id = ?", user.ID).Error)
+	assert.True(t, updated.CheckPassword("new-password"))
+	assert.True(t, updated.Verified)
+
+	// Unknown addresses are accepted without sending anything
+	assert.NoError(t, accountService.RequestPasswordReset("unknown@example.com"))
+}
+
+func TestAccountService_RateLimitsEmails(t *testing.T) {
+	accountService, user, _, broker := setupAccountService(t)
+
+	for i := 0; i < maxAccountEmailsPerWindow+2; i++ {
+		require.NoError(t, accountService.RequestPasswordReset(user.Email))
+	}
+
+	assert.Len(t, accountEmails(broker, contracts.TypeUserPasswordResetRequested), maxAccountEmailsPerWindow)
+}
+
+func TestAccountService_FailedPublishIsReported(t *testing.T) {
+	accountService, user, _, broker := setupAccountService(t)
+	require.NoError(t, broker.Close(context.Background()))
+
+	err := accountService.RequestPasswordReset(user.Email)
+	assert.ErrorIs(t, err, messaging.ErrClosed)
+
+	// The unsent token is replaced by the next request
+	require.ErrorIs(t, accountService.RequestVerification(user.Email), messaging.ErrClosed)
+}
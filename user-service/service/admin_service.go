@@ -0,0 +1,595 @@
+package service
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/contracts"
+	"github.com/yourusername/ticket-system/messaging"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"github.com/yourusername/ticket-system/user-service/oidc"
+	"github.com/yourusername/ticket-system/user-service/repository"
+	"github.com/yourusername/ticket-system/user-service/utils"
+	"gorm.io/gorm"
+)
+
+// Admin settings
+const (
+	// maxStaffImportRows is the largest number of accounts one import creates
+	maxStaffImportRows = 1000
+	// activityAuditEntries is the number of audit entries in an activity summary
+	activityAuditEntries = 20
+	// defaultStaffInviteTTL is how long imported staff can set their password
+	defaultStaffInviteTTL = 72 * time.Hour
+)
+
+// AdminService defines the interface for the operations admins use to
+// manage the accounts of other users
+type AdminService interface {
+	SearchUsers(req model.UserSearchRequest) ([]model.UserResponse, int64, error)
+	GetActivity(userID uuid.UUID) (*model.UserActivity, error)
+	ChangeRole(adminID, userID uuid.UUID, role string, client model.ClientInfo) (*model.UserResponse, error)
+	Impersonate(adminID, userID uuid.UUID, reason string, client model.ClientInfo) (*model.ImpersonationResponse, error)
+	ImportStaff(adminID uuid.UUID, r io.Reader, format string, dryRun bool, client model.ClientInfo) (*model.ImportResult, error)
+}
+
+// adminService implements AdminService interface
+type adminService struct {
+	userRepo         repository.UserRepository
+	sessionRepo      repository.SessionRepository
+	mfaRepo          repository.MFARepository
+	oidcRepo         repository.OIDCRepository
+	organizationRepo repository.OrganizationRepository
+	auditRepo        repository.AuditRepository
+	tokenRepo        repository.UserTokenRepository
+	outboxRepo       messaging.OutboxRepository
+	broker           messaging.Broker
+	sessionService   SessionService
+	db               *gorm.DB
+}
+
+// NewAdminService creates a new admin service
+func NewAdminService(
+	userRepo repository.UserRepository,
+	sessionRepo repository.SessionRepository,
+	mfaRepo repository.MFARepository,
+	oidcRepo repository.OIDCRepository,
+	organizationRepo repository.OrganizationRepository,
+	auditRepo repository.AuditRepository,
+	tokenRepo repository.UserTokenRepository,
+	outboxRepo messaging.OutboxRepository,
+	broker messaging.Broker,
+	sessionService SessionService,
+	db *gorm.DB,
+) AdminService {
+	return &adminService{
+		userRepo:         userRepo,
+		sessionRepo:      sessionRepo,
+		mfaRepo:          mfaRepo,
+		oidcRepo:         oidcRepo,
+		organizationRepo: organizationRepo,
+		auditRepo:        auditRepo,
+		tokenRepo:        tokenRepo,
+		outboxRepo:       outboxRepo,
+		broker:           broker,
+		sessionService:   sessionService,
+		db:               db,
+	}
+}
+
+// SearchUsers finds the users matching the filters of req, a page at a time
+func (s *adminService) SearchUsers(req model.UserSearchRequest) ([]model.UserResponse, int64, error) {
+	users, total, err := s.userRepo.Search(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search users: %w", err)
+	}
+
+	responses := make([]model.UserResponse, 0, len(users))
+	for _, user := range users {
+		responses = append(responses, user.ToResponse())
+	}
+	return responses, total, nil
+}
+
+// GetActivity summarises the sessions, sign in methods and organizations of
+// a user, together with the latest admin actions on their account.
+// Impersonation sessions are not counted as activity of the user.
+func (s *adminService) GetActivity(userID uuid.UUID) (*model.UserActivity, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	activity := &model.UserActivity{
+		User:              user.ToResponse(),
+		IdentityProviders: []string{},
+	}
+
+	// Sessions
+	sessions, err := s.sessionRepo.FindByUserID(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find sessions: %w", err)
+	}
+	for i := range sessions {
+		session := sessions[i]
+		if session.ImpersonatorID != nil {
+			continue
+		}
+		if session.Active() {
+			activity.ActiveSessions++
+		}
+		if activity.LastLoginAt == nil || session.CreatedAt.After(*activity.LastLoginAt) {
+			activity.LastLoginAt = &session.CreatedAt
+		}
+		if activity.LastSeenAt == nil || session.LastUsedAt.After(*activity.LastSeenAt) {
+			activity.LastSeenAt = &session.LastUsedAt
+			activity.LastIPAddress = session.IPAddress
+		}
+	}
+
+	// Sign in methods
+	factor, err := s.mfaRepo.FindFactor(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find MFA factor: %w", err)
+	}
+	activity.MFAEnabled = factor != nil && factor.ConfirmedAt != nil
+
+	identities, err := s.oidcRepo.FindIdentitiesByUserID(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find identities: %w", err)
+	}
+	for _, identity := range identities {
+		activity.IdentityProviders = append(activity.IdentityProviders, identity.Provider)
+	}
+
+	// Organizations
+	_, members, err := s.organizationRepo.FindByUserID(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find organizations: %w", err)
+	}
+	activity.Organizations = len(members)
+
+	// Admin actions
+	if activity.AuditLog, err = s.auditRepo.FindByTargetUserID(user.ID, activityAuditEntries); err != nil {
+		return nil, fmt.Errorf("failed to find audit log: %w", err)
+	}
+
+	return activity, nil
+}
+
+// ChangeRole gives a user another role. The role is carried by the access
+// tokens of the user, so all their sessions are revoked and the user signs
+// in again with the new role.
+func (s *adminService) ChangeRole(adminID, userID uuid.UUID, role string, client model.ClientInfo) (*model.UserResponse, error) {
+	if !containsString(model.UserRoles, role) {
+		return nil, errors.New("invalid role")
+	}
+
+	// Find user by ID
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	if user.Role == role {
+		return nil, errors.New("user already has this role")
+	}
+
+	previousRole := user.Role
+	user.Role = role
+
+	// Save user, its updated event and the audit entry to database
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.userRepo.WithTx(tx).Update(user); err != nil {
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+
+		// Sign user out everywhere, so that no token carries the old role
+		if _, err := s.sessionService.RevokeAllSessions(tx, user.ID, model.SessionRevokedRoleChanged); err != nil {
+			return err
+		}
+
+		// Publish user updated event
+		event := contracts.UserUpdated{UserState: contracts.UserState{UserID: user.ID, Email: user.Email}}
+		if err := messaging.EnqueueEvent(s.outboxRepo, tx, "user_events", user.ID.String(), event); err != nil {
+			return err
+		}
+
+		return s.audit(tx, adminID, user.ID, model.AuditActionRoleChanged, previousRole+" -> "+role, client)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"admin_id": adminID,
+		"user_id":  user.ID,
+		"role":     role,
+	}).Info("Changed role of user")
+
+	// Return user response
+	userResponse := user.ToResponse()
+	return &userResponse, nil
+}
+
+// Impersonate signs an admin in as a user for a single access token, which
+// names the admin in its act claim. Other admins cannot be impersonated, so
+// that impersonation never grants more than the admin already has.
+func (s *adminService) Impersonate(adminID, userID uuid.UUID, reason string, client model.ClientInfo) (*model.ImpersonationResponse, error) {
+	// Find user by ID
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	if user.Role == "admin" {
+		return nil, errors.New("admins cannot be impersonated")
+	}
+
+	if !user.Active {
+		return nil, errors.New("user is suspended")
+	}
+
+	// Start session and record the impersonation in one transaction
+	var tokens *model.TokenResponse
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if tokens, err = s.sessionService.StartImpersonation(tx, user, adminID, client); err != nil {
+			return err
+		}
+
+		return s.audit(tx, adminID, user.ID, model.AuditActionImpersonated, reason, client)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"admin_id": adminID,
+		"user_id":  user.ID,
+	}).Warn("Admin is impersonating user")
+
+	return &model.ImpersonationResponse{
+		Token:          tokens.Token,
+		ExpiresIn:      tokens.ExpiresIn,
+		User:           user.ToResponse(),
+		ImpersonatorID: adminID,
+	}, nil
+}
+
+// staffImportRow is a parsed row of a staff import file with the problems
+// found in it
+type staffImportRow struct {
+	row    int
+	record model.StaffImportRecord
+	errors []string
+}
+
+// ImportStaff validates every row of a CSV or JSON lines file and, unless
+// any row is invalid or dryRun is set, creates the accounts that do not
+// exist yet. New staff set their password through an invitation email.
+// Existing accounts are left as they are.
+func (s *adminService) ImportStaff(adminID uuid.UUID, r io.Reader, format string, dryRun bool, client model.ClientInfo) (*model.ImportResult, error) {
+	// Parse rows
+	var rows []staffImportRow
+	var err error
+	switch format {
+	case model.ImportFormatCSV:
+		rows, err = parseStaffImportCSV(r)
+	case model.ImportFormatJSONL:
+		rows, err = parseStaffImportJSONL(r)
+	default:
+		return nil, fmt.Errorf("invalid import format %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) > maxStaffImportRows {
+		return nil, fmt.Errorf("import file has more than %d rows", maxStaffImportRows)
+	}
+
+	// Validate rows and plan the accounts to create
+	result := &model.ImportResult{
+		DryRun: dryRun,
+		Total:  len(rows),
+		Rows:   make([]model.ImportRowResult, 0, len(rows)),
+	}
+	users := make([]*model.User, 0, len(rows))
+	userRows := make([]int, 0, len(rows))
+	seen := make(map[string]int)
+
+	for _, row := range rows {
+		row.record.Email = strings.ToLower(row.record.Email)
+		row.errors = append(row.errors, validateStaffImportRecord(row.record)...)
+
+		email := row.record.Email
+		if first, exists := seen[email]; exists {
+			row.errors = append(row.errors, fmt.Sprintf("email %s is already used in row %d", email, first))
+		} else if email != "" {
+			seen[email] = row.row
+		}
+
+		if len(row.errors) > 0 {
+			result.Errors = append(result.Errors, model.ImportRowError{
+				Row:    row.row,
+				Email:  email,
+				Errors: row.errors,
+			})
+			continue
+		}
+
+		existing, err := s.userRepo.FindByEmail(email)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find user: %w", err)
+		}
+
+		rowResult := model.ImportRowResult{Row: row.row, Email: email, Action: model.ImportActionCreate}
+		if existing != nil {
+			rowResult.UserID = existing.ID
+			rowResult.Action = model.ImportActionExisting
+			result.Existing++
+		} else {
+			users = append(users, &model.User{
+				Email:     email,
+				FirstName: row.record.FirstName,
+				LastName:  row.record.LastName,
+				Phone:     row.record.Phone,
+				Role:      row.record.Role,
+				Active:    true,
+			})
+			userRows = append(userRows, len(result.Rows))
+			result.Created++
+		}
+		result.Rows = append(result.Rows, rowResult)
+	}
+
+	// Nothing is written unless every row is valid
+	if len(result.Errors) > 0 || dryRun {
+		return result, nil
+	}
+
+	invitations := make([]contracts.UserInvited, 0, len(users))
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		for _, user := range users {
+			invitation, err := s.createStaff(tx, adminID, user, client)
+			if err != nil {
+				return err
+			}
+			invitations = append(invitations, *invitation)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.Applied = true
+
+	for i, user := range users {
+		result.Rows[userRows[i]].UserID = user.ID
+	}
+
+	// Invite the staff once their accounts exist. Staff whose invitation
+	// failed can set their password through a password reset.
+	for _, invitation := range invitations {
+		if err := publishAccountEmail(s.broker, invitation.UserID.String(), invitation); err != nil {
+			logrus.WithError(err).Errorf("Failed to invite staff member %s", invitation.UserID)
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"admin_id": adminID,
+		"created":  result.Created,
+		"existing": result.Existing,
+	}).Info("Imported staff accounts")
+
+	return result, nil
+}
+
+// createStaff creates the account of a staff member in tx and returns the
+// invitation to set their password, to be published after tx commits. Until
+// they do, the account has a random password nobody knows.
+func (s *adminService) createStaff(tx *gorm.DB, adminID uuid.UUID, user *model.User, client model.ClientInfo) (*contracts.UserInvited, error) {
+	password, err := oidc.RandomValue()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate password: %w", err)
+	}
+	user.Password = password // Will be hashed by GORM hook
+
+	if err := s.userRepo.WithTx(tx).Create(user); err != nil {
+		return nil, fmt.Errorf("failed to create user %s: %w", user.Email, err)
+	}
+
+	state := contracts.UserState{UserID: user.ID, Email: user.Email}
+	if err := messaging.EnqueueEvent(s.outboxRepo, tx, "user_events", user.ID.String(), contracts.UserCreated{UserState: state}); err != nil {
+		return nil, err
+	}
+
+	// The invitation is a password reset link, which verifies the address
+	// of the user as well
+	token, expiresAt, err := issueUserToken(s.tokenRepo.WithTx(tx), user.ID, model.TokenPurposePasswordReset, staffInviteTTL())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.audit(tx, adminID, user.ID, model.AuditActionUserImported, user.Role, client); err != nil {
+		return nil, err
+	}
+
+	return &contracts.UserInvited{
+		UserState: state,
+		Role:      user.Role,
+		SetupURL:  appLink("/reset-password", token),
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// audit records an admin action on the account of a user in tx
+func (s *adminService) audit(tx *gorm.DB, adminID, userID uuid.UUID, action, details string, client model.ClientInfo) error {
+	entry := &model.AdminAuditEntry{
+		AdminID:      adminID,
+		TargetUserID: userID,
+		Action:       action,
+		Details:      details,
+		IPAddress:    client.IPAddress,
+	}
+	if err := s.auditRepo.WithTx(tx).Create(entry); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+// validateStaffImportRecord returns the problems of a staff import record
+func validateStaffImportRecord(record model.StaffImportRecord) []string {
+	var errs []string
+
+	if record.Email == "" {
+		errs = append(errs, "email is required")
+	} else if !utils.IsValidEmail(record.Email) || len(record.Email) > 100 {
+		errs = append(errs, "email is invalid")
+	}
+	if record.FirstName == "" {
+		errs = append(errs, "first_name is required")
+	} else if len(record.FirstName) > 50 {
+		errs = append(errs, "first_name must be at most 50 characters")
+	}
+	if record.LastName == "" {
+		errs = append(errs, "last_name is required")
+	} else if len(record.LastName) > 50 {
+		errs = append(errs, "last_name must be at most 50 characters")
+	}
+	if record.Phone != "" && !utils.IsValidPhone(record.Phone) {
+		errs = append(errs, "phone is invalid")
+	}
+	if !containsString(model.UserRoles, record.Role) {
+		errs = append(errs, fmt.Sprintf("role must be one of %s", strings.Join(model.UserRoles, ", ")))
+	}
+
+	return errs
+}
+
+// parseStaffImportCSV parses a staff import CSV file with a header row
+func parseStaffImportCSV(r io.Reader) ([]staffImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	// Read header
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv header: %w", err)
+	}
+
+	index := make(map[string]int, len(header))
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(column))
+		if !containsString(model.ImportCSVColumns, column) {
+			return nil, fmt.Errorf("unknown csv column %s", column)
+		}
+		index[column] = i
+	}
+
+	rows := make([]staffImportRow, 0)
+	for rowNumber := 2; ; rowNumber++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		row := staffImportRow{row: rowNumber}
+		if err != nil {
+			// A wrong number of fields only affects this row
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) || parseErr.Err != csv.ErrFieldCount {
+				return nil, fmt.Errorf("failed to read csv: %w", err)
+			}
+			row.errors = append(row.errors, parseErr.Err.Error())
+		}
+
+		get := func(column string) string {
+			if i, ok := index[column]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		row.record = model.StaffImportRecord{
+			Email:     get("email"),
+			FirstName: get("first_name"),
+			LastName:  get("last_name"),
+			Phone:     get("phone"),
+			Role:      get("role"),
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// parseStaffImportJSONL parses a staff import file with one JSON object per line
+func parseStaffImportJSONL(r io.Reader) ([]staffImportRow, error) {
+	scanner := bufio.NewScanner(r)
+
+	rows := make([]staffImportRow, 0)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		row := staffImportRow{row: lineNumber}
+		decoder := json.NewDecoder(strings.NewReader(line))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&row.record); err != nil {
+			row.errors = append(row.errors, fmt.Sprintf("invalid json: %v", err))
+		}
+		row.record.Email = strings.TrimSpace(row.record.Email)
+
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read jsonl: %w", err)
+	}
+
+	return rows, nil
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// staffInviteTTL returns how long imported staff can set their password,
+// from the STAFF_INVITE_TTL environment variable or 72 hours by default
+func staffInviteTTL() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv("STAFF_INVITE_TTL"))
+	if err != nil || ttl <= 0 {
+		return defaultStaffInviteTTL
+	}
+	return ttl
+}
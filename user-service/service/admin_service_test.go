@@ -0,0 +1,210 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/ticket-system/claims"
+	"github.com/yourusername/ticket-system/contracts"
+	"github.com/yourusername/ticket-system/jwks"
+	"github.com/yourusername/ticket-system/messaging"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"github.com/yourusername/ticket-system/user-service/repository"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupAdminService creates an admin service on an in-memory database with
+// and broker with an admin and a user who is signed in
+func setupAdminService(t *testing.T) (AdminService, KeyService, *model.User, *model.User, *gorm.DB, *messaging.MemoryBroker) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	// Every connection opens its own in-memory database
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	userRepo := repository.NewUserRepository(db)
+	admin := &model.User{Email: "admin@example.com", Password: "password123", FirstName: "Ani", LastName: "Admin", Role: "admin", Active: true}
+	require.NoError(t, userRepo.Create(admin))
+	user := &model.User{Email: "budi@example.com", Password: "password123", FirstName: "Budi", LastName: "Santoso", Phone: "08123456789", Role: "user", Active: true}
+	require.NoError(t, userRepo.Create(user))
+
+	keyService, err := NewKeyService(repository.NewSigningKeyRepository(db), jwks.AlgorithmEdDSA, time.Hour)
+	require.NoError(t, err)
+
+	sessionRepo := repository.NewSessionRepository(db)
+	sessionService := NewSessionService(sessionRepo, userRepo, keyService, db)
+	_, err = sessionService.StartSession(user, model.ClientInfo{UserAgent: "test", IPAddress: "203.0.113.7"}, false)
+	require.NoError(t, err)
+
+	broker := messaging.NewMemoryBroker()
+	require.NoError(t, broker.DeclareExchange(accountEmailsExchange))
+
+	adminService := NewAdminService(
+		userRepo,
+		sessionRepo,
+		repository.NewMFARepository(db),
+		repository.NewOIDCRepository(db),
+		repository.NewOrganizationRepository(db),
+		repository.NewAuditRepository(db),
+		repository.NewUserTokenRepository(db),
+		messaging.NewOutboxRepository(db),
+		broker,
+		sessionService,
+		db,
+	)
+	return adminService, keyService, admin, user, db, broker
+}
+
+func TestAdminService_SearchUsers(t *testing.T) {
+	adminService, _, _, user, _, _ := setupAdminService(t)
+
+	users, total, err := adminService.SearchUsers(model.UserSearchRequest{Query: "SANTOSO", Page: 1, PageSize: 20})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, users, 1)
+	assert.Equal(t, user.ID, users[0].ID)
+
+	_, total, err = adminService.SearchUsers(model.UserSearchRequest{Role: "admin", Page: 1, PageSize: 20})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+
+	users, total, err = adminService.SearchUsers(model.UserSearchRequest{Email: "example.com", Page: 2, PageSize: 1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	require.Len(t, users, 1)
+	assert.Equal(t, user.Email, users[0].Email)
+}
+
+func TestAdminService_ChangeRole(t *testing.T) {
+	adminService, _, admin, user, db, _ := setupAdminService(t)
+	client := model.ClientInfo{IPAddress: "198.51.100.1"}
+
+	changed, err := adminService.ChangeRole(admin.ID, user.ID, "organizer", client)
+	require.NoError(t, err)
+	assert.Equal(t, "organizer", changed.Role)
+
+	// The user signs in again to get the new role
+	var session model.Session
+	require.NoError(t, db.First(&session, "user_id = ?", user.ID).Error)
+	assert.Equal(t, model.SessionRevokedRoleChanged, session.RevokedReason)
+
+	_, err = adminService.ChangeRole(admin.ID, user.ID, "organizer", client)
+	assert.EqualError(t, err, "user already has this role")
+
+	_, err = adminService.ChangeRole(admin.ID, user.ID, "superuser", client)
+	assert.EqualError(t, err, "invalid role")
+
+	activity, err := adminService.GetActivity(user.ID)
+	require.NoError(t, err)
+	require.Len(t, activity.AuditLog, 1)
+	assert.Equal(t, model.AuditActionRoleChanged, activity.AuditLog[0].Action)
+	assert.Equal(t, "user -> organizer", activity.AuditLog[0].Details)
+	assert.Equal(t, "198.51.100.1", activity.AuditLog[0].IPAddress)
+	assert.Equal(t, 0, activity.ActiveSessions)
+	assert.Equal(t, "203.0.113.7", activity.LastIPAddress)
+}
+
+func TestAdminService_Impersonate(t *testing.T) {
+	adminService, keyService, admin, user, _, _ := setupAdminService(t)
+	client := model.ClientInfo{IPAddress: "198.51.100.1"}
+
+	response, err := adminService.Impersonate(admin.ID, user.ID, "ticket #42", client)
+	require.NoError(t, err)
+	assert.Equal(t, admin.ID, response.ImpersonatorID)
+
+	// The token is the user's, with the admin as its actor
+	tokenClaims := &claims.Claims{}
+	_, err = jwt.ParseWithClaims(response.Token, tokenClaims, keyService.Keyfunc)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, tokenClaims.UserID)
+	require.NotNil(t, tokenClaims.Actor)
+	assert.Equal(t, admin.ID, tokenClaims.Actor.Subject)
+
+	// Impersonation is audited but is no activity of the user
+	activity, err := adminService.GetActivity(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, activity.ActiveSessions)
+	require.Len(t, activity.AuditLog, 1)
+	assert.Equal(t, model.AuditActionImpersonated, activity.AuditLog[0].Action)
+	assert.Equal(t, "ticket #42", activity.AuditLog[0].Details)
+
+	_, err = adminService.Impersonate(user.ID, admin.ID, "ticket #42", client)
+	assert.EqualError(t, err, "admins cannot be impersonated")
+
+	_, err = adminService.Impersonate(admin.ID, uuid.New(), "ticket #42", client)
+	assert.EqualError(t, err, "user not found")
+}
+
+func TestAdminService_ImportStaff(t *testing.T) {
+	adminService, _, admin, user, db, broker := setupAdminService(t)
+	client := model.ClientInfo{IPAddress: "198.51.100.1"}
+
+	file := "email,first_name,last_name,phone,role\n" +
+		"Sari@Example.com,Sari,Wulandari,,organizer\n" +
+		user.Email + ",Budi,Santoso,08123456789,user\n"
+
+	// A dry run writes nothing
+	result, err := adminService.ImportStaff(admin.ID, strings.NewReader(file), model.ImportFormatCSV, true, client)
+	require.NoError(t, err)
+	assert.False(t, result.Applied)
+	assert.Equal(t, 1, result.Created)
+	assert.Equal(t, 1, result.Existing)
+
+	var users int64
+	require.NoError(t, db.Model(&model.User{}).Count(&users).Error)
+	assert.Equal(t, int64(2), users)
+
+	// Invalid rows reject the whole import
+	invalid := file + "not-an-email,,Kurniawan,,owner\nsari@example.com,Sari,Wulandari,,organizer\n"
+	result, err = adminService.ImportStaff(admin.ID, strings.NewReader(invalid), model.ImportFormatCSV, false, client)
+	require.NoError(t, err)
+	require.Len(t, result.Errors, 2)
+	assert.Equal(t, 4, result.Errors[0].Row)
+	assert.Len(t, result.Errors[0].Errors, 3)
+	assert.Equal(t, []string{"email sari@example.com is already used in row 2"}, result.Errors[1].Errors)
+	assert.False(t, result.Applied)
+
+	// New staff are invited, existing users are left as they are
+	result, err = adminService.ImportStaff(admin.ID, strings.NewReader(file), model.ImportFormatCSV, false, client)
+	require.NoError(t, err)
+	assert.True(t, result.Applied)
+	require.Len(t, result.Rows, 2)
+	assert.Equal(t, model.ImportActionCreate, result.Rows[0].Action)
+	assert.NotEqual(t, uuid.Nil, result.Rows[0].UserID)
+	assert.Equal(t, model.ImportActionExisting, result.Rows[1].Action)
+	assert.Equal(t, user.ID, result.Rows[1].UserID)
+
+	var staff model.User
+	require.NoError(t, db.First(&staff, "email = ?", "sari@example.com").Error)
+	assert.Equal(t, "organizer", staff.Role)
+
+	// The invitation is published after the import, never through the outbox
+	published := broker.Published()
+	require.Len(t, published, 1)
+	assert.Equal(t, accountEmailsExchange, published[0].Exchange)
+	assert.Equal(t, contracts.TypeUserInvited, published[0].RoutingKey)
+	envelope, err := contracts.Parse(published[0].Body)
+	require.NoError(t, err)
+	var invited contracts.UserInvited
+	require.NoError(t, envelope.Decode(&invited))
+	assert.Equal(t, staff.ID, invited.UserID)
+	assert.Contains(t, invited.SetupURL, "/reset-password?token=")
+
+	var stored int64
+	require.NoError(t, db.Model(&messaging.OutboxMessage{}).Where("routing_key = ?", contracts.TypeUserInvited).Count(&stored).Error)
+	assert.Zero(t, stored)
+
+	// Running the import again changes nothing
+	result, err = adminService.ImportStaff(admin.ID, strings.NewReader(file), model.ImportFormatCSV, false, client)
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Created)
+	assert.Equal(t, 2, result.Existing)
+}
@@ -0,0 +1,325 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/contracts"
+	"github.com/yourusername/ticket-system/user-service/middleware"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"github.com/yourusername/ticket-system/user-service/repository"
+	"gorm.io/gorm"
+)
+
+const (
+	// apiKeyBytes is the number of random bytes in an API key
+	apiKeyBytes = 32
+	// apiKeyPrefix starts every API key, so that leaked keys are easy to find
+	apiKeyPrefix = "tk_"
+	// apiKeyVisiblePrefixLength is the length of the start of a key that is
+	// stored in clear to tell keys apart
+	apiKeyVisiblePrefixLength = len(apiKeyPrefix) + 8
+	// defaultAPIKeyRateLimit is the number of requests per minute allowed for
+	// a key created without a rate limit
+	defaultAPIKeyRateLimit = 60
+	// apiKeyRotationGrace is how long a rotated key keeps working, so that
+	// partners can deploy the new key
+	apiKeyRotationGrace = 24 * time.Hour
+)
+
+// API key errors
+var (
+	errAPIKeyNotFound     = errors.New("API key not found")
+	errInvalidAPIKeyScope = errors.New("invalid API key scope")
+	errInvalidAPIKey      = errors.New("invalid or expired API key")
+	errAPIKeyExpiryInPast = errors.New("expiry must be in the future")
+)
+
+// APIKeyService defines the interface for managing the API keys of
+// organizations and exchanging them for access tokens
+type APIKeyService interface {
+	CreateAPIKey(userID, organizationID uuid.UUID, req model.CreateAPIKeyRequest) (*model.CreatedAPIKeyResponse, error)
+	GetAPIKeys(userID, organizationID uuid.UUID) ([]model.APIKeyResponse, error)
+	RotateAPIKey(userID, organizationID, keyID uuid.UUID) (*model.CreatedAPIKeyResponse, error)
+	RevokeAPIKey(userID, organizationID, keyID uuid.UUID) error
+	IssueToken(key string) (*model.APIKeyTokenResponse, error)
+}
+
+// apiKeyService implements APIKeyService interface
+type apiKeyService struct {
+	apiKeyRepo       repository.APIKeyRepository
+	organizationRepo repository.OrganizationRepository
+	keyService       KeyService
+	db               *gorm.DB
+}
+
+// NewAPIKeyService creates a new API key service
+func NewAPIKeyService(apiKeyRepo repository.APIKeyRepository, organizationRepo repository.OrganizationRepository, keyService KeyService, db *gorm.DB) APIKeyService {
+	return &apiKeyService{
+		apiKeyRepo:       apiKeyRepo,
+		organizationRepo: organizationRepo,
+		keyService:       keyService,
+		db:               db,
+	}
+}
+
+// CreateAPIKey creates an API key for an organization and returns it with
+// its secret. Only owners and managers can manage API keys.
+func (s *apiKeyService) CreateAPIKey(userID, organizationID uuid.UUID, req model.CreateAPIKeyRequest) (*model.CreatedAPIKeyResponse, error) {
+	if err := s.authorize(userID, organizationID); err != nil {
+		return nil, err
+	}
+
+	scopes, err := normalizeScopes(req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	rateLimit := req.RateLimit
+	if rateLimit == 0 {
+		rateLimit = defaultAPIKeyRateLimit
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, errAPIKeyExpiryInPast
+	}
+
+	apiKey := &model.APIKey{
+		OrganizationID: organizationID,
+		Name:           req.Name,
+		Scopes:         strings.Join(scopes, " "),
+		RateLimit:      rateLimit,
+		CreatedBy:      userID,
+		ExpiresAt:      req.ExpiresAt,
+	}
+	secret, err := issueAPIKey(s.apiKeyRepo, apiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"organization_id": organizationID,
+		"api_key_id":      apiKey.ID,
+		"user_id":         userID,
+	}).Info("API key created")
+
+	return &model.CreatedAPIKeyResponse{APIKeyResponse: apiKey.ToResponse(), Key: secret}, nil
+}
+
+// GetAPIKeys returns the API keys of an organization, without their secrets
+func (s *apiKeyService) GetAPIKeys(userID, organizationID uuid.UUID) ([]model.APIKeyResponse, error) {
+	if err := s.authorize(userID, organizationID); err != nil {
+		return nil, err
+	}
+
+	keys, err := s.apiKeyRepo.FindByOrganizationID(organizationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find API keys: %w", err)
+	}
+
+	responses := make([]model.APIKeyResponse, len(keys))
+	for i := range keys {
+		responses[i] = keys[i].ToResponse()
+	}
+	return responses, nil
+}
+
+// RotateAPIKey replaces an API key by a new key with the same name, scopes,
+// rate limit and expiry. The old key keeps working for a grace period.
+func (s *apiKeyService) RotateAPIKey(userID, organizationID, keyID uuid.UUID) (*model.CreatedAPIKeyResponse, error) {
+	if err := s.authorize(userID, organizationID); err != nil {
+		return nil, err
+	}
+
+	old, err := s.findKey(organizationID, keyID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if !old.Active(now) {
+		return nil, errInvalidAPIKey
+	}
+
+	apiKey := &model.APIKey{
+		OrganizationID: organizationID,
+		Name:           old.Name,
+		Scopes:         old.Scopes,
+		RateLimit:      old.RateLimit,
+		CreatedBy:      userID,
+		ExpiresAt:      old.ExpiresAt,
+	}
+
+	// Save the new key and shorten the old one together
+	var secret string
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		txAPIKeyRepo := s.apiKeyRepo.WithTx(tx)
+
+		secret, err = issueAPIKey(txAPIKeyRepo, apiKey)
+		if err != nil {
+			return err
+		}
+
+		graceEnd := now.Add(apiKeyRotationGrace)
+		if old.ExpiresAt == nil || graceEnd.Before(*old.ExpiresAt) {
+			old.ExpiresAt = &graceEnd
+			if err := txAPIKeyRepo.UpdateExpiry(old); err != nil {
+				return fmt.Errorf("failed to update API key: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"organization_id": organizationID,
+		"api_key_id":      apiKey.ID,
+		"rotated_key_id":  old.ID,
+		"user_id":         userID,
+	}).Info("API key rotated")
+
+	return &model.CreatedAPIKeyResponse{APIKeyResponse: apiKey.ToResponse(), Key: secret}, nil
+}
+
+// RevokeAPIKey stops an API key from being exchanged for access tokens
+func (s *apiKeyService) RevokeAPIKey(userID, organizationID, keyID uuid.UUID) error {
+	if err := s.authorize(userID, organizationID); err != nil {
+		return err
+	}
+
+	apiKey, err := s.findKey(organizationID, keyID)
+	if err != nil {
+		return err
+	}
+	if err := s.apiKeyRepo.Revoke(apiKey); err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"organization_id": organizationID,
+		"api_key_id":      apiKey.ID,
+		"user_id":         userID,
+	}).Info("API key revoked")
+	return nil
+}
+
+// IssueToken exchanges an active API key for a short-lived access token
+// carrying the organization and scopes of the key, and records its use
+func (s *apiKeyService) IssueToken(key string) (*model.APIKeyTokenResponse, error) {
+	apiKey, err := s.apiKeyRepo.FindByHash(hashToken(key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find API key: %w", err)
+	}
+	now := time.Now()
+	if apiKey == nil || !apiKey.Active(now) {
+		return nil, errInvalidAPIKey
+	}
+
+	// The token must not outlive the key
+	ttl := apiKeyTokenTTL()
+	if apiKey.ExpiresAt != nil && apiKey.ExpiresAt.Sub(now) < ttl {
+		ttl = apiKey.ExpiresAt.Sub(now)
+	}
+
+	scopes := apiKey.ScopeList()
+	token, err := s.keyService.Sign(middleware.NewAPIKeyClaims(apiKey.ID, apiKey.OrganizationID, scopes, ttl))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	if err := s.apiKeyRepo.MarkUsed(apiKey.ID, now); err != nil {
+		return nil, fmt.Errorf("failed to mark API key as used: %w", err)
+	}
+
+	return &model.APIKeyTokenResponse{
+		Token:          token,
+		ExpiresIn:      int64(ttl.Seconds()),
+		KeyID:          apiKey.ID,
+		OrganizationID: apiKey.OrganizationID,
+		Scopes:         scopes,
+		RateLimit:      apiKey.RateLimit,
+	}, nil
+}
+
+// authorize checks that the user is an owner or manager of the organization
+func (s *apiKeyService) authorize(userID, organizationID uuid.UUID) error {
+	member, err := s.organizationRepo.FindMember(organizationID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to find organization member: %w", err)
+	}
+	if member == nil {
+		return errOrganizationNotFound
+	}
+	if member.Role != contracts.OrganizationRoleOwner && member.Role != contracts.OrganizationRoleManager {
+		return errOrganizationPermission
+	}
+	return nil
+}
+
+// findKey finds an API key of an organization
+func (s *apiKeyService) findKey(organizationID, keyID uuid.UUID) (*model.APIKey, error) {
+	apiKey, err := s.apiKeyRepo.FindByID(keyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find API key: %w", err)
+	}
+	if apiKey == nil || apiKey.OrganizationID != organizationID {
+		return nil, errAPIKeyNotFound
+	}
+	return apiKey, nil
+}
+
+// issueAPIKey generates the secret of a new API key, saves the key with the
+// hash of the secret and returns the secret
+func issueAPIKey(apiKeyRepo repository.APIKeyRepository, apiKey *model.APIKey) (string, error) {
+	value := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(value); err != nil {
+		return "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	secret := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(value)
+
+	apiKey.Prefix = secret[:apiKeyVisiblePrefixLength]
+	apiKey.KeyHash = hashToken(secret)
+	if err := apiKeyRepo.Create(apiKey); err != nil {
+		return "", fmt.Errorf("failed to create API key: %w", err)
+	}
+	return secret, nil
+}
+
+// normalizeScopes checks that every scope is known and removes duplicates
+func normalizeScopes(scopes []string) ([]string, error) {
+	known := make(map[string]bool)
+	for _, scope := range model.APIKeyScopes() {
+		known[scope] = true
+	}
+
+	seen := make(map[string]bool, len(scopes))
+	normalized := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !known[scope] {
+			return nil, errInvalidAPIKeyScope
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			normalized = append(normalized, scope)
+		}
+	}
+	return normalized, nil
+}
+
+// apiKeyTokenTTL returns how long the access tokens of API keys are valid,
+// from the API_KEY_TOKEN_TTL environment variable or 5 minutes by default.
+// Revoked keys keep working at the API gateway until their token expires.
+func apiKeyTokenTTL() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv("API_KEY_TOKEN_TTL"))
+	if err != nil || ttl <= 0 {
+		return 5 * time.Minute
+	}
+	return ttl
+}
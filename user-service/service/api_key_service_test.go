@@ -0,0 +1,127 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/ticket-system/claims"
+	"github.com/yourusername/ticket-system/contracts"
+	"github.com/yourusername/ticket-system/jwks"
+	"github.com/yourusername/ticket-system/messaging"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"github.com/yourusername/ticket-system/user-service/repository"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupAPIKeyService creates an API key service on an in-memory database
+// with an organization, its owner and a scanner
+func setupAPIKeyService(t *testing.T) (APIKeyService, KeyService, *model.OrganizationResponse, *model.User, *model.User) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	// Every connection opens its own in-memory database
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	userRepo := repository.NewUserRepository(db)
+	owner := &model.User{Email: "owner@example.com", Password: "password123", Role: "organizer", Active: true}
+	require.NoError(t, userRepo.Create(owner))
+	scanner := &model.User{Email: "scanner@example.com", Password: "password123", Role: "user", Active: true}
+	require.NoError(t, userRepo.Create(scanner))
+
+	organizationRepo := repository.NewOrganizationRepository(db)
+	organizationService := NewOrganizationService(organizationRepo, userRepo, messaging.NewOutboxRepository(db), db)
+	organization, err := organizationService.CreateOrganization(owner.ID, model.CreateOrganizationRequest{Name: "Venue Co"})
+	require.NoError(t, err)
+	_, err = organizationService.AddMember(owner.ID, organization.ID, model.AddOrganizationMemberRequest{Email: scanner.Email, Role: contracts.OrganizationRoleScanner})
+	require.NoError(t, err)
+
+	keyService, err := NewKeyService(repository.NewSigningKeyRepository(db), jwks.AlgorithmEdDSA, time.Hour)
+	require.NoError(t, err)
+
+	apiKeyService := NewAPIKeyService(repository.NewAPIKeyRepository(db), organizationRepo, keyService, db)
+	return apiKeyService, keyService, organization, owner, scanner
+}
+
+func TestAPIKeyService_IssueToken(t *testing.T) {
+	apiKeyService, keyService, organization, owner, scanner := setupAPIKeyService(t)
+
+	// Only owners and managers can manage keys, and only with known scopes
+	req := model.CreateAPIKeyRequest{Name: "Gate scanners", Scopes: []string{model.APIKeyScopeTicketsScan, model.APIKeyScopeTicketsScan}}
+	_, err := apiKeyService.CreateAPIKey(scanner.ID, organization.ID, req)
+	assert.EqualError(t, err, "insufficient organization permissions")
+	_, err = apiKeyService.CreateAPIKey(owner.ID, organization.ID, model.CreateAPIKeyRequest{Name: "Admin", Scopes: []string{"users:manage"}})
+	assert.EqualError(t, err, "invalid API key scope")
+
+	apiKey, err := apiKeyService.CreateAPIKey(owner.ID, organization.ID, req)
+	require.NoError(t, err)
+	assert.Equal(t, []string{model.APIKeyScopeTicketsScan}, apiKey.Scopes)
+	assert.Equal(t, 60, apiKey.RateLimit)
+	assert.True(t, len(apiKey.Key) > len(apiKey.Prefix))
+	assert.Equal(t, apiKey.Prefix, apiKey.Key[:len(apiKey.Prefix)])
+
+	// The key is exchanged for a token of the organization with its scopes
+	token, err := apiKeyService.IssueToken(apiKey.Key)
+	require.NoError(t, err)
+	assert.Equal(t, apiKey.ID, token.KeyID)
+
+	tokenClaims := &claims.Claims{}
+	_, err = jwt.ParseWithClaims(token.Token, tokenClaims, keyService.Keyfunc, jwt.WithValidMethods(jwks.Algorithms))
+	require.NoError(t, err)
+	assert.Equal(t, apiKey.ID, tokenClaims.UserID)
+	assert.Equal(t, model.APIKeyRole, tokenClaims.Role)
+	require.NotNil(t, tokenClaims.OrganizationID)
+	assert.Equal(t, organization.ID, *tokenClaims.OrganizationID)
+	assert.Equal(t, []string{model.APIKeyScopeTicketsScan}, tokenClaims.Scopes)
+
+	// The use of the key is recorded, but its secret is never listed
+	keys, err := apiKeyService.GetAPIKeys(owner.ID, organization.ID)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.NotNil(t, keys[0].LastUsedAt)
+
+	_, err = apiKeyService.IssueToken("tk_unknown")
+	assert.EqualError(t, err, "invalid or expired API key")
+}
+
+func TestAPIKeyService_RotateAndRevoke(t *testing.T) {
+	apiKeyService, _, organization, owner, _ := setupAPIKeyService(t)
+
+	old, err := apiKeyService.CreateAPIKey(owner.ID, organization.ID, model.CreateAPIKeyRequest{Name: "Reseller", Scopes: []string{model.APIKeyScopeEventsRead, model.APIKeyScopeBookingsCreate}, RateLimit: 600})
+	require.NoError(t, err)
+
+	// The rotated key keeps working for a grace period next to the new key
+	rotated, err := apiKeyService.RotateAPIKey(owner.ID, organization.ID, old.ID)
+	require.NoError(t, err)
+	assert.NotEqual(t, old.Key, rotated.Key)
+	assert.Equal(t, old.Scopes, rotated.Scopes)
+	assert.Equal(t, 600, rotated.RateLimit)
+
+	_, err = apiKeyService.IssueToken(old.Key)
+	require.NoError(t, err)
+	_, err = apiKeyService.IssueToken(rotated.Key)
+	require.NoError(t, err)
+
+	keys, err := apiKeyService.GetAPIKeys(owner.ID, organization.ID)
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	for _, key := range keys {
+		if key.ID == old.ID {
+			require.NotNil(t, key.ExpiresAt)
+			assert.WithinDuration(t, time.Now().Add(apiKeyRotationGrace), *key.ExpiresAt, time.Minute)
+		}
+	}
+
+	// Revoked keys are rejected at once
+	require.NoError(t, apiKeyService.RevokeAPIKey(owner.ID, organization.ID, rotated.ID))
+	_, err = apiKeyService.IssueToken(rotated.Key)
+	assert.EqualError(t, err, "invalid or expired API key")
+
+	_, err = apiKeyService.RotateAPIKey(owner.ID, organization.ID, rotated.ID)
+	assert.EqualError(t, err, "invalid or expired API key")
+}
@@ -0,0 +1,412 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/contracts"
+	"github.com/yourusername/ticket-system/messaging"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"github.com/yourusername/ticket-system/user-service/oidc"
+	"github.com/yourusername/ticket-system/user-service/repository"
+	"gorm.io/gorm"
+)
+
+// DataRequestService defines the interface for exporting and erasing the
+// personal data of a user across all services
+type DataRequestService interface {
+	CreateRequest(userID, requestedBy uuid.UUID, mode string) (*model.DataRequest, error)
+	GetRequest(id uuid.UUID) (*model.DataRequest, error)
+	GetUserRequests(userID uuid.UUID) ([]model.DataRequest, error)
+	Archive(id uuid.UUID) ([]byte, error)
+	HandlePrivacyEvent(msg []byte) error
+	StartCleanup(ctx context.Context, interval time.Duration)
+}
+
+// dataRequestService implements DataRequestService interface
+type dataRequestService struct {
+	dataRequestRepo  repository.DataRequestRepository
+	userRepo         repository.UserRepository
+	sessionRepo      repository.SessionRepository
+	mfaRepo          repository.MFARepository
+	oidcRepo         repository.OIDCRepository
+	organizationRepo repository.OrganizationRepository
+	outboxRepo       messaging.OutboxRepository
+	sessionService   SessionService
+	loginThrottle    LoginThrottleService
+	db               *gorm.DB
+}
+
+// NewDataRequestService creates a new data request service
+func NewDataRequestService(
+	dataRequestRepo repository.DataRequestRepository,
+	userRepo repository.UserRepository,
+	sessionRepo repository.SessionRepository,
+	mfaRepo repository.MFARepository,
+	oidcRepo repository.OIDCRepository,
+	organizationRepo repository.OrganizationRepository,
+	outboxRepo messaging.OutboxRepository,
+	sessionService SessionService,
+	loginThrottle LoginThrottleService,
+	db *gorm.DB,
+) DataRequestService {
+	return &dataRequestService{
+		dataRequestRepo:  dataRequestRepo,
+		userRepo:         userRepo,
+		sessionRepo:      sessionRepo,
+		mfaRepo:          mfaRepo,
+		oidcRepo:         oidcRepo,
+		organizationRepo: organizationRepo,
+		outboxRepo:       outboxRepo,
+		sessionService:   sessionService,
+		loginThrottle:    loginThrottle,
+		db:               db,
+	}
+}
+
+// CreateRequest starts a data export or erasure of a user, deleted users
+// included. The user service completes its own part right away and asks
+// the other services for theirs with a user.data_requested event.
+func (s *dataRequestService) CreateRequest(userID, requestedBy uuid.UUID, mode string) (*model.DataRequest, error) {
+	if mode != contracts.DataRequestExport && mode != contracts.DataRequestErasure {
+		return nil, errors.New("invalid data request mode")
+	}
+
+	user, err := s.userRepo.FindByIDWithDeleted(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	pending, err := s.dataRequestRepo.FindPendingByUserID(userID, mode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find data requests: %w", err)
+	}
+	if pending != nil {
+		return nil, errors.New("data request is already pending")
+	}
+
+	request := &model.DataRequest{
+		ID:          uuid.New(),
+		UserID:      userID,
+		Mode:        mode,
+		Status:      model.DataRequestPending,
+		RequestedBy: requestedBy,
+	}
+	for _, service := range model.DataRequestServices() {
+		request.Parts = append(request.Parts, model.DataRequestPart{
+			RequestID: request.ID,
+			Service:   service,
+			Status:    model.DataRequestPending,
+		})
+	}
+
+	// Failed logins are counted by email address, which is erased below
+	if mode == contracts.DataRequestErasure {
+		if err := s.loginThrottle.Unlock(user.Email); err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.dataRequestRepo.WithTx(tx).Create(request); err != nil {
+			return fmt.Errorf("failed to create data request: %w", err)
+		}
+
+		// Complete the part of the user service
+		part := model.DataRequestPart{RequestID: request.ID, Service: model.DataRequestServiceUser}
+		if mode == contracts.DataRequestExport {
+			part.Data, err = s.exportUserData(tx, user)
+		} else {
+			part.Erased, err = s.eraseUserData(tx, user)
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.completePart(tx, part); err != nil {
+			return err
+		}
+
+		event := contracts.UserDataRequested{
+			RequestID: request.ID,
+			UserID:    userID,
+			Mode:      mode,
+		}
+		return messaging.EnqueueEvent(s.outboxRepo, tx, "user_events", request.ID.String(), event)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"request_id":   request.ID,
+		"user_id":      userID,
+		"mode":         mode,
+		"requested_by": requestedBy,
+	}).Info("Data request started")
+
+	return s.dataRequestRepo.FindByID(request.ID)
+}
+
+// GetRequest gets a data request with the status of every part
+func (s *dataRequestService) GetRequest(id uuid.UUID) (*model.DataRequest, error) {
+	request, err := s.dataRequestRepo.FindByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find data request: %w", err)
+	}
+	if request == nil {
+		return nil, errors.New("data request not found")
+	}
+	return request, nil
+}
+
+// GetUserRequests gets the data requests of a user, newest first
+func (s *dataRequestService) GetUserRequests(userID uuid.UUID) ([]model.DataRequest, error) {
+	requests, err := s.dataRequestRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find data requests: %w", err)
+	}
+	return requests, nil
+}
+
+// Archive returns a ZIP archive of a completed export, with the data
+// collected by every service in a JSON file of its own
+func (s *dataRequestService) Archive(id uuid.UUID) ([]byte, error) {
+	request, err := s.GetRequest(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Mode != contracts.DataRequestExport {
+		return nil, errors.New("data request is not an export")
+	}
+	switch request.Status {
+	case model.DataRequestPending:
+		return nil, errors.New("data export is not completed")
+	case model.DataRequestExpired:
+		return nil, errors.New("data export has expired")
+	}
+
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+	for _, part := range request.Parts {
+		file, err := archive.Create(part.Service + ".json")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create archive: %w", err)
+		}
+
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, part.Data, "", "  "); err != nil {
+			return nil, fmt.Errorf("failed to read data of %s: %w", part.Service, err)
+		}
+		if _, err := indented.WriteTo(file); err != nil {
+			return nil, fmt.Errorf("failed to create archive: %w", err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("failed to create archive: %w", err)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// HandlePrivacyEvent completes the part of a data request another service
+// answered. Redelivered answers are ignored.
+func (s *dataRequestService) HandlePrivacyEvent(msg []byte) error {
+	envelope, err := contracts.Parse(msg)
+	if err != nil {
+		return fmt.Errorf("failed to parse privacy event: %w", err)
+	}
+
+	var part model.DataRequestPart
+	switch envelope.Type {
+	case contracts.TypePrivacyDataCollected:
+		var event contracts.PrivacyDataCollected
+		if err := envelope.Decode(&event); err != nil {
+			return fmt.Errorf("failed to parse privacy event: %w", err)
+		}
+		part = model.DataRequestPart{RequestID: event.RequestID, Service: event.Service, Data: event.Data}
+	case contracts.TypePrivacyDataErased:
+		var event contracts.PrivacyDataErased
+		if err := envelope.Decode(&event); err != nil {
+			return fmt.Errorf("failed to parse privacy event: %w", err)
+		}
+		part = model.DataRequestPart{RequestID: event.RequestID, Service: event.Service, Erased: event.Erased, Retained: event.Retained}
+	default:
+		logrus.Debugf("Ignoring privacy event type: %s", envelope.Type)
+		return nil
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return s.completePart(tx, part)
+	})
+}
+
+// StartCleanup periodically deletes the data of expired exports until ctx
+// is cancelled
+func (s *dataRequestService) StartCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := s.dataRequestRepo.ExpireBefore(time.Now())
+			if err != nil {
+				logrus.WithError(err).Error("Failed to expire data exports")
+				continue
+			}
+			if expired > 0 {
+				logrus.Infof("Deleted the data of %d expired data exports", expired)
+			}
+		}
+	}
+}
+
+// completePart saves the result of a part of a data request in tx, and
+// completes the request once every service has completed its part
+func (s *dataRequestService) completePart(tx *gorm.DB, part model.DataRequestPart) error {
+	now := time.Now()
+	part.CompletedAt = &now
+
+	txDataRequestRepo := s.dataRequestRepo.WithTx(tx)
+	completed, err := txDataRequestRepo.CompletePart(&part)
+	if err != nil {
+		return fmt.Errorf("failed to complete data request part: %w", err)
+	}
+	if !completed {
+		logrus.WithFields(logrus.Fields{
+			"request_id": part.RequestID,
+			"service":    part.Service,
+		}).Warn("Ignoring answer to unknown or completed data request part")
+		return nil
+	}
+
+	request, err := txDataRequestRepo.FindByID(part.RequestID)
+	if err != nil {
+		return fmt.Errorf("failed to find data request: %w", err)
+	}
+	for _, part := range request.Parts {
+		if part.Status != model.DataRequestCompleted {
+			return nil
+		}
+	}
+
+	request.CompletedAt = &now
+	if request.Mode == contracts.DataRequestExport {
+		expiresAt := now.Add(dataExportTTL())
+		request.ExpiresAt = &expiresAt
+	}
+	if err := txDataRequestRepo.MarkCompleted(request); err != nil {
+		return fmt.Errorf("failed to complete data request: %w", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"request_id": request.ID,
+		"user_id":    request.UserID,
+		"mode":       request.Mode,
+	}).Info("Data request completed")
+	return nil
+}
+
+// exportUserData collects the personal data the user service holds about
+// a user as a JSON document
+func (s *dataRequestService) exportUserData(tx *gorm.DB, user *model.User) ([]byte, error) {
+	export := model.UserDataExport{
+		Profile:    user.ToResponse(),
+		ExportedAt: time.Now(),
+	}
+
+	var err error
+	if export.Sessions, err = s.sessionRepo.WithTx(tx).FindByUserID(user.ID); err != nil {
+		return nil, fmt.Errorf("failed to find sessions: %w", err)
+	}
+	if export.Identities, err = s.oidcRepo.WithTx(tx).FindIdentitiesByUserID(user.ID); err != nil {
+		return nil, fmt.Errorf("failed to find identities: %w", err)
+	}
+	if export.MFA, err = s.mfaRepo.WithTx(tx).FindFactor(user.ID); err != nil {
+		return nil, fmt.Errorf("failed to find MFA factor: %w", err)
+	}
+	if _, export.Organizations, err = s.organizationRepo.WithTx(tx).FindByUserID(user.ID); err != nil {
+		return nil, fmt.Errorf("failed to find organizations: %w", err)
+	}
+
+	return json.Marshal(export)
+}
+
+// eraseUserData anonymises the profile of a user in tx, deleting the user,
+// signs them out everywhere and deletes their sessions' client details,
+// second factor and linked identities. It returns how many records were
+// erased.
+func (s *dataRequestService) eraseUserData(tx *gorm.DB, user *model.User) (int, error) {
+	// Other services clean up after deleted users, so tell them about users
+	// that were not deleted before
+	if !user.DeletedAt.Valid {
+		if _, err := s.sessionService.RevokeAllSessions(tx, user.ID, model.SessionRevokedDeleted); err != nil {
+			return 0, err
+		}
+		event := contracts.UserDeleted{UserID: user.ID}
+		if err := messaging.EnqueueEvent(s.outboxRepo, tx, "user_events", user.ID.String(), event); err != nil {
+			return 0, err
+		}
+	}
+
+	// The email address is unique, so it is replaced rather than cleared,
+	// and the password is replaced with a value no password matches
+	password, err := oidc.RandomValue()
+	if err != nil {
+		return 0, err
+	}
+	user.Email = fmt.Sprintf("erased-%s@erased.invalid", user.ID)
+	user.Password = password
+	if err := s.userRepo.WithTx(tx).Anonymize(user); err != nil {
+		return 0, fmt.Errorf("failed to anonymize user: %w", err)
+	}
+	erased := 1
+
+	sessions, err := s.sessionRepo.WithTx(tx).AnonymizeByUserID(user.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to anonymize sessions: %w", err)
+	}
+	identities, err := s.oidcRepo.WithTx(tx).DeleteIdentitiesByUserID(user.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete identities: %w", err)
+	}
+	erased += int(sessions + identities)
+
+	factor, err := s.mfaRepo.WithTx(tx).FindFactor(user.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find MFA factor: %w", err)
+	}
+	if factor != nil {
+		if err := s.mfaRepo.WithTx(tx).DeleteFactor(user.ID); err != nil {
+			return 0, fmt.Errorf("failed to delete MFA factor: %w", err)
+		}
+		erased++
+	}
+
+	return erased, nil
+}
+
+// dataExportTTL returns how long the data of a completed export can be
+// downloaded, from the DATA_EXPORT_TTL environment variable or 7 days by
+// default
+func dataExportTTL() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv("DATA_EXPORT_TTL"))
+	if err != nil || ttl <= 0 {
+		return 7 * 24 * time.Hour
+	}
+	return ttl
+}
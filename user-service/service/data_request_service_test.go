@@ -0,0 +1,183 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/ticket-system/contracts"
+	"github.com/yourusername/ticket-system/messaging"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"github.com/yourusername/ticket-system/user-service/repository"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupDataRequestService creates a data request service on an in-memory
+// database with one user who has a session
+func setupDataRequestService(t *testing.T) (DataRequestService, *model.User, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	// Every connection opens its own in-memory database
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	userRepo := repository.NewUserRepository(db)
+	user := &model.User{
+		Email:     "subject@example.com",
+		Password:  "password123",
+		FirstName: "Siti",
+		LastName:  "Rahma",
+		Phone:     "08123456789",
+		Role:      "user",
+		Active:    true,
+	}
+	require.NoError(t, userRepo.Create(user))
+
+	sessionRepo := repository.NewSessionRepository(db)
+	require.NoError(t, sessionRepo.Create(&model.Session{
+		UserID:     user.ID,
+		UserAgent:  "Mozilla/5.0",
+		IPAddress:  "203.0.113.7",
+		LastUsedAt: time.Now(),
+		ExpiresAt:  time.Now().Add(time.Hour),
+	}))
+
+	outboxRepo := messaging.NewOutboxRepository(db)
+	dataRequestService := NewDataRequestService(
+		repository.NewDataRequestRepository(db),
+		userRepo,
+		sessionRepo,
+		repository.NewMFARepository(db),
+		repository.NewOIDCRepository(db),
+		repository.NewOrganizationRepository(db),
+		outboxRepo,
+		NewSessionService(sessionRepo, userRepo, nil, db),
+		NewLoginThrottleService(repository.NewMemoryLoginAttemptStore(), outboxRepo),
+		db,
+	)
+	return dataRequestService, user, db
+}
+
+// privacyEvent returns the message a service answers a data request with
+func privacyEvent(t *testing.T, event contracts.Event) []byte {
+	body, err := contracts.Marshal(uuid.New().String(), "", event)
+	require.NoError(t, err)
+	return body
+}
+
+func TestDataRequestService_Export(t *testing.T) {
+	dataRequestService, user, db := setupDataRequestService(t)
+	adminID := uuid.New()
+
+	request, err := dataRequestService.CreateRequest(user.ID, adminID, contracts.DataRequestExport)
+	require.NoError(t, err)
+	assert.Equal(t, model.DataRequestPending, request.Status)
+	require.Len(t, request.Parts, 4)
+
+	// The user service completes its part right away and asks the others
+	var message messaging.OutboxMessage
+	require.NoError(t, db.Where("routing_key = ?", contracts.TypeUserDataRequested).First(&message).Error)
+	envelope, err := contracts.Parse(message.Payload)
+	require.NoError(t, err)
+	var requested contracts.UserDataRequested
+	require.NoError(t, envelope.Decode(&requested))
+	assert.Equal(t, request.ID, requested.RequestID)
+	assert.Equal(t, contracts.DataRequestExport, requested.Mode)
+
+	_, err = dataRequestService.CreateRequest(user.ID, adminID, contracts.DataRequestExport)
+	assert.EqualError(t, err, "data request is already pending")
+
+	_, err = dataRequestService.Archive(request.ID)
+	assert.EqualError(t, err, "data export is not completed")
+
+	// The other services answer, one of them twice
+	services := []string{model.DataRequestServiceEventTicket, model.DataRequestServicePayment, model.DataRequestServiceNotification}
+	for _, service := range services {
+		event := contracts.PrivacyDataCollected{
+			RequestID: request.ID,
+			UserID:    user.ID,
+			Service:   service,
+			Data:      json.RawMessage(`{"service":"` + service + `"}`),
+		}
+		require.NoError(t, dataRequestService.HandlePrivacyEvent(privacyEvent(t, event)))
+	}
+	duplicate := contracts.PrivacyDataCollected{RequestID: request.ID, UserID: user.ID, Service: services[0], Data: json.RawMessage(`{}`)}
+	require.NoError(t, dataRequestService.HandlePrivacyEvent(privacyEvent(t, duplicate)))
+
+	request, err = dataRequestService.GetRequest(request.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.DataRequestCompleted, request.Status)
+	require.NotNil(t, request.ExpiresAt)
+
+	// The archive holds a file per service
+	archive, err := dataRequestService.Archive(request.ID)
+	require.NoError(t, err)
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	require.NoError(t, err)
+
+	files := map[string]string{}
+	for _, file := range reader.File {
+		content, err := file.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(content)
+		require.NoError(t, err)
+		files[file.Name] = string(data)
+	}
+	require.Len(t, files, 4)
+	assert.Contains(t, files["user-service.json"], user.Email)
+	assert.Contains(t, files["user-service.json"], "203.0.113.7")
+	assert.JSONEq(t, `{"service":"event-ticket-service"}`, files["event-ticket-service.json"])
+}
+
+func TestDataRequestService_Erasure(t *testing.T) {
+	dataRequestService, user, db := setupDataRequestService(t)
+
+	request, err := dataRequestService.CreateRequest(user.ID, uuid.New(), contracts.DataRequestErasure)
+	require.NoError(t, err)
+
+	// The profile is anonymised and the user deleted
+	var erased model.User
+	require.NoError(t, db.Unscoped().First(&erased, "id = ?", user.ID).Error)
+	assert.NotEqual(t, user.Email, erased.Email)
+	assert.Empty(t, erased.FirstName)
+	assert.Empty(t, erased.Phone)
+	assert.True(t, erased.DeletedAt.Valid)
+
+	var session model.Session
+	require.NoError(t, db.First(&session, "user_id = ?", user.ID).Error)
+	assert.Empty(t, session.IPAddress)
+	assert.NotNil(t, session.RevokedAt)
+
+	var deletedEvents int64
+	require.NoError(t, db.Model(&messaging.OutboxMessage{}).Where("routing_key = ?", contracts.TypeUserDeleted).Count(&deletedEvents).Error)
+	assert.Equal(t, int64(1), deletedEvents)
+
+	// Erasures have no archive
+	_, err = dataRequestService.Archive(request.ID)
+	assert.EqualError(t, err, "data request is not an export")
+
+	for _, service := range []string{model.DataRequestServiceEventTicket, model.DataRequestServicePayment, model.DataRequestServiceNotification} {
+		event := contracts.PrivacyDataErased{RequestID: request.ID, UserID: user.ID, Service: service, Erased: 1, Retained: 2}
+		require.NoError(t, dataRequestService.HandlePrivacyEvent(privacyEvent(t, event)))
+	}
+
+	request, err = dataRequestService.GetRequest(request.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.DataRequestCompleted, request.Status)
+	assert.Nil(t, request.ExpiresAt)
+
+	// Deleted users can be erased again, without deleting them twice
+	_, err = dataRequestService.CreateRequest(user.ID, uuid.New(), contracts.DataRequestErasure)
+	require.NoError(t, err)
+	require.NoError(t, db.Model(&messaging.OutboxMessage{}).Where("routing_key = ?", contracts.TypeUserDeleted).Count(&deletedEvents).Error)
+	assert.Equal(t, int64(1), deletedEvents)
+}
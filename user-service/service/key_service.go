@@ -0,0 +1,305 @@
+package service
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/jwks"
+	"github.com/yourusername/ticket-system/user-service/middleware"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"github.com/yourusername/ticket-system/user-service/repository"
+)
+
+// Signing key settings
+const (
+	rsaKeyBits = 2048
+
+	// keyReloadInterval is how often the keys are reloaded from the
+	// database, to pick up keys rotated by other instances
+	keyReloadInterval = time.Minute
+
+	// keyMinReloadInterval bounds how often tokens signed with an unknown
+	// key reload the keys
+	keyMinReloadInterval = 10 * time.Second
+
+	// keyRetirementGrace is how long a retired key is published beyond the
+	// lifetime of the tokens it signed. It covers instances that keep
+	// signing with a key retired by another instance until they reload.
+	keyRetirementGrace = 5 * time.Minute
+)
+
+// KeyService defines the interface for signing and verifying access tokens
+type KeyService interface {
+	Sign(claims jwt.Claims) (string, error)
+	Keyfunc(token *jwt.Token) (interface{}, error)
+	JWKS() (jwks.Set, error)
+	StartRotation(ctx context.Context)
+}
+
+// signingKey is a loaded signing key
+type signingKey struct {
+	id         string
+	algorithm  string
+	privateKey crypto.Signer
+	createdAt  time.Time
+	retired    bool
+}
+
+// keyService implements KeyService interface
+type keyService struct {
+	keyRepo          repository.SigningKeyRepository
+	algorithm        string
+	rotationInterval time.Duration
+
+	mu       sync.RWMutex
+	keys     []signingKey // newest first
+	loadedAt time.Time
+}
+
+// NewKeyService creates a key service that signs with algorithm, RS256 or
+// EdDSA, and rotates to a new key every rotationInterval. A key is created
+// when there is none yet.
+func NewKeyService(keyRepo repository.SigningKeyRepository, algorithm string, rotationInterval time.Duration) (KeyService, error) {
+	if algorithm != jwks.AlgorithmRS256 && algorithm != jwks.AlgorithmEdDSA {
+		return nil, fmt.Errorf("unsupported signing algorithm %s", algorithm)
+	}
+
+	s := &keyService{
+		keyRepo:          keyRepo,
+		algorithm:        algorithm,
+		rotationInterval: rotationInterval,
+	}
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	if err := s.rotateIfDue(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Sign signs claims with the current signing key
+func (s *keyService) Sign(claims jwt.Claims) (string, error) {
+	key, err := s.currentKey()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.algorithm), claims)
+	token.Header["kid"] = key.id
+
+	tokenString, err := token.SignedString(key.privateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	return tokenString, nil
+}
+
+// Keyfunc returns the public key a token was signed with, for jwt.Parse
+func (s *keyService) Keyfunc(token *jwt.Token) (interface{}, error) {
+	keyID, _ := token.Header["kid"].(string)
+	key, found := s.findKey(keyID)
+
+	// The key may have been created by another instance since the last load
+	if !found && s.reloadable() {
+		if err := s.load(); err != nil {
+			return nil, err
+		}
+		key, found = s.findKey(keyID)
+	}
+
+	if !found {
+		return nil, fmt.Errorf("%w: %s", jwks.ErrKeyNotFound, keyID)
+	}
+
+	if token.Method.Alg() != key.algorithm {
+		return nil, fmt.Errorf("token is signed with %s, but key %s is a %s key", token.Method.Alg(), keyID, key.algorithm)
+	}
+	return key.privateKey.Public(), nil
+}
+
+// JWKS returns the public keys of all unexpired signing keys
+func (s *keyService) JWKS() (jwks.Set, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	set := jwks.Set{Keys: make([]jwks.JWK, 0, len(s.keys))}
+	for _, key := range s.keys {
+		jwk, err := jwks.NewJWK(key.id, key.algorithm, key.privateKey.Public())
+		if err != nil {
+			return jwks.Set{}, err
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set, nil
+}
+
+// StartRotation periodically reloads the keys and rotates to a new key when
+// the current one is due, until ctx is cancelled
+func (s *keyService) StartRotation(ctx context.Context) {
+	ticker := time.NewTicker(keyReloadInterval)
+	defer ticker.Stop()
+
+	cleanupTicker := time.NewTicker(time.Hour)
+	defer cleanupTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.load(); err != nil {
+				logrus.WithError(err).Error("Failed to reload signing keys")
+				continue
+			}
+			if err := s.rotateIfDue(); err != nil {
+				logrus.WithError(err).Error("Failed to rotate signing key")
+			}
+		case <-cleanupTicker.C:
+			if _, err := s.keyRepo.DeleteExpiredBefore(time.Now()); err != nil {
+				logrus.WithError(err).Error("Failed to delete expired signing keys")
+			}
+		}
+	}
+}
+
+// rotateIfDue creates a new signing key when there is no current key, the
+// current key is older than the rotation interval or uses another algorithm,
+// and retires the others. Instances rotating at the same time may each
+// create a key, all of them are published so that every token they sign can
+// be verified.
+func (s *keyService) rotateIfDue() error {
+	key, err := s.currentKey()
+	if err == nil && key.algorithm == s.algorithm && time.Since(key.createdAt) < s.rotationInterval {
+		return nil
+	}
+
+	privateKey, err := generateKey(s.algorithm)
+	if err != nil {
+		return err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return fmt.Errorf("failed to encode signing key: %w", err)
+	}
+
+	newKey := &model.SigningKey{
+		ID:         uuid.New().String(),
+		Algorithm:  s.algorithm,
+		PrivateKey: der,
+	}
+	if err := s.keyRepo.Create(newKey); err != nil {
+		return fmt.Errorf("failed to create signing key: %w", err)
+	}
+
+	// Publish the retired keys until the tokens they signed have expired
+	now := time.Now()
+	if err := s.keyRepo.RetireAllExcept(newKey.ID, now, now.Add(middleware.AccessTokenTTL()+keyRetirementGrace)); err != nil {
+		return fmt.Errorf("failed to retire signing keys: %w", err)
+	}
+
+	logrus.WithField("kid", newKey.ID).Infof("Rotated to new %s signing key", s.algorithm)
+	return s.load()
+}
+
+// load loads the unexpired keys from the database
+func (s *keyService) load() error {
+	stored, err := s.keyRepo.FindUnexpired()
+	if err != nil {
+		return fmt.Errorf("failed to find signing keys: %w", err)
+	}
+
+	keys := make([]signingKey, 0, len(stored))
+	for _, key := range stored {
+		privateKey, err := x509.ParsePKCS8PrivateKey(key.PrivateKey)
+		if err != nil {
+			return fmt.Errorf("failed to decode signing key %s: %w", key.ID, err)
+		}
+
+		signer, ok := privateKey.(crypto.Signer)
+		if !ok {
+			return fmt.Errorf("signing key %s is not a signer", key.ID)
+		}
+
+		keys = append(keys, signingKey{
+			id:         key.ID,
+			algorithm:  key.Algorithm,
+			privateKey: signer,
+			createdAt:  key.CreatedAt,
+			retired:    key.RetiredAt != nil,
+		})
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+	s.loadedAt = time.Now()
+	return nil
+}
+
+// currentKey returns the newest key that is not retired
+func (s *keyService) currentKey() (signingKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, key := range s.keys {
+		if !key.retired {
+			return key, nil
+		}
+	}
+	return signingKey{}, errors.New("no signing key available")
+}
+
+// findKey returns a loaded key by ID
+func (s *keyService) findKey(id string) (signingKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, key := range s.keys {
+		if key.id == id {
+			return key, true
+		}
+	}
+	return signingKey{}, false
+}
+
+// reloadable reports whether the keys were loaded long enough ago to load
+// them again for a token signed with an unknown key
+func (s *keyService) reloadable() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return time.Since(s.loadedAt) >= keyMinReloadInterval
+}
+
+// generateKey generates a private key for algorithm
+func generateKey(algorithm string) (crypto.Signer, error) {
+	switch algorithm {
+	case jwks.AlgorithmRS256:
+		key, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate RSA key: %w", err)
+		}
+		return key, nil
+	case jwks.AlgorithmEdDSA:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate Ed25519 key: %w", err)
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %s", algorithm)
+	}
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/ticket-system/jwks"
+	"github.com/yourusername/ticket-system/user-service/middleware"
+	"github.com/yourusername/ticket-system/user-service/repository"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupKeyRepository creates a signing key repository on an in-memory database
+func setupKeyRepository(t *testing.T) repository.SigningKeyRepository {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	// Every connection opens its own in-memory database
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	return repository.NewSigningKeyRepository(db)
+}
+
+func TestKeyService_SignAndVerify(t *testing.T) {
+	keyRepo := setupKeyRepository(t)
+	claims := middleware.NewClaims(uuid.New(), uuid.New(), "keys@example.com", "user", true, false)
+
+	for _, algorithm := range jwks.Algorithms {
+		keyService, err := NewKeyService(keyRepo, algorithm, time.Hour)
+		require.NoError(t, err)
+
+		token, err := keyService.Sign(claims)
+		require.NoError(t, err)
+
+		parsed, err := jwt.Parse(token, keyService.Keyfunc, jwt.WithValidMethods(jwks.Algorithms))
+		require.NoError(t, err)
+		assert.Equal(t, algorithm, parsed.Method.Alg())
+	}
+}
+
+func TestKeyService_RotationKeepsRetiredKeysPublished(t *testing.T) {
+	keyRepo := setupKeyRepository(t)
+	claims := middleware.NewClaims(uuid.New(), uuid.New(), "keys@example.com", "user", true, false)
+
+	oldKeys, err := NewKeyService(keyRepo, jwks.AlgorithmEdDSA, time.Hour)
+	require.NoError(t, err)
+	oldToken, err := oldKeys.Sign(claims)
+	require.NoError(t, err)
+
+	// Another instance finds the key due and rotates to a new one
+	newKeys, err := NewKeyService(keyRepo, jwks.AlgorithmEdDSA, 0)
+	require.NoError(t, err)
+	newToken, err := newKeys.Sign(claims)
+	require.NoError(t, err)
+
+	set, err := newKeys.JWKS()
+	require.NoError(t, err)
+	assert.Len(t, set.Keys, 2)
+
+	// Tokens signed with the retired key still verify
+	_, err = jwt.Parse(oldToken, newKeys.Keyfunc, jwt.WithValidMethods(jwks.Algorithms))
+	assert.NoError(t, err)
+	_, err = jwt.Parse(newToken, newKeys.Keyfunc, jwt.WithValidMethods(jwks.Algorithms))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/contracts"
+	"github.com/yourusername/ticket-system/messaging"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"github.com/yourusername/ticket-system/user-service/repository"
+)
+
+const (
+	// loginAttemptWindow is how long failed login attempts are counted for
+	loginAttemptWindow = 15 * time.Minute
+	// loginLockoutDuration is how long logins are locked after too many
+	// failed attempts
+	loginLockoutDuration = 15 * time.Minute
+	// maxLoginDelay is the longest delay between failed login attempts
+	// before a lockout
+	maxLoginDelay = 30 * time.Second
+)
+
+// loginThrottlePolicy decides how failed login attempts of an account or
+// from an IP address are slowed down. The first delayAfter failures are
+// free, every further failure doubles the delay before the next attempt
+// starting at a second, and lockAfter failures lock logins.
+type loginThrottlePolicy struct {
+	prefix     string
+	delayAfter int
+	lockAfter  int
+}
+
+var (
+	// accountLoginPolicy throttles the failed logins of an email address
+	accountLoginPolicy = loginThrottlePolicy{prefix: "email:", delayAfter: 3, lockAfter: 10}
+	// ipLoginPolicy throttles the failed logins from an IP address, which
+	// may be shared by many users
+	ipLoginPolicy = loginThrottlePolicy{prefix: "ip:", delayAfter: 20, lockAfter: 100}
+)
+
+// block returns how long logins are blocked after a number of failures, and
+// whether the block is a lockout
+func (p loginThrottlePolicy) block(failures int) (time.Duration, bool) {
+	if failures >= p.lockAfter {
+		return loginLockoutDuration, true
+	}
+	if failures <= p.delayAfter {
+		return 0, false
+	}
+
+	delay := time.Second << uint(failures-p.delayAfter-1)
+	if delay > maxLoginDelay || delay <= 0 {
+		delay = maxLoginDelay
+	}
+	return delay, false
+}
+
+// LoginThrottledError is returned when logins are blocked after too many
+// failed attempts
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+// Error implements error
+func (e *LoginThrottledError) Error() string {
+	return "too many failed login attempts"
+}
+
+// LoginThrottleService defines the interface for slowing down and locking
+// password logins after failed attempts per account and per IP address
+type LoginThrottleService interface {
+	Check(email, ipAddress string) error
+	RecordFailure(email, ipAddress string, user *model.User) error
+	RecordSuccess(email string) error
+	Unlock(email string) error
+	StartCleanup(ctx context.Context, interval time.Duration)
+}
+
+// loginThrottleService implements LoginThrottleService interface
+type loginThrottleService struct {
+	store      repository.LoginAttemptStore
+	outboxRepo messaging.OutboxRepository
+}
+
+// NewLoginThrottleService creates a new login throttle service that keeps
+// the failed attempts in store
+func NewLoginThrottleService(store repository.LoginAttemptStore, outboxRepo messaging.OutboxRepository) LoginThrottleService {
+	return &loginThrottleService{
+		store:      store,
+		outboxRepo: outboxRepo,
+	}
+}
+
+// Check returns a LoginThrottledError when logins to the account or from
+// the IP address are blocked
+func (s *loginThrottleService) Check(email, ipAddress string) error {
+	now := time.Now()
+
+	var blockedUntil time.Time
+	for _, key := range loginAttemptKeys(email, ipAddress) {
+		attempt, err := s.store.Find(key)
+		if err != nil {
+			return fmt.Errorf("failed to find login attempts: %w", err)
+		}
+		if until, blocked := attempt.Blocked(now); blocked && until.After(blockedUntil) {
+			blockedUntil = until
+		}
+	}
+
+	if blockedUntil.IsZero() {
+		return nil
+	}
+	return &LoginThrottledError{RetryAfter: blockedUntil.Sub(now)}
+}
+
+// RecordFailure counts a failed login to the account and from the IP
+// address, and blocks further attempts when there were too many. The user
+// of the account, if it exists, is alerted when their account is locked.
+func (s *loginThrottleService) RecordFailure(email, ipAddress string, user *model.User) error {
+	now := time.Now()
+	policies := []loginThrottlePolicy{accountLoginPolicy, ipLoginPolicy}
+
+	for i, key := range loginAttemptKeys(email, ipAddress) {
+		attempt, err := s.store.RecordFailure(key, now, loginAttemptWindow)
+		if err != nil {
+			return fmt.Errorf("failed to record login attempt: %w", err)
+		}
+
+		delay, lockout := policies[i].block(attempt.Failures)
+		if delay == 0 {
+			continue
+		}
+		lockedUntil := now.Add(delay)
+		if err := s.store.Lock(key, lockedUntil); err != nil {
+			return fmt.Errorf("failed to lock login attempts: %w", err)
+		}
+
+		// Alert the user once, when their account gets locked
+		if !lockout || attempt.Failures != policies[i].lockAfter {
+			continue
+		}
+		logrus.WithFields(logrus.Fields{
+			"key":          key,
+			"failures":     attempt.Failures,
+			"locked_until": lockedUntil,
+		}).Warn("Logins locked after too many failed attempts")
+
+		if policies[i] == accountLoginPolicy && user != nil {
+			event := contracts.UserLocked{
+				UserState:      contracts.UserState{UserID: user.ID, Email: user.Email},
+				FailedAttempts: attempt.Failures,
+				IPAddress:      ipAddress,
+				LockedUntil:    lockedUntil,
+			}
+			// The lock lives in the attempt store, which may be kept in
+			// memory, so there is no transaction to share with it. The alert
+			// is written on its own right after the lock; the lock does not
+			// depend on it, and only the failure that reaches the lockout
+			// writes it, so it is never sent twice.
+			if err := messaging.EnqueueEvent(s.outboxRepo, nil, "user_events", user.ID.String(), event); err != nil {
+				logrus.WithError(err).Error("Failed to publish user locked event")
+			}
+		}
+	}
+	return nil
+}
+
+// RecordSuccess forgets the failed logins to an account after a successful
+// one. Failures from the IP address keep counting, so that an attacker
+// cannot reset them with an account of their own.
+func (s *loginThrottleService) RecordSuccess(email string) error {
+	if err := s.store.Delete(accountLoginPolicy.prefix + normalizeLoginEmail(email)); err != nil {
+		return fmt.Errorf("failed to delete login attempts: %w", err)
+	}
+	return nil
+}
+
+// Unlock lifts the lockout of an account and forgets its failed logins
+func (s *loginThrottleService) Unlock(email string) error {
+	if err := s.RecordSuccess(email); err != nil {
+		return err
+	}
+
+	logrus.WithField("email", email).Info("Account logins unlocked")
+	return nil
+}
+
+// StartCleanup periodically deletes the failed login attempts that are not
+// counted anymore until ctx is cancelled
+func (s *loginThrottleService) StartCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := s.store.DeleteExpiredBefore(time.Now().Add(-loginAttemptWindow))
+			if err != nil {
+				logrus.WithError(err).Error("Failed to delete expired login attempts")
+				continue
+			}
+			if deleted > 0 {
+				logrus.Infof("Deleted %d expired login attempts", deleted)
+			}
+		}
+	}
+}
+
+// loginAttemptKeys returns the keys the failed logins to an account and
+// from an IP address are counted under, in the order of their policies
+func loginAttemptKeys(email, ipAddress string) []string {
+	return []string{
+		accountLoginPolicy.prefix + normalizeLoginEmail(email),
+		ipLoginPolicy.prefix + ipAddress,
+	}
+}
+
+// normalizeLoginEmail returns the email address failed logins are counted
+// under, so that case variants share a counter
+func normalizeLoginEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/ticket-system/contracts"
+	"github.com/yourusername/ticket-system/messaging"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"github.com/yourusername/ticket-system/user-service/repository"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupLoginThrottle creates a login throttle service on an in-memory
+// database, keeping the failed attempts in the database or in memory
+func setupLoginThrottle(t *testing.T, inMemory bool) (LoginThrottleService, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	// Every connection opens its own in-memory database
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	store := repository.NewMemoryLoginAttemptStore()
+	if !inMemory {
+		store = repository.NewPostgresLoginAttemptStore(db)
+	}
+	return NewLoginThrottleService(store, messaging.NewOutboxRepository(db)), db
+}
+
+// forEachLoginAttemptStore runs a test against both login attempt stores
+func forEachLoginAttemptStore(t *testing.T, test func(t *testing.T, throttle LoginThrottleService, db *gorm.DB)) {
+	for name, inMemory := range map[string]bool{"database": false, "memory": true} {
+		t.Run(name, func(t *testing.T) {
+			throttle, db := setupLoginThrottle(t, inMemory)
+			test(t, throttle, db)
+		})
+	}
+}
+
+func TestLoginThrottleService_DelaysAfterFailures(t *testing.T) {
+	forEachLoginAttemptStore(t, func(t *testing.T, throttle LoginThrottleService, db *gorm.DB) {
+		for i := 0; i < accountLoginPolicy.delayAfter; i++ {
+			require.NoError(t, throttle.RecordFailure("throttle@example.com", "10.0.0.1", nil))
+			assert.NoError(t, throttle.Check("throttle@example.com", "10.0.0.1"))
+		}
+
+		require.NoError(t, throttle.RecordFailure("throttle@example.com", "10.0.0.1", nil))
+		err := throttle.Check("THROTTLE@example.com ", "10.0.0.2")
+		var throttled *LoginThrottledError
+		require.ErrorAs(t, err, &throttled)
+		assert.InDelta(t, time.Second, throttled.RetryAfter, float64(100*time.Millisecond))
+
+		// Other accounts from the same IP address are not slowed down yet
+		assert.NoError(t, throttle.Check("other@example.com", "10.0.0.1"))
+	})
+}
+
+func TestLoginThrottleService_LocksAccount(t *testing.T) {
+	forEachLoginAttemptStore(t, func(t *testing.T, throttle LoginThrottleService, db *gorm.DB) {
+		user := &model.User{Email: "locked@example.com"}
+		for i := 0; i < accountLoginPolicy.lockAfter; i++ {
+			require.NoError(t, throttle.RecordFailure(user.Email, "10.0.0.1", user))
+		}
+
+		err := throttle.Check(user.Email, "10.0.0.2")
+		var throttled *LoginThrottledError
+		require.ErrorAs(t, err, &throttled)
+		assert.InDelta(t, loginLockoutDuration, throttled.RetryAfter, float64(time.Second))
+
+		// The user is alerted once about the lockout
+		require.NoError(t, throttle.RecordFailure(user.Email, "10.0.0.1", user))
+		var messages []messaging.OutboxMessage
+		require.NoError(t, db.Where("routing_key = ?", contracts.TypeUserLocked).Find(&messages).Error)
+		require.Len(t, messages, 1)
+
+		envelope, err := contracts.Parse(messages[0].Payload)
+		require.NoError(t, err)
+		var event contracts.UserLocked
+		require.NoError(t, envelope.Decode(&event))
+		assert.Equal(t, user.Email, event.Email)
+		assert.Equal(t, accountLoginPolicy.lockAfter, event.FailedAttempts)
+		assert.Equal(t, "10.0.0.1", event.IPAddress)
+	})
+}
+
+func TestLoginThrottleService_UnlockKeepsIPFailures(t *testing.T) {
+	forEachLoginAttemptStore(t, func(t *testing.T, throttle LoginThrottleService, db *gorm.DB) {
+		for i := 0; i < ipLoginPolicy.delayAfter; i++ {
+			require.NoError(t, throttle.RecordFailure("unlock@example.com", "10.0.0.1", nil))
+		}
+		require.Error(t, throttle.Check("unlock@example.com", "10.0.0.2"))
+
+		require.NoError(t, throttle.Unlock("unlock@example.com"))
+		assert.NoError(t, throttle.Check("unlock@example.com", "10.0.0.2"))
+
+		// The next failure from the IP address is slowed down
+		require.NoError(t, throttle.RecordFailure("unlock@example.com", "10.0.0.1", nil))
+		assert.NoError(t, throttle.Check("unlock@example.com", "10.0.0.2"))
+		assert.Error(t, throttle.Check("unlock@example.com", "10.0.0.1"))
+	})
+}
@@ -0,0 +1,580 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image/png"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/user-service/middleware"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"github.com/yourusername/ticket-system/user-service/repository"
+	"gorm.io/gorm"
+)
+
+// Two-factor authentication settings
+const (
+	totpPeriod           = 30
+	totpSkew             = 1 // Codes of the previous and the next time step are accepted as well
+	totpQRCodeSize       = 256
+	defaultMFAIssuer     = "Ticket System"
+	recoveryCodeCount    = 10
+	recoveryCodeBytes    = 5
+	mfaChallengeTTL      = 5 * time.Minute
+	maxMFAChallengeTries = 5
+)
+
+// Two-factor authentication errors
+var (
+	errInvalidMFACode      = errors.New("invalid code")
+	errInvalidMFAChallenge = errors.New("invalid or expired MFA token")
+	errMFANotEnabled       = errors.New("two-factor authentication is not enabled")
+)
+
+// MFAService defines the interface for two-factor authentication operations
+type MFAService interface {
+	Status(userID uuid.UUID) (*model.MFAStatusResponse, error)
+	Enroll(userID uuid.UUID) (*model.MFAEnrollmentResponse, error)
+	Confirm(userID, sessionID uuid.UUID, code string) ([]string, *model.TokenResponse, error)
+	RegenerateRecoveryCodes(userID uuid.UUID, code string) ([]string, error)
+	Disable(userID uuid.UUID, req model.DisableMFARequest) error
+	Reset(userID uuid.UUID) error
+	Enabled(userID uuid.UUID) (bool, error)
+	Required(role string) bool
+	StartChallenge(userID uuid.UUID) (string, time.Time, error)
+	RedeemChallenge(token, code string) (*model.User, error)
+	StartCleanup(ctx context.Context, interval time.Duration)
+}
+
+// mfaService implements MFAService interface
+type mfaService struct {
+	userRepo       repository.UserRepository
+	mfaRepo        repository.MFARepository
+	sessionService SessionService
+	db             *gorm.DB
+	encryptionKey  []byte
+	requiredRoles  map[string]bool
+}
+
+// NewMFAService creates a new MFA service. TOTP secrets are encrypted with a
+// key derived from encryptionKey.
+func NewMFAService(userRepo repository.UserRepository, mfaRepo repository.MFARepository, sessionService SessionService, db *gorm.DB, encryptionKey string) MFAService {
+	key := sha256.Sum256([]byte(encryptionKey))
+	return &mfaService{
+		userRepo:       userRepo,
+		mfaRepo:        mfaRepo,
+		sessionService: sessionService,
+		db:             db,
+		encryptionKey:  key[:],
+		requiredRoles:  middleware.MFARequiredRoles(),
+	}
+}
+
+// Status returns the two-factor authentication status of a user
+func (s *mfaService) Status(userID uuid.UUID) (*model.MFAStatusResponse, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	factor, err := s.mfaRepo.FindFactor(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find MFA factor: %w", err)
+	}
+
+	status := &model.MFAStatusResponse{
+		Enabled:  factor.Enabled(),
+		Required: s.Required(user.Role),
+	}
+	if factor.Enabled() {
+		status.ConfirmedAt = factor.ConfirmedAt
+		status.RecoveryCodesLeft, err = s.mfaRepo.CountRecoveryCodes(userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count recovery codes: %w", err)
+		}
+	}
+	return status, nil
+}
+
+// Enroll generates a new TOTP secret for a user. It replaces any earlier
+// secret that was not confirmed, and only protects logins once Confirm has
+// been called with a code of an authenticator app it was added to.
+func (s *mfaService) Enroll(userID uuid.UUID) (*model.MFAEnrollmentResponse, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	factor, err := s.mfaRepo.FindFactor(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find MFA factor: %w", err)
+	}
+
+	if factor.Enabled() {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	// Generate secret
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      mfaIssuer(),
+		AccountName: user.Email,
+		Period:      totpPeriod,
+		Digits:      otp.DigitsSix,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate TOTP secret: %w", err)
+	}
+
+	qrCode, err := qrCodeDataURI(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Save encrypted secret to database
+	secret, err := s.seal(key.Secret())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mfaRepo.SaveFactor(&model.MFAFactor{UserID: userID, Secret: secret}); err != nil {
+		return nil, fmt.Errorf("failed to save MFA factor: %w", err)
+	}
+
+	return &model.MFAEnrollmentResponse{
+		Secret:     key.Secret(),
+		OTPAuthURI: key.URL(),
+		QRCode:     qrCode,
+	}, nil
+}
+
+// Confirm enables the enrolled TOTP factor of a user with a first code and
+// returns the recovery codes of the user. The current session counts as
+// signed in with a second factor from then on, so a new access token is
+// returned as well.
+func (s *mfaService) Confirm(userID, sessionID uuid.UUID, code string) ([]string, *model.TokenResponse, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	factor, err := s.mfaRepo.FindFactor(userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find MFA factor: %w", err)
+	}
+
+	if factor == nil {
+		return nil, nil, errors.New("two-factor authentication is not enrolled")
+	}
+	if factor.Enabled() {
+		return nil, nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	if err := s.checkTOTP(factor, code); err != nil {
+		return nil, nil, err
+	}
+
+	// Enable factor and save it with its recovery codes to database
+	now := time.Now()
+	factor.ConfirmedAt = &now
+	var recoveryCodes []string
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		txMFARepo := s.mfaRepo.WithTx(tx)
+		if err := txMFARepo.SaveFactor(factor); err != nil {
+			return fmt.Errorf("failed to save MFA factor: %w", err)
+		}
+
+		var err error
+		recoveryCodes, err = issueRecoveryCodes(txMFARepo, userID)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	logrus.WithField("user_id", userID).Info("Two-factor authentication enabled")
+
+	tokens, err := s.sessionService.ElevateSession(user, sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return recoveryCodes, tokens, nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of a user, after
+// checking a TOTP code
+func (s *mfaService) RegenerateRecoveryCodes(userID uuid.UUID, code string) ([]string, error) {
+	factor, err := s.enabledFactor(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkTOTP(factor, code); err != nil {
+		return nil, err
+	}
+
+	return issueRecoveryCodes(s.mfaRepo, userID)
+}
+
+// Disable turns off two-factor authentication for a user whose role does not
+// require it, after checking their password and a TOTP or recovery code
+func (s *mfaService) Disable(userID uuid.UUID, req model.DisableMFARequest) error {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if !user.CheckPassword(req.Password) {
+		return errors.New("password is incorrect")
+	}
+
+	if s.Required(user.Role) {
+		return errors.New("two-factor authentication is required for your role")
+	}
+
+	factor, err := s.enabledFactor(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkCode(factor, req.Code); err != nil {
+		return err
+	}
+
+	if err := s.mfaRepo.DeleteFactor(userID); err != nil {
+		return fmt.Errorf("failed to delete MFA factor: %w", err)
+	}
+
+	logrus.WithField("user_id", userID).Info("Two-factor authentication disabled")
+	return nil
+}
+
+// Reset removes the TOTP factor and recovery codes of a user who has lost
+// access to both, so that they can enroll again. All sessions of the user are
+// revoked, the account may have been taken over.
+func (s *mfaService) Reset(userID uuid.UUID) error {
+	if _, err := s.findUser(userID); err != nil {
+		return err
+	}
+
+	factor, err := s.mfaRepo.FindFactor(userID)
+	if err != nil {
+		return fmt.Errorf("failed to find MFA factor: %w", err)
+	}
+
+	if factor == nil {
+		return errMFANotEnabled
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.mfaRepo.WithTx(tx).DeleteFactor(userID); err != nil {
+			return fmt.Errorf("failed to delete MFA factor: %w", err)
+		}
+
+		// Sign user out everywhere
+		_, err := s.sessionService.RevokeAllSessions(tx, userID, model.SessionRevokedMFAReset)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	logrus.WithField("user_id", userID).Warn("Two-factor authentication reset by admin")
+	return nil
+}
+
+// Enabled reports whether a user has confirmed a TOTP factor
+func (s *mfaService) Enabled(userID uuid.UUID) (bool, error) {
+	factor, err := s.mfaRepo.FindFactor(userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to find MFA factor: %w", err)
+	}
+	return factor.Enabled(), nil
+}
+
+// Required reports whether users of a role must use two-factor authentication
+func (s *mfaService) Required(role string) bool {
+	return s.requiredRoles[role]
+}
+
+// StartChallenge issues an MFA challenge for a user who has logged in with
+// their password and returns its token and expiry
+func (s *mfaService) StartChallenge(userID uuid.UUID) (string, time.Time, error) {
+	value := make([]byte, userTokenBytes)
+	if _, err := rand.Read(value); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate MFA token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(value)
+
+	challenge := &model.MFAChallenge{
+		UserID:    userID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(mfaChallengeTTL),
+	}
+	if err := s.mfaRepo.CreateChallenge(challenge); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to create MFA challenge: %w", err)
+	}
+	return token, challenge.ExpiresAt, nil
+}
+
+// RedeemChallenge checks a TOTP or recovery code for an MFA challenge and
+// returns the user who may now be signed in. A challenge stops working after
+// too many wrong codes. A wrong code is returned with the user of the
+// challenge, so that it can be counted as a failed login of their account.
+func (s *mfaService) RedeemChallenge(token, code string) (*model.User, error) {
+	challenge, err := s.mfaRepo.FindChallengeByHash(hashToken(token))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find MFA challenge: %w", err)
+	}
+
+	if challenge == nil || challenge.UsedAt != nil || time.Now().After(challenge.ExpiresAt) || challenge.Attempts >= maxMFAChallengeTries {
+		return nil, errInvalidMFAChallenge
+	}
+
+	user, err := s.userRepo.FindByID(challenge.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	if user == nil || !user.Active {
+		return nil, errInvalidMFAChallenge
+	}
+
+	factor, err := s.enabledFactor(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkCode(factor, code); err != nil {
+		if errors.Is(err, errInvalidMFACode) {
+			if err := s.mfaRepo.AddChallengeAttempt(challenge); err != nil {
+				return nil, fmt.Errorf("failed to count MFA challenge attempt: %w", err)
+			}
+			return user, err
+		}
+		return nil, err
+	}
+
+	unused, err := s.mfaRepo.MarkChallengeUsed(challenge)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark MFA challenge as used: %w", err)
+	}
+	if !unused {
+		return nil, errInvalidMFAChallenge
+	}
+
+	return user, nil
+}
+
+// StartCleanup periodically deletes expired MFA challenges until ctx is
+// cancelled
+func (s *mfaService) StartCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := s.mfaRepo.DeleteExpiredChallengesBefore(time.Now())
+			if err != nil {
+				logrus.WithError(err).Error("Failed to delete expired MFA challenges")
+				continue
+			}
+			if deleted > 0 {
+				logrus.Infof("Deleted %d expired MFA challenges", deleted)
+			}
+		}
+	}
+}
+
+// findUser finds a user by ID
+func (s *mfaService) findUser(userID uuid.UUID) (*model.User, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+	return user, nil
+}
+
+// enabledFactor finds the confirmed TOTP factor of a user
+func (s *mfaService) enabledFactor(userID uuid.UUID) (*model.MFAFactor, error) {
+	factor, err := s.mfaRepo.FindFactor(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find MFA factor: %w", err)
+	}
+
+	if !factor.Enabled() {
+		return nil, errMFANotEnabled
+	}
+	return factor, nil
+}
+
+// checkCode accepts either a TOTP code or an unused recovery code of a user
+func (s *mfaService) checkCode(factor *model.MFAFactor, code string) error {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) == int(otp.DigitsSix) {
+		return s.checkTOTP(factor, code)
+	}
+
+	used, err := s.mfaRepo.UseRecoveryCode(factor.UserID, hashRecoveryCode(code))
+	if err != nil {
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	if !used {
+		return errInvalidMFACode
+	}
+
+	logrus.WithField("user_id", factor.UserID).Info("Recovery code used")
+	return nil
+}
+
+// checkTOTP accepts a TOTP code of the current time step or the steps next to
+// it. Each code is accepted only once, and neither are codes older than the
+// last accepted one.
+func (s *mfaService) checkTOTP(factor *model.MFAFactor, code string) error {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	secret, err := s.open(factor.Secret)
+	if err != nil {
+		return err
+	}
+
+	opts := totp.ValidateOpts{
+		Period:    totpPeriod,
+		Digits:    otp.DigitsSix,
+		Algorithm: otp.AlgorithmSHA1,
+	}
+	current := time.Now().Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		expected, err := totp.GenerateCodeCustom(secret, time.Unix(step*totpPeriod, 0), opts)
+		if err != nil {
+			return fmt.Errorf("failed to generate TOTP code: %w", err)
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) != 1 {
+			continue
+		}
+
+		unused, err := s.mfaRepo.UseStep(factor.UserID, step)
+		if err != nil {
+			return fmt.Errorf("failed to use TOTP code: %w", err)
+		}
+		if !unused {
+			return errInvalidMFACode
+		}
+		factor.LastUsedStep = step
+		return nil
+	}
+	return errInvalidMFACode
+}
+
+// seal encrypts a TOTP secret for storage
+func (s *mfaService) seal(secret string) (string, error) {
+	gcm, err := s.cipher()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(secret), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// open decrypts a stored TOTP secret
+func (s *mfaService) open(sealed string) (string, error) {
+	gcm, err := s.cipher()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < gcm.NonceSize() {
+		return "", errors.New("failed to decrypt TOTP secret")
+	}
+	secret, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("failed to decrypt TOTP secret")
+	}
+	return string(secret), nil
+}
+
+// cipher returns the AES-GCM cipher that encrypts TOTP secrets
+func (s *mfaService) cipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(s.encryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// issueRecoveryCodes replaces the recovery codes of a user with new ones and
+// returns their values
+func issueRecoveryCodes(mfaRepo repository.MFARepository, userID uuid.UUID) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	records := make([]model.RecoveryCode, recoveryCodeCount)
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	for i := range codes {
+		value := make([]byte, recoveryCodeBytes)
+		if _, err := rand.Read(value); err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		code := strings.ToLower(encoding.EncodeToString(value))
+		codes[i] = code[:4] + "-" + code[4:]
+		records[i] = model.RecoveryCode{UserID: userID, CodeHash: hashRecoveryCode(code)}
+	}
+
+	if err := mfaRepo.ReplaceRecoveryCodes(userID, records); err != nil {
+		return nil, fmt.Errorf("failed to save recovery codes: %w", err)
+	}
+	return codes, nil
+}
+
+// hashRecoveryCode returns the hash under which a recovery code is stored,
+// ignoring case and dashes
+func hashRecoveryCode(code string) string {
+	return hashToken(strings.ToLower(strings.ReplaceAll(code, "-", "")))
+}
+
+// qrCodeDataURI renders the otpauth URI of a TOTP key as a PNG data URI
+func qrCodeDataURI(key *otp.Key) (string, error) {
+	img, err := key.Image(totpQRCodeSize, totpQRCodeSize)
+	if err != nil {
+		return "", fmt.Errorf("failed to render QR code: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", fmt.Errorf("failed to encode QR code: %w", err)
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// mfaIssuer returns the issuer shown in authenticator apps, from the
+// MFA_ISSUER environment variable or "Ticket System" by default
+func mfaIssuer() string {
+	if issuer := os.Getenv("MFA_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return defaultMFAIssuer
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/ticket-system/jwks"
+	"github.com/yourusername/ticket-system/messaging"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"github.com/yourusername/ticket-system/user-service/repository"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupMFAService creates an MFA service and a user service on an in-memory
+// database with one active organizer, who must use two-factor authentication
+func setupMFAService(t *testing.T) (MFAService, UserService, repository.SessionRepository, *model.User) {
+	t.Setenv("MFA_REQUIRED_ROLES", "admin,organizer")
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	// Every connection opens its own in-memory database
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	userRepo := repository.NewUserRepository(db)
+	user := &model.User{
+		Email:    "organizer@example.com",
+		Password: "password123",
+		Role:     "organizer",
+		Active:   true,
+	}
+	require.NoError(t, userRepo.Create(user))
+
+	keyService, err := NewKeyService(repository.NewSigningKeyRepository(db), jwks.AlgorithmEdDSA, time.Hour)
+	require.NoError(t, err)
+
+	sessionRepo := repository.NewSessionRepository(db)
+	outboxRepo := messaging.NewOutboxRepository(db)
+	sessionService := NewSessionService(sessionRepo, userRepo, keyService, db)
+	mfaService := NewMFAService(userRepo, repository.NewMFARepository(db), sessionService, db, "test-key")
+	userService := NewUserService(userRepo, outboxRepo, sessionService, nil, mfaService, nil, NewLoginThrottleService(repository.NewMemoryLoginAttemptStore(), outboxRepo), db)
+	return mfaService, userService, sessionRepo, user
+}
+
+// enrollMFA enables two-factor authentication for a user in the session of
+// a password login and returns the TOTP secret and recovery codes
+func enrollMFA(t *testing.T, mfaService MFAService, userService UserService, sessionRepo repository.SessionRepository, user *model.User) (string, []string) {
+	login, err := userService.Login(model.LoginRequest{Email: user.Email, Password: "password123"}, model.ClientInfo{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, login.Token)
+	assert.True(t, login.MFAEnrollmentRequired)
+
+	sessions, err := sessionRepo.FindActiveByUserID(user.ID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+
+	enrollment, err := mfaService.Enroll(user.ID)
+	require.NoError(t, err)
+	assert.Contains(t, enrollment.OTPAuthURI, "otpauth://totp/")
+	assert.Contains(t, enrollment.QRCode, "data:image/png;base64,")
+
+	code, err := totp.GenerateCode(enrollment.Secret, time.Now())
+	require.NoError(t, err)
+
+	recoveryCodes, tokens, err := mfaService.Confirm(user.ID, sessions[0].ID, code)
+	require.NoError(t, err)
+	assert.Len(t, recoveryCodes, recoveryCodeCount)
+	assert.NotEmpty(t, tokens.Token)
+
+	// The session counts as signed in with a second factor now
+	session, err := sessionRepo.FindByID(sessions[0].ID)
+	require.NoError(t, err)
+	assert.True(t, session.MFA)
+
+	// TOTP codes cannot be replayed
+	_, err = mfaService.RegenerateRecoveryCodes(user.ID, code)
+	assert.EqualError(t, err, "invalid code")
+
+	return enrollment.Secret, recoveryCodes
+}
+
+func TestMFAService_TwoStepLogin(t *testing.T) {
+	mfaService, userService, sessionRepo, user := setupMFAService(t)
+	_, recoveryCodes := enrollMFA(t, mfaService, userService, sessionRepo, user)
+
+	// The password alone only returns an MFA token
+	login, err := userService.Login(model.LoginRequest{Email: user.Email, Password: "password123"}, model.ClientInfo{})
+	require.NoError(t, err)
+	assert.True(t, login.MFARequired)
+	assert.NotEmpty(t, login.MFAToken)
+	assert.Empty(t, login.Token)
+
+	// A recovery code completes the login
+	completed, err := userService.LoginMFA(model.MFALoginRequest{MFAToken: login.MFAToken, Code: recoveryCodes[0]}, model.ClientInfo{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, completed.Token)
+	assert.False(t, completed.MFAEnrollmentRequired)
+
+	// MFA tokens and recovery codes are single use
+	_, err = userService.LoginMFA(model.MFALoginRequest{MFAToken: login.MFAToken, Code: recoveryCodes[1]}, model.ClientInfo{})
+	assert.EqualError(t, err, "invalid or expired MFA token")
+
+	login, err = userService.Login(model.LoginRequest{Email: user.Email, Password: "password123"}, model.ClientInfo{})
+	require.NoError(t, err)
+	_, err = userService.LoginMFA(model.MFALoginRequest{MFAToken: login.MFAToken, Code: recoveryCodes[0]}, model.ClientInfo{})
+	assert.EqualError(t, err, "invalid code")
+
+	// MFA tokens stop working after too many wrong codes
+	for i := 1; i < maxMFAChallengeTries; i++ {
+		_, err = userService.LoginMFA(model.MFALoginRequest{MFAToken: login.MFAToken, Code: "wrong-code"}, model.ClientInfo{})
+		assert.EqualError(t, err, "invalid code")
+	}
+	_, err = userService.LoginMFA(model.MFALoginRequest{MFAToken: login.MFAToken, Code: recoveryCodes[1]}, model.ClientInfo{})
+	assert.EqualError(t, err, "invalid or expired MFA token")
+}
+
+func TestMFAService_WrongCodesCountAsFailedLogins(t *testing.T) {
+	mfaService, userService, sessionRepo, user := setupMFAService(t)
+	enrollMFA(t, mfaService, userService, sessionRepo, user)
+
+	login, err := userService.Login(model.LoginRequest{Email: user.Email, Password: "password123"}, model.ClientInfo{IPAddress: "203.0.113.7"})
+	require.NoError(t, err)
+	for i := 0; i < accountLoginPolicy.delayAfter+1; i++ {
+		_, err = userService.LoginMFA(model.MFALoginRequest{MFAToken: login.MFAToken, Code: "wrong-code"}, model.ClientInfo{IPAddress: "203.0.113.7"})
+		assert.EqualError(t, err, "invalid code")
+	}
+
+	// The correct password does not start a new challenge with fresh tries
+	_, err = userService.Login(model.LoginRequest{Email: user.Email, Password: "password123"}, model.ClientInfo{IPAddress: "203.0.113.7"})
+	var throttled *LoginThrottledError
+	assert.ErrorAs(t, err, &throttled)
+}
+
+func TestMFAService_Reset(t *testing.T) {
+	mfaService, userService, sessionRepo, user := setupMFAService(t)
+	_, recoveryCodes := enrollMFA(t, mfaService, userService, sessionRepo, user)
+
+	// Organizers cannot turn two-factor authentication off themselves
+	err := mfaService.Disable(user.ID, model.DisableMFARequest{Password: "password123", Code: recoveryCodes[0]})
+	assert.EqualError(t, err, "two-factor authentication is required for your role")
+
+	require.NoError(t, mfaService.Reset(user.ID))
+
+	status, err := mfaService.Status(user.ID)
+	require.NoError(t, err)
+	assert.False(t, status.Enabled)
+	assert.True(t, status.Required)
+
+	// Resetting signs the user out everywhere
+	sessions, err := sessionRepo.FindActiveByUserID(user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+
+	assert.EqualError(t, mfaService.Reset(user.ID), "two-factor authentication is not enabled")
+}
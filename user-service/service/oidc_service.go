@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/contracts"
+	"github.com/yourusername/ticket-system/messaging"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"github.com/yourusername/ticket-system/user-service/oidc"
+	"github.com/yourusername/ticket-system/user-service/repository"
+	"gorm.io/gorm"
+)
+
+// oidcLoginTTL is how long a user has to sign in at the identity provider
+const oidcLoginTTL = 10 * time.Minute
+
+// OIDC login errors
+var (
+	errUnknownProvider      = errors.New("unknown identity provider")
+	errInvalidOIDCState     = errors.New("invalid or expired login state")
+	errOIDCLoginFailed      = errors.New("identity provider login failed")
+	errOIDCEmailNotVerified = errors.New("email address is not verified by the identity provider")
+)
+
+// OIDCService defines the interface for signing in with OpenID Connect
+// identity providers
+type OIDCService interface {
+	Providers() []string
+	StartLogin(provider string) (string, error)
+	Authenticate(provider string, req model.OIDCCallbackRequest) (*model.User, error)
+	StartCleanup(ctx context.Context, interval time.Duration)
+}
+
+// oidcService implements OIDCService interface
+type oidcService struct {
+	providers  map[string]*oidc.Provider
+	userRepo   repository.UserRepository
+	oidcRepo   repository.OIDCRepository
+	outboxRepo messaging.OutboxRepository
+	db         *gorm.DB
+}
+
+// NewOIDCService creates a new OIDC service for the given providers
+func NewOIDCService(providers []*oidc.Provider, userRepo repository.UserRepository, oidcRepo repository.OIDCRepository, outboxRepo messaging.OutboxRepository, db *gorm.DB) OIDCService {
+	byName := make(map[string]*oidc.Provider, len(providers))
+	for _, provider := range providers {
+		byName[provider.Name()] = provider
+	}
+
+	return &oidcService{
+		providers:  byName,
+		userRepo:   userRepo,
+		oidcRepo:   oidcRepo,
+		outboxRepo: outboxRepo,
+		db:         db,
+	}
+}
+
+// Providers returns the names of the configured identity providers
+func (s *oidcService) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// StartLogin starts a login at an identity provider and returns the URL to
+// send the user to
+func (s *oidcService) StartLogin(providerName string) (string, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", errUnknownProvider
+	}
+
+	// Generate state, nonce and PKCE code verifier of the login
+	var values [3]string
+	for i := range values {
+		value, err := oidc.RandomValue()
+		if err != nil {
+			return "", err
+		}
+		values[i] = value
+	}
+	state, nonce, codeVerifier := values[0], values[1], values[2]
+
+	authURL, err := provider.AuthCodeURL(context.Background(), state, nonce, oidc.CodeChallenge(codeVerifier))
+	if err != nil {
+		logrus.WithError(err).WithField("provider", providerName).Error("Failed to discover identity provider")
+		return "", errOIDCLoginFailed
+	}
+
+	loginState := &model.OIDCLoginState{
+		Provider:     providerName,
+		StateHash:    hashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    time.Now().Add(oidcLoginTTL),
+	}
+	if err := s.oidcRepo.CreateState(loginState); err != nil {
+		return "", fmt.Errorf("failed to create login state: %w", err)
+	}
+
+	return authURL, nil
+}
+
+// Authenticate completes a login at an identity provider and returns the
+// user it signs in. Identities that are not linked yet are linked to the
+// user with the same email address when the provider has verified it, and a
+// new user is created when there is none.
+func (s *oidcService) Authenticate(providerName string, req model.OIDCCallbackRequest) (*model.User, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, errUnknownProvider
+	}
+
+	// Find and use the login state of the callback
+	loginState, err := s.oidcRepo.FindStateByHash(hashToken(req.State))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find login state: %w", err)
+	}
+
+	if loginState == nil || loginState.Provider != providerName || loginState.UsedAt != nil || time.Now().After(loginState.ExpiresAt) {
+		return nil, errInvalidOIDCState
+	}
+
+	unused, err := s.oidcRepo.MarkStateUsed(loginState)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark login state as used: %w", err)
+	}
+	if !unused {
+		return nil, errInvalidOIDCState
+	}
+
+	// Redeem code and verify the ID token
+	ctx := context.Background()
+	rawIDToken, err := provider.Exchange(ctx, req.Code, loginState.CodeVerifier)
+	if err != nil {
+		logrus.WithError(err).WithField("provider", providerName).Warn("Failed to redeem authorization code")
+		return nil, errOIDCLoginFailed
+	}
+
+	claims, err := provider.VerifyIDToken(ctx, rawIDToken, loginState.Nonce)
+	if err != nil {
+		logrus.WithError(err).WithField("provider", providerName).Warn("Failed to verify ID token")
+		return nil, errOIDCLoginFailed
+	}
+
+	// Find the user of the identity, linking or creating one on first login
+	identity, err := s.oidcRepo.FindIdentity(providerName, claims.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find identity: %w", err)
+	}
+
+	var user *model.User
+	if identity != nil {
+		user, err = s.userRepo.FindByID(identity.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find user: %w", err)
+		}
+		if user == nil {
+			return nil, errors.New("user not found")
+		}
+
+		identity.Email = claims.Email
+		identity.LastLoginAt = time.Now()
+		if err := s.oidcRepo.UpdateLastLogin(identity); err != nil {
+			return nil, fmt.Errorf("failed to update identity: %w", err)
+		}
+	} else {
+		user, err = s.linkIdentity(providerName, claims)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !user.Active {
+		return nil, errors.New("user account is suspended")
+	}
+
+	return user, nil
+}
+
+// StartCleanup periodically deletes expired OIDC login states until ctx is
+// cancelled
+func (s *oidcService) StartCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := s.oidcRepo.DeleteExpiredStatesBefore(time.Now())
+			if err != nil {
+				logrus.WithError(err).Error("Failed to delete expired OIDC login states")
+				continue
+			}
+			if deleted > 0 {
+				logrus.Infof("Deleted %d expired OIDC login states", deleted)
+			}
+		}
+	}
+}
+
+// linkIdentity links a new identity to the user with its email address, or
+// to a new user when there is none
+func (s *oidcService) linkIdentity(providerName string, claims *oidc.IDTokenClaims) (*model.User, error) {
+	// Without a verified email address anyone could claim an account
+	if claims.Email == "" || !bool(claims.EmailVerified) {
+		return nil, errOIDCEmailNotVerified
+	}
+
+	user, err := s.userRepo.FindByEmail(claims.Email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	// Someone else may have registered the address without owning it, so
+	// only accounts that proved they own it are linked
+	if user != nil && !user.Verified {
+		return nil, errors.New("an account with this email address exists but is not verified")
+	}
+
+	now := time.Now()
+	identity := &model.OIDCIdentity{
+		Provider:    providerName,
+		Subject:     claims.Subject,
+		Email:       claims.Email,
+		LastLoginAt: now,
+	}
+
+	// Save identity, and the new user and its events, to database
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if user == nil {
+			password, err := oidc.RandomValue()
+			if err != nil {
+				return err
+			}
+
+			// The user can set a password with a password reset
+			user = &model.User{
+				Email:      claims.Email,
+				Password:   password, // Will be hashed by GORM hook
+				FirstName:  claims.GivenName,
+				LastName:   claims.FamilyName,
+				Role:       "user", // Default role
+				Active:     true,
+				Verified:   true,
+				VerifiedAt: &now,
+			}
+			if err := s.userRepo.WithTx(tx).Create(user); err != nil {
+				return fmt.Errorf("failed to create user: %w", err)
+			}
+
+			// Publish user created event
+			event := contracts.UserCreated{UserState: contracts.UserState{UserID: user.ID, Email: user.Email}}
+			if err := messaging.EnqueueEvent(s.outboxRepo, tx, "user_events", user.ID.String(), event); err != nil {
+				return err
+			}
+		}
+
+		identity.UserID = user.ID
+		if err := s.oidcRepo.WithTx(tx).CreateIdentity(identity); err != nil {
+			return fmt.Errorf("failed to create identity: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"user_id":  user.ID,
+		"provider": providerName,
+	}).Info("Linked identity provider account")
+	return user, nil
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/ticket-system/messaging"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"github.com/yourusername/ticket-system/user-service/oidc"
+	"github.com/yourusername/ticket-system/user-service/oidc/oidctest"
+	"github.com/yourusername/ticket-system/user-service/repository"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupOIDCService creates an OIDC service that signs in with a fake issuer
+// on an in-memory database
+func setupOIDCService(t *testing.T) (OIDCService, repository.UserRepository, *oidctest.Issuer) {
+	issuer, err := oidctest.NewIssuer("ticket-system")
+	require.NoError(t, err)
+	t.Cleanup(issuer.Close)
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	// Every connection opens its own in-memory database
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	provider := oidc.NewProvider(oidc.Config{
+		Name:        "test",
+		IssuerURL:   issuer.URL,
+		ClientID:    issuer.ClientID,
+		RedirectURL: "http://localhost:8080/api/v1/users/oidc/test/callback",
+	})
+
+	userRepo := repository.NewUserRepository(db)
+	oidcService := NewOIDCService([]*oidc.Provider{provider}, userRepo, repository.NewOIDCRepository(db), messaging.NewOutboxRepository(db), db)
+	return oidcService, userRepo, issuer
+}
+
+// authenticate signs a user in at the fake issuer and completes the login
+func authenticate(t *testing.T, oidcService OIDCService, issuer *oidctest.Issuer, user oidctest.User) (*model.User, model.OIDCCallbackRequest, error) {
+	authURL, err := oidcService.StartLogin("test")
+	require.NoError(t, err)
+
+	code, state, err := issuer.Authorize(authURL, user)
+	require.NoError(t, err)
+
+	req := model.OIDCCallbackRequest{Code: code, State: state}
+	authenticated, err := oidcService.Authenticate("test", req)
+	return authenticated, req, err
+}
+
+func TestOIDCService_CreatesAndLinksUsers(t *testing.T) {
+	oidcService, userRepo, issuer := setupOIDCService(t)
+	assert.Equal(t, []string{"test"}, oidcService.Providers())
+
+	_, err := oidcService.StartLogin("unknown")
+	assert.EqualError(t, err, "unknown identity provider")
+
+	// The first login creates a verified user
+	identity := oidctest.User{Subject: "subject-1", Email: "new@example.com", EmailVerified: true, GivenName: "Ada", FamilyName: "Lovelace"}
+	created, req, err := authenticate(t, oidcService, issuer, identity)
+	require.NoError(t, err)
+	assert.Equal(t, "new@example.com", created.Email)
+	assert.Equal(t, "Ada", created.FirstName)
+	assert.True(t, created.Verified)
+
+	// A callback can only complete a login once
+	_, err = oidcService.Authenticate("test", req)
+	assert.EqualError(t, err, "invalid or expired login state")
+
+	// Later logins sign in the same user
+	again, _, err := authenticate(t, oidcService, issuer, identity)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, again.ID)
+
+	// Identities are linked to verified users with the same email address
+	now := time.Now()
+	existing := &model.User{Email: "existing@example.com", Password: "password123", Role: "user", Active: true, Verified: true, VerifiedAt: &now}
+	require.NoError(t, userRepo.Create(existing))
+
+	linked, _, err := authenticate(t, oidcService, issuer, oidctest.User{Subject: "subject-2", Email: "existing@example.com", EmailVerified: true})
+	require.NoError(t, err)
+	assert.Equal(t, existing.ID, linked.ID)
+}
+
+func TestOIDCService_RejectsUnverifiedEmailAddresses(t *testing.T) {
+	oidcService, userRepo, issuer := setupOIDCService(t)
+
+	// The identity provider has not verified the address
+	_, _, err := authenticate(t, oidcService, issuer, oidctest.User{Subject: "subject-1", Email: "new@example.com"})
+	assert.EqualError(t, err, "email address is not verified by the identity provider")
+
+	// The local account has not proved it owns the address
+	unverified := &model.User{Email: "unverified@example.com", Password: "password123", Role: "user", Active: true}
+	require.NoError(t, userRepo.Create(unverified))
+
+	_, _, err = authenticate(t, oidcService, issuer, oidctest.User{Subject: "subject-2", Email: "unverified@example.com", EmailVerified: true})
+	assert.EqualError(t, err, "an account with this email address exists but is not verified")
+}
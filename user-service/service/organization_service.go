@@ -0,0 +1,374 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/contracts"
+	"github.com/yourusername/ticket-system/messaging"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"github.com/yourusername/ticket-system/user-service/repository"
+	"gorm.io/gorm"
+)
+
+// Organization errors
+var (
+	errOrganizationNotFound      = errors.New("organization not found")
+	errMemberNotFound            = errors.New("member not found")
+	errInvalidOrganizationRole   = errors.New("invalid organization role")
+	errOrganizationPermission    = errors.New("insufficient organization permissions")
+	errOrganizationNeedsOwner    = errors.New("organization must keep an owner")
+	errAlreadyOrganizationMember = errors.New("user is already a member of the organization")
+)
+
+// OrganizationService defines the interface for managing organizations and
+// their members
+type OrganizationService interface {
+	CreateOrganization(userID uuid.UUID, req model.CreateOrganizationRequest) (*model.OrganizationResponse, error)
+	GetOrganizations(userID uuid.UUID) ([]model.OrganizationResponse, error)
+	GetMembers(userID, organizationID uuid.UUID) ([]model.OrganizationMemberResponse, error)
+	AddMember(userID, organizationID uuid.UUID, req model.AddOrganizationMemberRequest) (*model.OrganizationMemberResponse, error)
+	UpdateMember(userID, organizationID, memberID uuid.UUID, req model.UpdateOrganizationMemberRequest) (*model.OrganizationMemberResponse, error)
+	RemoveMember(userID, organizationID, memberID uuid.UUID) error
+}
+
+// organizationService implements OrganizationService interface
+type organizationService struct {
+	organizationRepo repository.OrganizationRepository
+	userRepo         repository.UserRepository
+	outboxRepo       messaging.OutboxRepository
+	db               *gorm.DB
+}
+
+// NewOrganizationService creates a new organization service
+func NewOrganizationService(organizationRepo repository.OrganizationRepository, userRepo repository.UserRepository, outboxRepo messaging.OutboxRepository, db *gorm.DB) OrganizationService {
+	return &organizationService{
+		organizationRepo: organizationRepo,
+		userRepo:         userRepo,
+		outboxRepo:       outboxRepo,
+		db:               db,
+	}
+}
+
+// CreateOrganization creates an organization owned by the user
+func (s *organizationService) CreateOrganization(userID uuid.UUID, req model.CreateOrganizationRequest) (*model.OrganizationResponse, error) {
+	organization := &model.Organization{Name: req.Name}
+	member := &model.OrganizationMember{
+		UserID: userID,
+		Role:   contracts.OrganizationRoleOwner,
+	}
+
+	// Save organization, its owner and the member added event to database
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		txOrganizationRepo := s.organizationRepo.WithTx(tx)
+		if err := txOrganizationRepo.Create(organization); err != nil {
+			return fmt.Errorf("failed to create organization: %w", err)
+		}
+
+		member.OrganizationID = organization.ID
+		if err := txOrganizationRepo.CreateMember(member); err != nil {
+			return fmt.Errorf("failed to create organization member: %w", err)
+		}
+
+		return s.publishMemberEvent(tx, contracts.TypeOrganizationMemberAdded, member)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"organization_id": organization.ID,
+		"user_id":         userID,
+	}).Info("Organization created")
+
+	return &model.OrganizationResponse{
+		ID:        organization.ID,
+		Name:      organization.Name,
+		Role:      member.Role,
+		CreatedAt: organization.CreatedAt,
+	}, nil
+}
+
+// GetOrganizations returns the organizations the user is a member of
+func (s *organizationService) GetOrganizations(userID uuid.UUID) ([]model.OrganizationResponse, error) {
+	organizations, members, err := s.organizationRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find organizations: %w", err)
+	}
+
+	roles := make(map[uuid.UUID]string, len(members))
+	for _, member := range members {
+		roles[member.OrganizationID] = member.Role
+	}
+
+	responses := make([]model.OrganizationResponse, len(organizations))
+	for i, organization := range organizations {
+		responses[i] = model.OrganizationResponse{
+			ID:        organization.ID,
+			Name:      organization.Name,
+			Role:      roles[organization.ID],
+			CreatedAt: organization.CreatedAt,
+		}
+	}
+	return responses, nil
+}
+
+// GetMembers returns the members of an organization to one of its members
+func (s *organizationService) GetMembers(userID, organizationID uuid.UUID) ([]model.OrganizationMemberResponse, error) {
+	if _, err := s.findMembership(organizationID, userID); err != nil {
+		return nil, err
+	}
+
+	members, err := s.organizationRepo.FindMembers(organizationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find organization members: %w", err)
+	}
+
+	responses := make([]model.OrganizationMemberResponse, 0, len(members))
+	for _, member := range members {
+		user, err := s.userRepo.FindByID(member.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find user: %w", err)
+		}
+		if user == nil {
+			continue
+		}
+		responses = append(responses, memberResponse(&member, user))
+	}
+	return responses, nil
+}
+
+// AddMember adds a registered user to an organization with a role
+func (s *organizationService) AddMember(userID, organizationID uuid.UUID, req model.AddOrganizationMemberRequest) (*model.OrganizationMemberResponse, error) {
+	if !validOrganizationRole(req.Role) {
+		return nil, errInvalidOrganizationRole
+	}
+
+	actor, err := s.findMembership(organizationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !canManageRole(actor.Role, req.Role) {
+		return nil, errOrganizationPermission
+	}
+
+	// Find the user to add
+	user, err := s.userRepo.FindByEmail(req.Email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	existing, err := s.organizationRepo.FindMember(organizationID, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find organization member: %w", err)
+	}
+	if existing != nil {
+		return nil, errAlreadyOrganizationMember
+	}
+
+	member := &model.OrganizationMember{
+		OrganizationID: organizationID,
+		UserID:         user.ID,
+		Role:           req.Role,
+	}
+
+	// Save member and its added event to database
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.organizationRepo.WithTx(tx).CreateMember(member); err != nil {
+			return fmt.Errorf("failed to create organization member: %w", err)
+		}
+		return s.publishMemberEvent(tx, contracts.TypeOrganizationMemberAdded, member)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := memberResponse(member, user)
+	return &response, nil
+}
+
+// UpdateMember gives a member of an organization another role
+func (s *organizationService) UpdateMember(userID, organizationID, memberID uuid.UUID, req model.UpdateOrganizationMemberRequest) (*model.OrganizationMemberResponse, error) {
+	if !validOrganizationRole(req.Role) {
+		return nil, errInvalidOrganizationRole
+	}
+
+	actor, err := s.findMembership(organizationID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	member, err := s.organizationRepo.FindMember(organizationID, memberID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find organization member: %w", err)
+	}
+	if member == nil {
+		return nil, errMemberNotFound
+	}
+
+	// The member must be managed by the actor both before and after the change
+	if !canManageRole(actor.Role, member.Role) || !canManageRole(actor.Role, req.Role) {
+		return nil, errOrganizationPermission
+	}
+
+	user, err := s.userRepo.FindByID(member.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+	if user == nil {
+		return nil, errMemberNotFound
+	}
+
+	if member.Role == req.Role {
+		response := memberResponse(member, user)
+		return &response, nil
+	}
+
+	if err := s.ensureOtherOwner(organizationID, member); err != nil {
+		return nil, err
+	}
+
+	member.Role = req.Role
+
+	// Save role and its changed event to database
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.organizationRepo.WithTx(tx).UpdateMemberRole(member); err != nil {
+			return fmt.Errorf("failed to update organization member: %w", err)
+		}
+		return s.publishMemberEvent(tx, contracts.TypeOrganizationMemberRoleChanged, member)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := memberResponse(member, user)
+	return &response, nil
+}
+
+// RemoveMember removes a member from an organization. Members can always
+// leave an organization themselves.
+func (s *organizationService) RemoveMember(userID, organizationID, memberID uuid.UUID) error {
+	actor, err := s.findMembership(organizationID, userID)
+	if err != nil {
+		return err
+	}
+
+	member, err := s.organizationRepo.FindMember(organizationID, memberID)
+	if err != nil {
+		return fmt.Errorf("failed to find organization member: %w", err)
+	}
+	if member == nil {
+		return errMemberNotFound
+	}
+
+	if member.UserID != userID && !canManageRole(actor.Role, member.Role) {
+		return errOrganizationPermission
+	}
+
+	if err := s.ensureOtherOwner(organizationID, member); err != nil {
+		return err
+	}
+
+	// Delete member and save its removed event to database
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.organizationRepo.WithTx(tx).DeleteMember(organizationID, member.UserID); err != nil {
+			return fmt.Errorf("failed to delete organization member: %w", err)
+		}
+		return s.publishMemberEvent(tx, contracts.TypeOrganizationMemberRemoved, member)
+	})
+}
+
+// findMembership returns the membership of a user in an organization. The
+// organization is not found for users who are not its members, so that
+// they cannot tell which organizations exist.
+func (s *organizationService) findMembership(organizationID, userID uuid.UUID) (*model.OrganizationMember, error) {
+	member, err := s.organizationRepo.FindMember(organizationID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find organization member: %w", err)
+	}
+	if member == nil {
+		return nil, errOrganizationNotFound
+	}
+	return member, nil
+}
+
+// ensureOtherOwner checks that an organization keeps an owner when member
+// stops being one
+func (s *organizationService) ensureOtherOwner(organizationID uuid.UUID, member *model.OrganizationMember) error {
+	if member.Role != contracts.OrganizationRoleOwner {
+		return nil
+	}
+
+	owners, err := s.organizationRepo.CountMembersWithRole(organizationID, contracts.OrganizationRoleOwner)
+	if err != nil {
+		return fmt.Errorf("failed to count organization owners: %w", err)
+	}
+	if owners <= 1 {
+		return errOrganizationNeedsOwner
+	}
+	return nil
+}
+
+// publishMemberEvent writes an organization member event to the outbox in tx
+func (s *organizationService) publishMemberEvent(tx *gorm.DB, eventType string, member *model.OrganizationMember) error {
+	state := contracts.OrganizationMemberState{
+		OrganizationID: member.OrganizationID,
+		UserID:         member.UserID,
+		Role:           member.Role,
+	}
+
+	var event contracts.Event
+	switch eventType {
+	case contracts.TypeOrganizationMemberAdded:
+		event = contracts.OrganizationMemberAdded{OrganizationMemberState: state}
+	case contracts.TypeOrganizationMemberRoleChanged:
+		event = contracts.OrganizationMemberRoleChanged{OrganizationMemberState: state}
+	case contracts.TypeOrganizationMemberRemoved:
+		event = contracts.OrganizationMemberRemoved{OrganizationID: member.OrganizationID, UserID: member.UserID}
+	default:
+		return fmt.Errorf("unknown organization event type %s", eventType)
+	}
+
+	return messaging.EnqueueEvent(s.outboxRepo, tx, "user_events", member.OrganizationID.String(), event)
+}
+
+// validOrganizationRole reports whether role is a role of organization members
+func validOrganizationRole(role string) bool {
+	for _, valid := range contracts.OrganizationRoles() {
+		if role == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// canManageRole reports whether a member with actorRole may add, change or
+// remove members with role. Owners manage everyone, and managers manage the
+// box office and scanner staff.
+func canManageRole(actorRole, role string) bool {
+	switch actorRole {
+	case contracts.OrganizationRoleOwner:
+		return true
+	case contracts.OrganizationRoleManager:
+		return role == contracts.OrganizationRoleBoxOffice || role == contracts.OrganizationRoleScanner
+	default:
+		return false
+	}
+}
+
+// memberResponse converts a member and its user to a member response
+func memberResponse(member *model.OrganizationMember, user *model.User) model.OrganizationMemberResponse {
+	return model.OrganizationMemberResponse{
+		UserID:    member.UserID,
+		Email:     user.Email,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Role:      member.Role,
+		CreatedAt: member.CreatedAt,
+	}
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/ticket-system/contracts"
+	"github.com/yourusername/ticket-system/messaging"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"github.com/yourusername/ticket-system/user-service/repository"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupOrganizationService creates an organization service on an in-memory
+// database with an owner, a manager and a user who is not a member yet
+func setupOrganizationService(t *testing.T) (OrganizationService, *model.OrganizationResponse, *model.User, *model.User, *model.User) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	// Every connection opens its own in-memory database
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	userRepo := repository.NewUserRepository(db)
+	users := make([]*model.User, 3)
+	for i, email := range []string{"owner@example.com", "manager@example.com", "staff@example.com"} {
+		users[i] = &model.User{Email: email, Password: "password123", Role: "user", Active: true}
+		require.NoError(t, userRepo.Create(users[i]))
+	}
+
+	organizationService := NewOrganizationService(repository.NewOrganizationRepository(db), userRepo, messaging.NewOutboxRepository(db), db)
+	organization, err := organizationService.CreateOrganization(users[0].ID, model.CreateOrganizationRequest{Name: "Festival Co"})
+	require.NoError(t, err)
+	assert.Equal(t, contracts.OrganizationRoleOwner, organization.Role)
+
+	_, err = organizationService.AddMember(users[0].ID, organization.ID, model.AddOrganizationMemberRequest{Email: users[1].Email, Role: contracts.OrganizationRoleManager})
+	require.NoError(t, err)
+
+	return organizationService, organization, users[0], users[1], users[2]
+}
+
+func TestOrganizationService_ManagersOnlyManageStaff(t *testing.T) {
+	organizationService, organization, owner, manager, staff := setupOrganizationService(t)
+
+	// Managers cannot add owners or managers
+	_, err := organizationService.AddMember(manager.ID, organization.ID, model.AddOrganizationMemberRequest{Email: staff.Email, Role: contracts.OrganizationRoleOwner})
+	assert.EqualError(t, err, "insufficient organization permissions")
+
+	member, err := organizationService.AddMember(manager.ID, organization.ID, model.AddOrganizationMemberRequest{Email: staff.Email, Role: contracts.OrganizationRoleScanner})
+	require.NoError(t, err)
+	assert.Equal(t, contracts.OrganizationRoleScanner, member.Role)
+
+	_, err = organizationService.AddMember(manager.ID, organization.ID, model.AddOrganizationMemberRequest{Email: staff.Email, Role: contracts.OrganizationRoleBoxOffice})
+	assert.EqualError(t, err, "user is already a member of the organization")
+
+	// Managers can change staff roles, but not promote staff to manager
+	_, err = organizationService.UpdateMember(manager.ID, organization.ID, staff.ID, model.UpdateOrganizationMemberRequest{Role: contracts.OrganizationRoleManager})
+	assert.EqualError(t, err, "insufficient organization permissions")
+
+	member, err = organizationService.UpdateMember(manager.ID, organization.ID, staff.ID, model.UpdateOrganizationMemberRequest{Role: contracts.OrganizationRoleBoxOffice})
+	require.NoError(t, err)
+	assert.Equal(t, contracts.OrganizationRoleBoxOffice, member.Role)
+
+	_, err = organizationService.UpdateMember(manager.ID, organization.ID, owner.ID, model.UpdateOrganizationMemberRequest{Role: contracts.OrganizationRoleScanner})
+	assert.EqualError(t, err, "insufficient organization permissions")
+
+	_, err = organizationService.UpdateMember(owner.ID, organization.ID, staff.ID, model.UpdateOrganizationMemberRequest{Role: "admin"})
+	assert.EqualError(t, err, "invalid organization role")
+
+	members, err := organizationService.GetMembers(staff.ID, organization.ID)
+	require.NoError(t, err)
+	assert.Len(t, members, 3)
+
+	// Staff cannot remove others, but can leave themselves
+	assert.EqualError(t, organizationService.RemoveMember(staff.ID, organization.ID, manager.ID), "insufficient organization permissions")
+	require.NoError(t, organizationService.RemoveMember(staff.ID, organization.ID, staff.ID))
+
+	// Non-members cannot see the organization
+	_, err = organizationService.GetMembers(staff.ID, organization.ID)
+	assert.EqualError(t, err, "organization not found")
+}
+
+func TestOrganizationService_KeepsAnOwner(t *testing.T) {
+	organizationService, organization, owner, manager, _ := setupOrganizationService(t)
+
+	assert.EqualError(t, organizationService.RemoveMember(owner.ID, organization.ID, owner.ID), "organization must keep an owner")
+	_, err := organizationService.UpdateMember(owner.ID, organization.ID, owner.ID, model.UpdateOrganizationMemberRequest{Role: contracts.OrganizationRoleManager})
+	assert.EqualError(t, err, "organization must keep an owner")
+
+	// Once another member owns the organization, the first owner can leave
+	_, err = organizationService.UpdateMember(owner.ID, organization.ID, manager.ID, model.UpdateOrganizationMemberRequest{Role: contracts.OrganizationRoleOwner})
+	require.NoError(t, err)
+	require.NoError(t, organizationService.RemoveMember(owner.ID, organization.ID, owner.ID))
+
+	organizations, err := organizationService.GetOrganizations(manager.ID)
+	require.NoError(t, err)
+	require.Len(t, organizations, 1)
+	assert.Equal(t, contracts.OrganizationRoleOwner, organizations[0].Role)
+
+	organizations, err = organizationService.GetOrganizations(owner.ID)
+	require.NoError(t, err)
+	assert.Empty(t, organizations)
+}
@@ -0,0 +1,396 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sirupsen/logrus"
+	"github.com/yourusername/ticket-system/claims"
+	"github.com/yourusername/ticket-system/user-service/middleware"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"github.com/yourusername/ticket-system/user-service/repository"
+	"gorm.io/gorm"
+)
+
+// Session settings
+const (
+	refreshTokenBytes      = 32
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+	maxUserAgentLength     = 255
+)
+
+// errRefreshTokenReused is returned when a refresh token is redeemed twice
+var errRefreshTokenReused = errors.New("refresh token has already been used")
+
+// Session metrics
+var (
+	// refreshTokenReuseTotal tracks the number of detected refresh token reuses
+	refreshTokenReuseTotal = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "refresh_token_reuse_total",
+			Help: "Total number of reused refresh tokens, each of which revoked its session",
+		},
+	)
+)
+
+// SessionService defines the interface for session service operations
+type SessionService interface {
+	StartSession(user *model.User, client model.ClientInfo, mfa bool) (*model.TokenResponse, error)
+	ElevateSession(user *model.User, sessionID uuid.UUID) (*model.TokenResponse, error)
+	StartImpersonation(tx *gorm.DB, user *model.User, adminID uuid.UUID, client model.ClientInfo) (*model.TokenResponse, error)
+	Refresh(refreshToken string, client model.ClientInfo) (*model.TokenResponse, error)
+	ListSessions(userID, currentSessionID uuid.UUID) ([]model.SessionResponse, error)
+	RevokeSession(userID, sessionID uuid.UUID, reason string) error
+	RevokeAllSessions(tx *gorm.DB, userID uuid.UUID, reason string) (int64, error)
+	RevokedSessions() ([]model.RevokedSession, error)
+	StartCleanup(ctx context.Context, interval time.Duration)
+}
+
+// sessionService implements SessionService interface
+type sessionService struct {
+	sessionRepo repository.SessionRepository
+	userRepo    repository.UserRepository
+	keyService  KeyService
+	db          *gorm.DB
+}
+
+// NewSessionService creates a new session service
+func NewSessionService(sessionRepo repository.SessionRepository, userRepo repository.UserRepository, keyService KeyService, db *gorm.DB) SessionService {
+	return &sessionService{
+		sessionRepo: sessionRepo,
+		userRepo:    userRepo,
+		keyService:  keyService,
+		db:          db,
+	}
+}
+
+// StartSession starts a new session for a user who has just signed in, with
+// a second factor when mfa is set
+func (s *sessionService) StartSession(user *model.User, client model.ClientInfo, mfa bool) (*model.TokenResponse, error) {
+	now := time.Now()
+	session := &model.Session{
+		UserID:     user.ID,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(refreshTokenTTL()),
+		MFA:        mfa,
+	}
+	setClient(session, client)
+
+	// Save session and its first refresh token to database
+	var refreshToken string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		txSessionRepo := s.sessionRepo.WithTx(tx)
+		if err := txSessionRepo.Create(session); err != nil {
+			return fmt.Errorf("failed to create session: %w", err)
+		}
+
+		var err error
+		refreshToken, err = issueRefreshToken(txSessionRepo, session)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.tokenResponse(user, session, refreshToken)
+}
+
+// ElevateSession records that a user proved a second factor in a session
+// they signed in to without one, and returns an access token that says so.
+// The refresh token of the session stays valid and is not returned.
+func (s *sessionService) ElevateSession(user *model.User, sessionID uuid.UUID) (*model.TokenResponse, error) {
+	elevated, err := s.sessionRepo.MarkMFA(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update session: %w", err)
+	}
+
+	if !elevated {
+		return nil, errors.New("session not found")
+	}
+
+	session := &model.Session{ID: sessionID, UserID: user.ID, MFA: true}
+	return s.tokenResponse(user, session, "")
+}
+
+// StartImpersonation starts a session in tx in which an admin acts as a
+// user, and returns an access token that names the admin as its actor. The
+// admin has proven a second factor to get here, so the session has one too.
+// No refresh token is issued, so the session ends with its access token.
+func (s *sessionService) StartImpersonation(tx *gorm.DB, user *model.User, adminID uuid.UUID, client model.ClientInfo) (*model.TokenResponse, error) {
+	now := time.Now()
+	session := &model.Session{
+		UserID:         user.ID,
+		LastUsedAt:     now,
+		ExpiresAt:      now.Add(middleware.AccessTokenTTL()),
+		MFA:            true,
+		ImpersonatorID: &adminID,
+	}
+	setClient(session, client)
+
+	if err := s.sessionRepo.WithTx(tx).Create(session); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return s.tokenResponse(user, session, "")
+}
+
+// Refresh redeems a refresh token for a new access token and a new refresh
+// token. Redeeming a refresh token that was already used means it was
+// stolen, so the whole session is revoked.
+func (s *sessionService) Refresh(refreshToken string, client model.ClientInfo) (*model.TokenResponse, error) {
+	// Find refresh token by the hash of its value
+	token, err := s.sessionRepo.FindRefreshTokenByHash(hashToken(refreshToken))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find refresh token: %w", err)
+	}
+
+	if token == nil || time.Now().After(token.ExpiresAt) {
+		return nil, errors.New("invalid refresh token")
+	}
+
+	if token.UsedAt != nil {
+		return nil, s.revokeReusedToken(token)
+	}
+
+	// Find session of the token
+	session, err := s.sessionRepo.FindByID(token.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find session: %w", err)
+	}
+
+	if session == nil || !session.Active() {
+		return nil, errors.New("invalid refresh token")
+	}
+
+	// Find user of the session
+	user, err := s.userRepo.FindByID(session.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	if user == nil || !user.Active {
+		return nil, errors.New("invalid refresh token")
+	}
+
+	// Extend session
+	now := time.Now()
+	session.LastUsedAt = now
+	session.ExpiresAt = now.Add(refreshTokenTTL())
+	setClient(session, client)
+
+	// Replace refresh token and save session to database
+	var nextRefreshToken string
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		txSessionRepo := s.sessionRepo.WithTx(tx)
+		unused, err := txSessionRepo.MarkRefreshTokenUsed(token)
+		if err != nil {
+			return fmt.Errorf("failed to mark refresh token as used: %w", err)
+		}
+		if !unused {
+			return errRefreshTokenReused
+		}
+
+		active, err := txSessionRepo.Renew(session)
+		if err != nil {
+			return fmt.Errorf("failed to renew session: %w", err)
+		}
+		if !active {
+			return errors.New("invalid refresh token")
+		}
+
+		nextRefreshToken, err = issueRefreshToken(txSessionRepo, session)
+		return err
+	})
+	if errors.Is(err, errRefreshTokenReused) {
+		return nil, s.revokeReusedToken(token)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.tokenResponse(user, session, nextRefreshToken)
+}
+
+// ListSessions lists the active sessions of a user
+func (s *sessionService) ListSessions(userID, currentSessionID uuid.UUID) ([]model.SessionResponse, error) {
+	sessions, err := s.sessionRepo.FindActiveByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find sessions: %w", err)
+	}
+
+	responses := make([]model.SessionResponse, len(sessions))
+	for i := range sessions {
+		responses[i] = sessions[i].ToResponse(currentSessionID)
+	}
+	return responses, nil
+}
+
+// RevokeSession revokes a session of a user
+func (s *sessionService) RevokeSession(userID, sessionID uuid.UUID, reason string) error {
+	// Find session by ID
+	session, err := s.sessionRepo.FindByID(sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to find session: %w", err)
+	}
+
+	if session == nil || session.UserID != userID {
+		return errors.New("session not found")
+	}
+
+	revoked, err := s.sessionRepo.Revoke(sessionID, reason)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	if !revoked {
+		return errors.New("session not found")
+	}
+
+	return nil
+}
+
+// RevokeAllSessions revokes all sessions of a user in tx, or on its own when
+// tx is nil, and returns how many were active
+func (s *sessionService) RevokeAllSessions(tx *gorm.DB, userID uuid.UUID, reason string) (int64, error) {
+	sessionRepo := s.sessionRepo
+	if tx != nil {
+		sessionRepo = sessionRepo.WithTx(tx)
+	}
+
+	revoked, err := sessionRepo.RevokeAllByUserID(userID, reason)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return revoked, nil
+}
+
+// RevokedSessions returns the revoked sessions whose access tokens may still
+// be valid. The API gateway polls them to reject those tokens without
+// looking up every session.
+func (s *sessionService) RevokedSessions() ([]model.RevokedSession, error) {
+	accessTokenTTL := middleware.AccessTokenTTL()
+	sessions, err := s.sessionRepo.FindRevokedSince(time.Now().Add(-accessTokenTTL))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find revoked sessions: %w", err)
+	}
+
+	revoked := make([]model.RevokedSession, len(sessions))
+	for i, session := range sessions {
+		revoked[i] = model.RevokedSession{
+			ID:        session.ID,
+			RevokedAt: *session.RevokedAt,
+			ExpiresAt: session.RevokedAt.Add(accessTokenTTL),
+		}
+	}
+	return revoked, nil
+}
+
+// StartCleanup periodically deletes expired sessions and refresh tokens until
+// ctx is cancelled
+func (s *sessionService) StartCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := s.sessionRepo.DeleteExpiredBefore(time.Now())
+			if err != nil {
+				logrus.WithError(err).Error("Failed to delete expired sessions")
+				continue
+			}
+			if deleted > 0 {
+				logrus.Infof("Deleted %d expired sessions", deleted)
+			}
+		}
+	}
+}
+
+// revokeReusedToken revokes the session of a refresh token that was redeemed
+// a second time
+func (s *sessionService) revokeReusedToken(token *model.RefreshToken) error {
+	refreshTokenReuseTotal.Inc()
+	logrus.WithField("session_id", token.SessionID).Warn("Refresh token reuse detected, revoking session")
+
+	if _, err := s.sessionRepo.Revoke(token.SessionID, model.SessionRevokedReuseDetected); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return errRefreshTokenReused
+}
+
+// issueRefreshToken creates a new refresh token for a session and returns its value
+func issueRefreshToken(sessionRepo repository.SessionRepository, session *model.Session) (string, error) {
+	value := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(value); err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	refreshToken := base64.RawURLEncoding.EncodeToString(value)
+
+	token := &model.RefreshToken{
+		SessionID: session.ID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: session.ExpiresAt,
+	}
+	if err := sessionRepo.CreateRefreshToken(token); err != nil {
+		return "", fmt.Errorf("failed to create refresh token: %w", err)
+	}
+	return refreshToken, nil
+}
+
+// tokenResponse generates an access token for a session and pairs it with
+// the refresh token of the session
+func (s *sessionService) tokenResponse(user *model.User, session *model.Session, refreshToken string) (*model.TokenResponse, error) {
+	tokenClaims := middleware.NewClaims(user.ID, session.ID, user.Email, user.Role, user.Verified, session.MFA)
+	if session.ImpersonatorID != nil {
+		tokenClaims.Actor = &claims.Actor{Subject: *session.ImpersonatorID}
+	}
+
+	token, err := s.keyService.Sign(tokenClaims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	return &model.TokenResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(middleware.AccessTokenTTL().Seconds()),
+	}, nil
+}
+
+// hashToken returns the hash under which a refresh or user token is stored
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// setClient records the client a session was last used from
+func setClient(session *model.Session, client model.ClientInfo) {
+	userAgent := client.UserAgent
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	session.UserAgent = userAgent
+	session.IPAddress = client.IPAddress
+}
+
+// refreshTokenTTL returns how long a session stays signed in without being
+// used, from the REFRESH_TOKEN_TTL environment variable or 30 days by default
+func refreshTokenTTL() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv("REFRESH_TOKEN_TTL"))
+	if err != nil || ttl <= 0 {
+		return defaultRefreshTokenTTL
+	}
+	return ttl
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/yourusername/ticket-system/jwks"
+	"github.com/yourusername/ticket-system/user-service/model"
+	"github.com/yourusername/ticket-system/user-service/repository"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupSessionService creates a session service on an in-memory database
+// with one active user
+func setupSessionService(t *testing.T) (SessionService, *model.User) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	// Every connection opens its own in-memory database
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	userRepo := repository.NewUserRepository(db)
+	user := &model.User{
+		Email:    "session@example.com",
+		Password: "password123",
+		Role:     "user",
+		Active:   true,
+	}
+	require.NoError(t, userRepo.Create(user))
+
+	keyService, err := NewKeyService(repository.NewSigningKeyRepository(db), jwks.AlgorithmEdDSA, time.Hour)
+	require.NoError(t, err)
+
+	return NewSessionService(repository.NewSessionRepository(db), userRepo, keyService, db), user
+}
+
+func TestSessionService_Refresh(t *testing.T) {
+	sessionService, user := setupSessionService(t)
+	client := model.ClientInfo{UserAgent: "test", IPAddress: "127.0.0.1"}
+
+	tokens, err := sessionService.StartSession(user, client, false)
+	require.NoError(t, err)
+	assert.NotEmpty(t, tokens.Token)
+	assert.NotEmpty(t, tokens.RefreshToken)
+
+	// Refreshing rotates the refresh token
+	refreshed, err := sessionService.Refresh(tokens.RefreshToken, client)
+	require.NoError(t, err)
+	assert.NotEqual(t, tokens.RefreshToken, refreshed.RefreshToken)
+
+	// Reusing the old refresh token revokes the whole session
+	_, err = sessionService.Refresh(tokens.RefreshToken, client)
+	assert.EqualError(t, err, "refresh token has already been used")
+
+	_, err = sessionService.Refresh(refreshed.RefreshToken, client)
+	assert.EqualError(t, err, "invalid refresh token")
+
+	revoked, err := sessionService.RevokedSessions()
+	require.NoError(t, err)
+	assert.Len(t, revoked, 1)
+
+	_, err = sessionService.Refresh("unknown", client)
+	assert.EqualError(t, err, "invalid refresh token")
+}
+
+func TestSessionService_RevokeAllSessions(t *testing.T) {
+	sessionService, user := setupSessionService(t)
+	client := model.ClientInfo{UserAgent: "test", IPAddress: "127.0.0.1"}
+
+	for i := 0; i < 2; i++ {
+		_, err := sessionService.StartSession(user, client, false)
+		require.NoError(t, err)
+	}
+
+	sessions, err := sessionService.ListSessions(user.ID, user.ID)
+	require.NoError(t, err)
+	assert.Len(t, sessions, 2)
+
+	revokedCount, err := sessionService.RevokeAllSessions(nil, user.ID, model.SessionRevokedLogoutAll)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), revokedCount)
+
+	sessions, err = sessionService.ListSessions(user.ID, user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+
+	// The gateway rejects access tokens of the revoked sessions until they expire
+	revoked, err := sessionService.RevokedSessions()
+	require.NoError(t, err)
+	require.Len(t, revoked, 2)
+	assert.True(t, revoked[0].ExpiresAt.After(revoked[0].RevokedAt))
+}
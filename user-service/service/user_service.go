@@ -1,38 +1,57 @@
 package service
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
-	"github.com/yourusername/ticket-system/user-service/config"
-	"github.com/yourusername/ticket-system/user-service/middleware"
+	"github.com/yourusername/ticket-system/contracts"
+	"github.com/yourusername/ticket-system/messaging"
 	"github.com/yourusername/ticket-system/user-service/model"
 	"github.com/yourusername/ticket-system/user-service/repository"
+	"gorm.io/gorm"
 )
 
 // UserService defines the interface for user service operations
 type UserService interface {
 	Register(req model.RegisterRequest) (*model.UserResponse, error)
-	Login(req model.LoginRequest) (*model.LoginResponse, error)
+	Login(req model.LoginRequest, client model.ClientInfo) (*model.LoginResponse, error)
+	LoginMFA(req model.MFALoginRequest, client model.ClientInfo) (*model.LoginResponse, error)
+	LoginOIDC(provider string, req model.OIDCCallbackRequest, client model.ClientInfo) (*model.LoginResponse, error)
 	GetUserByID(id uuid.UUID) (*model.UserResponse, error)
 	UpdateProfile(id uuid.UUID, req model.UpdateProfileRequest) (*model.UserResponse, error)
 	ChangePassword(id uuid.UUID, req model.ChangePasswordRequest) error
+	SuspendUser(id uuid.UUID) (*model.UserResponse, error)
+	ReactivateUser(id uuid.UUID) (*model.UserResponse, error)
+	UnlockUser(id uuid.UUID) error
+	DeleteUser(id uuid.UUID) error
 }
 
 // userService implements UserService interface
 type userService struct {
-	userRepo repository.UserRepository
-	rmq      *config.RabbitMQ
+	userRepo       repository.UserRepository
+	outboxRepo     messaging.OutboxRepository
+	sessionService SessionService
+	accountService AccountService
+	mfaService     MFAService
+	oidcService    OIDCService
+	loginThrottle  LoginThrottleService
+	db             *gorm.DB
 }
 
 // NewUserService creates a new user service
-func NewUserService(userRepo repository.UserRepository, rmq *config.RabbitMQ) UserService {
+func NewUserService(userRepo repository.UserRepository, outboxRepo messaging.OutboxRepository, sessionService SessionService, accountService AccountService, mfaService MFAService, oidcService OIDCService, loginThrottle LoginThrottleService, db *gorm.DB) UserService {
 	return &userService{
-		userRepo: userRepo,
-		rmq:      rmq,
+		userRepo:       userRepo,
+		outboxRepo:     outboxRepo,
+		sessionService: sessionService,
+		accountService: accountService,
+		mfaService:     mfaService,
+		oidcService:    oidcService,
+		loginThrottle:  loginThrottle,
+		db:             db,
 	}
 }
 
@@ -59,54 +78,147 @@ func (s *userService) Register(req model.RegisterRequest) (*model.UserResponse,
 		Active:    true,
 	}
 
-	// Save user to database
-	if err := s.userRepo.Create(user); err != nil {
-		return nil, fmt.Errorf("failed to create user: %w", err)
+	// Save user and its created event to database
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.userRepo.WithTx(tx).Create(user); err != nil {
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+
+		// Publish user created event
+		return s.publishUserEvent(tx, "user.created", user)
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// Publish user created event
-	s.publishUserEvent("user.created", user)
+	// Send link to verify the email address. The user can ask for a new link
+	// if this fails.
+	if err := s.accountService.SendVerification(user); err != nil {
+		logrus.WithError(err).Errorf("Failed to send verification email to user %s", user.ID)
+	}
 
 	// Return user response
 	userResponse := user.ToResponse()
 	return &userResponse, nil
 }
 
-// Login authenticates a user and returns a JWT token
-func (s *userService) Login(req model.LoginRequest) (*model.LoginResponse, error) {
+// Login authenticates a user and returns a JWT token. Logins are slowed down
+// and locked after failed attempts for the account or from the IP address.
+func (s *userService) Login(req model.LoginRequest, client model.ClientInfo) (*model.LoginResponse, error) {
+	// Reject logins blocked after too many failed attempts
+	if err := s.loginThrottle.Check(req.Email, client.IPAddress); err != nil {
+		return nil, err
+	}
+
 	// Find user by email
 	user, err := s.userRepo.FindByEmail(req.Email)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find user: %w", err)
 	}
 
-	if user == nil {
+	// Check password, failures for unknown accounts are counted the same so
+	// that the responses do not reveal whether an account exists
+	if user == nil || !user.CheckPassword(req.Password) {
+		if err := s.loginThrottle.RecordFailure(req.Email, client.IPAddress, user); err != nil {
+			return nil, err
+		}
 		return nil, errors.New("invalid email or password")
 	}
 
-	// Check if user is active
+	// Check if user is active, only after the password so that the response
+	// does not reveal whether an account exists
 	if !user.Active {
-		return nil, errors.New("user account is inactive")
+		return nil, errors.New("user account is suspended")
 	}
 
-	// Check password
-	if !user.CheckPassword(req.Password) {
-		return nil, errors.New("invalid email or password")
+	return s.completeLogin(user, client)
+}
+
+// LoginOIDC authenticates a user with the callback of an identity provider
+// and returns a JWT token, or an MFA token when the user has two-factor
+// authentication enabled
+func (s *userService) LoginOIDC(provider string, req model.OIDCCallbackRequest, client model.ClientInfo) (*model.LoginResponse, error) {
+	user, err := s.oidcService.Authenticate(provider, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.completeLogin(user, client)
+}
+
+// completeLogin starts a session for a user who has proven their identity,
+// or asks for a code first when the user has two-factor authentication
+// enabled
+func (s *userService) completeLogin(user *model.User, client model.ClientInfo) (*model.LoginResponse, error) {
+	// Ask users with two-factor authentication for a code before starting
+	// a session
+	enabled, err := s.mfaService.Enabled(user.ID)
+	if err != nil {
+		return nil, err
 	}
 
-	// Generate JWT token
-	token, err := middleware.GenerateToken(user.ID, user.Email, user.Role)
+	if enabled {
+		mfaToken, expiresAt, err := s.mfaService.StartChallenge(user.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		return &model.LoginResponse{
+			ExpiresIn:   int64(time.Until(expiresAt).Seconds()),
+			MFARequired: true,
+			MFAToken:    mfaToken,
+		}, nil
+	}
+
+	return s.startSession(user, client, false)
+}
+
+// LoginMFA completes the login of a user with two-factor authentication with
+// the MFA token returned by Login and a TOTP or recovery code
+func (s *userService) LoginMFA(req model.MFALoginRequest, client model.ClientInfo) (*model.LoginResponse, error) {
+	user, err := s.mfaService.RedeemChallenge(req.MFAToken, req.Code)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate token: %w", err)
+		// Wrong codes count as failed logins, so that logging in with the
+		// password again for a new challenge does not give more tries
+		if errors.Is(err, errInvalidMFACode) {
+			if err := s.loginThrottle.RecordFailure(user.Email, client.IPAddress, user); err != nil {
+				return nil, err
+			}
+		}
+		return nil, err
+	}
+
+	return s.startSession(user, client, true)
+}
+
+// startSession starts a session for a user who has logged in and returns
+// its tokens
+func (s *userService) startSession(user *model.User, client model.ClientInfo, mfa bool) (*model.LoginResponse, error) {
+	// Failed logins to the account are only forgotten once every factor
+	// has been proven
+	if err := s.loginThrottle.RecordSuccess(user.Email); err != nil {
+		return nil, err
+	}
+
+	// Start session and generate its tokens
+	tokens, err := s.sessionService.StartSession(user, client, mfa)
+	if err != nil {
+		return nil, err
 	}
 
 	// Publish user login event
-	s.publishUserEvent("user.login", user)
+	if err := s.publishUserEvent(nil, "user.login", user); err != nil {
+		logrus.WithError(err).Error("Failed to publish user login event")
+	}
 
 	// Return login response
 	return &model.LoginResponse{
-		Token: token,
-		User:  user.ToResponse(),
+		Token:        tokens.Token,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		User:         user.ToResponse(),
+
+		MFAEnrollmentRequired: !mfa && s.mfaService.Required(user.Role),
 	}, nil
 }
 
@@ -150,13 +262,18 @@ func (s *userService) UpdateProfile(id uuid.UUID, req model.UpdateProfileRequest
 		user.Phone = req.Phone
 	}
 
-	// Save user to database
-	if err := s.userRepo.Update(user); err != nil {
-		return nil, fmt.Errorf("failed to update user: %w", err)
-	}
+	// Save user and its updated event to database
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.userRepo.WithTx(tx).Update(user); err != nil {
+			return fmt.Errorf("failed to update user: %w", err)
+		}
 
-	// Publish user updated event
-	s.publishUserEvent("user.updated", user)
+		// Publish user updated event
+		return s.publishUserEvent(tx, "user.updated", user)
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	// Return user response
 	userResponse := user.ToResponse()
@@ -183,37 +300,177 @@ func (s *userService) ChangePassword(id uuid.UUID, req model.ChangePasswordReque
 	// Update password
 	user.Password = req.NewPassword // Will be hashed by GORM hook
 
-	// Save user to database
-	if err := s.userRepo.Update(user); err != nil {
-		return fmt.Errorf("failed to update user: %w", err)
+	// Save user and its password changed event to database
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.userRepo.WithTx(tx).Update(user); err != nil {
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+
+		// Publish password changed event
+		return s.publishUserEvent(tx, "user.password_changed", user)
+	})
+}
+
+// SuspendUser suspends a user. Suspended users cannot log in, their tokens
+// are rejected, and the other services cancel their pending bookings.
+func (s *userService) SuspendUser(id uuid.UUID) (*model.UserResponse, error) {
+	// Find user by ID
+	user, err := s.userRepo.FindByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	if user == nil {
+		return nil, errors.New("user not found")
 	}
 
-	// Publish password changed event
-	s.publishUserEvent("user.password_changed", user)
+	if !user.Active {
+		return nil, errors.New("user is already suspended")
+	}
+
+	// Deactivate user
+	now := time.Now()
+	user.Active = false
+	user.SuspendedAt = &now
+
+	// Save user and its suspended event to database
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.userRepo.WithTx(tx).Update(user); err != nil {
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+
+		// Sign user out everywhere
+		if _, err := s.sessionService.RevokeAllSessions(tx, user.ID, model.SessionRevokedSuspended); err != nil {
+			return err
+		}
+
+		// Publish user suspended event
+		return s.publishUserEvent(tx, contracts.TypeUserSuspended, user)
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	return nil
+	// Return user response
+	userResponse := user.ToResponse()
+	return &userResponse, nil
 }
 
-// publishUserEvent publishes a user event to RabbitMQ
-func (s *userService) publishUserEvent(eventType string, user *model.User) {
-	// Create event payload
-	event := map[string]interface{}{
-		"event_type": eventType,
-		"user_id":    user.ID.String(),
-		"email":      user.Email,
-		"timestamp":  user.UpdatedAt,
+// ReactivateUser lifts the suspension of a user
+func (s *userService) ReactivateUser(id uuid.UUID) (*model.UserResponse, error) {
+	// Find user by ID
+	user, err := s.userRepo.FindByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	if user.Active {
+		return nil, errors.New("user is not suspended")
 	}
 
-	// Convert event to JSON
-	eventJSON, err := json.Marshal(event)
+	// Activate user
+	user.Active = true
+	user.SuspendedAt = nil
+
+	// Save user and its updated event to database
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.userRepo.WithTx(tx).Update(user); err != nil {
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+
+		// Publish user updated event
+		return s.publishUserEvent(tx, contracts.TypeUserUpdated, user)
+	})
 	if err != nil {
-		logrus.WithError(err).Error("Failed to marshal user event")
-		return
+		return nil, err
 	}
 
-	// Publish event to RabbitMQ
-	err = s.rmq.PublishMessage("user_events", eventType, eventJSON)
+	// Return user response
+	userResponse := user.ToResponse()
+	return &userResponse, nil
+}
+
+// UnlockUser lifts the lockout of the account of a user after too many
+// failed logins
+func (s *userService) UnlockUser(id uuid.UUID) error {
+	// Find user by ID
+	user, err := s.userRepo.FindByID(id)
 	if err != nil {
-		logrus.WithError(err).Error("Failed to publish user event")
+		return fmt.Errorf("failed to find user: %w", err)
 	}
-}
\ No newline at end of file
+
+	if user == nil {
+		return errors.New("user not found")
+	}
+
+	return s.loginThrottle.Unlock(user.Email)
+}
+
+// DeleteUser deletes a user. The other services cancel their pending and
+// confirmed bookings and forget their contact details.
+func (s *userService) DeleteUser(id uuid.UUID) error {
+	// Find user by ID
+	user, err := s.userRepo.FindByID(id)
+	if err != nil {
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+
+	if user == nil {
+		return errors.New("user not found")
+	}
+
+	// Deactivate user, so that the account stays unusable if it is restored
+	user.Active = false
+
+	// Delete user and save its deleted event to database
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		txUserRepo := s.userRepo.WithTx(tx)
+		if err := txUserRepo.Update(user); err != nil {
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+		if err := txUserRepo.Delete(user.ID); err != nil {
+			return fmt.Errorf("failed to delete user: %w", err)
+		}
+
+		// Sign user out everywhere
+		if _, err := s.sessionService.RevokeAllSessions(tx, user.ID, model.SessionRevokedDeleted); err != nil {
+			return err
+		}
+
+		// Publish user deleted event
+		return s.publishUserEvent(tx, contracts.TypeUserDeleted, user)
+	})
+}
+
+// publishUserEvent writes a user event to the outbox in tx, or on its own
+// when tx is nil
+func (s *userService) publishUserEvent(tx *gorm.DB, eventType string, user *model.User) error {
+	state := contracts.UserState{
+		UserID: user.ID,
+		Email:  user.Email,
+	}
+
+	var event contracts.Event
+	switch eventType {
+	case contracts.TypeUserCreated:
+		event = contracts.UserCreated{UserState: state}
+	case contracts.TypeUserUpdated:
+		event = contracts.UserUpdated{UserState: state}
+	case contracts.TypeUserLogin:
+		event = contracts.UserLogin{UserState: state}
+	case contracts.TypeUserPasswordChanged:
+		event = contracts.UserPasswordChanged{UserState: state}
+	case contracts.TypeUserSuspended:
+		event = contracts.UserSuspended{UserID: user.ID}
+	case contracts.TypeUserDeleted:
+		event = contracts.UserDeleted{UserID: user.ID}
+	default:
+		return fmt.Errorf("unknown user event type %s", eventType)
+	}
+
+	return messaging.EnqueueEvent(s.outboxRepo, tx, "user_events", user.ID.String(), event)
+}
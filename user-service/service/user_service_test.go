@@ -3,12 +3,16 @@ package service
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
-	"github.com/yourusername/ticket-system/user-service/config"
+	"github.com/yourusername/ticket-system/contracts"
+	"github.com/yourusername/ticket-system/messaging"
 	"github.com/yourusername/ticket-system/user-service/model"
+	"github.com/yourusername/ticket-system/user-service/repository"
+	"gorm.io/gorm"
 )
 
 // MockUserRepository is a mock implementation of UserRepository
@@ -29,6 +33,14 @@ func (m *MockUserRepository) FindByID(id uuid.UUID) (*model.User, error) {
 	return args.Get(0).(*model.User), args.Error(1)
 }
 
+func (m *MockUserRepository) FindByIDWithDeleted(id uuid.UUID) (*model.User, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.User), args.Error(1)
+}
+
 func (m *MockUserRepository) FindByEmail(email string) (*model.User, error) {
 	args := m.Called(email)
 	if args.Get(0) == nil {
@@ -37,6 +49,14 @@ func (m *MockUserRepository) FindByEmail(email string) (*model.User, error) {
 	return args.Get(0).(*model.User), args.Error(1)
 }
 
+func (m *MockUserRepository) Search(req model.UserSearchRequest) ([]model.User, int64, error) {
+	args := m.Called(req)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(int64), args.Error(2)
+	}
+	return args.Get(0).([]model.User), args.Get(1).(int64), args.Error(2)
+}
+
 func (m *MockUserRepository) Update(user *model.User) error {
 	args := m.Called(user)
 	return args.Error(0)
@@ -47,6 +67,15 @@ func (m *MockUserRepository) Delete(id uuid.UUID) error {
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) Anonymize(user *model.User) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) WithTx(tx *gorm.DB) repository.UserRepository {
+	return m
+}
+
 // MockRabbitMQ is a mock implementation of RabbitMQ
 type MockRabbitMQ struct {
 	mock.Mock
@@ -61,10 +90,51 @@ func (m *MockRabbitMQ) Close() {
 	m.Called()
 }
 
+// MockOutboxRepository is a mock implementation of OutboxRepository
+type MockOutboxRepository struct {
+	mock.Mock
+}
+
+func (m *MockOutboxRepository) Enqueue(tx *gorm.DB, message *messaging.OutboxMessage) error {
+	args := m.Called(tx, message)
+	return args.Error(0)
+}
+
+func (m *MockOutboxRepository) ClaimDue(limit int, lease time.Duration) ([]messaging.OutboxMessage, error) {
+	args := m.Called(limit, lease)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]messaging.OutboxMessage), args.Error(1)
+}
+
+func (m *MockOutboxRepository) MarkSent(message *messaging.OutboxMessage) error {
+	args := m.Called(message)
+	return args.Error(0)
+}
+
+func (m *MockOutboxRepository) MarkFailed(message *messaging.OutboxMessage, reason string, nextAttemptAt time.Time) error {
+	args := m.Called(message, reason, nextAttemptAt)
+	return args.Error(0)
+}
+
+func (m *MockOutboxRepository) Stats() (int64, *time.Time, error) {
+	args := m.Called()
+	if args.Get(1) == nil {
+		return args.Get(0).(int64), nil, args.Error(2)
+	}
+	return args.Get(0).(int64), args.Get(1).(*time.Time), args.Error(2)
+}
+
+func (m *MockOutboxRepository) DeleteSentBefore(before time.Time) (int64, error) {
+	args := m.Called(before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 func TestUserService_Register(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	mockRMQ := &config.RabbitMQ{} // Use actual struct for simplicity
-	userService := NewUserService(mockRepo, mockRMQ)
+	mockOutbox := new(MockOutboxRepository)
+	userService := NewUserService(mockRepo, mockOutbox, nil, nil, nil, nil, NewLoginThrottleService(repository.NewMemoryLoginAttemptStore(), mockOutbox), nil)
 
 	t.Run("successful registration", func(t *testing.T) {
 		req := model.RegisterRequest{
@@ -106,7 +176,7 @@ func TestUserService_Register(t *testing.T) {
 
 		mockRepo := new(MockUserRepository)
 		mockRepo.On("FindByEmail", req.Email).Return(existingUser, nil)
-		userService := NewUserService(mockRepo, mockRMQ)
+		userService := NewUserService(mockRepo, mockOutbox, nil, nil, nil, nil, NewLoginThrottleService(repository.NewMemoryLoginAttemptStore(), mockOutbox), nil)
 
 		result, err := userService.Register(req)
 
@@ -119,8 +189,8 @@ func TestUserService_Register(t *testing.T) {
 
 func TestUserService_Login(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	mockRMQ := &config.RabbitMQ{}
-	userService := NewUserService(mockRepo, mockRMQ)
+	mockOutbox := new(MockOutboxRepository)
+	userService := NewUserService(mockRepo, mockOutbox, nil, nil, nil, nil, NewLoginThrottleService(repository.NewMemoryLoginAttemptStore(), mockOutbox), nil)
 
 	t.Run("successful login", func(t *testing.T) {
 		req := model.LoginRequest{
@@ -142,7 +212,7 @@ func TestUserService_Login(t *testing.T) {
 
 		// Note: This test would need to mock the password checking
 		// For now, we'll test the flow assuming password is correct
-		result, err := userService.Login(req)
+		result, err := userService.Login(req, model.ClientInfo{})
 
 		// This might fail due to password hashing, but tests the structure
 		if err == nil {
@@ -161,9 +231,9 @@ func TestUserService_Login(t *testing.T) {
 
 		mockRepo := new(MockUserRepository)
 		mockRepo.On("FindByEmail", req.Email).Return(nil, errors.New("not found"))
-		userService := NewUserService(mockRepo, mockRMQ)
+		userService := NewUserService(mockRepo, mockOutbox, nil, nil, nil, nil, NewLoginThrottleService(repository.NewMemoryLoginAttemptStore(), mockOutbox), nil)
 
-		result, err := userService.Login(req)
+		result, err := userService.Login(req, model.ClientInfo{})
 
 		assert.Error(t, err)
 		assert.Nil(t, result)
@@ -173,8 +243,8 @@ func TestUserService_Login(t *testing.T) {
 
 func TestUserService_GetUserByID(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	mockRMQ := &config.RabbitMQ{}
-	userService := NewUserService(mockRepo, mockRMQ)
+	mockOutbox := new(MockOutboxRepository)
+	userService := NewUserService(mockRepo, mockOutbox, nil, nil, nil, nil, NewLoginThrottleService(repository.NewMemoryLoginAttemptStore(), mockOutbox), nil)
 
 	t.Run("user found", func(t *testing.T) {
 		userID := uuid.New()
@@ -203,7 +273,7 @@ func TestUserService_GetUserByID(t *testing.T) {
 
 		mockRepo := new(MockUserRepository)
 		mockRepo.On("FindByID", userID).Return(nil, errors.New("not found"))
-		userService := NewUserService(mockRepo, mockRMQ)
+		userService := NewUserService(mockRepo, mockOutbox, nil, nil, nil, nil, NewLoginThrottleService(repository.NewMemoryLoginAttemptStore(), mockOutbox), nil)
 
 		result, err := userService.GetUserByID(userID)
 
@@ -215,8 +285,8 @@ func TestUserService_GetUserByID(t *testing.T) {
 
 func TestUserService_UpdateProfile(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	mockRMQ := &config.RabbitMQ{}
-	userService := NewUserService(mockRepo, mockRMQ)
+	mockOutbox := new(MockOutboxRepository)
+	userService := NewUserService(mockRepo, mockOutbox, nil, nil, nil, nil, NewLoginThrottleService(repository.NewMemoryLoginAttemptStore(), mockOutbox), nil)
 
 	t.Run("successful update", func(t *testing.T) {
 		userID := uuid.New()
@@ -248,4 +318,42 @@ func TestUserService_UpdateProfile(t *testing.T) {
 		assert.Equal(t, req.Phone, result.Phone)
 		mockRepo.AssertExpectations(t)
 	})
-}
\ No newline at end of file
+}
+// The user events must match the shared event contracts that the other
+// services consume them by
+func TestUserService_PublishUserEvent_MatchesContract(t *testing.T) {
+	user := &model.User{
+		ID:    uuid.New(),
+		Email: "test@example.com",
+	}
+
+	eventTypes := []string{
+		contracts.TypeUserCreated,
+		contracts.TypeUserUpdated,
+		contracts.TypeUserLogin,
+		contracts.TypeUserPasswordChanged,
+	}
+
+	for _, eventType := range eventTypes {
+		t.Run(eventType, func(t *testing.T) {
+			var published *messaging.OutboxMessage
+			mockOutbox := new(MockOutboxRepository)
+			mockOutbox.On("Enqueue", mock.Anything, mock.AnythingOfType("*messaging.OutboxMessage")).
+				Run(func(args mock.Arguments) { published = args.Get(1).(*messaging.OutboxMessage) }).
+				Return(nil)
+			userService := &userService{userRepo: new(MockUserRepository), outboxRepo: mockOutbox}
+
+			err := userService.publishUserEvent(nil, eventType, user)
+
+			assert.NoError(t, err)
+			assert.Equal(t, "user_events", published.Exchange)
+			assert.Equal(t, eventType, published.RoutingKey)
+			assert.NoError(t, contracts.Validate(published.Payload))
+
+			envelope, err := contracts.Parse(published.Payload)
+			assert.NoError(t, err)
+			assert.Equal(t, published.ID.String(), envelope.ID)
+			assert.Equal(t, user.ID.String(), envelope.CorrelationID)
+		})
+	}
+}